@@ -24,3 +24,7 @@ import (
 // IATemplates holds IA template files in the struct of type embed.FS which implements the io/fs package's FS interface.
 //go:embed iatemplates/*
 var IATemplates embed.FS
+
+// IAAnomalyTemplates holds IA anomaly detection template files that compare metrics with their historical baselines.
+//go:embed iaanomalytemplates/*
+var IAAnomalyTemplates embed.FS
@@ -75,6 +75,7 @@ import (
 	"github.com/percona/pmm-managed/services/config"
 	"github.com/percona/pmm-managed/services/dbaas"
 	"github.com/percona/pmm-managed/services/grafana"
+	"github.com/percona/pmm-managed/services/ha"
 	"github.com/percona/pmm-managed/services/inventory"
 	inventorygrpc "github.com/percona/pmm-managed/services/inventory/grpc"
 	"github.com/percona/pmm-managed/services/management"
@@ -89,11 +90,13 @@ import (
 	"github.com/percona/pmm-managed/services/server"
 	"github.com/percona/pmm-managed/services/supervisord"
 	"github.com/percona/pmm-managed/services/telemetry"
+	"github.com/percona/pmm-managed/services/topology"
 	"github.com/percona/pmm-managed/services/versioncache"
 	"github.com/percona/pmm-managed/services/victoriametrics"
 	"github.com/percona/pmm-managed/services/vmalert"
 	"github.com/percona/pmm-managed/utils/clean"
 	"github.com/percona/pmm-managed/utils/interceptors"
+	"github.com/percona/pmm-managed/utils/jsonapi"
 	"github.com/percona/pmm-managed/utils/logger"
 )
 
@@ -146,6 +149,7 @@ type gRPCServerDeps struct {
 	vmalert              *vmalert.Service
 	settings             *models.Settings
 	alertsService        *ia.AlertsService
+	channelsService      *ia.ChannelsService
 	templatesService     *ia.TemplatesService
 	rulesService         *ia.RulesService
 	jobsService          *agents.JobsService
@@ -211,7 +215,7 @@ func runGRPCServer(ctx context.Context, deps *gRPCServerDeps) {
 	managementpb.RegisterAnnotationServer(gRPCServer, managementgrpc.NewAnnotationServer(deps.db, deps.grafanaClient))
 	managementpb.RegisterSecurityChecksServer(gRPCServer, management.NewChecksAPIService(deps.checksService))
 
-	iav1beta1.RegisterChannelsServer(gRPCServer, ia.NewChannelsService(deps.db, deps.alertmanager))
+	iav1beta1.RegisterChannelsServer(gRPCServer, deps.channelsService)
 	iav1beta1.RegisterTemplatesServer(gRPCServer, deps.templatesService)
 	iav1beta1.RegisterRulesServer(gRPCServer, deps.rulesService)
 	iav1beta1.RegisterAlertsServer(gRPCServer, deps.alertsService)
@@ -275,8 +279,26 @@ func runGRPCServer(ctx context.Context, deps *gRPCServerDeps) {
 }
 
 type http1ServerDeps struct {
-	logs       *supervisord.Logs
-	authServer *grafana.AuthServer
+	logs                     *supervisord.Logs
+	authServer               *grafana.AuthServer
+	alertmanager             *alertmanager.Service
+	rulesTester              *ia.RulesTester
+	channelsService          *ia.ChannelsService
+	slosService              *ia.SLOsService
+	agentsService            *inventory.AgentsService
+	bulkService              *inventory.BulkService
+	manifestService          *management.ManifestService
+	topologyService          *topology.Service
+	redactionPoliciesService *management.RedactionPoliciesService
+	driftService             *inventory.DriftService
+	scrapeHealthService      *inventory.ScrapeHealthService
+	upgradeCampaignsService  *management.UpgradeCampaignsService
+	resolutionsService       *management.MetricsResolutionOverridesService
+	relabelRulesService      *management.MetricRelabelRulesService
+	collectorsService        *management.CollectorsService
+	serviceDiscoveryService  *management.ServiceDiscoveryService
+	clustersService          *management.ClustersService
+	server                   *server.Server
 }
 
 // runHTTP1Server runs grpc-gateway and other HTTP 1.1 APIs (like auth_request and logs.zip)
@@ -364,6 +386,8 @@ func runHTTP1Server(ctx context.Context, deps *http1ServerDeps) {
 	mux := http.NewServeMux()
 	addLogsHandler(mux, deps.logs)
 	mux.Handle("/auth_request", deps.authServer)
+	mux.HandleFunc(alertmanager.NotifyPath, deps.alertmanager.ServeNotify)
+	addJSONAPIHandlers(mux, deps)
 	mux.Handle("/", proxyMux)
 
 	server := &http.Server{
@@ -386,6 +410,71 @@ func runHTTP1Server(ctx context.Context, deps *http1ServerDeps) {
 	cancel()
 }
 
+// addJSONAPIHandlers adds handlers for API methods that are not described with protobuf.
+func addJSONAPIHandlers(mux *http.ServeMux, deps *http1ServerDeps) {
+	mux.Handle("/v1/management/ia/Rules/Test", jsonapi.Handler("ia.Rules/TestAlertRule", deps.rulesTester, deps.rulesTester.TestAlertRule))
+	mux.Handle("/v1/management/ia/Channels/GetMessageTemplates", jsonapi.Handler("ia.Channels/GetMessageTemplates", deps.channelsService, deps.channelsService.GetMessageTemplates))
+	mux.Handle("/v1/management/ia/Channels/ChangeMessageTemplates", jsonapi.Handler("ia.Channels/ChangeMessageTemplates", deps.channelsService, deps.channelsService.ChangeMessageTemplates))
+	mux.Handle("/v1/management/ia/Channels/PreviewMessage", jsonapi.Handler("ia.Channels/PreviewMessage", deps.channelsService, deps.channelsService.PreviewMessage))
+	mux.Handle("/v1/management/ia/Channels/Test", jsonapi.Handler("ia.Channels/TestChannel", deps.channelsService, deps.channelsService.TestChannel))
+	mux.Handle("/v1/management/ia/Channels/ListDeliveryStatuses", jsonapi.Handler("ia.Channels/ListDeliveryStatuses", deps.channelsService, deps.channelsService.ListDeliveryStatuses))
+	mux.Handle("/v1/management/ia/SLOs/List", jsonapi.Handler("ia.SLOs/ListSLOs", deps.slosService, deps.slosService.ListSLOs))
+	mux.Handle("/v1/management/ia/SLOs/Add", jsonapi.Handler("ia.SLOs/AddSLO", deps.slosService, deps.slosService.AddSLO))
+	mux.Handle("/v1/management/ia/SLOs/Change", jsonapi.Handler("ia.SLOs/ChangeSLO", deps.slosService, deps.slosService.ChangeSLO))
+	mux.Handle("/v1/management/ia/SLOs/Remove", jsonapi.Handler("ia.SLOs/RemoveSLO", deps.slosService, deps.slosService.RemoveSLO))
+	mux.Handle("/v1/management/ia/SLOs/GetBudget", jsonapi.Handler("ia.SLOs/GetSLOBudget", deps.slosService, deps.slosService.GetSLOBudget))
+	mux.Handle("/v1/inventory/Agents/ListEvents", jsonapi.Handler("inventory.Agents/ListEvents", deps.agentsService, deps.agentsService.ListEvents))
+	mux.Handle("/v1/inventory/Bulk/Change", jsonapi.Handler("inventory.Bulk/Change", deps.bulkService, deps.bulkService.Change))
+	mux.Handle("/v1/management/Manifest/Plan", jsonapi.Handler("management.Manifest/Plan", deps.manifestService, deps.manifestService.Plan))
+	mux.Handle("/v1/management/Manifest/Apply", jsonapi.Handler("management.Manifest/Apply", deps.manifestService, deps.manifestService.Apply))
+	mux.Handle("/v1/inventory/Topology/Get", jsonapi.Handler("inventory.Topology/Get", deps.topologyService, deps.topologyService.Get))
+	mux.Handle("/v1/inventory/Topology/AddEdge", jsonapi.Handler("inventory.Topology/AddEdge", deps.topologyService, deps.topologyService.AddEdge))
+	mux.Handle("/v1/inventory/Topology/RemoveEdge", jsonapi.Handler("inventory.Topology/RemoveEdge", deps.topologyService, deps.topologyService.RemoveEdge))
+	mux.Handle("/v1/inventory/Topology/Discover", jsonapi.Handler("inventory.Topology/Discover", deps.topologyService, deps.topologyService.DiscoverNow))
+	mux.Handle("/v1/management/QAN/RedactionPolicies/List", jsonapi.Handler("management.RedactionPolicies/List", deps.redactionPoliciesService, deps.redactionPoliciesService.List))
+	mux.Handle("/v1/management/QAN/RedactionPolicies/Add", jsonapi.Handler("management.RedactionPolicies/Add", deps.redactionPoliciesService, deps.redactionPoliciesService.Add))
+	mux.Handle("/v1/management/QAN/RedactionPolicies/Change", jsonapi.Handler("management.RedactionPolicies/Change", deps.redactionPoliciesService, deps.redactionPoliciesService.Change))
+	mux.Handle("/v1/management/QAN/RedactionPolicies/Remove", jsonapi.Handler("management.RedactionPolicies/Remove", deps.redactionPoliciesService, deps.redactionPoliciesService.Remove))
+	mux.Handle("/v1/inventory/Agents/GetDrift", jsonapi.Handler("inventory.Agents/GetDrift", deps.driftService, deps.driftService.GetDrift))
+	mux.Handle("/v1/inventory/Agents/HealDrift", jsonapi.Handler("inventory.Agents/HealDrift", deps.driftService, deps.driftService.HealDrift))
+	mux.Handle("/v1/inventory/Agents/GetScrapeHealth", jsonapi.Handler("inventory.Agents/GetScrapeHealth", deps.scrapeHealthService, deps.scrapeHealthService.GetScrapeHealth))
+	mux.Handle("/v1/management/UpgradeCampaigns/Start", jsonapi.Handler("management.UpgradeCampaigns/Start", deps.upgradeCampaignsService, deps.upgradeCampaignsService.Start))
+	mux.Handle("/v1/management/UpgradeCampaigns/List", jsonapi.Handler("management.UpgradeCampaigns/List", deps.upgradeCampaignsService, deps.upgradeCampaignsService.List))
+	mux.Handle("/v1/management/UpgradeCampaigns/Pause", jsonapi.Handler("management.UpgradeCampaigns/Pause", deps.upgradeCampaignsService, deps.upgradeCampaignsService.Pause))
+	mux.Handle("/v1/management/UpgradeCampaigns/Resume", jsonapi.Handler("management.UpgradeCampaigns/Resume", deps.upgradeCampaignsService, deps.upgradeCampaignsService.Resume))
+	mux.Handle("/v1/management/UpgradeCampaigns/Cancel", jsonapi.Handler("management.UpgradeCampaigns/Cancel", deps.upgradeCampaignsService, deps.upgradeCampaignsService.Cancel))
+	mux.Handle("/v1/management/UpgradeCampaigns/GetPMMAgentUpgrade", jsonapi.Handler("management.UpgradeCampaigns/GetPMMAgentUpgrade", deps.upgradeCampaignsService, deps.upgradeCampaignsService.GetPMMAgentUpgrade))
+	mux.Handle("/v1/management/UpgradeCampaigns/ReportPMMAgentUpgrade", jsonapi.Handler("management.UpgradeCampaigns/ReportPMMAgentUpgrade", deps.upgradeCampaignsService, deps.upgradeCampaignsService.ReportPMMAgentUpgrade))
+	mux.Handle("/v1/management/MetricsResolutions/Overrides/List", jsonapi.Handler("management.MetricsResolutionOverrides/List", deps.resolutionsService, deps.resolutionsService.List))
+	mux.Handle("/v1/management/MetricsResolutions/Overrides/Add", jsonapi.Handler("management.MetricsResolutionOverrides/Add", deps.resolutionsService, deps.resolutionsService.Add))
+	mux.Handle("/v1/management/MetricsResolutions/Overrides/Change", jsonapi.Handler("management.MetricsResolutionOverrides/Change", deps.resolutionsService, deps.resolutionsService.Change))
+	mux.Handle("/v1/management/MetricsResolutions/Overrides/Remove", jsonapi.Handler("management.MetricsResolutionOverrides/Remove", deps.resolutionsService, deps.resolutionsService.Remove))
+	mux.Handle("/v1/management/MetricRelabelRules/List", jsonapi.Handler("management.MetricRelabelRules/List", deps.relabelRulesService, deps.relabelRulesService.List))
+	mux.Handle("/v1/management/MetricRelabelRules/Add", jsonapi.Handler("management.MetricRelabelRules/Add", deps.relabelRulesService, deps.relabelRulesService.Add))
+	mux.Handle("/v1/management/MetricRelabelRules/Change", jsonapi.Handler("management.MetricRelabelRules/Change", deps.relabelRulesService, deps.relabelRulesService.Change))
+	mux.Handle("/v1/management/MetricRelabelRules/Remove", jsonapi.Handler("management.MetricRelabelRules/Remove", deps.relabelRulesService, deps.relabelRulesService.Remove))
+	mux.Handle("/v1/management/Collectors/List", jsonapi.Handler("management.Collectors/List", deps.collectorsService, deps.collectorsService.List))
+	mux.Handle("/v1/management/Collectors/Change", jsonapi.Handler("management.Collectors/Change", deps.collectorsService, deps.collectorsService.Change))
+	mux.Handle("/v1/management/ServiceDiscovery/Sources/List", jsonapi.Handler("management.ServiceDiscovery/List", deps.serviceDiscoveryService, deps.serviceDiscoveryService.List))
+	mux.Handle("/v1/management/ServiceDiscovery/Sources/Add", jsonapi.Handler("management.ServiceDiscovery/Add", deps.serviceDiscoveryService, deps.serviceDiscoveryService.Add))
+	mux.Handle("/v1/management/ServiceDiscovery/Sources/Change", jsonapi.Handler("management.ServiceDiscovery/Change", deps.serviceDiscoveryService, deps.serviceDiscoveryService.Change))
+	mux.Handle("/v1/management/ServiceDiscovery/Sources/Remove", jsonapi.Handler("management.ServiceDiscovery/Remove", deps.serviceDiscoveryService, deps.serviceDiscoveryService.Remove))
+	mux.Handle("/v1/management/ServiceDiscovery/Sources/Sync", jsonapi.Handler("management.ServiceDiscovery/Sync", deps.serviceDiscoveryService, deps.serviceDiscoveryService.Sync))
+	mux.Handle("/v1/management/MySQL/Clusters/Add", jsonapi.Handler("management.Clusters/AddMySQL", deps.clustersService, deps.clustersService.AddMySQL))
+	mux.Handle("/v1/management/MongoDB/Clusters/Add", jsonapi.Handler("management.Clusters/AddMongoDB", deps.clustersService, deps.clustersService.AddMongoDB))
+	mux.Handle("/v1/management/PostgreSQL/Clusters/Add", jsonapi.Handler("management.Clusters/AddPostgreSQL", deps.clustersService, deps.clustersService.AddPostgreSQL))
+	mux.Handle("/v1/management/Clusters/List", jsonapi.Handler("management.Clusters/List", deps.clustersService, deps.clustersService.List))
+	mux.Handle("/v1/management/Clusters/Remove", jsonapi.Handler("management.Clusters/Remove", deps.clustersService, deps.clustersService.Remove))
+	mux.Handle("/v1/management/Clusters/RemoveMember", jsonapi.Handler("management.Clusters/RemoveMember", deps.clustersService, deps.clustersService.RemoveMember))
+	mux.Handle("/v1/management/Clusters/Sync", jsonapi.Handler("management.Clusters/Sync", deps.clustersService, deps.clustersService.Sync))
+	// serverpb methods extended with retention tiers; they replace grpc-gateway handlers
+	mux.Handle("/v1/Settings/RetentionTiers/Get", jsonapi.Handler("server.Server/GetRetentionTiers", deps.server, deps.server.GetRetentionTiers))
+	mux.Handle("/v1/Settings/RetentionTiers/Change", jsonapi.Handler("server.Server/ChangeRetentionTiers", deps.server, deps.server.ChangeRetentionTiers))
+	mux.Handle("/v1/Settings/RemoteWrite/Get", jsonapi.Handler("server.Server/GetRemoteWrite", deps.server, deps.server.GetRemoteWrite))
+	mux.Handle("/v1/Settings/RemoteWrite/Change", jsonapi.Handler("server.Server/ChangeRemoteWrite", deps.server, deps.server.ChangeRemoteWrite))
+	mux.Handle("/v1/Settings/RemoteWrite/Status", jsonapi.Handler("server.Server/GetRemoteWriteStatus", deps.server, deps.server.GetRemoteWriteStatus))
+}
+
 // runDebugServer runs debug server until context is canceled, then gracefully stops it.
 // TODO merge with HTTP1 server? https://jira.percona.com/browse/PMM-4326
 func runDebugServer(ctx context.Context) {
@@ -447,6 +536,44 @@ func runDebugServer(ctx context.Context) {
 	cancel()
 }
 
+// runHAServer runs HTTP server for requests forwarded by other pmm-managed instances.
+// It uses TLS if it is configured for high-availability service.
+func runHAServer(ctx context.Context, addr string, haService *ha.Service, handler http.Handler) {
+	l := logrus.WithField("component", "ha-server")
+	scheme := "http"
+	if haService.ServerTLSConfig() != nil {
+		scheme = "https"
+	}
+	l.Infof("Starting server on %s://%s/ ...", scheme, addr)
+
+	server := &http.Server{
+		Addr:      addr,
+		ErrorLog:  log.New(os.Stderr, "runHAServer: ", 0),
+		Handler:   haService.Authenticate(handler),
+		TLSConfig: haService.ServerTLSConfig(),
+	}
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			// certificate and key are already loaded into TLSConfig
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
+			l.Panic(err)
+		}
+		l.Info("Server stopped.")
+	}()
+
+	<-ctx.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	if err := server.Shutdown(ctx); err != nil {
+		l.Errorf("Failed to shutdown gracefully: %s", err)
+	}
+	cancel()
+}
+
 type setupDeps struct {
 	sqlDB        *sql.DB
 	supervisord  *supervisord.Service
@@ -517,7 +644,7 @@ func setup(ctx context.Context, deps *setupDeps) bool {
 	return true
 }
 
-func getQANClient(ctx context.Context, sqlDB *sql.DB, dbName, qanAPIAddr string) *qan.Client {
+func getQANClient(ctx context.Context, sqlDB *sql.DB, dbName, qanAPIAddr string, spool *qan.Spool) *qan.Client {
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 		grpc.WithBackoffMaxDelay(time.Second), //nolint:staticcheck
@@ -536,7 +663,7 @@ func getQANClient(ctx context.Context, sqlDB *sql.DB, dbName, qanAPIAddr string)
 	reformL := sqlmetrics.NewReform("postgres", dbName+"/qan", l.Tracef)
 	prom.MustRegister(reformL)
 	db := reform.NewDB(sqlDB, postgresql.Dialect, reformL)
-	return qan.NewClient(conn, db)
+	return qan.NewClient(conn, db, spool)
 }
 
 func migrateDB(ctx context.Context, sqlDB *sql.DB, dbName, dbAddress, dbUsername, dbPassword string) {
@@ -590,6 +717,14 @@ func main() {
 
 	grafanaAddrF := kingpin.Flag("grafana-addr", "Grafana HTTP API address").Default("127.0.0.1:3000").String()
 	qanAPIAddrF := kingpin.Flag("qan-api-addr", "QAN API gRPC API address").Default("127.0.0.1:9911").String()
+	qanSpoolDirF := kingpin.Flag("qan-spool-dir", "Directory for QAN data waiting to be sent to QAN API; empty value disables spooling, and data is lost if QAN API is unavailable").
+		Envar("PMM_QAN_SPOOL_DIR").Default("/srv/pmm-managed/qan-spool").String()
+	qanSpoolSizeF := kingpin.Flag("qan-spool-size", "Maximal size of QAN spool; the oldest data is dropped when it is full").
+		Envar("PMM_QAN_SPOOL_SIZE").Default("512MB").Bytes()
+	qanForwardWorkersF := kingpin.Flag("qan-forward-workers", "Number of concurrent requests to QAN API (or writes to QAN spool)").
+		Envar("PMM_QAN_FORWARD_WORKERS").Default("4").Int()
+	qanForwardBatchSizeF := kingpin.Flag("qan-forward-batch-size", "Maximal number of QAN buckets from all pmm-agents sent in one request (or spooled together)").
+		Envar("PMM_QAN_FORWARD_BATCH_SIZE").Default("5000").Int()
 	dbaasControllerAPIAddrF := kingpin.Flag("dbaas-controller-api-addr", "DBaaS Controller gRPC API address").Default("127.0.0.1:20201").String()
 
 	versionServiceAPIURLF := kingpin.Flag("version-service-api-url", "Version Service API URL").
@@ -602,6 +737,27 @@ func main() {
 
 	supervisordConfigDirF := kingpin.Flag("supervisord-config-dir", "Supervisord configuration directory").Required().String()
 
+	haInstanceIDF := kingpin.Flag("ha-instance-id", "Unique ID of this pmm-managed instance; enables high-availability mode").
+		Envar("PMM_HA_INSTANCE_ID").String()
+	haListenAddrF := kingpin.Flag("ha-listen-addr", "Listen address for requests forwarded by other pmm-managed instances; "+
+		"non-loopback address requires --ha-tls-cert-file and --ha-tls-key-file").
+		Envar("PMM_HA_LISTEN_ADDR").Default("127.0.0.1:7774").String()
+	haTLSCertFileF := kingpin.Flag("ha-tls-cert-file", "TLS certificate file for requests forwarded by other pmm-managed instances").
+		Envar("PMM_HA_TLS_CERT_FILE").String()
+	haTLSKeyFileF := kingpin.Flag("ha-tls-key-file", "TLS key file for requests forwarded by other pmm-managed instances").
+		Envar("PMM_HA_TLS_KEY_FILE").String()
+	haTLSCAFileF := kingpin.Flag("ha-tls-ca-file", "TLS CA certificate file used to verify other pmm-managed instances (system pool by default)").
+		Envar("PMM_HA_TLS_CA_FILE").String()
+	haAdvertiseAddrF := kingpin.Flag("ha-advertise-addr", "Address of this instance reachable by other pmm-managed instances").
+		Envar("PMM_HA_ADVERTISE_ADDR").String()
+
+	agentEventsRetentionF := kingpin.Flag("agent-events-retention", "How long to keep Agent connection and status history").
+		Envar("PMM_AGENT_EVENTS_RETENTION").Default("720h").Duration()
+	agentDriftAutoHealF := kingpin.Flag("agent-drift-auto-heal", "Restart Agents with configuration drift").
+		Envar("PMM_AGENT_DRIFT_AUTO_HEAL").Bool()
+	clusterMemberGracePeriodF := kingpin.Flag("cluster-member-grace-period", "How long to keep monitored cluster members that are not discovered; 0 keeps them until removed explicitly").
+		Envar("PMM_CLUSTER_MEMBER_GRACE_PERIOD").Default("15m").Duration()
+
 	logLevelF := kingpin.Flag("log-level", "Set logging level").Envar("PMM_LOG_LEVEL").Default("info").Enum("trace", "debug", "info", "warn", "error", "fatal")
 	debugF := kingpin.Flag("debug", "Enable debug logging").Envar("PMM_DEBUG").Bool()
 	traceF := kingpin.Flag("trace", "[DEPRECATED] Enable trace logging (implies debug)").Envar("PMM_TRACE").Bool()
@@ -661,8 +817,9 @@ func main() {
 		l.Panicf("failed to set PMM Server ID")
 	}
 
-	cleaner := clean.New(db)
+	cleaner := clean.New(db, *agentEventsRetentionF)
 	externalRules := vmalert.NewExternalRules()
+	remoteWrite := victoriametrics.NewRemoteWriteService()
 
 	vmParams, err := models.NewVictoriaMetricsParams(victoriametrics.BasePrometheusConfigPath)
 	if err != nil {
@@ -680,9 +837,35 @@ func main() {
 
 	minioService := minio.New()
 
-	qanClient := getQANClient(ctx, sqlDB, *postgresDBNameF, *qanAPIAddrF)
+	var qanSpool *qan.Spool
+	if *qanSpoolDirF != "" {
+		if qanSpool, err = qan.NewSpool(*qanSpoolDirF, int64(*qanSpoolSizeF)); err != nil {
+			l.Panicf("QAN spool problem: %+v.", err)
+		}
+		prom.MustRegister(qanSpool)
+	}
+	qanClient := getQANClient(ctx, sqlDB, *postgresDBNameF, *qanAPIAddrF, qanSpool)
+	qanForwarder := qan.NewForwarder(qanClient, qan.ForwarderParams{
+		Workers:   *qanForwardWorkersF,
+		BatchSize: *qanForwardBatchSizeF,
+		Spool:     qanSpool != nil,
+	})
+	prom.MustRegister(qanForwarder)
+
+	haService, err := ha.New(db, &ha.Params{
+		InstanceID:       *haInstanceIDF,
+		AdvertiseAddress: *haAdvertiseAddrF,
+		ListenAddress:    *haListenAddrF,
+		TLSCertFile:      *haTLSCertFileF,
+		TLSKeyFile:       *haTLSKeyFileF,
+		TLSCAFile:        *haTLSCAFileF,
+	})
+	if err != nil {
+		l.Fatalf("Could not create high-availability service: %s", err)
+	}
+	prom.MustRegister(haService)
 
-	agentsRegistry := agents.NewRegistry(db)
+	agentsRegistry := agents.NewRegistry(db, haService)
 	backupRemovalService := backup.NewRemovalService(db, minioService)
 	backupRetentionService := backup.NewRetentionService(db, backupRemovalService)
 	prom.MustRegister(agentsRegistry)
@@ -710,9 +893,15 @@ func main() {
 
 	jobsService := agents.NewJobsService(db, agentsRegistry, backupRetentionService)
 	agentsStateUpdater := agents.NewStateUpdater(db, agentsRegistry, vmdb)
-	agentsHandler := agents.NewHandler(db, qanClient, vmdb, agentsRegistry, agentsStateUpdater, jobsService)
+	agentsDriftDetector := agents.NewDriftDetector(agentsRegistry, agentsStateUpdater, *agentDriftAutoHealF)
+	prom.MustRegister(agentsDriftDetector)
+	agentsHandler := agents.NewHandler(db, qanForwarder, vmdb, agentsRegistry, agentsStateUpdater, jobsService)
 
 	actionsService := agents.NewActionsService(agentsRegistry)
+	topologyService := topology.New(db, actionsService)
+	upgradeCampaignsService := management.NewUpgradeCampaignsService(db, agentsRegistry)
+	serviceDiscoveryService := management.NewServiceDiscoveryService(db, vmdb)
+	clustersService := management.NewClustersService(db, actionsService, agentsStateUpdater, vmdb, *clusterMemberGracePeriodF)
 
 	checksService, err := checks.New(actionsService, alertManager, db, *victoriaMetricsURLF)
 	if err != nil {
@@ -730,6 +919,19 @@ func main() {
 	templatesService.CollectTemplates(ctx)
 	rulesService := ia.NewRulesService(db, templatesService, vmalert, alertManager)
 	alertsService := ia.NewAlertsService(db, alertManager, templatesService)
+	channelsService := ia.NewChannelsService(db, alertManager)
+	rulesTester, err := ia.NewRulesTester(db, templatesService, *victoriaMetricsURLF)
+	if err != nil {
+		l.Fatalf("Could not create rules tester: %s", err)
+	}
+	slosService, err := ia.NewSLOsService(db, rulesService, *victoriaMetricsURLF)
+	if err != nil {
+		l.Fatalf("Could not create SLOs service: %s", err)
+	}
+	scrapeHealthService, err := inventory.NewScrapeHealthService(db, *victoriaMetricsURLF)
+	if err != nil {
+		l.Fatalf("Could not create scrape health service: %s", err)
+	}
 
 	versionService := managementdbaas.NewVersionServiceClient(*versionServiceAPIURLF)
 
@@ -756,6 +958,8 @@ func main() {
 		AwsInstanceChecker:   awsInstanceChecker,
 		GrafanaClient:        grafanaClient,
 		VMAlertExternalRules: externalRules,
+		RemoteWrite:          remoteWrite,
+		RetentionValidator:   vmdb,
 		RulesService:         rulesService,
 		DbaasClient:          dbaasClient,
 		Emailer:              emailer,
@@ -858,6 +1062,20 @@ func main() {
 	l.Info("Starting services...")
 	var wg sync.WaitGroup
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		haService.Run(ctx)
+	}()
+
+	if haService.Enabled() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runHAServer(ctx, *haListenAddrF, haService, agents.NewForwardingHandler(agentsRegistry, agentsStateUpdater))
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -875,6 +1093,12 @@ func main() {
 		defer wg.Done()
 		vmdb.Run(ctx)
 	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		remoteWrite.Run(ctx)
+	}()
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -884,7 +1108,50 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		checksService.Run(ctx)
+		haService.RunAsLeader(ctx, checksService.Run)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		haService.RunAsLeader(ctx, topologyService.Run)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		haService.RunAsLeader(ctx, upgradeCampaignsService.Run)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		haService.RunAsLeader(ctx, serviceDiscoveryService.Run)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		haService.RunAsLeader(ctx, clustersService.Run)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		qanClient.Run(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		qanForwarder.Run(ctx)
+	}()
+
+	// every pmm-managed instance checks pmm-agents connected to it
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		agentsDriftDetector.Run(ctx)
 	}()
 
 	wg.Add(1)
@@ -896,19 +1163,19 @@ func main() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		telemetry.Run(ctx)
+		haService.RunAsLeader(ctx, telemetry.Run)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		schedulerService.Run(ctx)
+		haService.RunAsLeader(ctx, schedulerService.Run)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		versionCache.Run(ctx)
+		haService.RunAsLeader(ctx, versionCache.Run)
 	}()
 
 	wg.Add(1)
@@ -931,6 +1198,7 @@ func main() {
 				vmalert:              vmalert,
 				settings:             settings,
 				alertsService:        alertsService,
+				channelsService:      channelsService,
 				templatesService:     templatesService,
 				rulesService:         rulesService,
 				jobsService:          jobsService,
@@ -951,8 +1219,26 @@ func main() {
 	go func() {
 		defer wg.Done()
 		runHTTP1Server(ctx, &http1ServerDeps{
-			logs:       logs,
-			authServer: authServer,
+			logs:                     logs,
+			authServer:               authServer,
+			alertmanager:             alertManager,
+			rulesTester:              rulesTester,
+			channelsService:          channelsService,
+			slosService:              slosService,
+			agentsService:            inventory.NewAgentsService(db, agentsRegistry, agentsStateUpdater, vmdb, connectionCheck),
+			bulkService:              inventory.NewBulkService(db, agentsStateUpdater, vmdb),
+			manifestService:          management.NewManifestService(db, agentsStateUpdater, vmdb, connectionCheck, versionCache, defaultsFileParser),
+			topologyService:          topologyService,
+			redactionPoliciesService: management.NewRedactionPoliciesService(db),
+			driftService:             inventory.NewDriftService(db, agentsDriftDetector),
+			scrapeHealthService:      scrapeHealthService,
+			upgradeCampaignsService:  upgradeCampaignsService,
+			serviceDiscoveryService:  serviceDiscoveryService,
+			clustersService:          clustersService,
+			resolutionsService:       management.NewMetricsResolutionOverridesService(db, agentsStateUpdater, vmdb),
+			relabelRulesService:      management.NewMetricRelabelRulesService(db, agentsStateUpdater, vmdb, vmdb),
+			collectorsService:        management.NewCollectorsService(db, agentsStateUpdater, vmdb),
+			server:                   server,
 		})
 	}()
 
@@ -0,0 +1,57 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import "time"
+
+// AgentDriftKind represents a kind of difference between Agent configuration sent to pmm-agent
+// and Agent state reported by pmm-agent.
+type AgentDriftKind string
+
+// Agent drift kinds.
+const (
+	// AgentNotReported means that Agent was sent to pmm-agent, but pmm-agent did not report its state.
+	AgentNotReported AgentDriftKind = "not_reported"
+	// AgentNotRunning means that pmm-agent reported that Agent is not running.
+	AgentNotRunning AgentDriftKind = "not_running"
+	// AgentStaleConfig means that Agent configuration was changed, but pmm-agent did not report
+	// Agent restart since then, so it still runs with the old configuration.
+	AgentStaleConfig AgentDriftKind = "stale_config"
+	// AgentUnexpected means that pmm-agent reported running Agent that should not run (removed or disabled).
+	AgentUnexpected AgentDriftKind = "unexpected"
+)
+
+// AgentDrift represents a difference between configuration sent to pmm-agent and state reported by it
+// for a single Agent (or a group of Agents running as a single process, like rds_exporter).
+type AgentDrift struct {
+	AgentID   string
+	AgentType string
+	Kind      AgentDriftKind
+
+	// State last reported by pmm-agent; empty for AgentNotReported kind.
+	Status          string
+	ListenPort      uint32
+	ProcessExecPath string
+	// StartedAt is zero if pmm-agent did not report Agent start since it connected.
+	StartedAt  time.Time
+	ReportedAt time.Time
+
+	// ChangedAt, AddedArgs and RemovedArgs are set for AgentStaleConfig kind; secrets are masked.
+	ChangedAt   time.Time
+	AddedArgs   []string
+	RemovedArgs []string
+}
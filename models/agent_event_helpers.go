@@ -0,0 +1,134 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gopkg.in/reform.v1"
+)
+
+// CreateAgentEventParams are params for creating a new Agent event.
+type CreateAgentEventParams struct {
+	AgentID   string
+	Type      AgentEventType
+	Reason    string
+	OldStatus string
+	NewStatus string
+}
+
+// CreateAgentEvent records a new Agent event.
+// Node ID is taken from pmm-agent the Agent belongs to; it is empty if Agent is not found.
+func CreateAgentEvent(q *reform.Querier, params CreateAgentEventParams) (*AgentEvent, error) {
+	var nodeID string
+	agent := &Agent{AgentID: params.AgentID}
+	switch err := q.Reload(agent); err {
+	case nil:
+		if agent.PMMAgentID != nil {
+			agent = &Agent{AgentID: *agent.PMMAgentID}
+			if err = q.Reload(agent); err != nil && err != reform.ErrNoRows {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if agent.RunsOnNodeID != nil {
+			nodeID = *agent.RunsOnNodeID
+		}
+	case reform.ErrNoRows:
+		// Agent may be already removed or never existed (for example, on authentication failure)
+	default:
+		return nil, errors.WithStack(err)
+	}
+
+	event := &AgentEvent{
+		ID:        "/agent_event_id/" + uuid.New().String(),
+		AgentID:   params.AgentID,
+		NodeID:    nodeID,
+		Type:      params.Type,
+		Reason:    params.Reason,
+		OldStatus: params.OldStatus,
+		NewStatus: params.NewStatus,
+	}
+	if err := q.Insert(event); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return event, nil
+}
+
+// AgentEventFilters represents filters for Agent events.
+type AgentEventFilters struct {
+	// Return only events of that Agent.
+	AgentID string
+	// Return only events of Agents running on that Node.
+	NodeID string
+	// Return only events created after that time.
+	Since time.Time
+	// Return at most that number of newest events; 0 means no limit.
+	Limit int
+}
+
+// FindAgentEvents returns Agent events by filters, newest first.
+func FindAgentEvents(q *reform.Querier, filters AgentEventFilters) ([]*AgentEvent, error) {
+	var conditions []string
+	var args []interface{}
+	idx := 1
+	if filters.AgentID != "" {
+		conditions = append(conditions, fmt.Sprintf("agent_id = %s", q.Placeholder(idx)))
+		args = append(args, filters.AgentID)
+		idx++
+	}
+
+	if filters.NodeID != "" {
+		conditions = append(conditions, fmt.Sprintf("node_id = %s", q.Placeholder(idx)))
+		args = append(args, filters.NodeID)
+		idx++
+	}
+
+	if !filters.Since.IsZero() {
+		conditions = append(conditions, fmt.Sprintf("created_at > %s", q.Placeholder(idx)))
+		args = append(args, filters.Since)
+	}
+
+	var tail strings.Builder
+	if len(conditions) != 0 {
+		tail.WriteString("WHERE " + strings.Join(conditions, " AND ") + " ")
+	}
+	tail.WriteString("ORDER BY created_at DESC, id")
+	if filters.Limit > 0 {
+		fmt.Fprintf(&tail, " LIMIT %d", filters.Limit)
+	}
+
+	structs, err := q.SelectAllFrom(AgentEventTable, tail.String(), args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	events := make([]*AgentEvent, len(structs))
+	for i, s := range structs {
+		events[i] = s.(*AgentEvent)
+	}
+	return events, nil
+}
+
+// CleanupOldAgentEvents deletes Agent events older than a specified date.
+func CleanupOldAgentEvents(q *reform.Querier, olderThan time.Time) error {
+	_, err := q.DeleteFrom(AgentEventTable, " WHERE created_at <= $1", olderThan)
+	return errors.WithStack(err)
+}
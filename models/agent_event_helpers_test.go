@@ -0,0 +1,87 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+)
+
+func TestAgentEvents(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SkipFixtures, nil)
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, tx.Rollback())
+	}()
+	q := tx.Querier
+
+	for _, str := range []reform.Struct{
+		&models.Node{NodeID: "N1", NodeType: models.GenericNodeType, NodeName: "Node 1"},
+		&models.Agent{AgentID: "A1", AgentType: models.PMMAgentType, RunsOnNodeID: pointer.ToString("N1")},
+		&models.Agent{AgentID: "A2", AgentType: models.NodeExporterType, PMMAgentID: pointer.ToString("A1"), NodeID: pointer.ToString("N1")},
+	} {
+		require.NoError(t, q.Insert(str))
+	}
+
+	for _, params := range []models.CreateAgentEventParams{
+		{AgentID: "A1", Type: models.AgentConnected},
+		{AgentID: "A2", Type: models.AgentStatusChanged, OldStatus: "STARTING", NewStatus: "RUNNING"},
+		{AgentID: "A1", Type: models.AgentDisconnected, Reason: "done"},
+		{AgentID: "A3", Type: models.AgentDisconnected, Reason: "auth"},
+	} {
+		_, err = models.CreateAgentEvent(q, params)
+		require.NoError(t, err)
+	}
+
+	events, err := models.FindAgentEvents(q, models.AgentEventFilters{AgentID: "A1"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.AgentDisconnected, events[0].Type)
+	assert.Equal(t, "done", events[0].Reason)
+	assert.Equal(t, "N1", events[0].NodeID)
+	assert.Equal(t, models.AgentConnected, events[1].Type)
+
+	events, err = models.FindAgentEvents(q, models.AgentEventFilters{NodeID: "N1"})
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	events, err = models.FindAgentEvents(q, models.AgentEventFilters{AgentID: "A3"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Empty(t, events[0].NodeID)
+
+	events, err = models.FindAgentEvents(q, models.AgentEventFilters{NodeID: "N1", Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	require.NoError(t, models.CleanupOldAgentEvents(q, time.Now().Add(time.Minute)))
+	events, err = models.FindAgentEvents(q, models.AgentEventFilters{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
@@ -0,0 +1,73 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// AgentEventType represents type of Agent event.
+type AgentEventType string
+
+// Agent event types.
+const (
+	// AgentConnected is recorded when pmm-agent connects to pmm-managed.
+	AgentConnected AgentEventType = "connected"
+	// AgentDisconnected is recorded when pmm-agent disconnects or fails to authenticate; see reason.
+	AgentDisconnected AgentEventType = "disconnected"
+	// AgentStatusChanged is recorded when Agent status changes.
+	AgentStatusChanged AgentEventType = "status_changed"
+	// AgentProcessRestarted is recorded instead of AgentStatusChanged when Agent process is started again after running or waiting.
+	AgentProcessRestarted AgentEventType = "process_restarted"
+)
+
+// AgentEvent represents a single event in Agent connection and status history.
+// Events are kept even after Agent removal until they are cleaned up by retention.
+//reform:agent_events
+type AgentEvent struct {
+	ID      string `reform:"id,pk"`
+	AgentID string `reform:"agent_id"`
+	// NodeID is an ID of the Node where Agent runs (pmm-agent's runs_on_node_id).
+	NodeID    string         `reform:"node_id"`
+	Type      AgentEventType `reform:"type"`
+	Reason    string         `reform:"reason"`
+	OldStatus string         `reform:"old_status"`
+	NewStatus string         `reform:"new_status"`
+	CreatedAt time.Time      `reform:"created_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *AgentEvent) BeforeInsert() error {
+	s.CreatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *AgentEvent) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*AgentEvent)(nil)
+	_ reform.AfterFinder    = (*AgentEvent)(nil)
+)
@@ -184,6 +184,7 @@ type Agent struct {
 	RDSEnhancedMetricsDisabled bool           `reform:"rds_enhanced_metrics_disabled"`
 	PushMetrics                bool           `reform:"push_metrics"`
 	DisabledCollectors         pq.StringArray `reform:"disabled_collectors"`
+	EnabledCollectors          pq.StringArray `reform:"enabled_collectors"` // disabled by default, but enabled explicitly
 
 	MySQLOptions      *MySQLOptions      `reform:"mysql_options"`
 	MongoDBOptions    *MongoDBOptions    `reform:"mongo_db_tls_options"`
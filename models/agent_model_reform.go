@@ -60,6 +60,7 @@ func (v *agentTableType) Columns() []string {
 		"rds_enhanced_metrics_disabled",
 		"push_metrics",
 		"disabled_collectors",
+		"enabled_collectors",
 		"mysql_options",
 		"mongo_db_tls_options",
 		"postgresql_options",
@@ -120,6 +121,7 @@ var AgentTable = &agentTableType{
 			{Name: "RDSEnhancedMetricsDisabled", Type: "bool", Column: "rds_enhanced_metrics_disabled"},
 			{Name: "PushMetrics", Type: "bool", Column: "push_metrics"},
 			{Name: "DisabledCollectors", Type: "pq.StringArray", Column: "disabled_collectors"},
+			{Name: "EnabledCollectors", Type: "pq.StringArray", Column: "enabled_collectors"},
 			{Name: "MySQLOptions", Type: "*MySQLOptions", Column: "mysql_options"},
 			{Name: "MongoDBOptions", Type: "*MongoDBOptions", Column: "mongo_db_tls_options"},
 			{Name: "PostgreSQLOptions", Type: "*PostgreSQLOptions", Column: "postgresql_options"},
@@ -132,7 +134,7 @@ var AgentTable = &agentTableType{
 
 // String returns a string representation of this struct or record.
 func (s Agent) String() string {
-	res := make([]string, 36)
+	res := make([]string, 37)
 	res[0] = "AgentID: " + reform.Inspect(s.AgentID, true)
 	res[1] = "AgentType: " + reform.Inspect(s.AgentType, true)
 	res[2] = "RunsOnNodeID: " + reform.Inspect(s.RunsOnNodeID, true)
@@ -165,10 +167,11 @@ func (s Agent) String() string {
 	res[29] = "RDSEnhancedMetricsDisabled: " + reform.Inspect(s.RDSEnhancedMetricsDisabled, true)
 	res[30] = "PushMetrics: " + reform.Inspect(s.PushMetrics, true)
 	res[31] = "DisabledCollectors: " + reform.Inspect(s.DisabledCollectors, true)
-	res[32] = "MySQLOptions: " + reform.Inspect(s.MySQLOptions, true)
-	res[33] = "MongoDBOptions: " + reform.Inspect(s.MongoDBOptions, true)
-	res[34] = "PostgreSQLOptions: " + reform.Inspect(s.PostgreSQLOptions, true)
-	res[35] = "LogLevel: " + reform.Inspect(s.LogLevel, true)
+	res[32] = "EnabledCollectors: " + reform.Inspect(s.EnabledCollectors, true)
+	res[33] = "MySQLOptions: " + reform.Inspect(s.MySQLOptions, true)
+	res[34] = "MongoDBOptions: " + reform.Inspect(s.MongoDBOptions, true)
+	res[35] = "PostgreSQLOptions: " + reform.Inspect(s.PostgreSQLOptions, true)
+	res[36] = "LogLevel: " + reform.Inspect(s.LogLevel, true)
 	return strings.Join(res, ", ")
 }
 
@@ -208,6 +211,7 @@ func (s *Agent) Values() []interface{} {
 		s.RDSEnhancedMetricsDisabled,
 		s.PushMetrics,
 		s.DisabledCollectors,
+		s.EnabledCollectors,
 		s.MySQLOptions,
 		s.MongoDBOptions,
 		s.PostgreSQLOptions,
@@ -251,6 +255,7 @@ func (s *Agent) Pointers() []interface{} {
 		&s.RDSEnhancedMetricsDisabled,
 		&s.PushMetrics,
 		&s.DisabledCollectors,
+		&s.EnabledCollectors,
 		&s.MySQLOptions,
 		&s.MongoDBOptions,
 		&s.PostgreSQLOptions,
@@ -48,6 +48,8 @@ type Channel struct {
 	SlackConfig     *SlackConfig     `reform:"slack_config"`
 	WebHookConfig   *WebHookConfig   `reform:"webhook_config"`
 
+	MessageTemplates *MessageTemplates `reform:"message_templates"`
+
 	Disabled bool `reform:"disabled"`
 
 	CreatedAt time.Time `reform:"created_at"`
@@ -157,6 +159,31 @@ type TLSConfig struct {
 	KeyFileContent     string `json:"key_file_content,omitempty"`
 }
 
+// MessageTemplate is a user-defined notification message in Go template format of Alertmanager.
+type MessageTemplate struct {
+	// Title is a template of Slack message title, email subject or PagerDuty description.
+	// It is executed once per notification with access to .Status, .CommonLabels, .Alerts, etc.
+	Title string `json:"title,omitempty"`
+	// Body is a template of Slack message text, email HTML or PagerDuty details.
+	// It is executed for each alert of notification with access to .Labels, .Annotations, .StartsAt, etc.
+	Body string `json:"body,omitempty"`
+}
+
+// MessageTemplates represents user-defined notification message templates of the channel.
+// Built-in templates are used for everything that is not defined.
+type MessageTemplates struct {
+	// Default is used for alerts of all severities that don't have own template.
+	Default *MessageTemplate `json:"default,omitempty"`
+	// Severities contains templates by severity name (critical, warning, etc).
+	Severities map[string]*MessageTemplate `json:"severities,omitempty"`
+}
+
+// Value implements database/sql/driver.Valuer interface. Should be defined on the value.
+func (t MessageTemplates) Value() (driver.Value, error) { return jsonValue(t) }
+
+// Scan implements database/sql.Scanner interface. Should be defined on the pointer.
+func (t *MessageTemplates) Scan(src interface{}) error { return jsonScan(t, src) }
+
 // check interfaces.
 var (
 	_ reform.BeforeInserter = (*Channel)(nil)
@@ -0,0 +1,84 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/reform.v1"
+)
+
+// ChannelDelivery represents notifications delivered or failed to be delivered to the channel.
+type ChannelDelivery struct {
+	ChannelID string
+	Sent      int64
+	Failed    int64
+	// LastError is an error of the last failed delivery; ignored if Failed is zero.
+	LastError string
+	At        time.Time
+}
+
+// FindChannelDeliveryStatuses returns notification delivery statistics by channel ID.
+// Channels without any notifications are absent.
+func FindChannelDeliveryStatuses(q *reform.Querier) (map[string]*ChannelDeliveryStatus, error) {
+	structs, err := q.SelectAllFrom(ChannelDeliveryStatusTable, "")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := make(map[string]*ChannelDeliveryStatus, len(structs))
+	for _, s := range structs {
+		status := s.(*ChannelDeliveryStatus) //nolint:forcetypeassert
+		res[status.ChannelID] = status
+	}
+	return res, nil
+}
+
+// RecordChannelDelivery adds delivered and failed notifications to the channel statistics.
+// Notifications of removed channels are ignored.
+func RecordChannelDelivery(q *reform.Querier, d *ChannelDelivery) error {
+	if d.Sent == 0 && d.Failed == 0 {
+		return nil
+	}
+
+	var lastSuccessAt, lastFailureAt *time.Time
+	var lastError string
+	at := d.At.UTC()
+	if d.Sent > 0 {
+		lastSuccessAt = &at
+	}
+	if d.Failed > 0 {
+		lastFailureAt = &at
+		lastError = d.LastError
+	}
+
+	// single statement to avoid lost updates by concurrent notifications
+	now := Now()
+	_, err := q.Exec(`INSERT INTO ia_channel_delivery_statuses
+		(channel_id, sent, failed, last_success_at, last_failure_at, last_error, created_at, updated_at)
+		SELECT id, $2, $3, $4, $5, $6, $7, $7 FROM ia_channels WHERE id = $1
+		ON CONFLICT (channel_id) DO UPDATE SET
+			sent = ia_channel_delivery_statuses.sent + EXCLUDED.sent,
+			failed = ia_channel_delivery_statuses.failed + EXCLUDED.failed,
+			last_success_at = COALESCE(EXCLUDED.last_success_at, ia_channel_delivery_statuses.last_success_at),
+			last_failure_at = COALESCE(EXCLUDED.last_failure_at, ia_channel_delivery_statuses.last_failure_at),
+			last_error = CASE WHEN EXCLUDED.failed > 0 THEN EXCLUDED.last_error ELSE ia_channel_delivery_statuses.last_error END,
+			updated_at = EXCLUDED.updated_at`,
+		d.ChannelID, d.Sent, d.Failed, lastSuccessAt, lastFailureAt, lastError, now)
+	return errors.WithStack(err)
+}
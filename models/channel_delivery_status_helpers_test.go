@@ -0,0 +1,83 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+)
+
+func TestChannelDeliveryStatuses(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SkipFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, tx.Rollback())
+	}()
+	q := tx.Querier
+
+	channel, err := models.CreateChannel(q, &models.CreateChannelParams{
+		Summary:     "some summary",
+		EmailConfig: &models.EmailConfig{To: []string{"test@test.test"}},
+	})
+	require.NoError(t, err)
+
+	statuses, err := models.FindChannelDeliveryStatuses(q)
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+
+	t1 := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	err = models.RecordChannelDelivery(q, &models.ChannelDelivery{ChannelID: channel.ID, Sent: 2, At: t1})
+	require.NoError(t, err)
+	err = models.RecordChannelDelivery(q, &models.ChannelDelivery{ChannelID: channel.ID, Failed: 1, LastError: "boom", At: t2})
+	require.NoError(t, err)
+	err = models.RecordChannelDelivery(q, &models.ChannelDelivery{ChannelID: channel.ID, Sent: 1, LastError: "ignored", At: t2})
+	require.NoError(t, err)
+
+	err = models.RecordChannelDelivery(q, &models.ChannelDelivery{ChannelID: "removed", Failed: 1, At: t2})
+	require.NoError(t, err, "deliveries of removed channels should be ignored")
+
+	statuses, err = models.FindChannelDeliveryStatuses(q)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	actual := statuses[channel.ID]
+	assert.Equal(t, int64(3), actual.Sent)
+	assert.Equal(t, int64(1), actual.Failed)
+	assert.Equal(t, &t2, actual.LastSuccessAt)
+	assert.Equal(t, &t2, actual.LastFailureAt)
+	assert.Equal(t, "boom", actual.LastError)
+
+	err = models.RemoveChannel(q, channel.ID)
+	require.NoError(t, err)
+	statuses, err = models.FindChannelDeliveryStatuses(q)
+	require.NoError(t, err)
+	assert.Empty(t, statuses)
+}
@@ -0,0 +1,81 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// ChannelDeliveryStatus contains notification delivery statistics of the channel, including test notifications.
+//
+//reform:ia_channel_delivery_statuses
+type ChannelDeliveryStatus struct {
+	ChannelID string `reform:"channel_id,pk"`
+	// Sent is a number of successfully delivered notifications.
+	Sent int64 `reform:"sent"`
+	// Failed is a number of failed delivery attempts, including ones retried later by Alertmanager.
+	Failed int64 `reform:"failed"`
+	// LastSuccessAt is a time of the last successful delivery, if any.
+	LastSuccessAt *time.Time `reform:"last_success_at"`
+	// LastFailureAt is a time of the last failed delivery, if any.
+	LastFailureAt *time.Time `reform:"last_failure_at"`
+	// LastError is an error of the last failed delivery, if any.
+	LastError string `reform:"last_error"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *ChannelDeliveryStatus) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *ChannelDeliveryStatus) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *ChannelDeliveryStatus) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	if s.LastSuccessAt != nil {
+		t := s.LastSuccessAt.UTC()
+		s.LastSuccessAt = &t
+	}
+	if s.LastFailureAt != nil {
+		t := s.LastFailureAt.UTC()
+		s.LastFailureAt = &t
+	}
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*ChannelDeliveryStatus)(nil)
+	_ reform.BeforeUpdater  = (*ChannelDeliveryStatus)(nil)
+	_ reform.AfterFinder    = (*ChannelDeliveryStatus)(nil)
+)
@@ -355,6 +355,29 @@ func ChangeChannel(q *reform.Querier, channelID string, params *ChangeChannelPar
 	return row, nil
 }
 
+// ChangeChannelMessageTemplates sets user-defined notification message templates of the channel.
+// Nil or empty templates reset channel to built-in templates.
+func ChangeChannelMessageTemplates(q *reform.Querier, channelID string, templates *MessageTemplates) (*Channel, error) {
+	row, err := FindChannelByID(q, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if templates != nil && templates.Default == nil && len(templates.Severities) == 0 {
+		templates = nil
+	}
+	if templates != nil && row.Type == WebHook {
+		return nil, status.Error(codes.InvalidArgument, "Webhook channel doesn't support message templates.")
+	}
+
+	row.MessageTemplates = templates
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to update notifications channel")
+	}
+
+	return row, nil
+}
+
 // RemoveChannel removes notification channel with specified id.
 func RemoveChannel(q *reform.Querier, id string) error {
 	channel, err := FindChannelByID(q, id)
@@ -371,6 +394,15 @@ func RemoveChannel(q *reform.Querier, id string) error {
 		return status.Errorf(codes.FailedPrecondition, `You can't delete the "%s" channel when it's being used by a rule.`, channel.Summary)
 	}
 
+	inUse, err = channelInUseBySLO(q, id)
+	if err != nil {
+		return err
+	}
+
+	if inUse {
+		return status.Errorf(codes.FailedPrecondition, `You can't delete the "%s" channel when it's being used by an SLO.`, channel.Summary)
+	}
+
 	if err = q.Delete(&Channel{ID: id}); err != nil {
 		return errors.Wrap(err, "failed to delete notification channel")
 	}
@@ -389,3 +421,15 @@ func channelInUse(q *reform.Querier, id string) (bool, error) {
 		return false, errors.WithStack(err)
 	}
 }
+
+func channelInUseBySLO(q *reform.Querier, id string) (bool, error) {
+	_, err := q.SelectOneFrom(SLOTable, "WHERE channel_ids ? $1", id)
+	switch err {
+	case nil:
+		return true, nil
+	case reform.ErrNoRows:
+		return false, nil
+	default:
+		return false, errors.WithStack(err)
+	}
+}
@@ -154,6 +154,45 @@ func TestNotificationChannels(t *testing.T) {
 		assert.Equal(t, updated, actual)
 	})
 
+	t.Run("change message templates", func(t *testing.T) {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, tx.Rollback())
+		}()
+
+		q := tx.Querier
+
+		channel := createChannel(t, q)
+		assert.Nil(t, channel.MessageTemplates)
+
+		templates := &models.MessageTemplates{
+			Default: &models.MessageTemplate{Body: "{{ .Annotations.summary }}"},
+			Severities: map[string]*models.MessageTemplate{
+				"critical": {Title: "Wake up!", Body: "Runbook: {{ .Annotations.runbook }}"},
+			},
+		}
+		updated, err := models.ChangeChannelMessageTemplates(q, channel.ID, templates)
+		require.NoError(t, err)
+		assert.Equal(t, templates, updated.MessageTemplates)
+
+		actual, err := models.FindChannelByID(q, channel.ID)
+		require.NoError(t, err)
+		assert.Equal(t, templates, actual.MessageTemplates)
+
+		updated, err = models.ChangeChannelMessageTemplates(q, channel.ID, &models.MessageTemplates{})
+		require.NoError(t, err)
+		assert.Nil(t, updated.MessageTemplates)
+
+		webhook, err := models.CreateChannel(q, &models.CreateChannelParams{
+			Summary:       "webhook",
+			WebHookConfig: &models.WebHookConfig{URL: "http://example.com"},
+		})
+		require.NoError(t, err)
+		_, err = models.ChangeChannelMessageTemplates(q, webhook.ID, templates)
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Webhook channel doesn't support message templates."), err)
+	})
+
 	t.Run("remove", func(t *testing.T) {
 		tx, err := db.Begin()
 		require.NoError(t, err)
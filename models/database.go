@@ -715,6 +715,299 @@ var databaseSchema = [][]string{
 		`ALTER TABLE agents
 			ADD COLUMN log_level VARCHAR`,
 	},
+	64: {
+		`ALTER TABLE ia_channels
+			ADD COLUMN message_templates JSONB`,
+	},
+	65: {
+		`CREATE TABLE ia_slos (
+			id VARCHAR NOT NULL,
+			name VARCHAR NOT NULL CHECK (name <> ''),
+			summary VARCHAR NOT NULL,
+
+			service_id VARCHAR NOT NULL,
+			filters JSONB,
+
+			sli_type VARCHAR NOT NULL CHECK (sli_type <> ''),
+			expr VARCHAR NOT NULL,
+			threshold DOUBLE PRECISION NOT NULL,
+			percentile DOUBLE PRECISION NOT NULL,
+
+			target DOUBLE PRECISION NOT NULL,
+			"window" BIGINT NOT NULL,
+
+			disabled BOOLEAN NOT NULL,
+			channel_ids JSONB,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (name)
+		)`,
+	},
+	66: {
+		`CREATE TABLE ha_instances (
+			id VARCHAR NOT NULL CHECK (id <> ''),
+			address VARCHAR NOT NULL CHECK (address <> ''),
+			token VARCHAR NOT NULL CHECK (token <> ''),
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id)
+		)`,
+
+		`CREATE TABLE ha_pmm_agent_owners (
+			pmm_agent_id VARCHAR NOT NULL,
+			instance_id VARCHAR NOT NULL,
+			connected_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (pmm_agent_id),
+			FOREIGN KEY (pmm_agent_id) REFERENCES agents (agent_id) ON DELETE CASCADE,
+			FOREIGN KEY (instance_id) REFERENCES ha_instances (id) ON DELETE CASCADE
+		)`,
+
+		`CREATE TABLE ha_leases (
+			name VARCHAR NOT NULL,
+			holder VARCHAR NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (name)
+		)`,
+	},
+	67: {
+		`CREATE TABLE agent_events (
+			id VARCHAR NOT NULL,
+			agent_id VARCHAR NOT NULL CHECK (agent_id <> ''),
+			node_id VARCHAR NOT NULL,
+			type VARCHAR NOT NULL CHECK (type <> ''),
+			reason VARCHAR NOT NULL,
+			old_status VARCHAR NOT NULL,
+			new_status VARCHAR NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id)
+		)`,
+		`CREATE INDEX agent_events_agent_id_created_at_idx ON agent_events (agent_id, created_at)`,
+		`CREATE INDEX agent_events_node_id_created_at_idx ON agent_events (node_id, created_at)`,
+		`CREATE INDEX agent_events_created_at_idx ON agent_events (created_at)`,
+	},
+	68: {
+		`CREATE TABLE topology_edges (
+			id VARCHAR NOT NULL,
+			source_service_id VARCHAR NOT NULL CHECK (source_service_id <> ''),
+			target_service_id VARCHAR NOT NULL CHECK (target_service_id <> ''),
+			type VARCHAR NOT NULL CHECK (type <> ''),
+			auto BOOLEAN NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			FOREIGN KEY (source_service_id) REFERENCES services (service_id) ON DELETE CASCADE,
+			FOREIGN KEY (target_service_id) REFERENCES services (service_id) ON DELETE CASCADE,
+			UNIQUE (source_service_id, target_service_id, type),
+			CHECK (source_service_id <> target_service_id)
+		)`,
+		`CREATE INDEX topology_edges_target_service_id_idx ON topology_edges (target_service_id)`,
+	},
+	69: {
+		`CREATE TABLE qan_redaction_policies (
+			id VARCHAR NOT NULL,
+			name VARCHAR NOT NULL CHECK (name <> ''),
+			filters JSONB,
+			rules JSONB NOT NULL,
+			disabled BOOLEAN NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (name)
+		)`,
+	},
+	70: {
+		`CREATE TABLE metrics_resolution_overrides (
+			id VARCHAR NOT NULL,
+			name VARCHAR NOT NULL CHECK (name <> ''),
+
+			agent_id VARCHAR,
+			labels JSONB,
+
+			hr BIGINT NOT NULL,
+			mr BIGINT NOT NULL,
+			lr BIGINT NOT NULL,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (name),
+			FOREIGN KEY (agent_id) REFERENCES agents (agent_id) ON DELETE CASCADE
+		)`,
+	},
+	71: {
+		`CREATE TABLE metric_relabel_rules (
+			id VARCHAR NOT NULL,
+			name VARCHAR NOT NULL CHECK (name <> ''),
+
+			agent_types VARCHAR[],
+			labels JSONB,
+
+			configs JSONB NOT NULL,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (name)
+		)`,
+	},
+	72: {
+		`CREATE TABLE service_discovery_sources (
+			id VARCHAR NOT NULL,
+			name VARCHAR NOT NULL CHECK (name <> ''),
+			type VARCHAR NOT NULL CHECK (type <> ''),
+
+			file_sd_options JSONB,
+			consul_options JSONB,
+			kubernetes_options JSONB,
+
+			environment_label VARCHAR NOT NULL,
+			cluster_label VARCHAR NOT NULL,
+			external_group VARCHAR NOT NULL,
+			disabled BOOLEAN NOT NULL,
+
+			last_sync_at TIMESTAMP,
+			last_error VARCHAR NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (name)
+		)`,
+		`CREATE TABLE discovered_targets (
+			id VARCHAR NOT NULL,
+			source_id VARCHAR NOT NULL,
+			target_key VARCHAR NOT NULL CHECK (target_key <> ''),
+			node_id VARCHAR NOT NULL,
+			service_id VARCHAR NOT NULL,
+			agent_id VARCHAR NOT NULL,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (source_id, target_key),
+			FOREIGN KEY (source_id) REFERENCES service_discovery_sources (id) ON DELETE CASCADE,
+			FOREIGN KEY (node_id) REFERENCES nodes (node_id) ON DELETE CASCADE,
+			FOREIGN KEY (service_id) REFERENCES services (service_id) ON DELETE CASCADE,
+			FOREIGN KEY (agent_id) REFERENCES agents (agent_id) ON DELETE CASCADE
+		)`,
+	},
+	73: {
+		`ALTER TABLE agents ADD COLUMN enabled_collectors VARCHAR[]`,
+	},
+	74: {
+		`CREATE TABLE monitored_clusters (
+			id VARCHAR NOT NULL,
+			name VARCHAR NOT NULL CHECK (name <> ''),
+			type VARCHAR NOT NULL CHECK (type <> ''),
+
+			pmm_agent_id VARCHAR NOT NULL,
+			seed_address VARCHAR NOT NULL,
+			seed_port INTEGER NOT NULL,
+
+			username VARCHAR NOT NULL,
+			password VARCHAR NOT NULL,
+			tls BOOLEAN NOT NULL,
+			tls_skip_verify BOOLEAN NOT NULL,
+			mysql_options JSONB,
+
+			environment VARCHAR NOT NULL,
+			custom_labels JSONB,
+			push_metrics BOOLEAN NOT NULL,
+			qan BOOLEAN NOT NULL,
+			query_examples_disabled BOOLEAN NOT NULL,
+			table_count_tablestats_group_limit INTEGER NOT NULL,
+
+			last_sync_at TIMESTAMP,
+			last_error VARCHAR NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (name),
+			FOREIGN KEY (pmm_agent_id) REFERENCES agents (agent_id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE monitored_cluster_members (
+			id VARCHAR NOT NULL,
+			cluster_id VARCHAR NOT NULL,
+			address VARCHAR NOT NULL CHECK (address <> ''),
+			service_id VARCHAR NOT NULL,
+			node_id VARCHAR,
+			adopted BOOLEAN NOT NULL,
+
+			replication_set VARCHAR NOT NULL,
+			role VARCHAR NOT NULL,
+			state VARCHAR NOT NULL,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id),
+			UNIQUE (cluster_id, address),
+			FOREIGN KEY (cluster_id) REFERENCES monitored_clusters (id) ON DELETE CASCADE,
+			FOREIGN KEY (service_id) REFERENCES services (service_id) ON DELETE CASCADE
+		)`,
+	},
+	75: {
+		`ALTER TABLE monitored_clusters ADD COLUMN mongo_db_options JSONB`,
+	},
+	76: {
+		`ALTER TABLE monitored_clusters
+			ADD COLUMN postgresql_options JSONB,
+			ADD COLUMN patroni_url VARCHAR NOT NULL DEFAULT ''`,
+		`ALTER TABLE monitored_clusters ALTER COLUMN patroni_url DROP DEFAULT`,
+	},
+	77: {
+		`CREATE TABLE ia_channel_delivery_statuses (
+			channel_id VARCHAR NOT NULL,
+			sent BIGINT NOT NULL,
+			failed BIGINT NOT NULL,
+			last_success_at TIMESTAMP,
+			last_failure_at TIMESTAMP,
+			last_error VARCHAR NOT NULL,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (channel_id),
+			FOREIGN KEY (channel_id) REFERENCES ia_channels (id) ON DELETE CASCADE
+		)`,
+	},
+	78: {
+		`CREATE TABLE upgrade_campaigns (
+			id VARCHAR NOT NULL,
+			target_version VARCHAR NOT NULL CHECK (target_version <> ''),
+
+			wave_size INTEGER NOT NULL,
+			max_unavailable INTEGER NOT NULL,
+			max_failures INTEGER NOT NULL,
+			timeout BIGINT NOT NULL,
+
+			status VARCHAR NOT NULL CHECK (status <> ''),
+			pause_reason VARCHAR NOT NULL,
+			agents JSONB,
+
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL,
+
+			PRIMARY KEY (id)
+		)`,
+	},
+	79: {
+		`ALTER TABLE monitored_cluster_members ADD COLUMN missing_since TIMESTAMP`,
+	},
 }
 
 // ^^^ Avoid default values in schema definition. ^^^
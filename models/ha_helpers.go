@@ -0,0 +1,159 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// HAInstanceTimeout is a period after the last heartbeat when pmm-managed instance is considered dead.
+const HAInstanceTimeout = 30 * time.Second
+
+// RegisterHAInstance creates or updates pmm-managed instance with given ID.
+func RegisterHAInstance(q *reform.Querier, id, address, token string) (*HAInstance, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty instance ID.")
+	}
+
+	instance := &HAInstance{ID: id}
+	err := q.Reload(instance)
+	switch {
+	case err == nil:
+		instance.Address = address
+		instance.Token = token
+		if err = q.Update(instance); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case errors.Is(err, reform.ErrNoRows):
+		instance.Address = address
+		instance.Token = token
+		if err = q.Insert(instance); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	default:
+		return nil, errors.WithStack(err)
+	}
+
+	return instance, nil
+}
+
+// TouchHAInstance updates heartbeat time of pmm-managed instance with given ID.
+func TouchHAInstance(q *reform.Querier, id string) error {
+	instance := &HAInstance{ID: id}
+	if err := q.Reload(instance); err != nil {
+		if errors.Is(err, reform.ErrNoRows) {
+			return status.Errorf(codes.NotFound, "Instance with ID %q not found.", id)
+		}
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(q.UpdateColumns(instance, "updated_at"))
+}
+
+// RemoveHAInstance removes pmm-managed instance with given ID and its pmm-agents ownership.
+func RemoveHAInstance(q *reform.Querier, id string) error {
+	err := q.Delete(&HAInstance{ID: id})
+	if err != nil && !errors.Is(err, reform.ErrNoRows) {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// FindLiveHAInstances returns pmm-managed instances with recent heartbeats.
+func FindLiveHAInstances(q *reform.Querier) ([]*HAInstance, error) {
+	structs, err := q.SelectAllFrom(HAInstanceTable, "WHERE updated_at > $1 ORDER BY id", Now().Add(-HAInstanceTimeout))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := make([]*HAInstance, len(structs))
+	for i, s := range structs {
+		res[i] = s.(*HAInstance)
+	}
+	return res, nil
+}
+
+// SetPMMAgentOwner records that pmm-agent with given ID is connected to given pmm-managed instance.
+func SetPMMAgentOwner(q *reform.Querier, pmmAgentID, instanceID string) error {
+	_, err := q.Exec(`INSERT INTO ha_pmm_agent_owners (pmm_agent_id, instance_id, connected_at) VALUES ($1, $2, $3)
+		ON CONFLICT (pmm_agent_id) DO UPDATE SET instance_id = EXCLUDED.instance_id, connected_at = EXCLUDED.connected_at`,
+		pmmAgentID, instanceID, Now())
+	return errors.WithStack(err)
+}
+
+// RemovePMMAgentOwner removes pmm-agent's ownership if it is still held by given pmm-managed instance.
+func RemovePMMAgentOwner(q *reform.Querier, pmmAgentID, instanceID string) error {
+	_, err := q.DeleteFrom(HAPMMAgentOwnerTable, "WHERE pmm_agent_id = $1 AND instance_id = $2", pmmAgentID, instanceID)
+	return errors.WithStack(err)
+}
+
+// FindPMMAgentOwner returns live pmm-managed instance that holds connection of pmm-agent with given ID.
+func FindPMMAgentOwner(q *reform.Querier, pmmAgentID string) (*HAInstance, error) {
+	owner := &HAPMMAgentOwner{PMMAgentID: pmmAgentID}
+	if err := q.Reload(owner); err != nil {
+		if errors.Is(err, reform.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "pmm-agent with ID %q is not connected to any instance.", pmmAgentID)
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	instance := &HAInstance{ID: owner.InstanceID}
+	if err := q.Reload(instance); err != nil {
+		if errors.Is(err, reform.ErrNoRows) {
+			return nil, status.Errorf(codes.NotFound, "Instance with ID %q not found.", owner.InstanceID)
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	if instance.UpdatedAt.Before(Now().Add(-HAInstanceTimeout)) {
+		return nil, status.Errorf(codes.NotFound, "Instance with ID %q is not alive.", instance.ID)
+	}
+
+	return instance, nil
+}
+
+// AcquireHALease acquires or renews lease with given name for given holder.
+// It returns true if the lease is held by that holder until now+ttl, false if it is held by someone else.
+// Both expiration time and its check use database time, so clocks of pmm-managed instances may differ.
+func AcquireHALease(q *reform.Querier, name, holder string, ttl time.Duration) (bool, error) {
+	res, err := q.Exec(`INSERT INTO ha_leases (name, holder, expires_at)
+		VALUES ($1, $2, (now() AT TIME ZONE 'utc') + $3::interval)
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at
+		WHERE ha_leases.holder = EXCLUDED.holder OR ha_leases.expires_at < (now() AT TIME ZONE 'utc')`,
+		name, holder, fmt.Sprintf("%d microseconds", ttl.Microseconds()))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return n == 1, nil
+}
+
+// ReleaseHALease releases lease with given name if it is held by given holder.
+func ReleaseHALease(q *reform.Querier, name, holder string) error {
+	_, err := q.Exec(`DELETE FROM ha_leases WHERE name = $1 AND holder = $2`, name, holder)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,124 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestHA(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SkipFixtures, nil)
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+
+	setup := func(t *testing.T) (*reform.Querier, func()) {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		return tx.Querier, func() {
+			require.NoError(t, tx.Rollback())
+		}
+	}
+
+	t.Run("agent owner", func(t *testing.T) {
+		q, teardown := setup(t)
+		defer teardown()
+
+		for _, str := range []reform.Struct{
+			&models.Node{NodeID: "N1", NodeType: models.GenericNodeType, NodeName: "Node 1"},
+			&models.Agent{AgentID: "A1", AgentType: models.PMMAgentType, RunsOnNodeID: pointer.ToString("N1")},
+		} {
+			require.NoError(t, q.Insert(str))
+		}
+
+		_, err := models.RegisterHAInstance(q, "i1", "10.0.0.1:7774", "token1")
+		require.NoError(t, err)
+		_, err = models.RegisterHAInstance(q, "i2", "10.0.0.2:7774", "token2")
+		require.NoError(t, err)
+
+		_, err = models.FindPMMAgentOwner(q, "A1")
+		tests.AssertGRPCError(t, status.New(codes.NotFound, `pmm-agent with ID "A1" is not connected to any instance.`), err)
+
+		require.NoError(t, models.SetPMMAgentOwner(q, "A1", "i1"))
+		require.NoError(t, models.SetPMMAgentOwner(q, "A1", "i2"))
+		owner, err := models.FindPMMAgentOwner(q, "A1")
+		require.NoError(t, err)
+		assert.Equal(t, "i2", owner.ID)
+		assert.Equal(t, "token2", owner.Token)
+
+		// only the current owner removes ownership
+		require.NoError(t, models.RemovePMMAgentOwner(q, "A1", "i1"))
+		_, err = models.FindPMMAgentOwner(q, "A1")
+		require.NoError(t, err)
+
+		// ownership is removed with instance
+		require.NoError(t, models.RemoveHAInstance(q, "i2"))
+		_, err = models.FindPMMAgentOwner(q, "A1")
+		tests.AssertGRPCError(t, status.New(codes.NotFound, `pmm-agent with ID "A1" is not connected to any instance.`), err)
+
+		instances, err := models.FindLiveHAInstances(q)
+		require.NoError(t, err)
+		require.Len(t, instances, 1)
+		assert.Equal(t, "i1", instances[0].ID)
+	})
+
+	t.Run("lease", func(t *testing.T) {
+		q, teardown := setup(t)
+		defer teardown()
+
+		ok, err := models.AcquireHALease(q, "test", "i1", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = models.AcquireHALease(q, "test", "i2", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok, "held by another holder")
+
+		ok, err = models.AcquireHALease(q, "test", "i1", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok, "renewed")
+
+		require.NoError(t, models.ReleaseHALease(q, "test", "i2"))
+		ok, err = models.AcquireHALease(q, "test", "i2", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, ok, "not released by another holder")
+
+		require.NoError(t, models.ReleaseHALease(q, "test", "i1"))
+		ok, err = models.AcquireHALease(q, "test", "i2", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok, "released")
+
+		ok, err = models.AcquireHALease(q, "expired", "i1", -time.Second)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = models.AcquireHALease(q, "expired", "i2", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, ok, "expired lease")
+	})
+}
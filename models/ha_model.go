@@ -0,0 +1,82 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// HAInstance represents pmm-managed instance that shares the database with other instances.
+//reform:ha_instances
+type HAInstance struct {
+	ID string `reform:"id,pk"`
+	// Address is used by other instances to forward requests to pmm-agents connected to this instance.
+	Address string `reform:"address"`
+	// Token authenticates requests forwarded by other instances.
+	Token string `reform:"token"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	// UpdatedAt is updated periodically while instance is alive.
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *HAInstance) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *HAInstance) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *HAInstance) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	return nil
+}
+
+// HAPMMAgentOwner represents pmm-managed instance that holds pmm-agent's connection.
+//reform:ha_pmm_agent_owners
+type HAPMMAgentOwner struct {
+	PMMAgentID  string    `reform:"pmm_agent_id,pk"`
+	InstanceID  string    `reform:"instance_id"`
+	ConnectedAt time.Time `reform:"connected_at"`
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *HAPMMAgentOwner) AfterFind() error {
+	s.ConnectedAt = s.ConnectedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*HAInstance)(nil)
+	_ reform.BeforeUpdater  = (*HAInstance)(nil)
+	_ reform.AfterFinder    = (*HAInstance)(nil)
+	_ reform.AfterFinder    = (*HAPMMAgentOwner)(nil)
+)
@@ -0,0 +1,244 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// FindMetricRelabelRules returns all metric relabel rules.
+func FindMetricRelabelRules(q *reform.Querier) ([]*MetricRelabelRule, error) {
+	rows, err := q.SelectAllFrom(MetricRelabelRuleTable, "ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select metric relabel rules")
+	}
+
+	res := make([]*MetricRelabelRule, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*MetricRelabelRule)
+	}
+	return res, nil
+}
+
+// FindMetricRelabelRuleByID finds metric relabel rule by ID.
+func FindMetricRelabelRuleByID(q *reform.Querier, id string) (*MetricRelabelRule, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty metric relabel rule ID.")
+	}
+
+	r := &MetricRelabelRule{ID: id}
+	switch err := q.Reload(r); err {
+	case nil:
+		return r, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "Metric relabel rule with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+func checkUniqueMetricRelabelRuleName(q *reform.Querier, id, name string) error {
+	_, err := q.SelectOneFrom(MetricRelabelRuleTable, "WHERE name = $1 AND id <> $2", name, id)
+	switch err {
+	case nil:
+		return status.Errorf(codes.AlreadyExists, "Metric relabel rule with name %q already exists.", name)
+	case reform.ErrNoRows:
+		return nil
+	default:
+		return errors.WithStack(err)
+	}
+}
+
+// MetricRelabelRuleParams are params for creating and changing metric relabel rule.
+type MetricRelabelRuleParams struct {
+	Name       string
+	AgentTypes []AgentType
+	Labels     map[string]string
+	Configs    []RelabelConfig
+}
+
+// Validate checks metric relabel rule params without accessing the database.
+func (params *MetricRelabelRuleParams) Validate() error {
+	if params.Name == "" {
+		return status.Error(codes.InvalidArgument, "Empty metric relabel rule name.")
+	}
+
+	if len(params.AgentTypes) == 0 && len(params.Labels) == 0 {
+		return status.Error(codes.InvalidArgument, "Either agent types or labels are expected.")
+	}
+	for _, t := range params.AgentTypes {
+		switch t {
+		case NodeExporterType, MySQLdExporterType, MongoDBExporterType, PostgresExporterType, ProxySQLExporterType,
+			RDSExporterType, AzureDatabaseExporterType, ExternalExporterType, VMAgentType:
+		default:
+			return status.Errorf(codes.InvalidArgument, "Unsupported agent type %q.", t)
+		}
+	}
+
+	if len(params.Configs) == 0 {
+		return status.Error(codes.InvalidArgument, "At least one relabel config is expected.")
+	}
+	for i, c := range params.Configs {
+		if err := c.Validate(); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Relabel config #%d: %s.", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that relabel config has a supported action and all fields required by it.
+func (c *RelabelConfig) Validate() error {
+	switch c.Action {
+	case RelabelActionDrop, RelabelActionKeep:
+		if len(c.SourceLabels) == 0 {
+			return errors.Errorf("source_labels are required for %s action", c.Action)
+		}
+	case RelabelActionLabelMap:
+		if c.Regex == "" {
+			return errors.Errorf("regex is required for %s action", c.Action)
+		}
+	case RelabelActionHashMod:
+		if len(c.SourceLabels) == 0 || c.TargetLabel == "" || c.Modulus == 0 {
+			return errors.Errorf("source_labels, target_label and non-zero modulus are required for %s action", c.Action)
+		}
+	case "":
+		return errors.New("empty action")
+	default:
+		return errors.Errorf("unsupported action %q", c.Action)
+	}
+
+	for _, l := range c.SourceLabels {
+		if !labelNameRE.MatchString(l) {
+			return errors.Errorf("invalid source label %q", l)
+		}
+	}
+	if c.TargetLabel != "" && !labelNameRE.MatchString(c.TargetLabel) {
+		return errors.Errorf("invalid target label %q", c.TargetLabel)
+	}
+	if c.Regex != "" {
+		// relabel regexes are fully anchored
+		if _, err := regexp.Compile("^(?:" + c.Regex + ")$"); err != nil {
+			return errors.Errorf("invalid regex %q", c.Regex)
+		}
+	}
+	return nil
+}
+
+// apply validates params and sets them to the given rule.
+func (params *MetricRelabelRuleParams) apply(q *reform.Querier, r *MetricRelabelRule) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if err := checkUniqueMetricRelabelRuleName(q, r.ID, params.Name); err != nil {
+		return err
+	}
+
+	r.Name = params.Name
+	r.AgentTypes = nil
+	for _, t := range params.AgentTypes {
+		r.AgentTypes = append(r.AgentTypes, string(t))
+	}
+	r.Labels = params.Labels
+	r.Configs = params.Configs
+	return nil
+}
+
+// CreateMetricRelabelRule persists metric relabel rule.
+func CreateMetricRelabelRule(q *reform.Querier, params *MetricRelabelRuleParams) (*MetricRelabelRule, error) {
+	row := &MetricRelabelRule{ID: "/metric_relabel_rule_id/" + uuid.New().String()}
+	if err := params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create metric relabel rule")
+	}
+	return row, nil
+}
+
+// ChangeMetricRelabelRule replaces all fields of existing metric relabel rule.
+func ChangeMetricRelabelRule(q *reform.Querier, id string, params *MetricRelabelRuleParams) (*MetricRelabelRule, error) {
+	row, err := FindMetricRelabelRuleByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to change metric relabel rule")
+	}
+	return row, nil
+}
+
+// RemoveMetricRelabelRule removes metric relabel rule with specified id.
+func RemoveMetricRelabelRule(q *reform.Querier, id string) error {
+	if _, err := FindMetricRelabelRuleByID(q, id); err != nil {
+		return err
+	}
+
+	if err := q.Delete(&MetricRelabelRule{ID: id}); err != nil {
+		return errors.Wrap(err, "failed to delete metric relabel rule")
+	}
+	return nil
+}
+
+// Matches returns true if rule should be applied to Agent with given type and labels (see MergeLabels).
+func (r *MetricRelabelRule) Matches(agentType AgentType, labels map[string]string) bool {
+	if len(r.AgentTypes) == 0 && len(r.Labels) == 0 {
+		return false
+	}
+
+	if len(r.AgentTypes) != 0 {
+		var found bool
+		for _, t := range r.AgentTypes {
+			if AgentType(t) == agentType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for name, value := range r.Labels {
+		if v, ok := labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchingMetricRelabelRules returns rules that should be applied to Agent with given type and labels, in the given order.
+func MatchingMetricRelabelRules(rules []*MetricRelabelRule, agentType AgentType, labels map[string]string) []*MetricRelabelRule {
+	var res []*MetricRelabelRule
+	for _, r := range rules {
+		if r.Matches(agentType, labels) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
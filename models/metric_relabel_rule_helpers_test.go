@@ -0,0 +1,144 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestMetricRelabelRules(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Matches", func(t *testing.T) {
+		t.Parallel()
+
+		rules := []*models.MetricRelabelRule{{
+			Name:       "mysql-perf-schema",
+			AgentTypes: []string{string(models.MySQLdExporterType)},
+			Labels:     models.MatchLabels{"environment": "prod"},
+		}, {
+			Name:       "node",
+			AgentTypes: []string{string(models.NodeExporterType), string(models.VMAgentType)},
+		}, {
+			Name:   "dev",
+			Labels: models.MatchLabels{"environment": "dev"},
+		}, {
+			Name: "empty",
+		}}
+
+		names := func(rules []*models.MetricRelabelRule) []string {
+			var res []string
+			for _, r := range rules {
+				res = append(res, r.Name)
+			}
+			return res
+		}
+
+		prod := map[string]string{"environment": "prod", "node_name": "db1"}
+		dev := map[string]string{"environment": "dev"}
+		assert.Equal(t, []string{"mysql-perf-schema"}, names(models.MatchingMetricRelabelRules(rules, models.MySQLdExporterType, prod)))
+		assert.Equal(t, []string{"dev"}, names(models.MatchingMetricRelabelRules(rules, models.MySQLdExporterType, dev)))
+		assert.Equal(t, []string{"node", "dev"}, names(models.MatchingMetricRelabelRules(rules, models.NodeExporterType, dev)))
+		assert.Empty(t, models.MatchingMetricRelabelRules(rules, models.PostgresExporterType, nil))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		t.Parallel()
+
+		valid := models.MetricRelabelRuleParams{
+			Name:       "mysql-perf-schema",
+			AgentTypes: []models.AgentType{models.MySQLdExporterType},
+			Configs: []models.RelabelConfig{
+				{SourceLabels: []string{"__name__"}, Regex: "mysql_perf_schema_.+", Action: models.RelabelActionDrop},
+				{Regex: "__meta_(.+)", Action: models.RelabelActionLabelMap},
+				{SourceLabels: []string{"instance"}, TargetLabel: "shard", Modulus: 4, Action: models.RelabelActionHashMod},
+				{SourceLabels: []string{"shard"}, Regex: "0", Action: models.RelabelActionKeep},
+			},
+		}
+		require.NoError(t, valid.Validate())
+
+		for _, tc := range []struct {
+			name     string
+			modify   func(p *models.MetricRelabelRuleParams)
+			expected string
+		}{{
+			name:     "NoScope",
+			modify:   func(p *models.MetricRelabelRuleParams) { p.AgentTypes = nil },
+			expected: "Either agent types or labels are expected.",
+		}, {
+			name: "QANAgentType",
+			modify: func(p *models.MetricRelabelRuleParams) {
+				p.AgentTypes = []models.AgentType{models.QANMySQLSlowlogAgentType}
+			},
+			expected: `Unsupported agent type "qan-mysql-slowlog-agent".`,
+		}, {
+			name:     "NoConfigs",
+			modify:   func(p *models.MetricRelabelRuleParams) { p.Configs = nil },
+			expected: "At least one relabel config is expected.",
+		}, {
+			name: "UnsupportedAction",
+			modify: func(p *models.MetricRelabelRuleParams) {
+				p.Configs = []models.RelabelConfig{{SourceLabels: []string{"job"}, TargetLabel: "foo", Action: "replace"}}
+			},
+			expected: `Relabel config #1: unsupported action "replace".`,
+		}, {
+			name: "DropWithoutSourceLabels",
+			modify: func(p *models.MetricRelabelRuleParams) {
+				p.Configs = []models.RelabelConfig{{Regex: "mysql_.+", Action: models.RelabelActionDrop}}
+			},
+			expected: "Relabel config #1: source_labels are required for drop action.",
+		}, {
+			name: "HashModWithoutModulus",
+			modify: func(p *models.MetricRelabelRuleParams) {
+				p.Configs[2].Modulus = 0
+			},
+			expected: "Relabel config #3: source_labels, target_label and non-zero modulus are required for hashmod action.",
+		}, {
+			name: "InvalidRegex",
+			modify: func(p *models.MetricRelabelRuleParams) {
+				p.Configs[0].Regex = "mysql_perf_schema_(.+"
+			},
+			expected: `Relabel config #1: invalid regex "mysql_perf_schema_(.+".`,
+		}, {
+			name: "InvalidLabel",
+			modify: func(p *models.MetricRelabelRuleParams) {
+				p.Configs[0].SourceLabels = []string{"1abel"}
+			},
+			expected: `Relabel config #1: invalid source label "1abel".`,
+		}} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				p := valid
+				p.Configs = append([]models.RelabelConfig(nil), valid.Configs...)
+				tc.modify(&p)
+				err := p.Validate()
+				require.Error(t, err)
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				assert.Equal(t, tc.expected, status.Convert(err).Message())
+			})
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"github.com/lib/pq"
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// Metric relabel actions supported by metric relabel rules.
+const (
+	RelabelActionDrop     = "drop"
+	RelabelActionKeep     = "keep"
+	RelabelActionLabelMap = "labelmap"
+	RelabelActionHashMod  = "hashmod"
+)
+
+// RelabelConfig represents a single Prometheus relabel config applied to scraped metrics.
+type RelabelConfig struct {
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	Modulus      uint64   `json:"modulus,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+	Action       string   `json:"action"`
+}
+
+// RelabelConfigs represents relabel configs applied in order.
+type RelabelConfigs []RelabelConfig
+
+// Value implements database/sql/driver Valuer interface.
+func (c RelabelConfigs) Value() (driver.Value, error) { return jsonValue(c) }
+
+// Scan implements database/sql Scanner interface.
+func (c *RelabelConfigs) Scan(src interface{}) error { return jsonScan(c, src) }
+
+// MetricRelabelRule represents metric relabel configs added to scrape jobs of Agents
+// with given types and/or matching labels.
+//
+//reform:metric_relabel_rules
+type MetricRelabelRule struct {
+	ID   string `reform:"id,pk"`
+	Name string `reform:"name"`
+
+	// At least one of AgentTypes or Labels is set; both should match if both are set.
+	// Labels are matched against Node, Service and Agent labels.
+	AgentTypes pq.StringArray `reform:"agent_types"`
+	Labels     MatchLabels    `reform:"labels"`
+
+	Configs RelabelConfigs `reform:"configs"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (r *MetricRelabelRule) BeforeInsert() error {
+	now := Now()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (r *MetricRelabelRule) BeforeUpdate() error {
+	r.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (r *MetricRelabelRule) AfterFind() error {
+	r.CreatedAt = r.CreatedAt.UTC()
+	r.UpdatedAt = r.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*MetricRelabelRule)(nil)
+	_ reform.BeforeUpdater  = (*MetricRelabelRule)(nil)
+	_ reform.AfterFinder    = (*MetricRelabelRule)(nil)
+)
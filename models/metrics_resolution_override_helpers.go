@@ -0,0 +1,238 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/utils/validators"
+)
+
+// FindMetricsResolutionOverrides returns all metrics resolution overrides.
+func FindMetricsResolutionOverrides(q *reform.Querier) ([]*MetricsResolutionOverride, error) {
+	rows, err := q.SelectAllFrom(MetricsResolutionOverrideTable, "ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select metrics resolution overrides")
+	}
+
+	res := make([]*MetricsResolutionOverride, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*MetricsResolutionOverride)
+	}
+	return res, nil
+}
+
+// FindMetricsResolutionOverrideByID finds metrics resolution override by ID.
+func FindMetricsResolutionOverrideByID(q *reform.Querier, id string) (*MetricsResolutionOverride, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty metrics resolution override ID.")
+	}
+
+	o := &MetricsResolutionOverride{ID: id}
+	switch err := q.Reload(o); err {
+	case nil:
+		return o, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "Metrics resolution override with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+func checkUniqueMetricsResolutionOverrideName(q *reform.Querier, id, name string) error {
+	_, err := q.SelectOneFrom(MetricsResolutionOverrideTable, "WHERE name = $1 AND id <> $2", name, id)
+	switch err {
+	case nil:
+		return status.Errorf(codes.AlreadyExists, "Metrics resolution override with name %q already exists.", name)
+	case reform.ErrNoRows:
+		return nil
+	default:
+		return errors.WithStack(err)
+	}
+}
+
+// MetricsResolutionOverrideParams are params for creating and changing metrics resolution override.
+type MetricsResolutionOverrideParams struct {
+	Name    string
+	AgentID string
+	Labels  map[string]string
+	MetricsResolutions
+}
+
+// apply validates params and sets them to the given override.
+func (params *MetricsResolutionOverrideParams) apply(q *reform.Querier, o *MetricsResolutionOverride) error {
+	if params.Name == "" {
+		return status.Error(codes.InvalidArgument, "Empty metrics resolution override name.")
+	}
+	if err := checkUniqueMetricsResolutionOverrideName(q, o.ID, params.Name); err != nil {
+		return err
+	}
+
+	switch {
+	case params.AgentID != "" && len(params.Labels) != 0:
+		return status.Error(codes.InvalidArgument, "Agent ID and labels are mutually exclusive.")
+	case params.AgentID != "":
+		agent, err := FindAgentByID(q, params.AgentID)
+		if err != nil {
+			return err
+		}
+		switch agent.AgentType {
+		case PMMAgentType, QANMySQLPerfSchemaAgentType, QANMySQLSlowlogAgentType, QANMongoDBProfilerAgentType,
+			QANPostgreSQLPgStatementsAgentType, QANPostgreSQLPgStatMonitorAgentType:
+			return status.Errorf(codes.InvalidArgument, "Agent %q of type %s has no metrics.", agent.AgentID, agent.AgentType)
+		}
+	case len(params.Labels) == 0:
+		return status.Error(codes.InvalidArgument, "Either Agent ID or labels are expected.")
+	}
+
+	if params.HR == 0 && params.MR == 0 && params.LR == 0 {
+		return status.Error(codes.InvalidArgument, "At least one resolution is expected.")
+	}
+	for _, v := range []struct {
+		dur       time.Duration
+		fieldName string
+	}{
+		{params.HR, "hr"},
+		{params.MR, "mr"},
+		{params.LR, "lr"},
+	} {
+		if v.dur == 0 {
+			continue
+		}
+		if _, err := validators.ValidateMetricResolution(v.dur); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s: should be a natural number of seconds", v.fieldName)
+		}
+	}
+
+	o.Name = params.Name
+	o.AgentID = nil
+	if params.AgentID != "" {
+		o.AgentID = pointer.ToString(params.AgentID)
+	}
+	o.Labels = params.Labels
+	o.HR = params.HR
+	o.MR = params.MR
+	o.LR = params.LR
+	return nil
+}
+
+// CreateMetricsResolutionOverride persists metrics resolution override.
+func CreateMetricsResolutionOverride(q *reform.Querier, params *MetricsResolutionOverrideParams) (*MetricsResolutionOverride, error) {
+	row := &MetricsResolutionOverride{ID: "/metrics_resolution_override_id/" + uuid.New().String()}
+	if err := params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create metrics resolution override")
+	}
+	return row, nil
+}
+
+// ChangeMetricsResolutionOverride replaces all fields of existing metrics resolution override.
+func ChangeMetricsResolutionOverride(q *reform.Querier, id string, params *MetricsResolutionOverrideParams) (*MetricsResolutionOverride, error) {
+	row, err := FindMetricsResolutionOverrideByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to change metrics resolution override")
+	}
+	return row, nil
+}
+
+// RemoveMetricsResolutionOverride removes metrics resolution override with specified id.
+func RemoveMetricsResolutionOverride(q *reform.Querier, id string) error {
+	if _, err := FindMetricsResolutionOverrideByID(q, id); err != nil {
+		return err
+	}
+
+	if err := q.Delete(&MetricsResolutionOverride{ID: id}); err != nil {
+		return errors.Wrap(err, "failed to delete metrics resolution override")
+	}
+	return nil
+}
+
+// Matches returns true if override should be applied to Agent with given ID and labels.
+func (o *MetricsResolutionOverride) Matches(agentID string, labels map[string]string) bool {
+	if o.AgentID != nil {
+		return *o.AgentID == agentID
+	}
+
+	if len(o.Labels) == 0 {
+		return false
+	}
+	for name, value := range o.Labels {
+		if v, ok := labels[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ResolveMetricsResolutions returns metrics resolutions for Agent with given ID and labels (see MergeLabels).
+// Matching overrides are applied over global resolutions s from the least specific to the most specific:
+// label overrides with fewer labels first, then the Agent's own override.
+func ResolveMetricsResolutions(overrides []*MetricsResolutionOverride, agentID string, labels map[string]string, s MetricsResolutions) MetricsResolutions {
+	var matched []*MetricsResolutionOverride
+	for _, o := range overrides {
+		if o.Matches(agentID, labels) {
+			matched = append(matched, o)
+		}
+	}
+
+	specificity := func(o *MetricsResolutionOverride) int {
+		if o.AgentID != nil {
+			return math.MaxInt32
+		}
+		return len(o.Labels)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		si, sj := specificity(matched[i]), specificity(matched[j])
+		if si != sj {
+			return si < sj
+		}
+		return matched[i].Name < matched[j].Name
+	})
+
+	for _, o := range matched {
+		if o.HR != 0 {
+			s.HR = o.HR
+		}
+		if o.MR != 0 {
+			s.MR = o.MR
+		}
+		if o.LR != 0 {
+			s.LR = o.LR
+		}
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestResolveMetricsResolutions(t *testing.T) {
+	t.Parallel()
+
+	global := models.MetricsResolutions{HR: 5 * time.Second, MR: 10 * time.Second, LR: 60 * time.Second}
+	overrides := []*models.MetricsResolutionOverride{{
+		Name:   "primaries",
+		Labels: models.MatchLabels{"environment": "prod", "role": "primary"},
+		HR:     time.Second,
+	}, {
+		Name:   "prod",
+		Labels: models.MatchLabels{"environment": "prod"},
+		HR:     3 * time.Second,
+		MR:     5 * time.Second,
+	}, {
+		Name:   "dev",
+		Labels: models.MatchLabels{"environment": "dev"},
+		HR:     60 * time.Second,
+		MR:     60 * time.Second,
+	}, {
+		Name:    "agent",
+		AgentID: pointer.ToString("/agent_id/1"),
+		MR:      2 * time.Second,
+	}}
+
+	for _, tc := range []struct {
+		name     string
+		agentID  string
+		labels   map[string]string
+		expected models.MetricsResolutions
+	}{{
+		name:     "NoMatch",
+		agentID:  "/agent_id/2",
+		labels:   map[string]string{"environment": "staging"},
+		expected: global,
+	}, {
+		name:     "Labels",
+		agentID:  "/agent_id/2",
+		labels:   map[string]string{"environment": "dev", "role": "primary"},
+		expected: models.MetricsResolutions{HR: 60 * time.Second, MR: 60 * time.Second, LR: 60 * time.Second},
+	}, {
+		name:     "MoreSpecificLabels",
+		agentID:  "/agent_id/2",
+		labels:   map[string]string{"environment": "prod", "role": "primary"},
+		expected: models.MetricsResolutions{HR: time.Second, MR: 5 * time.Second, LR: 60 * time.Second},
+	}, {
+		name:     "Agent",
+		agentID:  "/agent_id/1",
+		labels:   map[string]string{"environment": "prod", "role": "primary"},
+		expected: models.MetricsResolutions{HR: time.Second, MR: 2 * time.Second, LR: 60 * time.Second},
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := models.ResolveMetricsResolutions(overrides, tc.agentID, tc.labels, global)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
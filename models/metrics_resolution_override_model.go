@@ -0,0 +1,84 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// MatchLabels represents labels that should all match.
+type MatchLabels map[string]string
+
+// Value implements database/sql/driver Valuer interface.
+func (l MatchLabels) Value() (driver.Value, error) { return jsonValue(l) }
+
+// Scan implements database/sql Scanner interface.
+func (l *MatchLabels) Scan(src interface{}) error { return jsonScan(l, src) }
+
+// MetricsResolutionOverride represents metrics resolutions used instead of global ones
+// for a single Agent or for all Agents with matching labels.
+//
+//reform:metrics_resolution_overrides
+type MetricsResolutionOverride struct {
+	ID   string `reform:"id,pk"`
+	Name string `reform:"name"`
+
+	// Either AgentID or Labels is set. Labels are matched against Node, Service and Agent labels.
+	AgentID *string     `reform:"agent_id"`
+	Labels  MatchLabels `reform:"labels"`
+
+	// Zero values are not overridden.
+	HR time.Duration `reform:"hr"`
+	MR time.Duration `reform:"mr"`
+	LR time.Duration `reform:"lr"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (o *MetricsResolutionOverride) BeforeInsert() error {
+	now := Now()
+	o.CreatedAt = now
+	o.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (o *MetricsResolutionOverride) BeforeUpdate() error {
+	o.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (o *MetricsResolutionOverride) AfterFind() error {
+	o.CreatedAt = o.CreatedAt.UTC()
+	o.UpdatedAt = o.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*MetricsResolutionOverride)(nil)
+	_ reform.BeforeUpdater  = (*MetricsResolutionOverride)(nil)
+	_ reform.AfterFinder    = (*MetricsResolutionOverride)(nil)
+)
@@ -0,0 +1,266 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"net"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// FindMonitoredClusters returns all monitored clusters.
+func FindMonitoredClusters(q *reform.Querier) ([]*MonitoredCluster, error) {
+	rows, err := q.SelectAllFrom(MonitoredClusterTable, "ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select monitored clusters")
+	}
+
+	res := make([]*MonitoredCluster, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*MonitoredCluster)
+	}
+	return res, nil
+}
+
+// FindMonitoredClusterByID finds monitored cluster by ID.
+func FindMonitoredClusterByID(q *reform.Querier, id string) (*MonitoredCluster, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty monitored cluster ID.")
+	}
+
+	c := &MonitoredCluster{ID: id}
+	switch err := q.Reload(c); err {
+	case nil:
+		return c, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "Monitored cluster with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+func checkUniqueMonitoredClusterName(q *reform.Querier, name string) error {
+	_, err := q.SelectOneFrom(MonitoredClusterTable, "WHERE name = $1", name)
+	switch err {
+	case nil:
+		return status.Errorf(codes.AlreadyExists, "Monitored cluster with name %q already exists.", name)
+	case reform.ErrNoRows:
+		return nil
+	default:
+		return errors.WithStack(err)
+	}
+}
+
+// MonitoredClusterParams are params for creating monitored cluster.
+type MonitoredClusterParams struct {
+	Name        string
+	Type        MonitoredClusterType
+	PMMAgentID  string
+	SeedAddress string
+	SeedPort    uint16
+
+	Username       string
+	Password       string
+	TLS            bool
+	TLSSkipVerify  bool
+	MySQLOptions   *MySQLOptions
+	MongoDBOptions *MongoDBOptions
+
+	// PostgreSQL only.
+	PostgreSQLOptions *PostgreSQLOptions
+	PatroniURL        string
+
+	Environment                    string
+	CustomLabels                   map[string]string
+	PushMetrics                    bool
+	QAN                            bool
+	QueryExamplesDisabled          bool
+	TableCountTablestatsGroupLimit int32
+}
+
+// Validate checks monitored cluster params without accessing the database.
+// Name is not checked as it may be discovered later.
+func (params *MonitoredClusterParams) Validate() error {
+	if params.Type.ServiceType() == "" {
+		return status.Errorf(codes.InvalidArgument, "Unsupported monitored cluster type %q.", params.Type)
+	}
+	if params.PMMAgentID == "" {
+		return status.Error(codes.InvalidArgument, "Empty pmm-agent ID.")
+	}
+	if params.SeedAddress == "" || params.SeedPort == 0 {
+		return status.Error(codes.InvalidArgument, "Seed address and port are expected.")
+	}
+	if params.MySQLOptions != nil && params.Type.ServiceType() != MySQLServiceType {
+		return status.Error(codes.InvalidArgument, "MySQL options are expected only for MySQL clusters.")
+	}
+	if params.MongoDBOptions != nil && params.Type.ServiceType() != MongoDBServiceType {
+		return status.Error(codes.InvalidArgument, "MongoDB options are expected only for MongoDB clusters.")
+	}
+	if params.PostgreSQLOptions != nil && params.Type.ServiceType() != PostgreSQLServiceType {
+		return status.Error(codes.InvalidArgument, "PostgreSQL options are expected only for PostgreSQL clusters.")
+	}
+	if params.PatroniURL != "" {
+		if params.Type.ServiceType() != PostgreSQLServiceType {
+			return status.Error(codes.InvalidArgument, "Patroni URL is expected only for PostgreSQL clusters.")
+		}
+		u, err := url.Parse(params.PatroniURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return status.Errorf(codes.InvalidArgument, "Invalid Patroni URL %q.", params.PatroniURL)
+		}
+	}
+	for name := range params.CustomLabels {
+		if !labelNameRE.MatchString(name) {
+			return status.Errorf(codes.InvalidArgument, "Invalid label name %q.", name)
+		}
+	}
+	return nil
+}
+
+// CreateMonitoredCluster persists monitored cluster. Members are added on sync.
+func CreateMonitoredCluster(q *reform.Querier, params *MonitoredClusterParams) (*MonitoredCluster, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	if params.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty monitored cluster name.")
+	}
+	if err := checkUniqueMonitoredClusterName(q, params.Name); err != nil {
+		return nil, err
+	}
+	pmmAgent, err := FindAgentByID(q, params.PMMAgentID)
+	if err != nil {
+		return nil, err
+	}
+	if pmmAgent.AgentType != PMMAgentType {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent %q is not a pmm-agent.", params.PMMAgentID)
+	}
+
+	row := &MonitoredCluster{
+		ID:                             "/monitored_cluster_id/" + uuid.New().String(),
+		Name:                           params.Name,
+		Type:                           params.Type,
+		PMMAgentID:                     params.PMMAgentID,
+		SeedAddress:                    params.SeedAddress,
+		SeedPort:                       params.SeedPort,
+		Username:                       params.Username,
+		Password:                       params.Password,
+		TLS:                            params.TLS,
+		TLSSkipVerify:                  params.TLSSkipVerify,
+		MySQLOptions:                   params.MySQLOptions,
+		MongoDBOptions:                 params.MongoDBOptions,
+		PostgreSQLOptions:              params.PostgreSQLOptions,
+		PatroniURL:                     params.PatroniURL,
+		Environment:                    params.Environment,
+		CustomLabels:                   params.CustomLabels,
+		PushMetrics:                    params.PushMetrics,
+		QAN:                            params.QAN,
+		QueryExamplesDisabled:          params.QueryExamplesDisabled,
+		TableCountTablestatsGroupLimit: params.TableCountTablestatsGroupLimit,
+	}
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create monitored cluster")
+	}
+	return row, nil
+}
+
+// RemoveMonitoredCluster removes monitored cluster with specified id
+// together with all Services, Agents and Nodes created for its members.
+func RemoveMonitoredCluster(q *reform.Querier, id string) error {
+	if _, err := FindMonitoredClusterByID(q, id); err != nil {
+		return err
+	}
+
+	members, err := FindMonitoredClusterMembers(q, id)
+	if err != nil {
+		return err
+	}
+	if err = RemoveMonitoredClusterMembers(q, members); err != nil {
+		return err
+	}
+
+	if err = q.Delete(&MonitoredCluster{ID: id}); err != nil {
+		return errors.Wrap(err, "failed to delete monitored cluster")
+	}
+	return nil
+}
+
+// FindMonitoredClusterMembers returns all members of monitored cluster with given ID.
+func FindMonitoredClusterMembers(q *reform.Querier, clusterID string) ([]*MonitoredClusterMember, error) {
+	rows, err := q.SelectAllFrom(MonitoredClusterMemberTable, "WHERE cluster_id = $1 ORDER BY address", clusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select monitored cluster members")
+	}
+
+	res := make([]*MonitoredClusterMember, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*MonitoredClusterMember)
+	}
+	return res, nil
+}
+
+// CreateMonitoredClusterMember persists link between cluster member and its Service.
+func CreateMonitoredClusterMember(q *reform.Querier, member *MonitoredClusterMember) error {
+	if _, _, err := net.SplitHostPort(member.Address); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid cluster member address %q.", member.Address)
+	}
+
+	member.ID = "/monitored_cluster_member_id/" + uuid.New().String()
+	if err := q.Insert(member); err != nil {
+		return errors.Wrap(err, "failed to create monitored cluster member")
+	}
+	return nil
+}
+
+// RemoveMonitoredClusterMembers removes given members. Services (with their Agents) created for them are removed,
+// and then Nodes created for them if they are not used by anything else. Adopted Services are kept.
+func RemoveMonitoredClusterMembers(q *reform.Querier, members []*MonitoredClusterMember) error {
+	nodeIDs := make(map[string]struct{}, len(members))
+	for _, m := range members {
+		if m.Adopted {
+			if err := q.Delete(m); err != nil && err != reform.ErrNoRows {
+				return errors.WithStack(err)
+			}
+			continue
+		}
+
+		if m.NodeID != nil {
+			nodeIDs[*m.NodeID] = struct{}{}
+		}
+
+		// member is removed by ON DELETE CASCADE
+		err := RemoveService(q, m.ServiceID, RemoveCascade)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	for nodeID := range nodeIDs {
+		// keep Nodes with Services or Agents added in other ways or for other members
+		err := RemoveNode(q, nodeID, RemoveRestrict)
+		switch status.Code(err) {
+		case codes.OK, codes.NotFound, codes.FailedPrecondition:
+		default:
+			return err
+		}
+	}
+	return nil
+}
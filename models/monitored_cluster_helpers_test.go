@@ -0,0 +1,90 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestMonitoredClusterParams(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *models.MonitoredClusterParams {
+		return &models.MonitoredClusterParams{
+			Type:         models.MySQLGroupReplicationClusterType,
+			PMMAgentID:   "pmm-server",
+			SeedAddress:  "mysql-1",
+			SeedPort:     3306,
+			CustomLabels: map[string]string{"team": "payments"},
+		}
+	}
+	assert.NoError(t, valid().Validate())
+
+	for _, tc := range []struct {
+		name     string
+		change   func(params *models.MonitoredClusterParams)
+		expected string
+	}{{
+		name:     "UnknownType",
+		change:   func(params *models.MonitoredClusterParams) { params.Type = "galera" },
+		expected: `Unsupported monitored cluster type "galera".`,
+	}, {
+		name:     "NoPMMAgent",
+		change:   func(params *models.MonitoredClusterParams) { params.PMMAgentID = "" },
+		expected: "Empty pmm-agent ID.",
+	}, {
+		name:     "NoSeedPort",
+		change:   func(params *models.MonitoredClusterParams) { params.SeedPort = 0 },
+		expected: "Seed address and port are expected.",
+	}, {
+		name:     "OtherOptions",
+		change:   func(params *models.MonitoredClusterParams) { params.MongoDBOptions = &models.MongoDBOptions{} },
+		expected: "MongoDB options are expected only for MongoDB clusters.",
+	}, {
+		name:     "PatroniURL",
+		change:   func(params *models.MonitoredClusterParams) { params.PatroniURL = "http://pg-1:8008" },
+		expected: "Patroni URL is expected only for PostgreSQL clusters.",
+	}, {
+		name: "InvalidPatroniURL",
+		change: func(params *models.MonitoredClusterParams) {
+			params.Type = models.PostgreSQLReplicationClusterType
+			params.PatroniURL = "pg-1:8008"
+		},
+		expected: `Invalid Patroni URL "pg-1:8008".`,
+	}, {
+		name:     "InvalidLabel",
+		change:   func(params *models.MonitoredClusterParams) { params.CustomLabels["1team"] = "payments" },
+		expected: `Invalid label name "1team".`,
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			params := valid()
+			tc.change(params)
+			err := params.Validate()
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Equal(t, tc.expected, status.Convert(err).Message())
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// MonitoredClusterType represents type of database cluster registered from a seed instance.
+type MonitoredClusterType string
+
+// Monitored cluster types.
+const (
+	MySQLGroupReplicationClusterType MonitoredClusterType = "mysql_group_replication"
+	MySQLInnoDBClusterType           MonitoredClusterType = "mysql_innodb_cluster"
+	MongoDBShardedClusterType        MonitoredClusterType = "mongodb_sharded"
+	PostgreSQLReplicationClusterType MonitoredClusterType = "postgresql_replication"
+)
+
+// ServiceType returns type of Services registered for cluster members.
+func (t MonitoredClusterType) ServiceType() ServiceType {
+	switch t {
+	case MySQLGroupReplicationClusterType, MySQLInnoDBClusterType:
+		return MySQLServiceType
+	case MongoDBShardedClusterType:
+		return MongoDBServiceType
+	case PostgreSQLReplicationClusterType:
+		return PostgreSQLServiceType
+	default:
+		return ""
+	}
+}
+
+// MonitoredCluster represents database cluster which members are discovered from a seed instance
+// and registered as Services with exporters running on a single pmm-agent.
+//
+//reform:monitored_clusters
+type MonitoredCluster struct {
+	ID   string               `reform:"id,pk"`
+	Name string               `reform:"name"` // also used as cluster label of members' Services
+	Type MonitoredClusterType `reform:"type"`
+
+	PMMAgentID  string `reform:"pmm_agent_id"`
+	SeedAddress string `reform:"seed_address"`
+	SeedPort    uint16 `reform:"seed_port"`
+
+	// Used by all members' Agents.
+	Username       string          `reform:"username"`
+	Password       string          `reform:"password"`
+	TLS            bool            `reform:"tls"`
+	TLSSkipVerify  bool            `reform:"tls_skip_verify"`
+	MySQLOptions   *MySQLOptions   `reform:"mysql_options"`
+	MongoDBOptions *MongoDBOptions `reform:"mongo_db_options"`
+	// PostgreSQL only.
+	PostgreSQLOptions *PostgreSQLOptions `reform:"postgresql_options"`
+	// Patroni REST API URL used for discovery instead of the primary if set; PostgreSQL only.
+	PatroniURL string `reform:"patroni_url"`
+
+	// Used by all members' Services and Agents.
+	Environment                    string      `reform:"environment"`
+	CustomLabels                   MatchLabels `reform:"custom_labels"`
+	PushMetrics                    bool        `reform:"push_metrics"`
+	QAN                            bool        `reform:"qan"`
+	QueryExamplesDisabled          bool        `reform:"query_examples_disabled"`
+	TableCountTablestatsGroupLimit int32       `reform:"table_count_tablestats_group_limit"`
+
+	LastSyncAt *time.Time `reform:"last_sync_at"`
+	LastError  string     `reform:"last_error"`
+	CreatedAt  time.Time  `reform:"created_at"`
+	UpdatedAt  time.Time  `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *MonitoredCluster) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *MonitoredCluster) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *MonitoredCluster) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	if s.LastSyncAt != nil {
+		t := s.LastSyncAt.UTC()
+		s.LastSyncAt = &t
+	}
+	return nil
+}
+
+// MonitoredClusterMember links discovered cluster member to its Service.
+//
+//reform:monitored_cluster_members
+type MonitoredClusterMember struct {
+	ID        string `reform:"id,pk"`
+	ClusterID string `reform:"cluster_id"`
+	Address   string `reform:"address"` // host:port
+	ServiceID string `reform:"service_id"`
+	// Set if Node was created for this member; nil if existing Node was used.
+	NodeID *string `reform:"node_id"`
+	// True if Service existed before discovery; such Services are not removed with the member.
+	Adopted bool `reform:"adopted"`
+
+	// Last discovered state.
+	ReplicationSet string `reform:"replication_set"`
+	Role           string `reform:"role"`
+	State          string `reform:"state"`
+	// Time of the first sync that did not discover the member; nil if it was discovered by the last sync.
+	MissingSince *time.Time `reform:"missing_since"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *MonitoredClusterMember) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *MonitoredClusterMember) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *MonitoredClusterMember) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	if s.MissingSince != nil {
+		t := s.MissingSince.UTC()
+		s.MissingSince = &t
+	}
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*MonitoredCluster)(nil)
+	_ reform.BeforeUpdater  = (*MonitoredCluster)(nil)
+	_ reform.AfterFinder    = (*MonitoredCluster)(nil)
+	_ reform.BeforeInserter = (*MonitoredClusterMember)(nil)
+	_ reform.BeforeUpdater  = (*MonitoredClusterMember)(nil)
+	_ reform.AfterFinder    = (*MonitoredClusterMember)(nil)
+)
@@ -0,0 +1,172 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// FindRedactionPolicies returns all QAN redaction policies.
+func FindRedactionPolicies(q *reform.Querier) ([]*RedactionPolicy, error) {
+	rows, err := q.SelectAllFrom(RedactionPolicyTable, "ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select redaction policies")
+	}
+
+	policies := make([]*RedactionPolicy, len(rows))
+	for i, s := range rows {
+		policies[i] = s.(*RedactionPolicy)
+	}
+
+	return policies, nil
+}
+
+// FindRedactionPolicyByID finds QAN redaction policy by ID.
+func FindRedactionPolicyByID(q *reform.Querier, id string) (*RedactionPolicy, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty redaction policy ID.")
+	}
+
+	policy := &RedactionPolicy{ID: id}
+	switch err := q.Reload(policy); err {
+	case nil:
+		return policy, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "Redaction policy with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+func checkUniqueRedactionPolicyName(q *reform.Querier, id, name string) error {
+	_, err := q.SelectOneFrom(RedactionPolicyTable, "WHERE name = $1 AND id <> $2", name, id)
+	switch err {
+	case nil:
+		return status.Errorf(codes.AlreadyExists, "Redaction policy with name %q already exists.", name)
+	case reform.ErrNoRows:
+		return nil
+	default:
+		return errors.WithStack(err)
+	}
+}
+
+// RedactionPolicyParams are params for creating and changing QAN redaction policy.
+type RedactionPolicyParams struct {
+	Name     string
+	Filters  Filters
+	Rules    RedactionRules
+	Disabled bool
+}
+
+// apply validates params and sets them to the given policy.
+func (params *RedactionPolicyParams) apply(q *reform.Querier, policy *RedactionPolicy) error {
+	if params.Name == "" {
+		return status.Error(codes.InvalidArgument, "Empty redaction policy name.")
+	}
+	if err := checkUniqueRedactionPolicyName(q, policy.ID, params.Name); err != nil {
+		return err
+	}
+
+	for _, f := range params.Filters {
+		if f.Key == "" {
+			return status.Error(codes.InvalidArgument, "Empty filter label name.")
+		}
+		switch f.Type {
+		case Equal:
+		case Regex:
+			if _, err := regexp.Compile(f.Val); err != nil {
+				return status.Errorf(codes.InvalidArgument, "Invalid filter regular expression %q: %s.", f.Val, err)
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "Unknown filter type %q.", f.Type)
+		}
+	}
+
+	if len(params.Rules) == 0 {
+		return status.Error(codes.InvalidArgument, "At least one redaction rule is expected.")
+	}
+	for _, r := range params.Rules {
+		if r.Pattern == "" {
+			return status.Error(codes.InvalidArgument, "Empty redaction rule pattern.")
+		}
+		switch r.Type {
+		case LiteralRedaction:
+		case RegexRedaction:
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return status.Errorf(codes.InvalidArgument, "Invalid redaction regular expression %q: %s.", r.Pattern, err)
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "Unknown redaction rule type %q.", r.Type)
+		}
+	}
+
+	policy.Name = params.Name
+	policy.Filters = params.Filters
+	policy.Rules = params.Rules
+	policy.Disabled = params.Disabled
+	return nil
+}
+
+// CreateRedactionPolicy persists QAN redaction policy.
+func CreateRedactionPolicy(q *reform.Querier, params *RedactionPolicyParams) (*RedactionPolicy, error) {
+	row := &RedactionPolicy{ID: "/redaction_policy_id/" + uuid.New().String()}
+	if err := params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create redaction policy")
+	}
+
+	return row, nil
+}
+
+// ChangeRedactionPolicy updates existing QAN redaction policy.
+func ChangeRedactionPolicy(q *reform.Querier, id string, params *RedactionPolicyParams) (*RedactionPolicy, error) {
+	row, err := FindRedactionPolicyByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to change redaction policy")
+	}
+
+	return row, nil
+}
+
+// RemoveRedactionPolicy removes QAN redaction policy with specified id.
+func RemoveRedactionPolicy(q *reform.Querier, id string) error {
+	if _, err := FindRedactionPolicyByID(q, id); err != nil {
+		return err
+	}
+
+	if err := q.Delete(&RedactionPolicy{ID: id}); err != nil {
+		return errors.Wrap(err, "failed to delete redaction policy")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestRedactionPolicies(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SkipFixtures, nil)
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+
+	newParams := func() *models.RedactionPolicyParams {
+		return &models.RedactionPolicyParams{
+			Name:    "emails",
+			Filters: models.Filters{{Type: models.Equal, Key: "environment", Val: "prod"}},
+			Rules:   models.RedactionRules{{Type: models.RegexRedaction, Pattern: `[\w.+-]+@[\w-]+\.[\w.]+`}},
+		}
+	}
+
+	t.Run("create, change and remove", func(t *testing.T) {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, tx.Rollback())
+		}()
+		q := tx.Querier
+
+		policy, err := models.CreateRedactionPolicy(q, newParams())
+		require.NoError(t, err)
+
+		_, err = models.CreateRedactionPolicy(q, newParams())
+		tests.AssertGRPCError(t, status.New(codes.AlreadyExists, `Redaction policy with name "emails" already exists.`), err)
+
+		params := newParams()
+		params.Rules = append(params.Rules, models.RedactionRule{Type: models.LiteralRedaction, Pattern: "s3cr3t", Replacement: "<secret>"})
+		params.Disabled = true
+		_, err = models.ChangeRedactionPolicy(q, policy.ID, params)
+		require.NoError(t, err)
+
+		policies, err := models.FindRedactionPolicies(q)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		assert.Equal(t, params.Rules, policies[0].Rules)
+		assert.Equal(t, params.Filters, policies[0].Filters)
+		assert.True(t, policies[0].Disabled)
+
+		require.NoError(t, models.RemoveRedactionPolicy(q, policy.ID))
+		err = models.RemoveRedactionPolicy(q, policy.ID)
+		tests.AssertGRPCError(t, status.New(codes.NotFound, `Redaction policy with ID "`+policy.ID+`" not found.`), err)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			modify   func(*models.RedactionPolicyParams)
+			expected *status.Status
+		}{
+			"NoRules": {
+				modify:   func(p *models.RedactionPolicyParams) { p.Rules = nil },
+				expected: status.New(codes.InvalidArgument, "At least one redaction rule is expected."),
+			},
+			"BadRegex": {
+				modify: func(p *models.RedactionPolicyParams) { p.Rules[0].Pattern = "(" },
+				expected: status.New(codes.InvalidArgument,
+					"Invalid redaction regular expression \"(\": error parsing regexp: missing closing ): `(`."),
+			},
+			"UnknownRuleType": {
+				modify:   func(p *models.RedactionPolicyParams) { p.Rules[0].Type = "hash" },
+				expected: status.New(codes.InvalidArgument, `Unknown redaction rule type "hash".`),
+			},
+			"EmptyFilterKey": {
+				modify:   func(p *models.RedactionPolicyParams) { p.Filters[0].Key = "" },
+				expected: status.New(codes.InvalidArgument, "Empty filter label name."),
+			},
+		} {
+			tc := tc
+			t.Run(name, func(t *testing.T) {
+				tx, err := db.Begin()
+				require.NoError(t, err)
+				defer func() {
+					require.NoError(t, tx.Rollback())
+				}()
+
+				params := newParams()
+				tc.modify(params)
+				_, err = models.CreateRedactionPolicy(tx.Querier, params)
+				tests.AssertGRPCError(t, tc.expected, err)
+			})
+		}
+	})
+}
@@ -0,0 +1,99 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// RedactionRuleType represents type of QAN redaction rule.
+type RedactionRuleType string
+
+// Available redaction rule types.
+const (
+	// RegexRedaction replaces all matches of regular expression; replacement may use $1-style references.
+	RegexRedaction = RedactionRuleType("regex")
+	// LiteralRedaction replaces all occurrences of a literal string.
+	LiteralRedaction = RedactionRuleType("literal")
+)
+
+// DefaultRedactionReplacement is used when rule replacement is empty.
+const DefaultRedactionReplacement = "***"
+
+// RedactionRule represents a single masking rule.
+type RedactionRule struct {
+	Type        RedactionRuleType `json:"type"`
+	Pattern     string            `json:"pattern"`
+	Replacement string            `json:"replacement,omitempty"`
+}
+
+// RedactionRules represents redaction rules slice.
+type RedactionRules []RedactionRule
+
+// Value implements database/sql/driver Valuer interface.
+func (r RedactionRules) Value() (driver.Value, error) { return jsonValue(r) }
+
+// Scan implements database/sql Scanner interface.
+func (r *RedactionRules) Scan(src interface{}) error { return jsonScan(r, src) }
+
+// RedactionPolicy represents a set of rules applied to query examples and fingerprints
+// before they are sent to QAN storage.
+//reform:qan_redaction_policies
+type RedactionPolicy struct {
+	ID   string `reform:"id,pk"`
+	Name string `reform:"name"`
+	// Filters select Services by labels; policy is applied to all Services if empty.
+	Filters  Filters        `reform:"filters"`
+	Rules    RedactionRules `reform:"rules"`
+	Disabled bool           `reform:"disabled"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (p *RedactionPolicy) BeforeInsert() error {
+	now := Now()
+	p.CreatedAt = now
+	p.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (p *RedactionPolicy) BeforeUpdate() error {
+	p.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (p *RedactionPolicy) AfterFind() error {
+	p.CreatedAt = p.CreatedAt.UTC()
+	p.UpdatedAt = p.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*RedactionPolicy)(nil)
+	_ reform.BeforeUpdater  = (*RedactionPolicy)(nil)
+	_ reform.AfterFinder    = (*RedactionPolicy)(nil)
+)
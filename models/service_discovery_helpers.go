@@ -0,0 +1,313 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"net"
+	"net/url"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// FindServiceDiscoverySources returns all service discovery sources.
+func FindServiceDiscoverySources(q *reform.Querier) ([]*ServiceDiscoverySource, error) {
+	rows, err := q.SelectAllFrom(ServiceDiscoverySourceTable, "ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select service discovery sources")
+	}
+
+	res := make([]*ServiceDiscoverySource, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*ServiceDiscoverySource)
+	}
+	return res, nil
+}
+
+// FindServiceDiscoverySourceByID finds service discovery source by ID.
+func FindServiceDiscoverySourceByID(q *reform.Querier, id string) (*ServiceDiscoverySource, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty service discovery source ID.")
+	}
+
+	s := &ServiceDiscoverySource{ID: id}
+	switch err := q.Reload(s); err {
+	case nil:
+		return s, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "Service discovery source with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+func checkUniqueServiceDiscoverySourceName(q *reform.Querier, id, name string) error {
+	_, err := q.SelectOneFrom(ServiceDiscoverySourceTable, "WHERE name = $1 AND id <> $2", name, id)
+	switch err {
+	case nil:
+		return status.Errorf(codes.AlreadyExists, "Service discovery source with name %q already exists.", name)
+	case reform.ErrNoRows:
+		return nil
+	default:
+		return errors.WithStack(err)
+	}
+}
+
+// ServiceDiscoverySourceParams are params for creating and changing service discovery source.
+type ServiceDiscoverySourceParams struct {
+	Name       string
+	Type       ServiceDiscoveryType
+	FileSD     *FileSDOptions
+	Consul     *ConsulOptions
+	Kubernetes *KubernetesOptions
+
+	// "environment", "cluster" and "external" are used if empty.
+	EnvironmentLabel string
+	ClusterLabel     string
+	ExternalGroup    string
+	Disabled         bool
+}
+
+// Validate checks service discovery source params without accessing the database and sets defaults.
+func (params *ServiceDiscoverySourceParams) Validate() error {
+	if params.Name == "" {
+		return status.Error(codes.InvalidArgument, "Empty service discovery source name.")
+	}
+
+	options := 0
+	for _, set := range []bool{params.FileSD != nil, params.Consul != nil, params.Kubernetes != nil} {
+		if set {
+			options++
+		}
+	}
+	if options > 1 {
+		return status.Error(codes.InvalidArgument, "Only options of the source type are expected.")
+	}
+
+	switch params.Type {
+	case FileSDServiceDiscoveryType:
+		o := params.FileSD
+		if o == nil || o.Path == "" {
+			return status.Error(codes.InvalidArgument, "file_sd path is expected.")
+		}
+		if !filepath.IsAbs(o.Path) {
+			return status.Errorf(codes.InvalidArgument, "file_sd path %q should be absolute.", o.Path)
+		}
+		if _, err := filepath.Match(o.Path, ""); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid file_sd path pattern %q.", o.Path)
+		}
+
+	case ConsulServiceDiscoveryType:
+		o := params.Consul
+		if o == nil || o.Address == "" {
+			return status.Error(codes.InvalidArgument, "Consul address is expected.")
+		}
+		if _, _, err := net.SplitHostPort(o.Address); err != nil {
+			return status.Errorf(codes.InvalidArgument, "Consul address %q should be host:port.", o.Address)
+		}
+		switch o.Scheme {
+		case "":
+			o.Scheme = "http"
+		case "http", "https":
+		default:
+			return status.Errorf(codes.InvalidArgument, "Unsupported Consul scheme %q.", o.Scheme)
+		}
+
+	case KubernetesServiceDiscoveryType:
+		o := params.Kubernetes
+		if o == nil {
+			params.Kubernetes = new(KubernetesOptions)
+			o = params.Kubernetes
+		}
+		if o.APIServer != "" {
+			u, err := url.Parse(o.APIServer)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return status.Errorf(codes.InvalidArgument, "Kubernetes API server %q should be http or https URL.", o.APIServer)
+			}
+		}
+		for _, ns := range o.Namespaces {
+			if ns == "" {
+				return status.Error(codes.InvalidArgument, "Empty Kubernetes namespace.")
+			}
+		}
+
+	default:
+		return status.Errorf(codes.InvalidArgument, "Unsupported service discovery source type %q.", params.Type)
+	}
+
+	if params.EnvironmentLabel == "" {
+		params.EnvironmentLabel = "environment"
+	}
+	if params.ClusterLabel == "" {
+		params.ClusterLabel = "cluster"
+	}
+	for _, l := range []string{params.EnvironmentLabel, params.ClusterLabel} {
+		if !labelNameRE.MatchString(l) {
+			return status.Errorf(codes.InvalidArgument, "Invalid label name %q.", l)
+		}
+	}
+	if params.EnvironmentLabel == params.ClusterLabel {
+		return status.Error(codes.InvalidArgument, "Environment and cluster labels should be different.")
+	}
+	if params.ExternalGroup == "" {
+		params.ExternalGroup = "external"
+	}
+
+	return nil
+}
+
+// apply validates params and sets them to the given source.
+func (params *ServiceDiscoverySourceParams) apply(q *reform.Querier, s *ServiceDiscoverySource) error {
+	if err := params.Validate(); err != nil {
+		return err
+	}
+	if err := checkUniqueServiceDiscoverySourceName(q, s.ID, params.Name); err != nil {
+		return err
+	}
+
+	s.Name = params.Name
+	s.Type = params.Type
+	s.FileSD = params.FileSD
+	s.Consul = params.Consul
+	s.Kubernetes = params.Kubernetes
+	s.EnvironmentLabel = params.EnvironmentLabel
+	s.ClusterLabel = params.ClusterLabel
+	s.ExternalGroup = params.ExternalGroup
+	s.Disabled = params.Disabled
+	return nil
+}
+
+// CreateServiceDiscoverySource persists service discovery source.
+func CreateServiceDiscoverySource(q *reform.Querier, params *ServiceDiscoverySourceParams) (*ServiceDiscoverySource, error) {
+	row := &ServiceDiscoverySource{ID: "/service_discovery_source_id/" + uuid.New().String()}
+	if err := params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create service discovery source")
+	}
+	return row, nil
+}
+
+// ChangeServiceDiscoverySource replaces all fields of existing service discovery source.
+// Existing discovered targets are kept until the next sync.
+func ChangeServiceDiscoverySource(q *reform.Querier, id string, params *ServiceDiscoverySourceParams) (*ServiceDiscoverySource, error) {
+	row, err := FindServiceDiscoverySourceByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to change service discovery source")
+	}
+	return row, nil
+}
+
+// RemoveServiceDiscoverySource removes service discovery source with specified id
+// together with all Services, Agents and Nodes created for discovered targets.
+func RemoveServiceDiscoverySource(q *reform.Querier, id string) error {
+	if _, err := FindServiceDiscoverySourceByID(q, id); err != nil {
+		return err
+	}
+
+	targets, err := FindDiscoveredTargets(q, id)
+	if err != nil {
+		return err
+	}
+	if err = RemoveDiscoveredTargets(q, targets); err != nil {
+		return err
+	}
+
+	if err = q.Delete(&ServiceDiscoverySource{ID: id}); err != nil {
+		return errors.Wrap(err, "failed to delete service discovery source")
+	}
+	return nil
+}
+
+// FindDiscoveredTargets returns all targets discovered by source with given ID.
+func FindDiscoveredTargets(q *reform.Querier, sourceID string) ([]*DiscoveredTarget, error) {
+	rows, err := q.SelectAllFrom(DiscoveredTargetTable, "WHERE source_id = $1 ORDER BY target_key", sourceID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select discovered targets")
+	}
+
+	res := make([]*DiscoveredTarget, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*DiscoveredTarget)
+	}
+	return res, nil
+}
+
+// CreateDiscoveredTarget persists link between discovered target and objects created for it.
+func CreateDiscoveredTarget(q *reform.Querier, sourceID, targetKey, nodeID, serviceID, agentID string) (*DiscoveredTarget, error) {
+	row := &DiscoveredTarget{
+		ID:        "/discovered_target_id/" + uuid.New().String(),
+		SourceID:  sourceID,
+		TargetKey: targetKey,
+		NodeID:    nodeID,
+		ServiceID: serviceID,
+		AgentID:   agentID,
+	}
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create discovered target")
+	}
+	return row, nil
+}
+
+// RemoveDiscoveredTargets removes Services (with their Agents) created for given discovered targets,
+// and then their Nodes if they are not used by anything else.
+func RemoveDiscoveredTargets(q *reform.Querier, targets []*DiscoveredTarget) error {
+	nodeIDs := make(map[string]struct{}, len(targets))
+	for _, t := range targets {
+		nodeIDs[t.NodeID] = struct{}{}
+
+		// discovered target is removed by ON DELETE CASCADE
+		err := RemoveService(q, t.ServiceID, RemoveCascade)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	for nodeID := range nodeIDs {
+		_, err := q.SelectOneFrom(DiscoveredTargetTable, "WHERE node_id = $1", nodeID)
+		switch err {
+		case nil:
+			continue
+		case reform.ErrNoRows:
+		default:
+			return errors.WithStack(err)
+		}
+
+		// keep Nodes with Services or Agents added in other ways
+		err = RemoveNode(q, nodeID, RemoveRestrict)
+		switch status.Code(err) {
+		case codes.OK, codes.NotFound, codes.FailedPrecondition:
+		default:
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestServiceDiscoverySourceParams(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Defaults", func(t *testing.T) {
+		t.Parallel()
+
+		params := &models.ServiceDiscoverySourceParams{
+			Name:   "consul",
+			Type:   models.ConsulServiceDiscoveryType,
+			Consul: &models.ConsulOptions{Address: "consul:8500"},
+		}
+		require.NoError(t, params.Validate())
+		assert.Equal(t, "http", params.Consul.Scheme)
+		assert.Equal(t, "environment", params.EnvironmentLabel)
+		assert.Equal(t, "cluster", params.ClusterLabel)
+		assert.Equal(t, "external", params.ExternalGroup)
+
+		params = &models.ServiceDiscoverySourceParams{
+			Name: "k8s",
+			Type: models.KubernetesServiceDiscoveryType,
+		}
+		require.NoError(t, params.Validate())
+		assert.Equal(t, &models.KubernetesOptions{}, params.Kubernetes)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		params   *models.ServiceDiscoverySourceParams
+		expected string
+	}{{
+		name:     "UnknownType",
+		params:   &models.ServiceDiscoverySourceParams{Name: "dns", Type: "dns"},
+		expected: `Unsupported service discovery source type "dns".`,
+	}, {
+		name: "OtherOptions",
+		params: &models.ServiceDiscoverySourceParams{
+			Name:       "files",
+			Type:       models.FileSDServiceDiscoveryType,
+			FileSD:     &models.FileSDOptions{Path: "/srv/file_sd/*.json"},
+			Kubernetes: &models.KubernetesOptions{},
+		},
+		expected: "Only options of the source type are expected.",
+	}, {
+		name: "RelativePath",
+		params: &models.ServiceDiscoverySourceParams{
+			Name:   "files",
+			Type:   models.FileSDServiceDiscoveryType,
+			FileSD: &models.FileSDOptions{Path: "file_sd/*.json"},
+		},
+		expected: `file_sd path "file_sd/*.json" should be absolute.`,
+	}, {
+		name: "ConsulAddress",
+		params: &models.ServiceDiscoverySourceParams{
+			Name:   "consul",
+			Type:   models.ConsulServiceDiscoveryType,
+			Consul: &models.ConsulOptions{Address: "http://consul:8500"},
+		},
+		expected: `Consul address "http://consul:8500" should be host:port.`,
+	}, {
+		name: "KubernetesAPIServer",
+		params: &models.ServiceDiscoverySourceParams{
+			Name:       "k8s",
+			Type:       models.KubernetesServiceDiscoveryType,
+			Kubernetes: &models.KubernetesOptions{APIServer: "kubernetes:6443"},
+		},
+		expected: `Kubernetes API server "kubernetes:6443" should be http or https URL.`,
+	}, {
+		name: "SameLabels",
+		params: &models.ServiceDiscoverySourceParams{
+			Name:             "k8s",
+			Type:             models.KubernetesServiceDiscoveryType,
+			EnvironmentLabel: "env",
+			ClusterLabel:     "env",
+		},
+		expected: "Environment and cluster labels should be different.",
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.params.Validate()
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.Equal(t, tc.expected, status.Convert(err).Message())
+		})
+	}
+}
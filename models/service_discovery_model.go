@@ -0,0 +1,185 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// ServiceDiscoveryType represents type of service discovery source.
+type ServiceDiscoveryType string
+
+// Service discovery source types.
+const (
+	FileSDServiceDiscoveryType     ServiceDiscoveryType = "file_sd"
+	ConsulServiceDiscoveryType     ServiceDiscoveryType = "consul"
+	KubernetesServiceDiscoveryType ServiceDiscoveryType = "kubernetes"
+)
+
+// FileSDOptions represents options of Prometheus file_sd JSON source.
+type FileSDOptions struct {
+	// Path to file_sd JSON file; may be a glob pattern.
+	Path string `json:"path"`
+}
+
+// Value implements database/sql/driver.Valuer interface. Should be defined on the value.
+func (o FileSDOptions) Value() (driver.Value, error) { return jsonValue(o) }
+
+// Scan implements database/sql.Scanner interface. Should be defined on the pointer.
+func (o *FileSDOptions) Scan(src interface{}) error { return jsonScan(o, src) }
+
+// ConsulOptions represents options of Consul catalog source.
+type ConsulOptions struct {
+	// Consul HTTP API address as host:port.
+	Address               string `json:"address"`
+	Scheme                string `json:"scheme"`
+	Datacenter            string `json:"datacenter"`
+	Token                 string `json:"token"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+	// Only those Consul services are discovered if set.
+	Services []string `json:"services"`
+	// Only Consul services with all those tags are discovered if set.
+	Tags []string `json:"tags"`
+}
+
+// Value implements database/sql/driver.Valuer interface. Should be defined on the value.
+func (o ConsulOptions) Value() (driver.Value, error) { return jsonValue(o) }
+
+// Scan implements database/sql.Scanner interface. Should be defined on the pointer.
+func (o *ConsulOptions) Scan(src interface{}) error { return jsonScan(o, src) }
+
+// KubernetesOptions represents options of Kubernetes pods source.
+// In-cluster configuration is used if APIServer is empty.
+type KubernetesOptions struct {
+	APIServer             string `json:"api_server"`
+	BearerToken           string `json:"bearer_token"`
+	TLSCa                 string `json:"tls_ca"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`
+	// Pods in all namespaces are discovered if empty.
+	Namespaces []string `json:"namespaces"`
+}
+
+// Value implements database/sql/driver.Valuer interface. Should be defined on the value.
+func (o KubernetesOptions) Value() (driver.Value, error) { return jsonValue(o) }
+
+// Scan implements database/sql.Scanner interface. Should be defined on the pointer.
+func (o *KubernetesOptions) Scan(src interface{}) error { return jsonScan(o, src) }
+
+// ServiceDiscoverySource represents source of external exporters targets
+// reconciled into External Services and external exporters.
+//
+//reform:service_discovery_sources
+type ServiceDiscoverySource struct {
+	ID   string               `reform:"id,pk"`
+	Name string               `reform:"name"`
+	Type ServiceDiscoveryType `reform:"type"`
+
+	// Exactly one of those options is set depending on Type.
+	FileSD     *FileSDOptions     `reform:"file_sd_options"`
+	Consul     *ConsulOptions     `reform:"consul_options"`
+	Kubernetes *KubernetesOptions `reform:"kubernetes_options"`
+
+	// Discovered labels with those names are mapped to Service's environment and cluster;
+	// other discovered labels are mapped to custom labels.
+	EnvironmentLabel string `reform:"environment_label"`
+	ClusterLabel     string `reform:"cluster_label"`
+	ExternalGroup    string `reform:"external_group"`
+	Disabled         bool   `reform:"disabled"`
+
+	LastSyncAt *time.Time `reform:"last_sync_at"`
+	LastError  string     `reform:"last_error"`
+	CreatedAt  time.Time  `reform:"created_at"`
+	UpdatedAt  time.Time  `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *ServiceDiscoverySource) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *ServiceDiscoverySource) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *ServiceDiscoverySource) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	if s.LastSyncAt != nil {
+		t := s.LastSyncAt.UTC()
+		s.LastSyncAt = &t
+	}
+	return nil
+}
+
+// DiscoveredTarget links target discovered by service discovery source
+// to Node, Service and external exporter created for it.
+//
+//reform:discovered_targets
+type DiscoveredTarget struct {
+	ID       string `reform:"id,pk"`
+	SourceID string `reform:"source_id"`
+	// scheme://host:port/path
+	TargetKey string `reform:"target_key"`
+	NodeID    string `reform:"node_id"`
+	ServiceID string `reform:"service_id"`
+	AgentID   string `reform:"agent_id"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (t *DiscoveredTarget) BeforeInsert() error {
+	now := Now()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (t *DiscoveredTarget) BeforeUpdate() error {
+	t.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (t *DiscoveredTarget) AfterFind() error {
+	t.CreatedAt = t.CreatedAt.UTC()
+	t.UpdatedAt = t.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*ServiceDiscoverySource)(nil)
+	_ reform.BeforeUpdater  = (*ServiceDiscoverySource)(nil)
+	_ reform.AfterFinder    = (*ServiceDiscoverySource)(nil)
+	_ reform.BeforeInserter = (*DiscoveredTarget)(nil)
+	_ reform.BeforeUpdater  = (*DiscoveredTarget)(nil)
+	_ reform.AfterFinder    = (*DiscoveredTarget)(nil)
+)
@@ -124,6 +124,76 @@ func FindServices(q *reform.Querier, filters ServiceFilters) ([]*Service, error)
 	return services, nil
 }
 
+// ServiceSelector selects Services by standard and custom labels.
+// All non-empty fields should match.
+type ServiceSelector struct {
+	ServiceType    *ServiceType
+	Environment    string
+	Cluster        string
+	ReplicationSet string
+	CustomLabels   map[string]string
+}
+
+// IsEmpty returns true if selector matches all Services.
+func (s *ServiceSelector) IsEmpty() bool {
+	return s.ServiceType == nil && s.Environment == "" && s.Cluster == "" && s.ReplicationSet == "" && len(s.CustomLabels) == 0
+}
+
+// FindServicesBySelector returns Services matching selector in a stable order.
+func FindServicesBySelector(q *reform.Querier, selector *ServiceSelector) ([]*Service, error) {
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"environment", selector.Environment},
+		{"cluster", selector.Cluster},
+		{"replication_set", selector.ReplicationSet},
+	} {
+		if c.value == "" {
+			continue
+		}
+		args = append(args, c.value)
+		conditions = append(conditions, fmt.Sprintf("%s = %s", c.column, q.Placeholder(len(args))))
+	}
+	if selector.ServiceType != nil {
+		args = append(args, *selector.ServiceType)
+		conditions = append(conditions, fmt.Sprintf("service_type = %s", q.Placeholder(len(args))))
+	}
+
+	var whereClause string
+	if len(conditions) != 0 {
+		whereClause = fmt.Sprintf("WHERE %s", strings.Join(conditions, " AND "))
+	}
+	structs, err := q.SelectAllFrom(ServiceTable, fmt.Sprintf("%s ORDER BY service_id", whereClause), args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// custom labels are stored as JSON, so match them there
+	services := make([]*Service, 0, len(structs))
+	for _, str := range structs {
+		service := str.(*Service)
+		labels, err := service.GetCustomLabels()
+		if err != nil {
+			return nil, err
+		}
+		matches := true
+		for k, v := range selector.CustomLabels {
+			if labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			services = append(services, service)
+		}
+	}
+
+	return services, nil
+}
+
 // FindServiceByID finds Service by ID.
 func FindServiceByID(q *reform.Querier, id string) (*Service, error) {
 	if id == "" {
@@ -186,6 +256,21 @@ func FindServiceByName(q *reform.Querier, name string) (*Service, error) {
 	}
 }
 
+// FindServiceByAddress returns Service of given type with given address and port, or nil if there is no such Service.
+func FindServiceByAddress(q *reform.Querier, serviceType ServiceType, address string, port uint16) (*Service, error) {
+	tail := fmt.Sprintf("WHERE service_type = %s AND address = %s AND port = %s ORDER BY service_id LIMIT 1",
+		q.Placeholder(1), q.Placeholder(2), q.Placeholder(3))
+	str, err := q.SelectOneFrom(ServiceTable, tail, serviceType, address, port)
+	switch err {
+	case nil:
+		return str.(*Service), nil
+	case reform.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
 // AddDBMSServiceParams contains parameters for adding DBMS (MySQL, PostgreSQL, MongoDB, External) Services.
 type AddDBMSServiceParams struct {
 	ServiceName    string
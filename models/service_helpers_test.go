@@ -267,6 +267,20 @@ func TestServiceHelpers(t *testing.T) {
 		}})
 	})
 
+	t.Run("FindServiceByAddress", func(t *testing.T) {
+		q, teardown := setup(t)
+		defer teardown(t)
+
+		service, err := models.FindServiceByAddress(q, models.MySQLServiceType, "127.0.0.1", 3306)
+		require.NoError(t, err)
+		require.NotNil(t, service)
+		assert.Equal(t, "S2", service.ServiceID)
+
+		service, err = models.FindServiceByAddress(q, models.MongoDBServiceType, "127.0.0.1", 3306)
+		require.NoError(t, err)
+		assert.Nil(t, service)
+	})
+
 	t.Run("RemoveService", func(t *testing.T) {
 		q, teardown := setup(t)
 		defer teardown(t)
@@ -17,11 +17,17 @@
 package models
 
 import (
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
+	config "github.com/percona/promconfig"
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/utils/validators"
 )
 
 // MetricsResolutions contains standard VictoriaMetrics metrics resolutions.
@@ -65,6 +71,8 @@ type Settings struct {
 
 	DataRetention time.Duration `json:"data_retention"`
 
+	RetentionTiers []RetentionTier `json:"retention_tiers"`
+
 	AWSPartitions []string `json:"aws_partitions"`
 
 	AWSInstanceChecked bool `json:"aws_instance_checked"`
@@ -95,6 +103,8 @@ type Settings struct {
 		Enabled bool `json:"enabled"`
 	} `json:"backup_management"`
 
+	RemoteWrite RemoteWrite `json:"remote_write"`
+
 	// PMMServerID is generated on the first start of PMM server.
 	PMMServerID string `json:"pmmServerID"`
 }
@@ -135,6 +145,180 @@ type SlackAlertingSettings struct {
 	URL string `json:"url"`
 }
 
+// RetentionTier contains data retention and downsampling of metrics selected by labels.
+// Metrics not selected by any tier are kept for Settings.DataRetention at full resolution.
+type RetentionTier struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels"`
+	Retention time.Duration     `json:"retention"`
+	// Ordered by offset.
+	Downsampling []DownsamplingPeriod `json:"downsampling,omitempty"`
+}
+
+// DownsamplingPeriod defines that samples older than Offset are downsampled to Interval.
+type DownsamplingPeriod struct {
+	Offset   time.Duration `json:"offset"`
+	Interval time.Duration `json:"interval"`
+}
+
+// Validate validates structure's fields.
+func (t *RetentionTier) Validate() error {
+	if t.Name == "" {
+		return errors.New("empty retention tier name")
+	}
+
+	if len(t.Labels) == 0 {
+		return errors.Errorf("retention tier %q: labels are expected", t.Name)
+	}
+	for name, value := range t.Labels {
+		if !labelNameRE.MatchString(name) {
+			return errors.Errorf("retention tier %q: invalid label name %q", t.Name, name)
+		}
+		if value == "" {
+			return errors.Errorf("retention tier %q: empty value of label %q", t.Name, name)
+		}
+	}
+
+	if _, err := validators.ValidateDataRetention(t.Retention); err != nil {
+		return errors.Errorf("retention tier %q: retention should be a natural number of days", t.Name)
+	}
+
+	var prev DownsamplingPeriod
+	for _, p := range t.Downsampling {
+		if p.Offset <= prev.Offset || p.Interval <= prev.Interval {
+			return errors.Errorf("retention tier %q: downsampling offsets and intervals should increase", t.Name)
+		}
+		if p.Offset >= t.Retention {
+			return errors.Errorf("retention tier %q: downsampling offset %s is not less than retention", t.Name, p.Offset)
+		}
+		if p.Interval%time.Second != 0 {
+			return errors.Errorf("retention tier %q: downsampling interval should be a natural number of seconds", t.Name)
+		}
+		prev = p
+	}
+
+	return nil
+}
+
+// RemoteWrite contains settings of forwarding PMM metrics to external TSDBs with remote-write protocol.
+type RemoteWrite struct {
+	Targets []RemoteWriteTarget `json:"targets"`
+	Queue   RemoteWriteQueue    `json:"queue"`
+}
+
+// RemoteWriteTarget represents a single external TSDB (Mimir, Thanos, VictoriaMetrics, etc.).
+type RemoteWriteTarget struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+
+	TLSCA                 string `json:"tls_ca,omitempty"`
+	TLSCert               string `json:"tls_cert,omitempty"`
+	TLSKey                string `json:"tls_key,omitempty"`
+	TLSServerName         string `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify,omitempty"`
+
+	// Prometheus relabel_configs in YAML format applied before sending;
+	// keep and drop actions filter forwarded series.
+	RelabelConfigs string `json:"relabel_configs,omitempty"`
+}
+
+// RemoteWriteQueue contains queue settings shared by all remote-write targets.
+type RemoteWriteQueue struct {
+	// Number of concurrent queues per target; 0 means vmagent's default.
+	Queues int `json:"queues,omitempty"`
+	// Interval of flushing data to targets; 0 means vmagent's default.
+	FlushInterval time.Duration `json:"flush_interval,omitempty"`
+	// Maximum on-disk buffer size per target for periods when target is unavailable; 0 means unlimited.
+	MaxDiskUsagePerTarget int64 `json:"max_disk_usage_per_target,omitempty"`
+}
+
+// Validate validates structure's fields.
+func (t *RemoteWriteTarget) Validate() error {
+	if t.Name == "" {
+		return errors.New("empty remote-write target name")
+	}
+
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return errors.Errorf("invalid url of remote-write target %q: %s", t.Name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("invalid url of remote-write target %q: expected http or https scheme", t.Name)
+	}
+	if u.Host == "" {
+		return errors.Errorf("invalid url of remote-write target %q: missing host", t.Name)
+	}
+	if u.User != nil {
+		return errors.Errorf("invalid url of remote-write target %q: use username and password fields", t.Name)
+	}
+
+	if t.Password != "" && t.Username == "" {
+		return errors.Errorf("remote-write target %q: password without username", t.Name)
+	}
+	if t.Username != "" && t.BearerToken != "" {
+		return errors.Errorf("remote-write target %q: both basic auth and bearer token are present", t.Name)
+	}
+	// vmagent splits per-target flag values by commas
+	for _, v := range []string{t.URL, t.Username, t.TLSServerName} {
+		if strings.Contains(v, ",") {
+			return errors.Errorf("remote-write target %q: commas are not supported in url, username and TLS server name", t.Name)
+		}
+	}
+	for name, value := range t.Headers {
+		if name == "" || strings.ContainsAny(name, ":^,") || strings.ContainsAny(value, "^,") {
+			return errors.Errorf("remote-write target %q: invalid header %q", t.Name, name)
+		}
+	}
+	if (t.TLSCert == "") != (t.TLSKey == "") {
+		return errors.Errorf("remote-write target %q: both TLS certificate and key are expected", t.Name)
+	}
+
+	if t.RelabelConfigs != "" {
+		var configs []*config.RelabelConfig
+		if err := yaml.Unmarshal([]byte(t.RelabelConfigs), &configs); err != nil {
+			return errors.Errorf("remote-write target %q: invalid relabel configs: %s", t.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate validates structure's fields.
+func (q *RemoteWriteQueue) Validate() error {
+	if q.Queues < 0 || q.Queues > 100 {
+		return errors.New("remote-write queues: should be in range [0, 100]")
+	}
+	if q.FlushInterval < 0 || q.FlushInterval%time.Second != 0 {
+		return errors.New("remote-write flush_interval: should be a natural number of seconds")
+	}
+	if q.MaxDiskUsagePerTarget < 0 {
+		return errors.New("remote-write max_disk_usage_per_target: should not be negative")
+	}
+	return nil
+}
+
+// Validate validates structure's fields.
+func (rw *RemoteWrite) Validate() error {
+	names := make(map[string]struct{}, len(rw.Targets))
+	for i := range rw.Targets {
+		t := &rw.Targets[i]
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[t.Name]; ok {
+			return errors.Errorf("duplicate remote-write target name %q", t.Name)
+		}
+		names[t.Name] = struct{}{}
+	}
+
+	return rw.Queue.Validate()
+}
+
 // STTCheckIntervals represents intervals between STT checks.
 type STTCheckIntervals struct {
 	StandardInterval time.Duration `json:"standard_interval"`
@@ -186,4 +370,6 @@ func (s *Settings) fillDefaults() {
 	// VictoriaMetrics CacheEnable is false by default
 	// PMMPublicAddress is empty by default
 	// Azurediscover.Enabled is false by default
+	// RemoteWrite.Targets is empty by default
+	// RetentionTiers is empty by default
 }
@@ -59,6 +59,10 @@ type ChangeSettingsParams struct {
 
 	DataRetention time.Duration
 
+	// Retention tiers; replace existing ones.
+	RetentionTiers       []RetentionTier
+	RemoveRetentionTiers bool
+
 	AWSPartitions []string
 
 	SSHKey string
@@ -116,6 +120,9 @@ type ChangeSettingsParams struct {
 	EnableBackupManagement bool
 	// Disable Backup Management features.
 	DisableBackupManagement bool
+
+	// Remote-write settings; replaces existing ones when not nil.
+	RemoteWrite *RemoteWrite
 }
 
 // SetPMMServerID should be run on start up to generate unique PMM Server ID.
@@ -168,6 +175,18 @@ func UpdateSettings(q reform.DBTX, params *ChangeSettingsParams) (*Settings, err
 	if params.DataRetention != 0 {
 		settings.DataRetention = params.DataRetention
 	}
+	if len(params.RetentionTiers) != 0 {
+		settings.RetentionTiers = params.RetentionTiers
+	}
+	if params.RemoveRetentionTiers {
+		settings.RetentionTiers = nil
+	}
+	// retention tiers can't keep data longer than the global retention
+	for _, t := range settings.RetentionTiers {
+		if settings.DataRetention != 0 && t.Retention > settings.DataRetention {
+			return nil, NewInvalidArgumentError("retention tier %q: retention %s is greater than data_retention %s", t.Name, t.Retention, settings.DataRetention)
+		}
+	}
 
 	if len(params.AWSPartitions) != 0 {
 		settings.AWSPartitions = deduplicateStrings(params.AWSPartitions)
@@ -280,6 +299,10 @@ func UpdateSettings(q reform.DBTX, params *ChangeSettingsParams) (*Settings, err
 		settings.BackupManagement.Enabled = true
 	}
 
+	if params.RemoteWrite != nil {
+		settings.RemoteWrite = *params.RemoteWrite
+	}
+
 	err = SaveSettings(q, settings)
 	if err != nil {
 		return nil, err
@@ -405,6 +428,21 @@ func ValidateSettings(params *ChangeSettingsParams) error {
 		}
 	}
 
+	if len(params.RetentionTiers) != 0 && params.RemoveRetentionTiers {
+		return errors.New("both retention_tiers and remove_retention_tiers are present")
+	}
+	names := make(map[string]struct{}, len(params.RetentionTiers))
+	for i := range params.RetentionTiers {
+		t := &params.RetentionTiers[i]
+		if err := t.Validate(); err != nil {
+			return err
+		}
+		if _, ok := names[t.Name]; ok {
+			return errors.Errorf("duplicate retention tier name %q", t.Name)
+		}
+		names[t.Name] = struct{}{}
+	}
+
 	if err := validators.ValidateAWSPartitions(params.AWSPartitions); err != nil {
 		return err
 	}
@@ -434,6 +472,12 @@ func ValidateSettings(params *ChangeSettingsParams) error {
 		return errors.New("both pmm_public_address and remove_pmm_public_address are present")
 	}
 
+	if params.RemoteWrite != nil {
+		if err := params.RemoteWrite.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
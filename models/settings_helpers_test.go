@@ -430,6 +430,133 @@ func TestSettings(t *testing.T) {
 			assert.True(t, errors.As(err, &errInvalidArgument))
 			assert.EqualError(t, err, "invalid argument: both enable_alerting and disable_alerting are present")
 		})
+
+		t.Run("RetentionTiers", func(t *testing.T) {
+			var errInvalidArgument *models.ErrInvalidArgument
+			dev := models.RetentionTier{
+				Name:      "dev",
+				Labels:    map[string]string{"environment": "dev"},
+				Retention: 14 * 24 * time.Hour,
+			}
+			prod := models.RetentionTier{
+				Name:      "prod",
+				Labels:    map[string]string{"environment": "prod"},
+				Retention: 365 * 24 * time.Hour,
+				Downsampling: []models.DownsamplingPeriod{
+					{Offset: 30 * 24 * time.Hour, Interval: 5 * time.Minute},
+					{Offset: 180 * 24 * time.Hour, Interval: time.Hour},
+				},
+			}
+
+			for _, tc := range []struct {
+				tier models.RetentionTier
+				err  string
+			}{{
+				tier: models.RetentionTier{Name: "dev", Retention: 24 * time.Hour},
+				err:  `invalid argument: retention tier "dev": labels are expected`,
+			}, {
+				tier: models.RetentionTier{Name: "dev", Labels: map[string]string{"env-name": "dev"}, Retention: 24 * time.Hour},
+				err:  `invalid argument: retention tier "dev": invalid label name "env-name"`,
+			}, {
+				tier: models.RetentionTier{Name: "dev", Labels: dev.Labels, Retention: 36 * time.Hour},
+				err:  `invalid argument: retention tier "dev": retention should be a natural number of days`,
+			}, {
+				tier: models.RetentionTier{Name: "prod", Labels: prod.Labels, Retention: prod.Retention, Downsampling: []models.DownsamplingPeriod{
+					prod.Downsampling[1], prod.Downsampling[0],
+				}},
+				err: `invalid argument: retention tier "prod": downsampling offsets and intervals should increase`,
+			}} {
+				_, err := models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{RetentionTiers: []models.RetentionTier{tc.tier}})
+				assert.True(t, errors.As(err, &errInvalidArgument))
+				assert.EqualError(t, err, tc.err)
+			}
+
+			_, err := models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{RetentionTiers: []models.RetentionTier{dev, dev}})
+			assert.EqualError(t, err, `invalid argument: duplicate retention tier name "dev"`)
+
+			// tiers can't be longer than the global retention
+			_, err = models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{
+				DataRetention:  30 * 24 * time.Hour,
+				RetentionTiers: []models.RetentionTier{dev, prod},
+			})
+			assert.EqualError(t, err, `invalid argument: retention tier "prod": retention 8760h0m0s is greater than data_retention 720h0m0s`)
+
+			ns, err := models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{
+				DataRetention:  365 * 24 * time.Hour,
+				RetentionTiers: []models.RetentionTier{dev, prod},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []models.RetentionTier{dev, prod}, ns.RetentionTiers)
+
+			_, err = models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{DataRetention: 30 * 24 * time.Hour})
+			assert.True(t, errors.As(err, &errInvalidArgument))
+
+			ns, err = models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{
+				DataRetention:        30 * 24 * time.Hour,
+				RemoveRetentionTiers: true,
+			})
+			require.NoError(t, err)
+			assert.Empty(t, ns.RetentionTiers)
+		})
+
+		t.Run("RemoteWrite", func(t *testing.T) {
+			var errInvalidArgument *models.ErrInvalidArgument
+			for _, tc := range []struct {
+				rw  models.RemoteWrite
+				err string
+			}{{
+				rw:  models.RemoteWrite{Targets: []models.RemoteWriteTarget{{Name: "mimir", URL: "mimir:9009/api/v1/push"}}},
+				err: `invalid argument: invalid url of remote-write target "mimir": expected http or https scheme`,
+			}, {
+				rw: models.RemoteWrite{Targets: []models.RemoteWriteTarget{
+					{Name: "mimir", URL: "https://mimir:9009/api/v1/push", Username: "pmm", BearerToken: "token"},
+				}},
+				err: `invalid argument: remote-write target "mimir": both basic auth and bearer token are present`,
+			}, {
+				rw: models.RemoteWrite{Targets: []models.RemoteWriteTarget{
+					{Name: "mimir", URL: "https://mimir:9009/api/v1/push", RelabelConfigs: "action: keep"},
+				}},
+				err: `invalid argument: remote-write target "mimir": invalid relabel configs: ` +
+					"yaml: unmarshal errors:\n  line 1: cannot unmarshal !!map into []*promconfig.RelabelConfig",
+			}, {
+				rw: models.RemoteWrite{Targets: []models.RemoteWriteTarget{
+					{Name: "mimir", URL: "https://mimir:9009/api/v1/push"},
+					{Name: "mimir", URL: "https://thanos:19291/api/v1/receive"},
+				}},
+				err: `invalid argument: duplicate remote-write target name "mimir"`,
+			}, {
+				rw:  models.RemoteWrite{Queue: models.RemoteWriteQueue{FlushInterval: 1500 * time.Millisecond}},
+				err: `invalid argument: remote-write flush_interval: should be a natural number of seconds`,
+			}} {
+				rw := tc.rw
+				_, err := models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{RemoteWrite: &rw})
+				assert.True(t, errors.As(err, &errInvalidArgument))
+				assert.EqualError(t, err, tc.err)
+			}
+
+			rw := &models.RemoteWrite{
+				Targets: []models.RemoteWriteTarget{{
+					Name:           "mimir",
+					URL:            "https://mimir:9009/api/v1/push",
+					Username:       "pmm",
+					Password:       "secret",
+					RelabelConfigs: "- source_labels: [__name__]\n  regex: mysql_.+\n  action: keep\n",
+				}},
+				Queue: models.RemoteWriteQueue{Queues: 4, FlushInterval: 5 * time.Second},
+			}
+			ns, err := models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{RemoteWrite: rw})
+			require.NoError(t, err)
+			assert.Equal(t, *rw, ns.RemoteWrite)
+
+			// other changes keep remote-write settings
+			ns, err = models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{EnableVMCache: true})
+			require.NoError(t, err)
+			assert.Equal(t, *rw, ns.RemoteWrite)
+
+			ns, err = models.UpdateSettings(sqlDB, &models.ChangeSettingsParams{RemoteWrite: &models.RemoteWrite{}})
+			require.NoError(t, err)
+			assert.Empty(t, ns.RemoteWrite.Targets)
+		})
 	})
 
 	t.Run("Set PMM server ID", func(t *testing.T) {
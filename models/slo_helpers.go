@@ -0,0 +1,209 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// minSLOWindow is the shortest SLO window; shorter windows make burn-rate alerting meaningless.
+const minSLOWindow = 24 * time.Hour
+
+// FindSLOs returns all SLOs.
+func FindSLOs(q *reform.Querier) ([]*SLO, error) {
+	rows, err := q.SelectAllFrom(SLOTable, "ORDER BY name")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select SLOs")
+	}
+
+	slos := make([]*SLO, len(rows))
+	for i, s := range rows {
+		slos[i] = s.(*SLO)
+	}
+
+	return slos, nil
+}
+
+// FindSLOByID finds SLO by ID.
+func FindSLOByID(q *reform.Querier, id string) (*SLO, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty SLO ID.")
+	}
+
+	slo := &SLO{ID: id}
+	switch err := q.Reload(slo); err {
+	case nil:
+		return slo, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "SLO with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+func checkUniqueSLOName(q *reform.Querier, id, name string) error {
+	_, err := q.SelectOneFrom(SLOTable, "WHERE name = $1 AND id <> $2", name, id)
+	switch err {
+	case nil:
+		return status.Errorf(codes.AlreadyExists, "SLO with name %q already exists.", name)
+	case reform.ErrNoRows:
+		return nil
+	default:
+		return errors.WithStack(err)
+	}
+}
+
+// SLOParams are params for creating and changing SLO.
+type SLOParams struct {
+	Name       string
+	Summary    string
+	ServiceID  string
+	Filters    Filters
+	SLIType    SLIType
+	Expr       string
+	Threshold  float64
+	Percentile float64
+	Target     float64
+	Window     time.Duration
+	Disabled   bool
+	ChannelIDs []string
+}
+
+// apply validates params and sets them to the given SLO.
+func (params *SLOParams) apply(q *reform.Querier, slo *SLO) error {
+	if params.Name == "" {
+		return status.Error(codes.InvalidArgument, "Empty SLO name.")
+	}
+	if err := checkUniqueSLOName(q, slo.ID, params.Name); err != nil {
+		return err
+	}
+
+	if params.ServiceID != "" {
+		if _, err := FindServiceByID(q, params.ServiceID); err != nil {
+			return err
+		}
+	}
+
+	percentile := params.Percentile
+	switch params.SLIType {
+	case AvailabilitySLI:
+		if params.Threshold != 0 {
+			return status.Error(codes.InvalidArgument, "Availability SLI doesn't support threshold.")
+		}
+	case LatencySLI:
+		if percentile == 0 {
+			percentile = 0.99
+		}
+		if percentile <= 0 || percentile >= 1 {
+			return status.Errorf(codes.InvalidArgument, "Latency percentile should be between 0 and 1, got %g.", percentile)
+		}
+		fallthrough
+	case ReplicationLagSLI:
+		if params.Threshold <= 0 {
+			return status.Errorf(codes.InvalidArgument, "Threshold should be positive for %s SLI.", params.SLIType)
+		}
+	default:
+		return status.Errorf(codes.InvalidArgument, "Unknown SLI type %q.", params.SLIType)
+	}
+	if params.SLIType != LatencySLI && percentile != 0 {
+		return status.Errorf(codes.InvalidArgument, "Percentile is supported only for %s SLI.", LatencySLI)
+	}
+
+	if params.Target <= 0 || params.Target >= 1 {
+		return status.Errorf(codes.InvalidArgument, "SLO target should be between 0 and 1, got %g.", params.Target)
+	}
+	if params.Window < minSLOWindow {
+		return status.Errorf(codes.InvalidArgument, "SLO window should be at least %s.", minSLOWindow)
+	}
+
+	var channelIDs []string
+	if len(params.ChannelIDs) != 0 {
+		channelIDs = deduplicateStrings(params.ChannelIDs)
+		channels, err := FindChannelsByIDs(q, channelIDs)
+		if err != nil {
+			return err
+		}
+		if len(channelIDs) != len(channels) {
+			missingChannelsIDs := findMissingChannels(channelIDs, channels)
+			return status.Errorf(codes.NotFound, "Failed to find all required channels: %v.", missingChannelsIDs)
+		}
+	}
+
+	slo.Name = params.Name
+	slo.Summary = params.Summary
+	slo.ServiceID = params.ServiceID
+	slo.Filters = params.Filters
+	slo.SLIType = params.SLIType
+	slo.Expr = params.Expr
+	slo.Threshold = params.Threshold
+	slo.Percentile = percentile
+	slo.Target = params.Target
+	slo.Window = params.Window
+	slo.Disabled = params.Disabled
+	slo.ChannelIDs = channelIDs
+	return nil
+}
+
+// CreateSLO persists SLO.
+func CreateSLO(q *reform.Querier, params *SLOParams) (*SLO, error) {
+	row := &SLO{ID: "/slo_id/" + uuid.New().String()}
+	if err := params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err := q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create SLO")
+	}
+
+	return row, nil
+}
+
+// ChangeSLO updates existing SLO.
+func ChangeSLO(q *reform.Querier, id string, params *SLOParams) (*SLO, error) {
+	row, err := FindSLOByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = params.apply(q, row); err != nil {
+		return nil, err
+	}
+
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to change SLO")
+	}
+
+	return row, nil
+}
+
+// RemoveSLO removes SLO with specified id.
+func RemoveSLO(q *reform.Querier, id string) error {
+	if _, err := FindSLOByID(q, id); err != nil {
+		return err
+	}
+
+	if err := q.Delete(&SLO{ID: id}); err != nil {
+		return errors.Wrap(err, "failed to delete SLO")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestSLOs(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SkipFixtures, nil)
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+
+	newParams := func(channelIDs ...string) *models.SLOParams {
+		return &models.SLOParams{
+			Name:       "mysql latency",
+			Filters:    models.Filters{{Type: models.Regex, Key: "environment", Val: "prod.*"}},
+			SLIType:    models.LatencySLI,
+			Threshold:  0.5,
+			Target:     0.99,
+			Window:     30 * 24 * time.Hour,
+			ChannelIDs: channelIDs,
+		}
+	}
+
+	t.Run("create, change and remove", func(t *testing.T) {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, tx.Rollback())
+		}()
+		q := tx.Querier
+
+		channel := createChannel(t, q)
+		slo, err := models.CreateSLO(q, newParams(channel.ID, channel.ID))
+		require.NoError(t, err)
+		assert.Equal(t, 0.99, slo.Percentile, "default percentile")
+		assert.Equal(t, models.ChannelIDs{channel.ID}, slo.ChannelIDs)
+
+		_, err = models.CreateSLO(q, newParams())
+		tests.AssertGRPCError(t, status.New(codes.AlreadyExists, `SLO with name "mysql latency" already exists.`), err)
+
+		err = models.RemoveChannel(q, channel.ID)
+		tests.AssertGRPCError(t, status.New(codes.FailedPrecondition, `You can't delete the "some summary" channel when it's being used by an SLO.`), err)
+
+		params := newParams()
+		params.Target = 0.999
+		changed, err := models.ChangeSLO(q, slo.ID, params)
+		require.NoError(t, err)
+		assert.Equal(t, 0.999, changed.Target)
+		assert.Empty(t, changed.ChannelIDs)
+
+		slos, err := models.FindSLOs(q)
+		require.NoError(t, err)
+		require.Len(t, slos, 1)
+		assert.Equal(t, changed, slos[0])
+
+		require.NoError(t, models.RemoveSLO(q, slo.ID))
+		_, err = models.FindSLOByID(q, slo.ID)
+		tests.AssertGRPCError(t, status.New(codes.NotFound, `SLO with ID "`+slo.ID+`" not found.`), err)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, tx.Rollback())
+		}()
+		q := tx.Querier
+
+		params := newParams()
+		params.SLIType = models.AvailabilitySLI
+		_, err = models.CreateSLO(q, params)
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Availability SLI doesn't support threshold."), err)
+
+		params = newParams()
+		params.Window = time.Hour
+		_, err = models.CreateSLO(q, params)
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "SLO window should be at least 24h0m0s."), err)
+
+		params = newParams()
+		params.Target = 1
+		_, err = models.CreateSLO(q, params)
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "SLO target should be between 0 and 1, got 1."), err)
+	})
+}
@@ -0,0 +1,96 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// SLIType represents type of service level indicator.
+type SLIType string
+
+// Available SLI types.
+const (
+	// AvailabilitySLI is a fraction of time when service is up.
+	AvailabilitySLI = SLIType("availability")
+	// LatencySLI is a fraction of time when latency percentile is not greater than threshold.
+	LatencySLI = SLIType("latency")
+	// ReplicationLagSLI is a fraction of time when replication lag is not greater than threshold.
+	ReplicationLagSLI = SLIType("replication_lag")
+)
+
+// SLO represents service level objective with error budget burn-rate alerting.
+//reform:ia_slos
+type SLO struct {
+	ID      string `reform:"id,pk"`
+	Name    string `reform:"name"`
+	Summary string `reform:"summary"`
+
+	// ServiceID and Filters select series of SLI; each selected service has own error budget.
+	ServiceID string  `reform:"service_id"`
+	Filters   Filters `reform:"filters"`
+
+	SLIType SLIType `reform:"sli_type"`
+	// Expr is a custom PromQL expression of SLI value; the default one for SLI type is used if empty.
+	Expr string `reform:"expr"`
+	// Threshold is a maximum good value of latency (seconds) and replication lag (seconds) SLIs.
+	Threshold float64 `reform:"threshold"`
+	// Percentile is a latency percentile (0.99 for p99).
+	Percentile float64 `reform:"percentile"`
+
+	// Target is a fraction of good time within the window (0.999 for 99.9%).
+	Target float64       `reform:"target"`
+	Window time.Duration `reform:"window"`
+
+	Disabled   bool       `reform:"disabled"`
+	ChannelIDs ChannelIDs `reform:"channel_ids"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *SLO) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *SLO) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *SLO) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*SLO)(nil)
+	_ reform.BeforeUpdater  = (*SLO)(nil)
+	_ reform.AfterFinder    = (*SLO)(nil)
+)
@@ -0,0 +1,163 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+// CreateTopologyEdgeParams are params for creating a new topology edge.
+type CreateTopologyEdgeParams struct {
+	SourceServiceID string
+	TargetServiceID string
+	Type            TopologyEdgeType
+	Auto            bool
+}
+
+// CreateTopologyEdge creates a new edge between two Services.
+func CreateTopologyEdge(q *reform.Querier, params CreateTopologyEdgeParams) (*TopologyEdge, error) {
+	switch params.Type {
+	case ReplicaOfEdge, ProxiedByEdge, ShardOfEdge:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown topology edge type %q.", params.Type)
+	}
+
+	if params.SourceServiceID == params.TargetServiceID {
+		return nil, status.Error(codes.InvalidArgument, "Service can't be linked to itself.")
+	}
+	for _, id := range []string{params.SourceServiceID, params.TargetServiceID} {
+		if _, err := FindServiceByID(q, id); err != nil {
+			return nil, err
+		}
+	}
+
+	edges, err := FindTopologyEdges(q, TopologyEdgeFilters{
+		SourceServiceID: params.SourceServiceID,
+		TargetServiceID: params.TargetServiceID,
+		Type:            params.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(edges) != 0 {
+		return nil, status.Errorf(codes.AlreadyExists, "Edge %s from %q to %q already exists.", params.Type, params.SourceServiceID, params.TargetServiceID)
+	}
+
+	edge := &TopologyEdge{
+		ID:              "/topology_edge_id/" + uuid.New().String(),
+		SourceServiceID: params.SourceServiceID,
+		TargetServiceID: params.TargetServiceID,
+		Type:            params.Type,
+		Auto:            params.Auto,
+	}
+	if err = q.Insert(edge); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return edge, nil
+}
+
+// RemoveTopologyEdge removes edge by ID.
+func RemoveTopologyEdge(q *reform.Querier, id string) error {
+	err := q.Delete(&TopologyEdge{ID: id})
+	if err == reform.ErrNoRows {
+		return status.Errorf(codes.NotFound, "Topology edge with ID %q not found.", id)
+	}
+	return errors.WithStack(err)
+}
+
+// TopologyEdgeFilters represents filters for topology edges.
+type TopologyEdgeFilters struct {
+	// Return only edges from that Service.
+	SourceServiceID string
+	// Return only edges to that Service.
+	TargetServiceID string
+	// Return only edges with that type.
+	Type TopologyEdgeType
+	// Return only automatically discovered edges.
+	AutoOnly bool
+}
+
+func (f TopologyEdgeFilters) tail(q *reform.Querier) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"source_service_id", f.SourceServiceID},
+		{"target_service_id", f.TargetServiceID},
+		{"type", string(f.Type)},
+	} {
+		if c.value == "" {
+			continue
+		}
+		args = append(args, c.value)
+		conditions = append(conditions, fmt.Sprintf("%s = %s", c.column, q.Placeholder(len(args))))
+	}
+	if f.AutoOnly {
+		conditions = append(conditions, "auto")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// FindTopologyEdges returns topology edges by filters in a stable order.
+func FindTopologyEdges(q *reform.Querier, filters TopologyEdgeFilters) ([]*TopologyEdge, error) {
+	tail, args := filters.tail(q)
+	structs, err := q.SelectAllFrom(TopologyEdgeTable, tail+" ORDER BY source_service_id, target_service_id, type", args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	edges := make([]*TopologyEdge, len(structs))
+	for i, s := range structs {
+		edges[i] = s.(*TopologyEdge)
+	}
+	return edges, nil
+}
+
+// ReplaceAutoTopologyEdges replaces automatically discovered edges selected by filters with new ones.
+// Edges that already exist (for example, added manually) are kept as is.
+func ReplaceAutoTopologyEdges(q *reform.Querier, filters TopologyEdgeFilters, edges []CreateTopologyEdgeParams) error {
+	filters.AutoOnly = true
+	tail, args := filters.tail(q)
+	if _, err := q.DeleteFrom(TopologyEdgeTable, tail, args...); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, params := range edges {
+		params.Auto = true
+		_, err := CreateTopologyEdge(q, params)
+		if status.Code(err) == codes.AlreadyExists {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models_test
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestTopologyEdges(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SkipFixtures, nil)
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, tx.Rollback())
+	}()
+	q := tx.Querier
+
+	for _, str := range []reform.Struct{
+		&models.Node{NodeID: "N1", NodeType: models.GenericNodeType, NodeName: "Node 1"},
+		&models.Service{ServiceID: "S1", ServiceType: models.MySQLServiceType, ServiceName: "mysql1", NodeID: "N1", Address: pointer.ToString("mysql1"), Port: pointer.ToUint16(3306)},
+		&models.Service{ServiceID: "S2", ServiceType: models.MySQLServiceType, ServiceName: "mysql2", NodeID: "N1", Address: pointer.ToString("mysql2"), Port: pointer.ToUint16(3306)},
+		&models.Service{ServiceID: "S3", ServiceType: models.ProxySQLServiceType, ServiceName: "proxysql", NodeID: "N1", Address: pointer.ToString("proxysql"), Port: pointer.ToUint16(6032)},
+	} {
+		require.NoError(t, q.Insert(str))
+	}
+
+	manual, err := models.CreateTopologyEdge(q, models.CreateTopologyEdgeParams{SourceServiceID: "S2", TargetServiceID: "S1", Type: models.ReplicaOfEdge})
+	require.NoError(t, err)
+	assert.False(t, manual.Auto)
+
+	_, err = models.CreateTopologyEdge(q, models.CreateTopologyEdgeParams{SourceServiceID: "S2", TargetServiceID: "S1", Type: models.ReplicaOfEdge})
+	tests.AssertGRPCError(t, status.New(codes.AlreadyExists, `Edge replica-of from "S2" to "S1" already exists.`), err)
+	_, err = models.CreateTopologyEdge(q, models.CreateTopologyEdgeParams{SourceServiceID: "S1", TargetServiceID: "S1", Type: models.ReplicaOfEdge})
+	tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Service can't be linked to itself."), err)
+	_, err = models.CreateTopologyEdge(q, models.CreateTopologyEdgeParams{SourceServiceID: "S1", TargetServiceID: "S2", Type: "depends-on"})
+	tests.AssertGRPCError(t, status.New(codes.InvalidArgument, `Unknown topology edge type "depends-on".`), err)
+
+	filters := models.TopologyEdgeFilters{TargetServiceID: "S3", Type: models.ProxiedByEdge}
+	err = models.ReplaceAutoTopologyEdges(q, filters, []models.CreateTopologyEdgeParams{
+		{SourceServiceID: "S1", TargetServiceID: "S3", Type: models.ProxiedByEdge},
+		{SourceServiceID: "S2", TargetServiceID: "S3", Type: models.ProxiedByEdge},
+	})
+	require.NoError(t, err)
+	edges, err := models.FindTopologyEdges(q, filters)
+	require.NoError(t, err)
+	require.Len(t, edges, 2)
+	assert.True(t, edges[0].Auto)
+
+	// mysql2 is removed from ProxySQL; manual edge is kept
+	err = models.ReplaceAutoTopologyEdges(q, filters, []models.CreateTopologyEdgeParams{
+		{SourceServiceID: "S1", TargetServiceID: "S3", Type: models.ProxiedByEdge},
+	})
+	require.NoError(t, err)
+	edges, err = models.FindTopologyEdges(q, models.TopologyEdgeFilters{})
+	require.NoError(t, err)
+	require.Len(t, edges, 2)
+	assert.Equal(t, "S1", edges[0].SourceServiceID)
+	assert.Equal(t, manual.ID, edges[1].ID)
+
+	require.NoError(t, models.RemoveTopologyEdge(q, manual.ID))
+	err = models.RemoveTopologyEdge(q, manual.ID)
+	tests.AssertGRPCError(t, status.New(codes.NotFound, `Topology edge with ID "`+manual.ID+`" not found.`), err)
+
+	// edges are removed with Service
+	_, err = q.DeleteFrom(models.ServiceTable, "WHERE service_id = $1", "S3")
+	require.NoError(t, err)
+	edges, err = models.FindTopologyEdges(q, models.TopologyEdgeFilters{})
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+}
@@ -0,0 +1,79 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// TopologyEdgeType represents type of relationship between Services.
+type TopologyEdgeType string
+
+// Topology edge types; edge is directed from source to target Service.
+const (
+	// ReplicaOfEdge means that source Service is a replica of target Service.
+	ReplicaOfEdge TopologyEdgeType = "replica-of"
+	// ProxiedByEdge means that source Service is accessed through target proxy Service (ProxySQL, HAProxy).
+	ProxiedByEdge TopologyEdgeType = "proxied-by"
+	// ShardOfEdge means that source Service is a shard of target Service (for example, MongoDB shard of mongos).
+	ShardOfEdge TopologyEdgeType = "shard-of"
+)
+
+// TopologyEdge represents a relationship between two Services.
+//reform:topology_edges
+type TopologyEdge struct {
+	ID              string           `reform:"id,pk"`
+	SourceServiceID string           `reform:"source_service_id"`
+	TargetServiceID string           `reform:"target_service_id"`
+	Type            TopologyEdgeType `reform:"type"`
+	// Auto is true for edges discovered by pmm-managed; they are replaced on every discovery.
+	Auto      bool      `reform:"auto"`
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (s *TopologyEdge) BeforeInsert() error {
+	now := Now()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (s *TopologyEdge) BeforeUpdate() error {
+	s.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (s *TopologyEdge) AfterFind() error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*TopologyEdge)(nil)
+	_ reform.BeforeUpdater  = (*TopologyEdge)(nil)
+	_ reform.AfterFinder    = (*TopologyEdge)(nil)
+)
@@ -0,0 +1,204 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/google/uuid"
+	"github.com/percona/pmm/version"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+)
+
+const (
+	defaultUpgradeWaveSize       = 10
+	defaultUpgradeMaxUnavailable = 1
+	defaultUpgradeTimeout        = 10 * time.Minute
+)
+
+// FindUpgradeCampaigns returns all pmm-agents upgrade campaigns.
+func FindUpgradeCampaigns(q *reform.Querier) ([]*UpgradeCampaign, error) {
+	rows, err := q.SelectAllFrom(UpgradeCampaignTable, "ORDER BY created_at")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select upgrade campaigns")
+	}
+
+	res := make([]*UpgradeCampaign, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*UpgradeCampaign)
+	}
+	return res, nil
+}
+
+// FindActiveUpgradeCampaigns returns running and paused pmm-agents upgrade campaigns.
+func FindActiveUpgradeCampaigns(q *reform.Querier) ([]*UpgradeCampaign, error) {
+	rows, err := q.SelectAllFrom(UpgradeCampaignTable, "WHERE status IN ($1, $2) ORDER BY created_at",
+		UpgradeCampaignRunning, UpgradeCampaignPaused)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select upgrade campaigns")
+	}
+
+	res := make([]*UpgradeCampaign, len(rows))
+	for i, r := range rows {
+		res[i] = r.(*UpgradeCampaign)
+	}
+	return res, nil
+}
+
+// FindUpgradeCampaignByID finds pmm-agents upgrade campaign by ID.
+func FindUpgradeCampaignByID(q *reform.Querier, id string) (*UpgradeCampaign, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty upgrade campaign ID.")
+	}
+
+	c := &UpgradeCampaign{ID: id}
+	switch err := q.Reload(c); err {
+	case nil:
+		return c, nil
+	case reform.ErrNoRows:
+		return nil, status.Errorf(codes.NotFound, "Upgrade campaign with ID %q not found.", id)
+	default:
+		return nil, errors.WithStack(err)
+	}
+}
+
+// CreateUpgradeCampaignParams are params for creating pmm-agents upgrade campaign.
+type CreateUpgradeCampaignParams struct {
+	TargetVersion string
+	// PMMAgents to upgrade; they are assigned to waves in this order.
+	PMMAgents []*Agent
+	// Zero values are replaced with defaults.
+	WaveSize       int
+	MaxUnavailable int
+	MaxFailures    int
+	Timeout        time.Duration
+}
+
+// CreateUpgradeCampaign validates params and persists running pmm-agents upgrade campaign.
+func CreateUpgradeCampaign(q *reform.Querier, params *CreateUpgradeCampaignParams) (*UpgradeCampaign, error) {
+	if _, err := version.Parse(params.TargetVersion); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid target version %q.", params.TargetVersion)
+	}
+	if len(params.PMMAgents) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "No pmm-agents to upgrade.")
+	}
+	if params.WaveSize < 0 || params.MaxUnavailable < 0 || params.MaxFailures < 0 || params.Timeout < 0 {
+		return nil, status.Error(codes.InvalidArgument, "Wave size, max unavailable, max failures and timeout should not be negative.")
+	}
+
+	active, err := FindActiveUpgradeCampaigns(q)
+	if err != nil {
+		return nil, err
+	}
+	busy := make(map[string]string)
+	for _, c := range active {
+		for _, a := range c.Agents {
+			busy[a.PMMAgentID] = c.ID
+		}
+	}
+
+	row := &UpgradeCampaign{
+		ID:             "/upgrade_campaign_id/" + uuid.New().String(),
+		TargetVersion:  params.TargetVersion,
+		WaveSize:       params.WaveSize,
+		MaxUnavailable: params.MaxUnavailable,
+		MaxFailures:    params.MaxFailures,
+		Timeout:        params.Timeout,
+		Status:         UpgradeCampaignRunning,
+		Agents:         make(UpgradeCampaignAgents, len(params.PMMAgents)),
+	}
+	if row.WaveSize == 0 {
+		row.WaveSize = defaultUpgradeWaveSize
+	}
+	if row.MaxUnavailable == 0 {
+		row.MaxUnavailable = defaultUpgradeMaxUnavailable
+	}
+	if row.Timeout == 0 {
+		row.Timeout = defaultUpgradeTimeout
+	}
+
+	for i, a := range params.PMMAgents {
+		if a.AgentType != PMMAgentType {
+			return nil, status.Errorf(codes.InvalidArgument, "Agent %q is not a pmm-agent.", a.AgentID)
+		}
+		if id := busy[a.AgentID]; id != "" {
+			return nil, status.Errorf(codes.FailedPrecondition, "pmm-agent %q is already upgraded by campaign %q.", a.AgentID, id)
+		}
+		row.Agents[i] = &UpgradeCampaignAgent{
+			PMMAgentID:  a.AgentID,
+			Wave:        i / row.WaveSize,
+			Status:      UpgradeAgentPending,
+			FromVersion: pointer.GetString(a.Version),
+		}
+	}
+
+	if err = q.Insert(row); err != nil {
+		return nil, errors.Wrap(err, "failed to create upgrade campaign")
+	}
+	return row, nil
+}
+
+// SetUpgradeCampaignStatus changes status of pmm-agents upgrade campaign.
+// Only running campaigns can be paused, and only paused campaigns can be resumed;
+// completed and canceled campaigns can't be changed. Failed pmm-agents are retried when campaign is resumed.
+func SetUpgradeCampaignStatus(q *reform.Querier, id string, s UpgradeCampaignStatus, reason string) (*UpgradeCampaign, error) {
+	row, err := FindUpgradeCampaignByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case row.Status == UpgradeCampaignCompleted || row.Status == UpgradeCampaignCanceled:
+		return nil, status.Errorf(codes.FailedPrecondition, "Upgrade campaign %q is %s.", id, row.Status)
+	case s == UpgradeCampaignPaused && row.Status != UpgradeCampaignRunning:
+		return nil, status.Errorf(codes.FailedPrecondition, "Upgrade campaign %q is not running.", id)
+	case s == UpgradeCampaignRunning && row.Status != UpgradeCampaignPaused:
+		return nil, status.Errorf(codes.FailedPrecondition, "Upgrade campaign %q is not paused.", id)
+	}
+
+	if s == UpgradeCampaignRunning {
+		for _, a := range row.Agents {
+			if a.Status == UpgradeAgentFailed {
+				a.Status = UpgradeAgentPending
+				a.Error = ""
+				a.StartedAt = nil
+				a.FetchedAt = nil
+				a.FinishedAt = nil
+			}
+		}
+	}
+
+	row.Status = s
+	row.PauseReason = reason
+	if err = q.Update(row); err != nil {
+		return nil, errors.Wrap(err, "failed to change upgrade campaign")
+	}
+	return row, nil
+}
+
+// UpgradeCampaignStats returns the number of campaign's pmm-agents by upgrade status.
+func UpgradeCampaignStats(c *UpgradeCampaign) map[UpgradeAgentStatus]int {
+	res := make(map[UpgradeAgentStatus]int)
+	for _, a := range c.Agents {
+		res[a.Status]++
+	}
+	return res
+}
@@ -0,0 +1,124 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"gopkg.in/reform.v1"
+)
+
+//go:generate reform
+
+// UpgradeCampaignStatus represents pmm-agents upgrade campaign status.
+type UpgradeCampaignStatus string
+
+// Upgrade campaign statuses.
+const (
+	UpgradeCampaignRunning   = UpgradeCampaignStatus("running")
+	UpgradeCampaignPaused    = UpgradeCampaignStatus("paused")
+	UpgradeCampaignCompleted = UpgradeCampaignStatus("completed")
+	UpgradeCampaignCanceled  = UpgradeCampaignStatus("canceled")
+)
+
+// UpgradeAgentStatus represents upgrade status of a single pmm-agent.
+type UpgradeAgentStatus string
+
+// pmm-agent upgrade statuses.
+const (
+	UpgradeAgentPending   = UpgradeAgentStatus("pending")
+	UpgradeAgentUpgrading = UpgradeAgentStatus("upgrading")
+	UpgradeAgentSucceeded = UpgradeAgentStatus("succeeded")
+	UpgradeAgentFailed    = UpgradeAgentStatus("failed")
+	// UpgradeAgentSkipped is used for pmm-agents removed during the campaign.
+	UpgradeAgentSkipped = UpgradeAgentStatus("skipped")
+)
+
+// UpgradeCampaignAgent represents a single pmm-agent upgraded by the campaign.
+type UpgradeCampaignAgent struct {
+	PMMAgentID  string             `json:"pmm_agent_id"`
+	Wave        int                `json:"wave"`
+	Status      UpgradeAgentStatus `json:"status"`
+	FromVersion string             `json:"from_version"`
+	Error       string             `json:"error,omitempty"`
+	StartedAt   *time.Time         `json:"started_at,omitempty"`
+	// FetchedAt is set when pmm-agent fetches the upgrade request.
+	FetchedAt  *time.Time `json:"fetched_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// UpgradeCampaignAgents represents pmm-agents upgraded by the campaign.
+type UpgradeCampaignAgents []*UpgradeCampaignAgent
+
+// Value implements database/sql/driver Valuer interface.
+func (a UpgradeCampaignAgents) Value() (driver.Value, error) { return jsonValue(a) }
+
+// Scan implements database/sql Scanner interface.
+func (a *UpgradeCampaignAgents) Scan(src interface{}) error { return jsonScan(a, src) }
+
+// UpgradeCampaign represents pmm-agents upgrade rolled out in waves.
+//
+//reform:upgrade_campaigns
+type UpgradeCampaign struct {
+	ID            string `reform:"id,pk"`
+	TargetVersion string `reform:"target_version"`
+
+	// WaveSize is the number of pmm-agents in a wave; the next wave starts when the previous one succeeds.
+	WaveSize int `reform:"wave_size"`
+	// MaxUnavailable is the maximal number of pmm-agents upgraded at the same time.
+	MaxUnavailable int `reform:"max_unavailable"`
+	// MaxFailures is the number of failed pmm-agents upgrades tolerated before the campaign is paused.
+	MaxFailures int `reform:"max_failures"`
+	// Timeout is the maximal time for pmm-agent to reconnect with the target version and become healthy.
+	Timeout time.Duration `reform:"timeout"`
+
+	Status      UpgradeCampaignStatus `reform:"status"`
+	PauseReason string                `reform:"pause_reason"`
+	Agents      UpgradeCampaignAgents `reform:"agents"`
+
+	CreatedAt time.Time `reform:"created_at"`
+	UpdatedAt time.Time `reform:"updated_at"`
+}
+
+// BeforeInsert implements reform.BeforeInserter interface.
+func (c *UpgradeCampaign) BeforeInsert() error {
+	now := Now()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	return nil
+}
+
+// BeforeUpdate implements reform.BeforeUpdater interface.
+func (c *UpgradeCampaign) BeforeUpdate() error {
+	c.UpdatedAt = Now()
+	return nil
+}
+
+// AfterFind implements reform.AfterFinder interface.
+func (c *UpgradeCampaign) AfterFind() error {
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.UpdatedAt = c.UpdatedAt.UTC()
+	return nil
+}
+
+// check interfaces.
+var (
+	_ reform.BeforeInserter = (*UpgradeCampaign)(nil)
+	_ reform.BeforeUpdater  = (*UpgradeCampaign)(nil)
+	_ reform.AfterFinder    = (*UpgradeCampaign)(nil)
+)
@@ -0,0 +1,74 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+)
+
+// actionResultCheckInterval is an interval of action result polling in RunAction.
+const actionResultCheckInterval = time.Second
+
+// RunAction creates action result for the given pmm-agent, starts action with the given function,
+// and waits up to timeout for action to complete. It returns action output; action result is removed.
+func RunAction(ctx context.Context, db *reform.DB, pmmAgentID string, timeout time.Duration, start func(actionID string) error) ([]byte, error) {
+	r, err := models.CreateActionResult(db.Querier, pmmAgentID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := db.Delete(r); err != nil {
+			logger.Get(ctx).Warnf("Failed to delete action result %s: %s.", r.ID, err)
+		}
+	}()
+
+	if err = start(r.ID); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(actionResultCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		}
+
+		res, err := models.FindActionResultByID(db.Querier, r.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !res.Done {
+			continue
+		}
+		if res.Error != "" {
+			return nil, errors.Errorf("action %s failed: %s", r.ID, res.Error)
+		}
+		return []byte(res.Output), nil
+	}
+}
@@ -46,7 +46,7 @@ func NewActionsService(r *Registry) *ActionsService {
 
 // StartMySQLExplainAction starts MySQL EXPLAIN Action on pmm-agent.
 func (s *ActionsService) StartMySQLExplainAction(ctx context.Context, id, pmmAgentID, dsn, query string, format agentpb.MysqlExplainOutputFormat, files map[string]string, tdp *models.DelimiterPair, tlsSkipVerify bool) error {
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
@@ -69,7 +69,7 @@ func (s *ActionsService) StartMySQLExplainAction(ctx context.Context, id, pmmAge
 		Timeout: defaultActionTimeout,
 	}
 
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -92,11 +92,11 @@ func (s *ActionsService) StartMySQLShowCreateTableAction(ctx context.Context, id
 		Timeout: defaultActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -119,11 +119,11 @@ func (s *ActionsService) StartMySQLShowTableStatusAction(ctx context.Context, id
 		Timeout: defaultActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -146,11 +146,11 @@ func (s *ActionsService) StartMySQLShowIndexAction(ctx context.Context, id, pmmA
 		Timeout: defaultActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -167,11 +167,11 @@ func (s *ActionsService) StartPostgreSQLShowCreateTableAction(ctx context.Contex
 		Timeout: defaultActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -188,11 +188,11 @@ func (s *ActionsService) StartPostgreSQLShowIndexAction(ctx context.Context, id,
 		Timeout: defaultActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -214,11 +214,11 @@ func (s *ActionsService) StartMongoDBExplainAction(ctx context.Context, id, pmmA
 		Timeout: defaultActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -241,11 +241,11 @@ func (s *ActionsService) StartMySQLQueryShowAction(ctx context.Context, id, pmmA
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -268,11 +268,11 @@ func (s *ActionsService) StartMySQLQuerySelectAction(ctx context.Context, id, pm
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -288,11 +288,11 @@ func (s *ActionsService) StartPostgreSQLQueryShowAction(ctx context.Context, id,
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -309,11 +309,11 @@ func (s *ActionsService) StartPostgreSQLQuerySelectAction(ctx context.Context, i
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -334,11 +334,11 @@ func (s *ActionsService) StartMongoDBQueryGetParameterAction(ctx context.Context
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -359,11 +359,11 @@ func (s *ActionsService) StartMongoDBQueryBuildInfoAction(ctx context.Context, i
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -384,11 +384,11 @@ func (s *ActionsService) StartMongoDBQueryGetCmdLineOptsAction(ctx context.Conte
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -409,11 +409,11 @@ func (s *ActionsService) StartMongoDBQueryReplSetGetStatusAction(ctx context.Con
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -434,11 +434,11 @@ func (s *ActionsService) StartMongoDBQueryGetDiagnosticDataAction(ctx context.Co
 		Timeout: defaultQueryActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -453,11 +453,11 @@ func (s *ActionsService) StartPTSummaryAction(ctx context.Context, id, pmmAgentI
 		Timeout: defaultPtActionTimeout,
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(aRequest)
+	_, err = agent.SendAndWaitResponse(aRequest)
 	return err
 }
 
@@ -476,11 +476,11 @@ func (s *ActionsService) StartPTPgSummaryAction(ctx context.Context, id, pmmAgen
 		Timeout: defaultPtActionTimeout,
 	}
 
-	pmmAgent, err := s.r.get(pmmAgentID)
+	pmmAgent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = pmmAgent.channel.SendAndWaitResponse(actionRequest)
+	_, err = pmmAgent.SendAndWaitResponse(actionRequest)
 	return err
 }
 
@@ -502,11 +502,11 @@ func (s *ActionsService) StartPTMongoDBSummaryAction(ctx context.Context, id, pm
 	}
 
 	// Agent which the action request will be sent to, got by the provided ID
-	pmmAgent, err := s.r.get(pmmAgentID)
+	pmmAgent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = pmmAgent.channel.SendAndWaitResponse(actionRequest)
+	_, err = pmmAgent.SendAndWaitResponse(actionRequest)
 	return err
 }
 
@@ -528,21 +528,21 @@ func (s *ActionsService) StartPTMySQLSummaryAction(ctx context.Context, id, pmmA
 		Timeout: defaultPtActionTimeout,
 	}
 
-	pmmAgent, err := s.r.get(pmmAgentID)
+	pmmAgent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
-	_, err = pmmAgent.channel.SendAndWaitResponse(actionRequest)
+	_, err = pmmAgent.SendAndWaitResponse(actionRequest)
 	return err
 }
 
 // StopAction stops action with given given id.
 func (s *ActionsService) StopAction(ctx context.Context, actionID string) error {
 	// TODO Seems that we have a bug here, we passing actionID to the method that expects pmmAgentID
-	agent, err := s.r.get(actionID)
+	agent, err := s.r.getChannel(actionID)
 	if err != nil {
 		return err
 	}
-	_, err = agent.channel.SendAndWaitResponse(&agentpb.StopActionRequest{ActionId: actionID})
+	_, err = agent.SendAndWaitResponse(&agentpb.StopActionRequest{ActionId: actionID})
 	return err
 }
@@ -77,7 +77,7 @@ func (c *ConnectionChecker) CheckConnectionToService(ctx context.Context, q *ref
 		}
 	}
 
-	pmmAgent, err := c.r.get(pmmAgentID)
+	pmmAgent, err := c.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
@@ -92,7 +92,7 @@ func (c *ConnectionChecker) CheckConnectionToService(ctx context.Context, q *ref
 		sanitizedDSN = strings.ReplaceAll(request.Dsn, word, "****")
 	}
 	l.Infof("CheckConnectionRequest: type: %s, DSN: %s timeout: %s.", request.Type, sanitizedDSN, request.Timeout)
-	resp, err := pmmAgent.channel.SendAndWaitResponse(request)
+	resp, err := pmmAgent.SendAndWaitResponse(request)
 	if err != nil {
 		return err
 	}
@@ -18,6 +18,7 @@ package agents
 
 import (
 	"context"
+	"crypto/tls"
 
 	"github.com/percona/pmm/api/agentpb"
 	"github.com/sirupsen/logrus"
@@ -32,10 +33,10 @@ type prometheusService interface {
 	BuildScrapeConfigForVMAgent(pmmAgentID string) ([]byte, error)
 }
 
-// qanClient is a subset of methods of qan.Client used by this package.
+// qanClient is a subset of methods of qan.Forwarder used by this package.
 // We use it instead of real type to avoid dependency cycle.
 type qanClient interface {
-	Collect(ctx context.Context, metricsBuckets []*agentpb.MetricsBucket) error
+	Enqueue(ctx context.Context, metricsBuckets []*agentpb.MetricsBucket) error
 }
 
 // retentionService is a subset of methods of backup.Client used by this package.
@@ -50,3 +51,11 @@ type jobsService interface {
 	handleJobResult(ctx context.Context, l *logrus.Entry, result *agentpb.JobResult)
 	handleJobProgress(ctx context.Context, progress *agentpb.JobProgress)
 }
+
+// haService is a subset of methods of ha.Service used by this package.
+// We use it instead of real type for testing and to avoid dependency cycle.
+type haService interface {
+	Enabled() bool
+	InstanceID() string
+	ClientTLSConfig() *tls.Config
+}
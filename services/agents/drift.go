@@ -0,0 +1,378 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/percona/pmm/api/inventorypb"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	driftCheckInterval = 5 * time.Minute
+
+	// driftGracePeriod is the time given to pmm-agent to apply sent configuration and report Agents state.
+	driftGracePeriod = time.Minute
+)
+
+// sentAgentConfig represents Agent configuration acknowledged by pmm-agent.
+type sentAgentConfig struct {
+	agentType string
+	config    proto.Message
+	args      []string // with secrets masked
+	sentAt    time.Time
+
+	// set when configuration is changed after it was sent the first time
+	changedAt   time.Time
+	addedArgs   []string
+	removedArgs []string
+}
+
+// reportedAgentState represents Agent state reported by pmm-agent.
+type reportedAgentState struct {
+	status          inventorypb.AgentStatus
+	listenPort      uint32
+	processExecPath string
+	startedAt       time.Time
+	reportedAt      time.Time
+}
+
+// driftState keeps Agents configuration acknowledged by a single connected pmm-agent
+// and Agents state reported by it since connection.
+type driftState struct {
+	rw       sync.Mutex
+	sent     map[string]*sentAgentConfig
+	reported map[string]*reportedAgentState
+}
+
+func newDriftState() *driftState {
+	return &driftState{
+		reported: make(map[string]*reportedAgentState),
+	}
+}
+
+// setSent records state acknowledged by pmm-agent. Secrets in state should be listed in RedactWords.
+func (s *driftState) setSent(state *agentpb.SetStateRequest, now time.Time) {
+	configs := make(map[string]*sentAgentConfig, len(state.AgentProcesses)+len(state.BuiltinAgents))
+	for id, p := range state.AgentProcesses {
+		args := make([]string, len(p.Args))
+		for i, arg := range p.Args {
+			for _, word := range p.RedactWords {
+				arg = strings.ReplaceAll(arg, word, "***")
+			}
+			args[i] = arg
+		}
+		configs[id] = &sentAgentConfig{
+			agentType: p.Type.String(),
+			config:    p,
+			args:      args,
+		}
+	}
+	for id, b := range state.BuiltinAgents {
+		configs[id] = &sentAgentConfig{
+			agentType: b.Type.String(),
+			config:    b,
+			// DSN is not included as it contains password
+			args: []string{
+				fmt.Sprintf("disable_query_examples=%t", b.DisableQueryExamples),
+				fmt.Sprintf("max_query_log_size=%d", b.MaxQueryLogSize),
+				fmt.Sprintf("tls=%t", b.Tls),
+				fmt.Sprintf("tls_skip_verify=%t", b.TlsSkipVerify),
+			},
+		}
+	}
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for id, c := range configs {
+		prev := s.sent[id]
+		switch {
+		case prev == nil:
+			c.sentAt = now
+		case proto.Equal(prev.config, c.config):
+			c.sentAt = prev.sentAt
+			c.changedAt = prev.changedAt
+			c.addedArgs = prev.addedArgs
+			c.removedArgs = prev.removedArgs
+		default:
+			c.sentAt = prev.sentAt
+			c.changedAt = now
+			c.addedArgs, c.removedArgs = diffArgs(prev.args, c.args)
+		}
+	}
+	s.sent = configs
+}
+
+// setReported records Agent state reported by pmm-agent.
+func (s *driftState) setReported(req *agentpb.StateChangedRequest, now time.Time) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if req.Status == inventorypb.AgentStatus_DONE {
+		delete(s.reported, req.AgentId)
+		return
+	}
+
+	r := s.reported[req.AgentId]
+	if r == nil {
+		r = new(reportedAgentState)
+		s.reported[req.AgentId] = r
+	}
+	r.status = req.Status
+	r.listenPort = req.ListenPort
+	r.processExecPath = req.ProcessExecPath
+	r.reportedAt = now
+	if req.Status == inventorypb.AgentStatus_STARTING {
+		r.startedAt = now
+	}
+}
+
+// drift returns Agents with drift. Differences younger than driftGracePeriod are ignored.
+func (s *driftState) drift(now time.Time) []*models.AgentDrift {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if s.sent == nil {
+		// state was not acknowledged yet
+		return nil
+	}
+
+	expired := func(t time.Time) bool { return now.Sub(t) > driftGracePeriod }
+
+	var res []*models.AgentDrift
+	for id, c := range s.sent {
+		r := s.reported[id]
+		d := &models.AgentDrift{
+			AgentID:   id,
+			AgentType: c.agentType,
+		}
+		if r != nil {
+			d.Status = r.status.String()
+			d.ListenPort = r.listenPort
+			d.ProcessExecPath = r.processExecPath
+			d.StartedAt = r.startedAt
+			d.ReportedAt = r.reportedAt
+		}
+
+		switch {
+		case r == nil:
+			if !expired(c.sentAt) {
+				continue
+			}
+			d.Kind = models.AgentNotReported
+
+		case r.status != inventorypb.AgentStatus_RUNNING:
+			if !expired(r.reportedAt) {
+				continue
+			}
+			d.Kind = models.AgentNotRunning
+
+		case !c.changedAt.IsZero() && r.startedAt.Before(c.changedAt):
+			if !expired(c.changedAt) {
+				continue
+			}
+			d.Kind = models.AgentStaleConfig
+			d.ChangedAt = c.changedAt
+			d.AddedArgs = c.addedArgs
+			d.RemovedArgs = c.removedArgs
+
+		default:
+			continue
+		}
+		res = append(res, d)
+	}
+
+	for id, r := range s.reported {
+		if s.sent[id] != nil || !expired(r.reportedAt) {
+			continue
+		}
+		res = append(res, &models.AgentDrift{
+			AgentID:         id,
+			Kind:            models.AgentUnexpected,
+			Status:          r.status.String(),
+			ListenPort:      r.listenPort,
+			ProcessExecPath: r.processExecPath,
+			StartedAt:       r.startedAt,
+			ReportedAt:      r.reportedAt,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].AgentID < res[j].AgentID })
+	return res
+}
+
+// diffArgs returns arguments present only in next and only in prev lists.
+func diffArgs(prev, next []string) (added, removed []string) {
+	counts := make(map[string]int, len(prev))
+	for _, arg := range prev {
+		counts[arg]++
+	}
+	for _, arg := range next {
+		if counts[arg] > 0 {
+			counts[arg]--
+			continue
+		}
+		added = append(added, arg)
+	}
+	for _, arg := range prev {
+		if counts[arg] > 0 {
+			counts[arg]--
+			removed = append(removed, arg)
+		}
+	}
+	return
+}
+
+// DriftDetector compares Agents configuration acknowledged by pmm-agents connected to this pmm-managed instance
+// with Agents state reported by them: pmm-agent reports Agent start after configuration change,
+// so configuration not followed by restart was not applied.
+type DriftDetector struct {
+	r        *Registry
+	state    *StateUpdater
+	autoHeal bool
+	l        *logrus.Entry
+
+	mDrifted *prom.GaugeVec
+}
+
+// NewDriftDetector creates new DriftDetector. If autoHeal is true, Agents with drift are restarted.
+func NewDriftDetector(registry *Registry, state *StateUpdater, autoHeal bool) *DriftDetector {
+	return &DriftDetector{
+		r:        registry,
+		state:    state,
+		autoHeal: autoHeal,
+		l:        logrus.WithField("component", "agents/drift"),
+		mDrifted: prom.NewGaugeVec(prom.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "config_drift",
+			Help:      "The number of Agents with configuration drift per pmm-agent.",
+		}, []string{"pmm_agent_id"}),
+	}
+}
+
+// Run checks connected pmm-agents periodically until ctx is canceled.
+func (d *DriftDetector) Run(ctx context.Context) {
+	d.l.Info("Starting...")
+	defer d.l.Info("Done.")
+
+	ticker := time.NewTicker(driftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		d.checkAll(ctx)
+	}
+}
+
+// checkAll checks connected pmm-agents, updates metrics, and heals drift if enabled.
+func (d *DriftDetector) checkAll(ctx context.Context) {
+	d.r.rw.RLock()
+	pmmAgents := make([]*pmmAgentInfo, 0, len(d.r.agents))
+	for _, agent := range d.r.agents {
+		pmmAgents = append(pmmAgents, agent)
+	}
+	d.r.rw.RUnlock()
+
+	d.mDrifted.Reset()
+	now := time.Now()
+	for _, agent := range pmmAgents {
+		drift := agent.drift.drift(now)
+		d.mDrifted.WithLabelValues(agent.id).Set(float64(len(drift)))
+		if len(drift) == 0 {
+			continue
+		}
+
+		d.l.Warnf("pmm-agent %s: %d Agents with configuration drift.", agent.id, len(drift))
+		if d.autoHeal {
+			if err := d.heal(ctx, agent, drift); err != nil {
+				d.l.Warnf("Failed to heal drift of %s: %s.", agent.id, err)
+			}
+		}
+	}
+}
+
+// CheckDrift returns configuration drift of Agents of given pmm-agent.
+// It returns FailedPrecondition error if pmm-agent is not connected to this pmm-managed instance.
+func (d *DriftDetector) CheckDrift(ctx context.Context, pmmAgentID string) ([]*models.AgentDrift, error) {
+	agent, err := d.r.get(pmmAgentID)
+	if err != nil {
+		return nil, err
+	}
+	return agent.drift.drift(time.Now()), nil
+}
+
+// Heal restarts Agents with drift of given pmm-agent and stops unexpected ones.
+func (d *DriftDetector) Heal(ctx context.Context, pmmAgentID string) error {
+	agent, err := d.r.get(pmmAgentID)
+	if err != nil {
+		return err
+	}
+	return d.heal(ctx, agent, agent.drift.drift(time.Now()))
+}
+
+func (d *DriftDetector) heal(ctx context.Context, agent *pmmAgentInfo, drift []*models.AgentDrift) error {
+	// pmm-agent stops Agents missing in the state, and starts them again with the full state
+	restart := make(map[string]struct{}, len(drift))
+	for _, a := range drift {
+		if a.Kind != models.AgentUnexpected {
+			restart[a.AgentID] = struct{}{}
+		}
+	}
+	if len(restart) != 0 {
+		nCtx, cancel := context.WithTimeout(ctx, stateChangeTimeout)
+		defer cancel()
+		if err := d.state.sendSetStateRequest(nCtx, agent, restart); err != nil {
+			return err
+		}
+	}
+
+	d.state.RequestStateUpdate(ctx, agent.id)
+	return nil
+}
+
+// Describe implements prometheus.Collector.
+func (d *DriftDetector) Describe(ch chan<- *prom.Desc) {
+	d.mDrifted.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (d *DriftDetector) Collect(ch chan<- prom.Metric) {
+	d.mDrifted.Collect(ch)
+}
+
+// check interfaces.
+var (
+	_ prom.Collector = (*DriftDetector)(nil)
+)
@@ -0,0 +1,168 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/percona/pmm/api/inventorypb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestDriftState(t *testing.T) {
+	t.Parallel()
+
+	const exporterID, qanID = "/agent_id/exporter", "/agent_id/qan"
+	state := func(args ...string) *agentpb.SetStateRequest {
+		return &agentpb.SetStateRequest{
+			AgentProcesses: map[string]*agentpb.SetStateRequest_AgentProcess{
+				exporterID: {
+					Type:        inventorypb.AgentType_MYSQLD_EXPORTER,
+					Args:        append([]string{"--mysqld.password=s3cr3t"}, args...),
+					RedactWords: []string{"s3cr3t"},
+				},
+			},
+			BuiltinAgents: map[string]*agentpb.SetStateRequest_BuiltinAgent{
+				qanID: {
+					Type: inventorypb.AgentType_QAN_MYSQL_PERFSCHEMA_AGENT,
+					Dsn:  "root:s3cr3t@tcp(127.0.0.1:3306)/",
+				},
+			},
+		}
+	}
+	report := func(s *driftState, id string, status inventorypb.AgentStatus, now time.Time) {
+		s.setReported(&agentpb.StateChangedRequest{
+			AgentId:         id,
+			Status:          status,
+			ListenPort:      42000,
+			ProcessExecPath: "/usr/local/percona/pmm2/exporters/mysqld_exporter",
+		}, now)
+	}
+
+	start := time.Now()
+	later := start.Add(driftGracePeriod + time.Second)
+
+	t.Run("NotAcknowledged", func(t *testing.T) {
+		t.Parallel()
+
+		s := newDriftState()
+		report(s, exporterID, inventorypb.AgentStatus_RUNNING, start)
+		assert.Empty(t, s.drift(later))
+	})
+
+	t.Run("NotReported", func(t *testing.T) {
+		t.Parallel()
+
+		s := newDriftState()
+		s.setSent(state("--collect.info_schema.tables"), start)
+		report(s, qanID, inventorypb.AgentStatus_RUNNING, start)
+		assert.Empty(t, s.drift(start), "grace period")
+
+		drift := s.drift(later)
+		require.Len(t, drift, 1)
+		assert.Equal(t, exporterID, drift[0].AgentID)
+		assert.Equal(t, "MYSQLD_EXPORTER", drift[0].AgentType)
+		assert.Equal(t, models.AgentNotReported, drift[0].Kind)
+	})
+
+	t.Run("NotRunning", func(t *testing.T) {
+		t.Parallel()
+
+		s := newDriftState()
+		s.setSent(state(), start)
+		report(s, exporterID, inventorypb.AgentStatus_STARTING, start)
+		report(s, exporterID, inventorypb.AgentStatus_WAITING, start)
+		report(s, qanID, inventorypb.AgentStatus_RUNNING, start)
+
+		drift := s.drift(later)
+		require.Len(t, drift, 1)
+		assert.Equal(t, models.AgentNotRunning, drift[0].Kind)
+		assert.Equal(t, "WAITING", drift[0].Status)
+		assert.Equal(t, uint32(42000), drift[0].ListenPort)
+		assert.Equal(t, start, drift[0].StartedAt)
+	})
+
+	t.Run("StaleConfig", func(t *testing.T) {
+		t.Parallel()
+
+		s := newDriftState()
+		s.setSent(state("--collect.info_schema.tables"), start)
+		report(s, exporterID, inventorypb.AgentStatus_STARTING, start)
+		report(s, exporterID, inventorypb.AgentStatus_RUNNING, start)
+		report(s, qanID, inventorypb.AgentStatus_RUNNING, start)
+
+		// the same configuration is sent again
+		s.setSent(state("--collect.info_schema.tables"), later)
+		assert.Empty(t, s.drift(later.Add(driftGracePeriod+time.Second)))
+
+		// configuration is changed, but pmm-agent does not restart exporter
+		changed := later.Add(time.Second)
+		s.setSent(state("--collect.perf_schema.eventsstatements"), changed)
+		assert.Empty(t, s.drift(changed), "grace period")
+
+		drift := s.drift(changed.Add(driftGracePeriod + time.Second))
+		require.Len(t, drift, 1)
+		assert.Equal(t, models.AgentStaleConfig, drift[0].Kind)
+		assert.Equal(t, changed, drift[0].ChangedAt)
+		assert.Equal(t, []string{"--collect.perf_schema.eventsstatements"}, drift[0].AddedArgs)
+		assert.Equal(t, []string{"--collect.info_schema.tables"}, drift[0].RemovedArgs)
+
+		// drift is kept when the same configuration is sent again
+		s.setSent(state("--collect.perf_schema.eventsstatements"), changed.Add(time.Minute))
+		assert.Len(t, s.drift(changed.Add(driftGracePeriod+time.Second)), 1)
+
+		// restart applies configuration
+		report(s, exporterID, inventorypb.AgentStatus_STARTING, changed.Add(time.Second))
+		report(s, exporterID, inventorypb.AgentStatus_RUNNING, changed.Add(time.Second))
+		assert.Empty(t, s.drift(changed.Add(2*driftGracePeriod)))
+	})
+
+	t.Run("Unexpected", func(t *testing.T) {
+		t.Parallel()
+
+		s := newDriftState()
+		s.setSent(state(), start)
+		report(s, exporterID, inventorypb.AgentStatus_RUNNING, start)
+		report(s, qanID, inventorypb.AgentStatus_RUNNING, start)
+
+		// Agent removed, but pmm-agent keeps running it
+		noQAN := state()
+		noQAN.BuiltinAgents = nil
+		s.setSent(noQAN, start)
+		drift := s.drift(later)
+		require.Len(t, drift, 1)
+		assert.Equal(t, qanID, drift[0].AgentID)
+		assert.Equal(t, models.AgentUnexpected, drift[0].Kind)
+		assert.Equal(t, "RUNNING", drift[0].Status)
+
+		report(s, qanID, inventorypb.AgentStatus_DONE, start)
+		assert.Empty(t, s.drift(later))
+	})
+}
+
+func TestDiffArgs(t *testing.T) {
+	t.Parallel()
+
+	added, removed := diffArgs([]string{"--a", "--b", "--b"}, []string{"--b", "--c"})
+	assert.Equal(t, []string{"--c"}, added)
+	assert.Equal(t, []string{"--a", "--b"}, removed)
+}
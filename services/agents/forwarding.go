@@ -0,0 +1,288 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+)
+
+// Paths of HTTP API used by pmm-managed instances to forward requests to pmm-agents connected to other instances.
+const (
+	forwardSendRequestPath = "/v1/internal/agents/SendRequest"
+	forwardStateUpdatePath = "/v1/internal/agents/RequestStateUpdate"
+	forwardKickPath        = "/v1/internal/agents/Kick"
+
+	forwardContentType = "application/x-protobuf"
+	forwardTimeout     = 2 * time.Minute
+)
+
+// agentChannel sends requests to pmm-agent and waits for responses.
+// It is implemented by channel.Channel for pmm-agents connected to this pmm-managed instance,
+// and by remoteChannel for pmm-agents connected to other instances.
+type agentChannel interface {
+	SendAndWaitResponse(payload agentpb.ServerRequestPayload) (agentpb.AgentResponsePayload, error)
+}
+
+// remoteChannel forwards requests to pmm-agent connected to another pmm-managed instance.
+type remoteChannel struct {
+	r          *Registry
+	instance   *models.HAInstance
+	pmmAgentID string
+}
+
+// SendAndWaitResponse implements agentChannel interface.
+func (c *remoteChannel) SendAndWaitResponse(payload agentpb.ServerRequestPayload) (agentpb.AgentResponsePayload, error) {
+	b, err := proto.Marshal(&agentpb.ServerMessage{Payload: payload.ServerMessageRequestPayload()})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), forwardTimeout)
+	defer cancel()
+	b, err = c.r.forward(ctx, c.instance, forwardSendRequestPath, c.pmmAgentID, b)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg agentpb.AgentMessage
+	if err = proto.Unmarshal(b, &msg); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal forwarded response")
+	}
+	if s := status.FromProto(msg.Status); s.Code() != codes.OK {
+		return nil, s.Err()
+	}
+
+	resp, _ := unwrapPayload(&msg).(agentpb.AgentResponsePayload)
+	if resp == nil {
+		return nil, errors.Errorf("unexpected forwarded response %s", &msg)
+	}
+	return resp, nil
+}
+
+// unwrapPayload returns message of the set payload oneof field of agentpb.ServerMessage or agentpb.AgentMessage.
+func unwrapPayload(m proto.Message) proto.Message {
+	rm := m.ProtoReflect()
+	oneof := rm.Descriptor().Oneofs().ByName(protoreflect.Name("payload"))
+	if oneof == nil {
+		return nil
+	}
+	fd := rm.WhichOneof(oneof)
+	if fd == nil {
+		return nil
+	}
+	return rm.Get(fd).Message().Interface()
+}
+
+// remoteChannel returns channel to pmm-agent connected to another live pmm-managed instance.
+func (r *Registry) remoteChannel(pmmAgentID string) (*remoteChannel, error) {
+	instance, err := models.FindPMMAgentOwner(r.db.Querier, pmmAgentID)
+	if err != nil {
+		return nil, err
+	}
+	if instance.ID == r.ha.InstanceID() {
+		// ownership record is stale: pmm-agent is not connected to this instance anymore
+		return nil, status.Errorf(codes.NotFound, "pmm-agent with ID %q is not connected to this instance.", pmmAgentID)
+	}
+
+	return &remoteChannel{
+		r:          r,
+		instance:   instance,
+		pmmAgentID: pmmAgentID,
+	}, nil
+}
+
+// forward sends request to another pmm-managed instance and returns response body.
+func (r *Registry) forward(ctx context.Context, instance *models.HAInstance, path, pmmAgentID string, body []byte) ([]byte, error) {
+	u := url.URL{
+		Scheme:   r.forwardScheme,
+		Host:     instance.Address,
+		Path:     path,
+		RawQuery: url.Values{"pmm_agent_id": []string{pmmAgentID}}.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+instance.Token)
+	req.Header.Set("Content-Type", forwardContentType)
+
+	resp, err := r.forwardClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to forward request to instance %s", instance.ID)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return b, nil
+	case http.StatusNotFound:
+		return nil, status.Errorf(codes.FailedPrecondition, "pmm-agent with ID %q is not currently connected", pmmAgentID)
+	default:
+		return nil, errors.Errorf("instance %s responded with status %d: %s", instance.ID, resp.StatusCode, b)
+	}
+}
+
+// forwardStateUpdate requests state update on pmm-agent connected to another pmm-managed instance.
+func (r *Registry) forwardStateUpdate(ctx context.Context, pmmAgentID string) error {
+	ch, err := r.remoteChannel(pmmAgentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.forward(ctx, ch.instance, forwardStateUpdatePath, pmmAgentID, nil)
+	return err
+}
+
+// forwardKick kicks pmm-agent connected to another pmm-managed instance.
+func (r *Registry) forwardKick(ctx context.Context, pmmAgentID string) error {
+	ch, err := r.remoteChannel(pmmAgentID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.forward(ctx, ch.instance, forwardKickPath, pmmAgentID, nil)
+	return err
+}
+
+// ForwardingHandler serves requests forwarded by other pmm-managed instances
+// to pmm-agents connected to this instance.
+// Requests should be authenticated by the caller (see ha.Service.Authenticate).
+type ForwardingHandler struct {
+	r     *Registry
+	state *StateUpdater
+	l     *logrus.Entry
+	mux   *http.ServeMux
+}
+
+// NewForwardingHandler creates a new ForwardingHandler.
+func NewForwardingHandler(r *Registry, state *StateUpdater) *ForwardingHandler {
+	h := &ForwardingHandler{
+		r:     r,
+		state: state,
+		l:     logrus.WithField("component", "agents/forwarding"),
+		mux:   http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc(forwardSendRequestPath, h.sendRequest)
+	h.mux.HandleFunc(forwardStateUpdatePath, h.requestStateUpdate)
+	h.mux.HandleFunc(forwardKickPath, h.kick)
+	return h
+}
+
+// ServeHTTP implements http.Handler interface.
+func (h *ForwardingHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+	h.mux.ServeHTTP(rw, req)
+}
+
+func (h *ForwardingHandler) sendRequest(rw http.ResponseWriter, req *http.Request) {
+	pmmAgentID := req.URL.Query().Get("pmm_agent_id")
+	agent, err := h.r.get(pmmAgentID)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(req.Body, 64<<20))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var msg agentpb.ServerMessage
+	if err = proto.Unmarshal(b, &msg); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	payload, _ := unwrapPayload(&msg).(agentpb.ServerRequestPayload)
+	if payload == nil {
+		http.Error(rw, "Unexpected request payload.", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := agent.channel.SendAndWaitResponse(payload)
+	res := new(agentpb.AgentMessage)
+	switch {
+	case err != nil:
+		res.Status = status.Convert(err).Proto()
+	case resp == nil:
+		// channel was closed while waiting for response
+		http.Error(rw, "pmm-agent disconnected.", http.StatusNotFound)
+		return
+	default:
+		res.Payload = resp.AgentMessageResponsePayload()
+	}
+
+	if b, err = proto.Marshal(res); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", forwardContentType)
+	if _, err = rw.Write(b); err != nil {
+		h.l.Warnf("Failed to write response: %s.", err)
+	}
+}
+
+func (h *ForwardingHandler) requestStateUpdate(rw http.ResponseWriter, req *http.Request) {
+	pmmAgentID := req.URL.Query().Get("pmm_agent_id")
+	if !h.r.isConnectedLocally(pmmAgentID) {
+		http.Error(rw, "pmm-agent is not connected.", http.StatusNotFound)
+		return
+	}
+
+	h.state.RequestStateUpdate(logger.Set(req.Context(), "forwarded"), pmmAgentID)
+}
+
+func (h *ForwardingHandler) kick(rw http.ResponseWriter, req *http.Request) {
+	pmmAgentID := req.URL.Query().Get("pmm_agent_id")
+	if !h.r.isConnectedLocally(pmmAgentID) {
+		http.Error(rw, "pmm-agent is not connected.", http.StatusNotFound)
+		return
+	}
+
+	h.r.Kick(logger.Set(req.Context(), "forwarded"), pmmAgentID)
+}
+
+// check interfaces.
+var (
+	_ agentChannel = (*remoteChannel)(nil)
+	_ http.Handler = (*ForwardingHandler)(nil)
+)
@@ -0,0 +1,145 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+type testHAService struct {
+	tlsConfig *tls.Config
+}
+
+func (testHAService) Enabled() bool                  { return true }
+func (testHAService) InstanceID() string             { return "test" }
+func (s testHAService) ClientTLSConfig() *tls.Config { return s.tlsConfig }
+
+func TestForwarding(t *testing.T) {
+	t.Parallel()
+
+	newRemoteChannel := func(t *testing.T, handler http.HandlerFunc) *remoteChannel {
+		srv := httptest.NewServer(handler)
+		t.Cleanup(srv.Close)
+
+		return &remoteChannel{
+			r: NewRegistry(nil, testHAService{}),
+			instance: &models.HAInstance{
+				ID:      "other",
+				Address: srv.Listener.Addr().String(),
+				Token:   "secret",
+			},
+			pmmAgentID: "/agent_id/1",
+		}
+	}
+
+	t.Run("Response", func(t *testing.T) {
+		t.Parallel()
+
+		ch := newRemoteChannel(t, func(rw http.ResponseWriter, req *http.Request) {
+			assert.Equal(t, forwardSendRequestPath, req.URL.Path)
+			assert.Equal(t, "/agent_id/1", req.URL.Query().Get("pmm_agent_id"))
+			assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+
+			b, err := ioutil.ReadAll(req.Body)
+			require.NoError(t, err)
+			var msg agentpb.ServerMessage
+			require.NoError(t, proto.Unmarshal(b, &msg))
+			req2, ok := unwrapPayload(&msg).(*agentpb.CheckConnectionRequest)
+			require.True(t, ok)
+			assert.Equal(t, "dsn", req2.Dsn)
+
+			b, err = proto.Marshal(&agentpb.AgentMessage{
+				Payload: (&agentpb.CheckConnectionResponse{}).AgentMessageResponsePayload(),
+			})
+			require.NoError(t, err)
+			_, _ = rw.Write(b)
+		})
+
+		resp, err := ch.SendAndWaitResponse(&agentpb.CheckConnectionRequest{Dsn: "dsn"})
+		require.NoError(t, err)
+		assert.IsType(t, &agentpb.CheckConnectionResponse{}, resp)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+
+		ch := newRemoteChannel(t, func(rw http.ResponseWriter, req *http.Request) {
+			b, err := proto.Marshal(&agentpb.AgentMessage{
+				Status: status.New(codes.InvalidArgument, "Invalid DSN.").Proto(),
+			})
+			require.NoError(t, err)
+			_, _ = rw.Write(b)
+		})
+
+		_, err := ch.SendAndWaitResponse(&agentpb.CheckConnectionRequest{Dsn: "dsn"})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Invalid DSN."), err)
+	})
+
+	t.Run("Disconnected", func(t *testing.T) {
+		t.Parallel()
+
+		ch := newRemoteChannel(t, NewForwardingHandler(NewRegistry(nil, testHAService{}), nil).ServeHTTP)
+
+		_, err := ch.SendAndWaitResponse(&agentpb.Ping{})
+		tests.AssertGRPCError(t, status.New(codes.FailedPrecondition, `pmm-agent with ID "/agent_id/1" is not currently connected`), err)
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			assert.NotNil(t, req.TLS)
+			b, err := proto.Marshal(&agentpb.AgentMessage{
+				Payload: (&agentpb.CheckConnectionResponse{}).AgentMessageResponsePayload(),
+			})
+			require.NoError(t, err)
+			_, _ = rw.Write(b)
+		}))
+		t.Cleanup(srv.Close)
+
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+		ch := &remoteChannel{
+			r: NewRegistry(nil, testHAService{tlsConfig: &tls.Config{RootCAs: pool}}), //nolint:gosec
+			instance: &models.HAInstance{
+				ID:      "other",
+				Address: srv.Listener.Addr().String(),
+				Token:   "secret",
+			},
+			pmmAgentID: "/agent_id/1",
+		}
+
+		resp, err := ch.SendAndWaitResponse(&agentpb.CheckConnectionRequest{Dsn: "dsn"})
+		require.NoError(t, err)
+		assert.IsType(t, &agentpb.CheckConnectionResponse{}, resp)
+	})
+}
@@ -73,6 +73,11 @@ func (h *Handler) Run(stream agentpb.Agent_ConnectServer) error {
 	}
 	defer func() {
 		l.Infof("Disconnecting client: %s.", disconnectReason)
+		h.r.recordEvent(ctx, models.CreateAgentEventParams{
+			AgentID: agent.id,
+			Type:    models.AgentDisconnected,
+			Reason:  disconnectReason,
+		})
 	}()
 
 	// run pmm-agent state update loop for the current agent.
@@ -120,6 +125,7 @@ func (h *Handler) Run(stream agentpb.Agent_ConnectServer) error {
 
 			case *agentpb.StateChangedRequest:
 				pprof.Do(ctx, pprof.Labels("request", "StateChangedRequest"), func(ctx context.Context) {
+					agent.drift.setReported(p, time.Now())
 					if err := h.stateChanged(ctx, p); err != nil {
 						l.Errorf("%+v", err)
 					}
@@ -132,8 +138,14 @@ func (h *Handler) Run(stream agentpb.Agent_ConnectServer) error {
 
 			case *agentpb.QANCollectRequest:
 				pprof.Do(ctx, pprof.Labels("request", "QANCollectRequest"), func(ctx context.Context) {
-					if err := h.qanClient.Collect(ctx, p.MetricsBucket); err != nil {
+					// data is acknowledged only when it is queued; see qan.Forwarder
+					if err := h.qanClient.Enqueue(ctx, p.MetricsBucket); err != nil {
 						l.Errorf("%+v", err)
+						agent.channel.Send(&channel.ServerResponse{
+							ID:     req.ID,
+							Status: status.Convert(err),
+						})
+						return
 					}
 
 					agent.channel.Send(&channel.ServerResponse{
@@ -170,7 +182,8 @@ func (h *Handler) Run(stream agentpb.Agent_ConnectServer) error {
 }
 
 func (h *Handler) updateAgentStatusForChildren(ctx context.Context, agentID string, status inventorypb.AgentStatus) error {
-	return h.db.InTransaction(func(t *reform.TX) error {
+	var events []*models.CreateAgentEventParams
+	e := h.db.InTransaction(func(t *reform.TX) error {
 		agents, err := models.FindAgents(t.Querier, models.AgentFilters{
 			PMMAgentID: agentID,
 		})
@@ -178,15 +191,25 @@ func (h *Handler) updateAgentStatusForChildren(ctx context.Context, agentID stri
 			return errors.Wrap(err, "failed to get pmm-agent's child agents")
 		}
 		for _, agent := range agents {
-			if err := updateAgentStatus(ctx, t.Querier, agent.AgentID, status, uint32(pointer.GetUint16(agent.ListenPort)), agent.ProcessExecPath); err != nil {
+			event, err := updateAgentStatus(ctx, t.Querier, agent.AgentID, status, uint32(pointer.GetUint16(agent.ListenPort)), agent.ProcessExecPath)
+			if err != nil {
 				return errors.Wrap(err, "failed to update agent's status")
 			}
+			if event != nil {
+				events = append(events, event)
+			}
 		}
 		return nil
 	})
+	if e != nil {
+		return e
+	}
+	h.recordAgentEvents(ctx, events)
+	return nil
 }
 
 func (h *Handler) stateChanged(ctx context.Context, req *agentpb.StateChangedRequest) error {
+	var events []*models.CreateAgentEventParams
 	e := h.db.InTransaction(func(tx *reform.TX) error {
 		agentIDs := h.r.roster.get(req.AgentId)
 		if agentIDs == nil {
@@ -194,15 +217,20 @@ func (h *Handler) stateChanged(ctx context.Context, req *agentpb.StateChangedReq
 		}
 
 		for _, agentID := range agentIDs {
-			if err := updateAgentStatus(ctx, tx.Querier, agentID, req.Status, req.ListenPort, pointer.ToStringOrNil(req.ProcessExecPath)); err != nil {
+			event, err := updateAgentStatus(ctx, tx.Querier, agentID, req.Status, req.ListenPort, pointer.ToStringOrNil(req.ProcessExecPath))
+			if err != nil {
 				return err
 			}
+			if event != nil {
+				events = append(events, event)
+			}
 		}
 		return nil
 	})
 	if e != nil {
 		return e
 	}
+	h.recordAgentEvents(ctx, events)
 	h.vmdb.RequestConfigurationUpdate()
 	agent, err := models.FindAgentByID(h.db.Querier, req.AgentId)
 	if err != nil {
@@ -233,7 +261,8 @@ func (h *Handler) SetAllAgentsStatusUnknown(ctx context.Context) error {
 	return nil
 }
 
-func updateAgentStatus(ctx context.Context, q *reform.Querier, agentID string, status inventorypb.AgentStatus, listenPort uint32, processExecPath *string) error {
+// updateAgentStatus updates Agent's status and returns Agent event that should be recorded, if any.
+func updateAgentStatus(ctx context.Context, q *reform.Querier, agentID string, status inventorypb.AgentStatus, listenPort uint32, processExecPath *string) (*models.CreateAgentEventParams, error) {
 	l := logger.Get(ctx)
 	l.Debugf("updateAgentStatus: %s %s %d", agentID, status, listenPort)
 
@@ -244,20 +273,57 @@ func updateAgentStatus(ctx context.Context, q *reform.Querier, agentID string, s
 	if err == reform.ErrNoRows {
 		switch status {
 		case inventorypb.AgentStatus_STOPPING, inventorypb.AgentStatus_DONE:
-			return nil
+			return nil, nil
 		}
 
 		l.Warnf("Failed to select Agent by ID for (%s, %s).", agentID, status)
 	}
 	if err != nil {
-		return errors.Wrap(err, "failed to select Agent by ID")
+		return nil, errors.Wrap(err, "failed to select Agent by ID")
 	}
 
+	oldStatus := agent.Status
 	agent.Status = status.String()
 	agent.ProcessExecPath = processExecPath
 	agent.ListenPort = pointer.ToUint16(uint16(listenPort))
 	if err = q.Update(agent); err != nil {
-		return errors.Wrap(err, "failed to update Agent")
+		return nil, errors.Wrap(err, "failed to update Agent")
+	}
+
+	if oldStatus == agent.Status {
+		return nil, nil
+	}
+	eventType := models.AgentStatusChanged
+	if isProcessRestart(oldStatus, status) {
+		eventType = models.AgentProcessRestarted
+	}
+	return &models.CreateAgentEventParams{
+		AgentID:   agentID,
+		Type:      eventType,
+		OldStatus: oldStatus,
+		NewStatus: agent.Status,
+	}, nil
+}
+
+// recordAgentEvents records Agent events after status changes are committed.
+// Events are informational, so failures are logged and don't affect status updates.
+func (h *Handler) recordAgentEvents(ctx context.Context, events []*models.CreateAgentEventParams) {
+	for _, event := range events {
+		if _, err := models.CreateAgentEvent(h.db.Querier, *event); err != nil {
+			logger.Get(ctx).Errorf("Failed to record Agent event for %s: %+v.", event.AgentID, err)
+		}
+	}
+}
+
+// isProcessRestart returns true if Agent process is started again after it was running or waiting for restart.
+func isProcessRestart(oldStatus string, newStatus inventorypb.AgentStatus) bool {
+	if newStatus != inventorypb.AgentStatus_STARTING {
+		return false
+	}
+	switch oldStatus {
+	case inventorypb.AgentStatus_RUNNING.String(), inventorypb.AgentStatus_WAITING.String():
+		return true
+	default:
+		return false
 	}
-	return nil
 }
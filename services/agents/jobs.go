@@ -341,12 +341,12 @@ func (s *JobsService) StartMySQLBackupJob(jobID, pmmAgentID string, timeout time
 		},
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
 
-	resp, err := agent.channel.SendAndWaitResponse(req)
+	resp, err := agent.SendAndWaitResponse(req)
 	if err != nil {
 		return err
 	}
@@ -398,12 +398,12 @@ func (s *JobsService) StartMongoDBBackupJob(
 		},
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
 
-	resp, err := agent.channel.SendAndWaitResponse(req)
+	resp, err := agent.SendAndWaitResponse(req)
 	if err != nil {
 		return err
 	}
@@ -446,12 +446,12 @@ func (s *JobsService) StartMySQLRestoreBackupJob(
 		},
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
 
-	resp, err := agent.channel.SendAndWaitResponse(req)
+	resp, err := agent.SendAndWaitResponse(req)
 	if err != nil {
 		return err
 	}
@@ -502,12 +502,12 @@ func (s *JobsService) StartMongoDBRestoreBackupJob(
 		},
 	}
 
-	agent, err := s.r.get(pmmAgentID)
+	agent, err := s.r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
 
-	resp, err := agent.channel.SendAndWaitResponse(req)
+	resp, err := agent.SendAndWaitResponse(req)
 	if err != nil {
 		return err
 	}
@@ -530,12 +530,12 @@ func (s *JobsService) StopJob(jobID string) error {
 		return nil
 	}
 
-	agent, err := s.r.get(jobResult.PMMAgentID)
+	agent, err := s.r.getChannel(jobResult.PMMAgentID)
 	if err != nil {
 		return errors.WithStack(err)
 	}
 
-	_, err = agent.channel.SendAndWaitResponse(&agentpb.StopJobRequest{JobId: jobID})
+	_, err = agent.SendAndWaitResponse(&agentpb.StopJobRequest{JobId: jobID})
 
 	return err
 }
@@ -31,11 +31,6 @@ import (
 	"github.com/percona/pmm-managed/utils/collectors"
 )
 
-type collectorArgs struct {
-	enabled     bool
-	enableParam string
-}
-
 var (
 	// New MongoDB Exporter will be released with PMM agent v2.10.0.
 	newMongoExporterPMMVersion = version.MustParse("2.9.99")
@@ -56,9 +51,9 @@ func mongodbExporterConfig(service *models.Service, exporter *models.Agent, reda
 	// was specified in the command line.
 	switch {
 	case !pmmAgentVersion.Less(v2_25_99): // >= 2.26
-		args = v226Args(exporter, tdp)
+		args = v226Args(exporter, tdp, pmmAgentVersion)
 	case !pmmAgentVersion.Less(v2_24_99): // >= 2.25
-		args = v225Args(exporter, tdp)
+		args = v225Args(exporter, tdp, pmmAgentVersion)
 	case !pmmAgentVersion.Less(newMongoExporterPMMVersion): // >= 2.10
 		args = []string{
 			"--mongodb.global-conn-pool",
@@ -114,28 +109,12 @@ func mongodbExporterConfig(service *models.Service, exporter *models.Agent, reda
 	return res, nil
 }
 
-func v226Args(exporter *models.Agent, tdp *models.DelimiterPair) []string {
-	collectAll := false
-	if exporter.MongoDBOptions != nil {
-		collectAll = exporter.MongoDBOptions.EnableAllCollectors
-	}
-
+func v226Args(exporter *models.Agent, tdp *models.DelimiterPair, pmmAgentVersion *version.Parsed) []string {
 	collstatsLimit := int32(200)
 	if exporter.MongoDBOptions != nil && exporter.MongoDBOptions.CollectionsLimit != -1 {
 		collstatsLimit = exporter.MongoDBOptions.CollectionsLimit
 	}
 
-	collectors := defaultCollectors(collectAll)
-
-	for _, collector := range exporter.DisabledCollectors {
-		col, ok := collectors[strings.ToLower(collector)]
-		if !ok {
-			continue
-		}
-		col.enabled = false
-		collectors[strings.ToLower(collector)] = col
-	}
-
 	args := []string{
 		"--mongodb.global-conn-pool",
 		"--compatible-mode",
@@ -152,49 +131,14 @@ func v226Args(exporter *models.Agent, tdp *models.DelimiterPair) []string {
 		args = append(args, fmt.Sprintf("--collector.collstats-limit=%d", collstatsLimit))
 	}
 
-	for _, collector := range collectors {
-		if collector.enabled && collector.enableParam != "" {
-			args = append(args, collector.enableParam)
-		}
-	}
+	// keep in sync with Prometheus scrape configs generator
+	catalog := collectors.Catalog(models.MongoDBExporterType, pmmAgentVersion)
+	args = append(args, catalog.Args(nil, exporter)...)
 
 	return args
 }
 
-func v225Args(exporter *models.Agent, tdp *models.DelimiterPair) []string {
-	type collectorArgs struct {
-		enabled      bool
-		enableParam  string
-		disableParam string
-	}
-
-	collectors := map[string]collectorArgs{
-		"diagnosticdata": {
-			enabled:      true,
-			disableParam: "--no-collector.diagnosticdata",
-		},
-		"replicasetstatus": {
-			enabled:      true,
-			disableParam: "--no-collector.replicasetstatus",
-		},
-		// disabled until we have better information on the resources usage impact
-		"dbstats": {
-			enabled:     false,
-			enableParam: "--collector.dbstats",
-		},
-		// disabled until we have better information on the resources usage impact
-		"topmetrics": {
-			enabled:     false,
-			enableParam: "--collector.topmetrics",
-		},
-	}
-
-	for _, collector := range exporter.DisabledCollectors {
-		col := collectors[strings.ToLower(collector)]
-		col.enabled = false
-		collectors[strings.ToLower(collector)] = col
-	}
-
+func v225Args(exporter *models.Agent, tdp *models.DelimiterPair, pmmAgentVersion *version.Parsed) []string {
 	args := []string{
 		"--mongodb.global-conn-pool",
 		"--compatible-mode",
@@ -210,51 +154,12 @@ func v225Args(exporter *models.Agent, tdp *models.DelimiterPair) []string {
 		args = append(args, fmt.Sprintf("--collector.collstats-limit=%d", exporter.MongoDBOptions.CollectionsLimit))
 	}
 
-	for _, collector := range collectors {
-		if collector.enabled && collector.enableParam != "" {
-			args = append(args, collector.enableParam)
-		}
-		if !collector.enabled && collector.disableParam != "" {
-			args = append(args, collector.disableParam)
-		}
-	}
+	catalog := collectors.Catalog(models.MongoDBExporterType, pmmAgentVersion)
+	args = append(args, catalog.Args(nil, exporter)...)
 
 	return args
 }
 
-func defaultCollectors(collectAll bool) map[string]collectorArgs {
-	return map[string]collectorArgs{
-		"diagnosticdata": {
-			enabled:     true,
-			enableParam: "--collector.diagnosticdata",
-		},
-		"replicasetstatus": {
-			enabled:     true,
-			enableParam: "--collector.replicasetstatus",
-		},
-		// disabled until we have better information on the resources usage impact
-		"collstats": {
-			enabled:     collectAll,
-			enableParam: "--collector.collstats",
-		},
-		// disabled until we have better information on the resources usage impact
-		"dbstats": {
-			enabled:     collectAll,
-			enableParam: "--collector.dbstats",
-		},
-		// disabled until we have better information on the resources usage impact
-		"indexstats": {
-			enabled:     collectAll,
-			enableParam: "--collector.indexstats",
-		},
-		// disabled until we have better information on the resources usage impact
-		"topmetrics": {
-			enabled:     collectAll,
-			enableParam: "--collector.topmetrics",
-		},
-	}
-}
-
 // qanMongoDBProfilerAgentConfig returns desired configuration of qan-mongodb-profiler-agent built-in agent.
 func qanMongoDBProfilerAgentConfig(service *models.Service, agent *models.Agent) *agentpb.SetStateRequest_BuiltinAgent {
 	tdp := agent.TemplateDelimiters(service)
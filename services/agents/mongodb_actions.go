@@ -0,0 +1,96 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/pkg/errors"
+)
+
+// ParseMongoDBConfigDB returns config server replica set name and hosts from mongos getCmdLineOpts result.
+func ParseMongoDBConfigDB(output []byte) (string, []string, error) {
+	docs, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	if len(docs) == 0 {
+		return "", nil, errors.New("empty getCmdLineOpts result")
+	}
+
+	parsed, _ := docs[0]["parsed"].(map[string]interface{})
+	sharding, _ := parsed["sharding"].(map[string]interface{})
+	configDB, _ := sharding["configDB"].(string)
+
+	// cfg/host1:27019,host2:27019
+	parts := strings.SplitN(configDB, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", nil, errors.New("not a mongos: no config servers in command line options")
+	}
+	return parts[0], strings.Split(parts[1], ","), nil
+}
+
+// ParseMongoDBReplicaSets returns hosts of replica sets monitored by mongos or mongod from getDiagnosticData result.
+func ParseMongoDBReplicaSets(output []byte) (map[string][]string, error) {
+	docs, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("empty getDiagnosticData result")
+	}
+
+	data, _ := docs[0]["data"].(map[string]interface{})
+	connPoolStats, _ := data["connPoolStats"].(map[string]interface{})
+	replicaSets, ok := connPoolStats["replicaSets"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("no replica sets in diagnostic data")
+	}
+
+	res := make(map[string][]string, len(replicaSets))
+	for name, v := range replicaSets {
+		set, _ := v.(map[string]interface{})
+
+		// connPoolStats command reports hosts as documents,
+		// while diagnostic data contains only ping times by host.
+		var hosts []string
+		if hostDocs, ok := set["hosts"].([]interface{}); ok {
+			for _, h := range hostDocs {
+				doc, _ := h.(map[string]interface{})
+				if addr, _ := doc["addr"].(string); addr != "" {
+					hosts = append(hosts, addr)
+				}
+			}
+		} else {
+			for addr := range set {
+				if _, _, err := net.SplitHostPort(addr); err == nil {
+					hosts = append(hosts, addr)
+				}
+			}
+		}
+		if len(hosts) == 0 {
+			return nil, errors.Errorf("no hosts of replica set %q in diagnostic data", name)
+		}
+
+		sort.Strings(hosts)
+		res[name] = hosts
+	}
+	return res, nil
+}
@@ -0,0 +1,100 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package agents
+
+import (
+	"testing"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMongoDBConfigDB(t *testing.T) {
+	t.Parallel()
+
+	output, err := agentpb.MarshalActionQueryDocsResult([]map[string]interface{}{{
+		"argv": []interface{}{"mongos", "--configdb", "cfg/cfg-1:27019,cfg-2:27019"},
+		"parsed": map[string]interface{}{
+			"sharding": map[string]interface{}{"configDB": "cfg/cfg-1:27019,cfg-2:27019"},
+		},
+	}})
+	require.NoError(t, err)
+	set, hosts, err := ParseMongoDBConfigDB(output)
+	require.NoError(t, err)
+	assert.Equal(t, "cfg", set)
+	assert.Equal(t, []string{"cfg-1:27019", "cfg-2:27019"}, hosts)
+
+	output, err = agentpb.MarshalActionQueryDocsResult([]map[string]interface{}{{
+		"argv":   []interface{}{"mongod", "--replSet", "rs0"},
+		"parsed": map[string]interface{}{"replication": map[string]interface{}{"replSet": "rs0"}},
+	}})
+	require.NoError(t, err)
+	_, _, err = ParseMongoDBConfigDB(output)
+	assert.EqualError(t, err, "not a mongos: no config servers in command line options")
+}
+
+func TestParseMongoDBReplicaSets(t *testing.T) {
+	t.Parallel()
+
+	t.Run("DiagnosticData", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := agentpb.MarshalActionQueryDocsResult([]map[string]interface{}{{
+			"data": map[string]interface{}{
+				"connPoolStats": map[string]interface{}{
+					"replicaSets": map[string]interface{}{
+						"cfg": map[string]interface{}{"cfg-1:27019": int64(0)},
+						"rs0": map[string]interface{}{"rs0-2:27018": int64(1), "rs0-1:27018": int64(0)},
+					},
+				},
+			},
+		}})
+		require.NoError(t, err)
+
+		actual, err := ParseMongoDBReplicaSets(output)
+		require.NoError(t, err)
+		expected := map[string][]string{
+			"cfg": {"cfg-1:27019"},
+			"rs0": {"rs0-1:27018", "rs0-2:27018"},
+		}
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("ConnPoolStats", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := agentpb.MarshalActionQueryDocsResult([]map[string]interface{}{{
+			"data": map[string]interface{}{
+				"connPoolStats": map[string]interface{}{
+					"replicaSets": map[string]interface{}{
+						"rs1": map[string]interface{}{
+							"hosts": []interface{}{
+								map[string]interface{}{"addr": "rs1-1:27018", "ok": true, "ismaster": true},
+							},
+						},
+					},
+				},
+			},
+		}})
+		require.NoError(t, err)
+
+		actual, err := ParseMongoDBReplicaSets(output)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"rs1": {"rs1-1:27018"}}, actual)
+	})
+}
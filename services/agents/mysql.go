@@ -34,63 +34,20 @@ import (
 func mysqldExporterConfig(service *models.Service, exporter *models.Agent, redactMode redactMode, pmmAgentVersion *version.Parsed) *agentpb.SetStateRequest_AgentProcess {
 	tdp := exporter.TemplateDelimiters(service)
 
-	args := []string{
-		// LR
-		"--collect.binlog_size",
-		"--collect.engine_tokudb_status",
-		"--collect.global_variables",
-		"--collect.heartbeat",
-		"--collect.info_schema.clientstats",
-		"--collect.info_schema.userstats",
-		"--collect.perf_schema.eventsstatements",
-		"--collect.custom_query.lr",
-
-		// MR
-		"--collect.engine_innodb_status",
-		"--collect.info_schema.innodb_cmp",
-		"--collect.info_schema.innodb_cmpmem",
-		"--collect.info_schema.processlist",
-		"--collect.info_schema.query_response_time",
-		"--collect.perf_schema.eventswaits",
-		"--collect.perf_schema.file_events",
-		"--collect.slave_status",
-		"--collect.custom_query.mr",
-
-		// HR
-		"--collect.global_status",
-		"--collect.info_schema.innodb_metrics",
-		"--collect.custom_query.hr",
-		"--collect.standard.go",
-		"--collect.standard.process",
-
-		"--collect.custom_query.lr.directory=" + pathsBase(pmmAgentVersion, tdp.Left, tdp.Right) + "/collectors/custom-queries/mysql/low-resolution",
-		"--collect.custom_query.mr.directory=" + pathsBase(pmmAgentVersion, tdp.Left, tdp.Right) + "/collectors/custom-queries/mysql/medium-resolution",
-		"--collect.custom_query.hr.directory=" + pathsBase(pmmAgentVersion, tdp.Left, tdp.Right) + "/collectors/custom-queries/mysql/high-resolution",
+	// keep in sync with Prometheus scrape configs generator
+	catalog := collectors.Catalog(models.MySQLdExporterType, pmmAgentVersion)
+	args := catalog.Args(nil, exporter)
+	args = append(args,
+		"--collect.custom_query.lr.directory="+pathsBase(pmmAgentVersion, tdp.Left, tdp.Right)+"/collectors/custom-queries/mysql/low-resolution",
+		"--collect.custom_query.mr.directory="+pathsBase(pmmAgentVersion, tdp.Left, tdp.Right)+"/collectors/custom-queries/mysql/medium-resolution",
+		"--collect.custom_query.hr.directory="+pathsBase(pmmAgentVersion, tdp.Left, tdp.Right)+"/collectors/custom-queries/mysql/high-resolution",
 
 		"--exporter.max-idle-conns=3",
 		"--exporter.max-open-conns=3",
 		"--exporter.conn-max-lifetime=55s",
 		"--exporter.global-conn-pool",
-		"--web.listen-address=:" + tdp.Left + " .listen_port " + tdp.Right,
-	}
-
-	if exporter.IsMySQLTablestatsGroupEnabled() {
-		// keep in sync with Prometheus scrape configs generator
-		tablestatsGroup := []string{
-			// LR
-			"--collect.info_schema.innodb_tablespaces",
-			"--collect.auto_increment.columns",
-			"--collect.info_schema.tables",
-			"--collect.info_schema.tablestats",
-			"--collect.perf_schema.indexiowaits",
-			"--collect.perf_schema.tableiowaits",
-			"--collect.perf_schema.file_instances",
-
-			// MR
-			"--collect.perf_schema.tablelocks",
-		}
-		args = append(args, tablestatsGroup...)
-	}
+		"--web.listen-address=:"+tdp.Left+" .listen_port "+tdp.Right,
+	)
 
 	args = collectors.FilterOutCollectors("--collect.", args, exporter.DisabledCollectors)
 
@@ -50,64 +50,11 @@ func nodeExporterConfig(node *models.Node, exporter *models.Agent, agentVersion
 
 	// do not tweak collectors on macOS as many (but not) of them are Linux-specific
 	if node.Distro != "darwin" {
-		args = append(args,
-			// LR
-			"--collector.bonding",
-			"--collector.entropy",
-			"--collector.uname",
-			"--collector.textfile.lr",
-
-			// MR
-			"--collector.textfile.mr",
-			"--collector.hwmon",
-
-			// HR
-			"--collector.buddyinfo",
-			"--collector.cpu",
-			"--collector.diskstats",
-			"--collector.filefd",
-			"--collector.filesystem",
-			"--collector.loadavg",
-			"--collector.meminfo",
-			"--collector.meminfo_numa",
-			"--collector.netdev",
-			"--collector.netstat",
-			"--collector.processes",
-			"--collector.stat",
-			"--collector.time",
-			"--collector.vmstat",
-			"--collector.textfile.hr",
-			"--collector.standard.go",
-			"--collector.standard.process",
-
-			// disabled
-			"--no-collector.arp",
-			"--no-collector.bcache",
-			"--no-collector.conntrack",
-			"--no-collector.drbd",
-			"--no-collector.edac",
-			"--no-collector.infiniband",
-			"--no-collector.interrupts",
-			"--no-collector.ipvs",
-			"--no-collector.ksmd",
-			"--no-collector.logind",
-			"--no-collector.mdadm",
-			"--no-collector.mountstats",
-			"--no-collector.netclass",
-			"--no-collector.nfs",
-			"--no-collector.nfsd",
-			"--no-collector.ntp",
-			"--no-collector.qdisc",
-			"--no-collector.runit",
-			"--no-collector.sockstat",
-			"--no-collector.supervisord",
-			"--no-collector.systemd",
-			"--no-collector.tcpstat",
-			"--no-collector.timex",
-			"--no-collector.wifi",
-			"--no-collector.xfs",
-			"--no-collector.zfs",
+		// keep in sync with Prometheus scrape configs generator
+		catalog := collectors.Catalog(models.NodeExporterType, agentVersion)
+		args = append(args, catalog.Args(node, exporter)...)
 
+		args = append(args,
 			// add more netstat fields
 			"--collector.netstat.fields=^(.*_(InErrors|InErrs|InCsumErrors)"+
 				"|Tcp_(ActiveOpens|PassiveOpens|RetransSegs|CurrEstab|AttemptFails|OutSegs|InSegs|EstabResets|OutRsts|OutSegs)|Tcp_Rto(Algorithm|Min|Max)"+
@@ -44,7 +44,7 @@ func NewDefaultsFileParser(r *Registry) *DefaultsFileParser {
 func (p *DefaultsFileParser) ParseDefaultsFile(ctx context.Context, pmmAgentID, filePath string, serviceType models.ServiceType) (*models.ParseDefaultsFileResult, error) {
 	l := logger.Get(ctx)
 
-	pmmAgent, err := p.r.get(pmmAgentID)
+	pmmAgent, err := p.r.getChannel(pmmAgentID)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +62,7 @@ func (p *DefaultsFileParser) ParseDefaultsFile(ctx context.Context, pmmAgentID,
 		return nil, err
 	}
 
-	resp, err := pmmAgent.channel.SendAndWaitResponse(request)
+	resp, err := pmmAgent.SendAndWaitResponse(request)
 	if err != nil {
 		return nil, err
 	}
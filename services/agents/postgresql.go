@@ -42,21 +42,14 @@ func postgresExporterConfig(service *models.Service, exporter *models.Agent, red
 
 	tdp := exporter.TemplateDelimiters(service)
 
-	args := []string{
-		// LR
-		"--collect.custom_query.lr",
-
-		// MR
-		"--collect.custom_query.mr",
-
-		// HR
-		"--collect.custom_query.hr",
-
-		"--collect.custom_query.lr.directory=" + pathsBase(pmmAgentVersion, tdp.Left, tdp.Right) + "/collectors/custom-queries/postgresql/low-resolution",
-		"--collect.custom_query.mr.directory=" + pathsBase(pmmAgentVersion, tdp.Left, tdp.Right) + "/collectors/custom-queries/postgresql/medium-resolution",
-		"--collect.custom_query.hr.directory=" + pathsBase(pmmAgentVersion, tdp.Left, tdp.Right) + "/collectors/custom-queries/postgresql/high-resolution",
-		"--web.listen-address=:" + tdp.Left + " .listen_port " + tdp.Right,
-	}
+	catalog := collectors.Catalog(models.PostgresExporterType, pmmAgentVersion)
+	args := catalog.Args(nil, exporter)
+	args = append(args,
+		"--collect.custom_query.lr.directory="+pathsBase(pmmAgentVersion, tdp.Left, tdp.Right)+"/collectors/custom-queries/postgresql/low-resolution",
+		"--collect.custom_query.mr.directory="+pathsBase(pmmAgentVersion, tdp.Left, tdp.Right)+"/collectors/custom-queries/postgresql/medium-resolution",
+		"--collect.custom_query.hr.directory="+pathsBase(pmmAgentVersion, tdp.Left, tdp.Right)+"/collectors/custom-queries/postgresql/high-resolution",
+		"--web.listen-address=:"+tdp.Left+" .listen_port "+tdp.Right,
+	)
 
 	if !pmmAgentVersion.Less(postgresExporterAutodiscoveryVersion) {
 		args = append(args,
@@ -30,32 +30,15 @@ import (
 	"github.com/percona/pmm-managed/utils/collectors"
 )
 
-var (
-	proxysqlExporterStatsCommandVersion = version.MustParse("2.18.99")
-	proxysqlExporterRuntimeVersion      = version.MustParse("2.20.99")
-)
-
 // proxysqlExporterConfig returns desired configuration of proxysql_exporter process.
 func proxysqlExporterConfig(service *models.Service, exporter *models.Agent, redactMode redactMode,
 	pmmAgentVersion *version.Parsed,
 ) *agentpb.SetStateRequest_AgentProcess {
 	tdp := exporter.TemplateDelimiters(service)
 
-	args := []string{
-		"-collect.mysql_connection_list",
-		"-collect.mysql_connection_pool",
-		"-collect.mysql_status",
-		"-collect.stats_memory_metrics",
-		"-web.listen-address=:" + tdp.Left + " .listen_port " + tdp.Right,
-	}
-
-	if !pmmAgentVersion.Less(proxysqlExporterStatsCommandVersion) {
-		args = append(args, "-collect.stats_command_counter")
-	}
-
-	if !pmmAgentVersion.Less(proxysqlExporterRuntimeVersion) {
-		args = append(args, "-collect.runtime_mysql_servers")
-	}
+	catalog := collectors.Catalog(models.ProxySQLExporterType, pmmAgentVersion)
+	args := catalog.Args(nil, exporter)
+	args = append(args, "-web.listen-address=:"+tdp.Left+" .listen_port "+tdp.Right)
 
 	if pointer.GetString(exporter.MetricsPath) != "" {
 		args = append(args, "-web.telemetry-path="+*exporter.MetricsPath)
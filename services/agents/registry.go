@@ -19,6 +19,7 @@ package agents
 
 import (
 	"context"
+	"net/http"
 	"sync"
 	"time"
 
@@ -70,11 +71,18 @@ type pmmAgentInfo struct {
 	id              string
 	stateChangeChan chan struct{}
 	kick            chan struct{}
+	drift           *driftState
 }
 
 // Registry keeps track of all connected pmm-agents.
+//
+// In high-availability mode, pmm-agents connected to this pmm-managed instance are recorded in the database,
+// and requests to pmm-agents connected to other instances are forwarded to them.
 type Registry struct {
-	db *reform.DB
+	db            *reform.DB
+	ha            haService
+	forwardClient *http.Client
+	forwardScheme string
 
 	rw     sync.RWMutex
 	agents map[string]*pmmAgentInfo // id -> info
@@ -89,10 +97,23 @@ type Registry struct {
 }
 
 // NewRegistry creates a new registry with given database connection.
-func NewRegistry(db *reform.DB) *Registry {
+func NewRegistry(db *reform.DB, ha haService) *Registry {
+	forwardTransport := http.DefaultTransport.(*http.Transport).Clone()
+	forwardScheme := "http"
+	if c := ha.ClientTLSConfig(); c != nil {
+		forwardTransport.TLSClientConfig = c
+		forwardScheme = "https"
+	}
+
 	agents := make(map[string]*pmmAgentInfo)
 	r := &Registry{
 		db: db,
+		ha: ha,
+		forwardClient: &http.Client{
+			Transport: forwardTransport,
+			Timeout:   forwardTimeout,
+		},
+		forwardScheme: forwardScheme,
 
 		agents: agents,
 
@@ -139,15 +160,22 @@ func NewRegistry(db *reform.DB) *Registry {
 	return r
 }
 
-// IsConnected returns true if pmm-agent with given ID is currently connected, false otherwise.
+// IsConnected returns true if pmm-agent with given ID is currently connected to this
+// or another live pmm-managed instance, false otherwise.
 func (r *Registry) IsConnected(pmmAgentID string) bool {
+	_, err := r.getChannel(pmmAgentID)
+	return err == nil
+}
+
+// isConnectedLocally returns true if pmm-agent with given ID is currently connected to this pmm-managed instance.
+func (r *Registry) isConnectedLocally(pmmAgentID string) bool {
 	_, err := r.get(pmmAgentID)
 	return err == nil
 }
 
 // PBMSwitchPITR switches Point-in-Time Recovery feature for pbm on the pmm-agent.
 func (r *Registry) PBMSwitchPITR(pmmAgentID, dsn string, files map[string]string, tdp *models.DelimiterPair, enabled bool) error {
-	agent, err := r.get(pmmAgentID)
+	agent, err := r.getChannel(pmmAgentID)
 	if err != nil {
 		return err
 	}
@@ -162,7 +190,7 @@ func (r *Registry) PBMSwitchPITR(pmmAgentID, dsn string, files map[string]string
 		Enabled: enabled,
 	}
 
-	_, err = agent.channel.SendAndWaitResponse(req)
+	_, err = agent.SendAndWaitResponse(req)
 	return err
 }
 
@@ -185,6 +213,14 @@ func (r *Registry) register(stream agentpb.Agent_ConnectServer) (*pmmAgentInfo,
 	})
 	if err != nil {
 		l.Warnf("Failed to authenticate connected pmm-agent %+v.", agentMD)
+		// record failures only for existing Agents to avoid flooding history with random IDs
+		if _, e := models.FindAgentByID(r.db.Querier, agentMD.ID); e == nil {
+			r.recordEvent(ctx, models.CreateAgentEventParams{
+				AgentID: agentMD.ID,
+				Type:    models.AgentDisconnected,
+				Reason:  "auth",
+			})
+		}
 		return nil, err
 	}
 	l.Infof("Connected pmm-agent: %+v.", agentMD)
@@ -207,19 +243,40 @@ func (r *Registry) register(stream agentpb.Agent_ConnectServer) (*pmmAgentInfo,
 		l.Warnf("Another pmm-agent with ID %q is already connected.", agentMD.ID)
 		r.Kick(ctx, agentMD.ID)
 	}
-	r.rw.Lock()
-	defer r.rw.Unlock()
-
 	agent := &pmmAgentInfo{
 		channel:         channel.New(stream),
 		id:              agentMD.ID,
 		stateChangeChan: make(chan struct{}, 1),
 		kick:            make(chan struct{}),
+		drift:           newDriftState(),
 	}
+	r.rw.Lock()
 	r.agents[agentMD.ID] = agent
+	r.rw.Unlock()
+
+	if r.ha.Enabled() {
+		if err = models.SetPMMAgentOwner(r.db.Querier, agentMD.ID, r.ha.InstanceID()); err != nil {
+			// pmm-agent is still usable from this instance
+			l.Errorf("Failed to record pmm-agent ownership: %+v.", err)
+		}
+	}
+
+	r.recordEvent(ctx, models.CreateAgentEventParams{
+		AgentID: agentMD.ID,
+		Type:    models.AgentConnected,
+	})
+
 	return agent, nil
 }
 
+// recordEvent adds event to pmm-agent connection history.
+// Failures are only logged to avoid breaking connection handling.
+func (r *Registry) recordEvent(ctx context.Context, params models.CreateAgentEventParams) {
+	if _, err := models.CreateAgentEvent(r.db.Querier, params); err != nil {
+		logger.Get(ctx).Errorf("Failed to record %s event for Agent %s: %+v.", params.Type, params.AgentID, err)
+	}
+}
+
 func authenticate(md *agentpb.AgentConnectMetadata, q *reform.Querier) (string, error) {
 	if md.ID == "" {
 		return "", status.Error(codes.PermissionDenied, "Empty Agent ID.")
@@ -263,18 +320,26 @@ func (r *Registry) unregister(pmmAgentID, disconnectReason string) *pmmAgentInfo
 	r.mDisconnects.WithLabelValues(disconnectReason).Inc()
 
 	r.rw.Lock()
-	defer r.rw.Unlock()
 
 	// We do not check that pmmAgentID is in fact ID of existing pmm-agent because
 	// it may be already deleted from the database, that's why we unregister it.
 
 	agent := r.agents[pmmAgentID]
 	if agent == nil {
+		r.rw.Unlock()
 		return nil
 	}
 
 	delete(r.agents, pmmAgentID)
 	r.roster.clear(pmmAgentID)
+	r.rw.Unlock()
+
+	if r.ha.Enabled() {
+		if err := models.RemovePMMAgentOwner(r.db.Querier, pmmAgentID, r.ha.InstanceID()); err != nil {
+			logrus.Errorf("Failed to remove pmm-agent %s ownership: %+v.", pmmAgentID, err)
+		}
+	}
+
 	return agent
 }
 
@@ -365,13 +430,20 @@ func removeVMAgentFromPMMAgent(q *reform.Querier, pmmAgentID string) error {
 }
 
 // Kick unregisters and forcefully disconnects pmm-agent with given ID.
+// pmm-agent connected to another pmm-managed instance is kicked by that instance.
 func (r *Registry) Kick(ctx context.Context, pmmAgentID string) {
+	l := logger.Get(ctx)
+
 	agent := r.unregister(pmmAgentID, "kick")
 	if agent == nil {
+		if r.ha.Enabled() {
+			if err := r.forwardKick(ctx, pmmAgentID); err != nil {
+				l.Debugf("Failed to kick pmm-agent %q connected to another instance: %s.", pmmAgentID, err)
+			}
+		}
 		return
 	}
 
-	l := logger.Get(ctx)
 	l.Debugf("pmm-agent with ID %q will be kicked in a moment.", pmmAgentID)
 
 	// see Run method
@@ -381,6 +453,7 @@ func (r *Registry) Kick(ctx context.Context, pmmAgentID string) {
 	// closing agent.kick is enough to exit runStateChangeHandler goroutine.
 }
 
+// get returns pmm-agent connected to this pmm-managed instance.
 func (r *Registry) get(pmmAgentID string) (*pmmAgentInfo, error) {
 	r.rw.RLock()
 	pmmAgent := r.agents[pmmAgentID]
@@ -391,6 +464,26 @@ func (r *Registry) get(pmmAgentID string) (*pmmAgentInfo, error) {
 	return pmmAgent, nil
 }
 
+// getChannel returns channel to pmm-agent connected to this or (in high-availability mode) another pmm-managed instance.
+func (r *Registry) getChannel(pmmAgentID string) (agentChannel, error) {
+	agent, err := r.get(pmmAgentID)
+	if err == nil {
+		return agent.channel, nil
+	}
+	if !r.ha.Enabled() {
+		return nil, err
+	}
+
+	ch, e := r.remoteChannel(pmmAgentID)
+	if e != nil {
+		if status.Code(e) != codes.NotFound {
+			logrus.Warnf("Failed to find pmm-agent %q owner: %+v.", pmmAgentID, e)
+		}
+		return nil, err
+	}
+	return ch, nil
+}
+
 // Describe implements prometheus.Collector.
 func (r *Registry) Describe(ch chan<- *prom.Desc) {
 	r.mConnects.Describe(ch)
@@ -63,6 +63,12 @@ func (u *StateUpdater) RequestStateUpdate(ctx context.Context, pmmAgentID string
 
 	agent, err := u.r.get(pmmAgentID)
 	if err != nil {
+		if u.r.ha.Enabled() {
+			// pmm-agent may be connected to another pmm-managed instance
+			if e := u.r.forwardStateUpdate(ctx, pmmAgentID); e == nil {
+				return
+			}
+		}
 		l.Infof("RequestStateUpdate: %s.", err)
 		return
 	}
@@ -127,7 +133,7 @@ func (u *StateUpdater) runStateChangeHandler(ctx context.Context, agent *pmmAgen
 			}
 
 			nCtx, cancel := context.WithTimeout(ctx, stateChangeTimeout)
-			err := u.sendSetStateRequest(nCtx, agent)
+			err := u.sendSetStateRequest(nCtx, agent, nil)
 			if err != nil {
 				l.Error(err)
 				u.RequestStateUpdate(ctx, agent.id)
@@ -138,7 +144,8 @@ func (u *StateUpdater) runStateChangeHandler(ctx context.Context, agent *pmmAgen
 }
 
 // sendSetStateRequest sends SetStateRequest to given pmm-agent.
-func (u *StateUpdater) sendSetStateRequest(ctx context.Context, agent *pmmAgentInfo) error {
+// Excluded Agents (or groups of Agents) are not sent, so pmm-agent stops them.
+func (u *StateUpdater) sendSetStateRequest(ctx context.Context, agent *pmmAgentInfo, exclude map[string]struct{}) error {
 	l := logger.Get(ctx)
 	start := time.Now()
 	defer func() {
@@ -160,16 +167,15 @@ func (u *StateUpdater) sendSetStateRequest(ctx context.Context, agent *pmmAgentI
 		return errors.Wrap(err, "failed to collect agents")
 	}
 
+	// RedactWords are always set as drift detection uses them to mask secrets;
+	// they are removed from the sent state in debug mode, see below
 	redactMode := redactSecrets
-	if l.Logger.GetLevel() >= logrus.DebugLevel {
-		redactMode = exposeSecrets
-	}
 
 	rdsExporters := make(map[*models.Node]*models.Agent)
 	agentProcesses := make(map[string]*agentpb.SetStateRequest_AgentProcess)
 	builtinAgents := make(map[string]*agentpb.SetStateRequest_BuiltinAgent)
 	for _, row := range agents {
-		if row.Disabled {
+		if _, ok := exclude[row.AgentID]; ok || row.Disabled {
 			continue
 		}
 
@@ -268,17 +274,27 @@ func (u *StateUpdater) sendSetStateRequest(ctx context.Context, agent *pmmAgentI
 		if err != nil {
 			return err
 		}
-		agentProcesses[groupID] = c
+		if _, ok := exclude[groupID]; !ok {
+			agentProcesses[groupID] = c
+		}
 	}
 	state := &agentpb.SetStateRequest{
 		AgentProcesses: agentProcesses,
 		BuiltinAgents:  builtinAgents,
 	}
-	l.Debugf("sendSetStateRequest:\n%s", proto.MarshalTextString(state))
-	resp, err := agent.channel.SendAndWaitResponse(state)
+	send := state
+	if l.Logger.GetLevel() >= logrus.DebugLevel {
+		send = proto.Clone(state).(*agentpb.SetStateRequest)
+		for _, p := range send.AgentProcesses {
+			p.RedactWords = nil
+		}
+	}
+	l.Debugf("sendSetStateRequest:\n%s", proto.MarshalTextString(send))
+	resp, err := agent.channel.SendAndWaitResponse(send)
 	if err != nil {
 		return err
 	}
 	l.Infof("SetState response: %+v.", resp)
+	agent.drift.setSent(state, time.Now())
 	return nil
 }
@@ -445,6 +445,7 @@ func recreateTLSConfigFiles(chanMap map[string]*models.Channel) error {
 func (svc *Service) populateConfig(cfg *alertmanager.Config) error {
 	var settings *models.Settings
 	var rules []*models.Rule
+	var slos []*models.SLO
 	var channels []*models.Channel
 	e := svc.db.InTransaction(func(tx *reform.TX) error {
 		var err error
@@ -458,6 +459,11 @@ func (svc *Service) populateConfig(cfg *alertmanager.Config) error {
 			return err
 		}
 
+		slos, err = models.FindSLOs(tx.Querier)
+		if err != nil {
+			return err
+		}
+
 		channels, err = models.FindChannels(tx.Querier)
 		if err != nil {
 			return err
@@ -513,24 +519,7 @@ func (svc *Service) populateConfig(cfg *alertmanager.Config) error {
 		}
 	}
 
-	if settings.IntegratedAlerting.EmailAlertingSettings != nil {
-		svc.l.Warn("Setting global email config, any user defined changes to the base config might be overwritten.")
-
-		cfg.Global.SMTPFrom = settings.IntegratedAlerting.EmailAlertingSettings.From
-		cfg.Global.SMTPHello = settings.IntegratedAlerting.EmailAlertingSettings.Hello
-		cfg.Global.SMTPSmarthost = settings.IntegratedAlerting.EmailAlertingSettings.Smarthost
-		cfg.Global.SMTPAuthIdentity = settings.IntegratedAlerting.EmailAlertingSettings.Identity
-		cfg.Global.SMTPAuthUsername = settings.IntegratedAlerting.EmailAlertingSettings.Username
-		cfg.Global.SMTPAuthPassword = settings.IntegratedAlerting.EmailAlertingSettings.Password
-		cfg.Global.SMTPAuthSecret = settings.IntegratedAlerting.EmailAlertingSettings.Secret
-		cfg.Global.SMTPRequireTLS = settings.IntegratedAlerting.EmailAlertingSettings.RequireTLS
-	}
-
-	if settings.IntegratedAlerting.SlackAlertingSettings != nil {
-		svc.l.Warn("Setting global Slack config, any user defined changes to the base config might be overwritten.")
-
-		cfg.Global.SlackAPIURL = settings.IntegratedAlerting.SlackAlertingSettings.URL
-	}
+	svc.populateGlobalConfig(cfg, settings)
 
 	recvSet := make(map[string]models.ChannelIDs) // stores unique combinations of channel IDs
 	for _, r := range rules {
@@ -556,64 +545,112 @@ func (svc *Service) populateConfig(cfg *alertmanager.Config) error {
 				svc.l.Warnf("Unhandled filter: %+v", f)
 			}
 		}
-		enabledChannels := make(models.ChannelIDs, 0, len(r.ChannelIDs))
-		for _, chID := range r.ChannelIDs {
-			if channel, ok := chanMap[chID]; ok {
-				if !channel.Disabled {
-					enabledChannels = append(enabledChannels, chID)
-				}
-			}
-		}
-		// make sure same slice with different order are not considered unique.
-		sort.Strings(enabledChannels)
-		recv := strings.Join(enabledChannels, receiverNameSeparator)
-		if len(enabledChannels) == 0 {
-			recv = "disabled"
-		} else {
-			recvSet[recv] = enabledChannels
-		}
-		route.Receiver = recv
+		route.Receiver = receiverName(r.ChannelIDs, chanMap, recvSet)
 
 		cfg.Route.Routes = append(cfg.Route.Routes, route)
 	}
 
-	receivers, err := svc.generateReceivers(chanMap, recvSet)
-	if err != nil {
-		return err
+	for _, slo := range slos {
+		// skip SLOs with 0 notification channels
+		if slo.Disabled || len(slo.ChannelIDs) == 0 {
+			continue
+		}
+
+		cfg.Route.Routes = append(cfg.Route.Routes, &alertmanager.Route{
+			Match: map[string]string{
+				"slo_id": slo.ID,
+			},
+			Receiver: receiverName(slo.ChannelIDs, chanMap, recvSet),
+		})
 	}
 
-	cfg.Receivers = append(cfg.Receivers, receivers...)
+	cfg.Receivers = append(cfg.Receivers, relayReceivers(recvSet)...)
 	return nil
 }
 
+// receiverName returns name of the receiver for enabled channels of the given ones,
+// and adds it to the unique set of channel IDs combinations.
+func receiverName(channelIDs models.ChannelIDs, chanMap map[string]*models.Channel, recvSet map[string]models.ChannelIDs) string {
+	enabledChannels := make(models.ChannelIDs, 0, len(channelIDs))
+	for _, chID := range channelIDs {
+		if channel, ok := chanMap[chID]; ok {
+			if !channel.Disabled {
+				enabledChannels = append(enabledChannels, chID)
+			}
+		}
+	}
+	if len(enabledChannels) == 0 {
+		return "disabled"
+	}
+
+	// make sure same slice with different order are not considered unique.
+	sort.Strings(enabledChannels)
+	recv := strings.Join(enabledChannels, receiverNameSeparator)
+	recvSet[recv] = enabledChannels
+	return recv
+}
+
+// populateGlobalConfig sets global email and Slack configuration from settings.
+func (svc *Service) populateGlobalConfig(cfg *alertmanager.Config, settings *models.Settings) {
+	if settings.IntegratedAlerting.EmailAlertingSettings != nil {
+		svc.l.Warn("Setting global email config, any user defined changes to the base config might be overwritten.")
+
+		cfg.Global.SMTPFrom = settings.IntegratedAlerting.EmailAlertingSettings.From
+		cfg.Global.SMTPHello = settings.IntegratedAlerting.EmailAlertingSettings.Hello
+		cfg.Global.SMTPSmarthost = settings.IntegratedAlerting.EmailAlertingSettings.Smarthost
+		cfg.Global.SMTPAuthIdentity = settings.IntegratedAlerting.EmailAlertingSettings.Identity
+		cfg.Global.SMTPAuthUsername = settings.IntegratedAlerting.EmailAlertingSettings.Username
+		cfg.Global.SMTPAuthPassword = settings.IntegratedAlerting.EmailAlertingSettings.Password
+		cfg.Global.SMTPAuthSecret = settings.IntegratedAlerting.EmailAlertingSettings.Secret
+		cfg.Global.SMTPRequireTLS = settings.IntegratedAlerting.EmailAlertingSettings.RequireTLS
+	}
+
+	if settings.IntegratedAlerting.SlackAlertingSettings != nil {
+		svc.l.Warn("Setting global Slack config, any user defined changes to the base config might be overwritten.")
+
+		cfg.Global.SlackAPIURL = settings.IntegratedAlerting.SlackAlertingSettings.URL
+	}
+}
+
+const pagerDutyDescription = notificationTitle +
+	"{{ range .Alerts -}}{{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}{{ end }}"
+
 func formatSlackText(labels ...string) string {
+	return "{{ range .Alerts -}}\n" + formatSlackAlertText(labels...) + "{{ end }}"
+}
+
+// formatSlackAlertText returns Slack text template for a single alert.
+func formatSlackAlertText(labels ...string) string {
 	const listEntryFormat = "{{ if .Labels.%[1]s }}     • *%[1]s:* `{{ .Labels.%[1]s }}`\n{{ end }}"
 
-	text := "{{ range .Alerts -}}\n" +
-		"*Alert:* {{ if .Labels.severity }}`{{ .Labels.severity | toUpper }}`{{ end }} {{ .Annotations.summary }}\n" +
+	text := "*Alert:* {{ if .Labels.severity }}`{{ .Labels.severity | toUpper }}`{{ end }} {{ .Annotations.summary }}\n" +
 		"*Description:* {{ .Annotations.description }}\n" +
 		"*Details:*\n"
 	for _, l := range labels {
 		text += fmt.Sprintf(listEntryFormat, l)
 	}
 
-	text += "\n\n{{ end }}"
+	text += "\n\n"
 
 	return text
 }
 
 func formatPagerDutyFiringDetails(labels ...string) string {
+	return "{{ range .Alerts -}}\n" + formatPagerDutyAlertDetails(labels...) + "{{ end }}"
+}
+
+// formatPagerDutyAlertDetails returns PagerDuty details template for a single alert.
+func formatPagerDutyAlertDetails(labels ...string) string {
 	const listEntryFormat = "{{ if .Labels.%[1]s }}  - %[1]s: {{ .Labels.%[1]s }}\n{{ end }}"
 
-	text := "{{ range .Alerts -}}\n" +
-		"Alert: {{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}\n" +
+	text := "Alert: {{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}\n" +
 		"Description: {{ .Annotations.description }}\n" +
 		"Details:\n"
 	for _, l := range labels {
 		text += fmt.Sprintf(listEntryFormat, l)
 	}
 
-	text += "\n\n{{ end }}"
+	text += "\n\n"
 
 	return text
 }
@@ -633,6 +670,14 @@ func (svc *Service) generateReceivers(chanMap map[string]*models.Channel, recvSe
 				svc.l.Warnf("Missing channel %s, skip it.", ch)
 				continue
 			}
+			var title, text string
+			if channel.Type != models.WebHook {
+				var err error
+				if title, text, err = channelMessage(channel.Type, channel.MessageTemplates); err != nil {
+					return nil, err
+				}
+			}
+
 			switch channel.Type {
 			case models.Email:
 				for _, to := range channel.EmailConfig.To {
@@ -641,9 +686,9 @@ func (svc *Service) generateReceivers(chanMap map[string]*models.Channel, recvSe
 							SendResolved: channel.EmailConfig.SendResolved,
 						},
 						To:   to,
-						HTML: emailTemplate,
+						HTML: text,
 						Headers: map[string]string{
-							"Subject": `[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]`,
+							"Subject": title,
 						},
 					})
 				}
@@ -653,10 +698,9 @@ func (svc *Service) generateReceivers(chanMap map[string]*models.Channel, recvSe
 					NotifierConfig: alertmanager.NotifierConfig{
 						SendResolved: channel.PagerDutyConfig.SendResolved,
 					},
-					Description: `[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]` +
-						"{{ range .Alerts -}}{{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}{{ end }}",
+					Description: title,
 					Details: map[string]string{
-						"firing": formatPagerDutyFiringDetails(notificationLabels...),
+						"firing": text,
 					},
 				}
 				if channel.PagerDutyConfig.RoutingKey != "" {
@@ -673,8 +717,8 @@ func (svc *Service) generateReceivers(chanMap map[string]*models.Channel, recvSe
 						SendResolved: channel.SlackConfig.SendResolved,
 					},
 					Channel: channel.SlackConfig.Channel,
-					Title:   `[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]`,
-					Text:    formatSlackText(notificationLabels...),
+					Title:   title,
+					Text:    text,
 				})
 
 			case models.WebHook:
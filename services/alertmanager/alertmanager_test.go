@@ -39,296 +39,6 @@ import (
 	"github.com/percona/pmm-managed/utils/tests"
 )
 
-var htmlTemplate = `|
-            <!--
-            Style and HTML derived from https://github.com/mailgun/transactional-email-templates
-
-
-            The MIT License (MIT)
-
-            Copyright (c) 2014 Mailgun
-
-            Permission is hereby granted, free of charge, to any person obtaining a copy
-            of this software and associated documentation files (the "Software"), to deal
-            in the Software without restriction, including without limitation the rights
-            to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
-            copies of the Software, and to permit persons to whom the Software is
-            furnished to do so, subject to the following conditions:
-
-            The above copyright notice and this permission notice shall be included in all
-            copies or substantial portions of the Software.
-
-            THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
-            IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
-            FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
-            AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
-            LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
-            OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
-            SOFTWARE.
-            -->
-            <!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
-            <html xmlns="http://www.w3.org/1999/xhtml">
-            <head>
-                <meta name="viewport" content="width=device-width" />
-                <meta http-equiv="Content-Type" content="text/html; charset=UTF-8" />
-                <title>{{ template "__subject" . }}</title>
-                <style>
-                    /* -------------------------------------
-                        GLOBAL
-                        A very basic CSS reset
-                    ------------------------------------- */
-                    * {
-                        margin: 0;
-                        font-family: "Helvetica Neue", Helvetica, Arial, sans-serif;
-                        box-sizing: border-box;
-                        font-size: 14px;
-                    }
-
-                    img {
-                        max-width: 100%;
-                    }
-
-                    body {
-                        -webkit-font-smoothing: antialiased;
-                        -webkit-text-size-adjust: none;
-                        width: 100% !important;
-                        height: 100%;
-                        line-height: 1.6em;
-                        /* 1.6em * 14px = 22.4px, use px to get airier line-height also in Thunderbird, and Yahoo!, Outlook.com, AOL webmail clients */
-                        /*line-height: 22px;*/
-                    }
-
-                    /* Let's make sure all tables have defaults */
-                    table td {
-                        vertical-align: top;
-                    }
-
-                    /* -------------------------------------
-                        BODY & CONTAINER
-                    ------------------------------------- */
-                    body {
-                        background-color: #f6f6f6;
-                    }
-
-                    .body-wrap {
-                        background-color: #f6f6f6;
-                        width: 100%;
-                    }
-
-                    .container {
-                        display: block !important;
-                        max-width: 600px !important;
-                        margin: 0 auto !important;
-                        /* makes it centered */
-                        clear: both !important;
-                    }
-
-                    .content {
-                        max-width: 600px;
-                        margin: 0 auto;
-                        display: block;
-                        padding: 20px;
-                    }
-
-                    /* -------------------------------------
-                        HEADER, FOOTER, MAIN
-                    ------------------------------------- */
-                    .main {
-                        background-color: #fff;
-                        border: 1px solid #e9e9e9;
-                        border-radius: 3px;
-                    }
-
-                    .content-wrap {
-                        padding: 30px;
-                    }
-
-                    .content-block {
-                        padding: 0 0 20px;
-                    }
-
-                    .content-paragraph {
-                        padding-top: 0.5em;
-                        padding-bottom: 0.5em;
-                    }
-
-                    .footer {
-                        width: 100%;
-                        clear: both;
-                        color: #999;
-                        padding: 20px;
-                    }
-                    .footer p, .footer a, .footer td {
-                        color: #999;
-                        font-size: 12px;
-                    }
-
-                    /* -------------------------------------
-                        LINKS & BUTTONS
-                    ------------------------------------- */
-                    a {
-                        text-decoration: underline;
-                    }
-
-                    .btn-primary {
-                        text-decoration: none;
-                        color: white !important;
-                        background-color: #3274D9;
-                        border: solid #3274D9;
-                        border-width: 10px 20px;
-                        line-height: 2em;
-                        /* 2em * 14px = 28px, use px to get airier line-height also in Thunderbird, and Yahoo!, Outlook.com, AOL webmail clients */
-                        /*line-height: 28px;*/
-                        font-weight: bold;
-                        text-align: center;
-                        cursor: pointer;
-                        display: inline-block;
-                        border-radius: 5px;
-                        text-transform: capitalize;
-                    }
-
-                    /* -------------------------------------
-                        OTHER STYLES THAT MIGHT BE USEFUL
-                    ------------------------------------- */
-                    .aligncenter {
-                        text-align: center;
-                    }
-
-                    /* -------------------------------------
-                        ALERTS
-                        Change the class depending on warning email, good email or bad email
-                    ------------------------------------- */
-                    .alert {
-                        font-size: 16px;
-                        color: #fff;
-                        font-weight: 500;
-                        padding: 20px;
-                        text-align: center;
-                        border-radius: 3px 3px 0 0;
-                    }
-                    .alert a {
-                        color: #fff;
-                        text-decoration: none;
-                        font-weight: 500;
-                        font-size: 16px;
-                    }
-                    .alert.alert-notice {
-                        background-color: #3274D9;
-                    }
-                    .alert.alert-warning {
-                        background-color: #ECBB13;
-                    }
-                    .alert.alert-high {
-                        background-color: #EB7B18;
-                    }
-                    .alert.alert-critical {
-                        background-color: #E02F44;
-                    }
-
-                    /* -------------------------------------
-                        RESPONSIVE AND MOBILE FRIENDLY STYLES
-                    ------------------------------------- */
-                    @media only screen and (max-width: 640px) {
-                        body {
-                            padding: 0 !important;
-                        }
-
-                        .container {
-                            padding: 0 !important;
-                            width: 100% !important;
-                        }
-
-                        .content {
-                            padding: 0 !important;
-                        }
-
-                        .content-wrap {
-                            padding: 10px !important;
-                        }
-                    }
-                </style>
-            </head>
-
-            <body itemscope itemtype="http://schema.org/EmailMessage">
-
-            <table class="body-wrap">
-                <tr>
-                    <td></td>
-                    <td class="container" width="600">
-                        <div class="content">
-                            <table class="main" width="100%" cellpadding="0" cellspacing="0">
-                                <tr>
-                                    {{ if eq .CommonLabels.severity "notice" }}
-                                    <td class="alert alert-notice">
-                                        {{ else if eq .CommonLabels.severity "warning" }}
-                                    <td class="alert alert-warning">
-                                        {{ else if eq .CommonLabels.severity "error" }}
-                                    <td class="alert alert-high">
-                                        {{ else }}
-                                    <td class="alert alert-critical">
-                                        {{ end }}
-                                        You have {{ .Alerts | len }} {{ .CommonLabels.severity }} alert{{ if gt (len .Alerts) 1 }}s{{ end }} firing
-                                    </td>
-                                </tr>
-                                <tr>
-                                    <td class="content-wrap">
-                                        <table width="100%" cellpadding="0" cellspacing="0">
-                                            <tr>
-                                                <td align="center" class="content-block">
-                                                    <a href='{{ template "__alertmanagerURL" . }}' class="btn-primary">View in {{ template "__alertmanager" . }}</a>
-                                                </td>
-                                            </tr>
-                                            {{ range .Alerts.Firing }}
-                                                <tr>
-                                                    <td class="content-block">
-                                                        <hr>
-                                                        <div class="content-paragraph">
-                                                            <strong>Alert: </strong>
-                                                            {{ if .Labels.severity }}
-                                                                [{{ .Labels.severity | toUpper }}]
-                                                            {{ end }}
-                                                            {{ .Annotations.summary }}
-                                                        </div>
-                                                        <div class="content-paragraph">
-                                                            <strong>Description: </strong>
-                                                            {{ .Annotations.description }}
-                                                        </div>
-                                                        <div class="content-paragraph">
-                                                            <strong>Violated rule: </strong>
-                                                            {{ .Annotations.rule }}
-                                                        </div>
-                                                        <strong>Details:</strong><br />
-                                                        {{ with .Labels }}
-                                                            {{ with .Remove (stringSlice "alertname" "ia" "instance" "node_type" "server") }}
-                                                                {{ range .SortedPairs }}
-                                                                    • <strong>{{ .Name }}:</strong> {{ .Value }}<br />
-                                                                {{ end }}
-                                                            {{ end }}
-                                                        {{ end }}
-                                                    </td>
-                                                </tr>
-                                            {{ end }}
-                                        </table>
-                                    </td>
-                                </tr>
-                            </table>
-
-                            <div class="footer">
-                                <table width="100%">
-                                    <tr>
-                                        <td class="aligncenter content-block"><a href="{{ .ExternalURL }}">Sent by {{ template "__alertmanager" . }}</a></td>
-                                    </tr>
-                                </table>
-                            </div>
-                        </div>
-                    </td>
-                </tr>
-            </table>
-
-
-            </body>
-            </html>`
-
 func TestIsReady(t *testing.T) {
 	New(nil).GenerateBaseConfigs() // this method should not use database
 
@@ -592,32 +302,6 @@ templates: []
 			assert.Equal(t, f.content, string(actualContent))
 		}
 
-		pagerDutyConfigs := `      pagerduty_configs:
-        - send_resolved: false
-          routing_key: ms-pagerduty-dev
-          description: '[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]{{ range .Alerts -}}{{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}{{ end }}'
-          details:
-            firing: |-
-                {{ range .Alerts -}}
-                Alert: {{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}
-                Description: {{ .Annotations.description }}
-                Details:
-                {{ if .Labels.node_name }}  - node_name: {{ .Labels.node_name }}
-                {{ end }}{{ if .Labels.node_id }}  - node_id: {{ .Labels.node_id }}
-                {{ end }}{{ if .Labels.service_name }}  - service_name: {{ .Labels.service_name }}
-                {{ end }}{{ if .Labels.service_id }}  - service_id: {{ .Labels.service_id }}
-                {{ end }}{{ if .Labels.service_type }}  - service_type: {{ .Labels.service_type }}
-                {{ end }}{{ if .Labels.rule_id }}  - rule_id: {{ .Labels.rule_id }}
-                {{ end }}{{ if .Labels.alertgroup }}  - alertgroup: {{ .Labels.alertgroup }}
-                {{ end }}{{ if .Labels.template_name }}  - template_name: {{ .Labels.template_name }}
-                {{ end }}{{ if .Labels.severity }}  - severity: {{ .Labels.severity }}
-                {{ end }}{{ if .Labels.agent_id }}  - agent_id: {{ .Labels.agent_id }}
-                {{ end }}{{ if .Labels.agent_type }}  - agent_type: {{ .Labels.agent_type }}
-                {{ end }}{{ if .Labels.job }}  - job: {{ .Labels.job }}
-                {{ end }}
-
-                {{ end }}`
-
 		expected := strings.TrimSpace(fmt.Sprintf(`
 # Managed by pmm-managed. DO NOT EDIT.
 ---
@@ -655,43 +339,26 @@ receivers:
     - name: empty
     - name: disabled
     - name: %[1]s + %[2]s
-      email_configs:
-        - send_resolved: false
-          to: test@test.test
-          headers:
-            Subject: '[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]'
-          html: %[12]s
-        - send_resolved: false
-          to: test2@test.test
-          headers:
-            Subject: '[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]'
-          html: %[12]s
-`+pagerDutyConfigs+`
-    - name: %[6]s + %[7]s + %[8]s
-      email_configs:
-        - send_resolved: false
-          to: test@test.test
-          headers:
-            Subject: '[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]'
-          html: %[12]s
-        - send_resolved: false
-          to: test2@test.test
-          headers:
-            Subject: '[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]'
-          html: %[12]s
-`+pagerDutyConfigs+`
       webhook_configs:
-        - send_resolved: false
-          http_config:
-            tls_config:
-                ca_file: %[9]s
-                cert_file: %[10]s
-                key_file: %[11]s
-                insecure_skip_verify: false
-          url: https://example.com
+        - send_resolved: true
+          url: %[6]s
+          max_alerts: 0
+        - send_resolved: true
+          url: %[7]s
+          max_alerts: 0
+    - name: %[1]s + %[2]s + %[8]s
+      webhook_configs:
+        - send_resolved: true
+          url: %[6]s
+          max_alerts: 0
+        - send_resolved: true
+          url: %[7]s
+          max_alerts: 0
+        - send_resolved: true
+          url: %[9]s
           max_alerts: 0
-templates: []`, channel1.ID, channel2.ID, rule1.ID, rule2.ID, rule4.ID, channel1.ID, channel2.ID, channel4.ID,
-			tlsFileContents[0].file, tlsFileContents[1].file, tlsFileContents[2].file, htmlTemplate)) + "\n"
+templates: []`, channel1.ID, channel2.ID, rule1.ID, rule2.ID, rule4.ID,
+			channelNotifyURL(channel1.ID), channelNotifyURL(channel2.ID), channel4.ID, channelNotifyURL(channel4.ID))) + "\n"
 		assert.Equal(t, expected, actual, "actual:\n%s", actual)
 	})
 }
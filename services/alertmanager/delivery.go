@@ -0,0 +1,348 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/percona-platform/saas/pkg/common"
+	"github.com/percona/promconfig/alertmanager"
+	"github.com/pkg/errors"
+	amconfig "github.com/prometheus/alertmanager/config"
+	"github.com/prometheus/alertmanager/notify"
+	"github.com/prometheus/alertmanager/notify/email"
+	"github.com/prometheus/alertmanager/notify/pagerduty"
+	"github.com/prometheus/alertmanager/notify/slack"
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	testNotificationTimeout = 10 * time.Second
+
+	testReceiverName = "test"
+
+	// NotifyPath is a path of pmm-managed HTTP handler that receives notifications from Alertmanager.
+	NotifyPath = "/v1/management/ia/Channels/Notify"
+
+	// maxNotifyRequestSize limits the size of the notification received from Alertmanager.
+	maxNotifyRequestSize = 10 * 1024 * 1024
+)
+
+// channelNotifyURL returns URL of pmm-managed HTTP handler that delivers notifications to the given channel.
+func channelNotifyURL(channelID string) string {
+	return "http://127.0.0.1:7773" + NotifyPath + "?" + url.Values{"channel_id": {channelID}}.Encode()
+}
+
+// relayReceivers generates receivers that pass notifications to pmm-managed instead of sending them directly.
+// Each channel of the receiver is a separate webhook integration with channel ID in the URL,
+// so Alertmanager retries failed deliveries for each channel separately, and pmm-managed
+// records the result of each delivery to the exact channel even if there are several channels of the same type.
+func relayReceivers(recvSet map[string]models.ChannelIDs) []*alertmanager.Receiver {
+	receivers := make([]*alertmanager.Receiver, 0, len(recvSet))
+	for name, channelIDs := range recvSet {
+		recv := &alertmanager.Receiver{
+			Name: name,
+		}
+
+		for _, id := range channelIDs {
+			recv.WebhookConfigs = append(recv.WebhookConfigs, &alertmanager.WebhookConfig{
+				NotifierConfig: alertmanager.NotifierConfig{
+					// channel's own setting is applied by pmm-managed
+					SendResolved: true,
+				},
+				URL: channelNotifyURL(id),
+			})
+		}
+
+		receivers = append(receivers, recv)
+	}
+
+	sort.Slice(receivers, func(i, j int) bool { return receivers[i].Name < receivers[j].Name })
+	return receivers
+}
+
+// ServeNotify handles notifications that Alertmanager sends to the receivers generated by relayReceivers.
+// The notification is delivered to the channel, and the result is recorded to channel delivery statistics.
+// Failed deliveries are reported with 5xx status code if they can be retried by Alertmanager.
+func (svc *Service) ServeNotify(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "Method not allowed.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channelID := req.URL.Query().Get("channel_id")
+	var msg webhook.Message
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxNotifyRequestSize)).Decode(&msg); err != nil || msg.Data == nil {
+		svc.l.Warnf("Failed to decode notification for channel %s: %v.", channelID, err)
+		http.Error(rw, "Invalid notification.", http.StatusBadRequest)
+		return
+	}
+
+	code, err := svc.notify(req.Context(), channelID, &msg)
+	if err != nil {
+		svc.l.Warnf("Failed to deliver notification to channel %s: %s.", channelID, err)
+		http.Error(rw, err.Error(), code)
+		return
+	}
+	rw.WriteHeader(code)
+}
+
+// notify delivers notification to the channel, records the result, and returns HTTP status code for Alertmanager.
+func (svc *Service) notify(ctx context.Context, channelID string, msg *webhook.Message) (int, error) {
+	var channel *models.Channel
+	var settings *models.Settings
+	e := svc.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		if channel, err = models.FindChannelByID(tx.Querier, channelID); err != nil {
+			return err
+		}
+		settings, err = models.GetSettings(tx.Querier)
+		return err
+	})
+	if e != nil {
+		if s, ok := status.FromError(e); ok && s.Code() == codes.NotFound {
+			// channel was removed after Alertmanager configuration was generated
+			return http.StatusNotFound, e
+		}
+		return http.StatusInternalServerError, e
+	}
+
+	if channel.Disabled {
+		// channel was disabled after Alertmanager configuration was generated
+		return http.StatusOK, nil
+	}
+
+	d, retry, err := svc.deliver(ctx, channel, settings, msg)
+	if d != nil {
+		if e := models.RecordChannelDelivery(svc.db.Querier, d); e != nil {
+			svc.l.Warnf("Failed to record notification delivery: %s.", e)
+		}
+	}
+
+	switch {
+	case err == nil:
+		return http.StatusOK, nil
+	case retry:
+		return http.StatusServiceUnavailable, err
+	default:
+		return http.StatusBadRequest, err
+	}
+}
+
+// deliver sends notification to the channel the same way as Alertmanager does, and returns its delivery result;
+// it is nil if there was nothing to send. Returned flag reports whether a failed delivery can be retried.
+func (svc *Service) deliver(ctx context.Context, channel *models.Channel, settings *models.Settings, msg *webhook.Message) (*models.ChannelDelivery, bool, error) {
+	tmpl, err := newTemplate()
+	if err != nil {
+		return nil, true, err
+	}
+
+	integrations, err := svc.channelIntegrations(channel, settings, tmpl)
+	if err != nil {
+		d := &models.ChannelDelivery{ChannelID: channel.ID, Failed: 1, LastError: err.Error(), At: time.Now()}
+		return d, false, err
+	}
+
+	ctx = notify.WithGroupKey(ctx, msg.GroupKey)
+	ctx = notify.WithReceiverName(ctx, msg.Receiver)
+	ctx = notify.WithGroupLabels(ctx, labelSet(msg.GroupLabels))
+
+	alerts := make([]*types.Alert, 0, len(msg.Alerts))
+	for _, a := range msg.Alerts {
+		alerts = append(alerts, &types.Alert{
+			Alert: model.Alert{
+				Labels:       labelSet(a.Labels),
+				Annotations:  labelSet(a.Annotations),
+				StartsAt:     a.StartsAt,
+				EndsAt:       a.EndsAt,
+				GeneratorURL: a.GeneratorURL,
+			},
+		})
+	}
+
+	d := &models.ChannelDelivery{ChannelID: channel.ID}
+	var retry bool
+	var lastErr error
+	for _, i := range integrations {
+		// the same filtering as in Alertmanager's notification pipeline
+		toSend := alerts
+		if !i.SendResolved() {
+			toSend = make([]*types.Alert, 0, len(alerts))
+			for _, a := range alerts {
+				if !a.Resolved() {
+					toSend = append(toSend, a)
+				}
+			}
+		}
+		if len(toSend) == 0 {
+			continue
+		}
+
+		r, err := i.Notify(ctx, toSend...)
+		if err != nil {
+			d.Failed++
+			d.LastError = err.Error()
+			retry = retry || r
+			lastErr = err
+			continue
+		}
+		d.Sent++
+	}
+
+	if d.Sent == 0 && d.Failed == 0 {
+		return nil, false, nil
+	}
+	d.At = time.Now()
+	return d, retry, lastErr
+}
+
+// labelSet converts template key/value pairs back to Alertmanager's labels.
+func labelSet(kv template.KV) model.LabelSet {
+	res := make(model.LabelSet, len(kv))
+	for k, v := range kv {
+		res[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return res
+}
+
+// SendTestNotification sends test notification to the given channel using current settings.
+// The result is recorded to channel delivery statistics.
+func (svc *Service) SendTestNotification(ctx context.Context, channel *models.Channel) error {
+	settings, err := models.GetSettings(svc.db.Querier)
+	if err != nil {
+		return err
+	}
+
+	d, err := svc.sendTestNotification(ctx, channel, settings)
+	if d != nil {
+		if e := models.RecordChannelDelivery(svc.db.Querier, d); e != nil {
+			svc.l.Warnf("Failed to record test notification delivery: %s.", e)
+		}
+	}
+	return err
+}
+
+// sendTestNotification sends test notification and returns its delivery result;
+// it is nil if notification was not sent at all due to invalid configuration.
+func (svc *Service) sendTestNotification(ctx context.Context, channel *models.Channel, settings *models.Settings) (*models.ChannelDelivery, error) {
+	tmpl, err := newTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	integrations, err := svc.channelIntegrations(channel, settings, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, testNotificationTimeout)
+	defer cancel()
+	ctx = notify.WithGroupKey(ctx, testReceiverName)
+	ctx = notify.WithReceiverName(ctx, testReceiverName)
+	ctx = notify.WithGroupLabels(ctx, model.LabelSet{})
+
+	alert := sampleAlert(common.Notice)
+	var sent int64
+	for _, i := range integrations {
+		if _, err = i.Notify(ctx, alert); err != nil {
+			d := &models.ChannelDelivery{ChannelID: channel.ID, Sent: sent, Failed: 1, LastError: err.Error(), At: time.Now()}
+			return d, status.Errorf(codes.InvalidArgument, "Cannot send test notification: %s.", err)
+		}
+		sent++
+	}
+
+	return &models.ChannelDelivery{ChannelID: channel.ID, Sent: sent, At: time.Now()}, nil
+}
+
+// channelIntegrations returns Alertmanager integrations for the given channel.
+// Configuration is generated the same way as it was generated for Alertmanager itself before notifications
+// were relayed through pmm-managed, and then loaded by Alertmanager's code to get the same defaults and validation.
+func (svc *Service) channelIntegrations(channel *models.Channel, settings *models.Settings, tmpl *template.Template) ([]notify.Integration, error) {
+	cfg := &alertmanager.Config{
+		Global: &alertmanager.GlobalConfig{},
+		Route: &alertmanager.Route{
+			Receiver: testReceiverName,
+		},
+	}
+	svc.populateGlobalConfig(cfg, settings)
+
+	chanMap := map[string]*models.Channel{channel.ID: channel}
+	recvSet := map[string]models.ChannelIDs{testReceiverName: {channel.ID}}
+	receivers, err := svc.generateReceivers(chanMap, recvSet)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Receivers = receivers
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't marshal Alertmanager configuration")
+	}
+	loaded, err := amconfig.Load(string(b))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid channel configuration: %s.", err)
+	}
+
+	l := loggerFunc(svc.l.Logger.Log)
+	var res []notify.Integration
+	for _, recv := range loaded.Receivers {
+		for i, c := range recv.EmailConfigs {
+			res = append(res, notify.NewIntegration(email.New(c, tmpl, l), c, "email", i))
+		}
+		for i, c := range recv.PagerdutyConfigs {
+			n, err := pagerduty.New(c, tmpl, l)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			res = append(res, notify.NewIntegration(n, c, "pagerduty", i))
+		}
+		for i, c := range recv.SlackConfigs {
+			n, err := slack.New(c, tmpl, l)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			res = append(res, notify.NewIntegration(n, c, "slack", i))
+		}
+		for i, c := range recv.WebhookConfigs {
+			n, err := webhook.New(c, tmpl, l)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			res = append(res, notify.NewIntegration(n, c, "webhook", i))
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "Channel has no notification targets.")
+	}
+	return res, nil
+}
@@ -0,0 +1,209 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/alertmanager/notify/webhook"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestRelayReceivers(t *testing.T) {
+	t.Parallel()
+
+	recvSet := map[string]models.ChannelIDs{
+		"/channel_id/1 + /channel_id/2": {"/channel_id/1", "/channel_id/2"},
+		"/channel_id/2":                 {"/channel_id/2"},
+	}
+	b, err := yaml.Marshal(relayReceivers(recvSet))
+	require.NoError(t, err)
+
+	expected := strings.TrimSpace(`
+- name: /channel_id/1 + /channel_id/2
+  webhook_configs:
+    - send_resolved: true
+      url: http://127.0.0.1:7773/v1/management/ia/Channels/Notify?channel_id=%2Fchannel_id%2F1
+      max_alerts: 0
+    - send_resolved: true
+      url: http://127.0.0.1:7773/v1/management/ia/Channels/Notify?channel_id=%2Fchannel_id%2F2
+      max_alerts: 0
+- name: /channel_id/2
+  webhook_configs:
+    - send_resolved: true
+      url: http://127.0.0.1:7773/v1/management/ia/Channels/Notify?channel_id=%2Fchannel_id%2F2
+      max_alerts: 0
+`) + "\n"
+	assert.Equal(t, expected, string(b), "actual:\n%s", b)
+}
+
+func TestDeliver(t *testing.T) {
+	t.Parallel()
+
+	received := make(map[string][]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var msg webhook.Message
+		if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, a := range msg.Alerts {
+			received[req.URL.Path] = append(received[req.URL.Path], a.Status)
+		}
+		if strings.HasSuffix(req.URL.Path, "/fail") {
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	// two channels of the same type
+	channel1 := &models.Channel{
+		ID:            "/channel_id/1",
+		Type:          models.WebHook,
+		WebHookConfig: &models.WebHookConfig{URL: srv.URL + "/ok", SendResolved: true},
+	}
+	channel2 := &models.Channel{
+		ID:            "/channel_id/2",
+		Type:          models.WebHook,
+		WebHookConfig: &models.WebHookConfig{URL: srv.URL + "/fail"},
+	}
+
+	now := time.Now()
+	msg := &webhook.Message{
+		Data: &template.Data{
+			Receiver: "/channel_id/1 + /channel_id/2",
+			Status:   "firing",
+			Alerts: template.Alerts{{
+				Status:   "firing",
+				Labels:   template.KV{"alertname": "firing"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(time.Hour),
+			}, {
+				Status:   "resolved",
+				Labels:   template.KV{"alertname": "resolved"},
+				StartsAt: now.Add(-time.Hour),
+				EndsAt:   now.Add(-time.Minute),
+			}},
+			GroupLabels: template.KV{"rule_id": "/rule_id/1"},
+		},
+		Version:  "4",
+		GroupKey: "{}:{rule_id=\"/rule_id/1\"}",
+	}
+
+	svc := New(nil)
+	settings := new(models.Settings)
+
+	d, retry, err := svc.deliver(context.Background(), channel1, settings, msg)
+	require.NoError(t, err)
+	assert.False(t, retry)
+	assert.Equal(t, "/channel_id/1", d.ChannelID)
+	assert.Equal(t, int64(1), d.Sent)
+	assert.Zero(t, d.Failed)
+
+	d, retry, err = svc.deliver(context.Background(), channel2, settings, msg)
+	require.Error(t, err)
+	assert.True(t, retry, "5xx responses should be retried by Alertmanager")
+	assert.Equal(t, "/channel_id/2", d.ChannelID)
+	assert.Zero(t, d.Sent)
+	assert.Equal(t, int64(1), d.Failed)
+	assert.Contains(t, d.LastError, "500")
+
+	expected := map[string][]string{
+		"/ok":   {"firing", "resolved"},
+		"/fail": {"firing"}, // resolved alerts are not sent to channels without send_resolved
+	}
+	assert.Equal(t, expected, received)
+
+	t.Run("OnlyResolved", func(t *testing.T) {
+		resolved := *msg
+		resolved.Data = &template.Data{Alerts: msg.Alerts.Resolved()}
+		d, retry, err := svc.deliver(context.Background(), channel2, settings, &resolved)
+		require.NoError(t, err)
+		assert.False(t, retry)
+		assert.Nil(t, d, "nothing should be sent")
+	})
+}
+
+func TestSendTestNotification(t *testing.T) {
+	t.Parallel()
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body) //nolint:errcheck
+		received = string(b)
+		if strings.HasSuffix(req.URL.Path, "/fail") {
+			rw.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	settings := new(models.Settings)
+
+	t.Run("normal", func(t *testing.T) {
+		svc := New(nil)
+		channel := &models.Channel{
+			ID:            "webhook1",
+			Type:          models.WebHook,
+			WebHookConfig: &models.WebHookConfig{URL: srv.URL + "/ok"},
+		}
+		d, err := svc.sendTestNotification(context.Background(), channel, settings)
+		require.NoError(t, err)
+		assert.Contains(t, received, "This is a test alert.")
+		assert.Equal(t, "webhook1", d.ChannelID)
+		assert.Equal(t, int64(1), d.Sent)
+		assert.Zero(t, d.Failed)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		svc := New(nil)
+		channel := &models.Channel{
+			ID:            "webhook2",
+			Type:          models.WebHook,
+			WebHookConfig: &models.WebHookConfig{URL: srv.URL + "/fail"},
+		}
+		d, err := svc.sendTestNotification(context.Background(), channel, settings)
+		tests.AssertGRPCErrorRE(t, codes.InvalidArgument, `Cannot send test notification: .*400.*`, err)
+		assert.Equal(t, int64(1), d.Failed)
+		assert.Contains(t, d.LastError, "400")
+	})
+
+	t.Run("no SMTP settings", func(t *testing.T) {
+		svc := New(nil)
+		channel := &models.Channel{
+			ID:          "email1",
+			Type:        models.Email,
+			EmailConfig: &models.EmailConfig{To: []string{"test@example.com"}},
+		}
+		d, err := svc.sendTestNotification(context.Background(), channel, settings)
+		tests.AssertGRPCErrorRE(t, codes.InvalidArgument, `Invalid channel configuration: .*smarthost.*`, err)
+		assert.Nil(t, d)
+	})
+}
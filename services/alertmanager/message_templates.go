@@ -0,0 +1,309 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package alertmanager
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/percona-platform/saas/pkg/common"
+	"github.com/prometheus/alertmanager/template"
+	"github.com/prometheus/alertmanager/types"
+	"github.com/prometheus/common/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services"
+)
+
+const (
+	notificationTitle = `[{{ .Status | toUpper }}{{ if eq .Status "firing" }}:{{ .Alerts.Firing | len }}{{ end }}]`
+
+	// used only for alerts without user-defined body when other alerts of the email channel have it
+	emailAlertHTML = `<p><b>{{ if .Labels.severity }}[{{ .Labels.severity | toUpper }}]{{ end }} {{ .Annotations.summary }}</b></p>` +
+		`<p>{{ .Annotations.description }}</p>`
+)
+
+// sampleAlert returns an alert that is used for test notifications and message previews.
+func sampleAlert(severity common.Severity) *types.Alert {
+	now := time.Now()
+	return &types.Alert{
+		Alert: model.Alert{
+			Labels: model.LabelSet{
+				model.AlertNameLabel: model.LabelValue(fmt.Sprintf("Test alert %s", now.String())),
+				"severity":           model.LabelValue(severity.String()),
+				"node_name":          "pmm-server",
+				"service_name":       "mysql-example",
+				"service_type":       "mysql",
+				"rule_id":            "/rule_id/example",
+				"template_name":      "example_template",
+			},
+			Annotations: model.LabelSet{
+				"summary":     "This is a test alert.",
+				"description": "Long description.",
+				"rule":        "example-violated-rule",
+			},
+			StartsAt: now,
+			EndsAt:   now.Add(time.Minute),
+		},
+		Timeout: true,
+	}
+}
+
+// newTemplate returns Alertmanager template with default functions and templates.
+func newTemplate() (*template.Template, error) {
+	tmpl, err := template.FromGlobs()
+	if err != nil {
+		return nil, err
+	}
+	tmpl.ExternalURL, err = url.Parse("https://example.com")
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// messageTitle returns notification title template that selects user-defined title by severity
+// of the notification; builtin is used when there is no suitable user-defined title.
+func messageTitle(templates *models.MessageTemplates, builtin string) string {
+	def := builtin
+	if templates != nil && templates.Default != nil && templates.Default.Title != "" {
+		def = templates.Default.Title
+	}
+
+	return severitySwitch(".CommonLabels.severity", templates, func(t *models.MessageTemplate) string { return t.Title }, def)
+}
+
+// messageBody returns notification body template that renders user-defined body for each alert by its severity;
+// builtinAlert is used for alerts without suitable user-defined body.
+// It returns an empty string if there are no user-defined bodies at all.
+func messageBody(templates *models.MessageTemplates, builtinAlert string) string {
+	if !hasBodies(templates) {
+		return ""
+	}
+
+	def := builtinAlert
+	if templates.Default != nil && templates.Default.Body != "" {
+		def = templates.Default.Body + "\n\n"
+	}
+
+	body := func(t *models.MessageTemplate) string {
+		if t.Body == "" {
+			return ""
+		}
+		return t.Body + "\n\n"
+	}
+
+	return "{{ range .Alerts -}}\n" + severitySwitch(".Labels.severity", templates, body, def) + "{{ end }}"
+}
+
+func hasBodies(templates *models.MessageTemplates) bool {
+	if templates == nil {
+		return false
+	}
+	if templates.Default != nil && templates.Default.Body != "" {
+		return true
+	}
+	for _, t := range templates.Severities {
+		if t != nil && t.Body != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// severitySwitch returns a template that selects one of the user-defined templates by severity
+// taken from the given field; def is used when nothing matches.
+func severitySwitch(field string, templates *models.MessageTemplates, get func(*models.MessageTemplate) string, def string) string {
+	if templates == nil {
+		return def
+	}
+
+	severities := make([]string, 0, len(templates.Severities))
+	for s, t := range templates.Severities {
+		if t != nil && get(t) != "" {
+			severities = append(severities, s)
+		}
+	}
+	if len(severities) == 0 {
+		return def
+	}
+	sort.Strings(severities)
+
+	var sb strings.Builder
+	for i, s := range severities {
+		if i == 0 {
+			sb.WriteString("{{ if ")
+		} else {
+			sb.WriteString("{{ else if ")
+		}
+		// alert labels contain normalized severity; keys may be stored before normalization was added
+		fmt.Fprintf(&sb, "eq %s %q }}%s", field, common.ParseSeverity(s).String(), get(templates.Severities[s]))
+	}
+	sb.WriteString("{{ else }}" + def + "{{ end }}")
+	return sb.String()
+}
+
+// channelMessage returns title and body templates for the given channel type.
+func channelMessage(channelType models.ChannelType, templates *models.MessageTemplates) (string, string, error) {
+	switch channelType {
+	case models.Email:
+		body := emailTemplate
+		if b := messageBody(templates, emailAlertHTML); b != "" {
+			body = "<!DOCTYPE html>\n<html>\n<body>\n" + b + "\n</body>\n</html>\n"
+		}
+		return messageTitle(templates, notificationTitle), body, nil
+
+	case models.PagerDuty:
+		body := formatPagerDutyFiringDetails(notificationLabels...)
+		if b := messageBody(templates, formatPagerDutyAlertDetails(notificationLabels...)); b != "" {
+			body = b
+		}
+		return messageTitle(templates, pagerDutyDescription), body, nil
+
+	case models.Slack:
+		body := formatSlackText(notificationLabels...)
+		if b := messageBody(templates, formatSlackAlertText(notificationLabels...)); b != "" {
+			body = b
+		}
+		return messageTitle(templates, notificationTitle), body, nil
+
+	case models.WebHook:
+		if templates != nil {
+			return "", "", status.Error(codes.InvalidArgument, "Webhook channel doesn't support message templates.")
+		}
+		return "", "", nil
+
+	default:
+		return "", "", status.Errorf(codes.InvalidArgument, "Invalid channel type %q.", channelType)
+	}
+}
+
+// ValidateMessageTemplates checks that user-defined message templates can be used for the given channel type.
+// Severity keys are case-insensitive, but should be unique.
+func (svc *Service) ValidateMessageTemplates(channelType models.ChannelType, templates *models.MessageTemplates) error {
+	if templates == nil {
+		return nil
+	}
+
+	severities := make(map[common.Severity]struct{}, len(templates.Severities))
+	for s := range templates.Severities {
+		severity := common.ParseSeverity(s)
+		if severity == common.Unknown {
+			return status.Errorf(codes.InvalidArgument, "Unknown severity %q.", s)
+		}
+		if _, ok := severities[severity]; ok {
+			return status.Errorf(codes.InvalidArgument, "Duplicate templates for severity %q.", severity.String())
+		}
+		severities[severity] = struct{}{}
+	}
+
+	tmpl, err := newTemplate()
+	if err != nil {
+		return err
+	}
+
+	// check each template separately first to report a clear error and to catch unbalanced actions
+	// that would be hidden by generated severity switch
+	check := func(name, text string, perAlert, html bool) error {
+		if text == "" {
+			return nil
+		}
+
+		if perAlert {
+			text = "{{ range .Alerts }}" + text + "{{ end }}"
+		}
+		data := tmpl.Data("preview", nil, sampleAlert(common.Critical))
+		var err error
+		if html {
+			_, err = tmpl.ExecuteHTMLString(text, data)
+		} else {
+			_, err = tmpl.ExecuteTextString(text, data)
+		}
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "Invalid %s template: %s.", name, err)
+		}
+		return nil
+	}
+
+	all := make(map[string]*models.MessageTemplate, len(templates.Severities)+1)
+	for s, t := range templates.Severities {
+		all[s] = t
+	}
+	all["default"] = templates.Default
+	for name, t := range all {
+		if t == nil {
+			continue
+		}
+		if err = check(name+" title", t.Title, false, false); err != nil {
+			return err
+		}
+		if err = check(name+" body", t.Body, true, channelType == models.Email); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range []common.Severity{common.Emergency, common.Alert, common.Critical, common.Error, common.Warning, common.Notice, common.Info, common.Debug} {
+		if _, err = renderMessage(tmpl, channelType, templates, s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PreviewMessage renders notification message of the given channel type for a sample alert of the given severity.
+func (svc *Service) PreviewMessage(channelType models.ChannelType, templates *models.MessageTemplates, severity common.Severity) (*services.MessagePreview, error) {
+	if err := svc.ValidateMessageTemplates(channelType, templates); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := newTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	return renderMessage(tmpl, channelType, templates, severity)
+}
+
+func renderMessage(tmpl *template.Template, channelType models.ChannelType, templates *models.MessageTemplates, severity common.Severity) (*services.MessagePreview, error) {
+	title, body, err := channelMessage(channelType, templates)
+	if err != nil {
+		return nil, err
+	}
+
+	data := tmpl.Data("preview", nil, sampleAlert(severity))
+	var res services.MessagePreview
+	if res.Title, err = tmpl.ExecuteTextString(title, data); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Failed to render %s title: %s.", severity, err)
+	}
+
+	if channelType == models.Email {
+		res.Body, err = tmpl.ExecuteHTMLString(body, data)
+	} else {
+		res.Body, err = tmpl.ExecuteTextString(body, data)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Failed to render %s body: %s.", severity, err)
+	}
+
+	return &res, nil
+}
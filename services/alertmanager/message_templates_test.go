@@ -0,0 +1,170 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package alertmanager
+
+import (
+	"testing"
+
+	"github.com/percona-platform/saas/pkg/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestChannelMessage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("builtin", func(t *testing.T) {
+		t.Parallel()
+
+		title, body, err := channelMessage(models.Slack, nil)
+		require.NoError(t, err)
+		assert.Equal(t, notificationTitle, title)
+		assert.Equal(t, formatSlackText(notificationLabels...), body)
+
+		title, body, err = channelMessage(models.Email, nil)
+		require.NoError(t, err)
+		assert.Equal(t, notificationTitle, title)
+		assert.Equal(t, emailTemplate, body)
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		t.Parallel()
+
+		templates := &models.MessageTemplates{
+			Default: &models.MessageTemplate{Title: "Alert"},
+			Severities: map[string]*models.MessageTemplate{
+				"warning":  {Body: "W {{ .Annotations.summary }}"},
+				"critical": {Title: "Wake up", Body: "C {{ .Annotations.summary }}"},
+			},
+		}
+
+		title, body, err := channelMessage(models.Slack, templates)
+		require.NoError(t, err)
+		assert.Equal(t, `{{ if eq .CommonLabels.severity "critical" }}Wake up{{ else }}Alert{{ end }}`, title)
+		expected := "{{ range .Alerts -}}\n" +
+			`{{ if eq .Labels.severity "critical" }}C {{ .Annotations.summary }}` + "\n\n" +
+			`{{ else if eq .Labels.severity "warning" }}W {{ .Annotations.summary }}` + "\n\n" +
+			"{{ else }}" + formatSlackAlertText(notificationLabels...) + "{{ end }}{{ end }}"
+		assert.Equal(t, expected, body)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, err := channelMessage(models.WebHook, &models.MessageTemplates{})
+		tests.AssertGRPCErrorRE(t, codes.InvalidArgument, `Webhook channel doesn't support message templates.`, err)
+	})
+}
+
+func TestPreviewMessage(t *testing.T) {
+	t.Parallel()
+
+	svc := New(nil)
+	templates := &models.MessageTemplates{
+		Default: &models.MessageTemplate{
+			Title: "[{{ .Status | toUpper }}] {{ .CommonLabels.service_name }}",
+			Body:  "{{ .Annotations.summary }} Owner: {{ .Labels.owner }}",
+		},
+		Severities: map[string]*models.MessageTemplate{
+			"critical": {Body: "Runbook: https://runbooks.example.com/{{ .Labels.template_name }}"},
+		},
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		actual, err := svc.PreviewMessage(models.Slack, templates, common.Warning)
+		require.NoError(t, err)
+		assert.Equal(t, "[FIRING] mysql-example", actual.Title)
+		assert.Equal(t, "This is a test alert. Owner: \n\n", actual.Body)
+	})
+
+	t.Run("severity", func(t *testing.T) {
+		t.Parallel()
+
+		actual, err := svc.PreviewMessage(models.PagerDuty, templates, common.Critical)
+		require.NoError(t, err)
+		assert.Equal(t, "Runbook: https://runbooks.example.com/example_template\n\n", actual.Body)
+	})
+
+	t.Run("severity case", func(t *testing.T) {
+		t.Parallel()
+
+		templates := &models.MessageTemplates{
+			Severities: map[string]*models.MessageTemplate{
+				" Critical": {Body: "Runbook: {{ .Labels.template_name }}"},
+			},
+		}
+		actual, err := svc.PreviewMessage(models.Slack, templates, common.Critical)
+		require.NoError(t, err)
+		assert.Equal(t, "Runbook: example_template\n\n", actual.Body)
+	})
+
+	t.Run("email", func(t *testing.T) {
+		t.Parallel()
+
+		actual, err := svc.PreviewMessage(models.Email, templates, common.Critical)
+		require.NoError(t, err)
+		assert.Contains(t, actual.Body, "<body>\nRunbook: https://runbooks.example.com/example_template\n\n\n</body>")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			name      string
+			templates *models.MessageTemplates
+			errRE     string
+		}{
+			{
+				name:      "unknown severity",
+				templates: &models.MessageTemplates{Severities: map[string]*models.MessageTemplate{"urgent": {Title: "x"}}},
+				errRE:     `Unknown severity "urgent".`,
+			},
+			{
+				name: "duplicate severity",
+				templates: &models.MessageTemplates{Severities: map[string]*models.MessageTemplate{
+					"critical": {Title: "x"},
+					"CRITICAL": {Title: "y"},
+				}},
+				errRE: `Duplicate templates for severity "critical".`,
+			},
+			{
+				name:      "unbalanced action",
+				templates: &models.MessageTemplates{Default: &models.MessageTemplate{Body: "{{ end }}"}},
+				errRE:     `Invalid default body template: .*`,
+			},
+			{
+				name:      "unknown function",
+				templates: &models.MessageTemplates{Severities: map[string]*models.MessageTemplate{"critical": {Title: "{{ .Status | shout }}"}}},
+				errRE:     `Invalid critical title template: .*function "shout" not defined.*`,
+			},
+		} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := svc.PreviewMessage(models.Slack, tc.templates, common.Critical)
+				tests.AssertGRPCErrorRE(t, codes.InvalidArgument, tc.errRE, err)
+			})
+		}
+	})
+}
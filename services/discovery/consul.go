@@ -0,0 +1,172 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// Consul service metadata keys overriding scheme and metrics path of discovered targets.
+const (
+	consulMetricsSchemeMeta = "metrics_scheme"
+	consulMetricsPathMeta   = "metrics_path"
+)
+
+// consulCatalogService represents a single entry of Consul /v1/catalog/service/:service response.
+type consulCatalogService struct {
+	Node           string            `json:"Node"`
+	Address        string            `json:"Address"`
+	Datacenter     string            `json:"Datacenter"`
+	ServiceName    string            `json:"ServiceName"`
+	ServiceAddress string            `json:"ServiceAddress"`
+	ServicePort    int               `json:"ServicePort"`
+	ServiceTags    []string          `json:"ServiceTags"`
+	ServiceMeta    map[string]string `json:"ServiceMeta"`
+}
+
+// discoverConsul returns targets for instances of Consul catalog services.
+// Service metadata is mapped to labels.
+func discoverConsul(ctx context.Context, o *models.ConsulOptions) ([]*Target, error) {
+	client, err := newHTTPClient("", o.TLSInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	c := &consulClient{
+		client: client,
+		opts:   o,
+	}
+
+	names := o.Services
+	if len(names) == 0 {
+		var services map[string][]string
+		if err = c.get(ctx, "/v1/catalog/services", nil, &services); err != nil {
+			return nil, err
+		}
+		for name, tags := range services {
+			if hasAllTags(tags, o.Tags) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+
+	var res []*Target
+	for _, name := range names {
+		var instances []consulCatalogService
+		query := url.Values{}
+		for _, tag := range o.Tags {
+			query.Add("tag", tag)
+		}
+		if err = c.get(ctx, "/v1/catalog/service/"+url.PathEscape(name), query, &instances); err != nil {
+			return nil, err
+		}
+
+		for _, i := range instances {
+			if !hasAllTags(i.ServiceTags, o.Tags) {
+				continue
+			}
+
+			host := i.ServiceAddress
+			if host == "" {
+				host = i.Address
+			}
+			labels := map[string]string{
+				"consul_service":    i.ServiceName,
+				"consul_node":       i.Node,
+				"consul_datacenter": i.Datacenter,
+			}
+			addLabels(labels, i.ServiceMeta)
+			delete(labels, consulMetricsSchemeMeta)
+			delete(labels, consulMetricsPathMeta)
+
+			address := net.JoinHostPort(host, strconv.Itoa(i.ServicePort))
+			t, err := newTarget(address, i.ServiceMeta[consulMetricsSchemeMeta], i.ServiceMeta[consulMetricsPathMeta], labels)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+// hasAllTags returns true if tags contain all expected tags.
+func hasAllTags(tags, expected []string) bool {
+	set := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		set[t] = struct{}{}
+	}
+	for _, t := range expected {
+		if _, ok := set[t]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+type consulClient struct {
+	client *http.Client
+	opts   *models.ConsulOptions
+}
+
+// get makes GET request to Consul HTTP API and decodes JSON response to res.
+func (c *consulClient) get(ctx context.Context, path string, query url.Values, res interface{}) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	if c.opts.Datacenter != "" {
+		query.Set("dc", c.opts.Datacenter)
+	}
+	u := url.URL{
+		Scheme:   c.opts.Scheme,
+		Host:     c.opts.Address,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if c.opts.Token != "" {
+		req.Header.Set("X-Consul-Token", c.opts.Token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Consul %s: unexpected status code %d", path, resp.StatusCode)
+	}
+	return errors.WithStack(json.NewDecoder(resp.Body).Decode(res))
+}
@@ -0,0 +1,151 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// newConsulStandIn returns a minimal stand-in of Consul catalog HTTP API.
+func newConsulStandIn(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/services", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "secret", req.Header.Get("X-Consul-Token"))
+		assert.Equal(t, "dc1", req.URL.Query().Get("dc"))
+		_, _ = rw.Write([]byte(`{"consul": [], "billing-exporter": ["metrics"], "search-exporter": ["metrics", "canary"]}`))
+	})
+	mux.HandleFunc("/v1/catalog/service/billing-exporter", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, []string{"metrics"}, req.URL.Query()["tag"])
+		_, _ = rw.Write([]byte(`[{
+			"Node": "node1", "Address": "10.0.0.1", "Datacenter": "dc1",
+			"ServiceName": "billing-exporter", "ServiceAddress": "", "ServicePort": 9200,
+			"ServiceTags": ["metrics"], "ServiceMeta": {"environment": "prod", "metrics_path": "/stats"}
+		}, {
+			"Node": "node2", "Address": "10.0.0.2", "Datacenter": "dc1",
+			"ServiceName": "billing-exporter", "ServiceAddress": "10.1.0.2", "ServicePort": 9200,
+			"ServiceTags": ["metrics"], "ServiceMeta": {"environment": "prod", "app-version": "1.2"}
+		}]`))
+	})
+	mux.HandleFunc("/v1/catalog/service/search-exporter", func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = rw.Write([]byte(`[{
+			"Node": "node3", "Address": "10.0.0.3", "Datacenter": "dc1",
+			"ServiceName": "search-exporter", "ServicePort": 9300,
+			"ServiceTags": ["metrics", "canary"], "ServiceMeta": {"metrics_scheme": "https"}
+		}]`))
+	})
+
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestConsul(t *testing.T) {
+	t.Parallel()
+
+	s := newConsulStandIn(t)
+	u, err := url.Parse(s.URL)
+	require.NoError(t, err)
+
+	source := &models.ServiceDiscoverySource{
+		Type: models.ConsulServiceDiscoveryType,
+		Consul: &models.ConsulOptions{
+			Address:    u.Host,
+			Datacenter: "dc1",
+			Token:      "secret",
+			Tags:       []string{"metrics"},
+		},
+	}
+	targets, err := Discover(context.Background(), source)
+	require.NoError(t, err)
+
+	expected := []*Target{{
+		Scheme:      "http",
+		Host:        "10.0.0.1",
+		Port:        9200,
+		MetricsPath: "/stats",
+		Labels: map[string]string{
+			"consul_service":    "billing-exporter",
+			"consul_node":       "node1",
+			"consul_datacenter": "dc1",
+			"environment":       "prod",
+		},
+	}, {
+		Scheme:      "http",
+		Host:        "10.1.0.2",
+		Port:        9200,
+		MetricsPath: "/metrics",
+		Labels: map[string]string{
+			"consul_service":    "billing-exporter",
+			"consul_node":       "node2",
+			"consul_datacenter": "dc1",
+			"environment":       "prod",
+			"app_version":       "1.2",
+		},
+	}, {
+		Scheme:      "https",
+		Host:        "10.0.0.3",
+		Port:        9300,
+		MetricsPath: "/metrics",
+		Labels: map[string]string{
+			"consul_service":    "search-exporter",
+			"consul_node":       "node3",
+			"consul_datacenter": "dc1",
+		},
+	}}
+	assert.Equal(t, expected, targets)
+
+	t.Run("Services", func(t *testing.T) {
+		t.Parallel()
+
+		source := &models.ServiceDiscoverySource{
+			Type: models.ConsulServiceDiscoveryType,
+			Consul: &models.ConsulOptions{
+				Address:    u.Host,
+				Datacenter: "dc1",
+				Token:      "secret",
+				Services:   []string{"search-exporter"},
+			},
+		}
+		targets, err := Discover(context.Background(), source)
+		require.NoError(t, err)
+		require.Len(t, targets, 1)
+		assert.Equal(t, "https://10.0.0.3:9300/metrics", targets[0].Key())
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		t.Parallel()
+
+		source := &models.ServiceDiscoverySource{
+			Type: models.ConsulServiceDiscoveryType,
+			Consul: &models.ConsulOptions{
+				Address:  u.Host,
+				Services: []string{"unknown"},
+			},
+		}
+		_, err := Discover(context.Background(), source)
+		assert.EqualError(t, err, "Consul /v1/catalog/service/unknown: unexpected status code 404")
+	})
+}
@@ -0,0 +1,190 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package discovery discovers external exporters targets in Prometheus service discovery sources.
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const requestTimeout = 30 * time.Second
+
+// Target represents a single discovered exporter endpoint.
+type Target struct {
+	Scheme      string
+	Host        string
+	Port        uint16
+	MetricsPath string
+	// Discovered labels with valid names; meta labels (with `__` prefix) are not included.
+	Labels map[string]string
+}
+
+// Key returns target's unique key in scheme://host:port/path form.
+func (t *Target) Key() string {
+	return t.Scheme + "://" + net.JoinHostPort(t.Host, strconv.Itoa(int(t.Port))) + t.MetricsPath
+}
+
+// Discover returns targets of the given source sorted by keys.
+func Discover(ctx context.Context, source *models.ServiceDiscoverySource) ([]*Target, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	var targets []*Target
+	var err error
+	switch source.Type {
+	case models.FileSDServiceDiscoveryType:
+		if source.FileSD == nil {
+			return nil, errors.New("no file_sd options")
+		}
+		targets, err = discoverFileSD(source.FileSD)
+	case models.ConsulServiceDiscoveryType:
+		if source.Consul == nil {
+			return nil, errors.New("no Consul options")
+		}
+		targets, err = discoverConsul(ctx, source.Consul)
+	case models.KubernetesServiceDiscoveryType:
+		if source.Kubernetes == nil {
+			return nil, errors.New("no Kubernetes options")
+		}
+		targets, err = discoverKubernetes(ctx, source.Kubernetes)
+	default:
+		return nil, errors.Errorf("unsupported service discovery source type %q", source.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return dedup(targets), nil
+}
+
+// dedup removes targets with duplicate keys (the first one wins) and sorts them by keys.
+func dedup(targets []*Target) []*Target {
+	seen := make(map[string]struct{}, len(targets))
+	res := make([]*Target, 0, len(targets))
+	for _, t := range targets {
+		key := t.Key()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		res = append(res, t)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Key() < res[j].Key() })
+	return res
+}
+
+// newTarget returns target for host:port address with defaults for empty scheme, path and port.
+func newTarget(address, scheme, metricsPath string, labels map[string]string) (*Target, error) {
+	switch scheme {
+	case "":
+		scheme = "http"
+	case "http", "https":
+	default:
+		return nil, errors.Errorf("unsupported scheme %q of target %q", scheme, address)
+	}
+	if metricsPath == "" {
+		metricsPath = "/metrics"
+	}
+	if !strings.HasPrefix(metricsPath, "/") {
+		metricsPath = "/" + metricsPath
+	}
+
+	host, portS, err := net.SplitHostPort(address)
+	if err != nil {
+		// like Prometheus, use default port for the scheme
+		host = address
+		portS = "80"
+		if scheme == "https" {
+			portS = "443"
+		}
+	}
+	port, err := strconv.ParseUint(portS, 10, 16)
+	if err != nil || port == 0 {
+		return nil, errors.Errorf("invalid port of target %q", address)
+	}
+	if host == "" {
+		return nil, errors.Errorf("empty host of target %q", address)
+	}
+
+	return &Target{
+		Scheme:      scheme,
+		Host:        host,
+		Port:        uint16(port),
+		MetricsPath: metricsPath,
+		Labels:      labels,
+	}, nil
+}
+
+var invalidLabelCharsRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// sanitizeLabelName replaces characters not allowed in label names (like in Kubernetes labels) with underscores.
+func sanitizeLabelName(name string) string {
+	name = invalidLabelCharsRE.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// addLabels adds labels with sanitized names to res, skipping meta labels and empty values.
+func addLabels(res, labels map[string]string) {
+	for name, value := range labels {
+		if strings.HasPrefix(name, "__") || value == "" {
+			continue
+		}
+		if name = sanitizeLabelName(name); name == "" || strings.HasPrefix(name, "__") {
+			continue
+		}
+		res[name] = value
+	}
+}
+
+// newHTTPClient returns HTTP client for service discovery API with given TLS options.
+func newHTTPClient(ca string, insecureSkipVerify bool) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+	}
+	if ca != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(ca)) {
+			return nil, errors.New("failed to parse CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+		Timeout: requestTimeout,
+	}, nil
+}
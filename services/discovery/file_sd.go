@@ -0,0 +1,84 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// fileSDGroup represents a single group of Prometheus file_sd JSON file.
+type fileSDGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// discoverFileSD returns targets from all file_sd JSON files matching path pattern.
+// Special __scheme__ and __metrics_path__ labels are honored.
+func discoverFileSD(o *models.FileSDOptions) ([]*Target, error) {
+	paths, err := filepath.Glob(o.Path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no files matching %q", o.Path)
+	}
+	sort.Strings(paths)
+
+	var res []*Target
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		targets, err := parseFileSD(b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %s", path)
+		}
+		res = append(res, targets...)
+	}
+	return res, nil
+}
+
+// parseFileSD parses file_sd JSON file content.
+func parseFileSD(b []byte) ([]*Target, error) {
+	var groups []fileSDGroup
+	if err := json.Unmarshal(b, &groups); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var res []*Target
+	for _, g := range groups {
+		labels := make(map[string]string, len(g.Labels))
+		addLabels(labels, g.Labels)
+
+		for _, address := range g.Targets {
+			t, err := newTarget(address, g.Labels["__scheme__"], g.Labels["__metrics_path__"], labels)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
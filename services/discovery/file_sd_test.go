@@ -0,0 +1,100 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestFileSD(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pmm-managed-file-sd-")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "apps.json"), []byte(`[
+		{
+			"targets": ["app1.example.com:9100", "10.0.0.2:9100"],
+			"labels": {"environment": "prod", "cluster": "eu", "team": "payments", "__meta_ignored": "x"}
+		},
+		{
+			"targets": ["app3.example.com"],
+			"labels": {"__scheme__": "https", "__metrics_path__": "/custom"}
+		}
+	]`), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "more.json"), []byte(`[
+		{"targets": ["app1.example.com:9100"], "labels": {"environment": "dev"}}
+	]`), 0o600))
+
+	source := &models.ServiceDiscoverySource{
+		Type:   models.FileSDServiceDiscoveryType,
+		FileSD: &models.FileSDOptions{Path: filepath.Join(dir, "*.json")},
+	}
+	targets, err := Discover(context.Background(), source)
+	require.NoError(t, err)
+
+	expected := []*Target{{
+		Scheme:      "http",
+		Host:        "10.0.0.2",
+		Port:        9100,
+		MetricsPath: "/metrics",
+		Labels:      map[string]string{"environment": "prod", "cluster": "eu", "team": "payments"},
+	}, {
+		// duplicate from more.json is ignored
+		Scheme:      "http",
+		Host:        "app1.example.com",
+		Port:        9100,
+		MetricsPath: "/metrics",
+		Labels:      map[string]string{"environment": "prod", "cluster": "eu", "team": "payments"},
+	}, {
+		Scheme:      "https",
+		Host:        "app3.example.com",
+		Port:        443,
+		MetricsPath: "/custom",
+		Labels:      map[string]string{},
+	}}
+	assert.Equal(t, expected, targets)
+	assert.Equal(t, "https://app3.example.com:443/custom", targets[2].Key())
+
+	t.Run("NoFiles", func(t *testing.T) {
+		t.Parallel()
+
+		source := &models.ServiceDiscoverySource{
+			Type:   models.FileSDServiceDiscoveryType,
+			FileSD: &models.FileSDOptions{Path: filepath.Join(dir, "*.yml")},
+		}
+		_, err := Discover(context.Background(), source)
+		assert.EqualError(t, err, "no files matching "+`"`+filepath.Join(dir, "*.yml")+`"`)
+	})
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := parseFileSD([]byte(`[{"targets": ["app:99999"]}]`))
+		assert.EqualError(t, err, `invalid port of target "app:99999"`)
+	})
+}
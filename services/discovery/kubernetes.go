@@ -0,0 +1,235 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// Pod annotations used by Prometheus Kubernetes scrape configuration examples.
+const (
+	scrapeAnnotation = "prometheus.io/scrape"
+	portAnnotation   = "prometheus.io/port"
+	pathAnnotation   = "prometheus.io/path"
+	schemeAnnotation = "prometheus.io/scheme"
+)
+
+// In-cluster service account files.
+const (
+	serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+	serviceAccountCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// kubernetesPodList represents Kubernetes PodList with fields used by discovery.
+type kubernetesPodList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []kubernetesPod `json:"items"`
+}
+
+// kubernetesPod represents Kubernetes Pod with fields used by discovery.
+type kubernetesPod struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Ports []struct {
+				ContainerPort int `json:"containerPort"`
+			} `json:"ports"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+// discoverKubernetes returns targets for running pods with `prometheus.io/scrape: "true"` annotation.
+// Pod labels are mapped to labels with sanitized names.
+func discoverKubernetes(ctx context.Context, o *models.KubernetesOptions) ([]*Target, error) {
+	c, err := newKubernetesClient(o)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{"/api/v1/pods"}
+	if len(o.Namespaces) != 0 {
+		paths = make([]string, len(o.Namespaces))
+		for i, ns := range o.Namespaces {
+			paths[i] = "/api/v1/namespaces/" + url.PathEscape(ns) + "/pods"
+		}
+	}
+
+	var res []*Target
+	for _, path := range paths {
+		var cont string
+		for {
+			var list kubernetesPodList
+			query := url.Values{"limit": []string{"500"}}
+			if cont != "" {
+				query.Set("continue", cont)
+			}
+			if err = c.get(ctx, path, query, &list); err != nil {
+				return nil, err
+			}
+
+			for _, pod := range list.Items {
+				t, err := podTarget(&pod)
+				if err != nil {
+					return nil, err
+				}
+				if t != nil {
+					res = append(res, t)
+				}
+			}
+
+			if cont = list.Metadata.Continue; cont == "" {
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// podTarget returns target for the given pod, or nil if pod should not be scraped.
+func podTarget(pod *kubernetesPod) (*Target, error) {
+	annotations := pod.Metadata.Annotations
+	if annotations[scrapeAnnotation] != "true" || pod.Status.Phase != "Running" || pod.Status.PodIP == "" {
+		return nil, nil
+	}
+
+	port := annotations[portAnnotation]
+	if port == "" {
+		// use the only declared container port
+		var ports []int
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				ports = append(ports, p.ContainerPort)
+			}
+		}
+		if len(ports) != 1 {
+			return nil, nil
+		}
+		port = strconv.Itoa(ports[0])
+	}
+
+	labels := map[string]string{
+		"kubernetes_namespace": pod.Metadata.Namespace,
+		"kubernetes_pod_name":  pod.Metadata.Name,
+	}
+	addLabels(labels, pod.Metadata.Labels)
+
+	address := net.JoinHostPort(pod.Status.PodIP, port)
+	t, err := newTarget(address, annotations[schemeAnnotation], annotations[pathAnnotation], labels)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pod %s/%s", pod.Metadata.Namespace, pod.Metadata.Name)
+	}
+	return t, nil
+}
+
+type kubernetesClient struct {
+	client    *http.Client
+	apiServer *url.URL
+	token     string
+}
+
+// newKubernetesClient returns Kubernetes API client for given options, or for in-cluster configuration
+// if API server is not set.
+func newKubernetesClient(o *models.KubernetesOptions) (*kubernetesClient, error) {
+	apiServer, token, ca := o.APIServer, o.BearerToken, o.TLSCa
+	if apiServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("Kubernetes API server is not set, and PMM Server does not run in Kubernetes cluster")
+		}
+		apiServer = "https://" + net.JoinHostPort(host, port)
+
+		if token == "" {
+			b, err := ioutil.ReadFile(serviceAccountTokenFile)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			token = strings.TrimSpace(string(b))
+		}
+		if ca == "" && !o.TLSInsecureSkipVerify {
+			b, err := ioutil.ReadFile(serviceAccountCAFile)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			ca = string(b)
+		}
+	}
+
+	u, err := url.Parse(apiServer)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client, err := newHTTPClient(ca, o.TLSInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kubernetesClient{
+		client:    client,
+		apiServer: u,
+		token:     token,
+	}, nil
+}
+
+// get makes GET request to Kubernetes API and decodes JSON response to res.
+func (c *kubernetesClient) get(ctx context.Context, path string, query url.Values, res interface{}) error {
+	u := *c.apiServer
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Kubernetes %s: unexpected status code %d", path, resp.StatusCode)
+	}
+	return errors.WithStack(json.NewDecoder(resp.Body).Decode(res))
+}
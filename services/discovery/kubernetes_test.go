@@ -0,0 +1,121 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestKubernetes(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/apps/pods", func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+
+		if req.URL.Query().Get("continue") == "" {
+			_, _ = rw.Write([]byte(`{"metadata": {"continue": "page2"}, "items": [{
+				"metadata": {
+					"name": "billing-7d4f", "namespace": "apps",
+					"labels": {"app.kubernetes.io/name": "billing", "environment": "prod"},
+					"annotations": {"prometheus.io/scrape": "true", "prometheus.io/port": "9102", "prometheus.io/path": "/stats"}
+				},
+				"status": {"phase": "Running", "podIP": "10.244.1.5"}
+			}, {
+				"metadata": {"name": "no-annotation", "namespace": "apps"},
+				"spec": {"containers": [{"ports": [{"containerPort": 8080}]}]},
+				"status": {"phase": "Running", "podIP": "10.244.1.6"}
+			}]}`))
+			return
+		}
+
+		assert.Equal(t, "page2", req.URL.Query().Get("continue"))
+		_, _ = rw.Write([]byte(`{"metadata": {}, "items": [{
+			"metadata": {
+				"name": "search-0", "namespace": "apps",
+				"annotations": {"prometheus.io/scrape": "true"}
+			},
+			"spec": {"containers": [{"ports": [{"containerPort": 9200}]}]},
+			"status": {"phase": "Running", "podIP": "10.244.2.7"}
+		}, {
+			"metadata": {
+				"name": "pending", "namespace": "apps",
+				"annotations": {"prometheus.io/scrape": "true", "prometheus.io/port": "9102"}
+			},
+			"status": {"phase": "Pending"}
+		}]}`))
+	})
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	source := &models.ServiceDiscoverySource{
+		Type: models.KubernetesServiceDiscoveryType,
+		Kubernetes: &models.KubernetesOptions{
+			APIServer:   s.URL,
+			BearerToken: "token",
+			Namespaces:  []string{"apps"},
+		},
+	}
+	targets, err := Discover(context.Background(), source)
+	require.NoError(t, err)
+
+	expected := []*Target{{
+		Scheme:      "http",
+		Host:        "10.244.1.5",
+		Port:        9102,
+		MetricsPath: "/stats",
+		Labels: map[string]string{
+			"kubernetes_namespace":   "apps",
+			"kubernetes_pod_name":    "billing-7d4f",
+			"app_kubernetes_io_name": "billing",
+			"environment":            "prod",
+		},
+	}, {
+		Scheme:      "http",
+		Host:        "10.244.2.7",
+		Port:        9200,
+		MetricsPath: "/metrics",
+		Labels: map[string]string{
+			"kubernetes_namespace": "apps",
+			"kubernetes_pod_name":  "search-0",
+		},
+	}}
+	assert.Equal(t, expected, targets)
+
+	t.Run("NotInCluster", func(t *testing.T) {
+		t.Parallel()
+
+		source := &models.ServiceDiscoverySource{
+			Type:       models.KubernetesServiceDiscoveryType,
+			Kubernetes: &models.KubernetesOptions{},
+		}
+		if _, ok := os.LookupEnv("KUBERNETES_SERVICE_HOST"); ok {
+			t.Skip("Running in Kubernetes.")
+		}
+		_, err := Discover(context.Background(), source)
+		assert.EqualError(t, err, "Kubernetes API server is not set, and PMM Server does not run in Kubernetes cluster")
+	})
+}
@@ -0,0 +1,356 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package ha coordinates pmm-managed instances that share the same database.
+//
+// Each instance registers itself in the database and sends heartbeats.
+// Instances elect a single leader with a database lease; background loops
+// that should not run concurrently (checks, scheduler, telemetry, etc.) run only on the leader.
+//
+// Requests to pmm-agents connected to other instances are forwarded to their forwarding servers
+// with per-instance bearer tokens. Forwarding server listens on loopback address by default;
+// TLS certificate and key are required to listen on any other address, so tokens are never sent in the clear.
+package ha
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	heartbeatInterval = 5 * time.Second
+	leaseTTL          = 3 * heartbeatInterval
+	leaderLease       = "pmm-managed-leader"
+)
+
+// Params represents high-availability parameters of pmm-managed instance.
+type Params struct {
+	// InstanceID is a unique ID of this instance; high-availability mode is disabled if it is empty.
+	InstanceID string
+	// AdvertiseAddress is an address (host:port) of this instance's forwarding server reachable by other instances.
+	AdvertiseAddress string
+	// ListenAddress is an address (host:port) this instance's forwarding server listens on.
+	ListenAddress string
+	// TLS certificate and key files of forwarding server; plain HTTP is used if they are empty.
+	// Forwarded requests contain bearer tokens, so TLS is required for non-loopback listen address.
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSCAFile is a file with CA certificates used to verify other instances' forwarding servers;
+	// system certificate pool is used if it is empty.
+	TLSCAFile string
+}
+
+// Service coordinates pmm-managed instances.
+// If high-availability mode is disabled, this instance is always a leader.
+type Service struct {
+	db     *reform.DB
+	l      *logrus.Entry
+	params Params
+	token  string
+
+	serverTLSConfig *tls.Config
+	clientTLSConfig *tls.Config
+
+	rw            sync.RWMutex
+	leader        bool
+	leaderChanged chan struct{} // closed and replaced on every leadership change
+
+	mLeader prom.Gauge
+}
+
+// New creates a new service and registers this instance in the database if high-availability mode is enabled.
+func New(db *reform.DB, params *Params) (*Service, error) {
+	s := &Service{
+		db:            db,
+		l:             logrus.WithField("component", "ha"),
+		params:        *params,
+		leaderChanged: make(chan struct{}),
+		mLeader: prom.NewGauge(prom.GaugeOpts{
+			Namespace: "pmm_managed",
+			Subsystem: "ha",
+			Name:      "leader",
+			Help:      "1 if this pmm-managed instance is a leader, 0 otherwise.",
+		}),
+	}
+
+	if !s.Enabled() {
+		s.leader = true
+		s.mLeader.Set(1)
+		return s, nil
+	}
+
+	if params.AdvertiseAddress == "" {
+		return nil, errors.New("advertise address is required in high-availability mode")
+	}
+	if err := s.setupTLS(); err != nil {
+		return nil, err
+	}
+
+	s.token = uuid.New().String()
+	if _, err := models.RegisterHAInstance(db.Querier, params.InstanceID, params.AdvertiseAddress, s.token); err != nil {
+		return nil, err
+	}
+
+	s.l.Infof("Registered instance %s with address %s.", params.InstanceID, params.AdvertiseAddress)
+	return s, nil
+}
+
+// Enabled returns true if high-availability mode is enabled.
+func (s *Service) Enabled() bool {
+	return s.params.InstanceID != ""
+}
+
+// setupTLS loads TLS configuration of forwarding server and client.
+func (s *Service) setupTLS() error {
+	p := s.params
+	if p.TLSCertFile == "" && p.TLSKeyFile == "" {
+		if !isLoopback(p.ListenAddress) {
+			return errors.Errorf("TLS certificate and key are required for forwarding server listening on non-loopback address %q", p.ListenAddress)
+		}
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(p.TLSCertFile, p.TLSKeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load TLS certificate and key")
+	}
+	s.serverTLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	s.clientTLSConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if p.TLSCAFile != "" {
+		b, err := os.ReadFile(p.TLSCAFile)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.Errorf("no CA certificates found in %s", p.TLSCAFile)
+		}
+		s.clientTLSConfig.RootCAs = pool
+	}
+	return nil
+}
+
+// isLoopback returns true if given listen address (host:port) is a loopback one.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ServerTLSConfig returns TLS configuration of this instance's forwarding server, or nil if it uses plain HTTP.
+func (s *Service) ServerTLSConfig() *tls.Config {
+	return s.serverTLSConfig
+}
+
+// ClientTLSConfig returns TLS configuration for requests to other instances' forwarding servers, or nil for plain HTTP.
+func (s *Service) ClientTLSConfig() *tls.Config {
+	return s.clientTLSConfig
+}
+
+// InstanceID returns ID of this pmm-managed instance.
+func (s *Service) InstanceID() string {
+	return s.params.InstanceID
+}
+
+// IsLeader returns true if this instance is a leader.
+func (s *Service) IsLeader() bool {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	return s.leader
+}
+
+// leaderState returns current leadership and a channel that is closed when it changes.
+func (s *Service) leaderState() (bool, <-chan struct{}) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	return s.leader, s.leaderChanged
+}
+
+func (s *Service) setLeader(leader bool) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if s.leader == leader {
+		return
+	}
+
+	s.leader = leader
+	close(s.leaderChanged)
+	s.leaderChanged = make(chan struct{})
+
+	if leader {
+		s.l.Info("This instance is a leader now.")
+		s.mLeader.Set(1)
+	} else {
+		s.l.Warn("This instance is not a leader anymore.")
+		s.mLeader.Set(0)
+	}
+}
+
+// Run sends heartbeats and takes part in leader election until ctx is canceled.
+// On exit, it releases leadership and unregisters this instance.
+func (s *Service) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	s.l.Info("Starting...")
+	defer s.l.Info("Done.")
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		s.heartbeat()
+
+		select {
+		case <-ctx.Done():
+			s.stop()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat updates this instance's heartbeat time and acquires or renews leader lease.
+func (s *Service) heartbeat() {
+	err := models.TouchHAInstance(s.db.Querier, s.params.InstanceID)
+	if status.Code(err) == codes.NotFound {
+		// removed by another instance or manually; pmm-agents ownership is lost until they reconnect
+		s.l.Warn("Instance is not registered, registering again.")
+		_, err = models.RegisterHAInstance(s.db.Querier, s.params.InstanceID, s.params.AdvertiseAddress, s.token)
+	}
+	if err != nil {
+		s.l.Errorf("Failed to send heartbeat: %+v.", err)
+	}
+
+	leader, err := models.AcquireHALease(s.db.Querier, leaderLease, s.params.InstanceID, leaseTTL)
+	if err != nil {
+		// we can't be sure that lease is still ours
+		s.l.Errorf("Failed to acquire leader lease: %+v.", err)
+		leader = false
+	}
+	s.setLeader(leader)
+}
+
+// stop releases leadership and unregisters this instance.
+func (s *Service) stop() {
+	s.setLeader(false)
+
+	if err := models.ReleaseHALease(s.db.Querier, leaderLease, s.params.InstanceID); err != nil {
+		s.l.Errorf("Failed to release leader lease: %+v.", err)
+	}
+	if err := models.RemoveHAInstance(s.db.Querier, s.params.InstanceID); err != nil {
+		s.l.Errorf("Failed to unregister instance: %+v.", err)
+	}
+}
+
+// RunAsLeader runs f while this instance is a leader.
+// Context passed to f is canceled when leadership is lost; f is started again when leadership is acquired again.
+// RunAsLeader returns when ctx is canceled or f returns by itself.
+func (s *Service) RunAsLeader(ctx context.Context, f func(ctx context.Context)) {
+	for {
+		leader, changed := s.leaderState()
+		if !leader {
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		fCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f(fCtx)
+		}()
+
+		select {
+		case <-changed:
+			cancel()
+			<-done
+		case <-done:
+			cancel()
+			return
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Authenticate returns HTTP handler that passes only requests from other pmm-managed instances to h.
+func (s *Service) Authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if s.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			http.Error(rw, "Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(rw, req)
+	})
+}
+
+// Describe implements prometheus.Collector.
+func (s *Service) Describe(ch chan<- *prom.Desc) {
+	s.mLeader.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (s *Service) Collect(ch chan<- prom.Metric) {
+	s.mLeader.Collect(ch)
+}
+
+// check interfaces.
+var (
+	_ prom.Collector = (*Service)(nil)
+)
@@ -0,0 +1,141 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAsLeader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Disabled", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := New(nil, &Params{})
+		require.NoError(t, err)
+		assert.False(t, s.Enabled())
+		assert.True(t, s.IsLeader())
+
+		var called bool
+		s.RunAsLeader(context.Background(), func(ctx context.Context) {
+			called = true
+		})
+		assert.True(t, called)
+	})
+
+	t.Run("Election", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := New(nil, &Params{})
+		require.NoError(t, err)
+		s.setLeader(false)
+
+		started := make(chan struct{})
+		stopped := make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.RunAsLeader(ctx, func(ctx context.Context) {
+				started <- struct{}{}
+				<-ctx.Done()
+				stopped <- struct{}{}
+			})
+		}()
+
+		select {
+		case <-started:
+			t.Fatal("started without leadership")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		for i := 0; i < 2; i++ {
+			s.setLeader(true)
+			<-started
+			s.setLeader(false)
+			<-stopped
+		}
+
+		cancel()
+		<-done
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	s := &Service{token: "secret"}
+	h := s.Authenticate(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+
+	for header, expected := range map[string]int{
+		"":              http.StatusUnauthorized,
+		"Bearer wrong":  http.StatusUnauthorized,
+		"Bearer secret": http.StatusNoContent,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", header)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, expected, rec.Code, "%q", header)
+	}
+
+	// disabled high-availability mode doesn't accept forwarded requests
+	s = &Service{}
+	h = s.Authenticate(http.NotFoundHandler())
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	for addr, expected := range map[string]bool{
+		"127.0.0.1:7774": true,
+		"[::1]:7774":     true,
+		"localhost:7774": true,
+		":7774":          false,
+		"0.0.0.0:7774":   false,
+		"10.0.0.1:7774":  false,
+		"invalid":        false,
+	} {
+		assert.Equal(t, expected, isLoopback(addr), "%s", addr)
+	}
+
+	s := &Service{params: Params{ListenAddress: "127.0.0.1:7774"}}
+	require.NoError(t, s.setupTLS())
+	assert.Nil(t, s.ServerTLSConfig())
+	assert.Nil(t, s.ClientTLSConfig())
+
+	s = &Service{params: Params{ListenAddress: ":7774"}}
+	assert.EqualError(t, s.setupTLS(), `TLS certificate and key are required for forwarding server listening on non-loopback address ":7774"`)
+
+	s = &Service{params: Params{ListenAddress: ":7774", TLSCertFile: "nonexistent.crt", TLSKeyFile: "nonexistent.key"}}
+	assert.Error(t, s.setupTLS())
+}
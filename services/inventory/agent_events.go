@@ -0,0 +1,108 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	defaultAgentEventsLimit = 100
+	maxAgentEventsLimit     = 1000
+)
+
+// ListAgentEventsRequest is a request of ListEvents method.
+type ListAgentEventsRequest struct {
+	// Return only events of that Agent; events are kept after Agent removal.
+	AgentID string `json:"agent_id"`
+	// Return only events of Agents running on that Node.
+	NodeID string `json:"node_id"`
+	// Return only events created after that time.
+	Since *time.Time `json:"since"`
+	// Maximum number of newest events to return; 100 by default.
+	Limit int `json:"limit"`
+}
+
+// AgentEvent represents a single event of Agent connection and status history.
+type AgentEvent struct {
+	AgentID   string    `json:"agent_id"`
+	NodeID    string    `json:"node_id"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason,omitempty"`
+	OldStatus string    `json:"old_status,omitempty"`
+	NewStatus string    `json:"new_status,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListAgentEventsResponse is a response of ListEvents method.
+type ListAgentEventsResponse struct {
+	// Events, newest first.
+	Events []*AgentEvent `json:"events"`
+}
+
+// ListEvents returns connection and status history of given Agent or all Agents on given Node.
+func (as *AgentsService) ListEvents(ctx context.Context, req *ListAgentEventsRequest) (*ListAgentEventsResponse, error) {
+	if req.AgentID == "" && req.NodeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "Either agent_id or node_id is expected.")
+	}
+
+	limit := req.Limit
+	switch {
+	case limit < 0:
+		return nil, status.Error(codes.InvalidArgument, "Limit should be positive.")
+	case limit == 0:
+		limit = defaultAgentEventsLimit
+	case limit > maxAgentEventsLimit:
+		limit = maxAgentEventsLimit
+	}
+
+	filters := models.AgentEventFilters{
+		AgentID: req.AgentID,
+		NodeID:  req.NodeID,
+		Limit:   limit,
+	}
+	if req.Since != nil {
+		filters.Since = *req.Since
+	}
+
+	events, err := models.FindAgentEvents(as.db.Querier, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListAgentEventsResponse{
+		Events: make([]*AgentEvent, len(events)),
+	}
+	for i, e := range events {
+		res.Events[i] = &AgentEvent{
+			AgentID:   e.AgentID,
+			NodeID:    e.NodeID,
+			Type:      string(e.Type),
+			Reason:    e.Reason,
+			OldStatus: e.OldStatus,
+			NewStatus: e.NewStatus,
+			CreatedAt: e.CreatedAt,
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestListAgentEventsValidation(t *testing.T) {
+	t.Parallel()
+
+	as := NewAgentsService(nil, nil, nil, nil, nil)
+
+	for _, tc := range []struct {
+		name     string
+		req      *ListAgentEventsRequest
+		expected *status.Status
+	}{
+		{
+			name:     "NoFilters",
+			req:      &ListAgentEventsRequest{},
+			expected: status.New(codes.InvalidArgument, "Either agent_id or node_id is expected."),
+		},
+		{
+			name:     "NegativeLimit",
+			req:      &ListAgentEventsRequest{AgentID: "/agent_id/1", Limit: -1},
+			expected: status.New(codes.InvalidArgument, "Limit should be positive."),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := as.ListEvents(context.Background(), tc.req)
+			assert.Nil(t, res)
+			tests.AssertGRPCError(t, tc.expected, err)
+		})
+	}
+}
@@ -0,0 +1,423 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/inventorypb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+)
+
+// logLevelAgentTypes contains types of Agents that support log level.
+var logLevelAgentTypes = map[models.AgentType]struct{}{
+	models.MySQLdExporterType:   {},
+	models.MongoDBExporterType:  {},
+	models.PostgresExporterType: {},
+	models.ProxySQLExporterType: {},
+}
+
+// queryExamplesAgentTypes contains types of Agents that support disabling query examples.
+var queryExamplesAgentTypes = map[models.AgentType]struct{}{
+	models.QANMySQLPerfSchemaAgentType:         {},
+	models.QANMySQLSlowlogAgentType:            {},
+	models.QANMongoDBProfilerAgentType:         {},
+	models.QANPostgreSQLPgStatMonitorAgentType: {},
+}
+
+// BulkService changes inventory objects selected by labels in a single transaction.
+type BulkService struct {
+	db    *reform.DB
+	state agentsStateUpdater
+	vmdb  prometheusService
+}
+
+// NewBulkService creates new BulkService.
+func NewBulkService(db *reform.DB, state agentsStateUpdater, vmdb prometheusService) *BulkService {
+	return &BulkService{
+		db:    db,
+		state: state,
+		vmdb:  vmdb,
+	}
+}
+
+// BulkSelector selects Services by standard and custom labels; all non-empty fields should match.
+// Agent changes are applied to Agents of selected Services.
+type BulkSelector struct {
+	ServiceType    string            `json:"service_type"`
+	Environment    string            `json:"environment"`
+	Cluster        string            `json:"cluster"`
+	ReplicationSet string            `json:"replication_set"`
+	CustomLabels   map[string]string `json:"custom_labels"`
+	// If set, Agent changes are applied only to Agents of that type.
+	// Node-level Agents (pmm-agent, node_exporter, vmagent, rds_exporter) don't belong to Services and can't be selected.
+	AgentType string `json:"agent_type"`
+}
+
+// BulkChangeRequest is a request of Change method.
+type BulkChangeRequest struct {
+	Selector *BulkSelector `json:"selector"`
+
+	// Custom labels to add or replace on selected Services.
+	SetCustomLabels map[string]string `json:"set_custom_labels"`
+	// Custom labels names to remove from selected Services.
+	RemoveCustomLabels []string `json:"remove_custom_labels"`
+
+	// Enable or disable Agents.
+	EnableAgents  bool `json:"enable_agents"`
+	DisableAgents bool `json:"disable_agents"`
+	// Exporters log level: fatal, error, warn, info, debug, or auto to reset it.
+	LogLevel string `json:"log_level"`
+	// Enable or disable query examples for QAN Agents.
+	EnableQueryExamples  bool `json:"enable_query_examples"`
+	DisableQueryExamples bool `json:"disable_query_examples"`
+
+	// Remove selected Services; with force, also remove their Agents.
+	RemoveServices bool `json:"remove_services"`
+	Force          bool `json:"force"`
+
+	// Return changes without applying them.
+	DryRun bool `json:"dry_run"`
+}
+
+// BulkChange represents changes of a single Service or Agent.
+type BulkChange struct {
+	ServiceID   string   `json:"service_id"`
+	ServiceName string   `json:"service_name,omitempty"`
+	AgentID     string   `json:"agent_id,omitempty"`
+	AgentType   string   `json:"agent_type,omitempty"`
+	Changes     []string `json:"changes"`
+}
+
+// BulkChangeResponse is a response of Change method.
+type BulkChangeResponse struct {
+	// Changed Services and Agents, in a stable order.
+	Changes []*BulkChange `json:"changes"`
+	// True if changes were not applied.
+	DryRun bool `json:"dry_run"`
+}
+
+// Change applies changes to all Services selected by labels and their Agents in a single transaction.
+// If any change fails, nothing is changed.
+func (s *BulkService) Change(ctx context.Context, req *BulkChangeRequest) (*BulkChangeResponse, error) {
+	selector, err := convertBulkSelector(req.Selector)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateBulkChangeRequest(req); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	changes, pmmAgentIDs, err := s.change(tx.Querier, selector, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &BulkChangeResponse{
+		Changes: changes,
+		DryRun:  req.DryRun,
+	}
+	if req.DryRun || len(changes) == 0 {
+		return res, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	logger.Get(ctx).Infof("Applied %d bulk changes.", len(changes))
+	for _, id := range pmmAgentIDs {
+		s.state.RequestStateUpdate(ctx, id)
+	}
+	s.vmdb.RequestConfigurationUpdate()
+
+	return res, nil
+}
+
+// change applies changes and returns them with IDs of pmm-agents that should receive state updates.
+func (s *BulkService) change(q *reform.Querier, selector *models.ServiceSelector, req *BulkChangeRequest) ([]*BulkChange, []string, error) {
+	services, err := models.FindServicesBySelector(q, selector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var changes []*BulkChange
+	pmmAgentIDs := make(map[string]struct{})
+	for _, service := range services {
+		if req.RemoveServices {
+			// Service may be already removed together with RDS or Azure Node of previous one
+			if _, err = models.FindServiceByID(q, service.ServiceID); err != nil {
+				if status.Code(err) != codes.NotFound {
+					return nil, nil, err
+				}
+				changes = append(changes, &BulkChange{
+					ServiceID:   service.ServiceID,
+					ServiceName: service.ServiceName,
+					Changes:     []string{"removed with its Node"},
+				})
+				continue
+			}
+		}
+
+		agents, err := models.FindAgents(q, models.AgentFilters{ServiceID: service.ServiceID})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, agent := range agents {
+			if req.Selector.AgentType != "" && string(agent.AgentType) != req.Selector.AgentType {
+				continue
+			}
+
+			agentChanges, err := changeBulkAgent(q, agent, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(agentChanges) == 0 {
+				continue
+			}
+
+			changes = append(changes, &BulkChange{
+				ServiceID: service.ServiceID,
+				AgentID:   agent.AgentID,
+				AgentType: string(agent.AgentType),
+				Changes:   agentChanges,
+			})
+			if id := pointer.GetString(agent.PMMAgentID); id != "" {
+				pmmAgentIDs[id] = struct{}{}
+			}
+		}
+
+		if req.RemoveServices {
+			ids, err := removeService(q, service.ServiceID, req.Force)
+			if err != nil {
+				return nil, nil, err
+			}
+			changes = append(changes, &BulkChange{
+				ServiceID:   service.ServiceID,
+				ServiceName: service.ServiceName,
+				Changes:     []string{"removed"},
+			})
+			for id := range ids {
+				pmmAgentIDs[id] = struct{}{}
+			}
+			// labels of Agents' metrics and removed Agents
+			for _, agent := range agents {
+				if id := pointer.GetString(agent.PMMAgentID); id != "" {
+					pmmAgentIDs[id] = struct{}{}
+				}
+			}
+			continue
+		}
+
+		serviceChanges, err := changeBulkService(q, service, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(serviceChanges) != 0 {
+			changes = append(changes, &BulkChange{
+				ServiceID:   service.ServiceID,
+				ServiceName: service.ServiceName,
+				Changes:     serviceChanges,
+			})
+			// labels of Agents' metrics and removed Agents
+			for _, agent := range agents {
+				if id := pointer.GetString(agent.PMMAgentID); id != "" {
+					pmmAgentIDs[id] = struct{}{}
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(pmmAgentIDs))
+	for id := range pmmAgentIDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return changes, ids, nil
+}
+
+// changeBulkAgent applies changes to a single Agent and returns their descriptions.
+func changeBulkAgent(q *reform.Querier, agent *models.Agent, req *BulkChangeRequest) ([]string, error) {
+	var changes []string
+
+	switch {
+	case req.EnableAgents && agent.Disabled:
+		agent.Disabled = false
+		changes = append(changes, "enabled")
+	case req.DisableAgents && !agent.Disabled:
+		agent.Disabled = true
+		changes = append(changes, "disabled")
+	}
+
+	if _, ok := logLevelAgentTypes[agent.AgentType]; ok && req.LogLevel != "" {
+		var logLevel *string
+		if req.LogLevel != inventorypb.LogLevel_auto.String() {
+			logLevel = pointer.ToString(req.LogLevel)
+		}
+		if pointer.GetString(agent.LogLevel) != pointer.GetString(logLevel) {
+			changes = append(changes, fmt.Sprintf("log_level: %q -> %q", pointer.GetString(agent.LogLevel), pointer.GetString(logLevel)))
+			agent.LogLevel = logLevel
+		}
+	}
+
+	if _, ok := queryExamplesAgentTypes[agent.AgentType]; ok {
+		switch {
+		case req.EnableQueryExamples && agent.QueryExamplesDisabled:
+			agent.QueryExamplesDisabled = false
+			changes = append(changes, "query examples enabled")
+		case req.DisableQueryExamples && !agent.QueryExamplesDisabled:
+			agent.QueryExamplesDisabled = true
+			changes = append(changes, "query examples disabled")
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err := q.Update(agent); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return changes, nil
+}
+
+// changeBulkService applies label changes to a single Service and returns their descriptions.
+func changeBulkService(q *reform.Querier, service *models.Service, req *BulkChangeRequest) ([]string, error) {
+	labels, err := service.GetCustomLabels()
+	if err != nil {
+		return nil, err
+	}
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	var changes []string
+	for _, name := range req.RemoveCustomLabels {
+		if v, ok := labels[name]; ok {
+			delete(labels, name)
+			changes = append(changes, fmt.Sprintf("custom label %s: %q -> removed", name, v))
+		}
+	}
+	names := make([]string, 0, len(req.SetCustomLabels))
+	for name := range req.SetCustomLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := req.SetCustomLabels[name]
+		if old, ok := labels[name]; !ok || old != v {
+			labels[name] = v
+			changes = append(changes, fmt.Sprintf("custom label %s: %q -> %q", name, old, v))
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	if err = service.SetCustomLabels(labels); err != nil {
+		return nil, err
+	}
+	if err = q.Update(service); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return changes, nil
+}
+
+func convertBulkSelector(selector *BulkSelector) (*models.ServiceSelector, error) {
+	if selector == nil {
+		return nil, status.Error(codes.InvalidArgument, "Selector is expected.")
+	}
+
+	res := &models.ServiceSelector{
+		Environment:    selector.Environment,
+		Cluster:        selector.Cluster,
+		ReplicationSet: selector.ReplicationSet,
+		CustomLabels:   selector.CustomLabels,
+	}
+	if selector.ServiceType != "" {
+		serviceType := models.ServiceType(selector.ServiceType)
+		switch serviceType {
+		case models.MySQLServiceType, models.MongoDBServiceType, models.PostgreSQLServiceType,
+			models.ProxySQLServiceType, models.HAProxyServiceType, models.ExternalServiceType:
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown service type %q.", selector.ServiceType)
+		}
+		res.ServiceType = &serviceType
+	}
+
+	if selector.AgentType != "" {
+		switch models.AgentType(selector.AgentType) {
+		case models.PMMAgentType, models.NodeExporterType, models.VMAgentType, models.RDSExporterType:
+			return nil, status.Errorf(codes.InvalidArgument, "Node-level agent type %q is not supported: selector matches Services and their Agents only.", selector.AgentType)
+		case models.MySQLdExporterType, models.MongoDBExporterType, models.PostgresExporterType, models.ProxySQLExporterType,
+			models.AzureDatabaseExporterType, models.ExternalExporterType,
+			models.QANMySQLPerfSchemaAgentType, models.QANMySQLSlowlogAgentType, models.QANMongoDBProfilerAgentType,
+			models.QANPostgreSQLPgStatementsAgentType, models.QANPostgreSQLPgStatMonitorAgentType:
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown agent type %q.", selector.AgentType)
+		}
+	}
+
+	// protect from changing the whole inventory by mistake
+	if res.IsEmpty() {
+		return nil, status.Error(codes.InvalidArgument, "Selector should not be empty.")
+	}
+	return res, nil
+}
+
+func validateBulkChangeRequest(req *BulkChangeRequest) error {
+	if req.EnableAgents && req.DisableAgents {
+		return status.Error(codes.InvalidArgument, "enable_agents and disable_agents are mutually exclusive.")
+	}
+	if req.EnableQueryExamples && req.DisableQueryExamples {
+		return status.Error(codes.InvalidArgument, "enable_query_examples and disable_query_examples are mutually exclusive.")
+	}
+	if req.LogLevel != "" {
+		if _, ok := inventorypb.LogLevel_value[req.LogLevel]; !ok {
+			return status.Errorf(codes.InvalidArgument, "Unknown log level %q.", req.LogLevel)
+		}
+	}
+	if req.RemoveServices {
+		if len(req.SetCustomLabels) != 0 || len(req.RemoveCustomLabels) != 0 {
+			return status.Error(codes.InvalidArgument, "Services can't be relabeled and removed at the same time.")
+		}
+	} else if req.Force {
+		return status.Error(codes.InvalidArgument, "force is used only with remove_services.")
+	}
+
+	if len(req.SetCustomLabels) == 0 && len(req.RemoveCustomLabels) == 0 && !req.EnableAgents && !req.DisableAgents &&
+		req.LogLevel == "" && !req.EnableQueryExamples && !req.DisableQueryExamples && !req.RemoveServices {
+		return status.Error(codes.InvalidArgument, "No changes requested.")
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/testdb"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestBulkChangeValidation(t *testing.T) {
+	t.Parallel()
+
+	s := NewBulkService(nil, nil, nil)
+	selector := &BulkSelector{Environment: "prod"}
+
+	for _, tc := range []struct {
+		name     string
+		req      *BulkChangeRequest
+		expected *status.Status
+	}{{
+		name:     "NoSelector",
+		req:      &BulkChangeRequest{EnableAgents: true},
+		expected: status.New(codes.InvalidArgument, "Selector is expected."),
+	}, {
+		name:     "EmptySelector",
+		req:      &BulkChangeRequest{Selector: &BulkSelector{}, EnableAgents: true},
+		expected: status.New(codes.InvalidArgument, "Selector should not be empty."),
+	}, {
+		name:     "UnknownServiceType",
+		req:      &BulkChangeRequest{Selector: &BulkSelector{ServiceType: "oracle"}, EnableAgents: true},
+		expected: status.New(codes.InvalidArgument, `Unknown service type "oracle".`),
+	}, {
+		name:     "NodeAgentType",
+		req:      &BulkChangeRequest{Selector: &BulkSelector{Environment: "prod", AgentType: "node_exporter"}, EnableAgents: true},
+		expected: status.New(codes.InvalidArgument, `Node-level agent type "node_exporter" is not supported: selector matches Services and their Agents only.`),
+	}, {
+		name:     "UnknownAgentType",
+		req:      &BulkChangeRequest{Selector: &BulkSelector{Environment: "prod", AgentType: "oracle_exporter"}, EnableAgents: true},
+		expected: status.New(codes.InvalidArgument, `Unknown agent type "oracle_exporter".`),
+	}, {
+		name:     "NoChanges",
+		req:      &BulkChangeRequest{Selector: selector},
+		expected: status.New(codes.InvalidArgument, "No changes requested."),
+	}, {
+		name:     "EnableAndDisable",
+		req:      &BulkChangeRequest{Selector: selector, EnableAgents: true, DisableAgents: true},
+		expected: status.New(codes.InvalidArgument, "enable_agents and disable_agents are mutually exclusive."),
+	}, {
+		name:     "UnknownLogLevel",
+		req:      &BulkChangeRequest{Selector: selector, LogLevel: "verbose"},
+		expected: status.New(codes.InvalidArgument, `Unknown log level "verbose".`),
+	}, {
+		name:     "RelabelAndRemove",
+		req:      &BulkChangeRequest{Selector: selector, RemoveServices: true, SetCustomLabels: map[string]string{"team": "a"}},
+		expected: status.New(codes.InvalidArgument, "Services can't be relabeled and removed at the same time."),
+	}, {
+		name:     "ForceWithoutRemove",
+		req:      &BulkChangeRequest{Selector: selector, EnableAgents: true, Force: true},
+		expected: status.New(codes.InvalidArgument, "force is used only with remove_services."),
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := s.Change(context.Background(), tc.req)
+			assert.Nil(t, res)
+			tests.AssertGRPCError(t, tc.expected, err)
+		})
+	}
+}
+
+func TestBulkChange(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SetupFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+	ctx := context.Background()
+
+	var prodAgentID string
+	for _, env := range []string{"prod", "dev"} {
+		service, err := models.AddNewService(db.Querier, models.MySQLServiceType, &models.AddDBMSServiceParams{
+			ServiceName:  "mysql-" + env,
+			NodeID:       models.PMMServerNodeID,
+			Environment:  env,
+			CustomLabels: map[string]string{"team": "old"},
+			Address:      pointer.ToString("127.0.0.1"),
+			Port:         pointer.ToUint16(3306),
+		})
+		require.NoError(t, err)
+
+		agent, err := models.CreateAgent(db.Querier, models.MySQLdExporterType, &models.CreateAgentParams{
+			PMMAgentID: models.PMMServerAgentID,
+			ServiceID:  service.ServiceID,
+		})
+		require.NoError(t, err)
+		if env == "prod" {
+			prodAgentID = agent.AgentID
+		}
+	}
+
+	state := &mockAgentsStateUpdater{}
+	state.Test(t)
+	vmdb := &mockPrometheusService{}
+	vmdb.Test(t)
+	defer state.AssertExpectations(t)
+	defer vmdb.AssertExpectations(t)
+
+	s := NewBulkService(db, state, vmdb)
+	req := &BulkChangeRequest{
+		Selector:        &BulkSelector{Environment: "prod", CustomLabels: map[string]string{"team": "old"}},
+		SetCustomLabels: map[string]string{"team": "new"},
+		DisableAgents:   true,
+		LogLevel:        "debug",
+		DryRun:          true,
+	}
+
+	t.Run("DryRun", func(t *testing.T) {
+		res, err := s.Change(ctx, req)
+		require.NoError(t, err)
+		assert.True(t, res.DryRun)
+		require.Len(t, res.Changes, 2)
+		assert.Equal(t, prodAgentID, res.Changes[0].AgentID)
+		assert.Equal(t, []string{"disabled", `log_level: "" -> "debug"`}, res.Changes[0].Changes)
+		assert.Equal(t, "mysql-prod", res.Changes[1].ServiceName)
+		assert.Equal(t, []string{`custom label team: "old" -> "new"`}, res.Changes[1].Changes)
+
+		agent, err := models.FindAgentByID(db.Querier, prodAgentID)
+		require.NoError(t, err)
+		assert.False(t, agent.Disabled)
+	})
+
+	t.Run("AgentType", func(t *testing.T) {
+		res, err := s.Change(ctx, &BulkChangeRequest{
+			Selector:      &BulkSelector{Environment: "prod", AgentType: string(models.QANMySQLSlowlogAgentType)},
+			DisableAgents: true,
+			DryRun:        true,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, res.Changes)
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		state.On("RequestStateUpdate", ctx, models.PMMServerAgentID).Once()
+		vmdb.On("RequestConfigurationUpdate").Once()
+
+		req.DryRun = false
+		res, err := s.Change(ctx, req)
+		require.NoError(t, err)
+		assert.False(t, res.DryRun)
+		assert.Len(t, res.Changes, 2)
+
+		agent, err := models.FindAgentByID(db.Querier, prodAgentID)
+		require.NoError(t, err)
+		assert.True(t, agent.Disabled)
+		assert.Equal(t, pointer.ToString("debug"), agent.LogLevel)
+
+		services, err := models.FindServicesBySelector(db.Querier, &models.ServiceSelector{CustomLabels: map[string]string{"team": "new"}})
+		require.NoError(t, err)
+		require.Len(t, services, 1)
+		assert.Equal(t, "mysql-prod", services[0].ServiceName)
+
+		// selector doesn't match anymore
+		res, err = s.Change(ctx, req)
+		require.NoError(t, err)
+		assert.Empty(t, res.Changes)
+	})
+
+	t.Run("RemoveRestrict", func(t *testing.T) {
+		_, err := s.Change(ctx, &BulkChangeRequest{
+			Selector:       &BulkSelector{ServiceType: string(models.MySQLServiceType)},
+			RemoveServices: true,
+		})
+		require.Error(t, err)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		services, err := models.FindServicesBySelector(db.Querier, &models.ServiceSelector{Environment: "dev"})
+		require.NoError(t, err)
+		assert.Len(t, services, 1, "nothing is removed")
+	})
+
+	t.Run("RemoveForceRDS", func(t *testing.T) {
+		node, err := models.CreateNode(db.Querier, models.RemoteRDSNodeType, &models.CreateNodeParams{
+			NodeName: "rds-node",
+			Address:  "rds-address",
+			Region:   pointer.ToString("us-east-1"),
+		})
+		require.NoError(t, err)
+
+		service, err := models.AddNewService(db.Querier, models.MySQLServiceType, &models.AddDBMSServiceParams{
+			ServiceName: "mysql-rds",
+			NodeID:      node.NodeID,
+			Environment: "rds",
+			Address:     pointer.ToString("rds-address"),
+			Port:        pointer.ToUint16(3306),
+		})
+		require.NoError(t, err)
+
+		mysqldExporter, err := models.CreateAgent(db.Querier, models.MySQLdExporterType, &models.CreateAgentParams{
+			PMMAgentID: models.PMMServerAgentID,
+			ServiceID:  service.ServiceID,
+		})
+		require.NoError(t, err)
+
+		rdsExporter, err := models.CreateAgent(db.Querier, models.RDSExporterType, &models.CreateAgentParams{
+			PMMAgentID: models.PMMServerAgentID,
+			NodeID:     node.NodeID,
+		})
+		require.NoError(t, err)
+
+		state.On("RequestStateUpdate", ctx, models.PMMServerAgentID).Once()
+		vmdb.On("RequestConfigurationUpdate").Once()
+
+		res, err := s.Change(ctx, &BulkChangeRequest{
+			Selector:       &BulkSelector{Environment: "rds"},
+			RemoveServices: true,
+			Force:          true,
+		})
+		require.NoError(t, err)
+		require.Len(t, res.Changes, 1)
+		assert.Equal(t, []string{"removed"}, res.Changes[0].Changes)
+
+		_, err = models.FindServiceByID(db.Querier, service.ServiceID)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = models.FindAgentByID(db.Querier, mysqldExporter.AgentID)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = models.FindAgentByID(db.Querier, rdsExporter.AgentID)
+		assert.Equal(t, codes.NotFound, status.Code(err), "orphaned Node's Agents are removed")
+		_, err = models.FindNodeByID(db.Querier, node.NodeID)
+		assert.Equal(t, codes.NotFound, status.Code(err), "orphaned Node is removed")
+	})
+}
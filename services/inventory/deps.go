@@ -43,6 +43,13 @@ type agentsStateUpdater interface {
 	RequestStateUpdate(ctx context.Context, pmmAgentID string)
 }
 
+// agentsDriftDetector is a subset of methods of agents.DriftDetector used by this package.
+// We use it instead of real type for testing and to avoid dependency cycle.
+type agentsDriftDetector interface {
+	CheckDrift(ctx context.Context, pmmAgentID string) ([]*models.AgentDrift, error)
+	Heal(ctx context.Context, pmmAgentID string) error
+}
+
 // prometheusService is a subset of methods of victoriametrics.Service used by this package.
 // We use it instead of real type to avoid dependency cycle.
 //
@@ -0,0 +1,182 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// DriftService reports and heals Agents configuration drift.
+type DriftService struct {
+	db    *reform.DB
+	drift agentsDriftDetector
+}
+
+// NewDriftService creates new DriftService.
+func NewDriftService(db *reform.DB, drift agentsDriftDetector) *DriftService {
+	return &DriftService{
+		db:    db,
+		drift: drift,
+	}
+}
+
+// AgentDrift represents a difference between configuration sent to pmm-agent and state reported by it
+// for a single Agent.
+type AgentDrift struct {
+	AgentID   string `json:"agent_id"`
+	AgentType string `json:"agent_type,omitempty"`
+	// One of not_reported, not_running, stale_config, unexpected.
+	Kind string `json:"kind"`
+	// State last reported by pmm-agent.
+	Status          string     `json:"status,omitempty"`
+	ListenPort      uint32     `json:"listen_port,omitempty"`
+	ProcessExecPath string     `json:"process_exec_path,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	ReportedAt      *time.Time `json:"reported_at,omitempty"`
+	// Set for stale_config kind; secrets are masked.
+	ChangedAt   *time.Time `json:"changed_at,omitempty"`
+	AddedArgs   []string   `json:"added_args,omitempty"`
+	RemovedArgs []string   `json:"removed_args,omitempty"`
+}
+
+// PMMAgentDrift represents drift of all Agents of a single pmm-agent.
+type PMMAgentDrift struct {
+	PMMAgentID string `json:"pmm_agent_id"`
+	// Version reported by pmm-agent when it connected.
+	PMMAgentVersion string        `json:"pmm_agent_version"`
+	Agents          []*AgentDrift `json:"agents"`
+}
+
+// GetDriftRequest is a request of GetDrift method.
+type GetDriftRequest struct {
+	// Return drift only of that pmm-agent; all pmm-agents by default.
+	PMMAgentID string `json:"pmm_agent_id"`
+}
+
+// GetDriftResponse is a response of GetDrift method.
+type GetDriftResponse struct {
+	// pmm-agents with drift.
+	PMMAgents []*PMMAgentDrift `json:"pmm_agents"`
+}
+
+// HealDriftRequest is a request of HealDrift method.
+type HealDriftRequest struct {
+	PMMAgentID string `json:"pmm_agent_id"`
+}
+
+// HealDriftResponse is a response of HealDrift method.
+type HealDriftResponse struct{}
+
+// GetDrift returns pmm-agents with Agents configuration drift.
+// Only pmm-agents connected to this pmm-managed instance are checked.
+func (s *DriftService) GetDrift(ctx context.Context, req *GetDriftRequest) (*GetDriftResponse, error) {
+	var pmmAgents []*models.Agent
+	if req.PMMAgentID != "" {
+		pmmAgent, err := findPMMAgent(s.db.Querier, req.PMMAgentID)
+		if err != nil {
+			return nil, err
+		}
+		pmmAgents = []*models.Agent{pmmAgent}
+	} else {
+		agentType := models.PMMAgentType
+		var err error
+		if pmmAgents, err = models.FindAgents(s.db.Querier, models.AgentFilters{AgentType: &agentType}); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &GetDriftResponse{
+		PMMAgents: []*PMMAgentDrift{},
+	}
+	for _, pmmAgent := range pmmAgents {
+		drift, err := s.drift.CheckDrift(ctx, pmmAgent.AgentID)
+		if err != nil {
+			if req.PMMAgentID == "" && status.Code(err) == codes.FailedPrecondition {
+				// not connected
+				continue
+			}
+			return nil, err
+		}
+		if len(drift) == 0 {
+			continue
+		}
+
+		d := &PMMAgentDrift{
+			PMMAgentID:      pmmAgent.AgentID,
+			PMMAgentVersion: pointer.GetString(pmmAgent.Version),
+			Agents:          make([]*AgentDrift, len(drift)),
+		}
+		for i, a := range drift {
+			d.Agents[i] = &AgentDrift{
+				AgentID:         a.AgentID,
+				AgentType:       a.AgentType,
+				Kind:            string(a.Kind),
+				Status:          a.Status,
+				ListenPort:      a.ListenPort,
+				ProcessExecPath: a.ProcessExecPath,
+				StartedAt:       timeOrNil(a.StartedAt),
+				ReportedAt:      timeOrNil(a.ReportedAt),
+				ChangedAt:       timeOrNil(a.ChangedAt),
+				AddedArgs:       a.AddedArgs,
+				RemovedArgs:     a.RemovedArgs,
+			}
+		}
+		res.PMMAgents = append(res.PMMAgents, d)
+	}
+	return res, nil
+}
+
+// HealDrift restarts Agents with drift of the given pmm-agent and stops unexpected ones.
+func (s *DriftService) HealDrift(ctx context.Context, req *HealDriftRequest) (*HealDriftResponse, error) {
+	pmmAgent, err := findPMMAgent(s.db.Querier, req.PMMAgentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.drift.Heal(ctx, pmmAgent.AgentID); err != nil {
+		return nil, err
+	}
+	return &HealDriftResponse{}, nil
+}
+
+// findPMMAgent returns pmm-agent with given ID.
+func findPMMAgent(q *reform.Querier, id string) (*models.Agent, error) {
+	pmmAgent, err := models.FindAgentByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+	if pmmAgent.AgentType != models.PMMAgentType {
+		return nil, status.Errorf(codes.InvalidArgument, "Agent %q is not a pmm-agent.", id)
+	}
+	return pmmAgent, nil
+}
+
+// timeOrNil returns pointer to the given time, or nil for zero time.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,325 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/pkg/errors"
+	metrics "github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// Scrape health statuses of a single Agent.
+const (
+	ScrapeHealthy          = "healthy"
+	ScrapePartiallyFailing = "partially_failing"
+	ScrapeFailing          = "failing"
+	ScrapeNoData           = "no_data"
+)
+
+// ScrapeHealthService reports health of scrapes of Agents' metrics using series
+// that VictoriaMetrics and vmagents in push metrics mode write for every scrape.
+type ScrapeHealthService struct {
+	db       *reform.DB
+	vmClient v1.API
+	l        *logrus.Entry
+}
+
+// NewScrapeHealthService creates new ScrapeHealthService that queries VictoriaMetrics at given address.
+func NewScrapeHealthService(db *reform.DB, vmAddress string) (*ScrapeHealthService, error) {
+	vmClient, err := metrics.NewClient(metrics.Config{Address: vmAddress})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &ScrapeHealthService{
+		db:       db,
+		vmClient: v1.NewAPI(vmClient),
+		l:        logrus.WithField("component", "inventory/scrape-health"),
+	}, nil
+}
+
+// JobScrapeHealth represents the last scrape of a single scrape job (one per metrics resolution).
+type JobScrapeHealth struct {
+	Job            string     `json:"job"`
+	Instance       string     `json:"instance"`
+	Up             bool       `json:"up"`
+	DurationSec    float64    `json:"scrape_duration_seconds"`
+	SamplesScraped float64    `json:"scrape_samples_scraped"`
+	LastScrapeAt   *time.Time `json:"last_scrape_at,omitempty"`
+}
+
+// AgentScrapeHealth represents scrape health of a single Agent.
+type AgentScrapeHealth struct {
+	AgentID     string `json:"agent_id"`
+	AgentType   string `json:"agent_type"`
+	PMMAgentID  string `json:"pmm_agent_id,omitempty"`
+	ServiceID   string `json:"service_id,omitempty"`
+	NodeID      string `json:"node_id,omitempty"`
+	PushMetrics bool   `json:"push_metrics"`
+	// Agent status reported by pmm-agent, like RUNNING.
+	AgentStatus string `json:"agent_status,omitempty"`
+	// One of: healthy, partially_failing, failing, no_data.
+	Health string             `json:"health"`
+	Jobs   []*JobScrapeHealth `json:"jobs"`
+}
+
+// GetScrapeHealthRequest is a request of GetScrapeHealth method; all non-empty filters should match.
+type GetScrapeHealthRequest struct {
+	AgentID   string `json:"agent_id"`
+	ServiceID string `json:"service_id"`
+	NodeID    string `json:"node_id"`
+	// Return only Agents which scrapes are not healthy.
+	UnhealthyOnly bool `json:"unhealthy_only"`
+}
+
+// GetScrapeHealthResponse is a response of GetScrapeHealth method.
+type GetScrapeHealthResponse struct {
+	Agents []*AgentScrapeHealth `json:"agents"`
+}
+
+// GetScrapeHealth returns scrape health of Agents with metrics.
+func (s *ScrapeHealthService) GetScrapeHealth(ctx context.Context, req *GetScrapeHealthRequest) (*GetScrapeHealthResponse, error) {
+	res := &GetScrapeHealthResponse{
+		Agents: []*AgentScrapeHealth{},
+	}
+	var instances map[string][]string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		agents, err := s.findAgents(tx.Querier, req)
+		if err != nil {
+			return err
+		}
+
+		res.Agents = make([]*AgentScrapeHealth, len(agents))
+		for i, a := range agents {
+			res.Agents[i] = a.health
+		}
+		instances = make(map[string][]string)
+		for _, a := range agents {
+			if a.instance != "" {
+				instances[a.instance] = append(instances[a.instance], a.health.AgentID)
+			}
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	jobs, err := s.queryJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	joinScrapeHealth(res.Agents, jobs, instances)
+
+	if req.UnhealthyOnly {
+		unhealthy := make([]*AgentScrapeHealth, 0, len(res.Agents))
+		for _, a := range res.Agents {
+			if a.Health != ScrapeHealthy {
+				unhealthy = append(unhealthy, a)
+			}
+		}
+		res.Agents = unhealthy
+	}
+	return res, nil
+}
+
+type scrapeHealthAgent struct {
+	health *AgentScrapeHealth
+	// instance label of scrape jobs without agent_id label (rds_exporter)
+	instance string
+}
+
+// findAgents returns Agents with metrics matching request filters.
+func (s *ScrapeHealthService) findAgents(q *reform.Querier, req *GetScrapeHealthRequest) ([]*scrapeHealthAgent, error) {
+	var agents []*models.Agent
+	if req.AgentID != "" {
+		agent, err := models.FindAgentByID(q, req.AgentID)
+		if err != nil {
+			return nil, err
+		}
+		agents = []*models.Agent{agent}
+	} else {
+		var err error
+		if agents, err = models.FindAgents(q, models.AgentFilters{ServiceID: req.ServiceID}); err != nil {
+			return nil, err
+		}
+	}
+
+	var res []*scrapeHealthAgent
+	for _, agent := range agents {
+		switch agent.AgentType {
+		case models.PMMAgentType, models.QANMySQLPerfSchemaAgentType, models.QANMySQLSlowlogAgentType, models.QANMongoDBProfilerAgentType,
+			models.QANPostgreSQLPgStatementsAgentType, models.QANPostgreSQLPgStatMonitorAgentType:
+			if req.AgentID != "" {
+				return nil, status.Errorf(codes.InvalidArgument, "Agent %q of type %s has no metrics.", agent.AgentID, agent.AgentType)
+			}
+			continue
+		}
+
+		a := &scrapeHealthAgent{
+			health: &AgentScrapeHealth{
+				AgentID:     agent.AgentID,
+				AgentType:   string(agent.AgentType),
+				PMMAgentID:  pointer.GetString(agent.PMMAgentID),
+				ServiceID:   pointer.GetString(agent.ServiceID),
+				NodeID:      pointer.GetString(agent.NodeID),
+				PushMetrics: agent.PushMetrics,
+				AgentStatus: agent.Status,
+				Jobs:        []*JobScrapeHealth{},
+			},
+		}
+		if agent.ServiceID != nil {
+			service, err := models.FindServiceByID(q, *agent.ServiceID)
+			if err != nil {
+				return nil, err
+			}
+			a.health.NodeID = service.NodeID
+		}
+		if req.NodeID != "" && a.health.NodeID != req.NodeID {
+			continue
+		}
+
+		// rds_exporter jobs are shared by all Agents of a single pmm-agent and have no agent_id label
+		if agent.AgentType == models.RDSExporterType && agent.PMMAgentID != nil && agent.ListenPort != nil && !agent.PushMetrics {
+			pmmAgent, err := models.FindAgentByID(q, *agent.PMMAgentID)
+			if err != nil {
+				return nil, err
+			}
+			node, err := models.FindNodeByID(q, pointer.GetString(pmmAgent.RunsOnNodeID))
+			if err != nil {
+				return nil, err
+			}
+			a.instance = net.JoinHostPort(node.Address, strconv.Itoa(int(*agent.ListenPort)))
+		}
+
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+// queryJobs returns the last scrapes of all scrape jobs.
+func (s *ScrapeHealthService) queryJobs(ctx context.Context) ([]*scrapeHealthJob, error) {
+	now := time.Now()
+	jobs := make(map[string]*scrapeHealthJob)
+	for _, q := range []struct {
+		expr  string
+		apply func(job *JobScrapeHealth, value float64)
+	}{
+		{"up", func(job *JobScrapeHealth, value float64) { job.Up = value == 1 }},
+		{"scrape_duration_seconds", func(job *JobScrapeHealth, value float64) { job.DurationSec = value }},
+		{"scrape_samples_scraped", func(job *JobScrapeHealth, value float64) { job.SamplesScraped = value }},
+		{"timestamp(up)", func(job *JobScrapeHealth, value float64) {
+			t := time.Unix(0, int64(value*float64(time.Second))).UTC()
+			job.LastScrapeAt = &t
+		}},
+	} {
+		value, warns, err := s.vmClient.Query(ctx, q.expr, now)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "Failed to query VictoriaMetrics: %s.", err)
+		}
+		for _, warn := range warns {
+			s.l.Warn(warn)
+		}
+		vector, ok := value.(model.Vector)
+		if !ok {
+			return nil, errors.Errorf("%s returned %s instead of instant vector", q.expr, value.Type())
+		}
+
+		for _, sample := range vector {
+			job := &scrapeHealthJob{
+				agentID: string(sample.Metric["agent_id"]),
+				JobScrapeHealth: JobScrapeHealth{
+					Job:      string(sample.Metric["job"]),
+					Instance: string(sample.Metric["instance"]),
+				},
+			}
+			key := job.agentID + "\x00" + job.Job + "\x00" + job.Instance
+			if jobs[key] == nil {
+				jobs[key] = job
+			}
+			q.apply(&jobs[key].JobScrapeHealth, float64(sample.Value))
+		}
+	}
+
+	res := make([]*scrapeHealthJob, 0, len(jobs))
+	for _, job := range jobs {
+		res = append(res, job)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Job < res[j].Job })
+	return res, nil
+}
+
+type scrapeHealthJob struct {
+	JobScrapeHealth
+	agentID string
+}
+
+// joinScrapeHealth adds jobs to Agents by agent_id label, or by instance label for jobs without it
+// (such jobs are shared by several Agents), and sets Agents' health.
+func joinScrapeHealth(agents []*AgentScrapeHealth, jobs []*scrapeHealthJob, instances map[string][]string) {
+	byID := make(map[string]*AgentScrapeHealth, len(agents))
+	for _, a := range agents {
+		byID[a.AgentID] = a
+	}
+
+	for _, job := range jobs {
+		agentIDs := []string{job.agentID}
+		if job.agentID == "" {
+			agentIDs = instances[job.Instance]
+		}
+		for _, agentID := range agentIDs {
+			if a := byID[agentID]; a != nil {
+				j := job.JobScrapeHealth
+				a.Jobs = append(a.Jobs, &j)
+			}
+		}
+	}
+
+	for _, a := range agents {
+		var up int
+		for _, j := range a.Jobs {
+			if j.Up {
+				up++
+			}
+		}
+
+		switch {
+		case len(a.Jobs) == 0:
+			a.Health = ScrapeNoData
+		case up == len(a.Jobs):
+			a.Health = ScrapeHealthy
+		case up == 0:
+			a.Health = ScrapeFailing
+		default:
+			a.Health = ScrapePartiallyFailing
+		}
+	}
+}
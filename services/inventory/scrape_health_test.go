@@ -0,0 +1,121 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVictoriaMetricsStandIn returns a minimal stand-in of VictoriaMetrics instant query API.
+func newVictoriaMetricsStandIn(t *testing.T) *httptest.Server {
+	series := []struct {
+		labels string
+		up     int
+	}{
+		{`"job": "mysqld_exporter_agent_id_1_hr-5s", "instance": "/agent_id/1", "agent_id": "/agent_id/1"`, 1},
+		{`"job": "mysqld_exporter_agent_id_1_mr-10s", "instance": "/agent_id/1", "agent_id": "/agent_id/1"`, 0},
+		{`"job": "node_exporter_agent_id_2_hr-5s", "instance": "/agent_id/2", "agent_id": "/agent_id/2"`, 1},
+		{`"job": "rds_exporter_10_0_0_1_42001_hr-5s", "instance": "10.0.0.1:42001"`, 0},
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		require.NoError(t, req.ParseForm())
+		assert.Equal(t, "/api/v1/query", req.URL.Path)
+
+		var result string
+		for i, s := range series {
+			if i != 0 {
+				result += ","
+			}
+			var value float64
+			switch req.Form.Get("query") {
+			case "up":
+				value = float64(s.up)
+			case "scrape_duration_seconds":
+				value = 0.25
+			case "scrape_samples_scraped":
+				value = float64(100 * s.up)
+			case "timestamp(up)":
+				value = 1600000000
+			default:
+				t.Errorf("unexpected query %q", req.Form.Get("query"))
+			}
+			result += fmt.Sprintf(`{"metric": {%s}, "value": [1600000001, "%g"]}`, s.labels, value)
+		}
+		_, _ = fmt.Fprintf(rw, `{"status": "success", "data": {"resultType": "vector", "result": [%s]}}`, result)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestScrapeHealth(t *testing.T) {
+	vm := newVictoriaMetricsStandIn(t)
+	s, err := NewScrapeHealthService(nil, vm.URL)
+	require.NoError(t, err)
+	s.l = logrus.WithField("test", t.Name())
+
+	jobs, err := s.queryJobs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, jobs, 4)
+
+	agents := []*AgentScrapeHealth{
+		{AgentID: "/agent_id/1", AgentType: "mysqld_exporter", AgentStatus: "RUNNING"},
+		{AgentID: "/agent_id/2", AgentType: "node_exporter", AgentStatus: "RUNNING", PushMetrics: true},
+		{AgentID: "/agent_id/3", AgentType: "rds_exporter", AgentStatus: "RUNNING"},
+		{AgentID: "/agent_id/4", AgentType: "postgres_exporter", AgentStatus: "WAITING"},
+		{AgentID: "/agent_id/5", AgentType: "rds_exporter", AgentStatus: "RUNNING"},
+	}
+	joinScrapeHealth(agents, jobs, map[string][]string{"10.0.0.1:42001": {"/agent_id/3", "/agent_id/5"}})
+
+	lastScrapeAt := time.Unix(1600000000, 0).UTC()
+	assert.Equal(t, ScrapePartiallyFailing, agents[0].Health)
+	assert.Equal(t, []*JobScrapeHealth{{
+		Job:            "mysqld_exporter_agent_id_1_hr-5s",
+		Instance:       "/agent_id/1",
+		Up:             true,
+		DurationSec:    0.25,
+		SamplesScraped: 100,
+		LastScrapeAt:   &lastScrapeAt,
+	}, {
+		Job:            "mysqld_exporter_agent_id_1_mr-10s",
+		Instance:       "/agent_id/1",
+		DurationSec:    0.25,
+		SamplesScraped: 0,
+		LastScrapeAt:   &lastScrapeAt,
+	}}, agents[0].Jobs)
+
+	assert.Equal(t, ScrapeHealthy, agents[1].Health)
+	assert.Len(t, agents[1].Jobs, 1)
+
+	assert.Equal(t, ScrapeFailing, agents[2].Health)
+	require.Len(t, agents[2].Jobs, 1)
+	assert.Equal(t, "rds_exporter_10_0_0_1_42001_hr-5s", agents[2].Jobs[0].Job)
+	assert.Equal(t, agents[2].Jobs, agents[4].Jobs)
+	assert.Equal(t, ScrapeFailing, agents[4].Health)
+
+	assert.Equal(t, ScrapeNoData, agents[3].Health)
+	assert.Empty(t, agents[3].Jobs)
+}
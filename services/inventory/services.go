@@ -237,72 +237,84 @@ func (ss *ServicesService) AddExternalService(ctx context.Context, params *model
 // Removes Service with the Agents if force == true.
 // Returns an error if force == false and Service has Agents.
 func (ss *ServicesService) Remove(ctx context.Context, id string, force bool) error {
-	pmmAgentIds := make(map[string]struct{})
+	var pmmAgentIds map[string]struct{}
 
 	if e := ss.db.InTransaction(func(tx *reform.TX) error {
-		service, err := models.FindServiceByID(tx.Querier, id)
-		if err != nil {
-			return err
-		}
+		var err error
+		pmmAgentIds, err = removeService(tx.Querier, id, force)
+		return err
+	}); e != nil {
+		return e
+	}
 
-		mode := models.RemoveRestrict
-		if force {
-			mode = models.RemoveCascade
+	for pmmAgentID := range pmmAgentIds {
+		ss.state.RequestStateUpdate(ctx, pmmAgentID)
+	}
 
-			agents, err := models.FindPMMAgentsForService(tx.Querier, id)
-			if err != nil {
-				return err
-			}
+	if force {
+		// It's required to regenerate victoriametrics config file for the agents which aren't run by pmm-agent.
+		ss.vmdb.RequestConfigurationUpdate()
+	}
 
-			for _, agent := range agents {
-				pmmAgentIds[agent.AgentID] = struct{}{}
-			}
-		}
+	return nil
+}
 
-		err = models.RemoveService(tx.Querier, id, mode)
+// removeService removes Service without any Agents, or with the Agents if force == true.
+// With force, RDS and Azure Nodes are removed too if they are not used by other pmm-agents.
+// Returns IDs of pmm-agents that should receive state updates.
+func removeService(q *reform.Querier, id string, force bool) (map[string]struct{}, error) {
+	pmmAgentIds := make(map[string]struct{})
+
+	service, err := models.FindServiceByID(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := models.RemoveRestrict
+	if force {
+		mode = models.RemoveCascade
+
+		agents, err := models.FindPMMAgentsForService(q, id)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if force {
-			node, err := models.FindNodeByID(tx.Querier, service.NodeID)
-			if err != nil {
-				return err
-			}
-
-			// For RDS and Azure remove also node.
-			if node.NodeType == models.RemoteRDSNodeType || node.NodeType == models.RemoteAzureDatabaseNodeType {
-				agents, err := models.FindAgents(tx.Querier, models.AgentFilters{NodeID: node.NodeID})
-				if err != nil {
-					return err
-				}
-				for _, agent := range agents {
-					if agent.PMMAgentID != nil {
-						pmmAgentIds[pointer.GetString(agent.PMMAgentID)] = struct{}{}
-					}
-				}
-
-				if len(pmmAgentIds) <= 1 {
-					if err = models.RemoveNode(tx.Querier, node.NodeID, models.RemoveCascade); err != nil {
-						return err
-					}
-				}
-			}
+		for _, agent := range agents {
+			pmmAgentIds[agent.AgentID] = struct{}{}
 		}
+	}
 
-		return nil
-	}); e != nil {
-		return e
+	if err = models.RemoveService(q, id, mode); err != nil {
+		return nil, err
 	}
 
-	for pmmAgentID := range pmmAgentIds {
-		ss.state.RequestStateUpdate(ctx, pmmAgentID)
+	if !force {
+		return pmmAgentIds, nil
 	}
 
-	if force {
-		// It's required to regenerate victoriametrics config file for the agents which aren't run by pmm-agent.
-		ss.vmdb.RequestConfigurationUpdate()
+	node, err := models.FindNodeByID(q, service.NodeID)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// For RDS and Azure remove also node.
+	if node.NodeType == models.RemoteRDSNodeType || node.NodeType == models.RemoteAzureDatabaseNodeType {
+		agents, err := models.FindAgents(q, models.AgentFilters{NodeID: node.NodeID})
+		if err != nil {
+			return nil, err
+		}
+		for _, agent := range agents {
+			if agent.PMMAgentID != nil {
+				pmmAgentIds[pointer.GetString(agent.PMMAgentID)] = struct{}{}
+			}
+		}
+
+		if len(pmmAgentIds) <= 1 {
+			if err = models.RemoveNode(q, node.NodeID, models.RemoveCascade); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return pmmAgentIds, nil
 }
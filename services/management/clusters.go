@@ -0,0 +1,770 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/managementpb"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/agents"
+)
+
+const (
+	clusterSyncInterval  = time.Minute
+	clusterActionTimeout = 30 * time.Second
+
+	// clusterRoleLabel is a custom label of cluster members' Services with the current member role.
+	clusterRoleLabel = "role"
+)
+
+// clusterMember represents a discovered database cluster member.
+type clusterMember struct {
+	Host           string
+	Port           uint16
+	ReplicationSet string
+	// Lowercase member role like "primary" or "secondary"; empty if unknown.
+	Role  string
+	State string
+}
+
+// Address returns member's host:port address.
+func (m *clusterMember) Address() string {
+	return net.JoinHostPort(m.Host, strconv.Itoa(int(m.Port)))
+}
+
+// clusterTopology is a result of cluster discovery.
+type clusterTopology struct {
+	// Cluster name reported by the database; empty if unknown.
+	Name    string
+	Members []*clusterMember
+}
+
+// ClustersService registers database clusters from a seed instance
+// and keeps Services of their members in sync with the cluster membership.
+type ClustersService struct {
+	db       *reform.DB
+	actions  actionsService
+	state    agentsStateUpdater
+	vmdb     prometheusService
+	http     *http.Client
+	discover func(ctx context.Context, cluster *models.MonitoredCluster, known []*models.MonitoredClusterMember) (*clusterTopology, error)
+	l        *logrus.Entry
+
+	// members missing for that period are removed; zero disables automatic removal
+	memberGracePeriod time.Duration
+}
+
+// NewClustersService creates new ClustersService.
+// Members that are not discovered are kept as missing for memberGracePeriod before they are removed;
+// if it is zero, they are kept until removed explicitly.
+func NewClustersService(db *reform.DB, actions actionsService, state agentsStateUpdater, vmdb prometheusService, memberGracePeriod time.Duration) *ClustersService {
+	s := &ClustersService{
+		db:                db,
+		actions:           actions,
+		state:             state,
+		vmdb:              vmdb,
+		http:              new(http.Client),
+		l:                 logrus.WithField("component", "clusters"),
+		memberGracePeriod: memberGracePeriod,
+	}
+	s.discover = s.discoverCluster
+	return s
+}
+
+// MonitoredClusterMember represents monitored cluster member in JSON API responses.
+type MonitoredClusterMember struct {
+	Address        string `json:"address"`
+	ServiceID      string `json:"service_id"`
+	ReplicationSet string `json:"replication_set,omitempty"`
+	Role           string `json:"role,omitempty"`
+	State          string `json:"state,omitempty"`
+	// True if Service was registered before the cluster; it is kept when the member leaves the cluster.
+	Adopted bool `json:"adopted,omitempty"`
+	// Set if the member was not discovered by the last sync.
+	MissingSince *time.Time `json:"missing_since,omitempty"`
+}
+
+// MonitoredCluster represents monitored cluster in JSON API responses.
+type MonitoredCluster struct {
+	ClusterID    string                    `json:"cluster_id"`
+	Name         string                    `json:"name"`
+	Type         string                    `json:"type"`
+	PMMAgentID   string                    `json:"pmm_agent_id"`
+	SeedAddress  string                    `json:"seed_address"`
+	SeedPort     uint16                    `json:"seed_port"`
+	Environment  string                    `json:"environment,omitempty"`
+	CustomLabels map[string]string         `json:"custom_labels,omitempty"`
+	Members      []*MonitoredClusterMember `json:"members"`
+	LastSyncAt   *time.Time                `json:"last_sync_at,omitempty"`
+	LastError    string                    `json:"last_error,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at"`
+}
+
+// ListClustersRequest is a request of List method.
+type ListClustersRequest struct{}
+
+// ListClustersResponse is a response of List method.
+type ListClustersResponse struct {
+	Clusters []*MonitoredCluster `json:"clusters"`
+}
+
+// RemoveClusterRequest is a request of Remove method.
+type RemoveClusterRequest struct {
+	ClusterID string `json:"cluster_id"`
+}
+
+// RemoveClusterResponse is a response of Remove method.
+type RemoveClusterResponse struct{}
+
+// RemoveClusterMemberRequest is a request of RemoveMember method.
+type RemoveClusterMemberRequest struct {
+	ClusterID string `json:"cluster_id"`
+	Address   string `json:"address"`
+}
+
+// RemoveClusterMemberResponse is a response of RemoveMember method.
+type RemoveClusterMemberResponse struct{}
+
+// SyncClusterRequest is a request of Sync method.
+type SyncClusterRequest struct {
+	ClusterID string `json:"cluster_id"`
+}
+
+// SyncClusterResponse is a response of Sync method.
+type SyncClusterResponse struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Missing int `json:"missing"`
+	Removed int `json:"removed"`
+}
+
+// addCluster discovers members of a new cluster from the seed instance, then registers the cluster and its members.
+// Known members are those that can't be discovered from the seed instance, like other mongos routers.
+func (s *ClustersService) addCluster(ctx context.Context, params *models.MonitoredClusterParams, known []*models.MonitoredClusterMember) (*MonitoredCluster, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	topology, err := s.discover(ctx, &models.MonitoredCluster{
+		Name:              params.Name,
+		Type:              params.Type,
+		PMMAgentID:        params.PMMAgentID,
+		SeedAddress:       params.SeedAddress,
+		SeedPort:          params.SeedPort,
+		Username:          params.Username,
+		Password:          params.Password,
+		TLS:               params.TLS,
+		TLSSkipVerify:     params.TLSSkipVerify,
+		MySQLOptions:      params.MySQLOptions,
+		MongoDBOptions:    params.MongoDBOptions,
+		PostgreSQLOptions: params.PostgreSQLOptions,
+		PatroniURL:        params.PatroniURL,
+	}, known)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Failed to discover cluster members: %s.", err)
+	}
+	if params.Name == "" {
+		params.Name = topology.Name
+	}
+
+	var res *MonitoredCluster
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		cluster, err := models.CreateMonitoredCluster(tx.Querier, params)
+		if err != nil {
+			return err
+		}
+		if _, err = s.reconcileClusterMembers(tx.Querier, cluster, topology); err != nil {
+			return err
+		}
+
+		now := models.Now()
+		cluster.LastSyncAt = &now
+		if err = tx.UpdateColumns(cluster, "last_sync_at"); err != nil {
+			return errors.WithStack(err)
+		}
+
+		res, err = convertMonitoredCluster(tx.Querier, cluster)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.state.RequestStateUpdate(ctx, params.PMMAgentID)
+	s.vmdb.RequestConfigurationUpdate()
+	return res, nil
+}
+
+// List returns all monitored clusters with their members.
+func (s *ClustersService) List(ctx context.Context, req *ListClustersRequest) (*ListClustersResponse, error) {
+	res := new(ListClustersResponse)
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		clusters, err := models.FindMonitoredClusters(tx.Querier)
+		if err != nil {
+			return err
+		}
+
+		res.Clusters = make([]*MonitoredCluster, len(clusters))
+		for i, cluster := range clusters {
+			if res.Clusters[i], err = convertMonitoredCluster(tx.Querier, cluster); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return res, nil
+}
+
+// Remove removes monitored cluster with all Services, Agents and Nodes created for its members.
+func (s *ClustersService) Remove(ctx context.Context, req *RemoveClusterRequest) (*RemoveClusterResponse, error) {
+	var pmmAgentID string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		cluster, err := models.FindMonitoredClusterByID(tx.Querier, req.ClusterID)
+		if err != nil {
+			return err
+		}
+		pmmAgentID = cluster.PMMAgentID
+
+		return models.RemoveMonitoredCluster(tx.Querier, req.ClusterID)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.state.RequestStateUpdate(ctx, pmmAgentID)
+	s.vmdb.RequestConfigurationUpdate()
+	return &RemoveClusterResponse{}, nil
+}
+
+// RemoveMember removes missing member of the given cluster without waiting for the grace period.
+func (s *ClustersService) RemoveMember(ctx context.Context, req *RemoveClusterMemberRequest) (*RemoveClusterMemberResponse, error) {
+	var pmmAgentID string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		cluster, err := models.FindMonitoredClusterByID(tx.Querier, req.ClusterID)
+		if err != nil {
+			return err
+		}
+		pmmAgentID = cluster.PMMAgentID
+
+		members, err := models.FindMonitoredClusterMembers(tx.Querier, cluster.ID)
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			if member.Address != req.Address {
+				continue
+			}
+
+			if member.MissingSince == nil {
+				return status.Errorf(codes.FailedPrecondition, "Member %q of cluster %q is not missing.", req.Address, cluster.Name)
+			}
+			return models.RemoveMonitoredClusterMembers(tx.Querier, []*models.MonitoredClusterMember{member})
+		}
+		return status.Errorf(codes.NotFound, "Member %q of cluster %q not found.", req.Address, cluster.Name)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.state.RequestStateUpdate(ctx, pmmAgentID)
+	s.vmdb.RequestConfigurationUpdate()
+	return &RemoveClusterMemberResponse{}, nil
+}
+
+// Sync discovers members of the given cluster and reconciles them immediately.
+func (s *ClustersService) Sync(ctx context.Context, req *SyncClusterRequest) (*SyncClusterResponse, error) {
+	cluster, err := models.FindMonitoredClusterByID(s.db.Querier, req.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.sync(ctx, cluster)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "Failed to sync cluster %q: %s.", cluster.Name, err)
+	}
+	return res, nil
+}
+
+// Run syncs all monitored clusters periodically until ctx is canceled.
+func (s *ClustersService) Run(ctx context.Context) {
+	s.l.Info("Starting...")
+	defer s.l.Info("Done.")
+
+	ticker := time.NewTicker(clusterSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.syncAll(ctx)
+	}
+}
+
+// syncAll syncs all monitored clusters.
+func (s *ClustersService) syncAll(ctx context.Context) {
+	clusters, err := models.FindMonitoredClusters(s.db.Querier)
+	if err != nil {
+		s.l.Errorf("Failed to find monitored clusters: %+v.", err)
+		return
+	}
+
+	for _, cluster := range clusters {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err = s.sync(ctx, cluster); err != nil {
+			s.l.Warnf("Failed to sync cluster %q: %s.", cluster.Name, err)
+		}
+	}
+}
+
+// sync discovers members of the given cluster and reconciles them.
+// Existing members are kept if discovery fails.
+func (s *ClustersService) sync(ctx context.Context, cluster *models.MonitoredCluster) (*SyncClusterResponse, error) {
+	known, err := models.FindMonitoredClusterMembers(s.db.Querier, cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+	topology, discoverErr := s.discover(ctx, cluster, known)
+
+	res := new(SyncClusterResponse)
+	e := recordSync(s.db, models.MonitoredClusterTable, cluster.ID, discoverErr, func(q *reform.Querier, locked reform.Struct) (string, error) {
+		var err error
+		res, err = s.reconcileClusterMembers(q, locked.(*models.MonitoredCluster), topology)
+		return "", err
+	})
+	if e != nil {
+		return nil, e
+	}
+	if discoverErr != nil {
+		return nil, discoverErr
+	}
+
+	if res.Added != 0 || res.Updated != 0 || res.Missing != 0 || res.Removed != 0 {
+		s.l.Infof("Cluster %q: %d members added, %d updated, %d missing, %d removed.", cluster.Name, res.Added, res.Updated, res.Missing, res.Removed)
+		s.state.RequestStateUpdate(ctx, cluster.PMMAgentID)
+		s.vmdb.RequestConfigurationUpdate()
+	}
+	return res, nil
+}
+
+// recordSync locks the row of monitored cluster or service discovery source with the given ID to avoid concurrent syncs
+// creating the same Services, reconciles it if discovery succeeded, and records sync time and the last error:
+// discovery error, or non-fatal error returned by reconcile.
+func recordSync(db *reform.DB, table reform.Table, id string, discoverErr error, reconcile func(q *reform.Querier, locked reform.Struct) (string, error)) error {
+	return db.InTransaction(func(tx *reform.TX) error {
+		locked, err := tx.SelectOneFrom(table, "WHERE id = $1 FOR UPDATE", id)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		var lastError string
+		if discoverErr == nil {
+			if lastError, err = reconcile(tx.Querier, locked); err != nil {
+				return err
+			}
+		} else {
+			lastError = discoverErr.Error()
+		}
+		query := fmt.Sprintf("UPDATE %s SET last_sync_at = $1, last_error = $2 WHERE id = $3", tx.QuoteIdentifier(table.Name()))
+		_, err = tx.Exec(query, models.Now(), lastError, id)
+		return errors.WithStack(err)
+	})
+}
+
+// discoverCluster discovers cluster members from the seed instance, or from known members if it is not available
+// (for example, from a new PostgreSQL primary after failover). Patroni is used instead if configured.
+func (s *ClustersService) discoverCluster(ctx context.Context, cluster *models.MonitoredCluster, known []*models.MonitoredClusterMember) (*clusterTopology, error) {
+	if cluster.PatroniURL != "" {
+		return s.discoverPatroniCluster(ctx, cluster)
+	}
+
+	candidates := []string{net.JoinHostPort(cluster.SeedAddress, strconv.Itoa(int(cluster.SeedPort)))}
+	for _, m := range known {
+		// sharded cluster is discovered only via mongos
+		if cluster.Type == models.MongoDBShardedClusterType && m.Role != mongosRole {
+			continue
+		}
+		if m.Address != candidates[0] {
+			candidates = append(candidates, m.Address)
+		}
+	}
+
+	errs := make([]string, 0, len(candidates))
+	for _, address := range candidates {
+		host, portS, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		port, err := strconv.ParseUint(portS, 10, 16)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var topology *clusterTopology
+		switch cluster.Type.ServiceType() {
+		case models.MySQLServiceType:
+			topology, err = s.discoverMySQLCluster(ctx, cluster, host, uint16(port))
+		case models.MongoDBServiceType:
+			topology, err = s.discoverMongoDBCluster(ctx, cluster, host, uint16(port), known)
+		case models.PostgreSQLServiceType:
+			topology, err = s.discoverPostgreSQLCluster(ctx, cluster, host, uint16(port))
+		default:
+			return nil, errors.Errorf("unhandled cluster type %q", cluster.Type)
+		}
+		if err == nil {
+			return topology, nil
+		}
+		if ctx.Err() != nil {
+			return nil, errors.WithStack(ctx.Err())
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", address, err))
+	}
+	return nil, errors.New(strings.Join(errs, "; "))
+}
+
+// reconcileClusterMembers creates, updates and removes Nodes, Services and Agents
+// so that they match discovered cluster members.
+// Members that were not discovered are marked as missing, because they may be restarting
+// or temporarily unreachable; they are removed only after the grace period.
+func (s *ClustersService) reconcileClusterMembers(q *reform.Querier, cluster *models.MonitoredCluster, topology *clusterTopology) (*SyncClusterResponse, error) {
+	existing, err := models.FindMonitoredClusterMembers(q, cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+	existingByAddress := make(map[string]*models.MonitoredClusterMember, len(existing))
+	for _, member := range existing {
+		existingByAddress[member.Address] = member
+	}
+
+	res := new(SyncClusterResponse)
+	for _, m := range topology.Members {
+		address := m.Address()
+		if member := existingByAddress[address]; member != nil {
+			delete(existingByAddress, address)
+
+			updated, err := updateClusterMember(q, cluster, member, m)
+			if err != nil {
+				return nil, err
+			}
+			if updated {
+				res.Updated++
+			}
+			continue
+		}
+
+		if err = addClusterMember(q, cluster, m); err != nil {
+			return nil, err
+		}
+		res.Added++
+	}
+
+	now := models.Now()
+	removed := make([]*models.MonitoredClusterMember, 0, len(existingByAddress))
+	for _, member := range existing {
+		if _, ok := existingByAddress[member.Address]; !ok {
+			continue
+		}
+
+		if member.MissingSince == nil {
+			if err = markClusterMemberMissing(q, cluster, member, now); err != nil {
+				return nil, err
+			}
+			res.Missing++
+			continue
+		}
+
+		if s.memberGracePeriod != 0 && now.Sub(*member.MissingSince) >= s.memberGracePeriod {
+			removed = append(removed, member)
+		}
+	}
+	if err = models.RemoveMonitoredClusterMembers(q, removed); err != nil {
+		return nil, err
+	}
+	res.Removed = len(removed)
+
+	return res, nil
+}
+
+// addClusterMember adopts existing Service of discovered member, or creates Node, Service and Agents for it.
+func addClusterMember(q *reform.Querier, cluster *models.MonitoredCluster, m *clusterMember) error {
+	member := &models.MonitoredClusterMember{
+		ClusterID:      cluster.ID,
+		Address:        m.Address(),
+		ReplicationSet: m.ReplicationSet,
+		Role:           m.Role,
+		State:          m.State,
+	}
+
+	serviceType := cluster.Type.ServiceType()
+	service, err := models.FindServiceByAddress(q, serviceType, m.Host, m.Port)
+	if err != nil {
+		return err
+	}
+
+	if service != nil {
+		member.Adopted = true
+		member.ServiceID = service.ServiceID
+		if _, err = relabelClusterMemberService(q, cluster, member, service); err != nil {
+			return err
+		}
+		return models.CreateMonitoredClusterMember(q, member)
+	}
+
+	nodeID, created, err := clusterMemberNode(q, cluster, m.Host)
+	if err != nil {
+		return err
+	}
+	if created {
+		member.NodeID = pointer.ToString(nodeID)
+	}
+
+	service, err = models.AddNewService(q, serviceType, &models.AddDBMSServiceParams{
+		ServiceName:    cluster.Name + "/" + member.Address,
+		NodeID:         nodeID,
+		Environment:    cluster.Environment,
+		Cluster:        cluster.Name,
+		ReplicationSet: m.ReplicationSet,
+		Address:        pointer.ToString(m.Host),
+		Port:           pointer.ToUint16(m.Port),
+		CustomLabels:   clusterMemberLabels(cluster.CustomLabels, m.Role),
+	})
+	if err != nil {
+		return err
+	}
+	member.ServiceID = service.ServiceID
+
+	switch serviceType {
+	case models.MySQLServiceType:
+		err = createMySQLClusterMemberAgents(q, cluster, service)
+	case models.MongoDBServiceType:
+		err = createMongoDBClusterMemberAgents(q, cluster, service)
+	case models.PostgreSQLServiceType:
+		err = createPostgreSQLClusterMemberAgents(q, cluster, service)
+	default:
+		err = errors.Errorf("unhandled Service type %q", serviceType)
+	}
+	if err != nil {
+		return err
+	}
+
+	return models.CreateMonitoredClusterMember(q, member)
+}
+
+// markClusterMemberMissing marks member as missing and removes its role label, because its role is unknown:
+// for example, a failed PostgreSQL primary should not be labeled as primary together with the new one after failover.
+func markClusterMemberMissing(q *reform.Querier, cluster *models.MonitoredCluster, member *models.MonitoredClusterMember, now time.Time) error {
+	service, err := models.FindServiceByID(q, member.ServiceID)
+	if err != nil {
+		return err
+	}
+
+	member.Role = ""
+	member.MissingSince = &now
+	if err = q.Update(member); err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = relabelClusterMemberService(q, cluster, member, service)
+	return err
+}
+
+// updateClusterMember updates labels of member's Service and member's state if they were changed.
+// Member is no longer missing.
+func updateClusterMember(q *reform.Querier, cluster *models.MonitoredCluster, member *models.MonitoredClusterMember, m *clusterMember) (bool, error) {
+	service, err := models.FindServiceByID(q, member.ServiceID)
+	if err != nil {
+		return false, err
+	}
+
+	updated := member.ReplicationSet != m.ReplicationSet || member.Role != m.Role || member.State != m.State || member.MissingSince != nil
+	if updated {
+		member.ReplicationSet = m.ReplicationSet
+		member.Role = m.Role
+		member.State = m.State
+		member.MissingSince = nil
+		if err = q.Update(member); err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+
+	relabeled, err := relabelClusterMemberService(q, cluster, member, service)
+	if err != nil {
+		return false, err
+	}
+	return updated || relabeled, nil
+}
+
+// relabelClusterMemberService sets cluster, replication set and role labels of member's Service.
+// Environment and other custom labels are set only for Services created for the cluster.
+func relabelClusterMemberService(q *reform.Querier, cluster *models.MonitoredCluster, member *models.MonitoredClusterMember, service *models.Service) (bool, error) {
+	customLabels, err := service.GetCustomLabels()
+	if err != nil {
+		return false, err
+	}
+
+	environment := service.Environment
+	var expectedLabels map[string]string
+	if member.Adopted {
+		expectedLabels = make(map[string]string, len(customLabels)+1)
+		for k, v := range customLabels {
+			expectedLabels[k] = v
+		}
+		delete(expectedLabels, clusterRoleLabel)
+		if member.Role != "" {
+			expectedLabels[clusterRoleLabel] = member.Role
+		}
+	} else {
+		environment = cluster.Environment
+		expectedLabels = clusterMemberLabels(cluster.CustomLabels, member.Role)
+	}
+	if len(customLabels) == 0 && len(expectedLabels) == 0 {
+		customLabels = expectedLabels
+	}
+
+	if service.Environment == environment && service.Cluster == cluster.Name &&
+		service.ReplicationSet == member.ReplicationSet && reflect.DeepEqual(customLabels, expectedLabels) {
+		return false, nil
+	}
+
+	service.Environment = environment
+	service.Cluster = cluster.Name
+	service.ReplicationSet = member.ReplicationSet
+	if err = service.SetCustomLabels(expectedLabels); err != nil {
+		return false, err
+	}
+	if err = q.Update(service); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// clusterMemberLabels returns custom labels of Service created for cluster member.
+func clusterMemberLabels(clusterLabels map[string]string, role string) map[string]string {
+	if len(clusterLabels) == 0 && role == "" {
+		return nil
+	}
+
+	res := make(map[string]string, len(clusterLabels)+1)
+	for k, v := range clusterLabels {
+		res[k] = v
+	}
+	if role != "" {
+		res[clusterRoleLabel] = role
+	}
+	return res
+}
+
+// clusterMemberNode returns ID of existing Node with given address, or creates a new Remote Node for cluster member.
+// It returns true if Node was created for the cluster.
+func clusterMemberNode(q *reform.Querier, cluster *models.MonitoredCluster, host string) (string, bool, error) {
+	nodeName := cluster.Name + "/" + host
+
+	nodes, err := models.FindNodes(q, models.NodeFilters{})
+	if err != nil {
+		return "", false, err
+	}
+	for _, node := range nodes {
+		if node.Address == host {
+			return node.NodeID, node.NodeName == nodeName, nil
+		}
+	}
+
+	node, err := models.CreateNode(q, models.RemoteNodeType, &models.CreateNodeParams{
+		NodeName: nodeName,
+		Address:  host,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return node.NodeID, true, nil
+}
+
+// runAction starts action on the given pmm-agent and returns its output.
+func (s *ClustersService) runAction(ctx context.Context, pmmAgentID string, start func(id string) error) ([]byte, error) {
+	return agents.RunAction(ctx, s.db, pmmAgentID, clusterActionTimeout, start)
+}
+
+// clusterMetricsMode returns metrics mode of cluster members' exporters for the given API value
+// (AUTO if empty), taking pmm-agent support into account.
+func clusterMetricsMode(q *reform.Querier, metricsMode string, pmmAgentID string) (managementpb.MetricsMode, error) {
+	mode := managementpb.MetricsMode_AUTO
+	if metricsMode != "" {
+		m, ok := managementpb.MetricsMode_value[strings.ToUpper(metricsMode)]
+		if !ok {
+			return mode, status.Errorf(codes.InvalidArgument, "Unsupported metrics mode %q.", metricsMode)
+		}
+		mode = managementpb.MetricsMode(m)
+	}
+	return supportedMetricsMode(q, mode, pmmAgentID)
+}
+
+func convertMonitoredCluster(q *reform.Querier, cluster *models.MonitoredCluster) (*MonitoredCluster, error) {
+	members, err := models.FindMonitoredClusterMembers(q, cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &MonitoredCluster{
+		ClusterID:    cluster.ID,
+		Name:         cluster.Name,
+		Type:         string(cluster.Type),
+		PMMAgentID:   cluster.PMMAgentID,
+		SeedAddress:  cluster.SeedAddress,
+		SeedPort:     cluster.SeedPort,
+		Environment:  cluster.Environment,
+		CustomLabels: cluster.CustomLabels,
+		Members:      make([]*MonitoredClusterMember, len(members)),
+		LastSyncAt:   cluster.LastSyncAt,
+		LastError:    cluster.LastError,
+		CreatedAt:    cluster.CreatedAt,
+	}
+	for i, m := range members {
+		res.Members[i] = &MonitoredClusterMember{
+			Address:        m.Address,
+			ServiceID:      m.ServiceID,
+			ReplicationSet: m.ReplicationSet,
+			Role:           m.Role,
+			State:          m.State,
+			Adopted:        m.Adopted,
+			MissingSince:   m.MissingSince,
+		}
+	}
+	return res, nil
+}
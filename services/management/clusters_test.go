@@ -0,0 +1,266 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+	"github.com/percona/pmm-managed/utils/testdb"
+)
+
+func TestParseMySQLGroupMembers(t *testing.T) {
+	t.Parallel()
+
+	columns := []string{"MEMBER_ID", "MEMBER_HOST", "MEMBER_PORT", "MEMBER_STATE", "MEMBER_ROLE", "group_name", "self_uuid"}
+
+	t.Run("Online", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := agentpb.MarshalActionQuerySQLResult(columns, [][]interface{}{
+			{"uuid-1", "mysql-1", int64(3306), "ONLINE", "PRIMARY", "group", "uuid-2"},
+			{"uuid-2", "mysql-2", int64(3306), "ONLINE", "SECONDARY", "group", "uuid-2"},
+			{"uuid-3", "mysql-3", int64(3307), "RECOVERING", "SECONDARY", "group", "uuid-2"},
+		})
+		require.NoError(t, err)
+
+		actual, err := parseMySQLGroupMembers(output)
+		require.NoError(t, err)
+		expected := &clusterTopology{Members: []*clusterMember{
+			{Host: "mysql-1", Port: 3306, ReplicationSet: "group", Role: "primary", State: "ONLINE"},
+			{Host: "mysql-2", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+			{Host: "mysql-3", Port: 3307, ReplicationSet: "group", Role: "secondary", State: "RECOVERING"},
+		}}
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("NotOnline", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := agentpb.MarshalActionQuerySQLResult(columns, [][]interface{}{
+			{"uuid-1", "mysql-1", int64(3306), "ERROR", "SECONDARY", "group", "uuid-1"},
+		})
+		require.NoError(t, err)
+
+		_, err = parseMySQLGroupMembers(output)
+		assert.EqualError(t, err, "member is ERROR")
+	})
+
+	t.Run("NotRunning", func(t *testing.T) {
+		t.Parallel()
+
+		output, err := agentpb.MarshalActionQuerySQLResult(columns, [][]interface{}{
+			{"uuid-1", "", nil, "OFFLINE", "", "", "uuid-2"},
+		})
+		require.NoError(t, err)
+
+		_, err = parseMySQLGroupMembers(output)
+		assert.EqualError(t, err, "Group Replication is not running")
+	})
+}
+
+func TestClusters(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SetupFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+	ctx := logger.Set(context.Background(), t.Name())
+
+	state := &mockAgentsStateUpdater{}
+	state.Test(t)
+	state.On("RequestStateUpdate", mock.Anything, models.PMMServerAgentID).Return()
+	defer state.AssertExpectations(t)
+
+	vmdb := &mockPrometheusService{}
+	vmdb.Test(t)
+	vmdb.On("RequestConfigurationUpdate").Return()
+	defer vmdb.AssertExpectations(t)
+
+	var topology *clusterTopology
+	s := NewClustersService(db, &mockActionsService{}, state, vmdb, 15*time.Minute)
+	s.discover = func(ctx context.Context, cluster *models.MonitoredCluster, known []*models.MonitoredClusterMember) (*clusterTopology, error) {
+		return topology, nil
+	}
+
+	// registered before the cluster
+	adopted, err := models.AddNewService(db.Querier, models.MySQLServiceType, &models.AddDBMSServiceParams{
+		ServiceName:  "mysql-3",
+		NodeID:       models.PMMServerNodeID,
+		Address:      pointer.ToString("mysql-3"),
+		Port:         pointer.ToUint16(3306),
+		CustomLabels: map[string]string{"team": "payments"},
+	})
+	require.NoError(t, err)
+
+	topology = &clusterTopology{
+		Name: "innodb",
+		Members: []*clusterMember{
+			{Host: "mysql-1", Port: 3306, ReplicationSet: "group", Role: "primary", State: "ONLINE"},
+			{Host: "mysql-2", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+			{Host: "mysql-3", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+		},
+	}
+	addRes, err := s.AddMySQL(ctx, &AddMySQLClusterRequest{
+		Type:               string(models.MySQLInnoDBClusterType),
+		PMMAgentID:         models.PMMServerAgentID,
+		Address:            "mysql-1",
+		Port:               3306,
+		Username:           "pmm",
+		Password:           "pmm",
+		Environment:        "prod",
+		QANMySQLPerfschema: true,
+	})
+	require.NoError(t, err)
+	cluster := addRes.Cluster
+	assert.Equal(t, "innodb", cluster.Name)
+	require.Len(t, cluster.Members, 3)
+	assert.True(t, cluster.Members[2].Adopted)
+	assert.Equal(t, adopted.ServiceID, cluster.Members[2].ServiceID)
+
+	service, err := models.FindServiceByName(db.Querier, "innodb/mysql-1:3306")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", service.Environment)
+	assert.Equal(t, "innodb", service.Cluster)
+	assert.Equal(t, "group", service.ReplicationSet)
+	customLabels, err := service.GetCustomLabels()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"role": "primary"}, customLabels)
+	agents, err := models.FindAgents(db.Querier, models.AgentFilters{ServiceID: service.ServiceID})
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+	node, err := models.FindNodeByID(db.Querier, service.NodeID)
+	require.NoError(t, err)
+	assert.Equal(t, "innodb/mysql-1", node.NodeName)
+
+	adopted, err = models.FindServiceByID(db.Querier, adopted.ServiceID)
+	require.NoError(t, err)
+	assert.Equal(t, "innodb", adopted.Cluster)
+	assert.Equal(t, "", adopted.Environment)
+	customLabels, err = adopted.GetCustomLabels()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments", "role": "secondary"}, customLabels)
+
+	// failover to mysql-3, mysql-2 is restarting
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "mysql-1", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+		{Host: "mysql-3", Port: 3306, ReplicationSet: "group", Role: "primary", State: "ONLINE"},
+	}}
+	syncRes, err := s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Updated: 2, Missing: 1}, syncRes)
+
+	service, err = models.FindServiceByName(db.Querier, "innodb/mysql-1:3306")
+	require.NoError(t, err)
+	customLabels, err = service.GetCustomLabels()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"role": "secondary"}, customLabels)
+	_, err = models.FindServiceByName(db.Querier, "innodb/mysql-2:3306")
+	assert.NoError(t, err, "missing member should be kept")
+
+	listRes, err := s.List(ctx, &ListClustersRequest{})
+	require.NoError(t, err)
+	require.Len(t, listRes.Clusters, 1)
+	require.Len(t, listRes.Clusters[0].Members, 3)
+	assert.Equal(t, "mysql-2:3306", listRes.Clusters[0].Members[1].Address)
+	assert.NotNil(t, listRes.Clusters[0].Members[1].MissingSince)
+
+	_, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	listRes, err = s.List(ctx, &ListClustersRequest{})
+	require.NoError(t, err)
+	assert.NotNil(t, listRes.Clusters[0].Members[1].MissingSince, "missing member should be kept for the grace period")
+
+	// mysql-2 is back
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "mysql-1", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+		{Host: "mysql-2", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+		{Host: "mysql-3", Port: 3306, ReplicationSet: "group", Role: "primary", State: "ONLINE"},
+	}}
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Updated: 1}, syncRes)
+	listRes, err = s.List(ctx, &ListClustersRequest{})
+	require.NoError(t, err)
+	require.Len(t, listRes.Clusters[0].Members, 3)
+	assert.Nil(t, listRes.Clusters[0].Members[1].MissingSince)
+
+	// mysql-2 left and is removed after the grace period
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "mysql-1", Port: 3306, ReplicationSet: "group", Role: "secondary", State: "ONLINE"},
+		{Host: "mysql-3", Port: 3306, ReplicationSet: "group", Role: "primary", State: "ONLINE"},
+	}}
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Missing: 1}, syncRes)
+
+	_, err = db.Exec("UPDATE monitored_cluster_members SET missing_since = $1 WHERE address = $2", models.Now().Add(-time.Hour), "mysql-2:3306")
+	require.NoError(t, err)
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Removed: 1}, syncRes)
+	_, err = models.FindServiceByName(db.Querier, "innodb/mysql-2:3306")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = models.FindNodeByName(db.Querier, "innodb/mysql-2")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// nothing changed
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{}, syncRes)
+
+	listRes, err = s.List(ctx, &ListClustersRequest{})
+	require.NoError(t, err)
+	require.Len(t, listRes.Clusters, 1)
+	assert.Len(t, listRes.Clusters[0].Members, 2)
+	assert.NotNil(t, listRes.Clusters[0].LastSyncAt)
+
+	// mysql-1 left and is removed explicitly
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "mysql-3", Port: 3306, ReplicationSet: "group", Role: "primary", State: "ONLINE"},
+	}}
+	_, err = s.RemoveMember(ctx, &RemoveClusterMemberRequest{ClusterID: cluster.ClusterID, Address: "mysql-1:3306"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "member is not missing yet")
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Missing: 1}, syncRes)
+	_, err = s.RemoveMember(ctx, &RemoveClusterMemberRequest{ClusterID: cluster.ClusterID, Address: "mysql-1:3306"})
+	require.NoError(t, err)
+	_, err = models.FindServiceByName(db.Querier, "innodb/mysql-1:3306")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = models.FindNodeByName(db.Querier, "innodb/mysql-1")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// adopted Service is kept
+	_, err = s.Remove(ctx, &RemoveClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	_, err = models.FindServiceByID(db.Querier, adopted.ServiceID)
+	assert.NoError(t, err)
+}
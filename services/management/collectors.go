@@ -0,0 +1,259 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"sort"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/version"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/collectors"
+)
+
+// CollectorsService allows to enable and disable exporters' collectors using collectors catalog.
+type CollectorsService struct {
+	db    *reform.DB
+	state agentsStateUpdater
+	vmdb  prometheusService
+}
+
+// NewCollectorsService creates new CollectorsService.
+func NewCollectorsService(db *reform.DB, state agentsStateUpdater, vmdb prometheusService) *CollectorsService {
+	return &CollectorsService{
+		db:    db,
+		state: state,
+		vmdb:  vmdb,
+	}
+}
+
+// Collector represents a single exporter's collector in JSON API responses.
+type Collector struct {
+	Name string `json:"name"`
+	// Resolution of scrape job that requests collector's metrics: hr, mr, lr; empty if all metrics are requested.
+	Resolution string `json:"resolution,omitempty"`
+	// One of: low, medium, high.
+	Cost string `json:"cost"`
+	// True if collector is enabled by default for this Agent.
+	Default bool `json:"default"`
+	// Current state, taking into account explicitly enabled and disabled collectors.
+	Enabled bool `json:"enabled"`
+	// Minimal supported pmm-agent version.
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// ListCollectorsRequest is a request of List method.
+type ListCollectorsRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
+// ListCollectorsResponse is a response of List and Change methods.
+type ListCollectorsResponse struct {
+	AgentID    string       `json:"agent_id"`
+	AgentType  string       `json:"agent_type"`
+	Collectors []*Collector `json:"collectors"`
+	// Names of exporter's flags that are not collectors, but could be disabled too.
+	Options []string `json:"options,omitempty"`
+}
+
+// ChangeCollectorsRequest is a request of Change method.
+type ChangeCollectorsRequest struct {
+	AgentID string   `json:"agent_id"`
+	Enable  []string `json:"enable"`
+	Disable []string `json:"disable"`
+}
+
+// List returns collectors catalog of Agent's exporter with collectors' state.
+func (s *CollectorsService) List(ctx context.Context, req *ListCollectorsRequest) (*ListCollectorsResponse, error) {
+	var res *ListCollectorsResponse
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		agent, node, catalog, err := findAgentCollectors(tx.Querier, req.AgentID)
+		if err != nil {
+			return err
+		}
+
+		res = convertCollectors(node, agent, catalog)
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return res, nil
+}
+
+// Change enables and disables collectors of Agent's exporter.
+func (s *CollectorsService) Change(ctx context.Context, req *ChangeCollectorsRequest) (*ListCollectorsResponse, error) {
+	var res *ListCollectorsResponse
+	var pmmAgentID string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		agent, node, catalog, err := findAgentCollectors(tx.Querier, req.AgentID)
+		if err != nil {
+			return err
+		}
+		pmmAgentID = pointer.GetString(agent.PMMAgentID)
+
+		if err = changeCollectors(node, agent, catalog, req.Enable, req.Disable); err != nil {
+			return err
+		}
+		if err = tx.Update(agent); err != nil {
+			return errors.WithStack(err)
+		}
+
+		res = convertCollectors(node, agent, catalog)
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	if pmmAgentID != "" {
+		s.state.RequestStateUpdate(ctx, pmmAgentID)
+	}
+	s.vmdb.RequestConfigurationUpdate()
+	return res, nil
+}
+
+// findAgentCollectors returns Agent, Node of node_exporter (nil for other types),
+// and collectors catalog of Agent's exporter shipped with its pmm-agent.
+func findAgentCollectors(q *reform.Querier, agentID string) (*models.Agent, *models.Node, *collectors.Exporter, error) {
+	agent, err := models.FindAgentByID(q, agentID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var pmmAgentVersion *version.Parsed
+	if agent.PMMAgentID != nil {
+		pmmAgent, err := models.FindAgentByID(q, *agent.PMMAgentID)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if pmmAgent.Version != nil {
+			// the latest catalog is used if version is unknown
+			pmmAgentVersion, _ = version.Parse(*pmmAgent.Version)
+		}
+	}
+
+	catalog := collectors.Catalog(agent.AgentType, pmmAgentVersion)
+	if catalog == nil {
+		return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "Collectors of %s Agent are not configurable.", agent.AgentType)
+	}
+
+	var node *models.Node
+	if agent.AgentType == models.NodeExporterType {
+		if node, err = models.FindNodeByID(q, pointer.GetString(agent.NodeID)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return agent, node, catalog, nil
+}
+
+// changeCollectors validates collectors names and changes lists of Agent's enabled and disabled collectors.
+// Collectors enabled by default are not stored in the list of enabled collectors.
+func changeCollectors(node *models.Node, agent *models.Agent, catalog *collectors.Exporter, enable, disable []string) error {
+	if err := catalog.Validate(disable); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Can't disable collectors: %s.", err)
+	}
+	for _, name := range enable {
+		if catalog.Find(name) == nil {
+			return status.Errorf(codes.InvalidArgument, "Can't enable collectors: unknown %s collector %q.", agent.AgentType, name)
+		}
+	}
+
+	normalize := func(name string) string {
+		if c := catalog.Find(name); c != nil {
+			return c.Name
+		}
+		return name // option
+	}
+
+	changed := make(map[string]bool, len(enable)+len(disable))
+	for _, name := range disable {
+		changed[normalize(name)] = false
+	}
+	for _, name := range enable {
+		name = normalize(name)
+		if enabled, ok := changed[name]; ok && !enabled {
+			return status.Errorf(codes.InvalidArgument, "Collector %q can't be both enabled and disabled.", name)
+		}
+		changed[name] = true
+	}
+
+	unchanged := func(names []string) []string {
+		var res []string
+		for _, name := range names {
+			if _, ok := changed[normalize(name)]; !ok {
+				res = append(res, name)
+			}
+		}
+		return res
+	}
+	enabled, disabled := unchanged(agent.EnabledCollectors), unchanged(agent.DisabledCollectors)
+
+	groups := collectors.Groups(node, agent)
+	for _, name := range sortedKeys(changed) {
+		switch {
+		case !changed[name]:
+			disabled = append(disabled, name)
+		case !catalog.Find(name).IsDefault(groups):
+			enabled = append(enabled, name)
+		}
+	}
+
+	agent.EnabledCollectors, agent.DisabledCollectors = enabled, disabled
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func convertCollectors(node *models.Node, agent *models.Agent, catalog *collectors.Exporter) *ListCollectorsResponse {
+	groups := collectors.Groups(node, agent)
+	enabled := catalog.Enabled(node, agent)
+
+	res := &ListCollectorsResponse{
+		AgentID:    agent.AgentID,
+		AgentType:  string(agent.AgentType),
+		Collectors: make([]*Collector, len(catalog.Collectors)),
+		Options:    catalog.Options,
+	}
+	for i, c := range catalog.Collectors {
+		collector := &Collector{
+			Name:       c.Name,
+			Resolution: string(c.Resolution),
+			Cost:       string(c.Cost),
+			Default:    c.IsDefault(groups),
+			Enabled:    enabled[c.Name],
+		}
+		if c.MinVersion != nil {
+			collector.MinVersion = c.MinVersion.String()
+		}
+		res.Collectors[i] = collector
+	}
+	return res
+}
@@ -0,0 +1,95 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/collectors"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestChangeCollectors(t *testing.T) {
+	catalog := collectors.Catalog(models.MySQLdExporterType, version.MustParse("2.30.0"))
+	newAgent := func() *models.Agent {
+		return &models.Agent{
+			AgentID:                        "/agent_id/mysqld",
+			AgentType:                      models.MySQLdExporterType,
+			TableCount:                     pointer.ToInt32(5000),
+			TableCountTablestatsGroupLimit: 1000,
+			DisabledCollectors:             []string{"heartbeat", "custom_query.hr.directory"},
+		}
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		agent := newAgent()
+		err := changeCollectors(nil, agent, catalog, []string{"Heartbeat", "info_schema.tables"}, []string{"binlog_size"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"info_schema.tables"}, []string(agent.EnabledCollectors))
+		assert.Equal(t, []string{"custom_query.hr.directory", "binlog_size"}, []string(agent.DisabledCollectors))
+
+		enabled := catalog.Enabled(nil, agent)
+		assert.True(t, enabled["heartbeat"])
+		assert.True(t, enabled["info_schema.tables"])
+		assert.False(t, enabled["info_schema.tablestats"])
+		assert.False(t, enabled["binlog_size"])
+
+		res := convertCollectors(nil, agent, catalog)
+		require.Len(t, res.Collectors, len(catalog.Collectors))
+		for _, c := range res.Collectors {
+			if c.Name == "info_schema.tables" {
+				assert.Equal(t, &Collector{Name: "info_schema.tables", Resolution: "lr", Cost: "high", Enabled: true}, c)
+			}
+		}
+
+		err = changeCollectors(nil, agent, catalog, nil, []string{"info_schema.tables"})
+		require.NoError(t, err)
+		assert.Empty(t, agent.EnabledCollectors)
+		assert.Equal(t, []string{"custom_query.hr.directory", "binlog_size", "info_schema.tables"}, []string(agent.DisabledCollectors))
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		agent := newAgent()
+		err := changeCollectors(nil, agent, catalog, nil, []string{"binlog_size", "no_such_collector"})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Can't disable collectors: unknown mysqld_exporter collectors: no_such_collector."), err)
+
+		err = changeCollectors(nil, agent, catalog, []string{"custom_query.hr.directory"}, nil)
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, `Can't enable collectors: unknown mysqld_exporter collector "custom_query.hr.directory".`), err)
+		assert.Equal(t, newAgent(), agent)
+	})
+
+	t.Run("Both", func(t *testing.T) {
+		agent := newAgent()
+		err := changeCollectors(nil, agent, catalog, []string{"binlog_size"}, []string{"binlog_size"})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, `Collector "binlog_size" can't be both enabled and disabled.`), err)
+	})
+
+	t.Run("NotConfigurableVersion", func(t *testing.T) {
+		assert.Nil(t, collectors.Catalog(models.MongoDBExporterType, version.MustParse("2.20.0")))
+		c := collectors.Catalog(models.ProxySQLExporterType, version.MustParse("2.19.0"))
+		assert.NotNil(t, c.Find("stats_command_counter"))
+		assert.Nil(t, c.Find("runtime_mysql_servers"))
+	})
+}
@@ -36,6 +36,7 @@ import (
 //go:generate mockery -name=connectionChecker -case=snake -inpkg -testonly
 //go:generate mockery -name=defaultsFileParser -case=snake -inpkg -testonly
 //go:generate mockery -name=versionCache -case=snake -inpkg -testonly
+//go:generate mockery -name=actionsService -case=snake -inpkg -testonly
 
 // agentsRegistry is a subset of methods of agents.Registry used by this package.
 // We use it instead of real type for testing and to avoid dependency cycle.
@@ -58,6 +59,12 @@ type prometheusService interface {
 	RequestConfigurationUpdate()
 }
 
+// metricRelabelRuleValidator is a subset of methods of victoriametrics.Service used by this package.
+// We use it instead of real type for testing and to avoid dependency cycle.
+type metricRelabelRuleValidator interface {
+	ValidateMetricRelabelRule(ctx context.Context, rule *models.MetricRelabelRule) error
+}
+
 // checksService is a subset of methods of checks.Service used by this package.
 // We use it instead of real type for testing and to avoid dependency cycle.
 type checksService interface {
@@ -101,3 +108,13 @@ type versionCache interface {
 type defaultsFileParser interface {
 	ParseDefaultsFile(ctx context.Context, pmmAgentID, filePath string, serviceType models.ServiceType) (*models.ParseDefaultsFileResult, error)
 }
+
+// actionsService is a subset of methods of agents.ActionsService used by this package.
+// We use it instead of real type for testing and to avoid dependency cycle.
+type actionsService interface {
+	StartMySQLQuerySelectAction(ctx context.Context, id, pmmAgentID, dsn, query string, files map[string]string, tdp *models.DelimiterPair, tlsSkipVerify bool) error
+	StartMongoDBQueryGetCmdLineOptsAction(ctx context.Context, id, pmmAgentID, dsn string, files map[string]string, tdp *models.DelimiterPair) error
+	StartMongoDBQueryGetDiagnosticDataAction(ctx context.Context, id, pmmAgentID, dsn string, files map[string]string, tdp *models.DelimiterPair) error
+	StartMongoDBQueryReplSetGetStatusAction(ctx context.Context, id, pmmAgentID, dsn string, files map[string]string, tdp *models.DelimiterPair) error
+	StartPostgreSQLQuerySelectAction(ctx context.Context, id, pmmAgentID, dsn, query string) error
+}
@@ -0,0 +1,264 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/percona-platform/saas/pkg/alert"
+	"github.com/percona/promconfig"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/data"
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	// deviationParam is a name of anomaly template parameter that is used in generated alert expression.
+	deviationParam = "deviation"
+
+	defaultBaselineOffset = promconfig.Duration(7 * 24 * time.Hour)
+	defaultBaselineWindow = promconfig.Duration(time.Hour)
+	baselineResolution    = "1m"
+
+	anomalyRecordingGroup = "PMM Integrated Alerting anomaly baselines"
+	anomalyRecordingFile  = "pmm_anomaly_baselines"
+)
+
+// anomalyTemplate is a template kind that compares a metric with its own historical baseline
+// (for example, with the same hour last week) instead of a static threshold.
+// Alert expression is generated; it uses series of recording rules that are generated too.
+type anomalyTemplate struct {
+	alert.Template `yaml:",inline"`
+
+	// Metric is a PromQL expression of observed metric; its labels identify alerts.
+	Metric string `yaml:"metric"`
+	// Baseline describes a historical time range that current value is compared with.
+	Baseline struct {
+		Offset promconfig.Duration `yaml:"offset"`
+		Window promconfig.Duration `yaml:"window"`
+	} `yaml:"baseline"`
+}
+
+// recordingRule represents vmalert recording rule.
+type recordingRule struct {
+	Record string `yaml:"record"`
+	Expr   string `yaml:"expr"`
+}
+
+// recordingRuleFile represents vmalert file with recording rules.
+type recordingRuleFile struct {
+	Group []recordingRuleGroup `yaml:"groups"`
+}
+
+type recordingRuleGroup struct {
+	Name  string          `yaml:"name"`
+	Rules []recordingRule `yaml:"rules"`
+}
+
+// parseAnomalyTemplates parses anomaly templates, sets defaults, generates alert expressions and validates templates.
+func parseAnomalyTemplates(reader io.Reader) ([]anomalyTemplate, error) {
+	d := yaml.NewDecoder(reader)
+	d.KnownFields(true)
+
+	var res []anomalyTemplate
+	for {
+		var c struct {
+			Templates []anomalyTemplate `yaml:"templates"`
+		}
+		if err := d.Decode(&c); err != nil {
+			if errors.Is(err, io.EOF) {
+				return res, nil
+			}
+			return nil, errors.Wrap(err, "failed to parse anomaly templates")
+		}
+
+		for _, t := range c.Templates {
+			if err := t.prepare(); err != nil {
+				return nil, errors.Wrapf(err, "invalid anomaly template %q", t.Name)
+			}
+			res = append(res, t)
+		}
+	}
+}
+
+// prepare sets defaults, generates alert expression and validates template.
+func (t *anomalyTemplate) prepare() error {
+	if strings.TrimSpace(t.Metric) == "" {
+		return errors.New("metric is empty")
+	}
+	if t.Expr != "" {
+		return errors.New("expression is generated and should not be set")
+	}
+
+	if t.Baseline.Offset == 0 {
+		t.Baseline.Offset = defaultBaselineOffset
+	}
+	if t.Baseline.Window == 0 {
+		t.Baseline.Window = defaultBaselineWindow
+	}
+	if t.Baseline.Offset < t.Baseline.Window {
+		return errors.Errorf("baseline offset %s should not be less than window %s", t.Baseline.Offset, t.Baseline.Window)
+	}
+
+	var found bool
+	for _, p := range t.Params {
+		if p.Name == deviationParam {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Params = append(t.Params, alert.Parameter{
+			Name:    deviationParam,
+			Summary: "Allowed deviation from the baseline in standard deviations",
+			Type:    alert.Float,
+			Range:   []interface{}{1, 10},
+			Value:   3,
+		})
+	}
+
+	value, avg, stddev := t.seriesNames()
+	t.Expr = fmt.Sprintf("%[1]s\nand\nabs(%[1]s - %[2]s) > [[ .%[4]s ]] * %[3]s", value, avg, stddev, deviationParam)
+
+	return t.Validate()
+}
+
+// seriesNames returns names of series recorded for the template.
+func (t *anomalyTemplate) seriesNames() (value, avg, stddev string) {
+	prefix := "pmm_anomaly:" + strings.TrimPrefix(t.Name, "pmm_") + ":"
+	return prefix + "value", prefix + "baseline_avg", prefix + "baseline_stddev"
+}
+
+// recordingRules returns recording rules for the current metric value and its baseline.
+// Baseline is calculated by the subquery over the metric expression, so it is available
+// right away if metrics retention is long enough.
+func (t *anomalyTemplate) recordingRules() []recordingRule {
+	value, avg, stddev := t.seriesNames()
+	metric := strings.TrimSpace(t.Metric)
+	baseline := fmt.Sprintf("(%s)[%s:%s] offset %s", metric, t.Baseline.Window, baselineResolution, t.Baseline.Offset)
+
+	return []recordingRule{
+		{Record: value, Expr: metric},
+		{Record: avg, Expr: "avg_over_time(" + baseline + ")"},
+		{Record: stddev, Expr: "stddev_over_time(" + baseline + ")"},
+	}
+}
+
+// loadAnomalyTemplatesFromAssets loads built-in anomaly templates from pmm-managed binary's assets.
+func (s *TemplatesService) loadAnomalyTemplatesFromAssets(ctx context.Context) ([]anomalyTemplate, error) {
+	var res []anomalyTemplate
+	walkDirFunc := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "error occurred while traversing anomaly templates folder: %s", path)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		b, err := fs.ReadFile(data.IAAnomalyTemplates, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read anomaly template asset: %s", path)
+		}
+
+		templates, err := parseAnomalyTemplates(bytes.NewReader(b))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse anomaly template asset: %s", path)
+		}
+
+		if l := len(templates); l != 1 {
+			return errors.Errorf("%q should contain exactly one template, got %d", path, l)
+		}
+		if err = validateBuiltInTemplate(path, &templates[0].Template); err != nil {
+			return err
+		}
+
+		res = append(res, templates[0])
+		return nil
+	}
+	if err := fs.WalkDir(data.IAAnomalyTemplates, ".", walkDirFunc); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// prepareRecordingRulesFile returns recording rules required by anomaly templates of enabled rules.
+// It returns nil if there are no such rules.
+func (s *RulesService) prepareRecordingRulesFile(rules []*models.Rule) *recordingRuleFile {
+	templates := s.templates.getTemplates()
+
+	var recordingRules []recordingRule
+	seen := make(map[string]struct{})
+	for _, r := range rules {
+		if r.Disabled {
+			continue
+		}
+		if _, ok := seen[r.TemplateName]; ok {
+			continue
+		}
+		seen[r.TemplateName] = struct{}{}
+
+		t, ok := templates[r.TemplateName]
+		if !ok {
+			if strings.Contains(r.ExprTemplate, "pmm_anomaly:") {
+				s.l.Warnf("Template %s of rule %s is not found, anomaly baselines are not recorded.", r.TemplateName, r.ID)
+			}
+			continue
+		}
+		recordingRules = append(recordingRules, t.RecordingRules...)
+	}
+
+	if len(recordingRules) == 0 {
+		return nil
+	}
+
+	return &recordingRuleFile{
+		Group: []recordingRuleGroup{{
+			Name:  anomalyRecordingGroup,
+			Rules: recordingRules,
+		}},
+	}
+}
+
+// writeRecordingRuleFile dumps recording rules to a file.
+func (s *RulesService) writeRecordingRuleFile(file *recordingRuleFile) error {
+	b, err := yaml.Marshal(file)
+	if err != nil {
+		return errors.Errorf("failed to marshal recording rules %v", err)
+	}
+	b = append([]byte("---\n"), b...)
+
+	path := s.rulesPath + "/" + anomalyRecordingFile + ".yml"
+	if err = ioutil.WriteFile(path, b, 0o644); err != nil {
+		return errors.Errorf("failed to dump recording rules to file %s: %v", s.rulesPath, err)
+	}
+
+	return nil
+}
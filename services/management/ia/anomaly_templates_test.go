@@ -0,0 +1,143 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestAnomalyTemplates(t *testing.T) {
+	t.Parallel()
+
+	t.Run("builtin are valid", func(t *testing.T) {
+		t.Parallel()
+
+		svc := &TemplatesService{l: logrus.WithField("test", t.Name())}
+		templates, err := svc.loadAnomalyTemplatesFromAssets(context.Background())
+		require.NoError(t, err)
+		require.NotEmpty(t, templates)
+
+		for _, tmpl := range templates {
+			expr, err := fillExprWithParams(tmpl.Expr, map[string]string{deviationParam: "3"})
+			require.NoError(t, err)
+			assert.Contains(t, expr, "> 3 * pmm_anomaly:")
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+
+		const yml = `---
+templates:
+  - name: test_qps_anomaly
+    version: 1
+    summary: QPS anomaly
+    metric: sum by (service_name) (rate(mysql_global_status_queries[5m]))
+    for: 5m
+    severity: warning
+`
+		templates, err := parseAnomalyTemplates(strings.NewReader(yml))
+		require.NoError(t, err)
+		require.Len(t, templates, 1)
+		tmpl := templates[0]
+
+		expected := "pmm_anomaly:test_qps_anomaly:value\n" +
+			"and\n" +
+			"abs(pmm_anomaly:test_qps_anomaly:value - pmm_anomaly:test_qps_anomaly:baseline_avg) > [[ .deviation ]] * pmm_anomaly:test_qps_anomaly:baseline_stddev"
+		assert.Equal(t, expected, tmpl.Expr)
+		require.Len(t, tmpl.Params, 1)
+		assert.Equal(t, deviationParam, tmpl.Params[0].Name)
+		assert.Equal(t, 3, tmpl.Params[0].Value)
+
+		expectedRules := []recordingRule{{
+			Record: "pmm_anomaly:test_qps_anomaly:value",
+			Expr:   "sum by (service_name) (rate(mysql_global_status_queries[5m]))",
+		}, {
+			Record: "pmm_anomaly:test_qps_anomaly:baseline_avg",
+			Expr:   "avg_over_time((sum by (service_name) (rate(mysql_global_status_queries[5m])))[1h:1m] offset 1w)",
+		}, {
+			Record: "pmm_anomaly:test_qps_anomaly:baseline_stddev",
+			Expr:   "stddev_over_time((sum by (service_name) (rate(mysql_global_status_queries[5m])))[1h:1m] offset 1w)",
+		}}
+		assert.Equal(t, expectedRules, tmpl.recordingRules())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			name string
+			yml  string
+			err  string
+		}{{
+			name: "expression is set",
+			yml:  "templates:\n  - {name: t, version: 1, summary: s, metric: up, expr: up, for: 5m, severity: warning}",
+			err:  `invalid anomaly template "t": expression is generated and should not be set`,
+		}, {
+			name: "no metric",
+			yml:  "templates:\n  - {name: t, version: 1, summary: s, for: 5m, severity: warning}",
+			err:  `invalid anomaly template "t": metric is empty`,
+		}, {
+			name: "window is too large",
+			yml:  "templates:\n  - {name: t, version: 1, summary: s, metric: up, baseline: {offset: 1h, window: 1d}, for: 5m, severity: warning}",
+			err:  `invalid anomaly template "t": baseline offset 1h should not be less than window 1d`,
+		}} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				_, err := parseAnomalyTemplates(strings.NewReader(tc.yml))
+				assert.EqualError(t, err, tc.err)
+			})
+		}
+	})
+
+	t.Run("recording rules", func(t *testing.T) {
+		t.Parallel()
+
+		templates := &TemplatesService{
+			templates: map[string]templateInfo{
+				"anomaly":   {RecordingRules: []recordingRule{{Record: "pmm_anomaly:a:value", Expr: "up"}}},
+				"threshold": {},
+			},
+		}
+		s := &RulesService{l: logrus.WithField("test", t.Name()), templates: templates}
+
+		file := s.prepareRecordingRulesFile([]*models.Rule{
+			{ID: "1", TemplateName: "threshold"},
+			{ID: "2", TemplateName: "anomaly", Disabled: true},
+		})
+		assert.Nil(t, file)
+
+		file = s.prepareRecordingRulesFile([]*models.Rule{
+			{ID: "1", TemplateName: "anomaly"},
+			{ID: "2", TemplateName: "anomaly"},
+			{ID: "3", TemplateName: "threshold"},
+		})
+		require.NotNil(t, file)
+		require.Len(t, file.Group, 1)
+		assert.Equal(t, []recordingRule{{Record: "pmm_anomaly:a:value", Expr: "up"}}, file.Group[0].Rules)
+	})
+}
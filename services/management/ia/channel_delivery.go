@@ -0,0 +1,90 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"context"
+	"time"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// TestChannelRequest is a request of TestChannel method.
+type TestChannelRequest struct {
+	ChannelID string `json:"channel_id"`
+}
+
+// TestChannelResponse is a response of TestChannel method.
+type TestChannelResponse struct{}
+
+// ListDeliveryStatusesRequest is a request of ListDeliveryStatuses method.
+type ListDeliveryStatusesRequest struct{}
+
+// ChannelDeliveryStatus represents notification delivery statistics of the channel, including test notifications.
+type ChannelDeliveryStatus struct {
+	Sent          int64      `json:"sent"`
+	Failed        int64      `json:"failed"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// ListDeliveryStatusesResponse is a response of ListDeliveryStatuses method.
+type ListDeliveryStatusesResponse struct {
+	// Delivery statuses by channel ID; channels without any notifications are absent.
+	DeliveryStatuses map[string]*ChannelDeliveryStatus `json:"delivery_statuses"`
+}
+
+// TestChannel sends test notification to the channel.
+func (s *ChannelsService) TestChannel(ctx context.Context, req *TestChannelRequest) (*TestChannelResponse, error) {
+	channel, err := models.FindChannelByID(s.db.Querier, req.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.alertManager.SendTestNotification(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	return &TestChannelResponse{}, nil
+}
+
+// ListDeliveryStatuses returns notification delivery statistics of channels.
+func (s *ChannelsService) ListDeliveryStatuses(ctx context.Context, req *ListDeliveryStatusesRequest) (*ListDeliveryStatusesResponse, error) {
+	statuses, err := models.FindChannelDeliveryStatuses(s.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListDeliveryStatusesResponse{
+		DeliveryStatuses: convertDeliveryStatuses(statuses),
+	}, nil
+}
+
+func convertDeliveryStatuses(statuses map[string]*models.ChannelDeliveryStatus) map[string]*ChannelDeliveryStatus {
+	res := make(map[string]*ChannelDeliveryStatus, len(statuses))
+	for id, st := range statuses {
+		res[id] = &ChannelDeliveryStatus{
+			Sent:          st.Sent,
+			Failed:        st.Failed,
+			LastSuccessAt: st.LastSuccessAt,
+			LastFailureAt: st.LastFailureAt,
+			LastError:     st.LastError,
+		}
+	}
+	return res
+}
@@ -0,0 +1,57 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestListDeliveryStatusesJSON(t *testing.T) {
+	at := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	res := &ListDeliveryStatusesResponse{
+		DeliveryStatuses: convertDeliveryStatuses(map[string]*models.ChannelDeliveryStatus{
+			"/channel_id/1": {ChannelID: "/channel_id/1", Sent: 3, Failed: 1, LastSuccessAt: &at, LastFailureAt: &at, LastError: "boom"},
+			"/channel_id/2": {ChannelID: "/channel_id/2", Sent: 1, LastSuccessAt: &at},
+		}),
+	}
+	b, err := json.Marshal(res)
+	require.NoError(t, err)
+	expected := `{
+		"delivery_statuses": {
+			"/channel_id/1": {
+				"sent": 3,
+				"failed": 1,
+				"last_success_at": "2022-01-02T03:04:05Z",
+				"last_failure_at": "2022-01-02T03:04:05Z",
+				"last_error": "boom"
+			},
+			"/channel_id/2": {
+				"sent": 1,
+				"failed": 0,
+				"last_success_at": "2022-01-02T03:04:05Z"
+			}
+		}
+	}`
+	assert.JSONEq(t, expected, string(b))
+}
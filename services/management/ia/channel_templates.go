@@ -0,0 +1,215 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"context"
+
+	"github.com/percona-platform/saas/pkg/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services"
+)
+
+// MessageTemplate represents user-defined notification message template.
+type MessageTemplate struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// MessageTemplates represents user-defined notification message templates of the channel.
+type MessageTemplates struct {
+	Default    *MessageTemplate            `json:"default,omitempty"`
+	Severities map[string]*MessageTemplate `json:"severities,omitempty"`
+}
+
+// GetMessageTemplatesRequest is a request of GetMessageTemplates method.
+type GetMessageTemplatesRequest struct {
+	ChannelID string `json:"channel_id"`
+}
+
+// GetMessageTemplatesResponse is a response of GetMessageTemplates method.
+type GetMessageTemplatesResponse struct {
+	MessageTemplates *MessageTemplates `json:"message_templates,omitempty"`
+}
+
+// ChangeMessageTemplatesRequest is a request of ChangeMessageTemplates method.
+// Empty message templates reset channel to built-in templates.
+type ChangeMessageTemplatesRequest struct {
+	ChannelID        string            `json:"channel_id"`
+	MessageTemplates *MessageTemplates `json:"message_templates"`
+}
+
+// ChangeMessageTemplatesResponse is a response of ChangeMessageTemplates method.
+type ChangeMessageTemplatesResponse struct{}
+
+// PreviewMessageRequest is a request of PreviewMessage method.
+// Either channel ID or channel type should be set. If message templates are not set,
+// templates of the channel (or built-in ones) are used.
+type PreviewMessageRequest struct {
+	ChannelID        string            `json:"channel_id"`
+	ChannelType      string            `json:"channel_type"`
+	MessageTemplates *MessageTemplates `json:"message_templates"`
+	Severity         string            `json:"severity"`
+}
+
+// PreviewMessageResponse is a response of PreviewMessage method.
+type PreviewMessageResponse struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// GetMessageTemplates returns user-defined notification message templates of the channel.
+func (s *ChannelsService) GetMessageTemplates(ctx context.Context, req *GetMessageTemplatesRequest) (*GetMessageTemplatesResponse, error) {
+	channel, err := models.FindChannelByID(s.db.Querier, req.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetMessageTemplatesResponse{MessageTemplates: convertModelToMessageTemplates(channel.MessageTemplates)}, nil
+}
+
+// ChangeMessageTemplates validates and sets user-defined notification message templates of the channel.
+func (s *ChannelsService) ChangeMessageTemplates(ctx context.Context, req *ChangeMessageTemplatesRequest) (*ChangeMessageTemplatesResponse, error) {
+	templates := convertMessageTemplatesToModel(req.MessageTemplates)
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		channel, err := models.FindChannelByID(tx.Querier, req.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		if err = s.alertManager.ValidateMessageTemplates(channel.Type, templates); err != nil {
+			return err
+		}
+		normalizeMessageTemplates(templates)
+
+		_, err = models.ChangeChannelMessageTemplates(tx.Querier, req.ChannelID, templates)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.alertManager.RequestConfigurationUpdate()
+
+	return &ChangeMessageTemplatesResponse{}, nil
+}
+
+// PreviewMessage renders notification message for a sample alert.
+func (s *ChannelsService) PreviewMessage(ctx context.Context, req *PreviewMessageRequest) (*PreviewMessageResponse, error) {
+	severity := common.Warning
+	if req.Severity != "" {
+		if severity = common.ParseSeverity(req.Severity); severity == common.Unknown {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown severity %q.", req.Severity)
+		}
+	}
+
+	channelType := models.ChannelType(req.ChannelType)
+	templates := convertMessageTemplatesToModel(req.MessageTemplates)
+	if req.ChannelID != "" {
+		channel, err := models.FindChannelByID(s.db.Querier, req.ChannelID)
+		if err != nil {
+			return nil, err
+		}
+		channelType = channel.Type
+		if templates == nil {
+			templates = channel.MessageTemplates
+		}
+	}
+	if channelType == "" {
+		return nil, status.Error(codes.InvalidArgument, "Channel ID or channel type is expected.")
+	}
+
+	preview, err := s.alertManager.PreviewMessage(channelType, templates, severity)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertMessagePreview(preview), nil
+}
+
+func convertMessagePreview(preview *services.MessagePreview) *PreviewMessageResponse {
+	return &PreviewMessageResponse{
+		Title: preview.Title,
+		Body:  preview.Body,
+	}
+}
+
+// normalizeMessageTemplates replaces validated severity keys with severities as they are in alert labels
+// (for example, "Critical" with "critical").
+func normalizeMessageTemplates(templates *models.MessageTemplates) {
+	if templates == nil || templates.Severities == nil {
+		return
+	}
+
+	severities := make(map[string]*models.MessageTemplate, len(templates.Severities))
+	for s, t := range templates.Severities {
+		severities[common.ParseSeverity(s).String()] = t
+	}
+	templates.Severities = severities
+}
+
+func convertMessageTemplatesToModel(templates *MessageTemplates) *models.MessageTemplates {
+	if templates == nil {
+		return nil
+	}
+
+	convert := func(t *MessageTemplate) *models.MessageTemplate {
+		if t == nil || (t.Title == "" && t.Body == "") {
+			return nil
+		}
+		return &models.MessageTemplate{Title: t.Title, Body: t.Body}
+	}
+
+	res := &models.MessageTemplates{
+		Default: convert(templates.Default),
+	}
+	for s, t := range templates.Severities {
+		if mt := convert(t); mt != nil {
+			if res.Severities == nil {
+				res.Severities = make(map[string]*models.MessageTemplate, len(templates.Severities))
+			}
+			res.Severities[s] = mt
+		}
+	}
+
+	if res.Default == nil && res.Severities == nil {
+		return nil
+	}
+	return res
+}
+
+func convertModelToMessageTemplates(templates *models.MessageTemplates) *MessageTemplates {
+	if templates == nil {
+		return nil
+	}
+
+	res := &MessageTemplates{}
+	if t := templates.Default; t != nil {
+		res.Default = &MessageTemplate{Title: t.Title, Body: t.Body}
+	}
+	if len(templates.Severities) != 0 {
+		res.Severities = make(map[string]*MessageTemplate, len(templates.Severities))
+		for s, t := range templates.Severities {
+			res.Severities[s] = &MessageTemplate{Title: t.Title, Body: t.Body}
+		}
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"context"
+	"testing"
+
+	"github.com/percona-platform/saas/pkg/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestConvertMessageTemplates(t *testing.T) {
+	t.Parallel()
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Nil(t, convertMessageTemplatesToModel(nil))
+		assert.Nil(t, convertMessageTemplatesToModel(&MessageTemplates{
+			Default:    &MessageTemplate{},
+			Severities: map[string]*MessageTemplate{"critical": {}, "warning": nil},
+		}))
+	})
+
+	t.Run("normal", func(t *testing.T) {
+		t.Parallel()
+
+		templates := &MessageTemplates{
+			Default:    &MessageTemplate{Title: "Alert"},
+			Severities: map[string]*MessageTemplate{"critical": {Body: "{{ .Annotations.summary }}"}},
+		}
+		actual := convertMessageTemplatesToModel(templates)
+		expected := &models.MessageTemplates{
+			Default:    &models.MessageTemplate{Title: "Alert"},
+			Severities: map[string]*models.MessageTemplate{"critical": {Body: "{{ .Annotations.summary }}"}},
+		}
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, templates, convertModelToMessageTemplates(actual))
+	})
+
+	t.Run("normalize", func(t *testing.T) {
+		t.Parallel()
+
+		templates := convertMessageTemplatesToModel(&MessageTemplates{
+			Severities: map[string]*MessageTemplate{"Critical ": {Title: "Critical"}},
+		})
+		normalizeMessageTemplates(templates)
+		expected := &models.MessageTemplates{
+			Severities: map[string]*models.MessageTemplate{"critical": {Title: "Critical"}},
+		}
+		assert.Equal(t, expected, templates)
+	})
+}
+
+func TestPreviewMessage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("normal", func(t *testing.T) {
+		t.Parallel()
+
+		templates := &MessageTemplates{Default: &MessageTemplate{Title: "Alert"}}
+		alertManager := &mockAlertManager{}
+		alertManager.On("PreviewMessage", models.Slack, convertMessageTemplatesToModel(templates), common.Critical).
+			Return(&services.MessagePreview{Title: "Alert", Body: "text"}, nil)
+		defer alertManager.AssertExpectations(t)
+
+		s := NewChannelsService(nil, alertManager)
+		actual, err := s.PreviewMessage(context.Background(), &PreviewMessageRequest{
+			ChannelType:      "slack",
+			MessageTemplates: templates,
+			Severity:         "critical",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &PreviewMessageResponse{Title: "Alert", Body: "text"}, actual)
+	})
+
+	t.Run("unknown severity", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewChannelsService(nil, &mockAlertManager{})
+		_, err := s.PreviewMessage(context.Background(), &PreviewMessageRequest{ChannelType: "slack", Severity: "urgent"})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, `Unknown severity "urgent".`), err)
+	})
+
+	t.Run("no channel", func(t *testing.T) {
+		t.Parallel()
+
+		s := NewChannelsService(nil, &mockAlertManager{})
+		_, err := s.PreviewMessage(context.Background(), &PreviewMessageRequest{})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Channel ID or channel type is expected."), err)
+	})
+}
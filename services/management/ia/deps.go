@@ -19,8 +19,10 @@ package ia
 import (
 	"context"
 
+	"github.com/percona-platform/saas/pkg/common"
 	"github.com/percona/pmm/api/alertmanager/ammodels"
 
+	"github.com/percona/pmm-managed/models"
 	"github.com/percona/pmm-managed/services"
 )
 
@@ -35,6 +37,9 @@ type alertManager interface {
 	SilenceAlerts(ctx context.Context, alerts []*ammodels.GettableAlert) error
 	UnsilenceAlerts(ctx context.Context, alerts []*ammodels.GettableAlert) error
 	RequestConfigurationUpdate()
+	ValidateMessageTemplates(channelType models.ChannelType, templates *models.MessageTemplates) error
+	PreviewMessage(channelType models.ChannelType, templates *models.MessageTemplates, severity common.Severity) (*services.MessagePreview, error)
+	SendTestNotification(ctx context.Context, channel *models.Channel) error
 }
 
 // vmAlert is is a subset of methods of vmalert.Service used by this package.
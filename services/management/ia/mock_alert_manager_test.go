@@ -5,9 +5,11 @@ package ia
 import (
 	context "context"
 
+	common "github.com/percona-platform/saas/pkg/common"
 	ammodels "github.com/percona/pmm/api/alertmanager/ammodels"
 	mock "github.com/stretchr/testify/mock"
 
+	models "github.com/percona/pmm-managed/models"
 	services "github.com/percona/pmm-managed/services"
 )
 
@@ -62,11 +64,48 @@ func (_m *mockAlertManager) GetAlerts(ctx context.Context, params *services.Filt
 	return r0, r1
 }
 
+// PreviewMessage provides a mock function with given fields: channelType, templates, severity
+func (_m *mockAlertManager) PreviewMessage(channelType models.ChannelType, templates *models.MessageTemplates, severity common.Severity) (*services.MessagePreview, error) {
+	ret := _m.Called(channelType, templates, severity)
+
+	var r0 *services.MessagePreview
+	if rf, ok := ret.Get(0).(func(models.ChannelType, *models.MessageTemplates, common.Severity) *services.MessagePreview); ok {
+		r0 = rf(channelType, templates, severity)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*services.MessagePreview)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(models.ChannelType, *models.MessageTemplates, common.Severity) error); ok {
+		r1 = rf(channelType, templates, severity)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RequestConfigurationUpdate provides a mock function with given fields:
 func (_m *mockAlertManager) RequestConfigurationUpdate() {
 	_m.Called()
 }
 
+// SendTestNotification provides a mock function with given fields: ctx, channel
+func (_m *mockAlertManager) SendTestNotification(ctx context.Context, channel *models.Channel) error {
+	ret := _m.Called(ctx, channel)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Channel) error); ok {
+		r0 = rf(ctx, channel)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SilenceAlerts provides a mock function with given fields: ctx, alerts
 func (_m *mockAlertManager) SilenceAlerts(ctx context.Context, alerts []*ammodels.GettableAlert) error {
 	ret := _m.Called(ctx, alerts)
@@ -94,3 +133,17 @@ func (_m *mockAlertManager) UnsilenceAlerts(ctx context.Context, alerts []*ammod
 
 	return r0
 }
+
+// ValidateMessageTemplates provides a mock function with given fields: channelType, templates
+func (_m *mockAlertManager) ValidateMessageTemplates(channelType models.ChannelType, templates *models.MessageTemplates) error {
+	ret := _m.Called(channelType, templates)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(models.ChannelType, *models.MessageTemplates) error); ok {
+		r0 = rf(channelType, templates)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
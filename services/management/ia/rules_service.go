@@ -151,6 +151,14 @@ func (s *RulesService) WriteVMAlertRulesFiles() {
 			s.l.Errorf("Failed to write alert rule file: %+v", err)
 		}
 	}
+
+	if file := s.prepareRecordingRulesFile(rules); file != nil {
+		if err = s.writeRecordingRuleFile(file); err != nil {
+			s.l.Errorf("Failed to write recording rule file: %+v", err)
+		}
+	}
+
+	s.writeSLORuleFiles()
 }
 
 // prepareRulesFiles converts collected IA rules to Alertmanager rule files content.
@@ -346,6 +354,29 @@ func (s *RulesService) CreateAlertRule(ctx context.Context, req *iav1beta1.Creat
 		return nil, status.Errorf(codes.InvalidArgument, "Template name or source rule id should be specified.")
 	}
 
+	params, err := prepareCreateRuleParams(s.db.Querier, s.templates, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rule *models.Rule
+	errTX := s.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		rule, err = models.CreateRule(tx.Querier, params)
+		return err
+	})
+	if errTX != nil {
+		return nil, errTX
+	}
+
+	s.updateConfigurations()
+
+	return &iav1beta1.CreateAlertRuleResponse{RuleId: rule.ID}, nil
+}
+
+// prepareCreateRuleParams converts rule creation request to model parameters
+// using either the given template or the source rule, and validates them.
+func prepareCreateRuleParams(q *reform.Querier, templates *TemplatesService, req *iav1beta1.CreateAlertRuleRequest) (*models.CreateRuleParams, error) {
 	params := &models.CreateRuleParams{
 		Name:         req.Name,
 		Disabled:     req.Disabled,
@@ -367,7 +398,7 @@ func (s *RulesService) CreateAlertRule(ctx context.Context, req *iav1beta1.Creat
 	}
 
 	if req.TemplateName != "" {
-		template, ok := s.templates.getTemplates()[req.TemplateName]
+		template, ok := templates.getTemplates()[req.TemplateName]
 		if !ok {
 			return nil, status.Errorf(codes.NotFound, "Unknown template %s.", req.TemplateName)
 		}
@@ -385,7 +416,7 @@ func (s *RulesService) CreateAlertRule(ctx context.Context, req *iav1beta1.Creat
 			return nil, err
 		}
 	} else {
-		sourceRule, err := models.FindRuleByID(s.db.Querier, req.SourceRuleId)
+		sourceRule, err := models.FindRuleByID(q, req.SourceRuleId)
 		if err != nil {
 			return nil, err
 		}
@@ -408,29 +439,16 @@ func (s *RulesService) CreateAlertRule(ctx context.Context, req *iav1beta1.Creat
 		}
 	}
 
-	if err := validateParameters(params.ParamsDefinitions, params.ParamsValues); err != nil {
+	if err = validateParameters(params.ParamsDefinitions, params.ParamsValues); err != nil {
 		return nil, err
 	}
 
 	// Check that we can compile expression with given parameters
-	_, err = fillExprWithParams(params.ExprTemplate, params.ParamsValues.AsStringMap())
-	if err != nil {
+	if _, err = fillExprWithParams(params.ExprTemplate, params.ParamsValues.AsStringMap()); err != nil {
 		return nil, err
 	}
 
-	var rule *models.Rule
-	errTX := s.db.InTransaction(func(tx *reform.TX) error {
-		var err error
-		rule, err = models.CreateRule(tx.Querier, params)
-		return err
-	})
-	if errTX != nil {
-		return nil, errTX
-	}
-
-	s.updateConfigurations()
-
-	return &iav1beta1.CreateAlertRuleResponse{RuleId: rule.ID}, nil
+	return params, nil
 }
 
 // UpdateAlertRule updates Integrated Alerting rule.
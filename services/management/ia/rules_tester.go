@@ -0,0 +1,353 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"time"
+
+	iav1beta1 "github.com/percona/pmm/api/managementpb/ia"
+	"github.com/pkg/errors"
+	metrics "github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	defaultTestStep = time.Minute
+	// VictoriaMetrics returns an error for range queries with more points per series than that.
+	maxTestPoints = 30000
+)
+
+// RulesTester evaluates Integrated Alerting rules against historical metrics without saving them.
+type RulesTester struct {
+	db        *reform.DB
+	l         *logrus.Entry
+	templates *TemplatesService
+	vmClient  v1.API
+}
+
+// ParamValue represents rule parameter value in JSON API requests.
+type ParamValue struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"` // one of iav1beta1.ParamType names: BOOL, FLOAT, STRING
+	Bool   bool    `json:"bool,omitempty"`
+	Float  float64 `json:"float,omitempty"`
+	String string  `json:"string,omitempty"`
+}
+
+// Filter represents rule filter in JSON API requests.
+type Filter struct {
+	Type  string `json:"type"` // one of iav1beta1.FilterType names: EQUAL, REGEX
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// TestAlertRuleRequest is a request to evaluate a rule against historical metrics.
+// Rule is defined either by template name or by source rule ID, like in CreateAlertRule.
+type TestAlertRuleRequest struct {
+	TemplateName string        `json:"template_name"`
+	SourceRuleID string        `json:"source_rule_id"`
+	Params       []*ParamValue `json:"params"`
+	For          string        `json:"for"`  // Go duration; rule default is used if empty
+	Step         string        `json:"step"` // Go duration; defaultTestStep is used if empty
+	Filters      []*Filter     `json:"filters"`
+	StartFrom    time.Time     `json:"start_from"`
+	EndAt        time.Time     `json:"end_at"` // current time is used if zero
+}
+
+// FiringInterval represents a period of time when alert would be firing.
+type FiringInterval struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// TestAlert represents a series for which the rule would have fired.
+type TestAlert struct {
+	Labels  map[string]string `json:"labels"`
+	Firing  []*FiringInterval `json:"firing"`
+	Fired   int               `json:"fired"` // number of times alert started firing
+	Seconds float64           `json:"seconds"`
+}
+
+// TestAlertRuleResponse contains rule evaluation results.
+type TestAlertRuleResponse struct {
+	Expr      string       `json:"expr"`
+	For       string       `json:"for"`
+	Step      string       `json:"step"`
+	StartFrom time.Time    `json:"start_from"`
+	EndAt     time.Time    `json:"end_at"`
+	Alerts    []*TestAlert `json:"alerts"`
+}
+
+// NewRulesTester creates a new RulesTester that queries VictoriaMetrics at given address.
+func NewRulesTester(db *reform.DB, templates *TemplatesService, vmAddress string) (*RulesTester, error) {
+	vmClient, err := metrics.NewClient(metrics.Config{Address: vmAddress})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &RulesTester{
+		db:        db,
+		l:         logrus.WithField("component", "management/ia/rules_tester"),
+		templates: templates,
+		vmClient:  v1.NewAPI(vmClient),
+	}, nil
+}
+
+// Enabled returns if service is enabled and can be used.
+func (s *RulesTester) Enabled() bool {
+	settings, err := models.GetSettings(s.db)
+	if err != nil {
+		s.l.WithError(err).Error("can't get settings")
+		return false
+	}
+	return settings.IntegratedAlerting.Enabled
+}
+
+// TestAlertRule evaluates rule expression with given parameters, duration and filters
+// over the given past window and returns series and intervals for which the rule would have fired.
+func (s *RulesTester) TestAlertRule(ctx context.Context, req *TestAlertRuleRequest) (*TestAlertRuleResponse, error) {
+	if req.TemplateName != "" && req.SourceRuleID != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Both template name and source rule id are specified.")
+	}
+	if req.TemplateName == "" && req.SourceRuleID == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Template name or source rule id should be specified.")
+	}
+
+	createReq, err := convertTestAlertRuleRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := prepareCreateRuleParams(s.db.Querier, s.templates, createReq)
+	if err != nil {
+		return nil, err
+	}
+
+	expr, err := fillExprWithParams(params.ExprTemplate, params.ParamsValues.AsStringMap())
+	if err != nil {
+		return nil, err
+	}
+
+	forD := params.For
+	if forD == 0 {
+		forD = params.DefaultFor
+	}
+
+	step := defaultTestStep
+	if req.Step != "" {
+		if step, err = time.ParseDuration(req.Step); err != nil || step <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid step %q.", req.Step)
+		}
+	}
+
+	end := req.EndAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := req.StartFrom
+	if start.IsZero() || !start.Before(end) {
+		return nil, status.Errorf(codes.InvalidArgument, "Start time should be before end time.")
+	}
+
+	// query data before the window start too, so alerts that were pending at start can fire inside the window
+	rng := v1.Range{
+		Start: start.Add(-forD),
+		End:   end,
+		Step:  step,
+	}
+	if points := rng.End.Sub(rng.Start) / step; points > maxTestPoints {
+		return nil, status.Errorf(codes.InvalidArgument, "Too many points per series (%d), increase step or decrease window.", points)
+	}
+
+	matchers, err := compileFilters(params.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	value, warns, err := s.vmClient.QueryRange(ctx, expr, rng)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Failed to execute rule expression: %s.", err)
+	}
+	for _, warn := range warns {
+		s.l.Warn(warn)
+	}
+
+	matrix, ok := value.(model.Matrix)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "Rule expression returned %s instead of range vector.", value.Type())
+	}
+
+	res := &TestAlertRuleResponse{
+		Expr:      expr,
+		For:       forD.String(),
+		Step:      step.String(),
+		StartFrom: start.UTC(),
+		EndAt:     end.UTC(),
+		Alerts:    []*TestAlert{},
+	}
+	for _, stream := range matrix {
+		if !matchFilters(stream.Metric, matchers) {
+			continue
+		}
+
+		intervals := findFiringIntervals(stream.Values, start, step, forD)
+		if len(intervals) == 0 {
+			continue
+		}
+
+		alert := &TestAlert{
+			Labels: make(map[string]string, len(stream.Metric)),
+			Firing: intervals,
+			Fired:  len(intervals),
+		}
+		for k, v := range stream.Metric {
+			alert.Labels[string(k)] = string(v)
+		}
+		for _, i := range intervals {
+			alert.Seconds += i.EndsAt.Sub(i.StartsAt).Seconds()
+		}
+		res.Alerts = append(res.Alerts, alert)
+	}
+
+	// show the noisiest series first
+	sort.SliceStable(res.Alerts, func(i, j int) bool {
+		return res.Alerts[i].Seconds > res.Alerts[j].Seconds
+	})
+
+	return res, nil
+}
+
+// convertTestAlertRuleRequest converts JSON API request to gRPC API request
+// to reuse the same validation as for rule creation.
+func convertTestAlertRuleRequest(req *TestAlertRuleRequest) (*iav1beta1.CreateAlertRuleRequest, error) {
+	res := &iav1beta1.CreateAlertRuleRequest{
+		TemplateName: req.TemplateName,
+		SourceRuleId: req.SourceRuleID,
+	}
+
+	if req.For != "" {
+		d, err := time.ParseDuration(req.For)
+		if err != nil || d < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid duration %q.", req.For)
+		}
+		res.For = durationpb.New(d)
+	}
+
+	for _, p := range req.Params {
+		v := &iav1beta1.ParamValue{
+			Name: p.Name,
+			Type: iav1beta1.ParamType(iav1beta1.ParamType_value[p.Type]),
+		}
+		switch v.Type {
+		case iav1beta1.ParamType_BOOL:
+			v.Value = &iav1beta1.ParamValue_Bool{Bool: p.Bool}
+		case iav1beta1.ParamType_FLOAT:
+			v.Value = &iav1beta1.ParamValue_Float{Float: p.Float}
+		case iav1beta1.ParamType_STRING:
+			v.Value = &iav1beta1.ParamValue_String_{String_: p.String}
+		case iav1beta1.ParamType_PARAM_TYPE_INVALID:
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid type %q of parameter %s.", p.Type, p.Name)
+		}
+		res.Params = append(res.Params, v)
+	}
+
+	for _, f := range req.Filters {
+		res.Filters = append(res.Filters, &iav1beta1.Filter{
+			Type:  iav1beta1.FilterType(iav1beta1.FilterType_value[f.Type]),
+			Key:   f.Key,
+			Value: f.Value,
+		})
+	}
+
+	return res, nil
+}
+
+type labelMatcher struct {
+	key string
+	re  *regexp.Regexp
+}
+
+// compileFilters converts rule filters to label matchers; values of equal filters are matched literally.
+func compileFilters(filters models.Filters) ([]labelMatcher, error) {
+	res := make([]labelMatcher, len(filters))
+	for i, f := range filters {
+		expr := regexp.QuoteMeta(f.Val)
+		if f.Type == models.Regex {
+			expr = f.Val
+		}
+
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid filter regex %q: %s.", f.Val, err)
+		}
+		res[i] = labelMatcher{key: f.Key, re: re}
+	}
+
+	return res, nil
+}
+
+// matchFilters returns true if all matchers match series labels.
+func matchFilters(metric model.Metric, matchers []labelMatcher) bool {
+	for _, m := range matchers {
+		if !m.re.MatchString(string(metric[model.LabelName(m.key)])) {
+			return false
+		}
+	}
+	return true
+}
+
+// findFiringIntervals emulates vmalert evaluation of alerting rule with given step and duration.
+// Alerting expression returns a sample only when condition is true. Alert becomes pending on the first sample,
+// starts firing when condition is true for forD, and is resolved on the next evaluation without a sample.
+// Only firing after start is reported.
+func findFiringIntervals(samples []model.SamplePair, start time.Time, step, forD time.Duration) []*FiringInterval {
+	var res []*FiringInterval
+	var activeAt, prev time.Time
+	var current *FiringInterval
+	for _, sample := range samples {
+		t := sample.Timestamp.Time()
+
+		if activeAt.IsZero() || t.Sub(prev) > step {
+			activeAt = t
+			current = nil
+		}
+		prev = t
+
+		if t.Sub(activeAt) < forD || t.Before(start) {
+			continue
+		}
+
+		if current == nil {
+			current = &FiringInterval{StartsAt: t.UTC()}
+			res = append(res, current)
+		}
+		current.EndsAt = t.Add(step).UTC()
+	}
+
+	return res
+}
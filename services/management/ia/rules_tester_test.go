@@ -0,0 +1,199 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"testing"
+	"time"
+
+	iav1beta1 "github.com/percona/pmm/api/managementpb/ia"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestFindFiringIntervals(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	step := time.Minute
+
+	// samples returns samples for given minutes after start
+	samples := func(minutes ...int) []model.SamplePair {
+		res := make([]model.SamplePair, len(minutes))
+		for i, m := range minutes {
+			res[i] = model.SamplePair{Timestamp: model.TimeFromUnixNano(start.Add(time.Duration(m) * step).UnixNano()), Value: 1}
+		}
+		return res
+	}
+	at := func(m int) time.Time {
+		return start.Add(time.Duration(m) * step)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		samples  []model.SamplePair
+		forD     time.Duration
+		expected []*FiringInterval
+	}{{
+		name:     "no samples",
+		forD:     3 * time.Minute,
+		expected: nil,
+	}, {
+		name:     "pending only",
+		samples:  samples(1, 2, 3, 5, 6),
+		forD:     3 * time.Minute,
+		expected: nil,
+	}, {
+		name:    "fired twice",
+		samples: samples(1, 2, 3, 4, 5, 7, 8, 9, 10),
+		forD:    3 * time.Minute,
+		expected: []*FiringInterval{
+			{StartsAt: at(4), EndsAt: at(6)},
+			{StartsAt: at(10), EndsAt: at(11)},
+		},
+	}, {
+		name:    "without duration",
+		samples: samples(1, 3),
+		forD:    0,
+		expected: []*FiringInterval{
+			{StartsAt: at(1), EndsAt: at(2)},
+			{StartsAt: at(3), EndsAt: at(4)},
+		},
+	}, {
+		name:    "pending before start",
+		samples: samples(-3, -2, -1, 0, 1),
+		forD:    2 * time.Minute,
+		expected: []*FiringInterval{
+			{StartsAt: at(0), EndsAt: at(2)},
+		},
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := findFiringIntervals(tc.samples, start, step, tc.forD)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestFilters(t *testing.T) {
+	t.Parallel()
+
+	metric := model.Metric{
+		"service_name": "mysql-1.prod",
+		"environment":  "prod",
+	}
+
+	for _, tc := range []struct {
+		name    string
+		filters models.Filters
+		match   bool
+	}{{
+		name:  "no filters",
+		match: true,
+	}, {
+		name:    "equal",
+		filters: models.Filters{{Type: models.Equal, Key: "environment", Val: "prod"}},
+		match:   true,
+	}, {
+		name:    "equal is literal",
+		filters: models.Filters{{Type: models.Equal, Key: "service_name", Val: "mysql-1.prod|.*"}},
+		match:   false,
+	}, {
+		name:    "regex is anchored",
+		filters: models.Filters{{Type: models.Regex, Key: "service_name", Val: "mysql-1"}},
+		match:   false,
+	}, {
+		name: "all filters should match",
+		filters: models.Filters{
+			{Type: models.Regex, Key: "service_name", Val: "mysql-.*"},
+			{Type: models.Equal, Key: "environment", Val: "dev"},
+		},
+		match: false,
+	}, {
+		name:    "missing label",
+		filters: models.Filters{{Type: models.Regex, Key: "cluster", Val: ".*"}},
+		match:   true,
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			matchers, err := compileFilters(tc.filters)
+			require.NoError(t, err)
+			assert.Equal(t, tc.match, matchFilters(metric, matchers))
+		})
+	}
+
+	t.Run("invalid regex", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := compileFilters(models.Filters{{Type: models.Regex, Key: "service_name", Val: "("}})
+		tests.AssertGRPCErrorRE(t, codes.InvalidArgument, `Invalid filter regex "\(".*`, err)
+	})
+}
+
+func TestConvertTestAlertRuleRequest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("normal", func(t *testing.T) {
+		t.Parallel()
+
+		actual, err := convertTestAlertRuleRequest(&TestAlertRuleRequest{
+			TemplateName: "test_template",
+			For:          "5m",
+			Params: []*ParamValue{
+				{Name: "threshold", Type: "FLOAT", Float: 80},
+			},
+			Filters: []*Filter{
+				{Type: "REGEX", Key: "environment", Value: "prod.*"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "test_template", actual.TemplateName)
+		assert.Equal(t, 5*time.Minute, actual.For.AsDuration())
+		require.Len(t, actual.Params, 1)
+		assert.Equal(t, iav1beta1.ParamType_FLOAT, actual.Params[0].Type)
+		assert.Equal(t, 80.0, actual.Params[0].GetFloat())
+		require.Len(t, actual.Filters, 1)
+		assert.Equal(t, iav1beta1.FilterType_REGEX, actual.Filters[0].Type)
+	})
+
+	t.Run("invalid duration", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertTestAlertRuleRequest(&TestAlertRuleRequest{TemplateName: "test_template", For: "five"})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, `Invalid duration "five".`), err)
+	})
+
+	t.Run("invalid param type", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertTestAlertRuleRequest(&TestAlertRuleRequest{
+			TemplateName: "test_template",
+			Params:       []*ParamValue{{Name: "threshold", Type: "INT"}},
+		})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, `Invalid type "INT" of parameter threshold.`), err)
+	})
+}
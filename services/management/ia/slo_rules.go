@@ -0,0 +1,236 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/percona-platform/saas/pkg/common"
+	"github.com/percona/promconfig"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// sliGroupingLabels are labels that identify SLI series; each series has own error budget.
+const sliGroupingLabels = "service_id, service_name, node_name"
+
+// burnRateAlert describes multi-window burn-rate alert: it fires when both long and short windows
+// burn error budget faster than allowed to consume given budget fraction during the long window.
+// See https://sre.google/workbook/alerting-on-slos/.
+type burnRateAlert struct {
+	long, short    time.Duration
+	budgetFraction float64
+	severity       common.Severity
+}
+
+var burnRateAlerts = []burnRateAlert{
+	{long: time.Hour, short: 5 * time.Minute, budgetFraction: 0.02, severity: common.Critical},
+	{long: 6 * time.Hour, short: 30 * time.Minute, budgetFraction: 0.05, severity: common.Critical},
+	{long: 24 * time.Hour, short: 2 * time.Hour, budgetFraction: 0.1, severity: common.Warning},
+	{long: 3 * 24 * time.Hour, short: 6 * time.Hour, budgetFraction: 0.1, severity: common.Warning},
+}
+
+// factor returns maximal allowed burn rate for the SLO window.
+func (a burnRateAlert) factor(window time.Duration) float64 {
+	return a.budgetFraction * float64(window) / float64(a.long)
+}
+
+// sloRule represents vmalert recording or alerting rule.
+type sloRule struct {
+	Record      string              `yaml:"record,omitempty"`
+	Alert       string              `yaml:"alert,omitempty"`
+	Expr        string              `yaml:"expr"`
+	Duration    promconfig.Duration `yaml:"for,omitempty"`
+	Labels      map[string]string   `yaml:"labels,omitempty"`
+	Annotations map[string]string   `yaml:"annotations,omitempty"`
+}
+
+type sloRuleGroup struct {
+	Name  string    `yaml:"name"`
+	Rules []sloRule `yaml:"rules"`
+}
+
+// sloRuleFile represents vmalert file with recording and alerting rules of a single SLO.
+type sloRuleFile struct {
+	sloID string
+	Group []sloRuleGroup `yaml:"groups"`
+}
+
+// sloSelector returns PromQL label matchers for SLO service and filters.
+func sloSelector(slo *models.SLO) string {
+	matchers := make([]string, 0, len(slo.Filters)+1)
+	if slo.ServiceID != "" {
+		matchers = append(matchers, fmt.Sprintf("service_id=%q", slo.ServiceID))
+	}
+	for _, f := range slo.Filters {
+		matchers = append(matchers, fmt.Sprintf("%s%s%q", f.Key, f.Type, f.Val))
+	}
+	return strings.Join(matchers, ", ")
+}
+
+// sliValueExpr returns PromQL expression of SLI value: custom one or default one for the SLI type.
+func sliValueExpr(slo *models.SLO) string {
+	if slo.Expr != "" {
+		return slo.Expr
+	}
+
+	selector := sloSelector(slo)
+	if selector != "" {
+		selector = ", " + selector
+	}
+
+	switch slo.SLIType {
+	case models.AvailabilitySLI:
+		return fmt.Sprintf(`max by (%s) ({__name__=~"mysql_up|pg_up|mongodb_up|proxysql_up"%s})`, sliGroupingLabels, selector)
+	case models.LatencySLI:
+		return fmt.Sprintf(`histogram_quantile(%g, sum by (le, %s) (rate({__name__="mysql_info_schema_query_response_time_seconds_bucket"%s}[5m])))`,
+			slo.Percentile, sliGroupingLabels, selector)
+	case models.ReplicationLagSLI:
+		return fmt.Sprintf(`max by (%s) ({__name__=~"mysql_slave_status_seconds_behind_master|pg_replication_lag|mongodb_mongod_replset_member_replication_lag"%s})`,
+			sliGroupingLabels, selector)
+	default:
+		return ""
+	}
+}
+
+// sliGoodExpr returns PromQL expression that is 1 when service is in good state and 0 otherwise.
+func sliGoodExpr(slo *models.SLO) string {
+	value := sliValueExpr(slo)
+	if slo.SLIType == models.AvailabilitySLI {
+		return fmt.Sprintf("(%s) > bool 0", value)
+	}
+	return fmt.Sprintf("(%s) <= bool %g", value, slo.Threshold)
+}
+
+// errorRatioExpr returns PromQL expression of the fraction of bad time within the given window.
+// Subquery is used instead of recorded series, so it works right after SLO creation.
+func errorRatioExpr(slo *models.SLO, window time.Duration) string {
+	return fmt.Sprintf("1 - avg_over_time((%s)[%s:1m])", sliGoodExpr(slo), promconfig.Duration(window))
+}
+
+func errorRatioRecord(window time.Duration) string {
+	return "pmm_slo:error_ratio:" + promconfig.Duration(window).String()
+}
+
+// prepareSLORuleFile returns recording and multi-window burn-rate alerting rules for the given SLO.
+func prepareSLORuleFile(slo *models.SLO) *sloRuleFile {
+	budget := 1 - slo.Target
+	sloLabels := map[string]string{"slo_id": slo.ID}
+
+	windows := make(map[time.Duration]struct{})
+	var alerts []sloRule
+	for _, a := range burnRateAlerts {
+		// long windows comparable with SLO window are useless
+		if a.long*2 > slo.Window {
+			continue
+		}
+		windows[a.long] = struct{}{}
+		windows[a.short] = struct{}{}
+
+		threshold := a.factor(slo.Window) * budget
+		alerts = append(alerts, sloRule{
+			Alert: slo.ID + "/" + promconfig.Duration(a.long).String(),
+			Expr: fmt.Sprintf("%s{slo_id=%q} > %.6g\nand\n%s{slo_id=%q} > %.6g",
+				errorRatioRecord(a.long), slo.ID, threshold, errorRatioRecord(a.short), slo.ID, threshold),
+			Labels: map[string]string{
+				"ia":          "1",
+				"slo_id":      slo.ID,
+				"severity":    a.severity.String(),
+				"burn_window": promconfig.Duration(a.long).String(),
+			},
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("SLO %s error budget burns too fast ({{ $labels.service_name }})", slo.Name),
+				"description": fmt.Sprintf("Error ratio of {{ $labels.service_name }} on {{ $labels.node_name }} is {{ $value | humanizePercentage }} "+
+					"over the last %s, that is more than %g times faster than allowed by %g%% target within %s.",
+					promconfig.Duration(a.long), a.factor(slo.Window), slo.Target*100, promconfig.Duration(slo.Window)),
+				"rule": slo.Name,
+			},
+		})
+	}
+
+	sorted := make([]time.Duration, 0, len(windows))
+	for w := range windows {
+		sorted = append(sorted, w)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	recording := []sloRule{{
+		Record: "pmm_slo:good",
+		Expr:   sliGoodExpr(slo),
+		Labels: sloLabels,
+	}}
+	for _, w := range sorted {
+		recording = append(recording, sloRule{
+			Record: errorRatioRecord(w),
+			Expr:   errorRatioExpr(slo, w),
+			Labels: sloLabels,
+		})
+	}
+
+	return &sloRuleFile{
+		sloID: slo.ID,
+		Group: []sloRuleGroup{{
+			Name:  "PMM SLO " + slo.Name + " recording",
+			Rules: recording,
+		}, {
+			Name:  "PMM SLO " + slo.Name + " alerting",
+			Rules: alerts,
+		}},
+	}
+}
+
+// writeSLORuleFiles writes rule files for all enabled SLOs.
+func (s *RulesService) writeSLORuleFiles() {
+	slos, err := models.FindSLOs(s.db.Querier)
+	if err != nil {
+		s.l.Errorf("Failed to get SLOs: %+v", err)
+		return
+	}
+
+	for _, slo := range slos {
+		if slo.Disabled {
+			s.l.Debugf("Skipping SLO %s as it is disabled.", slo.ID)
+			continue
+		}
+
+		if err = s.writeSLORuleFile(prepareSLORuleFile(slo)); err != nil {
+			s.l.Errorf("Failed to write SLO rule file: %+v", err)
+		}
+	}
+}
+
+// writeSLORuleFile dumps SLO rules to a file.
+func (s *RulesService) writeSLORuleFile(file *sloRuleFile) error {
+	b, err := yaml.Marshal(file)
+	if err != nil {
+		return errors.Errorf("failed to marshal SLO rules %v", err)
+	}
+	b = append([]byte("---\n"), b...)
+
+	path := s.rulesPath + "/slo_" + strings.TrimPrefix(file.sloID, "/slo_id/") + ".yml"
+	if err = ioutil.WriteFile(path, b, 0o644); err != nil {
+		return errors.Errorf("failed to dump SLO rules to file %s: %v", s.rulesPath, err)
+	}
+
+	return nil
+}
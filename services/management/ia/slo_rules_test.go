@@ -0,0 +1,146 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestSLORules(t *testing.T) {
+	t.Parallel()
+
+	t.Run("expressions", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range []struct {
+			name     string
+			slo      *models.SLO
+			expected string
+		}{{
+			name: "availability",
+			slo: &models.SLO{
+				SLIType:   models.AvailabilitySLI,
+				ServiceID: "/service_id/1",
+				Filters:   models.Filters{{Type: models.Regex, Key: "environment", Val: "prod.*"}},
+			},
+			expected: `(max by (service_id, service_name, node_name) ({__name__=~"mysql_up|pg_up|mongodb_up|proxysql_up", ` +
+				`service_id="/service_id/1", environment=~"prod.*"})) > bool 0`,
+		}, {
+			name: "latency",
+			slo: &models.SLO{
+				SLIType:    models.LatencySLI,
+				Threshold:  0.5,
+				Percentile: 0.99,
+			},
+			expected: `(histogram_quantile(0.99, sum by (le, service_id, service_name, node_name) ` +
+				`(rate({__name__="mysql_info_schema_query_response_time_seconds_bucket"}[5m])))) <= bool 0.5`,
+		}, {
+			name: "custom",
+			slo: &models.SLO{
+				SLIType:   models.ReplicationLagSLI,
+				Expr:      "max by (service_name) (custom_lag)",
+				Threshold: 30,
+			},
+			expected: `(max by (service_name) (custom_lag)) <= bool 30`,
+		}} {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				assert.Equal(t, tc.expected, sliGoodExpr(tc.slo))
+			})
+		}
+	})
+
+	t.Run("burn rate", func(t *testing.T) {
+		t.Parallel()
+
+		slo := &models.SLO{
+			ID:      "/slo_id/1",
+			Name:    "availability",
+			SLIType: models.AvailabilitySLI,
+			Target:  0.999,
+			Window:  30 * 24 * time.Hour,
+		}
+		file := prepareSLORuleFile(slo)
+		require.Len(t, file.Group, 2)
+
+		var records []string
+		for _, r := range file.Group[0].Rules {
+			records = append(records, r.Record)
+			assert.Equal(t, map[string]string{"slo_id": "/slo_id/1"}, r.Labels)
+		}
+		expectedRecords := []string{
+			"pmm_slo:good",
+			"pmm_slo:error_ratio:5m",
+			"pmm_slo:error_ratio:30m",
+			"pmm_slo:error_ratio:1h",
+			"pmm_slo:error_ratio:2h",
+			"pmm_slo:error_ratio:6h",
+			"pmm_slo:error_ratio:1d",
+			"pmm_slo:error_ratio:3d",
+		}
+		assert.Equal(t, expectedRecords, records)
+
+		alerts := file.Group[1].Rules
+		require.Len(t, alerts, 4)
+		assert.Equal(t, "/slo_id/1/1h", alerts[0].Alert)
+		expected := `pmm_slo:error_ratio:1h{slo_id="/slo_id/1"} > 0.0144` + "\nand\n" +
+			`pmm_slo:error_ratio:5m{slo_id="/slo_id/1"} > 0.0144`
+		assert.Equal(t, expected, alerts[0].Expr)
+		assert.Equal(t, "critical", alerts[0].Labels["severity"])
+		assert.Equal(t, "warning", alerts[3].Labels["severity"])
+		assert.Equal(t, "3d", alerts[3].Labels["burn_window"])
+	})
+
+	t.Run("short window", func(t *testing.T) {
+		t.Parallel()
+
+		slo := &models.SLO{
+			ID:      "/slo_id/1",
+			SLIType: models.AvailabilitySLI,
+			Target:  0.99,
+			Window:  24 * time.Hour,
+		}
+		file := prepareSLORuleFile(slo)
+		assert.Len(t, file.Group[0].Rules, 5)
+		assert.Len(t, file.Group[1].Rules, 2)
+	})
+
+	t.Run("custom expression with filters", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := convertSLOParams(&SLO{
+			Name:      "custom",
+			ServiceID: "/service_id/1",
+			SLIType:   "availability",
+			Expr:      "up",
+			Target:    0.99,
+			Window:    "720h",
+		})
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Service ID and filters can't be used with custom SLI expression."), err)
+	})
+}
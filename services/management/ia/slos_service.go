@@ -0,0 +1,359 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package ia
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	iav1beta1 "github.com/percona/pmm/api/managementpb/ia"
+	"github.com/percona/promconfig"
+	"github.com/pkg/errors"
+	metrics "github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// sloBudgetStep is a resolution of SLI subquery for error budget calculation.
+const sloBudgetStep = "5m"
+
+// SLOsService manages service level objectives and reports their error budgets.
+type SLOsService struct {
+	db       *reform.DB
+	l        *logrus.Entry
+	rules    *RulesService
+	vmClient v1.API
+}
+
+// SLO represents service level objective in JSON API requests and responses.
+type SLO struct {
+	SLOID      string    `json:"slo_id,omitempty"`
+	Name       string    `json:"name"`
+	Summary    string    `json:"summary,omitempty"`
+	ServiceID  string    `json:"service_id,omitempty"`
+	Filters    []*Filter `json:"filters,omitempty"`
+	SLIType    string    `json:"sli_type"`             // one of: availability, latency, replication_lag
+	Expr       string    `json:"expr,omitempty"`       // custom PromQL expression of SLI value
+	Threshold  float64   `json:"threshold,omitempty"`  // seconds, for latency and replication_lag SLIs
+	Percentile float64   `json:"percentile,omitempty"` // for latency SLI; 0.99 by default
+	Target     float64   `json:"target"`               // 0.999 for 99.9%
+	Window     string    `json:"window"`               // Go duration, at least 24h
+	Disabled   bool      `json:"disabled,omitempty"`
+	ChannelIDs []string  `json:"channel_ids,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+}
+
+// ListSLOsRequest is a request of ListSLOs method.
+type ListSLOsRequest struct{}
+
+// ListSLOsResponse is a response of ListSLOs method.
+type ListSLOsResponse struct {
+	SLOs []*SLO `json:"slos"`
+}
+
+// AddSLORequest is a request of AddSLO method.
+type AddSLORequest struct {
+	SLO
+}
+
+// AddSLOResponse is a response of AddSLO method.
+type AddSLOResponse struct {
+	SLOID string `json:"slo_id"`
+}
+
+// ChangeSLORequest is a request of ChangeSLO method; all fields are replaced.
+type ChangeSLORequest struct {
+	SLO
+}
+
+// ChangeSLOResponse is a response of ChangeSLO method.
+type ChangeSLOResponse struct{}
+
+// RemoveSLORequest is a request of RemoveSLO method.
+type RemoveSLORequest struct {
+	SLOID string `json:"slo_id"`
+}
+
+// RemoveSLOResponse is a response of RemoveSLO method.
+type RemoveSLOResponse struct{}
+
+// GetSLOBudgetRequest is a request of GetSLOBudget method.
+type GetSLOBudgetRequest struct {
+	SLOID string `json:"slo_id"`
+}
+
+// SLOBudget represents error budget of a single SLI series (usually, a service).
+type SLOBudget struct {
+	Labels map[string]string `json:"labels"`
+	// SLI is a fraction of good time within the SLO window.
+	SLI float64 `json:"sli"`
+	// Consumed is a fraction of error budget consumed within the SLO window; may be greater than 1.
+	Consumed float64 `json:"consumed"`
+	// Remaining is a fraction of error budget left; negative when SLO is violated.
+	Remaining float64 `json:"remaining"`
+	// BurnRate1h is a rate of error budget consumption during the last hour; 1 means budget lasts exactly for the window.
+	BurnRate1h float64 `json:"burn_rate_1h"`
+}
+
+// GetSLOBudgetResponse is a response of GetSLOBudget method.
+type GetSLOBudgetResponse struct {
+	Target  float64      `json:"target"`
+	Window  string       `json:"window"`
+	Budgets []*SLOBudget `json:"budgets"`
+}
+
+// NewSLOsService creates a new SLOsService that queries VictoriaMetrics at given address.
+func NewSLOsService(db *reform.DB, rules *RulesService, vmAddress string) (*SLOsService, error) {
+	vmClient, err := metrics.NewClient(metrics.Config{Address: vmAddress})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &SLOsService{
+		db:       db,
+		l:        logrus.WithField("component", "management/ia/slos"),
+		rules:    rules,
+		vmClient: v1.NewAPI(vmClient),
+	}, nil
+}
+
+// Enabled returns if service is enabled and can be used.
+func (s *SLOsService) Enabled() bool {
+	settings, err := models.GetSettings(s.db)
+	if err != nil {
+		s.l.WithError(err).Error("can't get settings")
+		return false
+	}
+	return settings.IntegratedAlerting.Enabled
+}
+
+// ListSLOs returns all SLOs.
+func (s *SLOsService) ListSLOs(ctx context.Context, req *ListSLOsRequest) (*ListSLOsResponse, error) {
+	slos, err := models.FindSLOs(s.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListSLOsResponse{SLOs: make([]*SLO, len(slos))}
+	for i, slo := range slos {
+		res.SLOs[i] = convertSLO(slo)
+	}
+	return res, nil
+}
+
+// AddSLO creates a new SLO and generates its recording and alerting rules.
+func (s *SLOsService) AddSLO(ctx context.Context, req *AddSLORequest) (*AddSLOResponse, error) {
+	params, err := convertSLOParams(&req.SLO)
+	if err != nil {
+		return nil, err
+	}
+
+	var slo *models.SLO
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		slo, err = models.CreateSLO(tx.Querier, params)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.rules.updateConfigurations()
+
+	return &AddSLOResponse{SLOID: slo.ID}, nil
+}
+
+// ChangeSLO replaces SLO parameters and regenerates its rules.
+func (s *SLOsService) ChangeSLO(ctx context.Context, req *ChangeSLORequest) (*ChangeSLOResponse, error) {
+	params, err := convertSLOParams(&req.SLO)
+	if err != nil {
+		return nil, err
+	}
+
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		_, err := models.ChangeSLO(tx.Querier, req.SLOID, params)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.rules.updateConfigurations()
+
+	return &ChangeSLOResponse{}, nil
+}
+
+// RemoveSLO removes SLO and its rules.
+func (s *SLOsService) RemoveSLO(ctx context.Context, req *RemoveSLORequest) (*RemoveSLOResponse, error) {
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		return models.RemoveSLO(tx.Querier, req.SLOID)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.rules.updateConfigurations()
+
+	return &RemoveSLOResponse{}, nil
+}
+
+// GetSLOBudget returns error budget consumption of each SLI series within the SLO window.
+func (s *SLOsService) GetSLOBudget(ctx context.Context, req *GetSLOBudgetRequest) (*GetSLOBudgetResponse, error) {
+	slo, err := models.FindSLOByID(s.db.Querier, req.SLOID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sliExpr := fmt.Sprintf("avg_over_time((%s)[%s:%s])", sliGoodExpr(slo), promconfig.Duration(slo.Window), sloBudgetStep)
+	sli, err := s.queryVector(ctx, sliExpr, now)
+	if err != nil {
+		return nil, err
+	}
+	errorRatio, err := s.queryVector(ctx, errorRatioExpr(slo, time.Hour), now)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GetSLOBudgetResponse{
+		Target:  slo.Target,
+		Window:  slo.Window.String(),
+		Budgets: make([]*SLOBudget, 0, len(sli)),
+	}
+	budget := 1 - slo.Target
+	for fp, sample := range sli {
+		b := &SLOBudget{
+			Labels:   make(map[string]string, len(sample.Metric)),
+			SLI:      float64(sample.Value),
+			Consumed: (1 - float64(sample.Value)) / budget,
+		}
+		b.Remaining = 1 - b.Consumed
+		if r := errorRatio[fp]; r != nil {
+			b.BurnRate1h = float64(r.Value) / budget
+		}
+		for k, v := range sample.Metric {
+			b.Labels[string(k)] = string(v)
+		}
+		res.Budgets = append(res.Budgets, b)
+	}
+
+	// show the most violated series first
+	sort.Slice(res.Budgets, func(i, j int) bool {
+		return res.Budgets[i].Remaining < res.Budgets[j].Remaining
+	})
+
+	return res, nil
+}
+
+// queryVector executes instant query and returns samples by series fingerprint.
+func (s *SLOsService) queryVector(ctx context.Context, expr string, ts time.Time) (map[model.Fingerprint]*model.Sample, error) {
+	value, warns, err := s.vmClient.Query(ctx, expr, ts)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Failed to execute SLI expression: %s.", err)
+	}
+	for _, warn := range warns {
+		s.l.Warn(warn)
+	}
+
+	vector, ok := value.(model.Vector)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "SLI expression returned %s instead of instant vector.", value.Type())
+	}
+
+	res := make(map[model.Fingerprint]*model.Sample, len(vector))
+	for _, sample := range vector {
+		res[sample.Metric.Fingerprint()] = sample
+	}
+	return res, nil
+}
+
+// convertSLOParams converts JSON API SLO to model params.
+func convertSLOParams(slo *SLO) (*models.SLOParams, error) {
+	window, err := time.ParseDuration(slo.Window)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid window %q.", slo.Window)
+	}
+
+	filters := make([]*iav1beta1.Filter, len(slo.Filters))
+	for i, f := range slo.Filters {
+		filters[i] = &iav1beta1.Filter{
+			Type:  iav1beta1.FilterType(iav1beta1.FilterType_value[f.Type]),
+			Key:   f.Key,
+			Value: f.Value,
+		}
+	}
+	modelFilters, err := convertFiltersToModel(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	if slo.Expr != "" {
+		if slo.ServiceID != "" || len(modelFilters) != 0 {
+			return nil, status.Error(codes.InvalidArgument, "Service ID and filters can't be used with custom SLI expression.")
+		}
+	}
+
+	return &models.SLOParams{
+		Name:       slo.Name,
+		Summary:    slo.Summary,
+		ServiceID:  slo.ServiceID,
+		Filters:    modelFilters,
+		SLIType:    models.SLIType(slo.SLIType),
+		Expr:       slo.Expr,
+		Threshold:  slo.Threshold,
+		Percentile: slo.Percentile,
+		Target:     slo.Target,
+		Window:     window,
+		Disabled:   slo.Disabled,
+		ChannelIDs: slo.ChannelIDs,
+	}, nil
+}
+
+// convertSLO converts SLO model to JSON API SLO.
+func convertSLO(slo *models.SLO) *SLO {
+	res := &SLO{
+		SLOID:      slo.ID,
+		Name:       slo.Name,
+		Summary:    slo.Summary,
+		ServiceID:  slo.ServiceID,
+		SLIType:    string(slo.SLIType),
+		Expr:       slo.Expr,
+		Threshold:  slo.Threshold,
+		Percentile: slo.Percentile,
+		Target:     slo.Target,
+		Window:     slo.Window.String(),
+		Disabled:   slo.Disabled,
+		ChannelIDs: slo.ChannelIDs,
+		CreatedAt:  slo.CreatedAt,
+	}
+	for _, f := range slo.Filters {
+		res.Filters = append(res.Filters, &Filter{
+			Type:  convertModelToFilterType(f.Type).String(),
+			Key:   f.Key,
+			Value: f.Val,
+		})
+	}
+	return res
+}
@@ -63,9 +63,10 @@ const (
 //      We probably can remove that type.
 type templateInfo struct {
 	alert.Template
-	Yaml      string
-	Source    iav1beta1.TemplateSource
-	CreatedAt *time.Time
+	Yaml           string
+	Source         iav1beta1.TemplateSource
+	CreatedAt      *time.Time
+	RecordingRules []recordingRule // for anomaly templates
 }
 
 // TemplatesService is responsible for interactions with IA rule templates.
@@ -151,6 +152,12 @@ func (s *TemplatesService) CollectTemplates(ctx context.Context) {
 		return
 	}
 
+	anomalyTemplates, err := s.loadAnomalyTemplatesFromAssets(ctx)
+	if err != nil {
+		s.l.Errorf("Failed to load built-in anomaly rule templates: %s.", err)
+		return
+	}
+
 	userDefinedTemplates, err := s.loadTemplatesFromUserFiles(ctx)
 	if err != nil {
 		s.l.Errorf("Failed to load user-defined rule templates: %s.", err)
@@ -170,7 +177,7 @@ func (s *TemplatesService) CollectTemplates(ctx context.Context) {
 		s.l.Errorf("Failed to download rule templates from SaaS: %s.", err)
 	}
 
-	templates := make([]templateInfo, 0, len(builtInTemplates)+len(userDefinedTemplates)+len(dbTemplates)+len(saasTemplates))
+	templates := make([]templateInfo, 0, len(builtInTemplates)+len(anomalyTemplates)+len(userDefinedTemplates)+len(dbTemplates)+len(saasTemplates))
 
 	for _, t := range builtInTemplates {
 		templates = append(templates, templateInfo{
@@ -179,6 +186,14 @@ func (s *TemplatesService) CollectTemplates(ctx context.Context) {
 		})
 	}
 
+	for _, t := range anomalyTemplates {
+		templates = append(templates, templateInfo{
+			Template:       t.Template,
+			Source:         iav1beta1.TemplateSource_BUILT_IN,
+			RecordingRules: t.recordingRules(),
+		})
+	}
+
 	for _, t := range userDefinedTemplates {
 		templates = append(templates, templateInfo{
 			Template: t,
@@ -239,27 +254,13 @@ func (s *TemplatesService) loadTemplatesFromAssets(ctx context.Context) ([]alert
 			return errors.Wrapf(err, "failed to parse rule template asset: %s", path)
 		}
 
-		// built-in-specific validations
-		// TODO move to some better / common place
-
 		if l := len(templates); l != 1 {
 			return errors.Errorf("%q should contain exactly one template, got %d", path, l)
 		}
 
 		t := templates[0]
-
-		filename := filepath.Base(path)
-		if strings.HasPrefix(filename, "pmm_") {
-			return errors.Errorf("%q file name should not start with 'pmm_' prefix", path)
-		}
-		if !strings.HasPrefix(t.Name, "pmm_") {
-			return errors.Errorf("%s %q: template name should start with 'pmm_' prefix", path, t.Name)
-		}
-		if expected := strings.TrimPrefix(t.Name, "pmm_") + ".yml"; filename != expected {
-			return errors.Errorf("template file name %q should be %q", filename, expected)
-		}
-		if len(t.Annotations) != 2 || t.Annotations["summary"] == "" || t.Annotations["description"] == "" {
-			return errors.Errorf("%s %q: template should contain exactly two annotations: summary and description", path, t.Name)
+		if err = validateBuiltInTemplate(path, &t); err != nil {
+			return err
 		}
 
 		res = append(res, t)
@@ -272,6 +273,25 @@ func (s *TemplatesService) loadTemplatesFromAssets(ctx context.Context) ([]alert
 	return res, nil
 }
 
+// validateBuiltInTemplate performs built-in-specific validations of the template loaded from the given asset path.
+// TODO move to some better / common place
+func validateBuiltInTemplate(path string, t *alert.Template) error {
+	filename := filepath.Base(path)
+	if strings.HasPrefix(filename, "pmm_") {
+		return errors.Errorf("%q file name should not start with 'pmm_' prefix", path)
+	}
+	if !strings.HasPrefix(t.Name, "pmm_") {
+		return errors.Errorf("%s %q: template name should start with 'pmm_' prefix", path, t.Name)
+	}
+	if expected := strings.TrimPrefix(t.Name, "pmm_") + ".yml"; filename != expected {
+		return errors.Errorf("template file name %q should be %q", filename, expected)
+	}
+	if len(t.Annotations) != 2 || t.Annotations["summary"] == "" || t.Annotations["description"] == "" {
+		return errors.Errorf("%s %q: template should contain exactly two annotations: summary and description", path, t.Name)
+	}
+	return nil
+}
+
 // loadTemplatesFromUserFiles loads user's alerting rule templates from /srv/ia/templates.
 func (s *TemplatesService) loadTemplatesFromUserFiles(ctx context.Context) ([]alert.Template, error) {
 	paths, err := dir.FindFilesWithExtensions(s.userTemplatesPath, "yml", "yaml")
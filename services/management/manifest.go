@@ -0,0 +1,975 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/AlekSi/pointer"
+	"github.com/google/uuid"
+	"github.com/percona/pmm/api/inventorypb"
+	"github.com/percona/pmm/api/managementpb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+)
+
+// Manifest represents desired state of PMM inventory.
+//
+// Nodes registered with pmm-admin can't be created from the manifest, only their labels are managed;
+// remote Nodes are created together with the first Service on them.
+// Agents are described by options of their Services the same way as in management API.
+type Manifest struct {
+	Nodes    []*NodeManifest    `json:"nodes" yaml:"nodes"`
+	Services []*ServiceManifest `json:"services" yaml:"services"`
+}
+
+// NodeManifest represents desired state of a single Node.
+type NodeManifest struct {
+	NodeName     string            `json:"node_name" yaml:"node_name"`
+	AZ           string            `json:"az" yaml:"az"`
+	Region       string            `json:"region" yaml:"region"`
+	CustomLabels map[string]string `json:"custom_labels" yaml:"custom_labels"`
+}
+
+// ServiceManifest represents desired state of a single Service and its Agents.
+type ServiceManifest struct {
+	ServiceName string             `json:"service_name" yaml:"service_name"`
+	ServiceType models.ServiceType `json:"service_type" yaml:"service_type"`
+	NodeName    string             `json:"node_name" yaml:"node_name"`
+	// RemoteNode is true if Service runs on a remote Node monitored by pmm-agent on another Node.
+	RemoteNode bool `json:"remote_node" yaml:"remote_node"`
+	// PMMAgentID is an ID of pmm-agent that runs Agents. Defaults to pmm-agent running on Node,
+	// or to pmm-agent on PMM Server for remote Nodes.
+	PMMAgentID     string            `json:"pmm_agent_id" yaml:"pmm_agent_id"`
+	Address        string            `json:"address" yaml:"address"`
+	Port           uint16            `json:"port" yaml:"port"`
+	Socket         string            `json:"socket" yaml:"socket"`
+	Database       string            `json:"database" yaml:"database"`
+	Environment    string            `json:"environment" yaml:"environment"`
+	Cluster        string            `json:"cluster" yaml:"cluster"`
+	ReplicationSet string            `json:"replication_set" yaml:"replication_set"`
+	CustomLabels   map[string]string `json:"custom_labels" yaml:"custom_labels"`
+
+	Username      string `json:"username" yaml:"username"`
+	Password      string `json:"password" yaml:"password"`
+	TLS           bool   `json:"tls" yaml:"tls"`
+	TLSSkipVerify bool   `json:"tls_skip_verify" yaml:"tls_skip_verify"`
+	// Exporter log level: fatal, error, warn, info, debug; empty for default.
+	LogLevel string `json:"log_level" yaml:"log_level"`
+	// QueryAnalytics is a QAN Agent kind: perfschema or slowlog for MySQL, profiler for MongoDB,
+	// pgstatements or pgstatmonitor for PostgreSQL; empty to disable QAN.
+	QueryAnalytics       string `json:"query_analytics" yaml:"query_analytics"`
+	DisableQueryExamples bool   `json:"disable_query_examples" yaml:"disable_query_examples"`
+	SkipConnectionCheck  bool   `json:"skip_connection_check" yaml:"skip_connection_check"`
+}
+
+// Manifest plan actions.
+const (
+	manifestCreate  = "create"
+	manifestChange  = "change"
+	manifestReplace = "replace"
+	manifestRemove  = "remove"
+)
+
+// ManifestAction represents a single planned change of inventory.
+type ManifestAction struct {
+	// Action is one of: create, change, replace (create again and remove the old one), remove.
+	Action string `json:"action"`
+	// Object is node or service.
+	Object string `json:"object"`
+	Name   string `json:"name"`
+	// Changes describes changed fields; secrets are not shown.
+	Changes []string `json:"changes,omitempty"`
+
+	node     *NodeManifest
+	service  *ServiceManifest
+	existing *models.Service
+}
+
+// ManifestRequest is a request of Plan and Apply methods.
+type ManifestRequest struct {
+	// Manifest in YAML or JSON format.
+	Manifest string `json:"manifest"`
+	// Remove MySQL, MongoDB, PostgreSQL and ProxySQL Services that are not present in the manifest.
+	Prune bool `json:"prune"`
+	// PlanHash returned by Plan; required by Apply.
+	// Apply fails if the plan for the current inventory state is different.
+	PlanHash string `json:"plan_hash"`
+}
+
+// ManifestResponse is a response of Plan and Apply methods.
+type ManifestResponse struct {
+	// Planned or applied actions, in order of execution.
+	Actions []*ManifestAction `json:"actions"`
+	// PlanHash identifies the manifest and planned actions.
+	PlanHash string `json:"plan_hash"`
+}
+
+// exporterTypes contains exporter type for each Service type managed by manifest.
+var exporterTypes = map[models.ServiceType]models.AgentType{
+	models.MySQLServiceType:      models.MySQLdExporterType,
+	models.MongoDBServiceType:    models.MongoDBExporterType,
+	models.PostgreSQLServiceType: models.PostgresExporterType,
+	models.ProxySQLServiceType:   models.ProxySQLExporterType,
+}
+
+// qanAgentTypes contains QAN Agent types for each Service type and query_analytics value.
+var qanAgentTypes = map[models.ServiceType]map[string]models.AgentType{
+	models.MySQLServiceType: {
+		"perfschema": models.QANMySQLPerfSchemaAgentType,
+		"slowlog":    models.QANMySQLSlowlogAgentType,
+	},
+	models.MongoDBServiceType: {
+		"profiler": models.QANMongoDBProfilerAgentType,
+	},
+	models.PostgreSQLServiceType: {
+		"pgstatements":  models.QANPostgreSQLPgStatementsAgentType,
+		"pgstatmonitor": models.QANPostgreSQLPgStatMonitorAgentType,
+	},
+}
+
+// ManifestService reconciles inventory with a desired state manifest.
+type ManifestService struct {
+	db    *reform.DB
+	state agentsStateUpdater
+	vmdb  prometheusService
+
+	mysql      *MySQLService
+	mongodb    *MongoDBService
+	postgresql *PostgreSQLService
+	proxysql   *ProxySQLService
+	services   *ServiceService
+}
+
+// NewManifestService creates new ManifestService.
+func NewManifestService(db *reform.DB, state agentsStateUpdater, vmdb prometheusService, cc connectionChecker, vc versionCache, dfp defaultsFileParser) *ManifestService {
+	return &ManifestService{
+		db:         db,
+		state:      state,
+		vmdb:       vmdb,
+		mysql:      NewMySQLService(db, state, cc, vc, dfp),
+		mongodb:    NewMongoDBService(db, state, cc),
+		postgresql: NewPostgreSQLService(db, state, cc),
+		proxysql:   NewProxySQLService(db, state, cc),
+		services:   NewServiceService(db, state, vmdb),
+	}
+}
+
+// Plan returns actions required to reconcile inventory with the manifest without applying them.
+func (s *ManifestService) Plan(ctx context.Context, req *ManifestRequest) (*ManifestResponse, error) {
+	m, err := parseManifest(req.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.plan(s.db.Querier, m, req.Prune)
+	if err != nil {
+		return nil, err
+	}
+	return &ManifestResponse{Actions: actions, PlanHash: planHash(req, actions)}, nil
+}
+
+// Apply reconciles inventory with the manifest.
+// It applies only the plan returned by Plan: if inventory was changed since then, Apply fails without changes.
+// Actions are applied one by one; if one fails, the following actions are not applied,
+// and Apply can be called again after fixing the problem.
+func (s *ManifestService) Apply(ctx context.Context, req *ManifestRequest) (*ManifestResponse, error) {
+	if req.PlanHash == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty plan hash, call Plan first.")
+	}
+
+	m, err := parseManifest(req.Manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := s.plan(s.db.Querier, m, req.Prune)
+	if err != nil {
+		return nil, err
+	}
+	hash := planHash(req, actions)
+	if hash != req.PlanHash {
+		return nil, status.Error(codes.FailedPrecondition, "Inventory or manifest was changed since plan, review the new plan.")
+	}
+
+	l := logger.Get(ctx)
+	for i, a := range actions {
+		l.Infof("Applying %s %s %q.", a.Action, a.Object, a.Name)
+		if err = s.apply(ctx, m, a); err != nil {
+			msg := fmt.Sprintf("Failed to %s %s %q (%d of %d actions applied)", a.Action, a.Object, a.Name, i, len(actions))
+			if st, ok := status.FromError(err); ok {
+				return nil, status.Errorf(st.Code(), "%s: %s", msg, st.Message())
+			}
+			return nil, errors.Wrap(err, msg)
+		}
+	}
+
+	return &ManifestResponse{Actions: actions, PlanHash: hash}, nil
+}
+
+// planHash returns a hash of the manifest request and planned actions.
+func planHash(req *ManifestRequest, actions []*ManifestAction) string {
+	b, err := json.Marshal(actions)
+	if err != nil {
+		panic(err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%t\n%d\n%s\n", req.Prune, len(req.Manifest), req.Manifest)
+	h.Write(b) //nolint:errcheck
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseManifest parses and validates manifest in YAML or JSON format.
+func parseManifest(data string) (*Manifest, error) {
+	var m Manifest
+	d := yaml.NewDecoder(strings.NewReader(data))
+	d.KnownFields(true)
+	if err := d.Decode(&m); err != nil {
+		if err == io.EOF {
+			return nil, status.Error(codes.InvalidArgument, "Empty manifest.")
+		}
+		return nil, status.Errorf(codes.InvalidArgument, "Failed to parse manifest: %s.", err)
+	}
+
+	nodeNames := make(map[string]struct{}, len(m.Nodes))
+	for _, n := range m.Nodes {
+		if n.NodeName == "" {
+			return nil, status.Error(codes.InvalidArgument, "Empty Node name.")
+		}
+		if _, ok := nodeNames[n.NodeName]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Node %q is defined twice.", n.NodeName)
+		}
+		nodeNames[n.NodeName] = struct{}{}
+	}
+
+	serviceNames := make(map[string]struct{}, len(m.Services))
+	for _, svc := range m.Services {
+		if svc.ServiceName == "" {
+			return nil, status.Error(codes.InvalidArgument, "Empty Service name.")
+		}
+		if _, ok := serviceNames[svc.ServiceName]; ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Service %q is defined twice.", svc.ServiceName)
+		}
+		serviceNames[svc.ServiceName] = struct{}{}
+
+		if _, ok := exporterTypes[svc.ServiceType]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Service %q: unsupported service type %q.", svc.ServiceName, svc.ServiceType)
+		}
+		if svc.NodeName == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "Service %q: empty Node name.", svc.ServiceName)
+		}
+		if svc.QueryAnalytics != "" {
+			if _, ok := qanAgentTypes[svc.ServiceType][svc.QueryAnalytics]; !ok {
+				return nil, status.Errorf(codes.InvalidArgument, "Service %q: unsupported query analytics %q.", svc.ServiceName, svc.QueryAnalytics)
+			}
+		}
+		if svc.LogLevel == inventorypb.LogLevel_auto.String() {
+			svc.LogLevel = ""
+		}
+		if _, ok := inventorypb.LogLevel_value[svc.LogLevel]; svc.LogLevel != "" && !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Service %q: unknown log level %q.", svc.ServiceName, svc.LogLevel)
+		}
+	}
+
+	return &m, nil
+}
+
+// plan returns actions required to reconcile inventory with the manifest.
+// Removals go first to free resources of pruned Services.
+func (s *ManifestService) plan(q *reform.Querier, m *Manifest, prune bool) ([]*ManifestAction, error) {
+	var actions []*ManifestAction
+
+	if prune {
+		desired := make(map[string]struct{}, len(m.Services))
+		for _, svc := range m.Services {
+			desired[svc.ServiceName] = struct{}{}
+		}
+
+		existing, err := models.FindServices(q, models.ServiceFilters{})
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range existing {
+			if _, ok := exporterTypes[service.ServiceType]; !ok {
+				continue
+			}
+			if _, ok := desired[service.ServiceName]; !ok {
+				actions = append(actions, &ManifestAction{
+					Action:   manifestRemove,
+					Object:   "service",
+					Name:     service.ServiceName,
+					existing: service,
+				})
+			}
+		}
+	}
+
+	remoteNodes := make(map[string]struct{})
+	for _, svc := range m.Services {
+		if svc.RemoteNode {
+			remoteNodes[svc.NodeName] = struct{}{}
+		}
+	}
+
+	for _, n := range m.Nodes {
+		node, err := models.FindNodeByName(q, n.NodeName)
+		if err != nil {
+			if _, ok := remoteNodes[n.NodeName]; ok && status.Code(err) == codes.NotFound {
+				// will be created with the first Service
+				continue
+			}
+			return nil, err
+		}
+
+		changes, err := reconcileNode(q, n, node, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) != 0 {
+			actions = append(actions, &ManifestAction{
+				Action:  manifestChange,
+				Object:  "node",
+				Name:    n.NodeName,
+				Changes: changes,
+				node:    n,
+			})
+		}
+	}
+
+	for _, svc := range m.Services {
+		existing, err := models.FindServiceByName(q, svc.ServiceName)
+		switch status.Code(err) {
+		case codes.OK:
+			changes, replace, err := reconcileService(q, svc, existing, false)
+			if err != nil {
+				return nil, err
+			}
+			action := manifestChange
+			if replace {
+				action = manifestReplace
+			}
+			if len(changes) != 0 {
+				actions = append(actions, &ManifestAction{
+					Action:   action,
+					Object:   "service",
+					Name:     svc.ServiceName,
+					Changes:  changes,
+					service:  svc,
+					existing: existing,
+				})
+			}
+
+		case codes.NotFound:
+			if svc.RemoteNode {
+				if _, err = models.FindNodeByName(q, svc.NodeName); err == nil {
+					return nil, status.Errorf(codes.AlreadyExists, "Service %q: remote Node %q already exists.", svc.ServiceName, svc.NodeName)
+				}
+			}
+			if _, err = resolvePMMAgentID(q, svc); err != nil {
+				return nil, err
+			}
+			actions = append(actions, &ManifestAction{
+				Action:  manifestCreate,
+				Object:  "service",
+				Name:    svc.ServiceName,
+				service: svc,
+			})
+
+		default:
+			return nil, err
+		}
+	}
+
+	return actions, nil
+}
+
+// apply applies a single planned action.
+func (s *ManifestService) apply(ctx context.Context, m *Manifest, a *ManifestAction) error {
+	switch {
+	case a.Object == "node":
+		var changes []string
+		err := s.db.InTransaction(func(tx *reform.TX) error {
+			node, err := models.FindNodeByName(tx.Querier, a.node.NodeName)
+			if err != nil {
+				return err
+			}
+			changes, err = reconcileNode(tx.Querier, a.node, node, true)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// Node labels are added to scraped metrics
+		if len(changes) != 0 {
+			s.vmdb.RequestConfigurationUpdate()
+		}
+		return nil
+
+	case a.Action == manifestCreate:
+		serviceID, err := s.add(ctx, m, a.service, "")
+		if err != nil {
+			return err
+		}
+		return s.change(ctx, a.service, serviceID)
+
+	case a.Action == manifestReplace:
+		return s.replace(ctx, m, a)
+
+	case a.Action == manifestChange:
+		return s.change(ctx, a.service, a.existing.ServiceID)
+
+	case a.Action == manifestRemove:
+		return s.remove(ctx, a.existing)
+
+	default:
+		return errors.Errorf("unhandled action %s %s", a.Action, a.Object)
+	}
+}
+
+// add adds Service and its Agents with management API and returns Service ID.
+// Non-empty suffix is added to Service and remote Node names.
+// Settings that API doesn't support should be applied by change.
+func (s *ManifestService) add(ctx context.Context, m *Manifest, svc *ServiceManifest, suffix string) (string, error) {
+	pmmAgentID, err := resolvePMMAgentID(s.db.Querier, svc)
+	if err != nil {
+		return "", err
+	}
+
+	serviceName := svc.ServiceName + suffix
+	nodeName := svc.NodeName
+	var addNode *managementpb.AddNodeParams
+	if svc.RemoteNode {
+		nodeName = ""
+		addNode = &managementpb.AddNodeParams{
+			NodeType: inventorypb.NodeType_REMOTE_NODE,
+			NodeName: svc.NodeName + suffix,
+		}
+		for _, n := range m.Nodes {
+			if n.NodeName == svc.NodeName {
+				addNode.Az = n.AZ
+				addNode.Region = n.Region
+				addNode.CustomLabels = n.CustomLabels
+			}
+		}
+	}
+
+	logLevel := inventorypb.LogLevel(inventorypb.LogLevel_value[svc.LogLevel])
+	switch svc.ServiceType {
+	case models.MySQLServiceType:
+		_, err = s.mysql.Add(ctx, &managementpb.AddMySQLRequest{
+			NodeName:             nodeName,
+			AddNode:              addNode,
+			ServiceName:          serviceName,
+			Address:              svc.Address,
+			Port:                 uint32(svc.Port),
+			Socket:               svc.Socket,
+			PmmAgentId:           pmmAgentID,
+			Environment:          svc.Environment,
+			Cluster:              svc.Cluster,
+			ReplicationSet:       svc.ReplicationSet,
+			Username:             svc.Username,
+			Password:             svc.Password,
+			QanMysqlPerfschema:   svc.QueryAnalytics == "perfschema",
+			QanMysqlSlowlog:      svc.QueryAnalytics == "slowlog",
+			CustomLabels:         svc.CustomLabels,
+			SkipConnectionCheck:  svc.SkipConnectionCheck,
+			DisableQueryExamples: svc.DisableQueryExamples,
+			Tls:                  svc.TLS,
+			TlsSkipVerify:        svc.TLSSkipVerify,
+			LogLevel:             logLevel,
+		})
+
+	case models.MongoDBServiceType:
+		_, err = s.mongodb.Add(ctx, &managementpb.AddMongoDBRequest{
+			NodeName:            nodeName,
+			AddNode:             addNode,
+			ServiceName:         serviceName,
+			Address:             svc.Address,
+			Port:                uint32(svc.Port),
+			Socket:              svc.Socket,
+			PmmAgentId:          pmmAgentID,
+			Environment:         svc.Environment,
+			Cluster:             svc.Cluster,
+			ReplicationSet:      svc.ReplicationSet,
+			Username:            svc.Username,
+			Password:            svc.Password,
+			QanMongodbProfiler:  svc.QueryAnalytics == "profiler",
+			CustomLabels:        svc.CustomLabels,
+			SkipConnectionCheck: svc.SkipConnectionCheck,
+			Tls:                 svc.TLS,
+			TlsSkipVerify:       svc.TLSSkipVerify,
+			LogLevel:            logLevel,
+		})
+
+	case models.PostgreSQLServiceType:
+		_, err = s.postgresql.Add(ctx, &managementpb.AddPostgreSQLRequest{
+			NodeName:                        nodeName,
+			AddNode:                         addNode,
+			ServiceName:                     serviceName,
+			Address:                         svc.Address,
+			Port:                            uint32(svc.Port),
+			Socket:                          svc.Socket,
+			Database:                        svc.Database,
+			PmmAgentId:                      pmmAgentID,
+			Environment:                     svc.Environment,
+			Cluster:                         svc.Cluster,
+			ReplicationSet:                  svc.ReplicationSet,
+			Username:                        svc.Username,
+			Password:                        svc.Password,
+			QanPostgresqlPgstatementsAgent:  svc.QueryAnalytics == "pgstatements",
+			QanPostgresqlPgstatmonitorAgent: svc.QueryAnalytics == "pgstatmonitor",
+			DisableQueryExamples:            svc.DisableQueryExamples,
+			CustomLabels:                    svc.CustomLabels,
+			SkipConnectionCheck:             svc.SkipConnectionCheck,
+			Tls:                             svc.TLS,
+			TlsSkipVerify:                   svc.TLSSkipVerify,
+			LogLevel:                        logLevel,
+		})
+
+	case models.ProxySQLServiceType:
+		_, err = s.proxysql.Add(ctx, &managementpb.AddProxySQLRequest{
+			NodeName:            nodeName,
+			AddNode:             addNode,
+			ServiceName:         serviceName,
+			Address:             svc.Address,
+			Port:                uint32(svc.Port),
+			Socket:              svc.Socket,
+			PmmAgentId:          pmmAgentID,
+			Environment:         svc.Environment,
+			Cluster:             svc.Cluster,
+			ReplicationSet:      svc.ReplicationSet,
+			Username:            svc.Username,
+			Password:            svc.Password,
+			CustomLabels:        svc.CustomLabels,
+			SkipConnectionCheck: svc.SkipConnectionCheck,
+			Tls:                 svc.TLS,
+			TlsSkipVerify:       svc.TLSSkipVerify,
+			LogLevel:            logLevel,
+		})
+
+	default:
+		return "", errors.Errorf("unhandled service type %s", svc.ServiceType)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	service, err := models.FindServiceByName(s.db.Querier, serviceName)
+	if err != nil {
+		return "", err
+	}
+	return service.ServiceID, nil
+}
+
+// replace re-creates Service. New Service is added under temporary name first,
+// so existing Service is not removed if that fails (for example, due to failed connection check).
+// Then existing Service is removed, and new one takes its name.
+func (s *ManifestService) replace(ctx context.Context, m *Manifest, a *ManifestAction) error {
+	suffix := "-" + uuid.New().String()
+	serviceID, err := s.add(ctx, m, a.service, suffix)
+	if err != nil {
+		return err
+	}
+
+	if err = s.remove(ctx, a.existing); err != nil {
+		service, e := models.FindServiceByID(s.db.Querier, serviceID)
+		if e == nil {
+			e = s.remove(ctx, service)
+		}
+		if e != nil {
+			logger.Get(ctx).Errorf("Failed to remove new Service %q: %s.", a.service.ServiceName+suffix, e)
+		}
+		return err
+	}
+
+	err = s.db.InTransaction(func(tx *reform.TX) error {
+		service, err := models.FindServiceByID(tx.Querier, serviceID)
+		if err != nil {
+			return err
+		}
+
+		if a.service.RemoteNode {
+			tmpNode, err := models.FindNodeByID(tx.Querier, service.NodeID)
+			if err != nil {
+				return err
+			}
+
+			node, err := models.FindNodeByName(tx.Querier, a.service.NodeName)
+			switch status.Code(err) {
+			case codes.NotFound:
+				// remote Node was removed with existing Service
+				tmpNode.NodeName = a.service.NodeName
+				if err = tx.Update(tmpNode); err != nil {
+					return errors.WithStack(err)
+				}
+
+			case codes.OK:
+				// remote Node is still used by other Services
+				service.NodeID = node.NodeID
+				if err = tx.Update(service); err != nil {
+					return errors.WithStack(err)
+				}
+				if err = models.RemoveNode(tx.Querier, tmpNode.NodeID, models.RemoveRestrict); err != nil {
+					return err
+				}
+
+			default:
+				return err
+			}
+		}
+
+		service.ServiceName = a.service.ServiceName
+		return errors.WithStack(tx.Update(service))
+	})
+	if err != nil {
+		return err
+	}
+
+	// labels of Agents include Service name
+	pmmAgentID, err := resolvePMMAgentID(s.db.Querier, a.service)
+	if err != nil {
+		return err
+	}
+	s.state.RequestStateUpdate(ctx, pmmAgentID)
+	s.vmdb.RequestConfigurationUpdate()
+
+	return s.change(ctx, a.service, serviceID)
+}
+
+// change applies in-place changes to existing Service and its Agents.
+func (s *ManifestService) change(ctx context.Context, svc *ServiceManifest, serviceID string) error {
+	var pmmAgentID string
+	var changes []string
+	err := s.db.InTransaction(func(tx *reform.TX) error {
+		service, err := models.FindServiceByID(tx.Querier, serviceID)
+		if err != nil {
+			return err
+		}
+
+		var replace bool
+		if changes, replace, err = reconcileService(tx.Querier, svc, service, true); err != nil {
+			return err
+		}
+		if replace {
+			return status.Errorf(codes.Aborted, "Service %q was changed concurrently.", svc.ServiceName)
+		}
+
+		pmmAgentID, err = resolvePMMAgentID(tx.Querier, svc)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(changes) != 0 {
+		s.state.RequestStateUpdate(ctx, pmmAgentID)
+		s.vmdb.RequestConfigurationUpdate()
+	}
+	return nil
+}
+
+// remove removes Service with its Agents, and its remote Node if there are no other Services on it.
+func (s *ManifestService) remove(ctx context.Context, service *models.Service) error {
+	node, err := models.FindNodeByID(s.db.Querier, service.NodeID)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.services.RemoveService(ctx, &managementpb.RemoveServiceRequest{ServiceId: service.ServiceID}); err != nil {
+		return err
+	}
+
+	if node.NodeType != models.RemoteNodeType {
+		return nil
+	}
+	err = models.RemoveNode(s.db.Querier, node.NodeID, models.RemoveRestrict)
+	if status.Code(err) == codes.FailedPrecondition {
+		// Node is still used
+		return nil
+	}
+	return err
+}
+
+// resolvePMMAgentID returns ID of pmm-agent that should run Agents of the Service.
+func resolvePMMAgentID(q *reform.Querier, svc *ServiceManifest) (string, error) {
+	if svc.PMMAgentID != "" {
+		agent, err := models.FindAgentByID(q, svc.PMMAgentID)
+		if err != nil {
+			return "", err
+		}
+		if agent.AgentType != models.PMMAgentType {
+			return "", status.Errorf(codes.InvalidArgument, "Service %q: Agent %q is not a pmm-agent.", svc.ServiceName, svc.PMMAgentID)
+		}
+		return agent.AgentID, nil
+	}
+
+	if svc.RemoteNode {
+		return models.PMMServerAgentID, nil
+	}
+
+	node, err := models.FindNodeByName(q, svc.NodeName)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return "", status.Errorf(codes.FailedPrecondition, "Service %q: Node %q is not registered.", svc.ServiceName, svc.NodeName)
+		}
+		return "", err
+	}
+
+	agents, err := models.FindPMMAgentsRunningOnNode(q, node.NodeID)
+	if err != nil {
+		return "", err
+	}
+	if len(agents) != 1 {
+		return "", status.Errorf(codes.FailedPrecondition,
+			"Service %q: Node %q has %d pmm-agents, pmm_agent_id should be set.", svc.ServiceName, svc.NodeName, len(agents))
+	}
+	return agents[0].AgentID, nil
+}
+
+// reconcileNode compares Node with the manifest and returns changes; they are saved if apply is true.
+func reconcileNode(q *reform.Querier, n *NodeManifest, node *models.Node, apply bool) ([]string, error) {
+	var changes []string
+	if node.AZ != n.AZ {
+		changes = append(changes, fmt.Sprintf("az: %q -> %q", node.AZ, n.AZ))
+		node.AZ = n.AZ
+	}
+	if pointer.GetString(node.Region) != n.Region {
+		changes = append(changes, fmt.Sprintf("region: %q -> %q", pointer.GetString(node.Region), n.Region))
+		node.Region = pointer.ToStringOrNil(n.Region)
+	}
+
+	labels, err := node.GetCustomLabels()
+	if err != nil {
+		return nil, err
+	}
+	if !labelsEqual(labels, n.CustomLabels) {
+		changes = append(changes, "custom_labels")
+		if err = node.SetCustomLabels(n.CustomLabels); err != nil {
+			return nil, err
+		}
+	}
+
+	if apply && len(changes) != 0 {
+		if err = q.Update(node); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return changes, nil
+}
+
+// reconcileService compares Service and its Agents with the manifest and returns changes.
+// If replace is true, Service should be removed and created again; otherwise,
+// changes are saved if apply is true.
+func reconcileService(q *reform.Querier, svc *ServiceManifest, service *models.Service, apply bool) (changes []string, replace bool, err error) {
+	node, err := models.FindNodeByID(q, service.NodeID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	agents, err := models.FindAgents(q, models.AgentFilters{ServiceID: service.ServiceID})
+	if err != nil {
+		return nil, false, err
+	}
+	var exporter *models.Agent
+	qanAgents := make(map[models.AgentType]*models.Agent)
+	for _, agent := range agents {
+		if agent.AgentType == exporterTypes[service.ServiceType] {
+			exporter = agent
+			continue
+		}
+		for _, t := range qanAgentTypes[service.ServiceType] {
+			if agent.AgentType == t {
+				qanAgents[t] = agent
+			}
+		}
+	}
+
+	// changes that require re-creation
+	for _, c := range []struct {
+		name     string
+		old, new string
+	}{
+		{"service_type", string(service.ServiceType), string(svc.ServiceType)},
+		{"node_name", node.NodeName, svc.NodeName},
+		{"address", pointer.GetString(service.Address), svc.Address},
+		{"port", fmt.Sprint(pointer.GetUint16(service.Port)), fmt.Sprint(svc.Port)},
+		{"socket", pointer.GetString(service.Socket), svc.Socket},
+	} {
+		if c.old != c.new {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", c.name, c.old, c.new))
+		}
+	}
+	if svc.Database != "" && service.DatabaseName != svc.Database {
+		changes = append(changes, fmt.Sprintf("database: %q -> %q", service.DatabaseName, svc.Database))
+	}
+	if exporter == nil {
+		changes = append(changes, fmt.Sprintf("%s is missing", exporterTypes[service.ServiceType]))
+	} else {
+		pmmAgentID, err := resolvePMMAgentID(q, svc)
+		if err != nil {
+			return nil, false, err
+		}
+		if pointer.GetString(exporter.PMMAgentID) != pmmAgentID {
+			changes = append(changes, fmt.Sprintf("pmm_agent_id: %q -> %q", pointer.GetString(exporter.PMMAgentID), pmmAgentID))
+		}
+	}
+	if len(changes) != 0 {
+		return changes, true, nil
+	}
+
+	// Service changes
+	for _, c := range []struct {
+		name string
+		old  *string
+		new  string
+	}{
+		{"environment", &service.Environment, svc.Environment},
+		{"cluster", &service.Cluster, svc.Cluster},
+		{"replication_set", &service.ReplicationSet, svc.ReplicationSet},
+	} {
+		if *c.old != c.new {
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", c.name, *c.old, c.new))
+			*c.old = c.new
+		}
+	}
+	labels, err := service.GetCustomLabels()
+	if err != nil {
+		return nil, false, err
+	}
+	if !labelsEqual(labels, svc.CustomLabels) {
+		changes = append(changes, "custom_labels")
+		if err = service.SetCustomLabels(svc.CustomLabels); err != nil {
+			return nil, false, err
+		}
+	}
+	if apply && len(changes) != 0 {
+		if err = q.Update(service); err != nil {
+			return nil, false, errors.WithStack(err)
+		}
+	}
+
+	// QAN Agents
+	desiredQAN := qanAgentTypes[service.ServiceType][svc.QueryAnalytics]
+	qanTypes := make([]string, 0, len(qanAgents))
+	for t := range qanAgents {
+		qanTypes = append(qanTypes, string(t))
+	}
+	sort.Strings(qanTypes)
+	for _, t := range qanTypes {
+		agent := qanAgents[models.AgentType(t)]
+		if agent.AgentType == desiredQAN {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: removed", t))
+		delete(qanAgents, agent.AgentType)
+		if apply {
+			if _, err = models.RemoveAgent(q, agent.AgentID, models.RemoveRestrict); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	if desiredQAN != "" && qanAgents[desiredQAN] == nil {
+		changes = append(changes, fmt.Sprintf("%s: added", desiredQAN))
+		if apply {
+			params := &models.CreateAgentParams{
+				PMMAgentID:            pointer.GetString(exporter.PMMAgentID),
+				ServiceID:             service.ServiceID,
+				Username:              svc.Username,
+				Password:              svc.Password,
+				TLS:                   svc.TLS,
+				TLSSkipVerify:         svc.TLSSkipVerify,
+				MySQLOptions:          exporter.MySQLOptions,
+				MongoDBOptions:        exporter.MongoDBOptions,
+				PostgreSQLOptions:     exporter.PostgreSQLOptions,
+				QueryExamplesDisabled: svc.DisableQueryExamples,
+				LogLevel:              svc.LogLevel,
+			}
+			if desiredQAN == models.QANMySQLSlowlogAgentType {
+				params.MaxQueryLogSize = defaultMaxSlowlogFileSize
+			}
+			if _, err = models.CreateAgent(q, desiredQAN, params); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	// Agents options
+	agents = []*models.Agent{exporter}
+	if a := qanAgents[desiredQAN]; a != nil {
+		agents = append(agents, a)
+	}
+	for _, agent := range agents {
+		var agentChanges []string
+		if pointer.GetString(agent.Username) != svc.Username || pointer.GetString(agent.Password) != svc.Password {
+			agentChanges = append(agentChanges, "credentials")
+			agent.Username = pointer.ToStringOrNil(svc.Username)
+			agent.Password = pointer.ToStringOrNil(svc.Password)
+		}
+		if agent.TLS != svc.TLS || agent.TLSSkipVerify != svc.TLSSkipVerify {
+			agentChanges = append(agentChanges, fmt.Sprintf("tls: %t/%t -> %t/%t", agent.TLS, agent.TLSSkipVerify, svc.TLS, svc.TLSSkipVerify))
+			agent.TLS = svc.TLS
+			agent.TLSSkipVerify = svc.TLSSkipVerify
+		}
+		if agent == exporter && pointer.GetString(agent.LogLevel) != svc.LogLevel {
+			agentChanges = append(agentChanges, fmt.Sprintf("log_level: %q -> %q", pointer.GetString(agent.LogLevel), svc.LogLevel))
+			agent.LogLevel = pointer.ToStringOrNil(svc.LogLevel)
+		}
+		if agent != exporter && agent.QueryExamplesDisabled != svc.DisableQueryExamples {
+			agentChanges = append(agentChanges, fmt.Sprintf("disable_query_examples: %t -> %t", agent.QueryExamplesDisabled, svc.DisableQueryExamples))
+			agent.QueryExamplesDisabled = svc.DisableQueryExamples
+		}
+
+		for _, c := range agentChanges {
+			changes = append(changes, fmt.Sprintf("%s: %s", agent.AgentType, c))
+		}
+		if apply && len(agentChanges) != 0 {
+			if err = q.Update(agent); err != nil {
+				return nil, false, errors.WithStack(err)
+			}
+		}
+	}
+
+	return changes, false, nil
+}
+
+// labelsEqual returns true if label sets are equal; nil and empty sets are equal.
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
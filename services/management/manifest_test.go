@@ -0,0 +1,241 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+	"github.com/percona/pmm-managed/utils/testdb"
+	"github.com/percona/pmm-managed/utils/tests"
+)
+
+func TestParseManifest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Valid", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := parseManifest(`
+nodes:
+  - node_name: db1
+    custom_labels: {team: a}
+services:
+  - service_name: mysql1
+    service_type: mysql
+    node_name: db1
+    address: 127.0.0.1
+    port: 3306
+    query_analytics: slowlog
+    log_level: auto
+`)
+		require.NoError(t, err)
+		require.Len(t, m.Nodes, 1)
+		assert.Equal(t, map[string]string{"team": "a"}, m.Nodes[0].CustomLabels)
+		require.Len(t, m.Services, 1)
+		assert.Equal(t, models.MySQLServiceType, m.Services[0].ServiceType)
+		assert.Equal(t, uint16(3306), m.Services[0].Port)
+		assert.Empty(t, m.Services[0].LogLevel, "auto is default")
+
+		// JSON is valid YAML
+		m, err = parseManifest(`{"services": [{"service_name": "pg", "service_type": "postgresql", "node_name": "db1"}]}`)
+		require.NoError(t, err)
+		assert.Equal(t, models.PostgreSQLServiceType, m.Services[0].ServiceType)
+	})
+
+	for _, tc := range []struct {
+		name     string
+		manifest string
+		expected *status.Status
+	}{{
+		name:     "Empty",
+		manifest: "",
+		expected: status.New(codes.InvalidArgument, "Empty manifest."),
+	}, {
+		name:     "UnknownField",
+		manifest: "services: [{service_name: a, servce_type: mysql}]",
+		expected: status.New(codes.InvalidArgument, "Failed to parse manifest: yaml: unmarshal errors:\n  line 1: field servce_type not found in type management.ServiceManifest."),
+	}, {
+		name:     "DuplicateService",
+		manifest: "services: [{service_name: a, service_type: mysql, node_name: n}, {service_name: a, service_type: mysql, node_name: n}]",
+		expected: status.New(codes.InvalidArgument, `Service "a" is defined twice.`),
+	}, {
+		name:     "UnsupportedType",
+		manifest: "services: [{service_name: a, service_type: haproxy, node_name: n}]",
+		expected: status.New(codes.InvalidArgument, `Service "a": unsupported service type "haproxy".`),
+	}, {
+		name:     "UnsupportedQAN",
+		manifest: "services: [{service_name: a, service_type: mongodb, node_name: n, query_analytics: slowlog}]",
+		expected: status.New(codes.InvalidArgument, `Service "a": unsupported query analytics "slowlog".`),
+	}, {
+		name:     "UnknownLogLevel",
+		manifest: "services: [{service_name: a, service_type: mysql, node_name: n, log_level: verbose}]",
+		expected: status.New(codes.InvalidArgument, `Service "a": unknown log level "verbose".`),
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := parseManifest(tc.manifest)
+			assert.Nil(t, m)
+			tests.AssertGRPCError(t, tc.expected, err)
+		})
+	}
+}
+
+func TestManifest(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SetupFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+	ctx := logger.Set(context.Background(), t.Name())
+
+	state := &mockAgentsStateUpdater{}
+	state.Test(t)
+	vmdb := &mockPrometheusService{}
+	vmdb.Test(t)
+	cc := &mockConnectionChecker{}
+	cc.Test(t)
+	vc := &mockVersionCache{}
+	vc.Test(t)
+	dfp := &mockDefaultsFileParser{}
+	dfp.Test(t)
+	defer func() {
+		state.AssertExpectations(t)
+		vmdb.AssertExpectations(t)
+		cc.AssertExpectations(t)
+		vc.AssertExpectations(t)
+		dfp.AssertExpectations(t)
+	}()
+
+	s := NewManifestService(db, state, vmdb, cc, vc, dfp)
+
+	const manifest = `
+services:
+  - service_name: mysql1
+    service_type: mysql
+    node_name: pmm-server
+    address: 127.0.0.1
+    port: 3306
+    username: pmm
+    password: secret
+    environment: %s
+    query_analytics: perfschema
+    skip_connection_check: true
+`
+
+	t.Run("Create", func(t *testing.T) {
+		req := &ManifestRequest{Manifest: fmt.Sprintf(manifest, "prod")}
+		res, err := s.Plan(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Actions, 1)
+		assert.Equal(t, manifestCreate, res.Actions[0].Action)
+
+		_, err = s.Apply(ctx, req)
+		tests.AssertGRPCError(t, status.New(codes.InvalidArgument, "Empty plan hash, call Plan first."), err)
+
+		state.On("RequestStateUpdate", ctx, models.PMMServerAgentID).Once()
+		vc.On("RequestSoftwareVersionsUpdate").Once()
+		req.PlanHash = res.PlanHash
+		_, err = s.Apply(ctx, req)
+		require.NoError(t, err)
+
+		// the same plan can't be applied twice
+		_, err = s.Apply(ctx, req)
+		tests.AssertGRPCError(t, status.New(codes.FailedPrecondition, "Inventory or manifest was changed since plan, review the new plan."), err)
+
+		res, err = s.Plan(ctx, req)
+		require.NoError(t, err)
+		assert.Empty(t, res.Actions)
+	})
+
+	t.Run("Change", func(t *testing.T) {
+		req := &ManifestRequest{Manifest: fmt.Sprintf(manifest, "dev")}
+		res, err := s.Plan(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Actions, 1)
+		assert.Equal(t, manifestChange, res.Actions[0].Action)
+		assert.Equal(t, []string{`environment: "prod" -> "dev"`}, res.Actions[0].Changes)
+
+		state.On("RequestStateUpdate", ctx, models.PMMServerAgentID).Once()
+		vmdb.On("RequestConfigurationUpdate").Once()
+		req.PlanHash = res.PlanHash
+		_, err = s.Apply(ctx, req)
+		require.NoError(t, err)
+
+		service, err := models.FindServiceByName(db.Querier, "mysql1")
+		require.NoError(t, err)
+		assert.Equal(t, "dev", service.Environment)
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		existing, err := models.FindServiceByName(db.Querier, "mysql1")
+		require.NoError(t, err)
+
+		req := &ManifestRequest{Manifest: strings.Replace(fmt.Sprintf(manifest, "dev"), "3306", "3307", 1)}
+		res, err := s.Plan(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Actions, 1)
+		assert.Equal(t, manifestReplace, res.Actions[0].Action)
+		assert.Equal(t, []string{`port: "3306" -> "3307"`}, res.Actions[0].Changes)
+
+		// add new, remove existing, rename new
+		state.On("RequestStateUpdate", ctx, models.PMMServerAgentID).Times(3)
+		vmdb.On("RequestConfigurationUpdate").Once()
+		vc.On("RequestSoftwareVersionsUpdate").Once()
+		req.PlanHash = res.PlanHash
+		_, err = s.Apply(ctx, req)
+		require.NoError(t, err)
+
+		service, err := models.FindServiceByName(db.Querier, "mysql1")
+		require.NoError(t, err)
+		assert.NotEqual(t, existing.ServiceID, service.ServiceID)
+		assert.Equal(t, uint16(3307), pointer.GetUint16(service.Port))
+		_, err = models.FindServiceByID(db.Querier, existing.ServiceID)
+		tests.AssertGRPCError(t, status.New(codes.NotFound, fmt.Sprintf("Service with ID %q not found.", existing.ServiceID)), err)
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		req := &ManifestRequest{Manifest: "services: []", Prune: true}
+		res, err := s.Plan(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Actions, 1)
+		assert.Equal(t, manifestRemove, res.Actions[0].Action)
+		assert.Equal(t, "mysql1", res.Actions[0].Name)
+
+		state.On("RequestStateUpdate", ctx, models.PMMServerAgentID).Once()
+		req.PlanHash = res.PlanHash
+		_, err = s.Apply(ctx, req)
+		require.NoError(t, err)
+
+		_, err = models.FindServiceByName(db.Querier, "mysql1")
+		tests.AssertGRPCError(t, status.New(codes.NotFound, `Service with name "mysql1" not found.`), err)
+	})
+}
@@ -0,0 +1,263 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// MetricRelabelRulesService manages metric relabel rules added to scrape jobs of selected Agents.
+type MetricRelabelRulesService struct {
+	db        *reform.DB
+	state     agentsStateUpdater
+	vmdb      prometheusService
+	validator metricRelabelRuleValidator
+}
+
+// NewMetricRelabelRulesService creates new MetricRelabelRulesService.
+func NewMetricRelabelRulesService(db *reform.DB, state agentsStateUpdater, vmdb prometheusService, validator metricRelabelRuleValidator) *MetricRelabelRulesService {
+	return &MetricRelabelRulesService{
+		db:        db,
+		state:     state,
+		vmdb:      vmdb,
+		validator: validator,
+	}
+}
+
+// RelabelConfig represents a single relabel config in JSON API requests and responses.
+type RelabelConfig struct {
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty"`
+	Regex        string   `json:"regex,omitempty"`
+	Modulus      uint64   `json:"modulus,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+	// One of: drop, keep, labelmap, hashmod.
+	Action string `json:"action"`
+}
+
+// MetricRelabelRule represents metric relabel rule in JSON API requests and responses.
+type MetricRelabelRule struct {
+	RuleID string `json:"rule_id,omitempty"`
+	Name   string `json:"name"`
+	// At least one of agent_types or labels should be set; both should match if both are set.
+	// Labels are matched against Node, Service and Agent labels.
+	AgentTypes []string          `json:"agent_types,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Relabel configs applied in order after relabel configs of rules with lesser names.
+	Configs   []*RelabelConfig `json:"configs"`
+	CreatedAt time.Time        `json:"created_at,omitempty"`
+}
+
+// ListMetricRelabelRulesRequest is a request of List method.
+type ListMetricRelabelRulesRequest struct{}
+
+// ListMetricRelabelRulesResponse is a response of List method.
+type ListMetricRelabelRulesResponse struct {
+	Rules []*MetricRelabelRule `json:"rules"`
+}
+
+// AddMetricRelabelRuleRequest is a request of Add method.
+type AddMetricRelabelRuleRequest struct {
+	MetricRelabelRule
+}
+
+// AddMetricRelabelRuleResponse is a response of Add method.
+type AddMetricRelabelRuleResponse struct {
+	RuleID string `json:"rule_id"`
+}
+
+// ChangeMetricRelabelRuleRequest is a request of Change method; all fields are replaced.
+type ChangeMetricRelabelRuleRequest struct {
+	MetricRelabelRule
+}
+
+// ChangeMetricRelabelRuleResponse is a response of Change method.
+type ChangeMetricRelabelRuleResponse struct{}
+
+// RemoveMetricRelabelRuleRequest is a request of Remove method.
+type RemoveMetricRelabelRuleRequest struct {
+	RuleID string `json:"rule_id"`
+}
+
+// RemoveMetricRelabelRuleResponse is a response of Remove method.
+type RemoveMetricRelabelRuleResponse struct{}
+
+// List returns all metric relabel rules.
+func (s *MetricRelabelRulesService) List(ctx context.Context, req *ListMetricRelabelRulesRequest) (*ListMetricRelabelRulesResponse, error) {
+	rules, err := models.FindMetricRelabelRules(s.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListMetricRelabelRulesResponse{Rules: make([]*MetricRelabelRule, len(rules))}
+	for i, r := range rules {
+		res.Rules[i] = convertMetricRelabelRule(r)
+	}
+	return res, nil
+}
+
+// Add creates a new metric relabel rule.
+func (s *MetricRelabelRulesService) Add(ctx context.Context, req *AddMetricRelabelRuleRequest) (*AddMetricRelabelRuleResponse, error) {
+	params := convertMetricRelabelRuleParams(&req.MetricRelabelRule)
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	var rule *models.MetricRelabelRule
+	var pmmAgentIDs []string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		if rule, err = models.CreateMetricRelabelRule(tx.Querier, params); err != nil {
+			return err
+		}
+		if err = s.validator.ValidateMetricRelabelRule(ctx, rule); err != nil {
+			return err
+		}
+		pmmAgentIDs, err = pushMetricsPMMAgentIDs(tx.Querier, relabelRulesMatcher(rule))
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.updateConfigurations(ctx, pmmAgentIDs)
+	return &AddMetricRelabelRuleResponse{RuleID: rule.ID}, nil
+}
+
+// Change replaces metric relabel rule parameters.
+func (s *MetricRelabelRulesService) Change(ctx context.Context, req *ChangeMetricRelabelRuleRequest) (*ChangeMetricRelabelRuleResponse, error) {
+	params := convertMetricRelabelRuleParams(&req.MetricRelabelRule)
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+
+	var pmmAgentIDs []string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		old, err := models.FindMetricRelabelRuleByID(tx.Querier, req.RuleID)
+		if err != nil {
+			return err
+		}
+		rule, err := models.ChangeMetricRelabelRule(tx.Querier, req.RuleID, params)
+		if err != nil {
+			return err
+		}
+		if err = s.validator.ValidateMetricRelabelRule(ctx, rule); err != nil {
+			return err
+		}
+		pmmAgentIDs, err = pushMetricsPMMAgentIDs(tx.Querier, relabelRulesMatcher(old, rule))
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.updateConfigurations(ctx, pmmAgentIDs)
+	return &ChangeMetricRelabelRuleResponse{}, nil
+}
+
+// Remove removes metric relabel rule.
+func (s *MetricRelabelRulesService) Remove(ctx context.Context, req *RemoveMetricRelabelRuleRequest) (*RemoveMetricRelabelRuleResponse, error) {
+	var pmmAgentIDs []string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		old, err := models.FindMetricRelabelRuleByID(tx.Querier, req.RuleID)
+		if err != nil {
+			return err
+		}
+		if pmmAgentIDs, err = pushMetricsPMMAgentIDs(tx.Querier, relabelRulesMatcher(old)); err != nil {
+			return err
+		}
+		return models.RemoveMetricRelabelRule(tx.Querier, req.RuleID)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.updateConfigurations(ctx, pmmAgentIDs)
+	return &RemoveMetricRelabelRuleResponse{}, nil
+}
+
+// updateConfigurations updates VictoriaMetrics configuration and vmagent configuration of given pmm-agents.
+func (s *MetricRelabelRulesService) updateConfigurations(ctx context.Context, pmmAgentIDs []string) {
+	s.vmdb.RequestConfigurationUpdate()
+	for _, id := range pmmAgentIDs {
+		s.state.RequestStateUpdate(ctx, id)
+	}
+}
+
+// relabelRulesMatcher returns a function that matches Agents matched by any of given rules.
+func relabelRulesMatcher(rules ...*models.MetricRelabelRule) func(agent *models.Agent, labels map[string]string) bool {
+	return func(agent *models.Agent, labels map[string]string) bool {
+		for _, r := range rules {
+			if r.Matches(agent.AgentType, labels) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func convertMetricRelabelRuleParams(r *MetricRelabelRule) *models.MetricRelabelRuleParams {
+	params := &models.MetricRelabelRuleParams{
+		Name:    r.Name,
+		Labels:  r.Labels,
+		Configs: make([]models.RelabelConfig, len(r.Configs)),
+	}
+	for _, t := range r.AgentTypes {
+		params.AgentTypes = append(params.AgentTypes, models.AgentType(t))
+	}
+	for i, c := range r.Configs {
+		params.Configs[i] = models.RelabelConfig{
+			SourceLabels: c.SourceLabels,
+			Separator:    c.Separator,
+			Regex:        c.Regex,
+			Modulus:      c.Modulus,
+			TargetLabel:  c.TargetLabel,
+			Replacement:  c.Replacement,
+			Action:       c.Action,
+		}
+	}
+	return params
+}
+
+func convertMetricRelabelRule(r *models.MetricRelabelRule) *MetricRelabelRule {
+	res := &MetricRelabelRule{
+		RuleID:     r.ID,
+		Name:       r.Name,
+		AgentTypes: r.AgentTypes,
+		Labels:     r.Labels,
+		Configs:    make([]*RelabelConfig, len(r.Configs)),
+		CreatedAt:  r.CreatedAt,
+	}
+	for i, c := range r.Configs {
+		res.Configs[i] = &RelabelConfig{
+			SourceLabels: c.SourceLabels,
+			Separator:    c.Separator,
+			Regex:        c.Regex,
+			Modulus:      c.Modulus,
+			TargetLabel:  c.TargetLabel,
+			Replacement:  c.Replacement,
+			Action:       c.Action,
+		}
+	}
+	return res
+}
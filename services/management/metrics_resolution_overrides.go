@@ -0,0 +1,304 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// MetricsResolutionOverridesService manages metrics resolutions used instead of global ones
+// for selected Agents.
+type MetricsResolutionOverridesService struct {
+	db    *reform.DB
+	state agentsStateUpdater
+	vmdb  prometheusService
+}
+
+// NewMetricsResolutionOverridesService creates new MetricsResolutionOverridesService.
+func NewMetricsResolutionOverridesService(db *reform.DB, state agentsStateUpdater, vmdb prometheusService) *MetricsResolutionOverridesService {
+	return &MetricsResolutionOverridesService{
+		db:    db,
+		state: state,
+		vmdb:  vmdb,
+	}
+}
+
+// MetricsResolutionOverride represents metrics resolution override in JSON API requests and responses.
+type MetricsResolutionOverride struct {
+	OverrideID string `json:"override_id,omitempty"`
+	Name       string `json:"name"`
+	// Either agent_id or labels should be set. Labels are matched against Node, Service and Agent labels.
+	AgentID string            `json:"agent_id,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	// Go durations; empty values are not overridden.
+	HR        string    `json:"hr,omitempty"`
+	MR        string    `json:"mr,omitempty"`
+	LR        string    `json:"lr,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// ListMetricsResolutionOverridesRequest is a request of List method.
+type ListMetricsResolutionOverridesRequest struct{}
+
+// ListMetricsResolutionOverridesResponse is a response of List method.
+type ListMetricsResolutionOverridesResponse struct {
+	Overrides []*MetricsResolutionOverride `json:"overrides"`
+}
+
+// AddMetricsResolutionOverrideRequest is a request of Add method.
+type AddMetricsResolutionOverrideRequest struct {
+	MetricsResolutionOverride
+}
+
+// AddMetricsResolutionOverrideResponse is a response of Add method.
+type AddMetricsResolutionOverrideResponse struct {
+	OverrideID string `json:"override_id"`
+}
+
+// ChangeMetricsResolutionOverrideRequest is a request of Change method; all fields are replaced.
+type ChangeMetricsResolutionOverrideRequest struct {
+	MetricsResolutionOverride
+}
+
+// ChangeMetricsResolutionOverrideResponse is a response of Change method.
+type ChangeMetricsResolutionOverrideResponse struct{}
+
+// RemoveMetricsResolutionOverrideRequest is a request of Remove method.
+type RemoveMetricsResolutionOverrideRequest struct {
+	OverrideID string `json:"override_id"`
+}
+
+// RemoveMetricsResolutionOverrideResponse is a response of Remove method.
+type RemoveMetricsResolutionOverrideResponse struct{}
+
+// List returns all metrics resolution overrides.
+func (s *MetricsResolutionOverridesService) List(ctx context.Context, req *ListMetricsResolutionOverridesRequest) (*ListMetricsResolutionOverridesResponse, error) {
+	overrides, err := models.FindMetricsResolutionOverrides(s.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListMetricsResolutionOverridesResponse{Overrides: make([]*MetricsResolutionOverride, len(overrides))}
+	for i, o := range overrides {
+		res.Overrides[i] = convertMetricsResolutionOverride(o)
+	}
+	return res, nil
+}
+
+// Add creates a new metrics resolution override.
+func (s *MetricsResolutionOverridesService) Add(ctx context.Context, req *AddMetricsResolutionOverrideRequest) (*AddMetricsResolutionOverrideResponse, error) {
+	params, err := convertMetricsResolutionOverrideParams(&req.MetricsResolutionOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	var override *models.MetricsResolutionOverride
+	var pmmAgentIDs []string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		if override, err = models.CreateMetricsResolutionOverride(tx.Querier, params); err != nil {
+			return err
+		}
+		pmmAgentIDs, err = pushMetricsPMMAgentIDs(tx.Querier, overridesMatcher(override))
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.updateConfigurations(ctx, pmmAgentIDs)
+	return &AddMetricsResolutionOverrideResponse{OverrideID: override.ID}, nil
+}
+
+// Change replaces metrics resolution override parameters.
+func (s *MetricsResolutionOverridesService) Change(ctx context.Context, req *ChangeMetricsResolutionOverrideRequest) (*ChangeMetricsResolutionOverrideResponse, error) {
+	params, err := convertMetricsResolutionOverrideParams(&req.MetricsResolutionOverride)
+	if err != nil {
+		return nil, err
+	}
+
+	var pmmAgentIDs []string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		old, err := models.FindMetricsResolutionOverrideByID(tx.Querier, req.OverrideID)
+		if err != nil {
+			return err
+		}
+		override, err := models.ChangeMetricsResolutionOverride(tx.Querier, req.OverrideID, params)
+		if err != nil {
+			return err
+		}
+		pmmAgentIDs, err = pushMetricsPMMAgentIDs(tx.Querier, overridesMatcher(old, override))
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.updateConfigurations(ctx, pmmAgentIDs)
+	return &ChangeMetricsResolutionOverrideResponse{}, nil
+}
+
+// Remove removes metrics resolution override.
+func (s *MetricsResolutionOverridesService) Remove(ctx context.Context, req *RemoveMetricsResolutionOverrideRequest) (*RemoveMetricsResolutionOverrideResponse, error) {
+	var pmmAgentIDs []string
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		old, err := models.FindMetricsResolutionOverrideByID(tx.Querier, req.OverrideID)
+		if err != nil {
+			return err
+		}
+		if pmmAgentIDs, err = pushMetricsPMMAgentIDs(tx.Querier, overridesMatcher(old)); err != nil {
+			return err
+		}
+		return models.RemoveMetricsResolutionOverride(tx.Querier, req.OverrideID)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.updateConfigurations(ctx, pmmAgentIDs)
+	return &RemoveMetricsResolutionOverrideResponse{}, nil
+}
+
+// updateConfigurations updates VictoriaMetrics configuration and vmagent configuration of given pmm-agents.
+func (s *MetricsResolutionOverridesService) updateConfigurations(ctx context.Context, pmmAgentIDs []string) {
+	s.vmdb.RequestConfigurationUpdate()
+	for _, id := range pmmAgentIDs {
+		s.state.RequestStateUpdate(ctx, id)
+	}
+}
+
+// overridesMatcher returns a function that matches Agents matched by any of given overrides.
+func overridesMatcher(overrides ...*models.MetricsResolutionOverride) func(agent *models.Agent, labels map[string]string) bool {
+	return func(agent *models.Agent, labels map[string]string) bool {
+		for _, o := range overrides {
+			if o.Matches(agent.AgentID, labels) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// pushMetricsPMMAgentIDs returns IDs of pmm-agents running Agents in push metrics mode matched by the given function
+// with Agent's merged labels.
+// Scrape configuration of those Agents is a part of pmm-agent's vmagent configuration.
+func pushMetricsPMMAgentIDs(q *reform.Querier, matches func(agent *models.Agent, labels map[string]string) bool) ([]string, error) {
+	agents, err := models.FindAgents(q, models.AgentFilters{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]struct{})
+	for _, agent := range agents {
+		if !agent.PushMetrics || agent.PMMAgentID == nil {
+			continue
+		}
+		if _, ok := ids[*agent.PMMAgentID]; ok {
+			continue
+		}
+
+		var service *models.Service
+		if agent.ServiceID != nil {
+			if service, err = models.FindServiceByID(q, *agent.ServiceID); err != nil {
+				return nil, err
+			}
+		}
+		var node *models.Node
+		switch {
+		case agent.NodeID != nil:
+			node, err = models.FindNodeByID(q, *agent.NodeID)
+		case service != nil:
+			node, err = models.FindNodeByID(q, service.NodeID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		labels, err := models.MergeLabels(node, service, agent)
+		if err != nil {
+			return nil, err
+		}
+		if matches(agent, labels) {
+			ids[*agent.PMMAgentID] = struct{}{}
+		}
+	}
+
+	res := make([]string, 0, len(ids))
+	for id := range ids {
+		res = append(res, id)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func convertMetricsResolutionOverrideParams(o *MetricsResolutionOverride) (*models.MetricsResolutionOverrideParams, error) {
+	params := &models.MetricsResolutionOverrideParams{
+		Name:    o.Name,
+		AgentID: o.AgentID,
+		Labels:  o.Labels,
+	}
+
+	for _, r := range []struct {
+		value     string
+		dst       *time.Duration
+		fieldName string
+	}{
+		{o.HR, &params.HR, "hr"},
+		{o.MR, &params.MR, "mr"},
+		{o.LR, &params.LR, "lr"},
+	} {
+		if r.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(r.value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid %s %q.", r.fieldName, r.value)
+		}
+		*r.dst = d
+	}
+
+	return params, nil
+}
+
+func convertMetricsResolutionOverride(o *models.MetricsResolutionOverride) *MetricsResolutionOverride {
+	res := &MetricsResolutionOverride{
+		OverrideID: o.ID,
+		Name:       o.Name,
+		AgentID:    pointer.GetString(o.AgentID),
+		Labels:     o.Labels,
+		CreatedAt:  o.CreatedAt,
+	}
+	if o.HR != 0 {
+		res.HR = o.HR.String()
+	}
+	if o.MR != 0 {
+		res.MR = o.MR.String()
+	}
+	if o.LR != 0 {
+		res.LR = o.LR.String()
+	}
+	return res
+}
@@ -0,0 +1,427 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/agents"
+	"github.com/percona/pmm-managed/utils/stringset"
+)
+
+// mongosRole is a role of mongos router members; they do not belong to any replica set.
+const mongosRole = "mongos"
+
+// AddMongoDBClusterRequest is a request of AddMongoDB method.
+type AddMongoDBClusterRequest struct {
+	// Used as cluster label of members' Services.
+	Name       string `json:"name"`
+	PMMAgentID string `json:"pmm_agent_id"`
+	// Address and port of mongos router.
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+	// Addresses (host:port) of other mongos routers; pmm-agent can't discover them.
+	MongosAddresses []string `json:"mongos_addresses,omitempty"`
+
+	Username                      string `json:"username"`
+	Password                      string `json:"password"`
+	TLS                           bool   `json:"tls"`
+	TLSSkipVerify                 bool   `json:"tls_skip_verify"`
+	TLSCertificateKey             string `json:"tls_certificate_key,omitempty"`
+	TLSCertificateKeyFilePassword string `json:"tls_certificate_key_file_password,omitempty"`
+	TLSCa                         string `json:"tls_ca,omitempty"`
+	AuthenticationMechanism       string `json:"authentication_mechanism,omitempty"`
+	AuthenticationDatabase        string `json:"authentication_database,omitempty"`
+	EnableAllCollectors           bool   `json:"enable_all_collectors,omitempty"`
+
+	Environment  string            `json:"environment,omitempty"`
+	CustomLabels map[string]string `json:"custom_labels,omitempty"`
+	// One of: AUTO (default), PULL, PUSH.
+	MetricsMode        string `json:"metrics_mode,omitempty"`
+	QANMongoDBProfiler bool   `json:"qan_mongodb_profiler"`
+}
+
+// AddMongoDBClusterResponse is a response of AddMongoDB method.
+type AddMongoDBClusterResponse struct {
+	Cluster *MonitoredCluster `json:"cluster"`
+}
+
+// AddMongoDB registers MongoDB sharded cluster: discovers config servers and shard replica set members via mongos
+// and adds Service, mongodb_exporter and optional QAN MongoDB Profiler Agent for each of them and for mongos.
+func (s *ClustersService) AddMongoDB(ctx context.Context, req *AddMongoDBClusterRequest) (*AddMongoDBClusterResponse, error) {
+	metricsMode, err := clusterMetricsMode(s.db.Querier, req.MetricsMode, req.PMMAgentID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &models.MonitoredClusterParams{
+		Name:          req.Name,
+		Type:          models.MongoDBShardedClusterType,
+		PMMAgentID:    req.PMMAgentID,
+		SeedAddress:   req.Address,
+		SeedPort:      req.Port,
+		Username:      req.Username,
+		Password:      req.Password,
+		TLS:           req.TLS,
+		TLSSkipVerify: req.TLSSkipVerify,
+		Environment:   req.Environment,
+		CustomLabels:  req.CustomLabels,
+		PushMetrics:   isPushMode(metricsMode),
+		QAN:           req.QANMongoDBProfiler,
+	}
+	if req.TLSCertificateKey != "" || req.TLSCertificateKeyFilePassword != "" || req.TLSCa != "" ||
+		req.AuthenticationMechanism != "" || req.AuthenticationDatabase != "" || req.EnableAllCollectors {
+		params.MongoDBOptions = &models.MongoDBOptions{
+			TLSCertificateKey:             req.TLSCertificateKey,
+			TLSCertificateKeyFilePassword: req.TLSCertificateKeyFilePassword,
+			TLSCa:                         req.TLSCa,
+			AuthenticationMechanism:       req.AuthenticationMechanism,
+			AuthenticationDatabase:        req.AuthenticationDatabase,
+			EnableAllCollectors:           req.EnableAllCollectors,
+		}
+	}
+
+	known := make([]*models.MonitoredClusterMember, 0, len(req.MongosAddresses))
+	for _, address := range req.MongosAddresses {
+		host, port, err := splitMongoDBAddress(address)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid mongos address %q.", address)
+		}
+		known = append(known, &models.MonitoredClusterMember{
+			Address: net.JoinHostPort(host, strconv.Itoa(int(port))),
+			Role:    mongosRole,
+		})
+	}
+
+	cluster, err := s.addCluster(ctx, params, known)
+	if err != nil {
+		return nil, err
+	}
+	return &AddMongoDBClusterResponse{Cluster: cluster}, nil
+}
+
+// discoverMongoDBCluster discovers sharded cluster members from the given mongos.
+//
+// pmm-agent has no Actions for listShards command or for reading config.shards and config.mongos collections,
+// so cluster membership is collected from what is available:
+//   - config servers are taken from mongos command line options;
+//   - shard replica sets are taken from replica set monitors of mongos routers and of the config server primary
+//     (it runs the balancer that connects to all shards), and from previously discovered shard members;
+//   - other mongos routers can't be discovered, so known ones (added with the cluster) are checked and kept.
+//
+// Members of each replica set are then discovered with replSetGetStatus on any available member.
+func (s *ClustersService) discoverMongoDBCluster(ctx context.Context, cluster *models.MonitoredCluster, host string, port uint16,
+	known []*models.MonitoredClusterMember,
+) (*clusterTopology, error) {
+	output, err := s.runMongoDBAction(ctx, cluster, host, port, s.actions.StartMongoDBQueryGetCmdLineOptsAction)
+	if err != nil {
+		return nil, err
+	}
+	configSet, configHosts, err := agents.ParseMongoDBConfigDB(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if output, err = s.runMongoDBAction(ctx, cluster, host, port, s.actions.StartMongoDBQueryGetDiagnosticDataAction); err != nil {
+		return nil, err
+	}
+	replicaSets, err := agents.ParseMongoDBReplicaSets(output)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &clusterTopology{
+		Members: []*clusterMember{{Host: host, Port: port, Role: mongosRole}},
+	}
+
+	seed := net.JoinHostPort(host, strconv.Itoa(int(port)))
+	knownSets := make(map[string][]string)
+	for _, m := range known {
+		switch {
+		case m.Address == seed:
+			continue
+		case m.Role != mongosRole:
+			if m.ReplicationSet != "" {
+				knownSets[m.ReplicationSet] = append(knownSets[m.ReplicationSet], m.Address)
+			}
+			continue
+		}
+
+		router, sets, err := s.checkMongos(ctx, cluster, m.Address, configSet)
+		if err != nil {
+			return nil, err
+		}
+		if router != nil {
+			res.Members = append(res.Members, router)
+		}
+		mergeMongoDBReplicaSets(replicaSets, sets)
+	}
+
+	configMembers, err := s.discoverMongoDBReplicaSet(ctx, cluster, configSet, configHosts)
+	if err != nil {
+		return nil, err
+	}
+	res.Members = append(res.Members, configMembers...)
+	for _, m := range configMembers {
+		if m.Role != "primary" {
+			continue
+		}
+		output, err := s.runMongoDBAction(ctx, cluster, m.Host, m.Port, s.actions.StartMongoDBQueryGetDiagnosticDataAction)
+		var sets map[string][]string
+		if err == nil {
+			sets, err = agents.ParseMongoDBReplicaSets(output)
+		}
+		if err != nil {
+			s.l.Warnf("Failed to get shards from config server primary %s: %s.", m.Address(), err)
+			continue
+		}
+		mergeMongoDBReplicaSets(replicaSets, sets)
+	}
+	delete(replicaSets, configSet)
+
+	// shards that were discovered before, but are not monitored by anyone now;
+	// they are skipped if not available (for example, removed from the cluster)
+	knownOnly := make(map[string]bool)
+	for name, hosts := range knownSets {
+		if _, ok := replicaSets[name]; !ok && name != configSet {
+			replicaSets[name] = hosts
+			knownOnly[name] = true
+		}
+	}
+	if len(replicaSets) == 0 {
+		return nil, errors.New("no shards found")
+	}
+
+	setNames := make([]string, 0, len(replicaSets))
+	for name := range replicaSets {
+		setNames = append(setNames, name)
+	}
+	sort.Strings(setNames)
+
+	for _, name := range setNames {
+		members, err := s.discoverMongoDBReplicaSet(ctx, cluster, name, replicaSets[name])
+		if err != nil {
+			if knownOnly[name] && ctx.Err() == nil {
+				s.l.Warnf("Skipping previously discovered shard: %s.", err)
+				continue
+			}
+			return nil, err
+		}
+		res.Members = append(res.Members, members...)
+	}
+	return res, nil
+}
+
+// checkMongos checks that mongos router with the given address still belongs to the cluster
+// with the given config server replica set, and returns it with replica sets it monitors.
+// Unreachable router is returned in "unreachable" state to be kept until it is removed manually;
+// nil is returned for router that belongs to other cluster or is not a mongos anymore.
+func (s *ClustersService) checkMongos(ctx context.Context, cluster *models.MonitoredCluster, address, configSet string) (*clusterMember, map[string][]string, error) {
+	host, port, err := splitMongoDBAddress(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	router := &clusterMember{Host: host, Port: port, Role: mongosRole}
+
+	output, err := s.runMongoDBAction(ctx, cluster, host, port, s.actions.StartMongoDBQueryGetCmdLineOptsAction)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, nil, errors.WithStack(ctx.Err())
+		}
+		s.l.Warnf("mongos %s is not available: %s.", address, err)
+		router.State = "unreachable"
+		return router, nil, nil
+	}
+	if set, _, err := agents.ParseMongoDBConfigDB(output); err != nil || set != configSet {
+		s.l.Warnf("%s is not a mongos router of cluster %q anymore.", address, cluster.Name)
+		return nil, nil, nil
+	}
+
+	var sets map[string][]string
+	if output, err = s.runMongoDBAction(ctx, cluster, host, port, s.actions.StartMongoDBQueryGetDiagnosticDataAction); err == nil {
+		sets, err = agents.ParseMongoDBReplicaSets(output)
+	}
+	if err != nil {
+		s.l.Warnf("Failed to get shards from mongos %s: %s.", address, err)
+	}
+	return router, sets, nil
+}
+
+// mergeMongoDBReplicaSets adds replica sets and their hosts from src to dst.
+func mergeMongoDBReplicaSets(dst, src map[string][]string) {
+	for name, hosts := range src {
+		set := make(map[string]struct{}, len(dst[name])+len(hosts))
+		for _, h := range dst[name] {
+			set[h] = struct{}{}
+		}
+		for _, h := range hosts {
+			set[h] = struct{}{}
+		}
+		dst[name] = stringset.ToSlice(set)
+	}
+}
+
+// discoverMongoDBReplicaSet returns members of replica set from the first available of given hosts.
+func (s *ClustersService) discoverMongoDBReplicaSet(ctx context.Context, cluster *models.MonitoredCluster, setName string, hosts []string) ([]*clusterMember, error) {
+	errs := make([]string, 0, len(hosts))
+	for _, address := range hosts {
+		host, port, err := splitMongoDBAddress(address)
+		if err != nil {
+			return nil, err
+		}
+
+		output, err := s.runMongoDBAction(ctx, cluster, host, port, s.actions.StartMongoDBQueryReplSetGetStatusAction)
+		if err == nil {
+			var set string
+			var members []*clusterMember
+			if set, members, err = parseMongoDBReplSetMembers(output); err == nil && set != setName {
+				err = errors.Errorf("member of replica set %q", set)
+			}
+			if err == nil {
+				return members, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, errors.WithStack(ctx.Err())
+		}
+		errs = append(errs, address+": "+err.Error())
+	}
+	return nil, errors.Errorf("replica set %q: %s", setName, strings.Join(errs, "; "))
+}
+
+// runMongoDBAction starts MongoDB query Action for the given instance on cluster's pmm-agent and returns its output.
+func (s *ClustersService) runMongoDBAction(ctx context.Context, cluster *models.MonitoredCluster, host string, port uint16,
+	start func(ctx context.Context, id, pmmAgentID, dsn string, files map[string]string, tdp *models.DelimiterPair) error,
+) ([]byte, error) {
+	agent := &models.Agent{
+		AgentType:      models.MongoDBExporterType,
+		Username:       pointer.ToString(cluster.Username),
+		Password:       pointer.ToString(cluster.Password),
+		TLS:            cluster.TLS,
+		TLSSkipVerify:  cluster.TLSSkipVerify,
+		MongoDBOptions: cluster.MongoDBOptions,
+	}
+	service := &models.Service{
+		ServiceType: models.MongoDBServiceType,
+		Address:     pointer.ToString(host),
+		Port:        pointer.ToUint16(port),
+	}
+
+	return s.runAction(ctx, cluster.PMMAgentID, func(id string) error {
+		tdp := agent.TemplateDelimiters(service)
+		dsn := agent.DSN(service, time.Second, "", tdp)
+		return start(ctx, id, cluster.PMMAgentID, dsn, agent.Files(), tdp)
+	})
+}
+
+// parseMongoDBReplSetMembers returns replica set name and members from replSetGetStatus result.
+func parseMongoDBReplSetMembers(output []byte) (string, []*clusterMember, error) {
+	docs, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+	if len(docs) == 0 {
+		return "", nil, errors.New("empty replSetGetStatus result")
+	}
+
+	set, _ := docs[0]["set"].(string)
+	if set == "" {
+		return "", nil, errors.New("not a replica set member")
+	}
+
+	members, _ := docs[0]["members"].([]interface{})
+	res := make([]*clusterMember, 0, len(members))
+	for _, m := range members {
+		member, _ := m.(map[string]interface{})
+		name, _ := member["name"].(string)
+		host, port, err := splitMongoDBAddress(name)
+		if err != nil {
+			return "", nil, err
+		}
+		state, _ := member["stateStr"].(string)
+		res = append(res, &clusterMember{
+			Host:           host,
+			Port:           port,
+			ReplicationSet: set,
+			Role:           strings.ToLower(state),
+			State:          state,
+		})
+	}
+	return set, res, nil
+}
+
+// splitMongoDBAddress splits host:port address; port defaults to 27017.
+func splitMongoDBAddress(address string) (string, uint16, error) {
+	if !strings.Contains(address, ":") {
+		return address, 27017, nil
+	}
+
+	host, portS, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, errors.Errorf("invalid MongoDB address %q", address)
+	}
+	port, err := strconv.ParseUint(portS, 10, 16)
+	if err != nil {
+		return "", 0, errors.Errorf("invalid MongoDB address %q", address)
+	}
+	return host, uint16(port), nil
+}
+
+// createMongoDBClusterMemberAgents adds mongodb_exporter and optional QAN MongoDB Profiler Agent for cluster member.
+// Profiler is not added for mongos.
+func createMongoDBClusterMemberAgents(q *reform.Querier, cluster *models.MonitoredCluster, service *models.Service) error {
+	_, err := models.CreateAgent(q, models.MongoDBExporterType, &models.CreateAgentParams{
+		PMMAgentID:     cluster.PMMAgentID,
+		ServiceID:      service.ServiceID,
+		Username:       cluster.Username,
+		Password:       cluster.Password,
+		TLS:            cluster.TLS,
+		TLSSkipVerify:  cluster.TLSSkipVerify,
+		MongoDBOptions: cluster.MongoDBOptions,
+		PushMetrics:    cluster.PushMetrics,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !cluster.QAN || service.ReplicationSet == "" {
+		return nil
+	}
+	_, err = models.CreateAgent(q, models.QANMongoDBProfilerAgentType, &models.CreateAgentParams{
+		PMMAgentID:     cluster.PMMAgentID,
+		ServiceID:      service.ServiceID,
+		Username:       cluster.Username,
+		Password:       cluster.Password,
+		TLS:            cluster.TLS,
+		TLSSkipVerify:  cluster.TLSSkipVerify,
+		MongoDBOptions: cluster.MongoDBOptions,
+	})
+	return err
+}
@@ -0,0 +1,64 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"testing"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMongoDBReplSetMembers(t *testing.T) {
+	t.Parallel()
+
+	output, err := agentpb.MarshalActionQueryDocsResult([]map[string]interface{}{{
+		"set": "rs0",
+		"members": []interface{}{
+			map[string]interface{}{"name": "rs0-1:27018", "stateStr": "PRIMARY", "self": true},
+			map[string]interface{}{"name": "rs0-2:27018", "stateStr": "SECONDARY"},
+			map[string]interface{}{"name": "rs0-3", "stateStr": "ARBITER"},
+		},
+	}})
+	require.NoError(t, err)
+
+	set, members, err := parseMongoDBReplSetMembers(output)
+	require.NoError(t, err)
+	assert.Equal(t, "rs0", set)
+	expected := []*clusterMember{
+		{Host: "rs0-1", Port: 27018, ReplicationSet: "rs0", Role: "primary", State: "PRIMARY"},
+		{Host: "rs0-2", Port: 27018, ReplicationSet: "rs0", Role: "secondary", State: "SECONDARY"},
+		{Host: "rs0-3", Port: 27017, ReplicationSet: "rs0", Role: "arbiter", State: "ARBITER"},
+	}
+	assert.Equal(t, expected, members)
+}
+
+func TestMergeMongoDBReplicaSets(t *testing.T) {
+	t.Parallel()
+
+	dst := map[string][]string{"rs0": {"rs0-2:27018"}}
+	mergeMongoDBReplicaSets(dst, map[string][]string{
+		"rs0": {"rs0-1:27018", "rs0-2:27018"},
+		"rs1": {"rs1-1:27018"},
+	})
+	expected := map[string][]string{
+		"rs0": {"rs0-1:27018", "rs0-2:27018"},
+		"rs1": {"rs1-1:27018"},
+	}
+	assert.Equal(t, expected, dst)
+}
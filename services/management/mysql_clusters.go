@@ -0,0 +1,271 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	// Queries are executed by pmm-agent that adds SELECT.
+	// replication_group_members columns depend on MySQL version (there is no MEMBER_ROLE in 5.7), so all are selected.
+	mysqlGroupMembersQuery = "m.*, @@GLOBAL.group_replication_group_name AS group_name, @@GLOBAL.server_uuid AS self_uuid " +
+		"FROM performance_schema.replication_group_members m"
+	mysqlInnoDBClusterNameQuery = "cluster_name FROM mysql_innodb_cluster_metadata.clusters " +
+		"WHERE attributes->>'$.group_replication_group_name' = @@GLOBAL.group_replication_group_name"
+)
+
+// AddMySQLClusterRequest is a request of AddMySQL method.
+type AddMySQLClusterRequest struct {
+	// Used as cluster label of members' Services; defaults to InnoDB Cluster name.
+	Name string `json:"name"`
+	// One of: mysql_group_replication (default), mysql_innodb_cluster.
+	Type       string `json:"type"`
+	PMMAgentID string `json:"pmm_agent_id"`
+	// Address and port of any cluster member.
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	TLS           bool   `json:"tls"`
+	TLSSkipVerify bool   `json:"tls_skip_verify"`
+	TLSCa         string `json:"tls_ca,omitempty"`
+	TLSCert       string `json:"tls_cert,omitempty"`
+	TLSKey        string `json:"tls_key,omitempty"`
+
+	Environment  string            `json:"environment,omitempty"`
+	CustomLabels map[string]string `json:"custom_labels,omitempty"`
+	// One of: AUTO (default), PULL, PUSH.
+	MetricsMode               string `json:"metrics_mode,omitempty"`
+	QANMySQLPerfschema        bool   `json:"qan_mysql_perfschema"`
+	DisableQueryExamples      bool   `json:"disable_query_examples"`
+	TablestatsGroupTableLimit int32  `json:"tablestats_group_table_limit"`
+}
+
+// AddMySQLClusterResponse is a response of AddMySQL method.
+type AddMySQLClusterResponse struct {
+	Cluster *MonitoredCluster `json:"cluster"`
+}
+
+// AddMySQL registers MySQL Group Replication or InnoDB Cluster: discovers members from the seed instance
+// and adds Service, mysqld_exporter and optional QAN MySQL PerfSchema Agent for each of them.
+func (s *ClustersService) AddMySQL(ctx context.Context, req *AddMySQLClusterRequest) (*AddMySQLClusterResponse, error) {
+	clusterType := models.MySQLGroupReplicationClusterType
+	if req.Type != "" {
+		clusterType = models.MonitoredClusterType(req.Type)
+	}
+	if clusterType.ServiceType() != models.MySQLServiceType {
+		return nil, status.Errorf(codes.InvalidArgument, "Unsupported MySQL cluster type %q.", req.Type)
+	}
+
+	metricsMode, err := clusterMetricsMode(s.db.Querier, req.MetricsMode, req.PMMAgentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// tweak according to MySQLService.Add
+	tablestatsGroupTableLimit := req.TablestatsGroupTableLimit
+	if tablestatsGroupTableLimit == 0 {
+		tablestatsGroupTableLimit = defaultTablestatsGroupTableLimit
+	}
+	if tablestatsGroupTableLimit < 0 {
+		tablestatsGroupTableLimit = -1
+	}
+
+	params := &models.MonitoredClusterParams{
+		Name:                           req.Name,
+		Type:                           clusterType,
+		PMMAgentID:                     req.PMMAgentID,
+		SeedAddress:                    req.Address,
+		SeedPort:                       req.Port,
+		Username:                       req.Username,
+		Password:                       req.Password,
+		TLS:                            req.TLS,
+		TLSSkipVerify:                  req.TLSSkipVerify,
+		Environment:                    req.Environment,
+		CustomLabels:                   req.CustomLabels,
+		PushMetrics:                    isPushMode(metricsMode),
+		QAN:                            req.QANMySQLPerfschema,
+		QueryExamplesDisabled:          req.DisableQueryExamples,
+		TableCountTablestatsGroupLimit: tablestatsGroupTableLimit,
+	}
+	if req.TLSCa != "" || req.TLSCert != "" || req.TLSKey != "" {
+		params.MySQLOptions = &models.MySQLOptions{
+			TLSCa:   req.TLSCa,
+			TLSCert: req.TLSCert,
+			TLSKey:  req.TLSKey,
+		}
+	}
+
+	cluster, err := s.addCluster(ctx, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &AddMySQLClusterResponse{Cluster: cluster}, nil
+}
+
+// discoverMySQLCluster discovers Group Replication members from the given instance.
+// The instance should be an ONLINE member of the group so that its view of the group is current.
+func (s *ClustersService) discoverMySQLCluster(ctx context.Context, cluster *models.MonitoredCluster, host string, port uint16) (*clusterTopology, error) {
+	agent := &models.Agent{
+		AgentType:     models.MySQLdExporterType,
+		Username:      pointer.ToString(cluster.Username),
+		Password:      pointer.ToString(cluster.Password),
+		TLS:           cluster.TLS,
+		TLSSkipVerify: cluster.TLSSkipVerify,
+		MySQLOptions:  cluster.MySQLOptions,
+	}
+	service := &models.Service{
+		ServiceType: models.MySQLServiceType,
+		Address:     pointer.ToString(host),
+		Port:        pointer.ToUint16(port),
+	}
+	query := func(query string) ([]byte, error) {
+		return s.runAction(ctx, cluster.PMMAgentID, func(id string) error {
+			dsn := agent.DSN(service, time.Second, "", nil)
+			return s.actions.StartMySQLQuerySelectAction(ctx, id, cluster.PMMAgentID, dsn, query, agent.Files(), agent.TemplateDelimiters(service), agent.TLSSkipVerify)
+		})
+	}
+
+	output, err := query(mysqlGroupMembersQuery)
+	if err != nil {
+		return nil, err
+	}
+	topology, err := parseMySQLGroupMembers(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if cluster.Type == models.MySQLInnoDBClusterType && cluster.Name == "" {
+		if output, err = query(mysqlInnoDBClusterNameQuery); err != nil {
+			return nil, err
+		}
+		if topology.Name, err = parseMySQLInnoDBClusterName(output); err != nil {
+			return nil, err
+		}
+	}
+
+	return topology, nil
+}
+
+// parseMySQLGroupMembers returns Group Replication members from replication_group_members rows.
+func parseMySQLGroupMembers(output []byte) (*clusterTopology, error) {
+	rows, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	str := func(row map[string]interface{}, column string) string {
+		if row[column] == nil {
+			return ""
+		}
+		return fmt.Sprint(row[column])
+	}
+
+	res := new(clusterTopology)
+	var selfState string
+	for _, row := range rows {
+		if str(row, "MEMBER_ID") == str(row, "self_uuid") {
+			selfState = str(row, "MEMBER_STATE")
+		}
+
+		// OFFLINE instance reports itself without host and port
+		host := str(row, "MEMBER_HOST")
+		if host == "" {
+			continue
+		}
+		port, err := strconv.ParseUint(str(row, "MEMBER_PORT"), 10, 16)
+		if err != nil {
+			return nil, errors.Errorf("unexpected replication_group_members row: %v", row)
+		}
+		res.Members = append(res.Members, &clusterMember{
+			Host:           host,
+			Port:           uint16(port),
+			ReplicationSet: str(row, "group_name"),
+			Role:           strings.ToLower(str(row, "MEMBER_ROLE")),
+			State:          str(row, "MEMBER_STATE"),
+		})
+	}
+
+	switch selfState {
+	case "ONLINE":
+		return res, nil
+	case "":
+		return nil, errors.New("Group Replication is not running")
+	default:
+		return nil, errors.Errorf("member is %s", selfState)
+	}
+}
+
+// parseMySQLInnoDBClusterName returns InnoDB Cluster name from metadata rows.
+func parseMySQLInnoDBClusterName(output []byte) (string, error) {
+	rows, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(rows) == 0 || rows[0]["cluster_name"] == nil {
+		return "", errors.New("InnoDB Cluster metadata not found")
+	}
+	return fmt.Sprint(rows[0]["cluster_name"]), nil
+}
+
+// createMySQLClusterMemberAgents adds mysqld_exporter and optional QAN MySQL PerfSchema Agent for cluster member.
+func createMySQLClusterMemberAgents(q *reform.Querier, cluster *models.MonitoredCluster, service *models.Service) error {
+	_, err := models.CreateAgent(q, models.MySQLdExporterType, &models.CreateAgentParams{
+		PMMAgentID:                     cluster.PMMAgentID,
+		ServiceID:                      service.ServiceID,
+		Username:                       cluster.Username,
+		Password:                       cluster.Password,
+		TLS:                            cluster.TLS,
+		TLSSkipVerify:                  cluster.TLSSkipVerify,
+		MySQLOptions:                   cluster.MySQLOptions,
+		TableCountTablestatsGroupLimit: cluster.TableCountTablestatsGroupLimit,
+		PushMetrics:                    cluster.PushMetrics,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !cluster.QAN {
+		return nil
+	}
+	_, err = models.CreateAgent(q, models.QANMySQLPerfSchemaAgentType, &models.CreateAgentParams{
+		PMMAgentID:            cluster.PMMAgentID,
+		ServiceID:             service.ServiceID,
+		Username:              cluster.Username,
+		Password:              cluster.Password,
+		TLS:                   cluster.TLS,
+		TLSSkipVerify:         cluster.TLSSkipVerify,
+		MySQLOptions:          cluster.MySQLOptions,
+		QueryExamplesDisabled: cluster.QueryExamplesDisabled,
+	})
+	return err
+}
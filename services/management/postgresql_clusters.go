@@ -0,0 +1,335 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/pkg/errors"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+const (
+	// Queries are executed by pmm-agent that adds SELECT.
+	postgreSQLRecoveryQuery    = "pg_is_in_recovery() AS in_recovery, host(inet_server_addr()) AS server_addr"
+	postgreSQLReplicationQuery = "host(client_addr) AS client_addr, application_name, state, sync_state FROM pg_stat_replication"
+
+	postgreSQLPrimaryRole = "primary"
+	postgreSQLStandbyRole = "standby"
+
+	patroniRequestTimeout = 10 * time.Second
+)
+
+// AddPostgreSQLClusterRequest is a request of AddPostgreSQL method.
+type AddPostgreSQLClusterRequest struct {
+	// Used as cluster label of members' Services; defaults to Patroni scope.
+	Name       string `json:"name"`
+	PMMAgentID string `json:"pmm_agent_id"`
+	// Address and port of the primary; standbys are expected to listen on the same port.
+	Address string `json:"address"`
+	Port    uint16 `json:"port"`
+	// Patroni REST API URL of any cluster member, like http://pg-1:8008.
+	// If set, members are discovered from Patroni instead of the primary.
+	PatroniURL string `json:"patroni_url,omitempty"`
+
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	TLS           bool   `json:"tls"`
+	TLSSkipVerify bool   `json:"tls_skip_verify"`
+	TLSCa         string `json:"tls_ca,omitempty"`
+	TLSCert       string `json:"tls_cert,omitempty"`
+	TLSKey        string `json:"tls_key,omitempty"`
+
+	Environment  string            `json:"environment,omitempty"`
+	CustomLabels map[string]string `json:"custom_labels,omitempty"`
+	// One of: AUTO (default), PULL, PUSH.
+	MetricsMode                    string `json:"metrics_mode,omitempty"`
+	QANPostgreSQLPgStatementsAgent bool   `json:"qan_postgresql_pgstatements_agent"`
+}
+
+// AddPostgreSQLClusterResponse is a response of AddPostgreSQL method.
+type AddPostgreSQLClusterResponse struct {
+	Cluster *MonitoredCluster `json:"cluster"`
+}
+
+// patroniClusterStatus is a subset of Patroni GET /cluster response.
+type patroniClusterStatus struct {
+	Scope   string `json:"scope"`
+	Members []struct {
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+		State string `json:"state"`
+		Host  string `json:"host"`
+		Port  uint16 `json:"port"`
+	} `json:"members"`
+}
+
+// AddPostgreSQL registers PostgreSQL streaming replication cluster: discovers standbys from the primary or Patroni
+// and adds Service, postgres_exporter and optional QAN PostgreSQL PgStatements Agent for each member.
+func (s *ClustersService) AddPostgreSQL(ctx context.Context, req *AddPostgreSQLClusterRequest) (*AddPostgreSQLClusterResponse, error) {
+	metricsMode, err := clusterMetricsMode(s.db.Querier, req.MetricsMode, req.PMMAgentID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := &models.MonitoredClusterParams{
+		Name:          req.Name,
+		Type:          models.PostgreSQLReplicationClusterType,
+		PMMAgentID:    req.PMMAgentID,
+		SeedAddress:   req.Address,
+		SeedPort:      req.Port,
+		Username:      req.Username,
+		Password:      req.Password,
+		TLS:           req.TLS,
+		TLSSkipVerify: req.TLSSkipVerify,
+		PatroniURL:    req.PatroniURL,
+		Environment:   req.Environment,
+		CustomLabels:  req.CustomLabels,
+		PushMetrics:   isPushMode(metricsMode),
+		QAN:           req.QANPostgreSQLPgStatementsAgent,
+	}
+	if req.TLSCa != "" || req.TLSCert != "" || req.TLSKey != "" {
+		params.PostgreSQLOptions = &models.PostgreSQLOptions{
+			SSLCa:   req.TLSCa,
+			SSLCert: req.TLSCert,
+			SSLKey:  req.TLSKey,
+		}
+	}
+
+	cluster, err := s.addCluster(ctx, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &AddPostgreSQLClusterResponse{Cluster: cluster}, nil
+}
+
+// discoverPostgreSQLCluster discovers standbys from pg_stat_replication of the given primary.
+// Standbys are expected to listen on the same port as the primary; cascading standbys are not discovered.
+func (s *ClustersService) discoverPostgreSQLCluster(ctx context.Context, cluster *models.MonitoredCluster, host string, port uint16) (*clusterTopology, error) {
+	// PostgreSQL query Action does not accept TLS files, so only sslmode is used
+	agent := &models.Agent{
+		AgentType:     models.PostgresExporterType,
+		Username:      pointer.ToString(cluster.Username),
+		Password:      pointer.ToString(cluster.Password),
+		TLS:           cluster.TLS,
+		TLSSkipVerify: cluster.TLSSkipVerify,
+	}
+	service := &models.Service{
+		ServiceType: models.PostgreSQLServiceType,
+		Address:     pointer.ToString(host),
+		Port:        pointer.ToUint16(port),
+	}
+	query := func(query string) ([]byte, error) {
+		return s.runAction(ctx, cluster.PMMAgentID, func(id string) error {
+			dsn := agent.DSN(service, time.Second, "postgres", nil)
+			return s.actions.StartPostgreSQLQuerySelectAction(ctx, id, cluster.PMMAgentID, dsn, query)
+		})
+	}
+
+	output, err := query(postgreSQLRecoveryQuery)
+	if err != nil {
+		return nil, err
+	}
+	primaryHost, err := parsePostgreSQLRecovery(output)
+	if err != nil {
+		return nil, err
+	}
+	// connected via Unix socket or proxy
+	if primaryHost == "" {
+		primaryHost = host
+	}
+
+	if output, err = query(postgreSQLReplicationQuery); err != nil {
+		return nil, err
+	}
+	standbys, err := parsePostgreSQLReplication(output, port, cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &clusterTopology{
+		Members: []*clusterMember{{
+			Host:           primaryHost,
+			Port:           port,
+			ReplicationSet: cluster.Name,
+			Role:           postgreSQLPrimaryRole,
+		}},
+	}
+	res.Members = append(res.Members, standbys...)
+	return res, nil
+}
+
+// discoverPatroniCluster discovers cluster members from Patroni REST API.
+func (s *ClustersService) discoverPatroniCluster(ctx context.Context, cluster *models.MonitoredCluster) (*clusterTopology, error) {
+	ctx, cancel := context.WithTimeout(ctx, patroniRequestTimeout)
+	defer cancel()
+
+	u := strings.TrimSuffix(cluster.PatroniURL, "/") + "/cluster"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Patroni returned %s: %s", resp.Status, b)
+	}
+
+	return parsePatroniCluster(b, cluster.Name)
+}
+
+// parsePatroniCluster returns cluster members from Patroni GET /cluster response.
+// Patroni scope is used as replication set if cluster name is not known yet.
+func parsePatroniCluster(b []byte, clusterName string) (*clusterTopology, error) {
+	var status patroniClusterStatus
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil, errors.Wrap(err, "failed to decode Patroni cluster status")
+	}
+
+	replicationSet := status.Scope
+	if replicationSet == "" {
+		replicationSet = clusterName
+	}
+
+	res := &clusterTopology{
+		Name:    status.Scope,
+		Members: make([]*clusterMember, 0, len(status.Members)),
+	}
+	var hasPrimary bool
+	for _, m := range status.Members {
+		if m.Host == "" || m.Port == 0 {
+			return nil, errors.Errorf("Patroni member %q has no host or port", m.Name)
+		}
+
+		role := postgreSQLStandbyRole
+		switch m.Role {
+		case "leader", "master", "primary":
+			role = postgreSQLPrimaryRole
+			hasPrimary = true
+		}
+		res.Members = append(res.Members, &clusterMember{
+			Host:           m.Host,
+			Port:           m.Port,
+			ReplicationSet: replicationSet,
+			Role:           role,
+			State:          m.State,
+		})
+	}
+
+	// keep existing members and their roles during leader election
+	if !hasPrimary {
+		return nil, errors.New("Patroni cluster has no leader")
+	}
+	return res, nil
+}
+
+// parsePostgreSQLRecovery returns the server address if it is a primary.
+func parsePostgreSQLRecovery(output []byte) (string, error) {
+	rows, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if len(rows) != 1 {
+		return "", errors.Errorf("unexpected pg_is_in_recovery result: %v", rows)
+	}
+
+	if inRecovery, _ := rows[0]["in_recovery"].(bool); inRecovery {
+		return "", errors.New("not a primary")
+	}
+	if rows[0]["server_addr"] == nil {
+		return "", nil
+	}
+	return fmt.Sprint(rows[0]["server_addr"]), nil
+}
+
+// parsePostgreSQLReplication returns standbys from pg_stat_replication rows.
+func parsePostgreSQLReplication(output []byte, port uint16, replicationSet string) ([]*clusterMember, error) {
+	rows, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := make([]*clusterMember, 0, len(rows))
+	for _, row := range rows {
+		// pg_basebackup and other clients connected via Unix socket
+		if row["client_addr"] == nil {
+			continue
+		}
+
+		state := fmt.Sprint(row["state"])
+		if row["sync_state"] != nil {
+			state += "/" + fmt.Sprint(row["sync_state"])
+		}
+		res = append(res, &clusterMember{
+			Host:           fmt.Sprint(row["client_addr"]),
+			Port:           port,
+			ReplicationSet: replicationSet,
+			Role:           postgreSQLStandbyRole,
+			State:          state,
+		})
+	}
+	return res, nil
+}
+
+// createPostgreSQLClusterMemberAgents adds postgres_exporter and optional QAN PostgreSQL PgStatements Agent for cluster member.
+func createPostgreSQLClusterMemberAgents(q *reform.Querier, cluster *models.MonitoredCluster, service *models.Service) error {
+	_, err := models.CreateAgent(q, models.PostgresExporterType, &models.CreateAgentParams{
+		PMMAgentID:        cluster.PMMAgentID,
+		ServiceID:         service.ServiceID,
+		Username:          cluster.Username,
+		Password:          cluster.Password,
+		TLS:               cluster.TLS,
+		TLSSkipVerify:     cluster.TLSSkipVerify,
+		PostgreSQLOptions: cluster.PostgreSQLOptions,
+		PushMetrics:       cluster.PushMetrics,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !cluster.QAN {
+		return nil
+	}
+	_, err = models.CreateAgent(q, models.QANPostgreSQLPgStatementsAgentType, &models.CreateAgentParams{
+		PMMAgentID:        cluster.PMMAgentID,
+		ServiceID:         service.ServiceID,
+		Username:          cluster.Username,
+		Password:          cluster.Password,
+		TLS:               cluster.TLS,
+		TLSSkipVerify:     cluster.TLSSkipVerify,
+		PostgreSQLOptions: cluster.PostgreSQLOptions,
+	})
+	return err
+}
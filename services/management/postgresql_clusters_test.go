@@ -0,0 +1,229 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+	"github.com/percona/pmm-managed/utils/testdb"
+)
+
+func TestParsePostgreSQLReplication(t *testing.T) {
+	t.Parallel()
+
+	output, err := agentpb.MarshalActionQuerySQLResult([]string{"in_recovery", "server_addr"}, [][]interface{}{
+		{false, "10.0.0.1"},
+	})
+	require.NoError(t, err)
+	host, err := parsePostgreSQLRecovery(output)
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", host)
+
+	output, err = agentpb.MarshalActionQuerySQLResult([]string{"in_recovery", "server_addr"}, [][]interface{}{
+		{true, "10.0.0.2"},
+	})
+	require.NoError(t, err)
+	_, err = parsePostgreSQLRecovery(output)
+	assert.EqualError(t, err, "not a primary")
+
+	output, err = agentpb.MarshalActionQuerySQLResult([]string{"client_addr", "application_name", "state", "sync_state"}, [][]interface{}{
+		{"10.0.0.2", "pg-2", "streaming", "sync"},
+		{"10.0.0.3", "pg-3", "catchup", "async"},
+		{nil, "pg_basebackup", "backup", "async"},
+	})
+	require.NoError(t, err)
+	standbys, err := parsePostgreSQLReplication(output, 5432, "pg")
+	require.NoError(t, err)
+	expected := []*clusterMember{
+		{Host: "10.0.0.2", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/sync"},
+		{Host: "10.0.0.3", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "catchup/async"},
+	}
+	assert.Equal(t, expected, standbys)
+}
+
+func TestDiscoverPatroniCluster(t *testing.T) {
+	t.Parallel()
+
+	leader := "pg-1"
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/cluster" {
+			http.NotFound(rw, req)
+			return
+		}
+
+		members := []map[string]interface{}{
+			{"name": "pg-1", "host": "10.0.0.1", "port": 5432, "role": "replica", "state": "streaming"},
+			{"name": "pg-2", "host": "10.0.0.2", "port": 5432, "role": "sync_standby", "state": "streaming"},
+		}
+		for _, m := range members {
+			if m["name"] == leader {
+				m["role"] = "leader"
+				m["state"] = "running"
+			}
+		}
+		require.NoError(t, json.NewEncoder(rw).Encode(map[string]interface{}{"scope": "pg-ha", "members": members}))
+	}))
+	defer ts.Close()
+
+	s := NewClustersService(nil, nil, nil, nil, 0)
+	cluster := &models.MonitoredCluster{
+		Type:       models.PostgreSQLReplicationClusterType,
+		PatroniURL: ts.URL + "/",
+	}
+
+	topology, err := s.discoverCluster(context.Background(), cluster, nil)
+	require.NoError(t, err)
+	expected := &clusterTopology{
+		Name: "pg-ha",
+		Members: []*clusterMember{
+			{Host: "10.0.0.1", Port: 5432, ReplicationSet: "pg-ha", Role: "primary", State: "running"},
+			{Host: "10.0.0.2", Port: 5432, ReplicationSet: "pg-ha", Role: "standby", State: "streaming"},
+		},
+	}
+	assert.Equal(t, expected, topology)
+
+	// after failover
+	leader = "pg-2"
+	topology, err = s.discoverCluster(context.Background(), cluster, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "standby", topology.Members[0].Role)
+	assert.Equal(t, "primary", topology.Members[1].Role)
+
+	// during election
+	leader = ""
+	_, err = s.discoverCluster(context.Background(), cluster, nil)
+	assert.EqualError(t, err, "Patroni cluster has no leader")
+
+	cluster.PatroniURL = ts.URL + "/patroni"
+	_, err = s.discoverCluster(context.Background(), cluster, nil)
+	assert.Contains(t, err.Error(), "Patroni returned 404 Not Found")
+}
+
+func TestPostgreSQLClusterMembers(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SetupFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+	ctx := logger.Set(context.Background(), t.Name())
+
+	state := &mockAgentsStateUpdater{}
+	state.Test(t)
+	state.On("RequestStateUpdate", mock.Anything, models.PMMServerAgentID).Return()
+	defer state.AssertExpectations(t)
+
+	vmdb := &mockPrometheusService{}
+	vmdb.Test(t)
+	vmdb.On("RequestConfigurationUpdate").Return()
+	defer vmdb.AssertExpectations(t)
+
+	var topology *clusterTopology
+	s := NewClustersService(db, &mockActionsService{}, state, vmdb, 0)
+	s.discover = func(ctx context.Context, cluster *models.MonitoredCluster, known []*models.MonitoredClusterMember) (*clusterTopology, error) {
+		return topology, nil
+	}
+
+	// role labels of members' Services by host
+	roles := func(t *testing.T) map[string]string {
+		t.Helper()
+
+		res := make(map[string]string)
+		for _, host := range []string{"pg-1", "pg-2", "pg-3"} {
+			service, err := models.FindServiceByName(db.Querier, "pg/"+host+":5432")
+			require.NoError(t, err)
+			customLabels, err := service.GetCustomLabels()
+			require.NoError(t, err)
+			res[host] = customLabels[clusterRoleLabel]
+		}
+		return res
+	}
+
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "pg-1", Port: 5432, ReplicationSet: "pg", Role: "primary"},
+		{Host: "pg-2", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+		{Host: "pg-3", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+	}}
+	addRes, err := s.AddPostgreSQL(ctx, &AddPostgreSQLClusterRequest{
+		Name:       "pg",
+		PMMAgentID: models.PMMServerAgentID,
+		Address:    "pg-1",
+		Port:       5432,
+		Username:   "pmm",
+		Password:   "pmm",
+	})
+	require.NoError(t, err)
+	cluster := addRes.Cluster
+	require.Len(t, cluster.Members, 3)
+	assert.Equal(t, map[string]string{"pg-1": "primary", "pg-2": "standby", "pg-3": "standby"}, roles(t))
+
+	// pg-3 is restarting and is absent in pg_stat_replication for one sync
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "pg-1", Port: 5432, ReplicationSet: "pg", Role: "primary"},
+		{Host: "pg-2", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+	}}
+	syncRes, err := s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Missing: 1}, syncRes)
+	assert.Equal(t, map[string]string{"pg-1": "primary", "pg-2": "standby", "pg-3": ""}, roles(t))
+
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "pg-1", Port: 5432, ReplicationSet: "pg", Role: "primary"},
+		{Host: "pg-2", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+		{Host: "pg-3", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+	}}
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Updated: 1}, syncRes)
+	assert.Equal(t, map[string]string{"pg-1": "primary", "pg-2": "standby", "pg-3": "standby"}, roles(t))
+
+	// failover to pg-2; failed primary is not labeled as primary
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "pg-2", Port: 5432, ReplicationSet: "pg", Role: "primary"},
+		{Host: "pg-3", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+	}}
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Updated: 1, Missing: 1}, syncRes)
+	assert.Equal(t, map[string]string{"pg-1": "", "pg-2": "primary", "pg-3": "standby"}, roles(t))
+
+	// old primary is back as a standby
+	topology = &clusterTopology{Members: []*clusterMember{
+		{Host: "pg-1", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+		{Host: "pg-2", Port: 5432, ReplicationSet: "pg", Role: "primary"},
+		{Host: "pg-3", Port: 5432, ReplicationSet: "pg", Role: "standby", State: "streaming/async"},
+	}}
+	syncRes, err = s.Sync(ctx, &SyncClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncClusterResponse{Updated: 1}, syncRes)
+	assert.Equal(t, map[string]string{"pg-1": "standby", "pg-2": "primary", "pg-3": "standby"}, roles(t))
+
+	_, err = s.Remove(ctx, &RemoveClusterRequest{ClusterID: cluster.ClusterID})
+	require.NoError(t, err)
+}
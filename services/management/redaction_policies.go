@@ -0,0 +1,223 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// RedactionPoliciesService manages QAN redaction policies applied to query examples and fingerprints
+// before they are sent to QAN storage.
+type RedactionPoliciesService struct {
+	db *reform.DB
+}
+
+// RedactionFilter selects Services by label.
+type RedactionFilter struct {
+	Type  string `json:"type"` // EQUAL or REGEX
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// RedactionRule represents a single masking rule.
+type RedactionRule struct {
+	Type        string `json:"type"` // regex or literal
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement,omitempty"` // *** by default; may use $1-style references for regex rules
+}
+
+// RedactionPolicy represents QAN redaction policy in JSON API requests and responses.
+type RedactionPolicy struct {
+	PolicyID  string             `json:"policy_id,omitempty"`
+	Name      string             `json:"name"`
+	Filters   []*RedactionFilter `json:"filters,omitempty"` // all Services if empty
+	Rules     []*RedactionRule   `json:"rules"`
+	Disabled  bool               `json:"disabled,omitempty"`
+	CreatedAt time.Time          `json:"created_at,omitempty"`
+}
+
+// ListRedactionPoliciesRequest is a request of List method.
+type ListRedactionPoliciesRequest struct{}
+
+// ListRedactionPoliciesResponse is a response of List method.
+type ListRedactionPoliciesResponse struct {
+	Policies []*RedactionPolicy `json:"policies"`
+}
+
+// AddRedactionPolicyRequest is a request of Add method.
+type AddRedactionPolicyRequest struct {
+	RedactionPolicy
+}
+
+// AddRedactionPolicyResponse is a response of Add method.
+type AddRedactionPolicyResponse struct {
+	PolicyID string `json:"policy_id"`
+}
+
+// ChangeRedactionPolicyRequest is a request of Change method; all fields are replaced.
+type ChangeRedactionPolicyRequest struct {
+	RedactionPolicy
+}
+
+// ChangeRedactionPolicyResponse is a response of Change method.
+type ChangeRedactionPolicyResponse struct{}
+
+// RemoveRedactionPolicyRequest is a request of Remove method.
+type RemoveRedactionPolicyRequest struct {
+	PolicyID string `json:"policy_id"`
+}
+
+// RemoveRedactionPolicyResponse is a response of Remove method.
+type RemoveRedactionPolicyResponse struct{}
+
+// NewRedactionPoliciesService creates new RedactionPoliciesService.
+func NewRedactionPoliciesService(db *reform.DB) *RedactionPoliciesService {
+	return &RedactionPoliciesService{
+		db: db,
+	}
+}
+
+// List returns all QAN redaction policies.
+func (s *RedactionPoliciesService) List(ctx context.Context, req *ListRedactionPoliciesRequest) (*ListRedactionPoliciesResponse, error) {
+	policies, err := models.FindRedactionPolicies(s.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListRedactionPoliciesResponse{Policies: make([]*RedactionPolicy, len(policies))}
+	for i, p := range policies {
+		res.Policies[i] = convertRedactionPolicy(p)
+	}
+	return res, nil
+}
+
+// Add creates a new QAN redaction policy. It is applied to data received after that.
+func (s *RedactionPoliciesService) Add(ctx context.Context, req *AddRedactionPolicyRequest) (*AddRedactionPolicyResponse, error) {
+	params, err := convertRedactionPolicyParams(&req.RedactionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy *models.RedactionPolicy
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		policy, err = models.CreateRedactionPolicy(tx.Querier, params)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+	return &AddRedactionPolicyResponse{PolicyID: policy.ID}, nil
+}
+
+// Change replaces QAN redaction policy parameters.
+func (s *RedactionPoliciesService) Change(ctx context.Context, req *ChangeRedactionPolicyRequest) (*ChangeRedactionPolicyResponse, error) {
+	params, err := convertRedactionPolicyParams(&req.RedactionPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		_, err := models.ChangeRedactionPolicy(tx.Querier, req.PolicyID, params)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+	return &ChangeRedactionPolicyResponse{}, nil
+}
+
+// Remove removes QAN redaction policy.
+func (s *RedactionPoliciesService) Remove(ctx context.Context, req *RemoveRedactionPolicyRequest) (*RemoveRedactionPolicyResponse, error) {
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		return models.RemoveRedactionPolicy(tx.Querier, req.PolicyID)
+	})
+	if e != nil {
+		return nil, e
+	}
+	return &RemoveRedactionPolicyResponse{}, nil
+}
+
+var redactionFilterTypes = map[string]models.FilterType{
+	"EQUAL": models.Equal,
+	"REGEX": models.Regex,
+}
+
+func convertRedactionPolicyParams(p *RedactionPolicy) (*models.RedactionPolicyParams, error) {
+	params := &models.RedactionPolicyParams{
+		Name:     p.Name,
+		Filters:  make(models.Filters, len(p.Filters)),
+		Rules:    make(models.RedactionRules, len(p.Rules)),
+		Disabled: p.Disabled,
+	}
+
+	for i, f := range p.Filters {
+		t, ok := redactionFilterTypes[f.Type]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown filter type %q.", f.Type)
+		}
+		params.Filters[i] = models.Filter{Type: t, Key: f.Key, Val: f.Value}
+	}
+
+	for i, r := range p.Rules {
+		params.Rules[i] = models.RedactionRule{
+			Type:        models.RedactionRuleType(r.Type),
+			Pattern:     r.Pattern,
+			Replacement: r.Replacement,
+		}
+	}
+
+	return params, nil
+}
+
+func convertRedactionPolicy(p *models.RedactionPolicy) *RedactionPolicy {
+	res := &RedactionPolicy{
+		PolicyID:  p.ID,
+		Name:      p.Name,
+		Filters:   make([]*RedactionFilter, len(p.Filters)),
+		Rules:     make([]*RedactionRule, len(p.Rules)),
+		Disabled:  p.Disabled,
+		CreatedAt: p.CreatedAt,
+	}
+
+	for i, f := range p.Filters {
+		var t string
+		for name, ft := range redactionFilterTypes {
+			if ft == f.Type {
+				t = name
+			}
+		}
+		res.Filters[i] = &RedactionFilter{Type: t, Key: f.Key, Value: f.Val}
+	}
+
+	for i, r := range p.Rules {
+		res.Rules[i] = &RedactionRule{
+			Type:        string(r.Type),
+			Pattern:     r.Pattern,
+			Replacement: r.Replacement,
+		}
+	}
+
+	return res
+}
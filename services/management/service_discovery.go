@@ -0,0 +1,628 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/discovery"
+)
+
+const serviceDiscoveryInterval = time.Minute
+
+// reservedLabels are standard labels of Nodes, Services and Agents; discovered labels with those names are ignored.
+var reservedLabels = map[string]struct{}{
+	"job":             {},
+	"instance":        {},
+	"node_id":         {},
+	"node_name":       {},
+	"node_type":       {},
+	"machine_id":      {},
+	"container_id":    {},
+	"container_name":  {},
+	"node_model":      {},
+	"region":          {},
+	"az":              {},
+	"service_id":      {},
+	"service_name":    {},
+	"service_type":    {},
+	"environment":     {},
+	"cluster":         {},
+	"replication_set": {},
+	"external_group":  {},
+	"agent_id":        {},
+	"agent_type":      {},
+}
+
+// ServiceDiscoveryService reconciles targets discovered by service discovery sources
+// into External Services and external exporters.
+type ServiceDiscoveryService struct {
+	db       *reform.DB
+	vmdb     prometheusService
+	discover func(ctx context.Context, source *models.ServiceDiscoverySource) ([]*discovery.Target, error)
+	syncCh   chan struct{}
+	l        *logrus.Entry
+}
+
+// NewServiceDiscoveryService creates new ServiceDiscoveryService.
+func NewServiceDiscoveryService(db *reform.DB, vmdb prometheusService) *ServiceDiscoveryService {
+	return &ServiceDiscoveryService{
+		db:       db,
+		vmdb:     vmdb,
+		discover: discovery.Discover,
+		syncCh:   make(chan struct{}, 1),
+		l:        logrus.WithField("component", "service-discovery"),
+	}
+}
+
+// FileSDOptions represents file_sd source options in JSON API requests and responses.
+type FileSDOptions struct {
+	// Absolute path to file_sd JSON file; may be a glob pattern.
+	Path string `json:"path"`
+}
+
+// ConsulOptions represents Consul source options in JSON API requests and responses.
+// Token is never returned; token_set is returned instead.
+type ConsulOptions struct {
+	Address               string   `json:"address"`
+	Scheme                string   `json:"scheme,omitempty"`
+	Datacenter            string   `json:"datacenter,omitempty"`
+	Token                 string   `json:"token,omitempty"`
+	TokenSet              bool     `json:"token_set,omitempty"`
+	TLSInsecureSkipVerify bool     `json:"tls_insecure_skip_verify,omitempty"`
+	Services              []string `json:"services,omitempty"`
+	Tags                  []string `json:"tags,omitempty"`
+}
+
+// KubernetesOptions represents Kubernetes source options in JSON API requests and responses.
+// Bearer token is never returned; bearer_token_set is returned instead.
+type KubernetesOptions struct {
+	// In-cluster configuration is used if empty.
+	APIServer             string   `json:"api_server,omitempty"`
+	BearerToken           string   `json:"bearer_token,omitempty"`
+	BearerTokenSet        bool     `json:"bearer_token_set,omitempty"`
+	TLSCa                 string   `json:"tls_ca,omitempty"`
+	TLSInsecureSkipVerify bool     `json:"tls_insecure_skip_verify,omitempty"`
+	Namespaces            []string `json:"namespaces,omitempty"`
+}
+
+// ServiceDiscoverySource represents service discovery source in JSON API requests and responses.
+type ServiceDiscoverySource struct {
+	SourceID string `json:"source_id,omitempty"`
+	Name     string `json:"name"`
+	// One of: file_sd, consul, kubernetes; only options of that type should be set.
+	Type       string             `json:"type"`
+	FileSD     *FileSDOptions     `json:"file_sd,omitempty"`
+	Consul     *ConsulOptions     `json:"consul,omitempty"`
+	Kubernetes *KubernetesOptions `json:"kubernetes,omitempty"`
+	// Discovered labels mapped to Service's environment and cluster ("environment" and "cluster" by default).
+	EnvironmentLabel string `json:"environment_label,omitempty"`
+	ClusterLabel     string `json:"cluster_label,omitempty"`
+	// External group of created Services ("external" by default).
+	ExternalGroup string `json:"external_group,omitempty"`
+	Disabled      bool   `json:"disabled,omitempty"`
+
+	Targets    int        `json:"targets"`
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+}
+
+// ListServiceDiscoverySourcesRequest is a request of List method.
+type ListServiceDiscoverySourcesRequest struct{}
+
+// ListServiceDiscoverySourcesResponse is a response of List method.
+type ListServiceDiscoverySourcesResponse struct {
+	Sources []*ServiceDiscoverySource `json:"sources"`
+}
+
+// AddServiceDiscoverySourceRequest is a request of Add method.
+type AddServiceDiscoverySourceRequest struct {
+	ServiceDiscoverySource
+}
+
+// AddServiceDiscoverySourceResponse is a response of Add method.
+type AddServiceDiscoverySourceResponse struct {
+	SourceID string `json:"source_id"`
+}
+
+// ChangeServiceDiscoverySourceRequest is a request of Change method; all fields are replaced,
+// except empty Consul token and Kubernetes bearer token which keep existing values.
+type ChangeServiceDiscoverySourceRequest struct {
+	ServiceDiscoverySource
+}
+
+// ChangeServiceDiscoverySourceResponse is a response of Change method.
+type ChangeServiceDiscoverySourceResponse struct{}
+
+// RemoveServiceDiscoverySourceRequest is a request of Remove method.
+type RemoveServiceDiscoverySourceRequest struct {
+	SourceID string `json:"source_id"`
+}
+
+// RemoveServiceDiscoverySourceResponse is a response of Remove method.
+type RemoveServiceDiscoverySourceResponse struct{}
+
+// SyncServiceDiscoverySourceRequest is a request of Sync method.
+type SyncServiceDiscoverySourceRequest struct {
+	SourceID string `json:"source_id"`
+}
+
+// SyncServiceDiscoverySourceResponse is a response of Sync method.
+type SyncServiceDiscoverySourceResponse struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+	// Errors of targets that were not added or updated, by target URL.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// List returns all service discovery sources.
+func (s *ServiceDiscoveryService) List(ctx context.Context, req *ListServiceDiscoverySourcesRequest) (*ListServiceDiscoverySourcesResponse, error) {
+	res := new(ListServiceDiscoverySourcesResponse)
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		sources, err := models.FindServiceDiscoverySources(tx.Querier)
+		if err != nil {
+			return err
+		}
+
+		res.Sources = make([]*ServiceDiscoverySource, len(sources))
+		for i, source := range sources {
+			targets, err := models.FindDiscoveredTargets(tx.Querier, source.ID)
+			if err != nil {
+				return err
+			}
+			res.Sources[i] = convertServiceDiscoverySource(source)
+			res.Sources[i].Targets = len(targets)
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return res, nil
+}
+
+// Add creates a new service discovery source; targets are discovered shortly after that.
+func (s *ServiceDiscoveryService) Add(ctx context.Context, req *AddServiceDiscoverySourceRequest) (*AddServiceDiscoverySourceResponse, error) {
+	params := convertServiceDiscoverySourceParams(&req.ServiceDiscoverySource)
+
+	source, err := models.CreateServiceDiscoverySource(s.db.Querier, params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.requestSync()
+	return &AddServiceDiscoverySourceResponse{SourceID: source.ID}, nil
+}
+
+// Change replaces service discovery source parameters.
+func (s *ServiceDiscoveryService) Change(ctx context.Context, req *ChangeServiceDiscoverySourceRequest) (*ChangeServiceDiscoverySourceResponse, error) {
+	params := convertServiceDiscoverySourceParams(&req.ServiceDiscoverySource)
+
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		old, err := models.FindServiceDiscoverySourceByID(tx.Querier, req.SourceID)
+		if err != nil {
+			return err
+		}
+		if params.Consul != nil && params.Consul.Token == "" && old.Consul != nil {
+			params.Consul.Token = old.Consul.Token
+		}
+		if params.Kubernetes != nil && params.Kubernetes.BearerToken == "" && old.Kubernetes != nil {
+			params.Kubernetes.BearerToken = old.Kubernetes.BearerToken
+		}
+
+		_, err = models.ChangeServiceDiscoverySource(tx.Querier, req.SourceID, params)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.requestSync()
+	return &ChangeServiceDiscoverySourceResponse{}, nil
+}
+
+// Remove removes service discovery source with all Services, Agents and Nodes created for its targets.
+func (s *ServiceDiscoveryService) Remove(ctx context.Context, req *RemoveServiceDiscoverySourceRequest) (*RemoveServiceDiscoverySourceResponse, error) {
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		return models.RemoveServiceDiscoverySource(tx.Querier, req.SourceID)
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	s.vmdb.RequestConfigurationUpdate()
+	return &RemoveServiceDiscoverySourceResponse{}, nil
+}
+
+// Sync discovers targets of the given source and reconciles them immediately.
+func (s *ServiceDiscoveryService) Sync(ctx context.Context, req *SyncServiceDiscoverySourceRequest) (*SyncServiceDiscoverySourceResponse, error) {
+	source, err := models.FindServiceDiscoverySourceByID(s.db.Querier, req.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Disabled {
+		return nil, status.Errorf(codes.FailedPrecondition, "Service discovery source %q is disabled.", source.Name)
+	}
+
+	res, err := s.sync(ctx, source)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "Failed to sync service discovery source %q: %s.", source.Name, err)
+	}
+	return res, nil
+}
+
+// Run syncs all enabled service discovery sources periodically and on request until ctx is canceled.
+func (s *ServiceDiscoveryService) Run(ctx context.Context) {
+	s.l.Info("Starting...")
+	defer s.l.Info("Done.")
+
+	ticker := time.NewTicker(serviceDiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.syncCh:
+		}
+
+		s.syncAll(ctx)
+	}
+}
+
+// requestSync requests sync of all sources by Run.
+func (s *ServiceDiscoveryService) requestSync() {
+	select {
+	case s.syncCh <- struct{}{}:
+	default:
+	}
+}
+
+// syncAll syncs all enabled sources.
+func (s *ServiceDiscoveryService) syncAll(ctx context.Context) {
+	sources, err := models.FindServiceDiscoverySources(s.db.Querier)
+	if err != nil {
+		s.l.Errorf("Failed to find service discovery sources: %+v.", err)
+		return
+	}
+
+	for _, source := range sources {
+		if source.Disabled {
+			continue
+		}
+		if _, err = s.sync(ctx, source); err != nil {
+			s.l.Warnf("Failed to sync service discovery source %q: %s.", source.Name, err)
+		}
+	}
+}
+
+// sync discovers targets of the given source and reconciles them.
+// Existing targets are kept if discovery fails.
+func (s *ServiceDiscoveryService) sync(ctx context.Context, source *models.ServiceDiscoverySource) (*SyncServiceDiscoverySourceResponse, error) {
+	targets, discoverErr := s.discover(ctx, source)
+
+	res := new(SyncServiceDiscoverySourceResponse)
+	e := recordSync(s.db, models.ServiceDiscoverySourceTable, source.ID, discoverErr, func(q *reform.Querier, locked reform.Struct) (string, error) {
+		var err error
+		if res, err = reconcileDiscoveredTargets(q, locked.(*models.ServiceDiscoverySource), targets); err != nil {
+			return "", err
+		}
+		return failedTargetsError(res.Failed), nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	if discoverErr != nil {
+		return nil, discoverErr
+	}
+
+	if res.Added != 0 || res.Updated != 0 || res.Removed != 0 {
+		s.l.Infof("Service discovery source %q: %d targets added, %d updated, %d removed.", source.Name, res.Added, res.Updated, res.Removed)
+		s.vmdb.RequestConfigurationUpdate()
+	}
+	if len(res.Failed) != 0 {
+		s.l.Warnf("Service discovery source %q: %s.", source.Name, failedTargetsError(res.Failed))
+	}
+	return res, nil
+}
+
+// reconcileDiscoveredTargets creates, updates and removes Nodes, Services and external exporters
+// so that they match targets discovered by the given source.
+func reconcileDiscoveredTargets(q *reform.Querier, source *models.ServiceDiscoverySource, targets []*discovery.Target) (*SyncServiceDiscoverySourceResponse, error) {
+	existing, err := models.FindDiscoveredTargets(q, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	existingByKey := make(map[string]*models.DiscoveredTarget, len(existing))
+	for _, dt := range existing {
+		existingByKey[dt.TargetKey] = dt
+	}
+
+	res := &SyncServiceDiscoverySourceResponse{
+		Failed: make(map[string]string),
+	}
+	for _, t := range targets {
+		key := t.Key()
+		dt := existingByKey[key]
+		delete(existingByKey, key)
+
+		// one invalid or conflicting target should not prevent others from syncing
+		var added, updated bool
+		err := inSavepoint(q, func() error {
+			var e error
+			added, updated, e = reconcileDiscoveredTarget(q, source, t, dt)
+			return e
+		})
+		switch {
+		case err != nil:
+			res.Failed[key] = err.Error()
+		case added:
+			res.Added++
+		case updated:
+			res.Updated++
+		}
+	}
+
+	// remove targets that are not discovered anymore
+	removed := make([]*models.DiscoveredTarget, 0, len(existingByKey))
+	for _, dt := range existing {
+		if _, ok := existingByKey[dt.TargetKey]; ok {
+			removed = append(removed, dt)
+		}
+	}
+	if err = models.RemoveDiscoveredTargets(q, removed); err != nil {
+		return nil, err
+	}
+	res.Removed = len(removed)
+
+	if len(res.Failed) == 0 {
+		res.Failed = nil
+	}
+	return res, nil
+}
+
+// reconcileDiscoveredTarget creates Node, Service and external exporter for the new discovered target,
+// or updates Service of existing one. It returns true if target was added or updated.
+func reconcileDiscoveredTarget(q *reform.Querier, source *models.ServiceDiscoverySource, t *discovery.Target, dt *models.DiscoveredTarget) (bool, bool, error) {
+	params := discoveredServiceParams(source, t)
+	if dt != nil {
+		updated, err := updateDiscoveredService(q, dt.ServiceID, params)
+		return false, updated, err
+	}
+
+	nodeName := source.Name + "/" + t.Host
+	node, err := models.FindNodeByName(q, nodeName)
+	if status.Code(err) == codes.NotFound {
+		node, err = models.CreateNode(q, models.RemoteNodeType, &models.CreateNodeParams{
+			NodeName: nodeName,
+			Address:  t.Host,
+		})
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	params.NodeID = node.NodeID
+	service, err := models.AddNewService(q, models.ExternalServiceType, params)
+	if err != nil {
+		return false, false, err
+	}
+
+	agent, err := models.CreateExternalExporter(q, &models.CreateExternalExporterParams{
+		RunsOnNodeID: node.NodeID,
+		ServiceID:    service.ServiceID,
+		Scheme:       t.Scheme,
+		MetricsPath:  t.MetricsPath,
+		ListenPort:   uint32(t.Port),
+	})
+	if err != nil {
+		return false, false, err
+	}
+
+	if _, err = models.CreateDiscoveredTarget(q, source.ID, t.Key(), node.NodeID, service.ServiceID, agent.AgentID); err != nil {
+		return false, false, err
+	}
+	return true, false, nil
+}
+
+// inSavepoint calls f in a savepoint of the current transaction, and rolls back to it if f fails,
+// so the transaction can be continued.
+func inSavepoint(q *reform.Querier, f func() error) error {
+	if _, err := q.Exec("SAVEPOINT discovered_target"); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := f(); err != nil {
+		if _, e := q.Exec("ROLLBACK TO SAVEPOINT discovered_target"); e != nil {
+			return errors.WithStack(e)
+		}
+		return err
+	}
+	_, err := q.Exec("RELEASE SAVEPOINT discovered_target")
+	return errors.WithStack(err)
+}
+
+// failedTargetsError returns the last error of service discovery source with failed targets, or empty string.
+func failedTargetsError(failed map[string]string) string {
+	if len(failed) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(failed))
+	for key := range failed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	errs := make([]string, len(keys))
+	for i, key := range keys {
+		errs[i] = key + ": " + failed[key]
+	}
+	return fmt.Sprintf("%d targets failed: %s", len(keys), strings.Join(errs, "; "))
+}
+
+// updateDiscoveredService updates labels of Service created for discovered target if they were changed.
+func updateDiscoveredService(q *reform.Querier, serviceID string, params *models.AddDBMSServiceParams) (bool, error) {
+	service, err := models.FindServiceByID(q, serviceID)
+	if err != nil {
+		return false, err
+	}
+	customLabels, err := service.GetCustomLabels()
+	if err != nil {
+		return false, err
+	}
+	if len(customLabels) == 0 && len(params.CustomLabels) == 0 {
+		customLabels = params.CustomLabels
+	}
+
+	if service.Environment == params.Environment && service.Cluster == params.Cluster &&
+		service.ExternalGroup == params.ExternalGroup && reflect.DeepEqual(customLabels, params.CustomLabels) {
+		return false, nil
+	}
+
+	service.Environment = params.Environment
+	service.Cluster = params.Cluster
+	service.ExternalGroup = params.ExternalGroup
+	if err = service.SetCustomLabels(params.CustomLabels); err != nil {
+		return false, err
+	}
+	if err = q.Update(service); err != nil {
+		return false, errors.WithStack(err)
+	}
+	return true, nil
+}
+
+// discoveredServiceParams returns params of External Service for discovered target, without NodeID.
+// Discovered labels are mapped to environment, cluster and custom labels; reserved labels are ignored.
+func discoveredServiceParams(source *models.ServiceDiscoverySource, t *discovery.Target) *models.AddDBMSServiceParams {
+	name := source.Name + "/" + net.JoinHostPort(t.Host, strconv.Itoa(int(t.Port)))
+	if t.MetricsPath != "/metrics" {
+		name += t.MetricsPath
+	}
+	if t.Scheme != "http" {
+		name = t.Scheme + "://" + name
+	}
+
+	params := &models.AddDBMSServiceParams{
+		ServiceName:   name,
+		Environment:   t.Labels[source.EnvironmentLabel],
+		Cluster:       t.Labels[source.ClusterLabel],
+		ExternalGroup: source.ExternalGroup,
+	}
+	for label, value := range t.Labels {
+		if label == source.EnvironmentLabel || label == source.ClusterLabel {
+			continue
+		}
+		if _, ok := reservedLabels[label]; ok {
+			continue
+		}
+		if params.CustomLabels == nil {
+			params.CustomLabels = make(map[string]string)
+		}
+		params.CustomLabels[label] = value
+	}
+	return params
+}
+
+func convertServiceDiscoverySourceParams(s *ServiceDiscoverySource) *models.ServiceDiscoverySourceParams {
+	params := &models.ServiceDiscoverySourceParams{
+		Name:             s.Name,
+		Type:             models.ServiceDiscoveryType(s.Type),
+		EnvironmentLabel: s.EnvironmentLabel,
+		ClusterLabel:     s.ClusterLabel,
+		ExternalGroup:    s.ExternalGroup,
+		Disabled:         s.Disabled,
+	}
+	if o := s.FileSD; o != nil {
+		params.FileSD = &models.FileSDOptions{Path: o.Path}
+	}
+	if o := s.Consul; o != nil {
+		params.Consul = &models.ConsulOptions{
+			Address:               o.Address,
+			Scheme:                o.Scheme,
+			Datacenter:            o.Datacenter,
+			Token:                 o.Token,
+			TLSInsecureSkipVerify: o.TLSInsecureSkipVerify,
+			Services:              o.Services,
+			Tags:                  o.Tags,
+		}
+	}
+	if o := s.Kubernetes; o != nil {
+		params.Kubernetes = &models.KubernetesOptions{
+			APIServer:             o.APIServer,
+			BearerToken:           o.BearerToken,
+			TLSCa:                 o.TLSCa,
+			TLSInsecureSkipVerify: o.TLSInsecureSkipVerify,
+			Namespaces:            o.Namespaces,
+		}
+	}
+	return params
+}
+
+func convertServiceDiscoverySource(s *models.ServiceDiscoverySource) *ServiceDiscoverySource {
+	res := &ServiceDiscoverySource{
+		SourceID:         s.ID,
+		Name:             s.Name,
+		Type:             string(s.Type),
+		EnvironmentLabel: s.EnvironmentLabel,
+		ClusterLabel:     s.ClusterLabel,
+		ExternalGroup:    s.ExternalGroup,
+		Disabled:         s.Disabled,
+		LastSyncAt:       s.LastSyncAt,
+		LastError:        s.LastError,
+		CreatedAt:        s.CreatedAt,
+	}
+	if o := s.FileSD; o != nil {
+		res.FileSD = &FileSDOptions{Path: o.Path}
+	}
+	if o := s.Consul; o != nil {
+		res.Consul = &ConsulOptions{
+			Address:               o.Address,
+			Scheme:                o.Scheme,
+			Datacenter:            o.Datacenter,
+			TokenSet:              o.Token != "",
+			TLSInsecureSkipVerify: o.TLSInsecureSkipVerify,
+			Services:              o.Services,
+			Tags:                  o.Tags,
+		}
+	}
+	if o := s.Kubernetes; o != nil {
+		res.Kubernetes = &KubernetesOptions{
+			APIServer:             o.APIServer,
+			BearerTokenSet:        o.BearerToken != "",
+			TLSCa:                 o.TLSCa,
+			TLSInsecureSkipVerify: o.TLSInsecureSkipVerify,
+			Namespaces:            o.Namespaces,
+		}
+	}
+	return res
+}
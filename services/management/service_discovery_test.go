@@ -0,0 +1,185 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/discovery"
+	"github.com/percona/pmm-managed/utils/logger"
+	"github.com/percona/pmm-managed/utils/testdb"
+)
+
+func TestDiscoveredServiceParams(t *testing.T) {
+	t.Parallel()
+
+	source := &models.ServiceDiscoverySource{
+		Name:             "consul",
+		EnvironmentLabel: "env",
+		ClusterLabel:     "cluster",
+		ExternalGroup:    "apps",
+	}
+	target := &discovery.Target{
+		Scheme:      "https",
+		Host:        "10.0.0.1",
+		Port:        9100,
+		MetricsPath: "/stats",
+		Labels: map[string]string{
+			"env":          "prod",
+			"cluster":      "eu",
+			"team":         "payments",
+			"service_name": "ignored",
+			"environment":  "ignored",
+		},
+	}
+
+	expected := &models.AddDBMSServiceParams{
+		ServiceName:   "https://consul/10.0.0.1:9100/stats",
+		Environment:   "prod",
+		Cluster:       "eu",
+		ExternalGroup: "apps",
+		CustomLabels:  map[string]string{"team": "payments"},
+	}
+	assert.Equal(t, expected, discoveredServiceParams(source, target))
+}
+
+func TestServiceDiscovery(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SetupFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+	ctx := logger.Set(context.Background(), t.Name())
+
+	vmdb := &mockPrometheusService{}
+	vmdb.Test(t)
+	vmdb.On("RequestConfigurationUpdate").Return()
+	defer vmdb.AssertExpectations(t)
+
+	var targets []*discovery.Target
+	s := NewServiceDiscoveryService(db, vmdb)
+	s.discover = func(ctx context.Context, source *models.ServiceDiscoverySource) ([]*discovery.Target, error) {
+		return targets, nil
+	}
+
+	addRes, err := s.Add(ctx, &AddServiceDiscoverySourceRequest{ServiceDiscoverySource{
+		Name:   "apps",
+		Type:   "file_sd",
+		FileSD: &FileSDOptions{Path: "/srv/file_sd/*.json"},
+	}})
+	require.NoError(t, err)
+	sourceID := addRes.SourceID
+
+	targets = []*discovery.Target{{
+		Scheme: "http", Host: "10.0.0.1", Port: 9100, MetricsPath: "/metrics",
+		Labels: map[string]string{"environment": "prod", "team": "payments"},
+	}, {
+		Scheme: "http", Host: "10.0.0.1", Port: 9200, MetricsPath: "/metrics",
+		Labels: map[string]string{"environment": "prod"},
+	}}
+	syncRes, err := s.Sync(ctx, &SyncServiceDiscoverySourceRequest{SourceID: sourceID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncServiceDiscoverySourceResponse{Added: 2}, syncRes)
+
+	service, err := models.FindServiceByName(db.Querier, "apps/10.0.0.1:9100")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", service.Environment)
+	assert.Equal(t, "external", service.ExternalGroup)
+	agents, err := models.FindAgents(db.Querier, models.AgentFilters{ServiceID: service.ServiceID})
+	require.NoError(t, err)
+	require.Len(t, agents, 1)
+	assert.Equal(t, models.ExternalExporterType, agents[0].AgentType)
+	assert.Equal(t, uint16(9100), *agents[0].ListenPort)
+	node, err := models.FindNodeByID(db.Querier, service.NodeID)
+	require.NoError(t, err)
+	assert.Equal(t, "apps/10.0.0.1", node.NodeName)
+	assert.Equal(t, "10.0.0.1", node.Address)
+
+	// labels changed, the second target is gone
+	targets = []*discovery.Target{{
+		Scheme: "http", Host: "10.0.0.1", Port: 9100, MetricsPath: "/metrics",
+		Labels: map[string]string{"environment": "dev", "cluster": "eu"},
+	}}
+	syncRes, err = s.Sync(ctx, &SyncServiceDiscoverySourceRequest{SourceID: sourceID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncServiceDiscoverySourceResponse{Updated: 1, Removed: 1}, syncRes)
+
+	service, err = models.FindServiceByName(db.Querier, "apps/10.0.0.1:9100")
+	require.NoError(t, err)
+	assert.Equal(t, "dev", service.Environment)
+	assert.Equal(t, "eu", service.Cluster)
+	customLabels, err := service.GetCustomLabels()
+	require.NoError(t, err)
+	assert.Empty(t, customLabels)
+	_, err = models.FindServiceByName(db.Querier, "apps/10.0.0.1:9200")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// nothing changed
+	syncRes, err = s.Sync(ctx, &SyncServiceDiscoverySourceRequest{SourceID: sourceID})
+	require.NoError(t, err)
+	assert.Equal(t, &SyncServiceDiscoverySourceResponse{}, syncRes)
+
+	listRes, err := s.List(ctx, &ListServiceDiscoverySourcesRequest{})
+	require.NoError(t, err)
+	require.Len(t, listRes.Sources, 1)
+	assert.Equal(t, 1, listRes.Sources[0].Targets)
+	assert.NotNil(t, listRes.Sources[0].LastSyncAt)
+
+	// conflicting target is skipped
+	_, err = models.AddNewService(db.Querier, models.ExternalServiceType, &models.AddDBMSServiceParams{
+		ServiceName: "apps/10.0.0.2:9100",
+		NodeID:      models.PMMServerNodeID,
+	})
+	require.NoError(t, err)
+	targets = []*discovery.Target{{
+		Scheme: "http", Host: "10.0.0.1", Port: 9100, MetricsPath: "/metrics",
+		Labels: map[string]string{"environment": "dev", "cluster": "eu"},
+	}, {
+		Scheme: "http", Host: "10.0.0.2", Port: 9100, MetricsPath: "/metrics",
+	}, {
+		Scheme: "http", Host: "10.0.0.3", Port: 9100, MetricsPath: "/metrics",
+	}}
+	syncRes, err = s.Sync(ctx, &SyncServiceDiscoverySourceRequest{SourceID: sourceID})
+	require.NoError(t, err)
+	assert.Equal(t, 1, syncRes.Added)
+	require.Len(t, syncRes.Failed, 1)
+	assert.Contains(t, syncRes.Failed["http://10.0.0.2:9100/metrics"], "already exists")
+	_, err = models.FindServiceByName(db.Querier, "apps/10.0.0.3:9100")
+	require.NoError(t, err)
+
+	listRes, err = s.List(ctx, &ListServiceDiscoverySourcesRequest{})
+	require.NoError(t, err)
+	require.Len(t, listRes.Sources, 1)
+	assert.Equal(t, 2, listRes.Sources[0].Targets)
+	assert.Contains(t, listRes.Sources[0].LastError, "1 targets failed: http://10.0.0.2:9100/metrics: ")
+
+	_, err = s.Remove(ctx, &RemoveServiceDiscoverySourceRequest{SourceID: sourceID})
+	require.NoError(t, err)
+	_, err = models.FindServiceByName(db.Querier, "apps/10.0.0.1:9100")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = models.FindNodeByName(db.Querier, "apps/10.0.0.1")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
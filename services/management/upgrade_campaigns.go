@@ -0,0 +1,662 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/api/inventorypb"
+	"github.com/percona/pmm/version"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+)
+
+const upgradeCampaignsInterval = 10 * time.Second
+
+// UpgradeCampaignsService rolls out pmm-agents upgrades in waves.
+//
+// The agent protocol has no upgrade request, so pmm-agents poll for it with GetPMMAgentUpgrade,
+// upgrade themselves and reconnect with the target version; failures are reported with ReportPMMAgentUpgrade.
+type UpgradeCampaignsService struct {
+	db       *reform.DB
+	registry agentsRegistry
+	l        *logrus.Entry
+}
+
+// NewUpgradeCampaignsService creates new UpgradeCampaignsService.
+func NewUpgradeCampaignsService(db *reform.DB, registry agentsRegistry) *UpgradeCampaignsService {
+	return &UpgradeCampaignsService{
+		db:       db,
+		registry: registry,
+		l:        logrus.WithField("component", "upgrade-campaigns"),
+	}
+}
+
+// UpgradeSelector selects pmm-agents to upgrade; all non-empty fields should match.
+// Labels select pmm-agents of matching Services.
+type UpgradeSelector struct {
+	PMMAgentIDs    []string          `json:"pmm_agent_ids"`
+	FromVersions   []string          `json:"from_versions"`
+	ServiceType    string            `json:"service_type"`
+	Environment    string            `json:"environment"`
+	Cluster        string            `json:"cluster"`
+	ReplicationSet string            `json:"replication_set"`
+	CustomLabels   map[string]string `json:"custom_labels"`
+	// Select all pmm-agents; other fields should be empty.
+	All bool `json:"all"`
+}
+
+// UpgradeCampaignAgent represents upgrade of a single pmm-agent in JSON API responses.
+type UpgradeCampaignAgent struct {
+	PMMAgentID  string     `json:"pmm_agent_id"`
+	Wave        int        `json:"wave"`
+	Status      string     `json:"status"` // one of: pending, upgrading, succeeded, failed, skipped
+	FromVersion string     `json:"from_version"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FetchedAt   *time.Time `json:"fetched_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// UpgradeCampaign represents pmm-agents upgrade campaign in JSON API responses.
+type UpgradeCampaign struct {
+	CampaignID     string                  `json:"campaign_id"`
+	TargetVersion  string                  `json:"target_version"`
+	WaveSize       int                     `json:"wave_size"`
+	MaxUnavailable int                     `json:"max_unavailable"`
+	MaxFailures    int                     `json:"max_failures"`
+	Timeout        string                  `json:"timeout"`
+	Status         string                  `json:"status"` // one of: running, paused, completed, canceled
+	PauseReason    string                  `json:"pause_reason,omitempty"`
+	Stats          map[string]int          `json:"stats"`
+	Agents         []*UpgradeCampaignAgent `json:"agents"`
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+}
+
+// StartUpgradeCampaignRequest is a request of Start method.
+type StartUpgradeCampaignRequest struct {
+	TargetVersion string           `json:"target_version"`
+	Selector      *UpgradeSelector `json:"selector"`
+	// Defaults: 10 pmm-agents per wave, 1 unavailable, pause on the first failure, 10m timeout (Go duration).
+	WaveSize       int    `json:"wave_size"`
+	MaxUnavailable int    `json:"max_unavailable"`
+	MaxFailures    int    `json:"max_failures"`
+	Timeout        string `json:"timeout"`
+	// Return selected pmm-agents without starting the campaign.
+	DryRun bool `json:"dry_run"`
+}
+
+// StartUpgradeCampaignResponse is a response of Start method.
+type StartUpgradeCampaignResponse struct {
+	Campaign *UpgradeCampaign `json:"campaign,omitempty"`
+	// pmm-agents that would be upgraded, for dry run.
+	PMMAgentIDs []string `json:"pmm_agent_ids,omitempty"`
+}
+
+// ListUpgradeCampaignsRequest is a request of List method.
+type ListUpgradeCampaignsRequest struct{}
+
+// ListUpgradeCampaignsResponse is a response of List method.
+type ListUpgradeCampaignsResponse struct {
+	Campaigns []*UpgradeCampaign `json:"campaigns"`
+}
+
+// ChangeUpgradeCampaignRequest is a request of Pause, Resume and Cancel methods.
+type ChangeUpgradeCampaignRequest struct {
+	CampaignID string `json:"campaign_id"`
+}
+
+// ChangeUpgradeCampaignResponse is a response of Pause, Resume and Cancel methods.
+type ChangeUpgradeCampaignResponse struct {
+	Campaign *UpgradeCampaign `json:"campaign"`
+}
+
+// GetPMMAgentUpgradeRequest is a request of GetPMMAgentUpgrade method.
+type GetPMMAgentUpgradeRequest struct {
+	PMMAgentID string `json:"pmm_agent_id"`
+}
+
+// GetPMMAgentUpgradeResponse is a response of GetPMMAgentUpgrade method.
+// Fields are empty if there is no upgrade request for pmm-agent.
+type GetPMMAgentUpgradeResponse struct {
+	CampaignID    string `json:"campaign_id,omitempty"`
+	TargetVersion string `json:"target_version,omitempty"`
+}
+
+// ReportPMMAgentUpgradeRequest is a request of ReportPMMAgentUpgrade method.
+type ReportPMMAgentUpgradeRequest struct {
+	PMMAgentID string `json:"pmm_agent_id"`
+	CampaignID string `json:"campaign_id"`
+	Error      string `json:"error"`
+}
+
+// ReportPMMAgentUpgradeResponse is a response of ReportPMMAgentUpgrade method.
+type ReportPMMAgentUpgradeResponse struct{}
+
+// Start selects pmm-agents older than the target version and starts upgrade campaign.
+func (s *UpgradeCampaignsService) Start(ctx context.Context, req *StartUpgradeCampaignRequest) (*StartUpgradeCampaignResponse, error) {
+	targetVersion, err := version.Parse(req.TargetVersion)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid target version %q.", req.TargetVersion)
+	}
+	var timeout time.Duration
+	if req.Timeout != "" {
+		if timeout, err = time.ParseDuration(req.Timeout); err != nil || timeout <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid timeout %q.", req.Timeout)
+		}
+	}
+
+	res := new(StartUpgradeCampaignResponse)
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		pmmAgents, err := selectPMMAgentsForUpgrade(tx.Querier, req.Selector, targetVersion)
+		if err != nil {
+			return err
+		}
+
+		if req.DryRun {
+			res.PMMAgentIDs = make([]string, len(pmmAgents))
+			for i, a := range pmmAgents {
+				res.PMMAgentIDs[i] = a.AgentID
+			}
+			return nil
+		}
+
+		c, err := models.CreateUpgradeCampaign(tx.Querier, &models.CreateUpgradeCampaignParams{
+			TargetVersion:  req.TargetVersion,
+			PMMAgents:      pmmAgents,
+			WaveSize:       req.WaveSize,
+			MaxUnavailable: req.MaxUnavailable,
+			MaxFailures:    req.MaxFailures,
+			Timeout:        timeout,
+		})
+		if err != nil {
+			return err
+		}
+		res.Campaign = convertUpgradeCampaign(c)
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	if res.Campaign != nil {
+		logger.Get(ctx).Infof("Started upgrade campaign %s of %d pmm-agents to %s.",
+			res.Campaign.CampaignID, len(res.Campaign.Agents), req.TargetVersion)
+	}
+	return res, nil
+}
+
+// List returns all upgrade campaigns.
+func (s *UpgradeCampaignsService) List(ctx context.Context, req *ListUpgradeCampaignsRequest) (*ListUpgradeCampaignsResponse, error) {
+	campaigns, err := models.FindUpgradeCampaigns(s.db.Querier)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ListUpgradeCampaignsResponse{Campaigns: make([]*UpgradeCampaign, len(campaigns))}
+	for i, c := range campaigns {
+		res.Campaigns[i] = convertUpgradeCampaign(c)
+	}
+	return res, nil
+}
+
+// Pause stops starting new upgrades of running campaign; already started upgrades are still watched.
+func (s *UpgradeCampaignsService) Pause(ctx context.Context, req *ChangeUpgradeCampaignRequest) (*ChangeUpgradeCampaignResponse, error) {
+	return s.setStatus(req.CampaignID, models.UpgradeCampaignPaused, "Paused by user.")
+}
+
+// Resume continues paused campaign; failed upgrades are retried.
+func (s *UpgradeCampaignsService) Resume(ctx context.Context, req *ChangeUpgradeCampaignRequest) (*ChangeUpgradeCampaignResponse, error) {
+	return s.setStatus(req.CampaignID, models.UpgradeCampaignRunning, "")
+}
+
+// Cancel stops campaign permanently.
+func (s *UpgradeCampaignsService) Cancel(ctx context.Context, req *ChangeUpgradeCampaignRequest) (*ChangeUpgradeCampaignResponse, error) {
+	return s.setStatus(req.CampaignID, models.UpgradeCampaignCanceled, "")
+}
+
+// GetPMMAgentUpgrade returns upgrade request for pmm-agent, if any, and marks it as fetched.
+// pmm-agent polls it, upgrades itself to the target version and reconnects.
+func (s *UpgradeCampaignsService) GetPMMAgentUpgrade(ctx context.Context, req *GetPMMAgentUpgradeRequest) (*GetPMMAgentUpgradeResponse, error) {
+	if req.PMMAgentID == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty pmm-agent ID.")
+	}
+
+	res := new(GetPMMAgentUpgradeResponse)
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		c, a, err := lockPMMAgentUpgrade(tx.Querier, req.PMMAgentID, "")
+		if err != nil || c == nil {
+			return err
+		}
+
+		if a.FetchedAt == nil {
+			a.FetchedAt = pointer.ToTime(models.Now())
+			if err = tx.Update(c); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		res.CampaignID = c.ID
+		res.TargetVersion = c.TargetVersion
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	return res, nil
+}
+
+// ReportPMMAgentUpgrade marks pmm-agent upgrade as failed.
+// Succeeded upgrade is not reported: it is detected when pmm-agent reconnects with the target version.
+func (s *UpgradeCampaignsService) ReportPMMAgentUpgrade(ctx context.Context, req *ReportPMMAgentUpgradeRequest) (*ReportPMMAgentUpgradeResponse, error) {
+	if req.PMMAgentID == "" || req.CampaignID == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty pmm-agent or upgrade campaign ID.")
+	}
+	if req.Error == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty error.")
+	}
+
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		c, a, err := lockPMMAgentUpgrade(tx.Querier, req.PMMAgentID, req.CampaignID)
+		if err != nil {
+			return err
+		}
+		if c == nil {
+			return status.Errorf(codes.FailedPrecondition, "pmm-agent %q is not upgraded by campaign %q.", req.PMMAgentID, req.CampaignID)
+		}
+
+		a.Status = models.UpgradeAgentFailed
+		a.Error = req.Error
+		a.FinishedAt = pointer.ToTime(models.Now())
+		return errors.WithStack(tx.Update(c))
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	logger.Get(ctx).Warnf("pmm-agent %s failed to upgrade: %s.", req.PMMAgentID, req.Error)
+	return new(ReportPMMAgentUpgradeResponse), nil
+}
+
+// lockPMMAgentUpgrade finds and locks active campaign (with given ID, if not empty) upgrading pmm-agent.
+// It returns nils if there is no such campaign.
+func lockPMMAgentUpgrade(q *reform.Querier, pmmAgentID, campaignID string) (*models.UpgradeCampaign, *models.UpgradeCampaignAgent, error) {
+	upgrading, err := json.Marshal([]map[string]string{{"pmm_agent_id": pmmAgentID, "status": string(models.UpgradeAgentUpgrading)}})
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	// lock campaign before reading it, see advanceAll
+	tail := "WHERE status IN ($1, $2) AND agents @> $3::jsonb ORDER BY created_at FOR UPDATE"
+	structs, err := q.SelectAllFrom(models.UpgradeCampaignTable, tail, models.UpgradeCampaignRunning, models.UpgradeCampaignPaused, string(upgrading))
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	for _, str := range structs {
+		c := str.(*models.UpgradeCampaign)
+		if campaignID != "" && c.ID != campaignID {
+			continue
+		}
+		for _, a := range c.Agents {
+			if a.PMMAgentID == pmmAgentID && a.Status == models.UpgradeAgentUpgrading {
+				return c, a, nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+func (s *UpgradeCampaignsService) setStatus(id string, st models.UpgradeCampaignStatus, reason string) (*ChangeUpgradeCampaignResponse, error) {
+	var c *models.UpgradeCampaign
+	e := s.db.InTransaction(func(tx *reform.TX) error {
+		// lock campaign before reading it, see advanceAll
+		if _, err := tx.SelectOneFrom(models.UpgradeCampaignTable, "WHERE id = $1 FOR UPDATE", id); err != nil && err != reform.ErrNoRows {
+			return errors.WithStack(err)
+		}
+
+		var err error
+		c, err = models.SetUpgradeCampaignStatus(tx.Querier, id, st, reason)
+		return err
+	})
+	if e != nil {
+		return nil, e
+	}
+	return &ChangeUpgradeCampaignResponse{Campaign: convertUpgradeCampaign(c)}, nil
+}
+
+// Run advances active campaigns until ctx is canceled.
+func (s *UpgradeCampaignsService) Run(ctx context.Context) {
+	s.l.Info("Starting...")
+	defer s.l.Info("Done.")
+
+	ticker := time.NewTicker(upgradeCampaignsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.advanceAll(ctx)
+	}
+}
+
+// advanceAll advances all active campaigns.
+func (s *UpgradeCampaignsService) advanceAll(ctx context.Context) {
+	campaigns, err := models.FindActiveUpgradeCampaigns(s.db.Querier)
+	if err != nil {
+		s.l.Errorf("Failed to find upgrade campaigns: %+v.", err)
+		return
+	}
+
+	for _, c := range campaigns {
+		e := s.db.InTransaction(func(tx *reform.TX) error {
+			// lock campaign to avoid lost updates by concurrent Pause and Cancel calls
+			str, err := tx.SelectOneFrom(models.UpgradeCampaignTable, "WHERE id = $1 FOR UPDATE", c.ID)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			return s.advance(ctx, tx.Querier, str.(*models.UpgradeCampaign))
+		})
+		if e != nil {
+			s.l.Errorf("Failed to advance upgrade campaign %s: %+v.", c.ID, e)
+		}
+	}
+}
+
+// advance checks upgraded pmm-agents of active campaign. For running campaign, it also starts
+// new upgrades in the current wave, and pauses or completes the campaign.
+func (s *UpgradeCampaignsService) advance(ctx context.Context, q *reform.Querier, c *models.UpgradeCampaign) error {
+	l := s.l.WithField("campaign_id", c.ID)
+	targetVersion, err := version.Parse(c.TargetVersion)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	now := models.Now()
+
+	for _, a := range c.Agents {
+		if a.Status != models.UpgradeAgentUpgrading {
+			continue
+		}
+
+		done, reason, err := s.checkUpgraded(q, a.PMMAgentID, targetVersion)
+		switch {
+		case err != nil:
+			if status.Code(err) != codes.NotFound {
+				return err
+			}
+			l.Warnf("pmm-agent %s was removed.", a.PMMAgentID)
+			a.Status = models.UpgradeAgentSkipped
+			a.FinishedAt = pointer.ToTime(now)
+		case done:
+			l.Infof("pmm-agent %s upgraded.", a.PMMAgentID)
+			a.Status = models.UpgradeAgentSucceeded
+			a.FinishedAt = pointer.ToTime(now)
+		case now.Sub(*a.StartedAt) > c.Timeout:
+			if a.FetchedAt == nil {
+				reason = "upgrade request was not fetched by pmm-agent"
+			}
+			l.Warnf("pmm-agent %s upgrade timed out: %s.", a.PMMAgentID, reason)
+			a.Status = models.UpgradeAgentFailed
+			a.Error = fmt.Sprintf("Timed out after %s: %s.", c.Timeout, reason)
+			a.FinishedAt = pointer.ToTime(now)
+		}
+	}
+
+	// failures reported by pmm-agents since the last check pause the campaign before the next upgrades are started
+	if c.Status == models.UpgradeCampaignRunning && pauseOnFailures(c) {
+		l.Warn(c.PauseReason)
+	}
+	if c.Status != models.UpgradeCampaignRunning {
+		return errors.WithStack(q.Update(c))
+	}
+
+	for _, a := range nextUpgradeAgents(c) {
+		a.StartedAt = pointer.ToTime(now)
+		if !s.registry.IsConnected(a.PMMAgentID) {
+			l.Warnf("pmm-agent %s is not connected.", a.PMMAgentID)
+			a.Status = models.UpgradeAgentFailed
+			a.Error = "pmm-agent is not connected."
+			a.FinishedAt = pointer.ToTime(now)
+			continue
+		}
+
+		// pmm-agent fetches upgrade request with GetPMMAgentUpgrade
+		a.Status = models.UpgradeAgentUpgrading
+		l.Infof("pmm-agent %s upgrade to %s requested.", a.PMMAgentID, c.TargetVersion)
+	}
+
+	stats := models.UpgradeCampaignStats(c)
+	switch {
+	case pauseOnFailures(c):
+		l.Warn(c.PauseReason)
+	case stats[models.UpgradeAgentPending] == 0 && stats[models.UpgradeAgentUpgrading] == 0:
+		c.Status = models.UpgradeCampaignCompleted
+		l.Infof("Completed: %v.", stats)
+	}
+
+	return errors.WithStack(q.Update(c))
+}
+
+// pauseOnFailures pauses running campaign and returns true if too many pmm-agents failed to upgrade.
+func pauseOnFailures(c *models.UpgradeCampaign) bool {
+	failed := models.UpgradeCampaignStats(c)[models.UpgradeAgentFailed]
+	if failed <= c.MaxFailures {
+		return false
+	}
+
+	c.Status = models.UpgradeCampaignPaused
+	c.PauseReason = fmt.Sprintf("%d pmm-agents failed to upgrade, maximum is %d.", failed, c.MaxFailures)
+	return true
+}
+
+// checkUpgraded returns true if pmm-agent is connected with the target (or newer) version,
+// and all its enabled Agents are running. Otherwise, it returns a reason.
+func (s *UpgradeCampaignsService) checkUpgraded(q *reform.Querier, pmmAgentID string, targetVersion *version.Parsed) (bool, string, error) {
+	pmmAgent, err := models.FindAgentByID(q, pmmAgentID)
+	if err != nil {
+		return false, "", err
+	}
+
+	if !s.registry.IsConnected(pmmAgentID) {
+		return false, "pmm-agent is not connected", nil
+	}
+	v, err := version.Parse(pointer.GetString(pmmAgent.Version))
+	if err != nil || v.Less(targetVersion) {
+		return false, fmt.Sprintf("pmm-agent version is %q", pointer.GetString(pmmAgent.Version)), nil
+	}
+
+	agents, err := models.FindAgents(q, models.AgentFilters{PMMAgentID: pmmAgentID})
+	if err != nil {
+		return false, "", err
+	}
+	for _, a := range agents {
+		if a.Disabled || a.AgentType == models.ExternalExporterType {
+			continue
+		}
+		if a.Status != inventorypb.AgentStatus_RUNNING.String() {
+			return false, fmt.Sprintf("%s %s is %s", a.AgentType, a.AgentID, a.Status), nil
+		}
+	}
+	return true, "", nil
+}
+
+// nextUpgradeAgents returns pending pmm-agents of the current wave that can be upgraded now
+// without exceeding the maximal number of unavailable pmm-agents.
+// The current wave is the first one with pending or upgrading pmm-agents.
+func nextUpgradeAgents(c *models.UpgradeCampaign) []*models.UpgradeCampaignAgent {
+	wave := -1
+	var upgrading int
+	for _, a := range c.Agents {
+		switch a.Status {
+		case models.UpgradeAgentUpgrading:
+			upgrading++
+		case models.UpgradeAgentPending:
+		default:
+			continue
+		}
+		if wave == -1 || a.Wave < wave {
+			wave = a.Wave
+		}
+	}
+
+	var res []*models.UpgradeCampaignAgent
+	for _, a := range c.Agents {
+		if upgrading+len(res) >= c.MaxUnavailable {
+			break
+		}
+		if a.Wave == wave && a.Status == models.UpgradeAgentPending {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// selectPMMAgentsForUpgrade returns pmm-agents selected by selector with versions older than the target one.
+func selectPMMAgentsForUpgrade(q *reform.Querier, selector *UpgradeSelector, targetVersion *version.Parsed) ([]*models.Agent, error) {
+	if selector == nil {
+		return nil, status.Error(codes.InvalidArgument, "Selector is expected.")
+	}
+
+	serviceSelector := &models.ServiceSelector{
+		Environment:    selector.Environment,
+		Cluster:        selector.Cluster,
+		ReplicationSet: selector.ReplicationSet,
+		CustomLabels:   selector.CustomLabels,
+	}
+	if selector.ServiceType != "" {
+		serviceType := models.ServiceType(selector.ServiceType)
+		serviceSelector.ServiceType = &serviceType
+	}
+
+	empty := len(selector.PMMAgentIDs) == 0 && len(selector.FromVersions) == 0 && serviceSelector.IsEmpty()
+	switch {
+	case selector.All && !empty:
+		return nil, status.Error(codes.InvalidArgument, "all can't be combined with other selector fields.")
+	case !selector.All && empty:
+		// protect from upgrading the whole fleet by mistake
+		return nil, status.Error(codes.InvalidArgument, "Selector should not be empty; use all to select all pmm-agents.")
+	}
+
+	agentType := models.PMMAgentType
+	pmmAgents, err := models.FindAgents(q, models.AgentFilters{AgentType: &agentType})
+	if err != nil {
+		return nil, err
+	}
+
+	filters := []func(*models.Agent) bool{
+		func(a *models.Agent) bool {
+			v, err := version.Parse(pointer.GetString(a.Version))
+			return err == nil && v.Less(targetVersion)
+		},
+	}
+	if len(selector.PMMAgentIDs) != 0 {
+		ids := make(map[string]struct{}, len(selector.PMMAgentIDs))
+		for _, id := range selector.PMMAgentIDs {
+			if _, err = models.FindAgentByID(q, id); err != nil {
+				return nil, err
+			}
+			ids[id] = struct{}{}
+		}
+		filters = append(filters, func(a *models.Agent) bool { _, ok := ids[a.AgentID]; return ok })
+	}
+	if len(selector.FromVersions) != 0 {
+		versions := make(map[string]struct{}, len(selector.FromVersions))
+		for _, v := range selector.FromVersions {
+			versions[v] = struct{}{}
+		}
+		filters = append(filters, func(a *models.Agent) bool { _, ok := versions[pointer.GetString(a.Version)]; return ok })
+	}
+	if !serviceSelector.IsEmpty() {
+		services, err := models.FindServicesBySelector(q, serviceSelector)
+		if err != nil {
+			return nil, err
+		}
+		ids := make(map[string]struct{})
+		for _, service := range services {
+			agents, err := models.FindPMMAgentsForService(q, service.ServiceID)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range agents {
+				ids[a.AgentID] = struct{}{}
+			}
+		}
+		filters = append(filters, func(a *models.Agent) bool { _, ok := ids[a.AgentID]; return ok })
+	}
+
+	res := make([]*models.Agent, 0, len(pmmAgents))
+	for _, a := range pmmAgents {
+		selected := true
+		for _, f := range filters {
+			selected = selected && f(a)
+		}
+		if selected {
+			res = append(res, a)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].AgentID < res[j].AgentID })
+	return res, nil
+}
+
+func convertUpgradeCampaign(c *models.UpgradeCampaign) *UpgradeCampaign {
+	res := &UpgradeCampaign{
+		CampaignID:     c.ID,
+		TargetVersion:  c.TargetVersion,
+		WaveSize:       c.WaveSize,
+		MaxUnavailable: c.MaxUnavailable,
+		MaxFailures:    c.MaxFailures,
+		Timeout:        c.Timeout.String(),
+		Status:         string(c.Status),
+		PauseReason:    c.PauseReason,
+		Stats:          make(map[string]int),
+		Agents:         make([]*UpgradeCampaignAgent, len(c.Agents)),
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+	}
+	for st, n := range models.UpgradeCampaignStats(c) {
+		res.Stats[string(st)] = n
+	}
+	for i, a := range c.Agents {
+		res.Agents[i] = &UpgradeCampaignAgent{
+			PMMAgentID:  a.PMMAgentID,
+			Wave:        a.Wave,
+			Status:      string(a.Status),
+			FromVersion: a.FromVersion,
+			Error:       a.Error,
+			StartedAt:   a.StartedAt,
+			FetchedAt:   a.FetchedAt,
+			FinishedAt:  a.FinishedAt,
+		}
+	}
+	return res
+}
@@ -0,0 +1,272 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package management
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+	"gopkg.in/reform.v1/dialects/postgresql"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/logger"
+	"github.com/percona/pmm-managed/utils/testdb"
+)
+
+// standInPMMAgents polls upgrade requests like real pmm-agents would:
+// upgraded pmm-agent reconnects with the target version, failing one reports an error.
+type standInPMMAgents struct {
+	db       *reform.DB
+	s        *UpgradeCampaignsService
+	ids      []string
+	failing  map[string]string
+	upgraded []string
+}
+
+func (p *standInPMMAgents) poll(ctx context.Context, t *testing.T) {
+	t.Helper()
+
+	for _, id := range p.ids {
+		res, err := p.s.GetPMMAgentUpgrade(ctx, &GetPMMAgentUpgradeRequest{PMMAgentID: id})
+		require.NoError(t, err)
+		if res.CampaignID == "" {
+			continue
+		}
+
+		if msg := p.failing[id]; msg != "" {
+			_, err = p.s.ReportPMMAgentUpgrade(ctx, &ReportPMMAgentUpgradeRequest{
+				PMMAgentID: id,
+				CampaignID: res.CampaignID,
+				Error:      msg,
+			})
+			require.NoError(t, err)
+			continue
+		}
+
+		// reconnect with the new version
+		agent, err := models.FindAgentByID(p.db.Querier, id)
+		require.NoError(t, err)
+		agent.Version = pointer.ToString(res.TargetVersion)
+		require.NoError(t, p.db.Update(agent))
+		p.upgraded = append(p.upgraded, id)
+	}
+}
+
+func TestNextUpgradeAgents(t *testing.T) {
+	t.Parallel()
+
+	c := &models.UpgradeCampaign{
+		MaxUnavailable: 2,
+		Agents: models.UpgradeCampaignAgents{
+			{PMMAgentID: "A1", Wave: 0, Status: models.UpgradeAgentSucceeded},
+			{PMMAgentID: "A2", Wave: 0, Status: models.UpgradeAgentUpgrading},
+			{PMMAgentID: "A3", Wave: 0, Status: models.UpgradeAgentPending},
+			{PMMAgentID: "A4", Wave: 0, Status: models.UpgradeAgentPending},
+			{PMMAgentID: "A5", Wave: 1, Status: models.UpgradeAgentPending},
+		},
+	}
+	next := nextUpgradeAgents(c)
+	require.Len(t, next, 1)
+	assert.Equal(t, "A3", next[0].PMMAgentID)
+
+	// the next wave starts only when the current one is finished
+	c.Agents[1].Status = models.UpgradeAgentFailed
+	c.Agents[2].Status = models.UpgradeAgentSucceeded
+	c.Agents[3].Status = models.UpgradeAgentUpgrading
+	assert.Empty(t, nextUpgradeAgents(c))
+
+	c.Agents[3].Status = models.UpgradeAgentSucceeded
+	next = nextUpgradeAgents(c)
+	require.Len(t, next, 1)
+	assert.Equal(t, "A5", next[0].PMMAgentID)
+
+	c.Agents[4].Status = models.UpgradeAgentSucceeded
+	assert.Empty(t, nextUpgradeAgents(c))
+}
+
+func TestUpgradeCampaigns(t *testing.T) {
+	sqlDB := testdb.Open(t, models.SetupFixtures, nil)
+	defer func() {
+		require.NoError(t, sqlDB.Close())
+	}()
+	db := reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf))
+	ctx := logger.Set(context.Background(), t.Name())
+
+	registry := &mockAgentsRegistry{}
+	registry.Test(t)
+	registry.On("IsConnected", mock.Anything).Return(true)
+	defer registry.AssertExpectations(t)
+
+	s := NewUpgradeCampaignsService(db, registry)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		agent, err := models.CreatePMMAgent(db.Querier, models.PMMServerNodeID, map[string]string{"team": "upgrade"})
+		require.NoError(t, err)
+		agent.Version = pointer.ToString("2.28.0")
+		require.NoError(t, db.Update(agent))
+		ids = append(ids, agent.AgentID)
+	}
+	sort.Strings(ids) // campaign order
+	pmmAgents := &standInPMMAgents{
+		db:      db,
+		s:       s,
+		ids:     ids,
+		failing: make(map[string]string),
+	}
+	defer func() {
+		for _, id := range ids {
+			_, err := models.RemoveAgent(db.Querier, id, models.RemoveCascade)
+			require.NoError(t, err)
+		}
+	}()
+
+	selector := &UpgradeSelector{PMMAgentIDs: ids}
+
+	t.Run("DryRun", func(t *testing.T) {
+		res, err := s.Start(ctx, &StartUpgradeCampaignRequest{
+			TargetVersion: "2.29.0",
+			Selector:      selector,
+			DryRun:        true,
+		})
+		require.NoError(t, err)
+		assert.Nil(t, res.Campaign)
+		assert.Len(t, res.PMMAgentIDs, 3)
+
+		// protect from upgrading the whole fleet by mistake
+		_, err = s.Start(ctx, &StartUpgradeCampaignRequest{
+			TargetVersion: "2.29.0",
+			Selector:      &UpgradeSelector{},
+		})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Waves", func(t *testing.T) {
+		res, err := s.Start(ctx, &StartUpgradeCampaignRequest{
+			TargetVersion: "2.29.0",
+			Selector:      selector,
+			WaveSize:      2,
+		})
+		require.NoError(t, err)
+		id := res.Campaign.CampaignID
+		assert.Equal(t, []int{0, 0, 1}, []int{res.Campaign.Agents[0].Wave, res.Campaign.Agents[1].Wave, res.Campaign.Agents[2].Wave})
+
+		// the same pmm-agents can't be upgraded by two campaigns
+		_, err = s.Start(ctx, &StartUpgradeCampaignRequest{TargetVersion: "2.30.0", Selector: selector})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		// one pmm-agent at a time
+		for i := 0; i < 3; i++ {
+			s.advanceAll(ctx)
+			c, err := models.FindUpgradeCampaignByID(db.Querier, id)
+			require.NoError(t, err)
+			assert.Equal(t, models.UpgradeAgentUpgrading, c.Agents[i].Status)
+			assert.Nil(t, c.Agents[i].FetchedAt)
+
+			pmmAgents.poll(ctx, t)
+			assert.Equal(t, ids[:i+1], pmmAgents.upgraded)
+			c, err = models.FindUpgradeCampaignByID(db.Querier, id)
+			require.NoError(t, err)
+			assert.NotNil(t, c.Agents[i].FetchedAt)
+		}
+
+		s.advanceAll(ctx)
+		c, err := models.FindUpgradeCampaignByID(db.Querier, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.UpgradeCampaignCompleted, c.Status)
+		assert.Equal(t, map[models.UpgradeAgentStatus]int{models.UpgradeAgentSucceeded: 3}, models.UpgradeCampaignStats(c))
+	})
+
+	t.Run("PauseOnFailure", func(t *testing.T) {
+		pmmAgents.upgraded = nil
+		pmmAgents.failing[ids[0]] = "package not found"
+
+		res, err := s.Start(ctx, &StartUpgradeCampaignRequest{
+			TargetVersion: "2.30.0",
+			Selector:      selector,
+		})
+		require.NoError(t, err)
+		id := res.Campaign.CampaignID
+
+		s.advanceAll(ctx)
+		pmmAgents.poll(ctx, t)
+
+		// the next pmm-agent is not requested to upgrade after the failure
+		s.advanceAll(ctx)
+		c, err := models.FindUpgradeCampaignByID(db.Querier, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.UpgradeCampaignPaused, c.Status)
+		assert.Equal(t, models.UpgradeAgentFailed, c.Agents[0].Status)
+		assert.Equal(t, "package not found", c.Agents[0].Error)
+		assert.Equal(t, models.UpgradeAgentPending, c.Agents[1].Status)
+		assert.Empty(t, pmmAgents.upgraded)
+
+		// nothing happens while paused
+		s.advanceAll(ctx)
+		pmmAgents.poll(ctx, t)
+		assert.Empty(t, pmmAgents.upgraded)
+
+		// failed pmm-agent is retried on resume
+		delete(pmmAgents.failing, ids[0])
+		_, err = s.Resume(ctx, &ChangeUpgradeCampaignRequest{CampaignID: id})
+		require.NoError(t, err)
+		s.advanceAll(ctx)
+		pmmAgents.poll(ctx, t)
+		assert.Equal(t, ids[:1], pmmAgents.upgraded)
+
+		_, err = s.Cancel(ctx, &ChangeUpgradeCampaignRequest{CampaignID: id})
+		require.NoError(t, err)
+		_, err = s.Resume(ctx, &ChangeUpgradeCampaignRequest{CampaignID: id})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("NotFetched", func(t *testing.T) {
+		pmmAgents.upgraded = nil
+
+		res, err := s.Start(ctx, &StartUpgradeCampaignRequest{
+			TargetVersion: "2.31.0",
+			Selector:      selector,
+			Timeout:       "1ns",
+		})
+		require.NoError(t, err)
+		id := res.Campaign.CampaignID
+
+		// pmm-agent does not poll for upgrade requests
+		s.advanceAll(ctx)
+		s.advanceAll(ctx)
+		c, err := models.FindUpgradeCampaignByID(db.Querier, id)
+		require.NoError(t, err)
+		assert.Equal(t, models.UpgradeCampaignPaused, c.Status)
+		assert.Equal(t, models.UpgradeAgentFailed, c.Agents[0].Status)
+		assert.Contains(t, c.Agents[0].Error, "upgrade request was not fetched by pmm-agent")
+
+		// there is nothing to report for pmm-agent that is not upgraded
+		_, err = s.ReportPMMAgentUpgrade(ctx, &ReportPMMAgentUpgradeRequest{PMMAgentID: ids[0], CampaignID: id, Error: "failed"})
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+		_, err = s.Cancel(ctx, &ChangeUpgradeCampaignRequest{CampaignID: id})
+		require.NoError(t, err)
+	})
+}
@@ -28,25 +28,111 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/reform.v1"
 
 	"github.com/percona/pmm-managed/models"
 	"github.com/percona/pmm-managed/utils/stringset"
 )
 
+const (
+	spoolMinDelay = time.Second
+	spoolMaxDelay = time.Minute
+
+	// maxRequestBuckets limits the number of buckets in a single request, so request to qan-api is not too big.
+	maxRequestBuckets = 25000
+)
+
 // Client represents qan-api client for data collection.
 type Client struct {
-	c  qanCollectorClient
-	db *reform.DB
-	l  *logrus.Entry
+	c     qanCollectorClient
+	db    *reform.DB
+	spool *Spool
+	l     *logrus.Entry
 }
 
 // NewClient returns new client for given gRPC connection.
-func NewClient(cc *grpc.ClientConn, db *reform.DB) *Client {
+// If spool is not nil, requests queued by Spool and requests that can't be sent because qan-api is unavailable
+// are stored there and sent later by Run.
+func NewClient(cc *grpc.ClientConn, db *reform.DB, spool *Spool) *Client {
 	return &Client{
-		c:  qanpb.NewCollectorClient(cc),
-		db: db,
-		l:  logrus.WithField("component", "qan"),
+		c:     qanpb.NewCollectorClient(cc),
+		db:    db,
+		spool: spool,
+		l:     logrus.WithField("component", "qan"),
+	}
+}
+
+// Run sends spooled requests to qan-api with exponential backoff until ctx is canceled.
+func (c *Client) Run(ctx context.Context) {
+	if c.spool == nil {
+		return
+	}
+
+	c.l.Info("Starting spool sender...")
+	defer c.l.Info("Spool sender done.")
+
+	delay := spoolMinDelay
+	batchSize := maxRequestBuckets
+	for {
+		req, names, err := c.spool.PeekBatch(batchSize)
+		switch {
+		case err != nil:
+			c.l.Errorf("Failed to read spooled request %s: %s.", names[0], err)
+			c.spool.Drop(names[0], "corrupted")
+			continue
+
+		case len(names) == 0:
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.spool.Added():
+			}
+			continue
+		}
+
+		_, err = c.c.Collect(ctx, req)
+		switch {
+		case err == nil:
+			for _, name := range names {
+				c.spool.Remove(name)
+			}
+			delay = spoolMinDelay
+			batchSize = maxRequestBuckets
+			continue
+
+		case !isRetryable(err) && len(names) > 1:
+			// send requests one by one to drop only the rejected one
+			c.l.Warnf("qan-api rejected %d spooled requests, retrying them one by one: %s.", len(names), err)
+			batchSize = 0
+			continue
+
+		case !isRetryable(err):
+			c.l.Errorf("qan-api rejected spooled request %s: %s.", names[0], err)
+			c.spool.Drop(names[0], "rejected")
+			continue
+		}
+
+		c.l.Debugf("Failed to send spooled request, retrying in %s: %s.", delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > spoolMaxDelay {
+			delay = spoolMaxDelay
+		}
+	}
+}
+
+// isRetryable returns true if CollectRequest may be sent again later.
+func isRetryable(err error) bool {
+	switch status.Code(errors.Cause(err)) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Canceled:
+		return true
+	default:
+		return false
 	}
 }
 
@@ -113,17 +199,58 @@ func (c *Client) Collect(ctx context.Context, metricsBuckets []*agentpb.MetricsB
 		}
 	}()
 
-	agents, err := collectAgents(c.db.Querier, metricsBuckets)
+	reqs, err := c.convert(metricsBuckets)
 	if err != nil {
 		return err
 	}
-	services, err := collectServices(c.db.Querier, agents)
+	for _, req := range reqs {
+		c.l.Debugf("%+v", req)
+		if err = c.send(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Spool adds labels to the data from pmm-agent and puts it into the spool; it is sent to qan-api by Run.
+func (c *Client) Spool(ctx context.Context, metricsBuckets []*agentpb.MetricsBucket) error {
+	if c.spool == nil {
+		return errors.New("QAN spool is disabled")
+	}
+
+	reqs, err := c.convert(metricsBuckets)
 	if err != nil {
 		return err
 	}
+	for _, req := range reqs {
+		if len(req.MetricsBucket) == 0 {
+			continue
+		}
+		if err = c.spool.Put(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convert adds labels to the data from pmm-agent and splits it into requests to qan-api.
+// It returns at least one request, even an empty one.
+func (c *Client) convert(metricsBuckets []*agentpb.MetricsBucket) ([]*qanpb.CollectRequest, error) {
+	agents, err := collectAgents(c.db.Querier, metricsBuckets)
+	if err != nil {
+		return nil, err
+	}
+	services, err := collectServices(c.db.Querier, agents)
+	if err != nil {
+		return nil, err
+	}
 	nodes, err := collectNodes(c.db.Querier, services)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	policies, err := loadRedactionPolicies(c.db.Querier)
+	if err != nil {
+		return nil, err
 	}
 
 	convertedMetricsBuckets := make([]*qanpb.MetricsBucket, 0, len(metricsBuckets))
@@ -184,13 +311,14 @@ func (c *Client) Collect(ctx context.Context, metricsBuckets []*agentpb.MetricsB
 			MQueryTimeP99:        m.Common.MQueryTimeP99,
 		}
 
+		rules := redactionRulesFor(policies, labels)
 		switch {
 		case m.Mysql != nil:
-			fillMySQL(mb, m.Mysql)
+			fillMySQL(mb, m.Mysql, rules)
 		case m.Mongodb != nil:
-			fillMongoDB(mb, m.Mongodb)
+			fillMongoDB(mb, m.Mongodb, rules)
 		case m.Postgresql != nil:
-			fillPostgreSQL(mb, m.Postgresql)
+			fillPostgreSQL(mb, m.Postgresql, rules)
 		}
 
 		// in order of fields in MetricsBucket
@@ -236,28 +364,42 @@ func (c *Client) Collect(ctx context.Context, metricsBuckets []*agentpb.MetricsB
 	}
 
 	// Slice metrics, so request to qan-api is not too big
-	const bucketSize = 25000
-	from, to := 0, bucketSize
+	var res []*qanpb.CollectRequest
+	from, to := 0, maxRequestBuckets
 	// Send at least one time, even though it's empty
 	for from <= len(convertedMetricsBuckets) {
 		if to > len(convertedMetricsBuckets) {
 			to = len(convertedMetricsBuckets)
 		}
-		qanReq := &qanpb.CollectRequest{
+		res = append(res, &qanpb.CollectRequest{
 			MetricsBucket: convertedMetricsBuckets[from:to],
-		}
-		c.l.Debugf("%+v", qanReq)
-		res, err := c.c.Collect(ctx, qanReq)
-		if err != nil {
-			return errors.Wrap(err, "failed to sent CollectRequest to QAN")
-		}
-		c.l.Debugf("%+v", res)
+		})
 
-		from += bucketSize
-		to += bucketSize
+		from += maxRequestBuckets
+		to += maxRequestBuckets
 	}
 
-	return nil
+	return res, nil
+}
+
+// send sends request to qan-api, or puts it into the spool if qan-api is unavailable
+// or there are older requests waiting in the spool.
+func (c *Client) send(ctx context.Context, req *qanpb.CollectRequest) error {
+	if c.spool != nil && c.spool.Len() != 0 {
+		return c.spool.Put(req)
+	}
+
+	res, err := c.c.Collect(ctx, req)
+	if err == nil {
+		c.l.Debugf("%+v", res)
+		return nil
+	}
+
+	if c.spool == nil || !isRetryable(err) {
+		return errors.Wrap(err, "failed to sent CollectRequest to QAN")
+	}
+	c.l.Warnf("Failed to send CollectRequest to QAN, spooling: %s.", err)
+	return c.spool.Put(req)
 }
 
 //nolint:staticcheck
@@ -287,7 +429,9 @@ func convertExampleType(exampleType agentpb.ExampleType) qanpb.ExampleType {
 	}
 }
 
-func fillMySQL(mb *qanpb.MetricsBucket, bm *agentpb.MetricsBucket_MySQL) {
+func fillMySQL(mb *qanpb.MetricsBucket, bm *agentpb.MetricsBucket_MySQL, rules []*redactionRule) {
+	redact(mb, rules)
+
 	mb.MLockTimeCnt = bm.MLockTimeCnt
 	mb.MLockTimeSum = bm.MLockTimeSum
 	mb.MLockTimeMin = bm.MLockTimeMin
@@ -436,7 +580,9 @@ func fillMySQL(mb *qanpb.MetricsBucket, bm *agentpb.MetricsBucket_MySQL) {
 	mb.MNoGoodIndexUsedSum = bm.MNoGoodIndexUsedSum
 }
 
-func fillMongoDB(mb *qanpb.MetricsBucket, bm *agentpb.MetricsBucket_MongoDB) {
+func fillMongoDB(mb *qanpb.MetricsBucket, bm *agentpb.MetricsBucket_MongoDB, rules []*redactionRule) {
+	redact(mb, rules)
+
 	mb.MDocsReturnedCnt = bm.MDocsReturnedCnt
 	mb.MDocsReturnedSum = bm.MDocsReturnedSum
 	mb.MDocsReturnedMin = bm.MDocsReturnedMin
@@ -456,7 +602,9 @@ func fillMongoDB(mb *qanpb.MetricsBucket, bm *agentpb.MetricsBucket_MongoDB) {
 	mb.MDocsScannedP99 = bm.MDocsScannedP99
 }
 
-func fillPostgreSQL(mb *qanpb.MetricsBucket, bp *agentpb.MetricsBucket_PostgreSQL) {
+func fillPostgreSQL(mb *qanpb.MetricsBucket, bp *agentpb.MetricsBucket_PostgreSQL, rules []*redactionRule) {
+	redact(mb, rules)
+
 	mb.MRowsSentCnt = bp.MRowsCnt
 	mb.MRowsSentSum = bp.MRowsSum
 
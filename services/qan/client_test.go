@@ -46,7 +46,7 @@ func TestClient(t *testing.T) {
 	ctx := logger.Set(context.Background(), t.Name())
 	defer func() {
 		assert.NoError(t, sqlDB.Close())
-		assert.Equal(t, 18, reformL.Requests())
+		assert.Equal(t, 22, reformL.Requests())
 	}()
 
 	for _, str := range []reform.Struct{
@@ -466,7 +466,7 @@ func TestClientPerformance(t *testing.T) {
 
 	reformL.Reset()
 	defer func() {
-		assert.Equal(t, 3, reformL.Requests())
+		assert.Equal(t, 4, reformL.Requests())
 	}()
 
 	client := &Client{
@@ -19,14 +19,23 @@ package qan
 import (
 	"context"
 
+	"github.com/percona/pmm/api/agentpb"
 	qanpb "github.com/percona/pmm/api/qanpb"
 	"google.golang.org/grpc"
 )
 
 //go:generate mockery -name=qanCollectorClient  -case=snake -inpkg -testonly
+//go:generate mockery -name=collector -case=snake -inpkg -testonly
 
 // qanClient is a subset of methods of qanpb.CollectorClient used by this package.
 // We use it instead of real type for testing.
 type qanCollectorClient interface {
 	Collect(ctx context.Context, in *qanpb.CollectRequest, opts ...grpc.CallOption) (*qanpb.CollectResponse, error)
 }
+
+// collector is a subset of methods of Client used by Forwarder.
+// We use it instead of real type for testing.
+type collector interface {
+	Collect(ctx context.Context, metricsBuckets []*agentpb.MetricsBucket) error
+	Spool(ctx context.Context, metricsBuckets []*agentpb.MetricsBucket) error
+}
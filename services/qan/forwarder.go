@@ -0,0 +1,254 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package qan
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/percona/pmm/api/agentpb"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultForwarderQueueSize     = 1000
+	defaultForwarderWorkers       = 4
+	defaultForwarderBatchSize     = 5000
+	defaultForwarderFlushInterval = time.Second
+	defaultForwarderEnqueueWait   = 3 * time.Second
+
+	// forwarderDrainTimeout limits the time for sending queued buckets on shutdown.
+	forwarderDrainTimeout = 10 * time.Second
+)
+
+// ForwarderParams represents Forwarder parameters; zero values are replaced with defaults.
+type ForwarderParams struct {
+	// Maximal number of QANCollectRequests waiting in memory.
+	QueueSize int
+	// Number of concurrent requests to qan-api.
+	Workers int
+	// Buckets from different pmm-agents are sent together until that number is reached.
+	BatchSize int
+	// Incomplete batch is sent after that interval.
+	FlushInterval time.Duration
+	// Maximal time Enqueue waits for free space in the full queue before dropping buckets.
+	EnqueueWait time.Duration
+	// Spool makes workers write batches to the Client's on-disk spool instead of sending them;
+	// they are sent by Client.Run then.
+	Spool bool
+}
+
+// Forwarder queues metrics buckets received from pmm-agents, and a fixed number of workers
+// convert and send (or spool) them in batches, so slow qan-api or disk does not block pmm-agent streams.
+type Forwarder struct {
+	c      collector
+	params ForwarderParams
+	queue  chan []*agentpb.MetricsBucket
+	l      *logrus.Entry
+
+	mQueued  prom.Gauge
+	mBatches prom.Counter
+	mDropped prom.Counter
+}
+
+// NewForwarder creates new Forwarder that sends data with the given Client.
+func NewForwarder(c collector, params ForwarderParams) *Forwarder {
+	if params.QueueSize <= 0 {
+		params.QueueSize = defaultForwarderQueueSize
+	}
+	if params.Workers <= 0 {
+		params.Workers = defaultForwarderWorkers
+	}
+	if params.BatchSize <= 0 {
+		params.BatchSize = defaultForwarderBatchSize
+	}
+	if params.FlushInterval <= 0 {
+		params.FlushInterval = defaultForwarderFlushInterval
+	}
+	if params.EnqueueWait <= 0 {
+		params.EnqueueWait = defaultForwarderEnqueueWait
+	}
+
+	const subsystem = "qan_forwarder"
+	return &Forwarder{
+		c:      c,
+		params: params,
+		queue:  make(chan []*agentpb.MetricsBucket, params.QueueSize),
+		l:      logrus.WithField("component", "qan/forwarder"),
+		mQueued: prom.NewGauge(prom.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: subsystem,
+			Name:      "queue_length",
+			Help:      "The current number of QAN collect requests waiting in memory.",
+		}),
+		mBatches: prom.NewCounter(prom.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: subsystem,
+			Name:      "batches_total",
+			Help:      "A total number of batches sent to qan-api or spooled.",
+		}),
+		mDropped: prom.NewCounter(prom.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: subsystem,
+			Name:      "dropped_total",
+			Help:      "A total number of QAN collect requests rejected because the queue was full.",
+		}),
+	}
+}
+
+// Enqueue queues metrics buckets and returns when they are queued; pmm-agent should be acknowledged only then.
+// If the queue is full, it waits up to EnqueueWait for free space, slowing down pmm-agent,
+// and then drops buckets and returns Unavailable error.
+//
+// Queued buckets are lost on crash. On shutdown, buckets that can't be sent (or spooled)
+// within forwarderDrainTimeout are lost too.
+func (f *Forwarder) Enqueue(ctx context.Context, metricsBuckets []*agentpb.MetricsBucket) error {
+	select {
+	case f.queue <- metricsBuckets:
+		f.mQueued.Inc()
+		return nil
+	default:
+	}
+
+	t := time.NewTimer(f.params.EnqueueWait)
+	defer t.Stop()
+
+	select {
+	case f.queue <- metricsBuckets:
+		f.mQueued.Inc()
+		return nil
+	case <-t.C:
+	case <-ctx.Done():
+	}
+
+	f.mDropped.Inc()
+	return status.Errorf(codes.Unavailable, "QAN queue is full, %d buckets dropped.", len(metricsBuckets))
+}
+
+// Run runs workers until ctx is canceled, then sends remaining queued buckets.
+func (f *Forwarder) Run(ctx context.Context) {
+	f.l.Info("Starting...")
+	defer f.l.Info("Done.")
+
+	var wg sync.WaitGroup
+	for i := 0; i < f.params.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.runWorker(ctx)
+		}()
+	}
+	wg.Wait()
+
+	// ctx is canceled; send what is left with a fresh context
+	drainCtx, cancel := context.WithTimeout(context.Background(), forwarderDrainTimeout)
+	defer cancel()
+	var batch []*agentpb.MetricsBucket
+	for drained := false; !drained; {
+		select {
+		case buckets := <-f.queue:
+			f.mQueued.Dec()
+			batch = append(batch, buckets...)
+		default:
+			drained = true
+		}
+	}
+	f.send(drainCtx, batch)
+}
+
+// runWorker collects buckets from the queue into batches and sends them until ctx is canceled.
+func (f *Forwarder) runWorker(ctx context.Context) {
+	ticker := time.NewTicker(f.params.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []*agentpb.MetricsBucket
+	for {
+		select {
+		case <-ctx.Done():
+			// return current batch to the queue, or send it if the queue is full
+			if len(batch) != 0 {
+				select {
+				case f.queue <- batch:
+					f.mQueued.Inc()
+				default:
+					drainCtx, cancel := context.WithTimeout(context.Background(), forwarderDrainTimeout)
+					f.send(drainCtx, batch)
+					cancel()
+				}
+			}
+			return
+
+		case buckets := <-f.queue:
+			f.mQueued.Dec()
+			batch = append(batch, buckets...)
+			if len(batch) < f.params.BatchSize {
+				continue
+			}
+
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		// qan-api may be slow, but sending should not be interrupted by pmm-agent disconnects
+		f.send(context.Background(), batch)
+		batch = nil
+	}
+}
+
+// send sends a batch to qan-api or puts it into the spool; errors are logged.
+func (f *Forwarder) send(ctx context.Context, batch []*agentpb.MetricsBucket) {
+	if len(batch) == 0 {
+		return
+	}
+
+	f.mBatches.Inc()
+	if f.params.Spool {
+		if err := f.c.Spool(ctx, batch); err != nil {
+			f.l.Errorf("Failed to spool %d buckets: %+v.", len(batch), err)
+		}
+		return
+	}
+
+	if err := f.c.Collect(ctx, batch); err != nil {
+		f.l.Errorf("Failed to send %d buckets: %+v.", len(batch), err)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (f *Forwarder) Describe(ch chan<- *prom.Desc) {
+	f.mQueued.Describe(ch)
+	f.mBatches.Describe(ch)
+	f.mDropped.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (f *Forwarder) Collect(ch chan<- prom.Metric) {
+	f.mQueued.Collect(ch)
+	f.mBatches.Collect(ch)
+	f.mDropped.Collect(ch)
+}
+
+// check interfaces.
+var (
+	_ prom.Collector = (*Forwarder)(nil)
+)
@@ -0,0 +1,156 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package qan
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func buckets(queryIDs ...string) []*agentpb.MetricsBucket {
+	res := make([]*agentpb.MetricsBucket, len(queryIDs))
+	for i, id := range queryIDs {
+		res[i] = &agentpb.MetricsBucket{Common: &agentpb.MetricsBucket_Common{Queryid: id}}
+	}
+	return res
+}
+
+func queryIDs(buckets []*agentpb.MetricsBucket) []string {
+	res := make([]string, len(buckets))
+	for i, b := range buckets {
+		res[i] = b.Common.Queryid
+	}
+	return res
+}
+
+func TestForwarder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Batches", func(t *testing.T) {
+		t.Parallel()
+
+		c := &mockCollector{}
+		c.Test(t)
+		t.Cleanup(func() { c.AssertExpectations(t) })
+
+		sent := make(chan []string, 10)
+		c.On("Collect", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			sent <- queryIDs(args.Get(1).([]*agentpb.MetricsBucket))
+		})
+
+		f := NewForwarder(c, ForwarderParams{Workers: 1, BatchSize: 3, FlushInterval: time.Hour})
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Run(ctx)
+		}()
+
+		// buckets from different pmm-agents are sent together
+		require.NoError(t, f.Enqueue(context.Background(), buckets("a1", "a2")))
+		require.NoError(t, f.Enqueue(context.Background(), buckets("b1")))
+		assert.Equal(t, []string{"a1", "a2", "b1"}, <-sent)
+
+		// incomplete batch is sent on shutdown
+		require.NoError(t, f.Enqueue(context.Background(), buckets("c1")))
+		cancel()
+		wg.Wait()
+		assert.Equal(t, []string{"c1"}, <-sent)
+		assert.Empty(t, sent)
+	})
+
+	t.Run("FlushInterval", func(t *testing.T) {
+		t.Parallel()
+
+		c := &mockCollector{}
+		c.Test(t)
+		t.Cleanup(func() { c.AssertExpectations(t) })
+
+		sent := make(chan []string, 10)
+		c.On("Collect", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			sent <- queryIDs(args.Get(1).([]*agentpb.MetricsBucket))
+		})
+
+		f := NewForwarder(c, ForwarderParams{Workers: 2, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go f.Run(ctx)
+
+		require.NoError(t, f.Enqueue(context.Background(), buckets("a1")))
+		assert.Equal(t, []string{"a1"}, <-sent)
+	})
+
+	t.Run("QueueFull", func(t *testing.T) {
+		t.Parallel()
+
+		c := &mockCollector{}
+		c.Test(t)
+		t.Cleanup(func() { c.AssertExpectations(t) })
+
+		// workers are not running, so the second request is dropped after waiting
+		ctx := context.Background()
+		f := NewForwarder(c, ForwarderParams{QueueSize: 1, EnqueueWait: 10 * time.Millisecond})
+		require.NoError(t, f.Enqueue(ctx, buckets("a1")))
+		err := f.Enqueue(ctx, buckets("b1"))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Len(t, f.queue, 1)
+
+		// free space is waited for
+		f = NewForwarder(c, ForwarderParams{QueueSize: 1, EnqueueWait: time.Minute})
+		require.NoError(t, f.Enqueue(ctx, buckets("a1")))
+		go func(queue <-chan []*agentpb.MetricsBucket) {
+			time.Sleep(10 * time.Millisecond)
+			<-queue
+		}(f.queue)
+		require.NoError(t, f.Enqueue(ctx, buckets("b1")))
+		assert.Equal(t, []string{"b1"}, queryIDs(<-f.queue))
+	})
+
+	t.Run("Spool", func(t *testing.T) {
+		t.Parallel()
+
+		c := &mockCollector{}
+		c.Test(t)
+		t.Cleanup(func() { c.AssertExpectations(t) })
+
+		spooled := make(chan []string, 10)
+		c.On("Spool", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			spooled <- queryIDs(args.Get(1).([]*agentpb.MetricsBucket))
+		})
+
+		// buckets are spooled by workers, not by Enqueue
+		f := NewForwarder(c, ForwarderParams{Workers: 1, BatchSize: 2, FlushInterval: time.Hour, Spool: true})
+		require.NoError(t, f.Enqueue(context.Background(), buckets("a1")))
+		require.NoError(t, f.Enqueue(context.Background(), buckets("b1")))
+		assert.Empty(t, spooled)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go f.Run(ctx)
+		assert.Equal(t, []string{"a1", "b1"}, <-spooled)
+	})
+}
@@ -0,0 +1,156 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package qan
+
+import (
+	"regexp"
+	"strings"
+
+	qanpb "github.com/percona/pmm/api/qanpb"
+	"github.com/pkg/errors"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// redactionRule is a compiled models.RedactionRule.
+type redactionRule struct {
+	re          *regexp.Regexp // nil for literal rules
+	literal     string
+	replacement string
+}
+
+// apply returns s with all pattern matches replaced.
+func (r *redactionRule) apply(s string) string {
+	if r.re != nil {
+		return r.re.ReplaceAllString(s, r.replacement)
+	}
+	return strings.ReplaceAll(s, r.literal, r.replacement)
+}
+
+// redactionPolicy is a compiled models.RedactionPolicy.
+type redactionPolicy struct {
+	equal map[string]string
+	regex map[string]*regexp.Regexp
+	rules []*redactionRule
+}
+
+// matches returns true if all policy filters match given labels.
+func (p *redactionPolicy) matches(labels map[string]string) bool {
+	for k, v := range p.equal {
+		if labels[k] != v {
+			return false
+		}
+	}
+	for k, re := range p.regex {
+		if !re.MatchString(labels[k]) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadRedactionPolicies returns compiled enabled redaction policies.
+// Error is returned if any policy can't be compiled, so data is never forwarded unredacted.
+func loadRedactionPolicies(q *reform.Querier) ([]*redactionPolicy, error) {
+	policies, err := models.FindRedactionPolicies(q)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*redactionPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if policy.Disabled {
+			continue
+		}
+
+		p, err := compileRedactionPolicy(policy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compile redaction policy %q", policy.Name)
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func compileRedactionPolicy(policy *models.RedactionPolicy) (*redactionPolicy, error) {
+	p := &redactionPolicy{
+		equal: make(map[string]string),
+		regex: make(map[string]*regexp.Regexp),
+		rules: make([]*redactionRule, len(policy.Rules)),
+	}
+
+	for _, f := range policy.Filters {
+		switch f.Type {
+		case models.Equal:
+			p.equal[f.Key] = f.Val
+		case models.Regex:
+			// anchored like Prometheus label matchers
+			re, err := regexp.Compile("^(?:" + f.Val + ")$")
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			p.regex[f.Key] = re
+		default:
+			return nil, errors.Errorf("unknown filter type %q", f.Type)
+		}
+	}
+
+	for i, r := range policy.Rules {
+		rule := &redactionRule{
+			replacement: r.Replacement,
+		}
+		if rule.replacement == "" {
+			rule.replacement = models.DefaultRedactionReplacement
+		}
+
+		switch r.Type {
+		case models.RegexRedaction:
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			rule.re = re
+		case models.LiteralRedaction:
+			rule.literal = r.Pattern
+		default:
+			return nil, errors.Errorf("unknown redaction rule type %q", r.Type)
+		}
+		p.rules[i] = rule
+	}
+
+	return p, nil
+}
+
+// redactionRulesFor returns rules of all policies matching given Service labels.
+func redactionRulesFor(policies []*redactionPolicy, labels map[string]string) []*redactionRule {
+	var res []*redactionRule
+	for _, p := range policies {
+		if p.matches(labels) {
+			res = append(res, p.rules...)
+		}
+	}
+	return res
+}
+
+// redact applies rules to query example and fingerprint.
+func redact(mb *qanpb.MetricsBucket, rules []*redactionRule) {
+	for _, r := range rules {
+		mb.Example = r.apply(mb.Example)
+		mb.Fingerprint = r.apply(mb.Fingerprint)
+	}
+}
@@ -0,0 +1,87 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package qan
+
+import (
+	"testing"
+
+	"github.com/percona/pmm/api/agentpb"
+	qanpb "github.com/percona/pmm/api/qanpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestRedaction(t *testing.T) {
+	t.Parallel()
+
+	var policies []*redactionPolicy
+	for _, policy := range []*models.RedactionPolicy{{
+		Name: "emails",
+		Rules: models.RedactionRules{{
+			Type:    models.RegexRedaction,
+			Pattern: `[\w.+-]+@[\w-]+\.[\w.]+`,
+		}},
+	}, {
+		Name:    "prod secrets",
+		Filters: models.Filters{{Type: models.Regex, Key: "environment", Val: "prod|staging"}},
+		Rules: models.RedactionRules{{
+			Type:        models.LiteralRedaction,
+			Pattern:     "s3cr3t",
+			Replacement: "<secret>",
+		}, {
+			Type:        models.RegexRedaction,
+			Pattern:     `card = '(\d{4})\d+'`,
+			Replacement: "card = '${1}****'",
+		}},
+	}} {
+		p, err := compileRedactionPolicy(policy)
+		require.NoError(t, err)
+		policies = append(policies, p)
+	}
+
+	const example = "SELECT * FROM users WHERE email = 'john.doe@example.com' AND password = 's3cr3t' AND card = '4111111111111111'"
+
+	for name, tc := range map[string]struct {
+		labels   map[string]string
+		expected string
+	}{
+		"Dev": {
+			labels:   map[string]string{"environment": "dev"},
+			expected: "SELECT * FROM users WHERE email = '***' AND password = 's3cr3t' AND card = '4111111111111111'",
+		},
+		"Prod": {
+			labels:   map[string]string{"environment": "prod"},
+			expected: "SELECT * FROM users WHERE email = '***' AND password = '<secret>' AND card = '4111****'",
+		},
+		"Unanchored": {
+			labels:   map[string]string{"environment": "preprod"},
+			expected: "SELECT * FROM users WHERE email = '***' AND password = 's3cr3t' AND card = '4111111111111111'",
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mb := &qanpb.MetricsBucket{Example: example, Fingerprint: "SELECT * FROM users WHERE email = 'admin@example.com'"}
+			fillMySQL(mb, &agentpb.MetricsBucket_MySQL{}, redactionRulesFor(policies, tc.labels))
+			assert.Equal(t, tc.expected, mb.Example)
+			assert.Equal(t, "SELECT * FROM users WHERE email = '***'", mb.Fingerprint)
+		})
+	}
+}
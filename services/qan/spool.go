@@ -0,0 +1,311 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package qan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	qanpb "github.com/percona/pmm/api/qanpb"
+	"github.com/pkg/errors"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	prometheusNamespace = "pmm_managed"
+	prometheusSubsystem = "qan_spool"
+
+	spoolFileExt = ".pb"
+)
+
+// Spool is a bounded on-disk FIFO queue of CollectRequests waiting to be sent to qan-api.
+// Each request is stored in a separate file; when the size limit is reached, the oldest requests are dropped.
+type Spool struct {
+	dir     string
+	maxSize int64
+	l       *logrus.Entry
+
+	rw    sync.Mutex
+	files []spoolFile // oldest first
+	size  int64
+	seq   uint64
+
+	added chan struct{}
+
+	mRequests prom.Gauge
+	mBytes    prom.Gauge
+	mDropped  *prom.CounterVec
+}
+
+type spoolFile struct {
+	name string
+	size int64
+}
+
+// NewSpool creates a spool in the given directory, picking up requests left from the previous run.
+func NewSpool(dir string, maxSize int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := &Spool{
+		dir:     dir,
+		maxSize: maxSize,
+		l:       logrus.WithField("component", "qan/spool"),
+		added:   make(chan struct{}, 1),
+		mRequests: prom.NewGauge(prom.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "requests",
+			Help:      "The current number of QAN requests waiting in the spool.",
+		}),
+		mBytes: prom.NewGauge(prom.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "bytes",
+			Help:      "The current size of QAN requests waiting in the spool.",
+		}),
+		mDropped: prom.NewCounterVec(prom.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "dropped_total",
+			Help:      "A total number of QAN requests dropped from the spool.",
+		}, []string{"reason"}),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolFileExt+".tmp") {
+			// incomplete file written before crash
+			if err = os.Remove(filepath.Join(dir, e.Name())); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			continue
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), spoolFileExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		s.files = append(s.files, spoolFile{name: e.Name(), size: info.Size()})
+		s.size += info.Size()
+	}
+	// names start with zero-padded timestamps
+	sort.Slice(s.files, func(i, j int) bool { return s.files[i].name < s.files[j].name })
+
+	if len(s.files) != 0 {
+		s.l.Infof("Found %d spooled requests (%d bytes).", len(s.files), s.size)
+		s.added <- struct{}{}
+	}
+	s.updateMetrics()
+	return s, nil
+}
+
+// Put adds request to the end of the spool.
+func (s *Spool) Put(req *qanpb.CollectRequest) error {
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	size := int64(len(b))
+	if size > s.maxSize {
+		s.mDropped.WithLabelValues("too_large").Inc()
+		return errors.Errorf("request of %d bytes is larger than spool size limit %d", size, s.maxSize)
+	}
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for s.size+size > s.maxSize && len(s.files) != 0 {
+		s.l.Warnf("Spool is full, dropping the oldest request %s.", s.files[0].name)
+		s.removeFirst()
+		s.mDropped.WithLabelValues("full").Inc()
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d%s", time.Now().UnixNano(), s.seq, spoolFileExt)
+	if err = s.writeFile(name, b); err != nil {
+		return err
+	}
+
+	s.files = append(s.files, spoolFile{name: name, size: size})
+	s.size += size
+	s.updateMetrics()
+
+	select {
+	case s.added <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// PeekBatch returns the oldest requests merged into a single request with at most maxBuckets buckets
+// (but at least the oldest request), and their names in order; names are empty if spool is empty.
+// If the oldest request can't be read, the error is returned with its name.
+func (s *Spool) PeekBatch(maxBuckets int) (*qanpb.CollectRequest, []string, error) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	res := new(qanpb.CollectRequest)
+	var names []string
+	for _, f := range s.files {
+		req, err := s.read(f.name)
+		if err != nil {
+			if len(names) == 0 {
+				return nil, []string{f.name}, err
+			}
+			break
+		}
+		if len(names) != 0 && len(res.MetricsBucket)+len(req.MetricsBucket) > maxBuckets {
+			break
+		}
+
+		res.MetricsBucket = append(res.MetricsBucket, req.MetricsBucket...)
+		names = append(names, f.name)
+	}
+	return res, names, nil
+}
+
+// Remove removes the oldest request returned by PeekBatch. It does nothing if it was already dropped.
+func (s *Spool) Remove(name string) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if len(s.files) != 0 && s.files[0].name == name {
+		s.removeFirst()
+		s.updateMetrics()
+	}
+}
+
+// Drop removes the oldest request returned by PeekBatch that can't be sent and counts it with the given reason.
+func (s *Spool) Drop(name, reason string) {
+	s.Remove(name)
+	s.mDropped.WithLabelValues(reason).Inc()
+}
+
+// Len returns the number of spooled requests.
+func (s *Spool) Len() int {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	return len(s.files)
+}
+
+// Added returns a channel that receives a value when request is added to the empty spool.
+func (s *Spool) Added() <-chan struct{} {
+	return s.added
+}
+
+// read reads spooled request.
+func (s *Spool) read(name string) (*qanpb.CollectRequest, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, name)) //nolint:gosec
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var req qanpb.CollectRequest
+	if err = proto.Unmarshal(b, &req); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &req, nil
+}
+
+// writeFile atomically writes a new file with the given name to the spool directory.
+// File data is synced before rename, and directory is synced after it,
+// so a crash leaves either the complete file or no file at all.
+func (s *Spool) writeFile(name string, b []byte) error {
+	tmp := filepath.Join(s.dir, name+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640) //nolint:gosec
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+
+	if err = os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		_ = os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+	return syncDir(s.dir)
+}
+
+// syncDir makes directory entries changes durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir) //nolint:gosec
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err = d.Sync(); err != nil {
+		d.Close() //nolint:errcheck
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(d.Close())
+}
+
+// removeFirst removes the oldest file. The caller must hold the lock.
+func (s *Spool) removeFirst() {
+	f := s.files[0]
+	if err := os.Remove(filepath.Join(s.dir, f.name)); err != nil && !os.IsNotExist(err) {
+		s.l.Errorf("Failed to remove spooled request: %s.", err)
+	}
+	s.files = s.files[1:]
+	s.size -= f.size
+}
+
+// updateMetrics updates gauges. The caller must hold the lock.
+func (s *Spool) updateMetrics() {
+	s.mRequests.Set(float64(len(s.files)))
+	s.mBytes.Set(float64(s.size))
+}
+
+// Describe implements prometheus.Collector.
+func (s *Spool) Describe(ch chan<- *prom.Desc) {
+	s.mRequests.Describe(ch)
+	s.mBytes.Describe(ch)
+	s.mDropped.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (s *Spool) Collect(ch chan<- prom.Metric) {
+	s.mRequests.Collect(ch)
+	s.mBytes.Collect(ch)
+	s.mDropped.Collect(ch)
+}
+
+// check interfaces.
+var (
+	_ prom.Collector = (*Spool)(nil)
+)
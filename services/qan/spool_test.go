@@ -0,0 +1,229 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package qan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qanpb "github.com/percona/pmm/api/qanpb"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func collectRequest(queryID string) *qanpb.CollectRequest {
+	return &qanpb.CollectRequest{
+		MetricsBucket: []*qanpb.MetricsBucket{{Queryid: queryID, Fingerprint: "SELECT * FROM t WHERE id = ?"}},
+	}
+}
+
+func TestSpool(t *testing.T) {
+	t.Parallel()
+
+	size := int64(proto.Size(collectRequest("q1")))
+
+	t.Run("FIFO", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		s, err := NewSpool(dir, 10*size)
+		require.NoError(t, err)
+
+		_, names, err := s.PeekBatch(10)
+		require.NoError(t, err)
+		assert.Empty(t, names)
+
+		require.NoError(t, s.Put(collectRequest("q1")))
+		require.NoError(t, s.Put(collectRequest("q2")))
+		assert.Equal(t, 2, s.Len())
+
+		// requests are kept across restarts, incomplete files are removed
+		tmp := filepath.Join(dir, "00000000000000000000-0000000000.pb.tmp")
+		require.NoError(t, os.WriteFile(tmp, []byte("garbage"), 0o640))
+		s, err = NewSpool(dir, 10*size)
+		require.NoError(t, err)
+		assert.Equal(t, 2, s.Len())
+		assert.NoFileExists(t, tmp)
+
+		require.NoError(t, s.Put(collectRequest("q3")))
+		for _, queryIDs := range [][]string{{"q1", "q2"}, {"q3"}} {
+			req, names, err := s.PeekBatch(2)
+			require.NoError(t, err)
+			require.Len(t, names, len(queryIDs))
+			require.Len(t, req.MetricsBucket, len(queryIDs))
+			for i, queryID := range queryIDs {
+				assert.Equal(t, queryID, req.MetricsBucket[i].Queryid)
+			}
+			for _, name := range names {
+				s.Remove(name)
+			}
+		}
+		assert.Equal(t, 0, s.Len())
+
+		// the oldest request is returned even if it is larger than the limit
+		require.NoError(t, s.Put(collectRequest("q4")))
+		req, names, err := s.PeekBatch(0)
+		require.NoError(t, err)
+		assert.Len(t, names, 1)
+		assert.Equal(t, "q4", req.MetricsBucket[0].Queryid)
+	})
+
+	t.Run("Full", func(t *testing.T) {
+		t.Parallel()
+
+		s, err := NewSpool(t.TempDir(), 2*size)
+		require.NoError(t, err)
+
+		for _, queryID := range []string{"q1", "q2", "q3"} {
+			require.NoError(t, s.Put(collectRequest(queryID)))
+		}
+		assert.Equal(t, 2, s.Len())
+
+		req, _, err := s.PeekBatch(1)
+		require.NoError(t, err)
+		assert.Equal(t, "q2", req.MetricsBucket[0].Queryid)
+
+		s, err = NewSpool(t.TempDir(), size-1)
+		require.NoError(t, err)
+		expected := fmt.Sprintf("request of %d bytes is larger than spool size limit %d", size, size-1)
+		assert.EqualError(t, s.Put(collectRequest("q1")), expected)
+		assert.Equal(t, 0, s.Len())
+	})
+}
+
+func TestClientSpool(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewSpool(t.TempDir(), 1024*1024)
+	require.NoError(t, err)
+
+	c := &mockQanCollectorClient{}
+	c.Test(t)
+	t.Cleanup(func() { c.AssertExpectations(t) })
+
+	client := &Client{
+		c:     c,
+		spool: s,
+		l:     logrus.WithField("test", t.Name()),
+	}
+
+	ctx := context.Background()
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+	c.On("Collect", ctx, mock.Anything).Return(nil, unavailable).Once()
+	require.NoError(t, client.send(ctx, collectRequest("q1")))
+
+	// older requests are waiting, so a new one goes straight to the spool
+	require.NoError(t, client.send(ctx, collectRequest("q2")))
+	assert.Equal(t, 2, s.Len())
+
+	// requests rejected by qan-api are not spooled
+	c.On("Collect", ctx, mock.Anything).Return(nil, status.Error(codes.InvalidArgument, "bad bucket")).Once()
+	assert.Error(t, (&Client{c: c, spool: nil, l: client.l}).send(ctx, collectRequest("q3")))
+
+	// spooled requests are sent in a single batch
+	runCtx, cancel := context.WithCancel(ctx)
+	var sent []string
+	c.On("Collect", runCtx, mock.Anything).Return(nil, unavailable).Once()
+	c.On("Collect", runCtx, mock.Anything).Return(&qanpb.CollectResponse{}, nil).Once().Run(func(args mock.Arguments) {
+		for _, b := range args.Get(1).(*qanpb.CollectRequest).MetricsBucket {
+			sent = append(sent, b.Queryid)
+		}
+		cancel()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		client.Run(runCtx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("spool is not drained")
+	}
+
+	assert.Equal(t, []string{"q1", "q2"}, sent)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestClientSpoolRejected(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewSpool(t.TempDir(), 1024*1024)
+	require.NoError(t, err)
+	for _, queryID := range []string{"q1", "bad", "q3"} {
+		require.NoError(t, s.Put(collectRequest(queryID)))
+	}
+
+	c := &mockQanCollectorClient{}
+	c.Test(t)
+	t.Cleanup(func() { c.AssertExpectations(t) })
+
+	client := &Client{
+		c:     c,
+		spool: s,
+		l:     logrus.WithField("test", t.Name()),
+	}
+
+	// rejected batch is retried one by one, and only the rejected request is dropped
+	ctx, cancel := context.WithCancel(context.Background())
+	var sent [][]string
+	c.On("Collect", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		var queryIDs []string
+		for _, b := range args.Get(1).(*qanpb.CollectRequest).MetricsBucket {
+			queryIDs = append(queryIDs, b.Queryid)
+		}
+		sent = append(sent, queryIDs)
+	}).Return(func(ctx context.Context, req *qanpb.CollectRequest, opts ...grpc.CallOption) *qanpb.CollectResponse {
+		return &qanpb.CollectResponse{}
+	}, func(ctx context.Context, req *qanpb.CollectRequest, opts ...grpc.CallOption) error {
+		for _, b := range req.MetricsBucket {
+			if b.Queryid == "bad" {
+				return status.Error(codes.InvalidArgument, "bad bucket")
+			}
+		}
+		if s.Len() == 1 {
+			cancel()
+		}
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		client.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("spool is not drained")
+	}
+
+	expected := [][]string{{"q1", "bad", "q3"}, {"q1"}, {"bad", "q3"}, {"bad"}, {"q3"}}
+	assert.Equal(t, expected, sent)
+	assert.Equal(t, 0, s.Len())
+}
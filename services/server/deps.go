@@ -24,6 +24,7 @@ import (
 	"github.com/percona/pmm/version"
 
 	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/victoriametrics"
 )
 
 //go:generate mockery -name=grafanaClient -case=snake -inpkg -testonly
@@ -37,6 +38,8 @@ import (
 //go:generate mockery -name=rulesService -case=snake -inpkg -testonly
 //go:generate mockery -name=emailer -case=snake -inpkg -testonly
 //go:generate mockery -name=templatesService -case=snake -inpkg -testonly
+//go:generate mockery -name=remoteWriteService -case=snake -inpkg -testonly
+//go:generate mockery -name=retentionValidator -case=snake -inpkg -testonly
 
 // healthChecker interface wraps all services that implements the IsReady method to report the
 // service health for the Readiness check.
@@ -91,6 +94,20 @@ type vmAlertExternalRules interface {
 	WriteRules(rules string) error
 }
 
+// retentionValidator is a subset of methods of victoriametrics.Service used by this package.
+// We use it instead of real type for testing.
+type retentionValidator interface {
+	ValidateRetentionFlags(ctx context.Context, settings *models.Settings) error
+}
+
+// remoteWriteService is a subset of methods of victoriametrics.RemoteWriteService used by this package.
+// We use it instead of real type for testing.
+type remoteWriteService interface {
+	ValidateConfiguration(ctx context.Context, rw *models.RemoteWrite, interval time.Duration) error
+	WriteConfiguration(settings *models.Settings) error
+	Status(rw *models.RemoteWrite) []*victoriametrics.RemoteWriteTargetStatus
+}
+
 // supervisordService is a subset of methods of supervisord.Service used by this package.
 // We use it instead of real type for testing and to avoid dependency cycle.
 type supervisordService interface {
@@ -0,0 +1,250 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// RemoteWriteTarget represents remote-write target in JSON API requests and responses.
+// Password, bearer token and TLS key are never returned; *_set fields are returned instead.
+type RemoteWriteTarget struct {
+	Name                  string            `json:"name"`
+	URL                   string            `json:"url"`
+	Username              string            `json:"username,omitempty"`
+	Password              string            `json:"password,omitempty"`
+	PasswordSet           bool              `json:"password_set,omitempty"`
+	BearerToken           string            `json:"bearer_token,omitempty"`
+	BearerTokenSet        bool              `json:"bearer_token_set,omitempty"`
+	Headers               map[string]string `json:"headers,omitempty"`
+	TLSCA                 string            `json:"tls_ca,omitempty"`
+	TLSCert               string            `json:"tls_cert,omitempty"`
+	TLSKey                string            `json:"tls_key,omitempty"`
+	TLSKeySet             bool              `json:"tls_key_set,omitempty"`
+	TLSServerName         string            `json:"tls_server_name,omitempty"`
+	TLSInsecureSkipVerify bool              `json:"tls_insecure_skip_verify,omitempty"`
+	RelabelConfigs        string            `json:"relabel_configs,omitempty"`
+}
+
+// RemoteWriteQueue represents remote-write queue settings in JSON API requests and responses.
+type RemoteWriteQueue struct {
+	Queues int `json:"queues,omitempty"`
+	// Go duration.
+	FlushInterval         string `json:"flush_interval,omitempty"`
+	MaxDiskUsagePerTarget int64  `json:"max_disk_usage_per_target,omitempty"`
+}
+
+// GetRemoteWriteRequest is a request of GetRemoteWrite method.
+type GetRemoteWriteRequest struct{}
+
+// GetRemoteWriteResponse is a response of GetRemoteWrite method.
+type GetRemoteWriteResponse struct {
+	Targets []*RemoteWriteTarget `json:"targets"`
+	Queue   *RemoteWriteQueue    `json:"queue"`
+}
+
+// ChangeRemoteWriteRequest is a request of ChangeRemoteWrite method.
+// All remote-write settings are replaced; empty password, bearer token and TLS key
+// keep existing values of the target with the same name.
+type ChangeRemoteWriteRequest struct {
+	Targets []*RemoteWriteTarget `json:"targets"`
+	Queue   *RemoteWriteQueue    `json:"queue"`
+}
+
+// ChangeRemoteWriteResponse is a response of ChangeRemoteWrite method.
+type ChangeRemoteWriteResponse struct{}
+
+// GetRemoteWriteStatusRequest is a request of GetRemoteWriteStatus method.
+type GetRemoteWriteStatusRequest struct{}
+
+// RemoteWriteTargetStatus represents forwarding health of a single remote-write target.
+type RemoteWriteTargetStatus struct {
+	Name           string  `json:"name"`
+	URL            string  `json:"url"`
+	Healthy        bool    `json:"healthy"`
+	Error          string  `json:"error,omitempty"`
+	SentRequests   float64 `json:"sent_requests"`
+	FailedRequests float64 `json:"failed_requests"`
+	DroppedBlocks  float64 `json:"dropped_blocks"`
+	PendingBytes   float64 `json:"pending_bytes"`
+}
+
+// GetRemoteWriteStatusResponse is a response of GetRemoteWriteStatus method.
+type GetRemoteWriteStatusResponse struct {
+	Targets []*RemoteWriteTargetStatus `json:"targets"`
+}
+
+// GetRemoteWrite returns remote-write settings.
+func (s *Server) GetRemoteWrite(ctx context.Context, req *GetRemoteWriteRequest) (*GetRemoteWriteResponse, error) {
+	settings, err := models.GetSettings(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	rw := settings.RemoteWrite
+	res := &GetRemoteWriteResponse{
+		Targets: make([]*RemoteWriteTarget, len(rw.Targets)),
+		Queue: &RemoteWriteQueue{
+			Queues:                rw.Queue.Queues,
+			MaxDiskUsagePerTarget: rw.Queue.MaxDiskUsagePerTarget,
+		},
+	}
+	if rw.Queue.FlushInterval != 0 {
+		res.Queue.FlushInterval = rw.Queue.FlushInterval.String()
+	}
+	for i, t := range rw.Targets {
+		res.Targets[i] = &RemoteWriteTarget{
+			Name:                  t.Name,
+			URL:                   t.URL,
+			Username:              t.Username,
+			PasswordSet:           t.Password != "",
+			BearerTokenSet:        t.BearerToken != "",
+			Headers:               t.Headers,
+			TLSCA:                 t.TLSCA,
+			TLSCert:               t.TLSCert,
+			TLSKeySet:             t.TLSKey != "",
+			TLSServerName:         t.TLSServerName,
+			TLSInsecureSkipVerify: t.TLSInsecureSkipVerify,
+			RelabelConfigs:        t.RelabelConfigs,
+		}
+	}
+	return res, nil
+}
+
+// ChangeRemoteWrite replaces remote-write settings and reconfigures vmagent forwarding metrics.
+func (s *Server) ChangeRemoteWrite(ctx context.Context, req *ChangeRemoteWriteRequest) (*ChangeRemoteWriteResponse, error) {
+	s.envRW.RLock()
+	defer s.envRW.RUnlock()
+
+	rw := &models.RemoteWrite{
+		Targets: make([]models.RemoteWriteTarget, len(req.Targets)),
+	}
+	if q := req.Queue; q != nil {
+		rw.Queue.Queues = q.Queues
+		rw.Queue.MaxDiskUsagePerTarget = q.MaxDiskUsagePerTarget
+		if q.FlushInterval != "" {
+			d, err := time.ParseDuration(q.FlushInterval)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "Invalid flush_interval %q.", q.FlushInterval)
+			}
+			rw.Queue.FlushInterval = d
+		}
+	}
+	for i, t := range req.Targets {
+		rw.Targets[i] = models.RemoteWriteTarget{
+			Name:                  t.Name,
+			URL:                   t.URL,
+			Username:              t.Username,
+			Password:              t.Password,
+			BearerToken:           t.BearerToken,
+			Headers:               t.Headers,
+			TLSCA:                 t.TLSCA,
+			TLSCert:               t.TLSCert,
+			TLSKey:                t.TLSKey,
+			TLSServerName:         t.TLSServerName,
+			TLSInsecureSkipVerify: t.TLSInsecureSkipVerify,
+			RelabelConfigs:        t.RelabelConfigs,
+		}
+	}
+
+	errTX := s.db.InTransaction(func(tx *reform.TX) error {
+		oldSettings, err := models.GetSettings(tx)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		keepRemoteWriteSecrets(rw, &oldSettings.RemoteWrite)
+
+		var errInvalidArgument *models.ErrInvalidArgument
+		newSettings, err := models.UpdateSettings(tx, &models.ChangeSettingsParams{RemoteWrite: rw})
+		switch {
+		case err == nil:
+		case errors.As(err, &errInvalidArgument):
+			return status.Errorf(codes.InvalidArgument, "Invalid argument: %s.", errInvalidArgument.Details)
+		default:
+			return errors.WithStack(err)
+		}
+
+		return s.remoteWrite.ValidateConfiguration(ctx, rw, newSettings.MetricsResolutions.MR)
+	})
+	if errTX != nil {
+		return nil, errTX
+	}
+
+	if err := s.UpdateConfigurations(ctx); err != nil {
+		return nil, err
+	}
+	return &ChangeRemoteWriteResponse{}, nil
+}
+
+// keepRemoteWriteSecrets sets empty secrets of new targets to values of old targets with the same names.
+func keepRemoteWriteSecrets(rw, old *models.RemoteWrite) {
+	oldTargets := make(map[string]*models.RemoteWriteTarget, len(old.Targets))
+	for i := range old.Targets {
+		oldTargets[old.Targets[i].Name] = &old.Targets[i]
+	}
+
+	for i := range rw.Targets {
+		t := &rw.Targets[i]
+		o := oldTargets[t.Name]
+		if o == nil {
+			continue
+		}
+		if t.Password == "" && t.Username != "" && t.Username == o.Username {
+			t.Password = o.Password
+		}
+		if t.BearerToken == "" && t.Username == "" {
+			t.BearerToken = o.BearerToken
+		}
+		if t.TLSKey == "" && t.TLSCert != "" && t.TLSCert == o.TLSCert {
+			t.TLSKey = o.TLSKey
+		}
+	}
+}
+
+// GetRemoteWriteStatus returns forwarding health of remote-write targets.
+func (s *Server) GetRemoteWriteStatus(ctx context.Context, req *GetRemoteWriteStatusRequest) (*GetRemoteWriteStatusResponse, error) {
+	settings, err := models.GetSettings(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := s.remoteWrite.Status(&settings.RemoteWrite)
+	res := &GetRemoteWriteStatusResponse{
+		Targets: make([]*RemoteWriteTargetStatus, len(statuses)),
+	}
+	for i, st := range statuses {
+		res.Targets[i] = &RemoteWriteTargetStatus{
+			Name:           st.Name,
+			URL:            st.URL,
+			Healthy:        st.Healthy,
+			Error:          st.Error,
+			SentRequests:   st.SentRequests,
+			FailedRequests: st.FailedRequests,
+			DroppedBlocks:  st.DroppedBlocks,
+			PendingBytes:   st.PendingBytes,
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,144 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/percona/pmm/api/serverpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// RetentionTier represents retention tier in JSON API requests and responses.
+// All durations are Go durations.
+type RetentionTier struct {
+	Name         string                `json:"name"`
+	Labels       map[string]string     `json:"labels"`
+	Retention    string                `json:"retention"`
+	Downsampling []*DownsamplingPeriod `json:"downsampling,omitempty"`
+}
+
+// DownsamplingPeriod represents downsampling period of retention tier.
+type DownsamplingPeriod struct {
+	Offset   string `json:"offset"`
+	Interval string `json:"interval"`
+}
+
+// GetRetentionTiersRequest is a request of GetRetentionTiers method.
+type GetRetentionTiersRequest struct{}
+
+// ChangeRetentionTiersRequest is a request of ChangeRetentionTiers method.
+type ChangeRetentionTiersRequest struct {
+	// Retention tiers; replace existing ones.
+	RetentionTiers       []*RetentionTier `json:"retention_tiers"`
+	RemoveRetentionTiers bool             `json:"remove_retention_tiers"`
+}
+
+// RetentionTiersResponse is a response of GetRetentionTiers and ChangeRetentionTiers methods.
+type RetentionTiersResponse struct {
+	// Retention tiers of metrics selected by labels; metrics not selected by any tier are kept for data_retention.
+	RetentionTiers []*RetentionTier `json:"retention_tiers"`
+}
+
+// GetRetentionTiers returns retention tiers that are not described with protobuf and not returned by GetSettings.
+func (s *Server) GetRetentionTiers(ctx context.Context, req *GetRetentionTiersRequest) (*RetentionTiersResponse, error) {
+	settings, err := models.GetSettings(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return &RetentionTiersResponse{RetentionTiers: convertRetentionTiers(settings.RetentionTiers)}, nil
+}
+
+// ChangeRetentionTiers replaces or removes retention tiers.
+func (s *Server) ChangeRetentionTiers(ctx context.Context, req *ChangeRetentionTiersRequest) (*RetentionTiersResponse, error) {
+	switch {
+	case len(req.RetentionTiers) != 0 && req.RemoveRetentionTiers:
+		return nil, status.Error(codes.InvalidArgument, "Both retention_tiers and remove_retention_tiers are present.")
+	case len(req.RetentionTiers) == 0 && !req.RemoveRetentionTiers:
+		return nil, status.Error(codes.InvalidArgument, "Either retention_tiers or remove_retention_tiers is expected.")
+	}
+
+	tiers := make([]models.RetentionTier, 0, len(req.RetentionTiers))
+	for _, t := range req.RetentionTiers {
+		tier, err := convertRetentionTier(t)
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, *tier)
+	}
+
+	_, settings, err := s.changeSettings(ctx, new(serverpb.ChangeSettingsRequest), tiers, req.RemoveRetentionTiers)
+	if err != nil {
+		return nil, err
+	}
+	return &RetentionTiersResponse{RetentionTiers: convertRetentionTiers(settings.RetentionTiers)}, nil
+}
+
+func convertRetentionTiers(tiers []models.RetentionTier) []*RetentionTier {
+	res := make([]*RetentionTier, len(tiers))
+	for i, t := range tiers {
+		tier := &RetentionTier{
+			Name:      t.Name,
+			Labels:    t.Labels,
+			Retention: t.Retention.String(),
+		}
+		for _, p := range t.Downsampling {
+			tier.Downsampling = append(tier.Downsampling, &DownsamplingPeriod{
+				Offset:   p.Offset.String(),
+				Interval: p.Interval.String(),
+			})
+		}
+		res[i] = tier
+	}
+	return res
+}
+
+func convertRetentionTier(t *RetentionTier) (*models.RetentionTier, error) {
+	parse := func(value, fieldName string) (time.Duration, error) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "Invalid %s %q of retention tier %q.", fieldName, value, t.Name)
+		}
+		return d, nil
+	}
+
+	retention, err := parse(t.Retention, "retention")
+	if err != nil {
+		return nil, err
+	}
+	res := &models.RetentionTier{
+		Name:      t.Name,
+		Labels:    t.Labels,
+		Retention: retention,
+	}
+
+	for _, p := range t.Downsampling {
+		var period models.DownsamplingPeriod
+		if period.Offset, err = parse(p.Offset, "downsampling offset"); err != nil {
+			return nil, err
+		}
+		if period.Interval, err = parse(p.Interval, "downsampling interval"); err != nil {
+			return nil, err
+		}
+		res.Downsampling = append(res.Downsampling, period)
+	}
+	return res, nil
+}
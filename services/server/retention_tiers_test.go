@@ -0,0 +1,77 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestChangeRetentionTiers(t *testing.T) {
+	t.Parallel()
+
+	// invalid requests are rejected before settings are changed
+	s := new(Server)
+	ctx := context.Background()
+	dev := &RetentionTier{Name: "dev", Labels: map[string]string{"environment": "dev"}, Retention: "336h"}
+
+	_, err := s.ChangeRetentionTiers(ctx, &ChangeRetentionTiersRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.ChangeRetentionTiers(ctx, &ChangeRetentionTiersRequest{
+		RetentionTiers:       []*RetentionTier{dev},
+		RemoveRetentionTiers: true,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.ChangeRetentionTiers(ctx, &ChangeRetentionTiersRequest{
+		RetentionTiers: []*RetentionTier{{Name: "dev", Labels: dev.Labels, Retention: "14d"}},
+	})
+	assert.Equal(t, status.Error(codes.InvalidArgument, `Invalid retention "14d" of retention tier "dev".`), err)
+}
+
+func TestConvertRetentionTiers(t *testing.T) {
+	t.Parallel()
+
+	tier := &RetentionTier{
+		Name:         "prod",
+		Labels:       map[string]string{"environment": "prod"},
+		Retention:    "8760h0m0s",
+		Downsampling: []*DownsamplingPeriod{{Offset: "720h0m0s", Interval: "5m0s"}},
+	}
+	actual, err := convertRetentionTier(tier)
+	require.NoError(t, err)
+	expected := &models.RetentionTier{
+		Name:         "prod",
+		Labels:       map[string]string{"environment": "prod"},
+		Retention:    8760 * time.Hour,
+		Downsampling: []models.DownsamplingPeriod{{Offset: 720 * time.Hour, Interval: 5 * time.Minute}},
+	}
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, []*RetentionTier{tier}, convertRetentionTiers([]models.RetentionTier{*actual}))
+
+	_, err = convertRetentionTier(&RetentionTier{Name: "prod", Retention: "8760h", Downsampling: []*DownsamplingPeriod{{Offset: "30d"}}})
+	assert.Equal(t, status.Error(codes.InvalidArgument, `Invalid downsampling offset "30d" of retention tier "prod".`), err)
+}
@@ -58,6 +58,8 @@ type Server struct {
 	agentsState          agentsStateUpdater
 	vmalert              vmAlertService
 	vmalertExternalRules vmAlertExternalRules
+	remoteWrite          remoteWriteService
+	retentionValidator   retentionValidator
 	alertmanager         alertmanagerService
 	checksService        checksService
 	templatesService     templatesService
@@ -101,6 +103,8 @@ type Params struct {
 	ChecksService        checksService
 	TemplatesService     templatesService
 	VMAlertExternalRules vmAlertExternalRules
+	RemoteWrite          remoteWriteService
+	RetentionValidator   retentionValidator
 	Supervisord          supervisordService
 	TelemetryService     telemetryService
 	AwsInstanceChecker   *AWSInstanceChecker
@@ -127,6 +131,8 @@ func NewServer(params *Params) (*Server, error) {
 		checksService:        params.ChecksService,
 		templatesService:     params.TemplatesService,
 		vmalertExternalRules: params.VMAlertExternalRules,
+		remoteWrite:          params.RemoteWrite,
+		retentionValidator:   params.RetentionValidator,
 		supervisord:          params.Supervisord,
 		telemetryService:     params.TelemetryService,
 		awsInstanceChecker:   params.AwsInstanceChecker,
@@ -156,8 +162,18 @@ func (s *Server) UpdateSettingsFromEnv(env []string) []error {
 	}
 
 	err := s.db.InTransaction(func(tx *reform.TX) error {
-		_, err := models.UpdateSettings(tx, envSettings)
-		return err
+		settings, err := models.UpdateSettings(tx, envSettings)
+		if err != nil {
+			return err
+		}
+
+		// VictoriaMetrics would not start with unsupported flags (for example, Enterprise-only ones)
+		if len(envSettings.RetentionTiers) != 0 {
+			if err = s.retentionValidator.ValidateRetentionFlags(context.Background(), settings); err != nil {
+				return errors.Wrap(err, "DATA_RETENTION_TIERS")
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return []error{err}
@@ -569,11 +585,27 @@ func (s *Server) validateChangeSettingsRequest(ctx context.Context, req *serverp
 
 // ChangeSettings changes PMM Server settings.
 func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSettingsRequest) (*serverpb.ChangeSettingsResponse, error) {
+	res, _, err := s.changeSettings(ctx, req, nil, false)
+	return res, err
+}
+
+// changeSettings changes PMM Server settings and retention tiers that are not described with protobuf.
+// It returns API response and new settings.
+func (s *Server) changeSettings(ctx context.Context, req *serverpb.ChangeSettingsRequest,
+	retentionTiers []models.RetentionTier, removeRetentionTiers bool,
+) (*serverpb.ChangeSettingsResponse, *models.Settings, error) {
 	s.envRW.RLock()
 	defer s.envRW.RUnlock()
 
+	// gRPC validator interceptor is not used for calls from JSON API handlers
+	if err := req.Validate(); err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err := s.validateChangeSettingsRequest(ctx, req); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if (len(retentionTiers) != 0 || removeRetentionTiers) && len(s.envSettings.RetentionTiers) != 0 {
+		return nil, nil, status.Error(codes.FailedPrecondition, "Retention tiers are set via DATA_RETENTION_TIERS environment variable.")
 	}
 
 	var newSettings, oldSettings *models.Settings
@@ -621,6 +653,9 @@ func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSetting
 
 			EnableDBaaS:  req.EnableDbaas,
 			DisableDBaaS: req.DisableDbaas,
+
+			RetentionTiers:       retentionTiers,
+			RemoveRetentionTiers: removeRetentionTiers,
 		}
 
 		if req.EmailAlertingSettings != nil {
@@ -654,6 +689,13 @@ func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSetting
 			return errors.WithStack(err)
 		}
 
+		// VictoriaMetrics would not start with unsupported flags
+		if len(retentionTiers) != 0 {
+			if err = s.retentionValidator.ValidateRetentionFlags(ctx, newSettings); err != nil {
+				return err
+			}
+		}
+
 		// absent value means "do not change"
 		if req.SshKey != "" {
 			if err = s.writeSSHKey(req.SshKey); err != nil {
@@ -675,11 +717,11 @@ func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSetting
 		return nil
 	})
 	if errTX != nil {
-		return nil, errTX
+		return nil, nil, errTX
 	}
 
 	if err := s.UpdateConfigurations(ctx); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// When IA moved from disabled state to enabled create rules files.
@@ -729,7 +771,7 @@ func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSetting
 	if !oldSettings.DBaaS.Enabled && newSettings.DBaaS.Enabled {
 		err := s.dbaasClient.Connect(ctx)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -737,13 +779,13 @@ func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSetting
 	if oldSettings.DBaaS.Enabled && !newSettings.DBaaS.Enabled {
 		err := s.dbaasClient.Disconnect()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	if isAgentsStateUpdateNeeded(req.MetricsResolutions) {
 		if err := s.agentsState.UpdateAgentsState(ctx); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -751,7 +793,7 @@ func (s *Server) ChangeSettings(ctx context.Context, req *serverpb.ChangeSetting
 
 	return &serverpb.ChangeSettingsResponse{
 		Settings: s.convertSettings(newSettings, err == nil),
-	}, nil
+	}, newSettings, nil
 }
 
 // TestEmailAlertingSettings tests email alerting SMTP settings by sending testing email.
@@ -803,6 +845,10 @@ func (s *Server) UpdateConfigurations(ctx context.Context) error {
 			return errors.Wrap(err, "failed to get SSO details")
 		}
 	}
+	// vmagent started by supervisord uses remote-write configuration files
+	if err := s.remoteWrite.WriteConfiguration(settings); err != nil {
+		return errors.Wrap(err, "failed to write remote-write configuration")
+	}
 	if err := s.supervisord.UpdateConfiguration(settings, ssoDetails); err != nil {
 		return errors.Wrap(err, "failed to update supervisord configuration")
 	}
@@ -74,6 +74,10 @@ func TestServer(t *testing.T) {
 		var ts mockTelemetryService
 		ts.Test(t)
 
+		var rw mockRemoteWriteService
+		rw.Test(t)
+		rw.On("WriteConfiguration", mock.Anything).Return(nil)
+
 		s, err := NewServer(&Params{
 			DB:                   reform.NewDB(sqlDB, postgresql.Dialect, reform.NewPrintfLogger(t.Logf)),
 			VMDB:                 &mvmdb,
@@ -84,6 +88,7 @@ func TestServer(t *testing.T) {
 			AgentsStateUpdater:   mState,
 			Supervisord:          &r,
 			VMAlertExternalRules: &par,
+			RemoteWrite:          &rw,
 			TelemetryService:     &ts,
 		})
 		require.NoError(t, err)
@@ -190,6 +195,28 @@ func TestServer(t *testing.T) {
 			require.EqualError(t, errs[0], `environment variable "DATA_RETENTION=30" has invalid duration 30`)
 			assert.Zero(t, s.envSettings.DataRetention)
 		})
+
+		t.Run("RetentionTiersRejectedByVictoriaMetrics", func(t *testing.T) {
+			s := newServer(t)
+			var rv mockRetentionValidator
+			rv.Test(t)
+			rv.On("ValidateRetentionFlags", mock.Anything, mock.Anything).
+				Return(status.Error(codes.FailedPrecondition, "VictoriaMetrics does not accept retention tiers: unsupported flag.")).Once()
+			s.retentionValidator = &rv
+			t.Cleanup(func() { rv.AssertExpectations(t) })
+
+			errs := s.UpdateSettingsFromEnv([]string{
+				"DATA_RETENTION=8760h",
+				"DATA_RETENTION_TIERS=dev:environment=dev:336h",
+			})
+			require.Len(t, errs, 1)
+			assert.EqualError(t, errs[0], "DATA_RETENTION_TIERS: rpc error: code = FailedPrecondition desc = VictoriaMetrics does not accept retention tiers: unsupported flag.")
+			assert.Empty(t, s.envSettings.RetentionTiers)
+
+			settings, err := models.GetSettings(s.db)
+			require.NoError(t, err)
+			assert.Empty(t, settings.RetentionTiers)
+		})
 	})
 
 	t.Run("ValidateChangeSettingsRequest", func(t *testing.T) {
@@ -283,6 +310,32 @@ func TestServer(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, s)
 	})
+
+	t.Run("ChangeSettings invalid alerting settings", func(t *testing.T) {
+		server := newServer(t)
+		server.UpdateSettingsFromEnv([]string{})
+
+		ctx := context.TODO()
+		for name, req := range map[string]*serverpb.ChangeSettingsRequest{
+			"EmptyFrom": {
+				EmailAlertingSettings: &serverpb.EmailAlertingSettings{Smarthost: "0.0.0.0:8080"},
+			},
+			"EmptySmarthost": {
+				EmailAlertingSettings: &serverpb.EmailAlertingSettings{From: "me@example.com"},
+			},
+			"EmptySlackURL": {
+				SlackAlertingSettings: &serverpb.SlackAlertingSettings{},
+			},
+		} {
+			_, err := server.ChangeSettings(ctx, req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), name)
+		}
+
+		settings, err := models.GetSettings(server.db)
+		require.NoError(t, err)
+		assert.Nil(t, settings.IntegratedAlerting.EmailAlertingSettings)
+		assert.Nil(t, settings.IntegratedAlerting.SlackAlertingSettings)
+	})
 }
 
 func TestServer_TestEmailAlertingSettings(t *testing.T) {
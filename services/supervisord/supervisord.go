@@ -45,6 +45,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/victoriametrics"
 )
 
 const (
@@ -429,6 +430,7 @@ func (s *Service) marshalConfig(tmpl *template.Template, settings *models.Settin
 		"DataRetentionHours":  int(settings.DataRetention.Hours()),
 		"DataRetentionDays":   int(settings.DataRetention.Hours() / 24),
 		"VMAlertFlags":        s.vmParams.VMAlertFlags,
+		"VMRetentionFlags":    victoriametrics.RetentionFlags(settings),
 		"VMDBCacheDisable":    !settings.VictoriaMetrics.CacheEnabled,
 		"PerconaTestDbaas":    settings.DBaaS.Enabled,
 		"ClickhouseAddr":      clickhouseAddr,
@@ -437,6 +439,11 @@ func (s *Service) marshalConfig(tmpl *template.Template, settings *models.Settin
 		"ClickhouseBlockSize": clickhouseBlockSize,
 	}
 
+	vmagentFlags := victoriametrics.RemoteWriteFlags(settings)
+	templateParams["VMAgentEnabled"] = len(vmagentFlags) != 0
+	templateParams["VMAgentFlags"] = vmagentFlags
+	templateParams["VMAgentConfigHash"] = victoriametrics.RemoteWriteConfigHash(settings)
+
 	if ssoDetails != nil {
 		u, err := url.Parse(ssoDetails.IssuerURL)
 		if err != nil {
@@ -621,6 +628,9 @@ command =
 	/usr/sbin/victoriametrics
 		--promscrape.config=/etc/victoriametrics-promscrape.yml
 		--retentionPeriod={{ .DataRetentionDays }}d
+{{- range $index, $param := .VMRetentionFlags }}
+		{{ $param }}
+{{- end }}
 		--storageDataPath=/srv/victoriametrics/data
 		--httpListenAddr=127.0.0.1:9090
 		--search.disableCache={{ .VMDBCacheDisable }}
@@ -680,6 +690,30 @@ stdout_logfile_backups = 3
 redirect_stderr = true
 {{end}}
 
+{{define "vmagent"}}
+[program:vmagent]
+{{- if .VMAgentConfigHash }}
+; remote-write configuration hash: {{ .VMAgentConfigHash }}
+{{- end }}
+priority = 7
+command =
+	/usr/sbin/vmagent
+{{- range $index, $param := .VMAgentFlags }}
+		{{ $param }}
+{{- end }}
+user = pmm
+autorestart = {{ .VMAgentEnabled }}
+autostart = {{ .VMAgentEnabled }}
+startretries = 10
+startsecs = 1
+stopsignal = INT
+stopwaitsecs = 300
+stdout_logfile = /srv/logs/vmagent.log
+stdout_logfile_maxbytes = 10MB
+stdout_logfile_backups = 3
+redirect_stderr = true
+{{end}}
+
 {{define "alertmanager"}}
 [program:alertmanager]
 priority = 8
@@ -107,6 +107,80 @@ func TestDBaaSController(t *testing.T) {
 	}
 }
 
+func TestVMAgent(t *testing.T) {
+	t.Parallel()
+
+	pmmUpdateCheck := NewPMMUpdateChecker(logrus.WithField("component", "supervisord/pmm-update-checker_logs"))
+	configDir := filepath.Join("..", "..", "testdata", "supervisord.d")
+	vmParams := &models.VictoriaMetricsParams{}
+	s := New(configDir, pmmUpdateCheck, vmParams)
+
+	var tp *template.Template
+	for _, tmpl := range templates.Templates() {
+		if tmpl.Name() == "vmagent" {
+			tp = tmpl
+			break
+		}
+	}
+
+	settings := &models.Settings{
+		MetricsResolutions: models.MetricsResolutions{MR: 10 * time.Second},
+		RemoteWrite: models.RemoteWrite{
+			Targets: []models.RemoteWriteTarget{{
+				Name:     "mimir",
+				URL:      "https://mimir:9009/api/v1/push",
+				Username: "pmm",
+				Password: "secret",
+				Headers:  map[string]string{"X-Scope-OrgID": "databases"},
+			}},
+			Queue: models.RemoteWriteQueue{Queues: 4},
+		},
+	}
+
+	expected, err := ioutil.ReadFile(filepath.Join(configDir, "vmagent_enabled.ini")) //nolint:gosec
+	require.NoError(t, err)
+	actual, err := s.marshalConfig(tp, settings, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestVictoriaMetricsRetentionTiers(t *testing.T) {
+	t.Parallel()
+
+	pmmUpdateCheck := NewPMMUpdateChecker(logrus.WithField("component", "supervisord/pmm-update-checker_logs"))
+	configDir := filepath.Join("..", "..", "testdata", "supervisord.d")
+	vmParams := &models.VictoriaMetricsParams{}
+	s := New(configDir, pmmUpdateCheck, vmParams)
+
+	var tp *template.Template
+	for _, tmpl := range templates.Templates() {
+		if tmpl.Name() == "victoriametrics" {
+			tp = tmpl
+			break
+		}
+	}
+
+	settings := &models.Settings{
+		DataRetention: 365 * 24 * time.Hour,
+		RetentionTiers: []models.RetentionTier{{
+			Name:      "dev",
+			Labels:    map[string]string{"environment": "dev"},
+			Retention: 14 * 24 * time.Hour,
+		}, {
+			Name:         "prod",
+			Labels:       map[string]string{"environment": "prod"},
+			Retention:    365 * 24 * time.Hour,
+			Downsampling: []models.DownsamplingPeriod{{Offset: 30 * 24 * time.Hour, Interval: 5 * time.Minute}},
+		}},
+	}
+
+	expected, err := ioutil.ReadFile(filepath.Join(configDir, "victoriametrics_retention_tiers.ini")) //nolint:gosec
+	require.NoError(t, err)
+	actual, err := s.marshalConfig(tp, settings, nil)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
 func TestParseStatus(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,220 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package topology
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// Node represents a Service in topology graph.
+type Node struct {
+	ServiceID      string `json:"service_id"`
+	ServiceName    string `json:"service_name"`
+	ServiceType    string `json:"service_type"`
+	NodeID         string `json:"node_id"`
+	Cluster        string `json:"cluster,omitempty"`
+	ReplicationSet string `json:"replication_set,omitempty"`
+}
+
+// Edge represents a directed relationship between two Services.
+type Edge struct {
+	EdgeID          string `json:"edge_id"`
+	SourceServiceID string `json:"source_service_id"`
+	TargetServiceID string `json:"target_service_id"`
+	// One of replica-of, proxied-by, shard-of.
+	// All types are discovered automatically: replica-of for MongoDB replica set members,
+	// shard-of for members of shards that mongos has connected to, proxied-by for ProxySQL backends.
+	Type string `json:"type"`
+	// True if edge was discovered automatically.
+	Auto bool `json:"auto"`
+}
+
+// GetRequest is a request of Get method.
+type GetRequest struct {
+	// Return only Services connected to that Service (directly or transitively, in any direction).
+	ServiceID string `json:"service_id"`
+}
+
+// GetResponse is a response of Get method.
+type GetResponse struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+}
+
+// AddEdgeRequest is a request of AddEdge method.
+type AddEdgeRequest struct {
+	SourceServiceID string `json:"source_service_id"`
+	TargetServiceID string `json:"target_service_id"`
+	Type            string `json:"type"`
+}
+
+// AddEdgeResponse is a response of AddEdge method.
+type AddEdgeResponse struct {
+	Edge *Edge `json:"edge"`
+}
+
+// RemoveEdgeRequest is a request of RemoveEdge method.
+type RemoveEdgeRequest struct {
+	EdgeID string `json:"edge_id"`
+}
+
+// RemoveEdgeResponse is a response of RemoveEdge method.
+type RemoveEdgeResponse struct{}
+
+// DiscoverRequest is a request of Discover method.
+type DiscoverRequest struct{}
+
+// DiscoverResponse is a response of Discover method.
+type DiscoverResponse struct{}
+
+// Get returns topology graph of all Services, or connected component of a single Service.
+func (s *Service) Get(ctx context.Context, req *GetRequest) (*GetResponse, error) {
+	var res *GetResponse
+	err := s.db.InTransaction(func(tx *reform.TX) error {
+		services, err := models.FindServices(tx.Querier, models.ServiceFilters{})
+		if err != nil {
+			return err
+		}
+		edges, err := models.FindTopologyEdges(tx.Querier, models.TopologyEdgeFilters{})
+		if err != nil {
+			return err
+		}
+
+		if req.ServiceID != "" {
+			if _, err = models.FindServiceByID(tx.Querier, req.ServiceID); err != nil {
+				return err
+			}
+			services, edges = connectedComponent(req.ServiceID, services, edges)
+		}
+
+		res = &GetResponse{
+			Nodes: make([]*Node, len(services)),
+			Edges: make([]*Edge, len(edges)),
+		}
+		for i, service := range services {
+			res.Nodes[i] = &Node{
+				ServiceID:      service.ServiceID,
+				ServiceName:    service.ServiceName,
+				ServiceType:    string(service.ServiceType),
+				NodeID:         service.NodeID,
+				Cluster:        service.Cluster,
+				ReplicationSet: service.ReplicationSet,
+			}
+		}
+		for i, edge := range edges {
+			res.Edges[i] = convertEdge(edge)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// AddEdge adds a manual edge between two Services.
+func (s *Service) AddEdge(ctx context.Context, req *AddEdgeRequest) (*AddEdgeResponse, error) {
+	if req.SourceServiceID == "" || req.TargetServiceID == "" {
+		return nil, status.Error(codes.InvalidArgument, "Both source_service_id and target_service_id are expected.")
+	}
+
+	var edge *models.TopologyEdge
+	err := s.db.InTransaction(func(tx *reform.TX) error {
+		var err error
+		edge, err = models.CreateTopologyEdge(tx.Querier, models.CreateTopologyEdgeParams{
+			SourceServiceID: req.SourceServiceID,
+			TargetServiceID: req.TargetServiceID,
+			Type:            models.TopologyEdgeType(req.Type),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AddEdgeResponse{Edge: convertEdge(edge)}, nil
+}
+
+// RemoveEdge removes an edge. Automatically discovered edges may be added again by the next discovery.
+func (s *Service) RemoveEdge(ctx context.Context, req *RemoveEdgeRequest) (*RemoveEdgeResponse, error) {
+	if req.EdgeID == "" {
+		return nil, status.Error(codes.InvalidArgument, "Empty edge_id.")
+	}
+	if err := models.RemoveTopologyEdge(s.db.Querier, req.EdgeID); err != nil {
+		return nil, err
+	}
+	return &RemoveEdgeResponse{}, nil
+}
+
+// DiscoverNow runs topology discovery immediately.
+func (s *Service) DiscoverNow(ctx context.Context, req *DiscoverRequest) (*DiscoverResponse, error) {
+	s.Discover(ctx)
+	return &DiscoverResponse{}, nil
+}
+
+// connectedComponent returns Services and edges reachable from given Service ignoring edge direction,
+// so the result shows everything affected by this Service failure.
+func connectedComponent(serviceID string, services []*models.Service, edges []*models.TopologyEdge) ([]*models.Service, []*models.TopologyEdge) {
+	adjacent := make(map[string][]string)
+	for _, edge := range edges {
+		adjacent[edge.SourceServiceID] = append(adjacent[edge.SourceServiceID], edge.TargetServiceID)
+		adjacent[edge.TargetServiceID] = append(adjacent[edge.TargetServiceID], edge.SourceServiceID)
+	}
+
+	visited := map[string]struct{}{serviceID: {}}
+	queue := []string{serviceID}
+	for len(queue) != 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacent[id] {
+			if _, ok := visited[next]; ok {
+				continue
+			}
+			visited[next] = struct{}{}
+			queue = append(queue, next)
+		}
+	}
+
+	resServices := make([]*models.Service, 0, len(visited))
+	for _, service := range services {
+		if _, ok := visited[service.ServiceID]; ok {
+			resServices = append(resServices, service)
+		}
+	}
+	resEdges := make([]*models.TopologyEdge, 0, len(edges))
+	for _, edge := range edges {
+		if _, ok := visited[edge.SourceServiceID]; ok {
+			resEdges = append(resEdges, edge)
+		}
+	}
+	return resServices, resEdges
+}
+
+func convertEdge(edge *models.TopologyEdge) *Edge {
+	return &Edge{
+		EdgeID:          edge.ID,
+		SourceServiceID: edge.SourceServiceID,
+		TargetServiceID: edge.TargetServiceID,
+		Type:            string(edge.Type),
+		Auto:            edge.Auto,
+	}
+}
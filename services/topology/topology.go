@@ -0,0 +1,350 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package topology maintains relationships between Services and discovers them automatically.
+package topology
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/reform.v1"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/services/agents"
+)
+
+const (
+	discoveryInterval  = 10 * time.Minute
+	discoveryWorkers   = 8
+	resultAwaitTimeout = 30 * time.Second
+
+	// proxySQLServersQuery is executed on ProxySQL admin interface; pmm-agent adds SELECT.
+	proxySQLServersQuery = "DISTINCT hostname, port FROM runtime_mysql_servers"
+)
+
+// Service maintains Services topology.
+type Service struct {
+	db      *reform.DB
+	actions actionsService
+	l       *logrus.Entry
+}
+
+// New creates new Service.
+func New(db *reform.DB, actions actionsService) *Service {
+	return &Service{
+		db:      db,
+		actions: actions,
+		l:       logrus.WithField("component", "topology"),
+	}
+}
+
+// Run discovers topology periodically until ctx is canceled.
+func (s *Service) Run(ctx context.Context) {
+	s.l.Info("Starting...")
+	defer s.l.Info("Done.")
+
+	ticker := time.NewTicker(discoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Discover(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Discover updates automatically discovered edges: replica-of from MongoDB replSetGetStatus,
+// shard-of from mongos, and proxied-by from ProxySQL mysql_servers. Failures for a single Service are logged and skipped.
+func (s *Service) Discover(ctx context.Context) {
+	var services []*models.Service
+	for _, serviceType := range []models.ServiceType{models.MongoDBServiceType, models.ProxySQLServiceType} {
+		found, err := models.FindServices(s.db.Querier, models.ServiceFilters{ServiceType: &serviceType})
+		if err != nil {
+			s.l.Errorf("Failed to find Services: %+v.", err)
+			return
+		}
+		services = append(services, found...)
+	}
+
+	// unavailable Services take the whole action timeout, so discover them concurrently
+	sem := make(chan struct{}, discoveryWorkers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for _, service := range services {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		wg.Add(1)
+		go func(service *models.Service) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			var err error
+			switch service.ServiceType {
+			case models.MongoDBServiceType:
+				err = s.discoverMongoDB(ctx, service)
+			case models.ProxySQLServiceType:
+				err = s.discoverProxySQL(ctx, service)
+			}
+			if err != nil {
+				s.l.Warnf("Failed to discover topology of %s Service %q: %s.", service.ServiceType, service.ServiceName, err)
+			}
+		}(service)
+	}
+}
+
+// discoverMongoDB replaces replica-of edge from MongoDB Service to the primary of its replica set.
+// Services that are not replica set members are checked for being mongos.
+func (s *Service) discoverMongoDB(ctx context.Context, service *models.Service) error {
+	output, err := s.runAction(ctx, service, func(id, pmmAgentID, dsn string, agent *models.Agent) error {
+		return s.actions.StartMongoDBQueryReplSetGetStatusAction(ctx, id, pmmAgentID, dsn, agent.Files(), agent.TemplateDelimiters(service))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		// replSetGetStatus is not supported by mongos
+		mongosErr := s.discoverMongos(ctx, service)
+		if mongosErr == nil {
+			return nil
+		}
+		return errors.Errorf("%s; %s", err, mongosErr)
+	}
+
+	primary, self, err := parseReplSetGetStatus(output)
+	if err != nil {
+		return err
+	}
+
+	return s.db.InTransaction(func(tx *reform.TX) error {
+		var edges []models.CreateTopologyEdgeParams
+		if primary != "" && !self {
+			target, err := findServiceByAddress(tx.Querier, models.MongoDBServiceType, primary)
+			if err != nil {
+				return err
+			}
+			if target != nil {
+				edges = append(edges, models.CreateTopologyEdgeParams{
+					SourceServiceID: service.ServiceID,
+					TargetServiceID: target.ServiceID,
+					Type:            models.ReplicaOfEdge,
+				})
+			}
+		}
+
+		return models.ReplaceAutoTopologyEdges(tx.Querier, models.TopologyEdgeFilters{
+			SourceServiceID: service.ServiceID,
+			Type:            models.ReplicaOfEdge,
+		}, edges)
+	})
+}
+
+// discoverMongos replaces shard-of edges from MongoDB Services of shard replica sets members to mongos Service.
+// Shards are taken from mongos replica set monitor as pmm-agent has no Action for listShards command,
+// so only shards that mongos has connected to are linked.
+func (s *Service) discoverMongos(ctx context.Context, service *models.Service) error {
+	output, err := s.runAction(ctx, service, func(id, pmmAgentID, dsn string, agent *models.Agent) error {
+		return s.actions.StartMongoDBQueryGetCmdLineOptsAction(ctx, id, pmmAgentID, dsn, agent.Files(), agent.TemplateDelimiters(service))
+	})
+	if err != nil {
+		return err
+	}
+	configSet, _, err := agents.ParseMongoDBConfigDB(output)
+	if err != nil {
+		return err
+	}
+
+	output, err = s.runAction(ctx, service, func(id, pmmAgentID, dsn string, agent *models.Agent) error {
+		return s.actions.StartMongoDBQueryGetDiagnosticDataAction(ctx, id, pmmAgentID, dsn, agent.Files(), agent.TemplateDelimiters(service))
+	})
+	if err != nil {
+		return err
+	}
+	replicaSets, err := agents.ParseMongoDBReplicaSets(output)
+	if err != nil {
+		return err
+	}
+	delete(replicaSets, configSet)
+
+	setNames := make([]string, 0, len(replicaSets))
+	for name := range replicaSets {
+		setNames = append(setNames, name)
+	}
+	sort.Strings(setNames)
+
+	return s.db.InTransaction(func(tx *reform.TX) error {
+		var edges []models.CreateTopologyEdgeParams
+		for _, name := range setNames {
+			for _, address := range replicaSets[name] {
+				source, err := findServiceByAddress(tx.Querier, models.MongoDBServiceType, address)
+				if err != nil {
+					return err
+				}
+				if source == nil {
+					s.l.Debugf("mongos %q: no MongoDB Service for %s shard member %s.", service.ServiceName, name, address)
+					continue
+				}
+				edges = append(edges, models.CreateTopologyEdgeParams{
+					SourceServiceID: source.ServiceID,
+					TargetServiceID: service.ServiceID,
+					Type:            models.ShardOfEdge,
+				})
+			}
+		}
+
+		return models.ReplaceAutoTopologyEdges(tx.Querier, models.TopologyEdgeFilters{
+			TargetServiceID: service.ServiceID,
+			Type:            models.ShardOfEdge,
+		}, edges)
+	})
+}
+
+// discoverProxySQL replaces proxied-by edges from MySQL Services to ProxySQL Service.
+func (s *Service) discoverProxySQL(ctx context.Context, service *models.Service) error {
+	output, err := s.runAction(ctx, service, func(id, pmmAgentID, dsn string, agent *models.Agent) error {
+		return s.actions.StartMySQLQuerySelectAction(ctx, id, pmmAgentID, dsn, proxySQLServersQuery, agent.Files(), agent.TemplateDelimiters(service), agent.TLSSkipVerify)
+	})
+	if err != nil {
+		return err
+	}
+
+	servers, err := parseProxySQLServers(output)
+	if err != nil {
+		return err
+	}
+
+	return s.db.InTransaction(func(tx *reform.TX) error {
+		var edges []models.CreateTopologyEdgeParams
+		for _, server := range servers {
+			source, err := findServiceByAddress(tx.Querier, models.MySQLServiceType, server)
+			if err != nil {
+				return err
+			}
+			if source == nil {
+				s.l.Debugf("ProxySQL %q: no MySQL Service for %s.", service.ServiceName, server)
+				continue
+			}
+			edges = append(edges, models.CreateTopologyEdgeParams{
+				SourceServiceID: source.ServiceID,
+				TargetServiceID: service.ServiceID,
+				Type:            models.ProxiedByEdge,
+			})
+		}
+
+		return models.ReplaceAutoTopologyEdges(tx.Querier, models.TopologyEdgeFilters{
+			TargetServiceID: service.ServiceID,
+			Type:            models.ProxiedByEdge,
+		}, edges)
+	})
+}
+
+// runAction starts action on pmm-agent that monitors the Service and returns its output.
+func (s *Service) runAction(ctx context.Context, service *models.Service, start func(id, pmmAgentID, dsn string, agent *models.Agent) error) ([]byte, error) {
+	pmmAgents, err := models.FindPMMAgentsForService(s.db.Querier, service.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(pmmAgents) == 0 {
+		return nil, errors.New("no available pmm-agents")
+	}
+	pmmAgentID := pmmAgents[0].AgentID
+
+	dsn, agent, err := models.FindDSNByServiceIDandPMMAgentID(s.db.Querier, service.ServiceID, pmmAgentID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return agents.RunAction(ctx, s.db, pmmAgentID, resultAwaitTimeout, func(id string) error {
+		return start(id, pmmAgentID, dsn, agent)
+	})
+}
+
+// parseReplSetGetStatus returns address of the primary replica set member,
+// and true if this member is the primary.
+func parseReplSetGetStatus(output []byte) (string, bool, error) {
+	docs, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return "", false, errors.WithStack(err)
+	}
+	if len(docs) == 0 {
+		return "", false, errors.New("empty replSetGetStatus result")
+	}
+
+	members, _ := docs[0]["members"].([]interface{})
+	for _, m := range members {
+		member, _ := m.(map[string]interface{})
+		if member["stateStr"] != "PRIMARY" {
+			continue
+		}
+		name, _ := member["name"].(string)
+		self, _ := member["self"].(bool)
+		return name, self, nil
+	}
+
+	// no primary during election, or standalone server
+	return "", false, nil
+}
+
+// parseProxySQLServers returns host:port addresses of ProxySQL backend servers.
+func parseProxySQLServers(output []byte) ([]string, error) {
+	rows, err := agentpb.UnmarshalActionQueryResult(output)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res := make([]string, 0, len(rows))
+	for _, row := range rows {
+		host := fmt.Sprint(row["hostname"])
+		port := fmt.Sprint(row["port"])
+		if row["hostname"] == nil || row["port"] == nil {
+			return nil, errors.Errorf("unexpected mysql_servers row: %v", row)
+		}
+		res = append(res, net.JoinHostPort(host, port))
+	}
+	return res, nil
+}
+
+// findServiceByAddress returns Service of given type with given host:port address, or nil.
+func findServiceByAddress(q *reform.Querier, serviceType models.ServiceType, address string) (*models.Service, error) {
+	host, portS, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	port, err := strconv.ParseUint(portS, 10, 16)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return models.FindServiceByAddress(q, serviceType, host, uint16(port))
+}
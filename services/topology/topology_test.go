@@ -0,0 +1,104 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package topology
+
+import (
+	"testing"
+
+	"github.com/percona/pmm/api/agentpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestParseReplSetGetStatus(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		members []interface{}
+		primary string
+		self    bool
+	}{
+		"Secondary": {
+			members: []interface{}{
+				map[string]interface{}{"name": "mongo1:27017", "stateStr": "PRIMARY"},
+				map[string]interface{}{"name": "mongo2:27017", "stateStr": "SECONDARY", "self": true},
+			},
+			primary: "mongo1:27017",
+		},
+		"Primary": {
+			members: []interface{}{
+				map[string]interface{}{"name": "mongo1:27017", "stateStr": "PRIMARY", "self": true},
+				map[string]interface{}{"name": "mongo2:27017", "stateStr": "SECONDARY"},
+			},
+			primary: "mongo1:27017",
+			self:    true,
+		},
+		"Election": {
+			members: []interface{}{
+				map[string]interface{}{"name": "mongo1:27017", "stateStr": "SECONDARY", "self": true},
+				map[string]interface{}{"name": "mongo2:27017", "stateStr": "SECONDARY"},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := agentpb.MarshalActionQueryDocsResult([]map[string]interface{}{{"set": "rs0", "members": tc.members}})
+			require.NoError(t, err)
+
+			primary, self, err := parseReplSetGetStatus(output)
+			require.NoError(t, err)
+			assert.Equal(t, tc.primary, primary)
+			assert.Equal(t, tc.self, self)
+		})
+	}
+}
+
+func TestParseProxySQLServers(t *testing.T) {
+	t.Parallel()
+
+	output, err := agentpb.MarshalActionQuerySQLResult([]string{"hostname", "port"}, [][]interface{}{
+		{"mysql1", "3306"},
+		{"10.0.0.2", int64(3307)},
+	})
+	require.NoError(t, err)
+
+	servers, err := parseProxySQLServers(output)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql1:3306", "10.0.0.2:3307"}, servers)
+}
+
+func TestConnectedComponent(t *testing.T) {
+	t.Parallel()
+
+	services := []*models.Service{{ServiceID: "m1"}, {ServiceID: "m2"}, {ServiceID: "p1"}, {ServiceID: "other"}}
+	edges := []*models.TopologyEdge{
+		{ID: "e1", SourceServiceID: "m2", TargetServiceID: "m1", Type: models.ReplicaOfEdge},
+		{ID: "e2", SourceServiceID: "m1", TargetServiceID: "p1", Type: models.ProxiedByEdge},
+	}
+
+	actualServices, actualEdges := connectedComponent("m2", services, edges)
+	assert.Equal(t, services[:3], actualServices)
+	assert.Equal(t, edges, actualEdges)
+
+	actualServices, actualEdges = connectedComponent("other", services, edges)
+	assert.Equal(t, services[3:], actualServices)
+	assert.Empty(t, actualEdges)
+}
@@ -65,3 +65,9 @@ type FilterParams struct {
 	// ServiceID is the ID of service to be matched (if any).
 	ServiceID string
 }
+
+// MessagePreview represents notification message rendered for a sample alert.
+type MessagePreview struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
@@ -217,14 +217,18 @@ func (s *Service) RequestSoftwareVersionsUpdate() {
 }
 
 // Run runs software version cache service.
+// It may be called again after return (for example, when this pmm-managed instance becomes a leader again).
 func (s *Service) Run(ctx context.Context) {
-	time.Sleep(startupDelay) // sleep a while, so the server establishes the connections with agents.
+	// sleep a while, so the server establishes the connections with agents.
+	select {
+	case <-time.After(startupDelay):
+	case <-ctx.Done():
+		return
+	}
 
 	s.l.Info("Starting...")
 	defer s.l.Info("Done.")
 
-	defer close(s.updateCh)
-
 	var checkAfter time.Duration
 	for {
 		select {
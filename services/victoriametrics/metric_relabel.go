@@ -0,0 +1,55 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package victoriametrics
+
+import (
+	"context"
+
+	config "github.com/percona/promconfig"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// ValidateMetricRelabelRule checks that VictoriaMetrics accepts relabel configs of the given rule
+// with `victoriametrics -dryRun`, so that configuration with it could be reloaded.
+func (svc *Service) ValidateMetricRelabelRule(ctx context.Context, rule *models.MetricRelabelRule) error {
+	cfg := &config.Config{
+		ScrapeConfigs: []*config.ScrapeConfig{{
+			JobName:              "metric_relabel_rule",
+			MetricRelabelConfigs: metricRelabelConfigs([]*models.MetricRelabelRule{rule}),
+			ServiceDiscoveryConfig: config.ServiceDiscoveryConfig{
+				StaticConfigs: []*config.Group{{
+					Targets: []string{"127.0.0.1:9090"},
+				}},
+			},
+		}},
+	}
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = svc.validateConfig(ctx, b)
+	if s, ok := status.FromError(err); ok && s.Code() == codes.Aborted {
+		return status.Errorf(codes.InvalidArgument, "Invalid relabel configs: %s", s.Message())
+	}
+	return err
+}
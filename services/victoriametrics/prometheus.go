@@ -29,11 +29,21 @@ import (
 
 // AddScrapeConfigs - adds agents scrape configuration to given scrape config,
 // pmm_agent_id and push_metrics used for filtering.
+// Global metrics resolutions s are replaced by matching metrics resolution overrides,
+// and matching metric relabel rules are added to metric_relabel_configs of Agents' jobs.
 func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s *models.MetricsResolutions, pmmAgentID *string, pushMetrics bool) error {
 	agents, err := models.FindAgentsForScrapeConfig(q, pmmAgentID, pushMetrics)
 	if err != nil {
 		return errors.WithStack(err)
 	}
+	overrides, err := models.FindMetricsResolutionOverrides(q)
+	if err != nil {
+		return err
+	}
+	relabelRules, err := models.FindMetricRelabelRules(q)
+	if err != nil {
+		return err
+	}
 
 	var rdsParams []*scrapeConfigParams
 	for _, agent := range agents {
@@ -103,10 +113,22 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			continue
 		}
 
+		agentS := s
+		var agentRelabelRules []*models.MetricRelabelRule
+		if len(overrides) != 0 || len(relabelRules) != 0 {
+			labels, err := models.MergeLabels(paramsNode, paramsService, agent)
+			if err != nil {
+				return err
+			}
+			resolutions := models.ResolveMetricsResolutions(overrides, agent.AgentID, labels, *s)
+			agentS = &resolutions
+			agentRelabelRules = models.MatchingMetricRelabelRules(relabelRules, agent.AgentType, labels)
+		}
+
 		var scfgs []*config.ScrapeConfig
 		switch agent.AgentType {
 		case models.NodeExporterType:
-			scfgs, err = scrapeConfigsForNodeExporter(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForNodeExporter(agentS, &scrapeConfigParams{
 				host:    paramsHost,
 				node:    paramsNode,
 				service: nil,
@@ -114,7 +136,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			})
 
 		case models.MySQLdExporterType:
-			scfgs, err = scrapeConfigsForMySQLdExporter(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForMySQLdExporter(agentS, &scrapeConfigParams{
 				host:    paramsHost,
 				node:    paramsNode,
 				service: paramsService,
@@ -122,7 +144,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			})
 
 		case models.MongoDBExporterType:
-			scfgs, err = scrapeConfigsForMongoDBExporter(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForMongoDBExporter(agentS, &scrapeConfigParams{
 				host:            paramsHost,
 				node:            paramsNode,
 				service:         paramsService,
@@ -131,7 +153,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			})
 
 		case models.PostgresExporterType:
-			scfgs, err = scrapeConfigsForPostgresExporter(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForPostgresExporter(agentS, &scrapeConfigParams{
 				host:        paramsHost,
 				node:        paramsNode,
 				service:     paramsService,
@@ -140,7 +162,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			})
 
 		case models.ProxySQLExporterType:
-			scfgs, err = scrapeConfigsForProxySQLExporter(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForProxySQLExporter(agentS, &scrapeConfigParams{
 				host:    paramsHost,
 				node:    paramsNode,
 				service: paramsService,
@@ -156,15 +178,17 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 
 		case models.RDSExporterType:
 			rdsParams = append(rdsParams, &scrapeConfigParams{
-				host:    paramsHost,
-				node:    paramsNode,
-				service: paramsService,
-				agent:   agent,
+				host:               paramsHost,
+				node:               paramsNode,
+				service:            paramsService,
+				agent:              agent,
+				metricsResolutions: agentS,
+				metricRelabelRules: agentRelabelRules,
 			})
 			continue
 
 		case models.ExternalExporterType:
-			scfgs, err = scrapeConfigsForExternalExporter(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForExternalExporter(agentS, &scrapeConfigParams{
 				host:    paramsHost,
 				node:    paramsNode,
 				service: paramsService,
@@ -172,7 +196,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			})
 
 		case models.VMAgentType:
-			scfgs, err = scrapeConfigsForVMAgent(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForVMAgent(agentS, &scrapeConfigParams{
 				host:    paramsHost,
 				node:    paramsNode,
 				service: nil,
@@ -180,7 +204,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 			})
 
 		case models.AzureDatabaseExporterType:
-			scfgs, err = scrapeConfigsForAzureDatabase(s, &scrapeConfigParams{
+			scfgs, err = scrapeConfigsForAzureDatabase(agentS, &scrapeConfigParams{
 				host:    paramsHost,
 				node:    paramsNode,
 				service: paramsService,
@@ -195,6 +219,7 @@ func AddScrapeConfigs(l *logrus.Entry, cfg *config.Config, q *reform.Querier, s
 		if err != nil {
 			l.Warnf("Failed to add %s %q, skipping: %s.", agent.AgentType, agent.AgentID, err)
 		}
+		addMetricRelabelConfigs(scfgs, agentRelabelRules)
 		cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, scfgs...)
 	}
 
@@ -0,0 +1,535 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package victoriametrics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/percona/pmm/utils/pdeathsig"
+	config "github.com/percona/promconfig"
+	"github.com/pkg/errors"
+	"github.com/prometheus/common/expfmt"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	"github.com/percona/pmm-managed/models"
+	"github.com/percona/pmm-managed/utils/dir"
+)
+
+const (
+	// remoteWriteDir contains files used by vmagent that forwards metrics to remote-write targets.
+	remoteWriteDir = "/srv/vmagent"
+
+	remoteWriteVMAgentAddr    = "127.0.0.1:8430"
+	remoteWriteStatusInterval = 30 * time.Second
+)
+
+// remoteWriteFile is a file referenced by vmagent flags.
+type remoteWriteFile struct {
+	path    string // relative to remote-write directory
+	content []byte
+	secret  bool
+}
+
+// remoteWriteConfig contains vmagent flags and files for forwarding metrics to remote-write targets.
+type remoteWriteConfig struct {
+	flags []string
+	files []remoteWriteFile
+}
+
+// renderRemoteWriteConfig renders vmagent configuration for given remote-write settings.
+// vmagent scrapes all metrics from VictoriaMetrics federation endpoint with given interval
+// and forwards them to all targets. Per-target flags are positional, so each used flag is repeated
+// for every target, with empty values for targets that do not use it.
+func renderRemoteWriteConfig(baseDir string, rw *models.RemoteWrite, interval time.Duration) (*remoteWriteConfig, error) {
+	if len(rw.Targets) == 0 {
+		return &remoteWriteConfig{}, nil
+	}
+
+	scrapeCfg := &config.Config{
+		GlobalConfig: config.GlobalConfig{
+			ScrapeInterval: config.Duration(interval),
+			ScrapeTimeout:  ScrapeTimeout(interval),
+		},
+		ScrapeConfigs: []*config.ScrapeConfig{{
+			JobName:         "pmm-server-federation",
+			HonorLabels:     true,
+			HonorTimestamps: true,
+			Params:          url.Values{"match[]": []string{`{__name__=~".+"}`}},
+			MetricsPath:     "/prometheus/federate",
+			StreamParse:     true,
+			ServiceDiscoveryConfig: config.ServiceDiscoveryConfig{
+				StaticConfigs: []*config.Group{{
+					Targets: []string{"127.0.0.1:9090"},
+				}},
+			},
+		}},
+	}
+	b, err := yaml.Marshal(scrapeCfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't marshal vmagent scrape configuration")
+	}
+
+	res := &remoteWriteConfig{
+		flags: []string{
+			"--httpListenAddr=" + remoteWriteVMAgentAddr,
+			"--promscrape.config=" + filepath.Join(baseDir, "scrape.yml"),
+			"--promscrape.configCheckInterval=10s",
+			"--remoteWrite.tmpDataPath=" + filepath.Join(baseDir, "data"),
+		},
+		files: []remoteWriteFile{{
+			path:    "scrape.yml",
+			content: append([]byte("# Managed by pmm-managed. DO NOT EDIT.\n---\n"), b...),
+		}},
+	}
+
+	perTarget := make(map[string][]string)
+	perTargetFlag := func(i int, name, value string) {
+		if perTarget[name] == nil {
+			perTarget[name] = make([]string, len(rw.Targets))
+		}
+		perTarget[name][i] = value
+	}
+	targetFile := func(i int, name string, content string, secret bool) string {
+		path := filepath.Join("targets", strconv.Itoa(i+1), name)
+		res.files = append(res.files, remoteWriteFile{path: path, content: []byte(content), secret: secret})
+		return filepath.Join(baseDir, path)
+	}
+
+	for i, t := range rw.Targets {
+		perTargetFlag(i, "remoteWrite.url", t.URL)
+
+		if t.Username != "" {
+			perTargetFlag(i, "remoteWrite.basicAuth.username", t.Username)
+		}
+		if t.Password != "" {
+			perTargetFlag(i, "remoteWrite.basicAuth.passwordFile", targetFile(i, "password", t.Password, true))
+		}
+		if t.BearerToken != "" {
+			perTargetFlag(i, "remoteWrite.bearerTokenFile", targetFile(i, "bearer_token", t.BearerToken, true))
+		}
+		if len(t.Headers) != 0 {
+			headers := make([]string, 0, len(t.Headers))
+			for name, value := range t.Headers {
+				headers = append(headers, name+": "+value)
+			}
+			sort.Strings(headers)
+			perTargetFlag(i, "remoteWrite.headers", strings.Join(headers, "^^"))
+		}
+
+		if t.TLSCA != "" {
+			perTargetFlag(i, "remoteWrite.tlsCAFile", targetFile(i, "ca.pem", t.TLSCA, false))
+		}
+		if t.TLSCert != "" {
+			perTargetFlag(i, "remoteWrite.tlsCertFile", targetFile(i, "cert.pem", t.TLSCert, false))
+			perTargetFlag(i, "remoteWrite.tlsKeyFile", targetFile(i, "key.pem", t.TLSKey, true))
+		}
+		if t.TLSServerName != "" {
+			perTargetFlag(i, "remoteWrite.tlsServerName", t.TLSServerName)
+		}
+		if t.TLSInsecureSkipVerify {
+			perTargetFlag(i, "remoteWrite.tlsInsecureSkipVerify", "true")
+		}
+
+		if t.RelabelConfigs != "" {
+			perTargetFlag(i, "remoteWrite.urlRelabelConfig", targetFile(i, "relabel.yml", t.RelabelConfigs, false))
+		}
+	}
+
+	names := make([]string, 0, len(perTarget))
+	for name := range perTarget {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range perTarget[name] {
+			if value == "" && strings.HasSuffix(name, "tlsInsecureSkipVerify") {
+				value = "false"
+			}
+			res.flags = append(res.flags, "--"+name+"="+quoteFlagValue(value))
+		}
+	}
+
+	if q := rw.Queue.Queues; q != 0 {
+		res.flags = append(res.flags, "--remoteWrite.queues="+strconv.Itoa(q))
+	}
+	if fi := rw.Queue.FlushInterval; fi != 0 {
+		res.flags = append(res.flags, "--remoteWrite.flushInterval="+fi.String())
+	}
+	if du := rw.Queue.MaxDiskUsagePerTarget; du != 0 {
+		res.flags = append(res.flags, "--remoteWrite.maxDiskUsagePerURL="+strconv.FormatInt(du, 10))
+	}
+
+	return res, nil
+}
+
+// quoteFlagValue quotes flag value for supervisord configuration file if needed.
+func quoteFlagValue(value string) string {
+	// supervisord expands %(name)s in the command
+	value = strings.ReplaceAll(value, "%", "%%")
+
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !strings.ContainsRune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,:/=@%+^", r)
+	}) == -1 {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// RemoteWriteFlags returns vmagent flags for forwarding metrics to remote-write targets from settings.
+// It returns nil if there are no targets, or if settings are invalid.
+func RemoteWriteFlags(settings *models.Settings) []string {
+	cfg, err := renderRemoteWriteConfig(remoteWriteDir, &settings.RemoteWrite, settings.MetricsResolutions.MR)
+	if err != nil {
+		logrus.WithField("component", "victoriametrics/remote-write").Errorf("Failed to render flags: %s.", err)
+		return nil
+	}
+	return cfg.flags
+}
+
+// RemoteWriteConfigHash returns a hash of remote-write settings.
+// It is used to restart vmagent when content of referenced files changes.
+func RemoteWriteConfigHash(settings *models.Settings) string {
+	if len(settings.RemoteWrite.Targets) == 0 {
+		return ""
+	}
+
+	b, err := json.Marshal(settings.RemoteWrite)
+	if err != nil {
+		return ""
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:8])
+}
+
+// RemoteWriteTargetStatus represents forwarding health of a single remote-write target.
+type RemoteWriteTargetStatus struct {
+	Name string
+	URL  string
+
+	Healthy bool
+	Error   string
+
+	// counters since vmagent start
+	SentRequests   float64
+	FailedRequests float64
+	DroppedBlocks  float64
+
+	// data buffered on disk while target is unavailable or slow
+	PendingBytes float64
+}
+
+// remoteWriteCounters contains vmagent metrics of a single remote-write target.
+type remoteWriteCounters struct {
+	sentRequests   float64
+	failedRequests float64
+	errors         float64
+	droppedBlocks  float64
+	pendingBytes   float64
+}
+
+// RemoteWriteService writes configuration of vmagent that forwards metrics to remote-write targets,
+// validates it, and tracks forwarding health.
+type RemoteWriteService struct {
+	dir        string
+	vmagentURL string
+	client     *http.Client
+	l          *logrus.Entry
+
+	rw        sync.RWMutex
+	previous  map[int]*remoteWriteCounters
+	current   map[int]*remoteWriteCounters
+	scrapeErr error
+}
+
+// NewRemoteWriteService creates new RemoteWriteService.
+func NewRemoteWriteService() *RemoteWriteService {
+	return &RemoteWriteService{
+		dir:        remoteWriteDir,
+		vmagentURL: "http://" + remoteWriteVMAgentAddr + "/metrics",
+		client:     &http.Client{Timeout: 5 * time.Second},
+		l:          logrus.WithField("component", "victoriametrics/remote-write"),
+	}
+}
+
+// WriteConfiguration writes files referenced by RemoteWriteFlags.
+// Files of removed targets are removed.
+func (s *RemoteWriteService) WriteConfiguration(settings *models.Settings) error {
+	cfg, err := renderRemoteWriteConfig(s.dir, &settings.RemoteWrite, settings.MetricsResolutions.MR)
+	if err != nil {
+		return err
+	}
+
+	if err = dir.CreateDataDir(s.dir, "pmm", "pmm", dirPerm); err != nil {
+		return err
+	}
+	if err = os.RemoveAll(filepath.Join(s.dir, "targets")); err != nil {
+		return errors.WithStack(err)
+	}
+	return writeRemoteWriteFiles(s.dir, cfg.files)
+}
+
+func writeRemoteWriteFiles(baseDir string, files []remoteWriteFile) error {
+	for _, f := range files {
+		path := filepath.Join(baseDir, f.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return errors.WithStack(err)
+		}
+		perm := os.FileMode(0o640)
+		if f.secret {
+			perm = 0o600
+		}
+		if err := ioutil.WriteFile(path, f.content, perm); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// ValidateConfiguration validates given remote-write settings with `vmagent -dryRun`.
+func (s *RemoteWriteService) ValidateConfiguration(ctx context.Context, rw *models.RemoteWrite, interval time.Duration) error {
+	if len(rw.Targets) == 0 {
+		return nil
+	}
+
+	tmpDir, err := ioutil.TempDir("", "pmm-managed-config-vmagent-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+	cfg, err := renderRemoteWriteConfig(tmpDir, rw, interval)
+	if err != nil {
+		return err
+	}
+	if err = writeRemoteWriteFiles(tmpDir, cfg.files); err != nil {
+		return err
+	}
+
+	args := []string{"-dryRun"}
+	for _, f := range cfg.flags {
+		// flags are quoted for supervisord, but here they are passed to vmagent directly
+		name, value := splitFlag(f)
+		args = append(args, name+"="+value)
+	}
+	cmd := exec.CommandContext(ctx, "vmagent", args...) //nolint:gosec
+	pdeathsig.Set(cmd, unix.SIGKILL)
+
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		s.l.Errorf("%s", b)
+		if m := checkFailedRE.FindStringSubmatch(string(b)); len(m) == 2 {
+			return status.Error(codes.InvalidArgument, m[1])
+		}
+		return status.Errorf(codes.InvalidArgument, "Invalid remote-write configuration: %s.", strings.TrimSpace(lastLine(b)))
+	}
+	s.l.Debugf("%s", b)
+
+	return nil
+}
+
+// splitFlag splits rendered flag into name and unquoted value.
+func splitFlag(flag string) (string, string) {
+	parts := strings.SplitN(flag, "=", 2)
+	value := strings.ReplaceAll(parts[1], "%%", "%")
+	if strings.HasPrefix(value, "'") {
+		value = strings.ReplaceAll(strings.Trim(value, "'"), `'"'"'`, "'")
+	}
+	return parts[0], value
+}
+
+// lastLine returns the last non-empty line of output.
+func lastLine(b []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	return lines[len(lines)-1]
+}
+
+// Run collects forwarding health metrics from vmagent until ctx is canceled.
+func (s *RemoteWriteService) Run(ctx context.Context) {
+	s.l.Info("Starting...")
+	defer s.l.Info("Done.")
+
+	ticker := time.NewTicker(remoteWriteStatusInterval)
+	defer ticker.Stop()
+
+	for {
+		s.collect(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collect scrapes vmagent metrics and stores them with the previous sample.
+func (s *RemoteWriteService) collect(ctx context.Context) {
+	counters, err := s.scrape(ctx)
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	s.scrapeErr = err
+	if err != nil {
+		s.previous, s.current = nil, nil
+		return
+	}
+	s.previous, s.current = s.current, counters
+}
+
+func (s *RemoteWriteService) scrape(ctx context.Context) (map[int]*remoteWriteCounters, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.vmagentURL, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != 200 {
+		return nil, errors.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	return parseRemoteWriteCounters(resp.Body)
+}
+
+// parseRemoteWriteCounters extracts per-target counters from vmagent metrics in text format.
+// vmagent labels them with "<1-based target index>:secret-url".
+func parseRemoteWriteCounters(r io.Reader) (map[int]*remoteWriteCounters, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse vmagent metrics")
+	}
+
+	res := make(map[int]*remoteWriteCounters)
+	for name, mf := range families {
+		for _, m := range mf.Metric {
+			var index int
+			var statusCode string
+			for _, lp := range m.Label {
+				switch lp.GetName() {
+				case "url":
+					index, _ = strconv.Atoi(strings.SplitN(lp.GetValue(), ":", 2)[0])
+				case "status_code":
+					statusCode = lp.GetValue()
+				}
+			}
+			if index == 0 {
+				continue
+			}
+
+			c := res[index]
+			if c == nil {
+				c = new(remoteWriteCounters)
+				res[index] = c
+			}
+			// vmagent does not always expose metric types
+			var value float64
+			switch {
+			case m.Counter != nil:
+				value = m.Counter.GetValue()
+			case m.Gauge != nil:
+				value = m.Gauge.GetValue()
+			case m.Untyped != nil:
+				value = m.Untyped.GetValue()
+			}
+			switch name {
+			case "vmagent_remotewrite_requests_total":
+				if strings.HasPrefix(statusCode, "2") {
+					c.sentRequests += value
+				} else {
+					c.failedRequests += value
+				}
+			case "vmagent_remotewrite_errors_total":
+				c.errors += value
+			case "vmagent_remotewrite_packets_dropped_total":
+				c.droppedBlocks += value
+			case "vmagent_remotewrite_pending_data_bytes":
+				c.pendingBytes += value
+			}
+		}
+	}
+	return res, nil
+}
+
+// Status returns forwarding health of given remote-write targets.
+// A target is healthy if there were no failed requests or connection errors since the previous collection.
+func (s *RemoteWriteService) Status(rw *models.RemoteWrite) []*RemoteWriteTargetStatus {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	res := make([]*RemoteWriteTargetStatus, len(rw.Targets))
+	for i, t := range rw.Targets {
+		st := &RemoteWriteTargetStatus{
+			Name: t.Name,
+			URL:  t.URL,
+		}
+		res[i] = st
+
+		if s.scrapeErr != nil {
+			st.Error = fmt.Sprintf("vmagent is not available: %s", s.scrapeErr)
+			continue
+		}
+		cur := s.current[i+1]
+		if cur == nil {
+			st.Error = "no forwarding metrics yet"
+			continue
+		}
+		st.SentRequests = cur.sentRequests
+		st.FailedRequests = cur.failedRequests
+		st.DroppedBlocks = cur.droppedBlocks
+		st.PendingBytes = cur.pendingBytes
+
+		prev := s.previous[i+1]
+		if prev == nil {
+			prev = new(remoteWriteCounters)
+		}
+		switch {
+		case cur.errors > prev.errors:
+			st.Error = "connection errors"
+		case cur.failedRequests > prev.failedRequests:
+			st.Error = "requests rejected by target"
+		case cur.droppedBlocks > prev.droppedBlocks:
+			st.Error = "data dropped"
+		default:
+			st.Healthy = true
+		}
+	}
+	return res
+}
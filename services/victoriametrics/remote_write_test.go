@@ -0,0 +1,165 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package victoriametrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestRemoteWriteConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Empty", func(t *testing.T) {
+		t.Parallel()
+
+		cfg, err := renderRemoteWriteConfig("/srv/vmagent", &models.RemoteWrite{}, 10*time.Second)
+		require.NoError(t, err)
+		assert.Empty(t, cfg.flags)
+		assert.Empty(t, cfg.files)
+	})
+
+	t.Run("Normal", func(t *testing.T) {
+		t.Parallel()
+
+		rw := &models.RemoteWrite{
+			Targets: []models.RemoteWriteTarget{{
+				Name:           "mimir",
+				URL:            "https://mimir:9009/api/v1/push",
+				Username:       "pmm",
+				Password:       "pass'word",
+				Headers:        map[string]string{"X-Scope-OrgID": "databases"},
+				RelabelConfigs: "- source_labels: [__name__]\n  regex: mysql_.+\n  action: keep\n",
+			}, {
+				Name:                  "thanos",
+				URL:                   "http://thanos:19291/api/v1/receive",
+				BearerToken:           "token",
+				TLSInsecureSkipVerify: true,
+			}},
+			Queue: models.RemoteWriteQueue{
+				Queues:        4,
+				FlushInterval: 5 * time.Second,
+			},
+		}
+		cfg, err := renderRemoteWriteConfig("/srv/vmagent", rw, 10*time.Second)
+		require.NoError(t, err)
+
+		expected := []string{
+			"--httpListenAddr=127.0.0.1:8430",
+			"--promscrape.config=/srv/vmagent/scrape.yml",
+			"--promscrape.configCheckInterval=10s",
+			"--remoteWrite.tmpDataPath=/srv/vmagent/data",
+			"--remoteWrite.basicAuth.passwordFile=/srv/vmagent/targets/1/password",
+			"--remoteWrite.basicAuth.passwordFile=''",
+			"--remoteWrite.basicAuth.username=pmm",
+			"--remoteWrite.basicAuth.username=''",
+			"--remoteWrite.bearerTokenFile=''",
+			"--remoteWrite.bearerTokenFile=/srv/vmagent/targets/2/bearer_token",
+			"--remoteWrite.headers='X-Scope-OrgID: databases'",
+			"--remoteWrite.headers=''",
+			"--remoteWrite.tlsInsecureSkipVerify=false",
+			"--remoteWrite.tlsInsecureSkipVerify=true",
+			"--remoteWrite.url=https://mimir:9009/api/v1/push",
+			"--remoteWrite.url=http://thanos:19291/api/v1/receive",
+			"--remoteWrite.urlRelabelConfig=/srv/vmagent/targets/1/relabel.yml",
+			"--remoteWrite.urlRelabelConfig=''",
+			"--remoteWrite.queues=4",
+			"--remoteWrite.flushInterval=5s",
+		}
+		assert.Equal(t, expected, cfg.flags)
+
+		files := make(map[string]string)
+		for _, f := range cfg.files {
+			files[f.path] = string(f.content)
+			assert.Equal(t, f.path != "scrape.yml" && f.path != "targets/1/relabel.yml", f.secret, "%s", f.path)
+		}
+		assert.Equal(t, "pass'word", files["targets/1/password"])
+		assert.Equal(t, "token", files["targets/2/bearer_token"])
+		assert.Equal(t, rw.Targets[0].RelabelConfigs, files["targets/1/relabel.yml"])
+		assert.Contains(t, files["scrape.yml"], "metrics_path: /prometheus/federate")
+		assert.Contains(t, files["scrape.yml"], "scrape_interval: 10s")
+	})
+
+	t.Run("Quote", func(t *testing.T) {
+		t.Parallel()
+
+		for value, expected := range map[string]string{
+			"":                       "''",
+			"https://mimir:9009/api": "https://mimir:9009/api",
+			"it's 100%":              `'it'"'"'s 100%%'`,
+		} {
+			quoted := quoteFlagValue(value)
+			assert.Equal(t, expected, quoted)
+
+			_, actual := splitFlag("--flag=" + quoted)
+			assert.Equal(t, value, actual)
+		}
+	})
+}
+
+func TestRemoteWriteStatus(t *testing.T) {
+	t.Parallel()
+
+	const metrics = `
+vmagent_remotewrite_requests_total{url="1:secret-url",status_code="204"} 10
+vmagent_remotewrite_requests_total{url="2:secret-url",status_code="204"} 3
+vmagent_remotewrite_requests_total{url="2:secret-url",status_code="400"} 2
+vmagent_remotewrite_errors_total{url="1:secret-url"} 0
+vmagent_remotewrite_errors_total{url="2:secret-url"} 0
+vmagent_remotewrite_pending_data_bytes{path="/srv/vmagent/data/persistent-queue/1_1",url="1:secret-url"} 0
+vmagent_remotewrite_pending_data_bytes{path="/srv/vmagent/data/persistent-queue/2_2",url="2:secret-url"} 4096
+vm_app_version{version="vmagent"} 1
+`
+	counters, err := parseRemoteWriteCounters(strings.NewReader(metrics))
+	require.NoError(t, err)
+	require.Len(t, counters, 2)
+	assert.Equal(t, &remoteWriteCounters{sentRequests: 3, failedRequests: 2, pendingBytes: 4096}, counters[2])
+
+	rw := &models.RemoteWrite{
+		Targets: []models.RemoteWriteTarget{
+			{Name: "mimir", URL: "https://mimir:9009/api/v1/push"},
+			{Name: "thanos", URL: "http://thanos:19291/api/v1/receive"},
+		},
+	}
+
+	s := NewRemoteWriteService()
+	s.previous = map[int]*remoteWriteCounters{
+		1: {sentRequests: 5},
+		2: {sentRequests: 3, failedRequests: 1},
+	}
+	s.current = counters
+	statuses := s.Status(rw)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Healthy)
+	assert.Equal(t, float64(10), statuses[0].SentRequests)
+	assert.False(t, statuses[1].Healthy)
+	assert.Equal(t, "requests rejected by target", statuses[1].Error)
+	assert.Equal(t, float64(4096), statuses[1].PendingBytes)
+
+	s.scrapeErr = errors.New("connection refused")
+	for _, st := range s.Status(rw) {
+		assert.False(t, st.Healthy)
+		assert.Equal(t, "vmagent is not available: connection refused", st.Error)
+	}
+}
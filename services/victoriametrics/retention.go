@@ -0,0 +1,121 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package victoriametrics
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/percona/pmm/utils/pdeathsig"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// RetentionFlags returns VictoriaMetrics flags for retention tiers from settings:
+// one -retentionFilter and -downsampling.period flags per tier.
+// Both flags require VictoriaMetrics Enterprise; see ValidateRetentionFlags.
+func RetentionFlags(settings *models.Settings) []string {
+	var res []string
+	for _, t := range settings.RetentionTiers {
+		filter := seriesSelector(t.Labels)
+		res = append(res, "--retentionFilter="+quoteFlagValue(filter+":"+formatDuration(t.Retention)))
+		for _, p := range t.Downsampling {
+			period := filter + ":" + formatDuration(p.Offset) + ":" + formatDuration(p.Interval)
+			res = append(res, "--downsampling.period="+quoteFlagValue(period))
+		}
+	}
+	return res
+}
+
+// seriesSelector returns series selector matching all given labels.
+func seriesSelector(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matchers := make([]string, len(names))
+	for i, name := range names {
+		matchers[i] = name + "=" + strconv.Quote(labels[name])
+	}
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// formatDuration formats duration in VictoriaMetrics format using the largest unit without loss of precision.
+func formatDuration(d time.Duration) string {
+	for _, u := range []struct {
+		unit time.Duration
+		name string
+	}{
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+	} {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d%s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
+
+// ValidateRetentionFlags checks that VictoriaMetrics accepts retention flags for given settings
+// with `victoriametrics -dryRun`. It fails if VictoriaMetrics does not support them.
+func (svc *Service) ValidateRetentionFlags(ctx context.Context, settings *models.Settings) error {
+	flags := RetentionFlags(settings)
+	if len(flags) == 0 {
+		return nil
+	}
+
+	f, err := ioutil.TempFile("", "pmm-managed-config-victoriametrics-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	args := []string{"-dryRun", "-promscrape.config", f.Name()}
+	for _, flag := range flags {
+		// flags are quoted for supervisord, but here they are passed to VictoriaMetrics directly
+		name, value := splitFlag(flag)
+		args = append(args, name+"="+value)
+	}
+	cmd := exec.CommandContext(ctx, "victoriametrics", args...) //nolint:gosec
+	pdeathsig.Set(cmd, unix.SIGKILL)
+
+	b, err := cmd.CombinedOutput()
+	if err != nil {
+		svc.l.Errorf("%s", b)
+		return status.Errorf(codes.FailedPrecondition, "VictoriaMetrics does not accept retention tiers: %s.", strings.TrimSpace(lastLine(b)))
+	}
+	svc.l.Debugf("%s", b)
+
+	return nil
+}
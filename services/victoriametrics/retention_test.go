@@ -0,0 +1,56 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package victoriametrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestRetentionFlags(t *testing.T) {
+	t.Parallel()
+
+	settings := &models.Settings{
+		DataRetention: 365 * 24 * time.Hour,
+		RetentionTiers: []models.RetentionTier{{
+			Name:      "dev",
+			Labels:    map[string]string{"environment": "dev"},
+			Retention: 14 * 24 * time.Hour,
+		}, {
+			Name:      "prod",
+			Labels:    map[string]string{"environment": "prod", "cluster": "main"},
+			Retention: 365 * 24 * time.Hour,
+			Downsampling: []models.DownsamplingPeriod{
+				{Offset: 30 * 24 * time.Hour, Interval: 5 * time.Minute},
+				{Offset: 180 * 24 * time.Hour, Interval: 90 * time.Second},
+			},
+		}},
+	}
+	expected := []string{
+		`--retentionFilter='{environment="dev"}:14d'`,
+		`--retentionFilter='{cluster="main",environment="prod"}:365d'`,
+		`--downsampling.period='{cluster="main",environment="prod"}:30d:5m'`,
+		`--downsampling.period='{cluster="main",environment="prod"}:180d:90s'`,
+	}
+	assert.Equal(t, expected, RetentionFlags(settings))
+
+	assert.Empty(t, RetentionFlags(&models.Settings{DataRetention: 30 * 24 * time.Hour}))
+}
@@ -164,6 +164,39 @@ type scrapeConfigParams struct {
 	agent           *models.Agent
 	pmmAgentVersion *version.Parsed
 	streamParse     bool
+	// metricsResolutions are used instead of global ones for Agents grouped by host (like rds_exporter).
+	metricsResolutions *models.MetricsResolutions
+	// metricRelabelRules matching Agents grouped by host (like rds_exporter).
+	metricRelabelRules []*models.MetricRelabelRule
+}
+
+// metricRelabelConfigs converts relabel configs of given rules to Prometheus format, in order.
+func metricRelabelConfigs(rules []*models.MetricRelabelRule) []*config.RelabelConfig {
+	var res []*config.RelabelConfig
+	for _, r := range rules {
+		for _, c := range r.Configs {
+			res = append(res, &config.RelabelConfig{
+				SourceLabels: c.SourceLabels,
+				Separator:    c.Separator,
+				Regex:        c.Regex,
+				Modulus:      c.Modulus,
+				TargetLabel:  c.TargetLabel,
+				Replacement:  c.Replacement,
+				Action:       c.Action,
+			})
+		}
+	}
+	return res
+}
+
+// addMetricRelabelConfigs adds relabel configs of given rules to metric_relabel_configs of all scrape configs.
+func addMetricRelabelConfigs(scfgs []*config.ScrapeConfig, rules []*models.MetricRelabelRule) {
+	if len(rules) == 0 {
+		return
+	}
+	for _, cfg := range scfgs {
+		cfg.MetricRelabelConfigs = append(cfg.MetricRelabelConfigs, metricRelabelConfigs(rules)...)
+	}
 }
 
 // scrapeConfigForStandardExporter returns scrape config for endpoint with given parameters.
@@ -219,153 +252,46 @@ func scrapeConfigForRDSExporter(intervalName string, interval time.Duration, hos
 	}
 }
 
-func scrapeConfigsForNodeExporter(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
-	var hr, mr, lr *config.ScrapeConfig
-	var err error
-	var hrCollect []string
-
-	if params.node.Distro != "darwin" {
-		mrCollect := []string{
-			"hwmon",
-			"textfile.mr",
-		}
-		mrCollect = collectors.FilterOutCollectors("", mrCollect, params.agent.DisabledCollectors)
-		mr, err = scrapeConfigForStandardExporter("mr", s.MR, params, mrCollect)
-		if err != nil {
-			return nil, err
-		}
+// scrapeConfigsForCollectors returns HR, MR and LR scrape configs requesting metrics of collectors
+// from catalog enabled for the Agent. Resolutions without collectors enabled by default or explicitly are skipped.
+func scrapeConfigsForCollectors(s *models.MetricsResolutions, params *scrapeConfigParams, catalog *collectors.Exporter) ([]*config.ScrapeConfig, error) {
+	enabled := catalog.Enabled(params.node, params.agent)
 
-		lrCollect := []string{
-			"bonding",
-			"entropy",
-			"textfile.lr",
-			"uname",
+	withoutDisabled := *params.agent
+	withoutDisabled.DisabledCollectors = nil
+	available := catalog.Enabled(params.node, &withoutDisabled)
+
+	var r []*config.ScrapeConfig
+	for _, res := range []struct {
+		resolution collectors.Resolution
+		interval   time.Duration
+	}{
+		{collectors.HR, s.HR},
+		{collectors.MR, s.MR},
+		{collectors.LR, s.LR},
+	} {
+		if len(catalog.Collect(res.resolution, available)) == 0 {
+			continue
 		}
-		lrCollect = collectors.FilterOutCollectors("", lrCollect, params.agent.DisabledCollectors)
-		lr, err = scrapeConfigForStandardExporter("lr", s.LR, params, lrCollect)
+
+		cfg, err := scrapeConfigForStandardExporter(string(res.resolution), res.interval, params, catalog.Collect(res.resolution, enabled))
 		if err != nil {
 			return nil, err
 		}
-
-		hrCollect = append(hrCollect,
-			"buddyinfo",
-			"filefd",
-			"meminfo_numa",
-			"netstat",
-			"processes",
-			"standard.go",
-			"standard.process",
-			"stat",
-			"textfile.hr",
-			"vmstat")
-	}
-
-	hrCollect = append(
-		hrCollect,
-		"cpu",
-		"diskstats",
-		"filesystem",
-		"loadavg",
-		"meminfo",
-		"netdev",
-		"time")
-	hrCollect = collectors.FilterOutCollectors("", hrCollect, params.agent.DisabledCollectors)
-
-	hr, err = scrapeConfigForStandardExporter("hr", s.HR, params, hrCollect)
-	if err != nil {
-		return nil, err
-	}
-
-	var r []*config.ScrapeConfig
-	if hr != nil {
-		r = append(r, hr)
-	}
-	if mr != nil {
-		r = append(r, mr)
-	}
-	if lr != nil {
-		r = append(r, lr)
+		r = append(r, cfg)
 	}
 	return r, nil
 }
 
+func scrapeConfigsForNodeExporter(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
+	// keep in sync with node_exporter Agent flags generator
+	return scrapeConfigsForCollectors(s, params, collectors.Catalog(models.NodeExporterType, params.pmmAgentVersion))
+}
+
 // scrapeConfigsForMySQLdExporter returns scrape config for mysqld_exporter.
 func scrapeConfigsForMySQLdExporter(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
 	// keep in sync with mysqld_exporter Agent flags generator
-	hrOptions := []string{
-		"global_status",
-		"info_schema.innodb_metrics",
-		"custom_query.hr",
-		"standard.go",
-		"standard.process",
-	}
-	hrOptions = collectors.FilterOutCollectors("", hrOptions, params.agent.DisabledCollectors)
-
-	hr, err := scrapeConfigForStandardExporter("hr", s.HR, params, hrOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	mrOptions := []string{
-		"engine_innodb_status",
-		"info_schema.innodb_cmp",
-		"info_schema.innodb_cmpmem",
-		"info_schema.processlist",
-		"info_schema.query_response_time",
-		"perf_schema.eventswaits",
-		"perf_schema.file_events",
-		"slave_status",
-		"custom_query.mr",
-	}
-	if params.agent.IsMySQLTablestatsGroupEnabled() {
-		mrOptions = append(mrOptions, "perf_schema.tablelocks")
-	}
-
-	mrOptions = collectors.FilterOutCollectors("", mrOptions, params.agent.DisabledCollectors)
-	mr, err := scrapeConfigForStandardExporter("mr", s.MR, params, mrOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	lrOptions := []string{
-		"binlog_size",
-		"engine_tokudb_status",
-		"global_variables",
-		"heartbeat",
-		"info_schema.clientstats",
-		"info_schema.userstats",
-		"perf_schema.eventsstatements",
-		"perf_schema.file_instances",
-		"custom_query.lr",
-	}
-	if params.agent.IsMySQLTablestatsGroupEnabled() {
-		lrOptions = append(lrOptions,
-			"auto_increment.columns",
-			"info_schema.tables",
-			"info_schema.tablestats",
-			"info_schema.innodb_tablespaces",
-			"perf_schema.indexiowaits",
-			"perf_schema.tableiowaits")
-	}
-
-	lrOptions = collectors.FilterOutCollectors("", lrOptions, params.agent.DisabledCollectors)
-
-	lr, err := scrapeConfigForStandardExporter("lr", s.LR, params, lrOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	var r []*config.ScrapeConfig
-	if hr != nil {
-		r = append(r, hr)
-	}
-	if mr != nil {
-		r = append(r, mr)
-	}
-	if lr != nil {
-		r = append(r, lr)
-	}
-	return r, nil
+	return scrapeConfigsForCollectors(s, params, collectors.Catalog(models.MySQLdExporterType, params.pmmAgentVersion))
 }
 
 func scrapeConfigsForMongoDBExporter(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
@@ -383,78 +309,13 @@ func scrapeConfigsForMongoDBExporter(s *models.MetricsResolutions, params *scrap
 		}
 		return r, nil
 	}
-	hr, err := scrapeConfigForStandardExporter("hr", s.HR, params, []string{
-		"diagnosticdata",
-		"replicasetstatus",
-		"topmetrics",
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	var r []*config.ScrapeConfig
-	if hr != nil {
-		r = append(r, hr)
-	}
-	if params.agent.MongoDBOptions != nil && params.agent.MongoDBOptions.EnableAllCollectors {
-		lr, err := scrapeConfigForStandardExporter("lr", s.LR, params, []string{
-			"dbstats",
-			"indexstats",
-			"collstats",
-		})
-		if err != nil {
-			return nil, err
-		}
 
-		if lr != nil {
-			r = append(r, lr)
-		}
-	}
-	return r, nil
+	// keep in sync with mongodb_exporter Agent flags generator
+	return scrapeConfigsForCollectors(s, params, collectors.Catalog(models.MongoDBExporterType, params.pmmAgentVersion))
 }
 
 func scrapeConfigsForPostgresExporter(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
-	hrOptions := []string{
-		"exporter",
-		"custom_query.hr",
-		"standard.go",
-		"standard.process",
-	}
-	hrOptions = collectors.FilterOutCollectors("", hrOptions, params.agent.DisabledCollectors)
-	hr, err := scrapeConfigForStandardExporter("hr", s.HR, params, hrOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	mrOptions := []string{
-		"custom_query.mr",
-	}
-	mrOptions = collectors.FilterOutCollectors("", mrOptions, params.agent.DisabledCollectors)
-	mr, err := scrapeConfigForStandardExporter("mr", s.MR, params, mrOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	lrOptions := []string{
-		"custom_query.lr",
-	}
-	lrOptions = collectors.FilterOutCollectors("", lrOptions, params.agent.DisabledCollectors)
-	lr, err := scrapeConfigForStandardExporter("lr", s.LR, params, lrOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	var r []*config.ScrapeConfig
-	if hr != nil {
-		r = append(r, hr)
-	}
-	if mr != nil {
-		r = append(r, mr)
-	}
-	if lr != nil {
-		r = append(r, lr)
-	}
-	return r, nil
+	return scrapeConfigsForCollectors(s, params, collectors.Catalog(models.PostgresExporterType, params.pmmAgentVersion))
 }
 
 func scrapeConfigsForProxySQLExporter(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
@@ -470,12 +331,33 @@ func scrapeConfigsForProxySQLExporter(s *models.MetricsResolutions, params *scra
 	return r, nil
 }
 
+// scrapeConfigsForRDSExporter returns scrape configs for rds_exporters grouped by host and port.
+// The finest metrics resolutions of all Agents in the group are used.
+// Only metric relabel rules matching all Agents in the group are applied.
 func scrapeConfigsForRDSExporter(s *models.MetricsResolutions, params []*scrapeConfigParams) []*config.ScrapeConfig {
-	hostportSet := make(map[string]struct{}, len(params))
+	hostportSet := make(map[string]*models.MetricsResolutions, len(params))
+	hostportRules := make(map[string][]*models.MetricRelabelRule, len(params))
 	for _, p := range params {
 		port := int(*p.agent.ListenPort)
 		hostport := net.JoinHostPort(p.host, strconv.Itoa(port))
-		hostportSet[hostport] = struct{}{}
+
+		ps := s
+		if p.metricsResolutions != nil {
+			ps = p.metricsResolutions
+		}
+		hs := hostportSet[hostport]
+		if hs == nil {
+			hostportSet[hostport] = &models.MetricsResolutions{MR: ps.MR, LR: ps.LR}
+			hostportRules[hostport] = p.metricRelabelRules
+			continue
+		}
+		hostportRules[hostport] = intersectMetricRelabelRules(hostportRules[hostport], p.metricRelabelRules)
+		if ps.MR < hs.MR {
+			hs.MR = ps.MR
+		}
+		if ps.LR < hs.LR {
+			hs.LR = ps.LR
+		}
 	}
 
 	hostports := make([]string, 0, len(hostportSet))
@@ -486,14 +368,32 @@ func scrapeConfigsForRDSExporter(s *models.MetricsResolutions, params []*scrapeC
 
 	r := make([]*config.ScrapeConfig, 0, len(hostports)*2)
 	for _, hostport := range hostports {
-		mr := scrapeConfigForRDSExporter("mr", s.MR, hostport, "/enhanced")
-		lr := scrapeConfigForRDSExporter("lr", s.LR, hostport, "/basic")
+		hs := hostportSet[hostport]
+		mr := scrapeConfigForRDSExporter("mr", hs.MR, hostport, "/enhanced")
+		lr := scrapeConfigForRDSExporter("lr", hs.LR, hostport, "/basic")
+		addMetricRelabelConfigs([]*config.ScrapeConfig{mr, lr}, hostportRules[hostport])
 		r = append(r, mr, lr)
 	}
 
 	return r
 }
 
+// intersectMetricRelabelRules returns rules from a that are also present in b, in order of a.
+func intersectMetricRelabelRules(a, b []*models.MetricRelabelRule) []*models.MetricRelabelRule {
+	ids := make(map[string]struct{}, len(b))
+	for _, r := range b {
+		ids[r.ID] = struct{}{}
+	}
+
+	var res []*models.MetricRelabelRule
+	for _, r := range a {
+		if _, ok := ids[r.ID]; ok {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
 func scrapeConfigsForAzureDatabase(s *models.MetricsResolutions, params *scrapeConfigParams) ([]*config.ScrapeConfig, error) {
 	labels, err := mergeLabels(params.node, params.service, params.agent)
 	if err != nil {
@@ -1133,6 +1133,77 @@ func TestScrapeConfig(t *testing.T) {
 				assertScrapeConfigsEqual(t, expected[i], actual[i])
 			}
 		})
+
+		t.Run("MetricsResolutionOverrides", func(t *testing.T) {
+			params := []*scrapeConfigParams{
+				// the finest resolutions of a single rds_exporter process are used
+				{
+					host:               "1.1.1.1",
+					agent:              &models.Agent{ListenPort: pointer.ToUint16(12345)},
+					metricsResolutions: &models.MetricsResolutions{MR: 5 * time.Second, LR: 30 * time.Second},
+				},
+				{
+					host:  "1.1.1.1",
+					agent: &models.Agent{ListenPort: pointer.ToUint16(12345)},
+				},
+				{
+					host:               "2.2.2.2",
+					agent:              &models.Agent{ListenPort: pointer.ToUint16(12345)},
+					metricsResolutions: &models.MetricsResolutions{MR: 30 * time.Second, LR: 120 * time.Second},
+				},
+			}
+
+			actual := scrapeConfigsForRDSExporter(s, params)
+			require.Len(t, actual, 4)
+			assert.Equal(t, config.Duration(s.MR), actual[0].ScrapeInterval)
+			assert.Equal(t, config.Duration(30*time.Second), actual[1].ScrapeInterval)
+			assert.Equal(t, config.Duration(30*time.Second), actual[2].ScrapeInterval)
+			assert.Equal(t, config.Duration(120*time.Second), actual[3].ScrapeInterval)
+		})
+
+		t.Run("MetricRelabelRules", func(t *testing.T) {
+			dropProcessList := &models.MetricRelabelRule{
+				ID: "/metric_relabel_rule_id/1",
+				Configs: models.RelabelConfigs{
+					{SourceLabels: []string{"__name__"}, Regex: "rdsosmetrics_processList_.+", Action: "drop"},
+				},
+			}
+			keepShard := &models.MetricRelabelRule{
+				ID: "/metric_relabel_rule_id/2",
+				Configs: models.RelabelConfigs{
+					{SourceLabels: []string{"instance"}, TargetLabel: "shard", Modulus: 2, Action: "hashmod"},
+				},
+			}
+			params := []*scrapeConfigParams{
+				// only rules matching all rds_exporters of a single process are applied
+				{
+					host:               "1.1.1.1",
+					agent:              &models.Agent{ListenPort: pointer.ToUint16(12345)},
+					metricRelabelRules: []*models.MetricRelabelRule{dropProcessList, keepShard},
+				},
+				{
+					host:               "1.1.1.1",
+					agent:              &models.Agent{ListenPort: pointer.ToUint16(12345)},
+					metricRelabelRules: []*models.MetricRelabelRule{dropProcessList},
+				},
+				{
+					host:  "2.2.2.2",
+					agent: &models.Agent{ListenPort: pointer.ToUint16(12345)},
+				},
+			}
+
+			actual := scrapeConfigsForRDSExporter(s, params)
+			require.Len(t, actual, 4)
+			expected := []*config.RelabelConfig{{
+				SourceLabels: []string{"__name__"},
+				Regex:        "rdsosmetrics_processList_.+",
+				Action:       "drop",
+			}}
+			assert.Equal(t, expected, actual[0].MetricRelabelConfigs)
+			assert.Equal(t, expected, actual[1].MetricRelabelConfigs)
+			assert.Empty(t, actual[2].MetricRelabelConfigs)
+			assert.Empty(t, actual[3].MetricRelabelConfigs)
+		})
 	})
 
 	t.Run("scrapeConfigsForExternalExporter", func(t *testing.T) {
@@ -1265,6 +1336,29 @@ func TestScrapeConfig(t *testing.T) {
 			require.EqualError(t, err, "failed to decode custom labels: unexpected end of JSON input")
 		})
 	})
+	t.Run("addMetricRelabelConfigs", func(t *testing.T) {
+		rules := []*models.MetricRelabelRule{{
+			Configs: models.RelabelConfigs{
+				{SourceLabels: []string{"__name__"}, Regex: "mysql_perf_schema_.+", Action: "drop"},
+			},
+		}, {
+			Configs: models.RelabelConfigs{
+				{SourceLabels: []string{"instance"}, TargetLabel: "shard", Modulus: 4, Action: "hashmod"},
+				{SourceLabels: []string{"shard"}, Regex: "0", Action: "keep"},
+			},
+		}}
+		scfgs := []*config.ScrapeConfig{{JobName: "hr"}, {JobName: "mr"}}
+
+		addMetricRelabelConfigs(scfgs, rules)
+		expected := []*config.RelabelConfig{
+			{SourceLabels: []string{"__name__"}, Regex: "mysql_perf_schema_.+", Action: "drop"},
+			{SourceLabels: []string{"instance"}, TargetLabel: "shard", Modulus: 4, Action: "hashmod"},
+			{SourceLabels: []string{"shard"}, Regex: "0", Action: "keep"},
+		}
+		for _, cfg := range scfgs {
+			assert.Equal(t, expected, cfg.MetricRelabelConfigs)
+		}
+	})
 }
 
 func assertScrapeConfigsEqual(t *testing.T, expected, actual *config.ScrapeConfig) {
@@ -333,6 +333,9 @@ func (svc *Service) populateConfig(cfg *config.Config) error {
 		}
 		cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, scrapeConfigForVictoriaMetrics(s.HR))
 		cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, scrapeConfigForVMAlert(s.HR))
+		if len(settings.RemoteWrite.Targets) != 0 {
+			cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, scrapeConfigForRemoteWriteVMAgent(s.MR))
+		}
 		AddInternalServicesToScrape(cfg, s, settings.DBaaS.Enabled)
 		return AddScrapeConfigs(svc.l, cfg, tx.Querier, &s, nil, false)
 	})
@@ -374,6 +377,24 @@ func scrapeConfigForVMAlert(interval time.Duration) *config.ScrapeConfig {
 	}
 }
 
+// scrapeConfigForRemoteWriteVMAgent returns scrape config for vmagent forwarding metrics to remote-write targets.
+func scrapeConfigForRemoteWriteVMAgent(interval time.Duration) *config.ScrapeConfig {
+	return &config.ScrapeConfig{
+		JobName:        "vmagent-remote-write",
+		ScrapeInterval: config.Duration(interval),
+		ScrapeTimeout:  ScrapeTimeout(interval),
+		MetricsPath:    "/metrics",
+		ServiceDiscoveryConfig: config.ServiceDiscoveryConfig{
+			StaticConfigs: []*config.Group{
+				{
+					Targets: []string{remoteWriteVMAgentAddr},
+					Labels:  map[string]string{"instance": "pmm-server"},
+				},
+			},
+		},
+	}
+}
+
 // BuildScrapeConfigForVMAgent builds scrape configuration for given pmm-agent.
 func (svc *Service) BuildScrapeConfigForVMAgent(pmmAgentID string) ([]byte, error) {
 	var cfg config.Config
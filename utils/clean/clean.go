@@ -14,7 +14,7 @@
 // You should have received a copy of the GNU Affero General Public License
 // along with this program. If not, see <https://www.gnu.org/licenses/>.
 
-// Package clean has the old actions results cleaner.
+// Package clean has the old actions results and Agent events cleaner.
 package clean
 
 import (
@@ -29,12 +29,17 @@ import (
 
 // CleanResults has unexported fields for the results cleanup function.
 type CleanResults struct {
-	db *reform.DB
+	db                   *reform.DB
+	agentEventsRetention time.Duration
 }
 
 // New returns a new CleanResults instance.
-func New(db *reform.DB) *CleanResults {
-	return &CleanResults{db: db}
+// Agent events older than agentEventsRetention are removed; zero value disables their cleanup.
+func New(db *reform.DB, agentEventsRetention time.Duration) *CleanResults {
+	return &CleanResults{
+		db:                   db,
+		agentEventsRetention: agentEventsRetention,
+	}
 }
 
 // Run starts the clean process.
@@ -49,6 +54,12 @@ func (c *CleanResults) Run(ctx context.Context, interval time.Duration, olderTha
 			l.Error(err)
 		}
 
+		if c.agentEventsRetention > 0 {
+			if err := models.CleanupOldAgentEvents(c.db.Querier, models.Now().Add(-c.agentEventsRetention)); err != nil {
+				l.Error(err)
+			}
+		}
+
 		select {
 		case <-ctx.Done():
 			return
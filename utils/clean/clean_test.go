@@ -84,7 +84,7 @@ func TestCleaner(t *testing.T) {
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		defer cancel()
 
-		c := New(db)
+		c := New(db, 0)
 		go func() {
 			c.Run(ctx, 5*time.Second, 5*time.Second) // delete rows older that 5 seconds
 		}()
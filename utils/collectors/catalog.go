@@ -0,0 +1,250 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collectors
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/percona/pmm/version"
+	"github.com/pkg/errors"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+// Resolution is a metrics resolution of scrape job that requests collector's metrics with collect[] parameter.
+type Resolution string
+
+// Metrics resolutions.
+const (
+	HR Resolution = "hr"
+	MR Resolution = "mr"
+	LR Resolution = "lr"
+)
+
+// Cost is a relative cost of collector's metrics for the monitored system.
+type Cost string
+
+// Collector costs.
+const (
+	CostLow    Cost = "low"
+	CostMedium Cost = "medium"
+	CostHigh   Cost = "high"
+)
+
+// Collectors groups; collectors in a group are enabled by default only if the group is enabled for Agent.
+const (
+	// mysqld_exporter collectors that are enabled only for a limited number of tables,
+	// see models.Agent.IsMySQLTablestatsGroupEnabled.
+	GroupTablestats = "tablestats"
+	// mongodb_exporter collectors that are enabled with models.MongoDBOptions.EnableAllCollectors.
+	GroupAll = "all"
+	// node_exporter collectors that are enabled on Linux.
+	GroupLinux = "linux"
+)
+
+// Collector describes a single exporter's collector.
+type Collector struct {
+	Name string
+	// Empty if metrics are not requested by name.
+	Resolution Resolution
+	Cost       Cost
+	Default    bool
+	Group      string
+	// Minimal pmm-agent version that ships exporter with this collector; nil for all versions.
+	MinVersion *version.Parsed
+	// Flags passed to exporter when collector is enabled or disabled; empty for none.
+	EnableFlag  string
+	DisableFlag string
+	// Group of collectors with which enable flag is passed to exporter; empty if it does not depend on groups.
+	FlagGroup string
+}
+
+// IsDefault returns true if collector is enabled by default with given enabled groups.
+func (c *Collector) IsDefault(groups map[string]bool) bool {
+	return c.Default && (c.Group == "" || groups[c.Group])
+}
+
+// Exporter contains collectors catalog of a single exporter version.
+type Exporter struct {
+	AgentType  models.AgentType
+	Collectors []Collector
+	// Names of exporter flags that are not collectors, but can be removed with disabled collectors
+	// for compatibility; see FilterOutCollectors.
+	Options []string
+}
+
+var (
+	v2_18_99 = version.MustParse("2.18.99")
+	v2_20_99 = version.MustParse("2.20.99")
+	v2_24_99 = version.MustParse("2.24.99")
+	v2_25_99 = version.MustParse("2.25.99")
+)
+
+// Catalog returns collectors catalog of exporter of given type shipped with given pmm-agent version
+// (nil for the latest one), or nil if exporter's collectors are not configurable.
+func Catalog(agentType models.AgentType, pmmAgentVersion *version.Parsed) *Exporter {
+	var res *Exporter
+	switch agentType {
+	case models.NodeExporterType:
+		res = nodeExporter()
+	case models.MySQLdExporterType:
+		res = mysqldExporter()
+	case models.MongoDBExporterType:
+		switch {
+		case pmmAgentVersion == nil || !pmmAgentVersion.Less(v2_25_99): // >= 2.26
+			res = mongodbExporter()
+		case !pmmAgentVersion.Less(v2_24_99): // 2.25
+			res = mongodbExporterV225()
+		default:
+			return nil
+		}
+	case models.PostgresExporterType:
+		res = postgresExporter()
+	case models.ProxySQLExporterType:
+		res = proxysqlExporter()
+	default:
+		return nil
+	}
+
+	if pmmAgentVersion != nil {
+		collectors := make([]Collector, 0, len(res.Collectors))
+		for _, c := range res.Collectors {
+			if c.MinVersion == nil || !pmmAgentVersion.Less(c.MinVersion) {
+				collectors = append(collectors, c)
+			}
+		}
+		res.Collectors = collectors
+	}
+	return res
+}
+
+// Find returns collector by name, or nil.
+func (e *Exporter) Find(name string) *Collector {
+	name = strings.ToLower(name)
+	for i, c := range e.Collectors {
+		if c.Name == name {
+			return &e.Collectors[i]
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if some of given names are neither collectors nor options of exporter.
+func (e *Exporter) Validate(names []string) error {
+	var unknown []string
+	for _, name := range names {
+		if e.Find(name) != nil {
+			continue
+		}
+
+		var found bool
+		for _, o := range e.Options {
+			if o == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) != 0 {
+		return errors.Errorf("unknown %s collectors: %s", e.AgentType, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Groups returns collectors groups enabled for given Agent running on given Node.
+func Groups(node *models.Node, agent *models.Agent) map[string]bool {
+	switch agent.AgentType {
+	case models.NodeExporterType:
+		return map[string]bool{GroupLinux: node == nil || node.Distro != "darwin"}
+	case models.MySQLdExporterType:
+		return map[string]bool{GroupTablestats: agent.IsMySQLTablestatsGroupEnabled()}
+	case models.MongoDBExporterType:
+		return map[string]bool{GroupAll: agent.MongoDBOptions != nil && agent.MongoDBOptions.EnableAllCollectors}
+	default:
+		return nil
+	}
+}
+
+// Enabled returns a set of collectors enabled for given Agent running on given Node:
+// collectors enabled by default or explicitly, and not disabled explicitly.
+func (e *Exporter) Enabled(node *models.Node, agent *models.Agent) map[string]bool {
+	groups := Groups(node, agent)
+	res := make(map[string]bool, len(e.Collectors))
+	for _, c := range e.Collectors {
+		if c.IsDefault(groups) {
+			res[c.Name] = true
+		}
+	}
+	for _, name := range agent.EnabledCollectors {
+		if c := e.Find(name); c != nil {
+			res[c.Name] = true
+		}
+	}
+	for _, name := range agent.DisabledCollectors {
+		if c := e.Find(name); c != nil {
+			delete(res, c.Name)
+		}
+	}
+	return res
+}
+
+// Args returns exporter flags for collectors enabled for given Agent running on given Node.
+func (e *Exporter) Args(node *models.Node, agent *models.Agent) []string {
+	groups := Groups(node, agent)
+	enabled := e.Enabled(node, agent)
+	var res []string
+	for _, c := range e.Collectors {
+		flag := c.DisableFlag
+		if enabled[c.Name] {
+			flag = c.EnableFlag
+			if c.FlagGroup != "" && !groups[c.FlagGroup] {
+				flag = ""
+			}
+		}
+		if flag != "" {
+			res = append(res, flag)
+		}
+	}
+	return res
+}
+
+// Collect returns sorted collect[] parameters of scrape job with given resolution for given set of enabled collectors.
+func (e *Exporter) Collect(resolution Resolution, enabled map[string]bool) []string {
+	var res []string
+	for _, c := range e.Collectors {
+		if c.Resolution == resolution && enabled[c.Name] {
+			res = append(res, c.Name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// flags sets enable flags of all collectors without them to prefix followed by collector's name.
+func flags(prefix string, collectors []Collector) []Collector {
+	for i, c := range collectors {
+		if c.EnableFlag == "" {
+			collectors[i].EnableFlag = prefix + c.Name
+		}
+	}
+	return collectors
+}
@@ -0,0 +1,120 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/percona/pmm/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Versions", func(t *testing.T) {
+		t.Parallel()
+
+		assert.Nil(t, Catalog(models.PMMAgentType, nil))
+		assert.Nil(t, Catalog(models.MongoDBExporterType, version.MustParse("2.24.0")))
+		assert.Nil(t, Catalog(models.MongoDBExporterType, version.MustParse("2.25.0")).Find("collstats"))
+		assert.NotNil(t, Catalog(models.MongoDBExporterType, version.MustParse("2.26.0")).Find("collstats"))
+		assert.NotNil(t, Catalog(models.MongoDBExporterType, nil).Find("collstats"))
+
+		c := Catalog(models.ProxySQLExporterType, version.MustParse("2.18.0"))
+		assert.Nil(t, c.Find("stats_command_counter"))
+		assert.Nil(t, c.Find("runtime_mysql_servers"))
+		c = Catalog(models.ProxySQLExporterType, version.MustParse("2.21.0"))
+		assert.NotNil(t, c.Find("stats_command_counter"))
+		assert.NotNil(t, c.Find("runtime_mysql_servers"))
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.MySQLdExporterType, nil)
+		require.NotNil(t, c.Find("Binlog_Size"))
+		assert.Equal(t, "binlog_size", c.Find("Binlog_Size").Name)
+		assert.Nil(t, c.Find("no_such_collector"))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.MySQLdExporterType, nil)
+		assert.NoError(t, c.Validate([]string{"binlog_size", "custom_query.lr.directory"}))
+		assert.EqualError(t, c.Validate([]string{"binlog_size", "foo", "bar"}), "unknown mysqld_exporter collectors: foo, bar")
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.MySQLdExporterType, nil)
+		agent := &models.Agent{
+			AgentType:                      models.MySQLdExporterType,
+			TableCount:                     pointer.ToInt32(5000),
+			TableCountTablestatsGroupLimit: 1000,
+			EnabledCollectors:              []string{"info_schema.tables"},
+			DisabledCollectors:             []string{"heartbeat"},
+		}
+		enabled := c.Enabled(nil, agent)
+		assert.True(t, enabled["binlog_size"])
+		assert.True(t, enabled["info_schema.tables"])
+		assert.False(t, enabled["info_schema.tablestats"])
+		assert.False(t, enabled["heartbeat"])
+	})
+
+	t.Run("Groups", func(t *testing.T) {
+		t.Parallel()
+
+		nodeExporter := &models.Agent{AgentType: models.NodeExporterType}
+		assert.Equal(t, map[string]bool{GroupLinux: true}, Groups(nil, nodeExporter))
+		assert.Equal(t, map[string]bool{GroupLinux: false}, Groups(&models.Node{Distro: "darwin"}, nodeExporter))
+
+		mongodbExporter := &models.Agent{
+			AgentType:      models.MongoDBExporterType,
+			MongoDBOptions: &models.MongoDBOptions{EnableAllCollectors: true},
+		}
+		assert.Equal(t, map[string]bool{GroupAll: true}, Groups(nil, mongodbExporter))
+		assert.Nil(t, Groups(nil, &models.Agent{AgentType: models.ProxySQLExporterType}))
+	})
+
+	t.Run("Args", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.MongoDBExporterType, version.MustParse("2.25.0"))
+		agent := &models.Agent{
+			AgentType:          models.MongoDBExporterType,
+			EnabledCollectors:  []string{"dbstats"},
+			DisabledCollectors: []string{"replicasetstatus"},
+		}
+		assert.Equal(t, []string{"--no-collector.replicasetstatus", "--collector.dbstats"}, c.Args(nil, agent))
+	})
+
+	t.Run("Collect", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.PostgresExporterType, nil)
+		enabled := c.Enabled(nil, &models.Agent{AgentType: models.PostgresExporterType, DisabledCollectors: []string{"standard.go"}})
+		assert.Equal(t, []string{"custom_query.hr", "exporter", "standard.process"}, c.Collect(HR, enabled))
+		assert.Equal(t, []string{"custom_query.lr"}, c.Collect(LR, enabled))
+	})
+}
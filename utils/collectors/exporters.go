@@ -0,0 +1,189 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collectors
+
+import (
+	"github.com/percona/pmm-managed/models"
+)
+
+// keep in sync with exporters' documentation and default flags of supported pmm-agent versions
+
+func nodeExporter() *Exporter {
+	// collectors enabled by node_exporter itself, but not useful enough for PMM
+	var disabled []Collector
+	for _, name := range []string{
+		"arp", "bcache", "conntrack", "drbd", "edac", "infiniband", "interrupts", "ipvs", "ksmd", "logind",
+		"mdadm", "mountstats", "netclass", "nfs", "nfsd", "ntp", "qdisc", "runit", "sockstat", "supervisord",
+		"systemd", "tcpstat", "timex", "wifi", "xfs", "zfs",
+	} {
+		disabled = append(disabled, Collector{
+			Name: name, Resolution: LR, Cost: CostMedium, Group: GroupLinux, DisableFlag: "--no-collector." + name,
+		})
+	}
+
+	return &Exporter{
+		AgentType: models.NodeExporterType,
+		Collectors: flags("--collector.", append([]Collector{
+			{Name: "bonding", Resolution: LR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "entropy", Resolution: LR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "textfile.lr", Resolution: LR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "uname", Resolution: LR, Cost: CostLow, Default: true, Group: GroupLinux},
+
+			{Name: "hwmon", Resolution: MR, Cost: CostMedium, Default: true, Group: GroupLinux},
+			{Name: "textfile.mr", Resolution: MR, Cost: CostLow, Default: true, Group: GroupLinux},
+
+			{Name: "buddyinfo", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "cpu", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "diskstats", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "filefd", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "filesystem", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "loadavg", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "meminfo", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "meminfo_numa", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "netdev", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "netstat", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "processes", Resolution: HR, Cost: CostMedium, Default: true, Group: GroupLinux},
+			{Name: "standard.go", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "standard.process", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "stat", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "textfile.hr", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+			{Name: "time", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "vmstat", Resolution: HR, Cost: CostLow, Default: true, Group: GroupLinux},
+		}, disabled...)),
+		Options: []string{
+			"textfile.directory.lr",
+			"textfile.directory.mr",
+			"textfile.directory.hr",
+			"netstat.fields",
+			"vmstat.fields",
+		},
+	}
+}
+
+func mysqldExporter() *Exporter {
+	return &Exporter{
+		AgentType: models.MySQLdExporterType,
+		Collectors: flags("--collect.", []Collector{
+			{Name: "binlog_size", Resolution: LR, Cost: CostMedium, Default: true},
+			{Name: "engine_tokudb_status", Resolution: LR, Cost: CostLow, Default: true},
+			{Name: "global_variables", Resolution: LR, Cost: CostLow, Default: true},
+			{Name: "heartbeat", Resolution: LR, Cost: CostLow, Default: true},
+			{Name: "info_schema.clientstats", Resolution: LR, Cost: CostLow, Default: true},
+			{Name: "info_schema.userstats", Resolution: LR, Cost: CostLow, Default: true},
+			{Name: "perf_schema.eventsstatements", Resolution: LR, Cost: CostHigh, Default: true},
+			// always scraped, but collected by exporter only with tablestats group
+			{Name: "perf_schema.file_instances", Resolution: LR, Cost: CostMedium, Default: true, FlagGroup: GroupTablestats},
+			{Name: "custom_query.lr", Resolution: LR, Cost: CostMedium, Default: true},
+
+			{Name: "auto_increment.columns", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+			{Name: "info_schema.innodb_tablespaces", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+			{Name: "info_schema.tables", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+			{Name: "info_schema.tablestats", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+			{Name: "perf_schema.indexiowaits", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+			{Name: "perf_schema.tableiowaits", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+
+			{Name: "engine_innodb_status", Resolution: MR, Cost: CostMedium, Default: true},
+			{Name: "info_schema.innodb_cmp", Resolution: MR, Cost: CostLow, Default: true},
+			{Name: "info_schema.innodb_cmpmem", Resolution: MR, Cost: CostLow, Default: true},
+			{Name: "info_schema.processlist", Resolution: MR, Cost: CostMedium, Default: true},
+			{Name: "info_schema.query_response_time", Resolution: MR, Cost: CostLow, Default: true},
+			{Name: "perf_schema.eventswaits", Resolution: MR, Cost: CostMedium, Default: true},
+			{Name: "perf_schema.file_events", Resolution: MR, Cost: CostMedium, Default: true},
+			{Name: "slave_status", Resolution: MR, Cost: CostLow, Default: true},
+			{Name: "custom_query.mr", Resolution: MR, Cost: CostMedium, Default: true},
+
+			{Name: "perf_schema.tablelocks", Resolution: MR, Cost: CostHigh, Default: true, Group: GroupTablestats},
+
+			{Name: "global_status", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "info_schema.innodb_metrics", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "custom_query.hr", Resolution: HR, Cost: CostMedium, Default: true},
+			{Name: "standard.go", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "standard.process", Resolution: HR, Cost: CostLow, Default: true},
+		}),
+		Options: []string{
+			"custom_query.lr.directory",
+			"custom_query.mr.directory",
+			"custom_query.hr.directory",
+		},
+	}
+}
+
+func mongodbExporter() *Exporter {
+	return &Exporter{
+		AgentType: models.MongoDBExporterType,
+		Collectors: flags("--collector.", []Collector{
+			{Name: "diagnosticdata", Resolution: HR, Cost: CostLow, Default: true},
+			{Name: "replicasetstatus", Resolution: HR, Cost: CostLow, Default: true},
+			// enabled only with all collectors until we have better information on the resources usage impact
+			{Name: "topmetrics", Resolution: HR, Cost: CostMedium, Default: true, Group: GroupAll},
+			{Name: "collstats", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupAll},
+			{Name: "dbstats", Resolution: LR, Cost: CostMedium, Default: true, Group: GroupAll},
+			{Name: "indexstats", Resolution: LR, Cost: CostHigh, Default: true, Group: GroupAll},
+		}),
+	}
+}
+
+// mongodbExporterV225 returns catalog of mongodb_exporter shipped with pmm-agent 2.25
+// that does not support collect[] parameters.
+func mongodbExporterV225() *Exporter {
+	return &Exporter{
+		AgentType: models.MongoDBExporterType,
+		Collectors: []Collector{
+			{Name: "diagnosticdata", Cost: CostLow, Default: true, DisableFlag: "--no-collector.diagnosticdata"},
+			{Name: "replicasetstatus", Cost: CostLow, Default: true, DisableFlag: "--no-collector.replicasetstatus"},
+			// disabled until we have better information on the resources usage impact
+			{Name: "dbstats", Cost: CostMedium, EnableFlag: "--collector.dbstats"},
+			{Name: "topmetrics", Cost: CostMedium, EnableFlag: "--collector.topmetrics"},
+		},
+	}
+}
+
+func postgresExporter() *Exporter {
+	return &Exporter{
+		AgentType: models.PostgresExporterType,
+		Collectors: append(flags("--collect.", []Collector{
+			{Name: "custom_query.lr", Resolution: LR, Cost: CostMedium, Default: true},
+			{Name: "custom_query.mr", Resolution: MR, Cost: CostMedium, Default: true},
+			{Name: "custom_query.hr", Resolution: HR, Cost: CostMedium, Default: true},
+		}),
+			// always enabled in exporter, but could be excluded from scrapes
+			Collector{Name: "exporter", Resolution: HR, Cost: CostLow, Default: true},
+			Collector{Name: "standard.go", Resolution: HR, Cost: CostLow, Default: true},
+			Collector{Name: "standard.process", Resolution: HR, Cost: CostLow, Default: true},
+		),
+		Options: []string{
+			"custom_query.lr.directory",
+			"custom_query.mr.directory",
+			"custom_query.hr.directory",
+		},
+	}
+}
+
+func proxysqlExporter() *Exporter {
+	// proxysql_exporter does not support collect[] parameters
+	return &Exporter{
+		AgentType: models.ProxySQLExporterType,
+		Collectors: flags("-collect.", []Collector{
+			{Name: "mysql_connection_list", Cost: CostLow, Default: true},
+			{Name: "mysql_connection_pool", Cost: CostLow, Default: true},
+			{Name: "mysql_status", Cost: CostLow, Default: true},
+			{Name: "stats_memory_metrics", Cost: CostLow, Default: true},
+			{Name: "stats_command_counter", Cost: CostLow, Default: true, MinVersion: v2_18_99},
+			{Name: "runtime_mysql_servers", Cost: CostLow, Default: true, MinVersion: v2_20_99},
+		}),
+	}
+}
@@ -0,0 +1,91 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/pmm-managed/models"
+)
+
+func TestExporters(t *testing.T) {
+	t.Parallel()
+
+	for _, agentType := range []models.AgentType{
+		models.NodeExporterType,
+		models.MySQLdExporterType,
+		models.MongoDBExporterType,
+		models.PostgresExporterType,
+		models.ProxySQLExporterType,
+	} {
+		agentType := agentType
+		t.Run(string(agentType), func(t *testing.T) {
+			t.Parallel()
+
+			c := Catalog(agentType, nil)
+			assert.Equal(t, agentType, c.AgentType)
+
+			names := make(map[string]bool, len(c.Collectors))
+			for _, collector := range c.Collectors {
+				assert.False(t, names[collector.Name], "duplicate collector %q", collector.Name)
+				names[collector.Name] = true
+				assert.NotEmpty(t, collector.Cost, "collector %q", collector.Name)
+			}
+			for _, o := range c.Options {
+				assert.False(t, names[o], "option %q is also a collector", o)
+			}
+		})
+	}
+
+	t.Run("MySQLdTablestatsGroup", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.MySQLdExporterType, nil)
+		agent := &models.Agent{
+			AgentType:                      models.MySQLdExporterType,
+			TableCount:                     pointer.ToInt32(5000),
+			TableCountTablestatsGroupLimit: 1000,
+		}
+
+		// perf_schema.file_instances is always scraped, but collected by exporter only with tablestats group
+		enabled := c.Enabled(nil, agent)
+		assert.Contains(t, c.Collect(LR, enabled), "perf_schema.file_instances")
+		assert.NotContains(t, c.Collect(LR, enabled), "info_schema.tables")
+		assert.NotContains(t, c.Args(nil, agent), "--collect.perf_schema.file_instances")
+		assert.NotContains(t, c.Args(nil, agent), "--collect.info_schema.tables")
+
+		agent.TableCount = pointer.ToInt32(500)
+		enabled = c.Enabled(nil, agent)
+		assert.Contains(t, c.Collect(LR, enabled), "perf_schema.file_instances")
+		assert.Contains(t, c.Collect(LR, enabled), "info_schema.tables")
+		assert.Contains(t, c.Args(nil, agent), "--collect.perf_schema.file_instances")
+		assert.Contains(t, c.Args(nil, agent), "--collect.info_schema.tables")
+	})
+
+	t.Run("NodeExporterDisabledByDefault", func(t *testing.T) {
+		t.Parallel()
+
+		c := Catalog(models.NodeExporterType, nil)
+		args := c.Args(nil, &models.Agent{AgentType: models.NodeExporterType, EnabledCollectors: []string{"wifi"}})
+		assert.Contains(t, args, "--collector.cpu")
+		assert.Contains(t, args, "--no-collector.arp")
+		assert.NotContains(t, args, "--no-collector.wifi")
+	})
+}
@@ -59,6 +59,9 @@ func (e InvalidDurationError) Error() string { return string(e) }
 //  - DISABLE_TELEMETRY is a boolean flag to enable or disable pmm telemetry (and disable STT if telemetry is disabled);
 //  - METRICS_RESOLUTION, METRICS_RESOLUTION, METRICS_RESOLUTION_HR, METRICS_RESOLUTION_LR are durations of metrics resolution;
 //  - DATA_RETENTION is the duration of how long keep time-series data in ClickHouse;
+//  - DATA_RETENTION_TIERS are retention tiers of metrics selected by labels, separated by semicolons,
+//    in the name:label=value[,label=value]:retention[:offset=interval[,offset=interval]] format,
+//    for example "dev:environment=dev:336h;prod:environment=prod:8760h:720h=5m,4320h=1h";
 //  - ENABLE_ALERTING enables Integrated Alerting;
 //  - ENABLE_AZUREDISCOVER enables Azure Discover;
 //  - ENABLE_DBAAS enables Database as a Service feature, it's a replacement for deprecated PERCONA_TEST_DBAAS which still works but will be removed eventually;
@@ -117,6 +120,11 @@ func ParseEnvVars(envs []string) (envSettings *models.ChangeSettingsParams, errs
 			if envSettings.DataRetention, err = parseStringDuration(v); err != nil {
 				err = formatEnvVariableError(err, env, v)
 			}
+		case "DATA_RETENTION_TIERS":
+			// label values are case-sensitive
+			if envSettings.RetentionTiers, err = parseRetentionTiers(p[1]); err != nil {
+				err = fmt.Errorf("invalid value %q for environment variable %q: %s", p[1], k, err)
+			}
 		case "ENABLE_VM_CACHE":
 			envSettings.EnableVMCache, err = strconv.ParseBool(v)
 			if err != nil {
@@ -218,6 +226,59 @@ func parseStringDuration(value string) (time.Duration, error) {
 	return d, nil
 }
 
+// parseRetentionTiers parses retention tiers in DATA_RETENTION_TIERS format.
+func parseRetentionTiers(value string) ([]models.RetentionTier, error) {
+	var res []models.RetentionTier
+	for _, tier := range strings.Split(value, ";") {
+		if tier = strings.TrimSpace(tier); tier == "" {
+			continue
+		}
+
+		parts := strings.Split(tier, ":")
+		if len(parts) != 3 && len(parts) != 4 {
+			return nil, errors.Errorf("expected name:labels:retention[:downsampling] for tier %q", tier)
+		}
+
+		t := models.RetentionTier{
+			Name:   parts[0],
+			Labels: make(map[string]string),
+		}
+		for _, label := range strings.Split(parts[1], ",") {
+			kv := strings.SplitN(label, "=", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("invalid label %q of tier %q", label, t.Name)
+			}
+			t.Labels[kv[0]] = kv[1]
+		}
+
+		var err error
+		if t.Retention, err = parseStringDuration(parts[2]); err != nil {
+			return nil, errors.Errorf("invalid retention %q of tier %q", parts[2], t.Name)
+		}
+
+		if len(parts) == 4 {
+			for _, period := range strings.Split(parts[3], ",") {
+				kv := strings.SplitN(period, "=", 2)
+				if len(kv) != 2 {
+					return nil, errors.Errorf("invalid downsampling period %q of tier %q", period, t.Name)
+				}
+				var p models.DownsamplingPeriod
+				if p.Offset, err = parseStringDuration(kv[0]); err != nil {
+					return nil, errors.Errorf("invalid downsampling offset %q of tier %q", kv[0], t.Name)
+				}
+				if p.Interval, err = parseStringDuration(kv[1]); err != nil {
+					return nil, errors.Errorf("invalid downsampling interval %q of tier %q", kv[1], t.Name)
+				}
+				t.Downsampling = append(t.Downsampling, p)
+			}
+		}
+
+		res = append(res, t)
+	}
+
+	return res, nil
+}
+
 func parsePlatformAPITimeout(d string) (time.Duration, string) {
 	if d == "" {
 		msg := fmt.Sprintf("Environment variable %q is not set, using %q as a default timeout for platform API.", envPlatfromAPITimeout, defaultPlatformAPITimeout.String())
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/percona/pmm-managed/models"
 )
@@ -59,6 +60,41 @@ func TestEnvVarValidator(t *testing.T) {
 		assert.Nil(t, gotWarns)
 	})
 
+	t.Run("Retention tiers", func(t *testing.T) {
+		t.Parallel()
+
+		envs := []string{
+			"DATA_RETENTION=8760h",
+			"DATA_RETENTION_TIERS=dev:environment=Dev:336h;prod:environment=prod,cluster=main:8760h:720h=5m,4320h=1h",
+		}
+		expectedEnvVars := &models.ChangeSettingsParams{
+			DataRetention: 8760 * time.Hour,
+			RetentionTiers: []models.RetentionTier{{
+				Name:      "dev",
+				Labels:    map[string]string{"environment": "Dev"},
+				Retention: 336 * time.Hour,
+			}, {
+				Name:      "prod",
+				Labels:    map[string]string{"environment": "prod", "cluster": "main"},
+				Retention: 8760 * time.Hour,
+				Downsampling: []models.DownsamplingPeriod{
+					{Offset: 720 * time.Hour, Interval: 5 * time.Minute},
+					{Offset: 4320 * time.Hour, Interval: time.Hour},
+				},
+			}},
+		}
+
+		gotEnvVars, gotErrs, gotWarns := ParseEnvVars(envs)
+		assert.Equal(t, expectedEnvVars, gotEnvVars)
+		assert.Nil(t, gotErrs)
+		assert.Nil(t, gotWarns)
+
+		_, gotErrs, _ = ParseEnvVars([]string{"DATA_RETENTION_TIERS=dev:environment=dev"})
+		require.Len(t, gotErrs, 1)
+		assert.EqualError(t, gotErrs[0], `invalid value "dev:environment=dev" for environment variable "DATA_RETENTION_TIERS": `+
+			`expected name:labels:retention[:downsampling] for tier "dev:environment=dev"`)
+	})
+
 	t.Run("Unknown env variables", func(t *testing.T) {
 		t.Parallel()
 
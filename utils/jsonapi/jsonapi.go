@@ -0,0 +1,149 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package jsonapi serves JSON API methods that are not described with protobuf and not handled by grpc-gateway.
+//
+// Requests and responses follow grpc-gateway conventions used by the rest of PMM API:
+// POST method, snake_case field names, and the same error payload.
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	grpc_gateway "github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona/pmm-managed/utils/logger"
+)
+
+// maxRequestSize limits the size of the request body.
+const maxRequestSize = 5 * 1024 * 1024
+
+type serviceEnabled interface {
+	Enabled() bool
+}
+
+// httpError is the same as serverpb.HttpError, but without protobuf-specific details.
+type httpError struct {
+	Error   string `json:"error"`
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler returns http.Handler for a single JSON API method.
+//
+// Request body is decoded into Req and passed to method; its result is encoded as JSON response.
+// gRPC status errors are returned to the client as is, other errors are logged and replaced with Internal.
+// If svc implements Enabled() method and returns false, requests are rejected with FailedPrecondition.
+func Handler[Req any, Res any](name string, svc interface{}, method func(context.Context, *Req) (*Res, error)) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		l := logrus.WithField("request", logger.MakeRequestID())
+		ctx := logger.SetEntry(req.Context(), l)
+
+		res, err := handle(ctx, l, name, req, func(ctx context.Context, r *Req) (interface{}, error) {
+			if s, ok := svc.(serviceEnabled); ok && !s.Enabled() {
+				return nil, status.Errorf(codes.FailedPrecondition, "Service %s is disabled.", name)
+			}
+
+			return method(ctx, r)
+		})
+		write(l, rw, res, err)
+	})
+}
+
+func handle[Req any](ctx context.Context, l *logrus.Entry, name string, req *http.Request,
+	f func(context.Context, *Req) (interface{}, error),
+) (res interface{}, err error) {
+	start := time.Now()
+	prefix := "JSON " + name
+	l.Infof("Starting %s ...", prefix)
+
+	defer func() {
+		dur := time.Since(start)
+
+		if p := recover(); p != nil {
+			l.Errorf("%s done in %s with panic: %+v\nStack: %s", prefix, dur, p, debug.Stack())
+			res, err = nil, status.Error(codes.Internal, "Internal server error.")
+			return
+		}
+
+		_, gRPCError := status.FromError(errors.Cause(err))
+		switch {
+		case err == nil:
+			l.Infof("%s done in %s.", prefix, dur)
+		case gRPCError:
+			l.Warnf("%s done in %s with gRPC error: %+v", prefix, dur, err)
+		default:
+			l.Errorf("%s done in %s with unexpected error: %+v", prefix, dur, err)
+			err = status.Error(codes.Internal, "Internal server error.")
+		}
+	}()
+
+	if req.Method != http.MethodPost {
+		err = status.Errorf(codes.Unimplemented, "Method %s is not supported.", req.Method)
+		return
+	}
+
+	r := new(Req)
+	b, err := io.ReadAll(io.LimitReader(req.Body, maxRequestSize))
+	if err != nil {
+		err = status.Errorf(codes.InvalidArgument, "Failed to read request: %s.", err)
+		return
+	}
+	if len(b) != 0 {
+		if err = json.Unmarshal(b, r); err != nil {
+			err = status.Errorf(codes.InvalidArgument, "Failed to decode request: %s.", err)
+			return
+		}
+	}
+
+	res, err = f(ctx, r)
+	return //nolint:nakedret
+}
+
+func write(l *logrus.Entry, rw http.ResponseWriter, res interface{}, err error) {
+	code := http.StatusOK
+	if err != nil {
+		s := status.Convert(errors.Cause(err))
+		code = grpc_gateway.HTTPStatusFromCode(s.Code())
+		res = &httpError{
+			Error:   s.Message(),
+			Code:    int32(s.Code()),
+			Message: s.Message(),
+		}
+	}
+
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		l.Errorf("Failed to marshal response: %s.", err)
+		code = http.StatusInternalServerError
+		b = []byte(`{"code": 13, "message": "failed to marshal response"}`)
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if _, err = rw.Write(b); err != nil {
+		l.Debugf("Failed to write response: %s.", err)
+	}
+}
@@ -0,0 +1,135 @@
+// pmm-managed
+// Copyright (C) 2017 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package jsonapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type testRequest struct {
+	Name string `json:"name"`
+}
+
+type testResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+type testService struct {
+	enabled bool
+}
+
+func (s *testService) Enabled() bool {
+	return s.enabled
+}
+
+func (s *testService) Greet(ctx context.Context, req *testRequest) (*testResponse, error) {
+	switch req.Name {
+	case "":
+		return nil, status.Error(codes.InvalidArgument, "Empty name.")
+	case "panic":
+		panic("boom")
+	case "internal":
+		return nil, errors.New("secret details")
+	}
+	return &testResponse{Greeting: "Hello, " + req.Name}, nil
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		enabled bool
+		method  string
+		body    string
+		code    int
+		resBody string
+	}{{
+		name:    "ok",
+		enabled: true,
+		method:  http.MethodPost,
+		body:    `{"name": "PMM", "unknown": 1}`,
+		code:    http.StatusOK,
+		resBody: "{\n  \"greeting\": \"Hello, PMM\"\n}",
+	}, {
+		name:    "gRPC error",
+		enabled: true,
+		method:  http.MethodPost,
+		body:    ``,
+		code:    http.StatusBadRequest,
+		resBody: "{\n  \"error\": \"Empty name.\",\n  \"code\": 3,\n  \"message\": \"Empty name.\"\n}",
+	}, {
+		name:    "invalid JSON",
+		enabled: true,
+		method:  http.MethodPost,
+		body:    `{`,
+		code:    http.StatusBadRequest,
+		resBody: "{\n  \"error\": \"Failed to decode request: unexpected end of JSON input.\",\n  \"code\": 3,\n  \"message\": \"Failed to decode request: unexpected end of JSON input.\"\n}",
+	}, {
+		name:    "internal error",
+		enabled: true,
+		method:  http.MethodPost,
+		body:    `{"name": "internal"}`,
+		code:    http.StatusInternalServerError,
+		resBody: "{\n  \"error\": \"Internal server error.\",\n  \"code\": 13,\n  \"message\": \"Internal server error.\"\n}",
+	}, {
+		name:    "panic",
+		enabled: true,
+		method:  http.MethodPost,
+		body:    `{"name": "panic"}`,
+		code:    http.StatusInternalServerError,
+		resBody: "{\n  \"error\": \"Internal server error.\",\n  \"code\": 13,\n  \"message\": \"Internal server error.\"\n}",
+	}, {
+		name:    "disabled",
+		enabled: false,
+		method:  http.MethodPost,
+		body:    `{"name": "PMM"}`,
+		code:    http.StatusBadRequest,
+		resBody: "{\n  \"error\": \"Service test.Greet is disabled.\",\n  \"code\": 9,\n  \"message\": \"Service test.Greet is disabled.\"\n}",
+	}, {
+		name:    "GET",
+		enabled: true,
+		method:  http.MethodGet,
+		code:    http.StatusNotImplemented,
+		resBody: "{\n  \"error\": \"Method GET is not supported.\",\n  \"code\": 12,\n  \"message\": \"Method GET is not supported.\"\n}",
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := &testService{enabled: tc.enabled}
+			h := Handler("test.Greet", svc, svc.Greet)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tc.method, "/v1/Test/Greet", strings.NewReader(tc.body))
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, tc.resBody, rec.Body.String())
+		})
+	}
+}